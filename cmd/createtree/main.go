@@ -37,7 +37,9 @@ import (
 	"github.com/google/trillian/client"
 	"github.com/google/trillian/client/rpcflags"
 	"github.com/google/trillian/cmd"
+	"github.com/google/trillian/storage/storagepb"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"k8s.io/klog/v2"
 )
@@ -51,6 +53,7 @@ var (
 	displayName     = flag.String("display_name", "", "Display name of the new tree")
 	description     = flag.String("description", "", "Description of the new tree")
 	maxRootDuration = flag.Duration("max_root_duration", time.Hour, "Interval after which a new signed root is produced despite no submissions; zero means never")
+	leafCompression = flag.String("leaf_compression", storagepb.LeafCompression_NO_LEAF_COMPRESSION.String(), "Algorithm which the leaf values and extra data of the new tree are compressed with when they're stored: NO_LEAF_COMPRESSION, GZIP or ZSTD. Only supported by the SQL and memory storage")
 
 	configFile = flag.String("config", "", "Config file containing flags, file contents can be overridden by command line flags")
 
@@ -96,6 +99,11 @@ func newRequest() (*trillian.CreateTreeRequest, error) {
 		return nil, fmt.Errorf("unknown TreeType: %v", *treeType)
 	}
 
+	lc, ok := storagepb.LeafCompression_value[*leafCompression]
+	if !ok {
+		return nil, fmt.Errorf("unknown LeafCompression: %v", *leafCompression)
+	}
+
 	ctr := &trillian.CreateTreeRequest{Tree: &trillian.Tree{
 		TreeState:       trillian.TreeState(ts),
 		TreeType:        trillian.TreeType(tt),
@@ -103,6 +111,13 @@ func newRequest() (*trillian.CreateTreeRequest, error) {
 		Description:     *description,
 		MaxRootDuration: durationpb.New(*maxRootDuration),
 	}}
+	if lc := storagepb.LeafCompression(lc); lc != storagepb.LeafCompression_NO_LEAF_COMPRESSION {
+		settings, err := anypb.New(&storagepb.LogStorageSettings{LeafCompression: lc})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal storage settings: %v", err)
+		}
+		ctr.Tree.StorageSettings = settings
+	}
 	klog.Infof("Creating tree %+v", ctr.Tree)
 
 	return ctr, nil
@@ -87,6 +87,17 @@ func TestCreateTree(t *testing.T) {
 			validateErr: errors.New("unknown TreeType"),
 			wantErr:     true,
 		},
+		{
+			desc:     "leafCompression",
+			setFlags: func() { *leafCompression = "ZSTD" },
+			wantTree: defaultTree,
+		},
+		{
+			desc:        "invalidLeafCompression",
+			setFlags:    func() { *leafCompression = "LZMA" },
+			validateErr: errors.New("unknown LeafCompression"),
+			wantErr:     true,
+		},
 		{
 			desc:      "createErr",
 			createErr: status.Errorf(codes.Unavailable, "create tree failed"),
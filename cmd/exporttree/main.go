@@ -0,0 +1,132 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the exporttree
+// command, which writes a log tree to an archive that can be read by the
+// importtree command.
+//
+// If the log server keeps large leaf values in a blob store, or archives old
+// leaves, the same stores must be given with --leaf_blob_store and
+// --leaf_archive_store, so that the archive holds the leaf values rather than
+// references to them.
+//
+// Example usage:
+// $ ./exporttree --storage_system=mysql --tree_id=treeid --archive=tree.trlarc
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/archive"
+	"github.com/google/trillian/storage/blob"
+	"github.com/google/trillian/storage/leafarchive"
+	"k8s.io/klog/v2"
+
+	// Register supported storage providers.
+	_ "github.com/google/trillian/storage/bolt"
+	_ "github.com/google/trillian/storage/cloudspanner"
+	_ "github.com/google/trillian/storage/crdb"
+	_ "github.com/google/trillian/storage/memory"
+	_ "github.com/google/trillian/storage/mysql"
+	_ "github.com/google/trillian/storage/postgresql"
+	_ "github.com/google/trillian/storage/sqlite"
+)
+
+var (
+	storageSystem    = flag.String("storage_system", "mysql", fmt.Sprintf("Storage system to use. One of: %v", storage.Providers()))
+	treeID           = flag.Int64("tree_id", 0, "ID of the log tree to export")
+	archivePath      = flag.String("archive", "", "Path of the archive to write, or - for stdout")
+	batchSize        = flag.Int("batch_size", archive.DefaultBatchSize, "Number of leaves or nodes read from storage at a time")
+	includeTiles     = flag.Bool("include_tiles", false, "If true, the Merkle tree nodes are included in the archive")
+	leafBlobStore    = flag.String("leaf_blob_store", "", "Blob store holding large leaf values, as for the log server, if any")
+	leafArchiveStore = flag.String("leaf_archive_store", "", "Blob store holding archived leaves, as for the log server, if any")
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+	defer klog.Flush()
+
+	if *treeID == 0 {
+		klog.Exit("--tree_id must be set")
+	}
+	if *archivePath == "" {
+		klog.Exit("--archive must be set")
+	}
+
+	ctx := context.Background()
+	sp, err := storage.NewProviderWithContext(ctx, *storageSystem, monitoring.InertMetricFactory{})
+	if err != nil {
+		klog.Exitf("Failed to get storage provider: %v", err)
+	}
+	defer sp.Close()
+	if *leafArchiveStore != "" {
+		as, err := blob.OpenStore(*leafArchiveStore)
+		if err != nil {
+			klog.Exitf("Failed to open leaf archive store: %v", err)
+		}
+		// Leaves are read in order, so each segment is only decoded once.
+		sp = leafarchive.NewProvider(sp, as, 1)
+	}
+	if *leafBlobStore != "" {
+		bs, err := blob.OpenStore(*leafBlobStore)
+		if err != nil {
+			klog.Exitf("Failed to open leaf blob store: %v", err)
+		}
+		// Nothing is written to the blob store, as no leaves are queued.
+		sp = blob.NewProvider(sp, bs, math.MaxInt32)
+	}
+
+	if err := run(ctx, sp); err != nil {
+		klog.Exitf("Failed to export tree %d: %v", *treeID, err)
+	}
+	klog.Infof("Exported tree %d to %s", *treeID, *archivePath)
+}
+
+func run(ctx context.Context, sp storage.Provider) error {
+	var w io.Writer = os.Stdout
+	var f *os.File
+	if *archivePath != "-" {
+		var err error
+		if f, err = os.Create(*archivePath); err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+
+	opts := archive.ExportOptions{BatchSize: *batchSize, IncludeTiles: *includeTiles}
+	if err := archive.Export(ctx, bw, sp.AdminStorage(), sp.LogStorage(), *treeID, opts); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if f != nil {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		return f.Close()
+	}
+	return nil
+}
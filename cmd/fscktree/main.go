@@ -0,0 +1,179 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the fscktree
+// command, which checks the integrity of log trees in storage.
+//
+// The leaves of each tree are read up to its latest log root, their hashes
+// recomputed, and the Merkle tree rebuilt and compared with the stored tiles
+// and with the root hashes of all the stored log roots, or only the latest
+// one if the storage system can't list them. Missing leaves, hash mismatches
+// and inconsistent roots are reported, and the command exits with an error if
+// any are found.
+//
+// With --repair and --rewrite_tree_head, missing or wrong tiles are rewritten
+// if the leaves match the latest log root, together with a copy of that root
+// with a fresh timestamp, which storage needs to read the new tiles. Clients
+// see it as a new signed tree head of the same size and root hash. The logs
+// should be frozen or their signers stopped first, and log servers caching
+// tiles with --subtree_tile_cache_size restarted afterwards, as they may have
+// cached the wrong tiles.
+//
+// Example usage:
+// $ ./fscktree --storage_system=mysql --tree_ids=1,2
+// $ ./fscktree --storage_system=mysql --tree_ids=1 --repair --rewrite_tree_head
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/blob"
+	"github.com/google/trillian/storage/fsck"
+	"github.com/google/trillian/storage/leafarchive"
+	"k8s.io/klog/v2"
+
+	// Register supported storage providers.
+	_ "github.com/google/trillian/storage/bolt"
+	_ "github.com/google/trillian/storage/cloudspanner"
+	_ "github.com/google/trillian/storage/crdb"
+	_ "github.com/google/trillian/storage/memory"
+	_ "github.com/google/trillian/storage/mysql"
+	_ "github.com/google/trillian/storage/postgresql"
+	_ "github.com/google/trillian/storage/sqlite"
+)
+
+var (
+	storageSystem    = flag.String("storage_system", "", fmt.Sprintf("Storage system to check trees in. One of: %v", storage.Providers()))
+	treeIDs          = flag.String("tree_ids", "", "Comma-separated IDs of the log trees to check. If empty, all the log trees in storage which aren't deleted are checked")
+	batchSize        = flag.Int("batch_size", fsck.DefaultBatchSize, "Number of leaves read in each transaction")
+	maxProblems      = flag.Int("max_problems", 100, "Maximum number of problems reported for each tree, or 0 for no limit")
+	repair           = flag.Bool("repair", false, "If true, missing or wrong tiles are rewritten when the leaves match the latest log root. Requires --rewrite_tree_head")
+	rewriteTreeHead  = flag.Bool("rewrite_tree_head", false, "If true, --repair stores a copy of the latest log root with a fresh timestamp, without which storage doesn't read the rewritten tiles. Clients see it as a new signed tree head of the same size and root hash. Log servers with --subtree_tile_cache_size must be restarted after a repair")
+	leafBlobStore    = flag.String("leaf_blob_store", "", "Blob store holding large leaf values, as for the log server, if any")
+	leafArchiveStore = flag.String("leaf_archive_store", "", "Blob store holding archived leaves, as for the log server, if any")
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+	defer klog.Flush()
+
+	if *storageSystem == "" {
+		klog.Exit("--storage_system must be set")
+	}
+	if *repair && !*rewriteTreeHead {
+		klog.Exit("--repair stores a new tree head, and requires --rewrite_tree_head")
+	}
+
+	ctx := context.Background()
+	sp, err := storage.NewProviderWithContext(ctx, *storageSystem, monitoring.InertMetricFactory{})
+	if err != nil {
+		klog.Exitf("Failed to get storage provider: %v", err)
+	}
+	defer sp.Close()
+	if *leafArchiveStore != "" {
+		as, err := blob.OpenStore(*leafArchiveStore)
+		if err != nil {
+			klog.Exitf("Failed to open leaf archive store: %v", err)
+		}
+		sp = leafarchive.NewProvider(sp, as, 1)
+	}
+	if *leafBlobStore != "" {
+		bs, err := blob.OpenStore(*leafBlobStore)
+		if err != nil {
+			klog.Exitf("Failed to open leaf blob store: %v", err)
+		}
+		// Nothing is written to the blob store, as no leaves are queued.
+		sp = blob.NewProvider(sp, bs, math.MaxInt32)
+	}
+
+	trees, err := treesToCheck(ctx, sp.AdminStorage())
+	if err != nil {
+		klog.Exitf("Failed to find trees to check: %v", err)
+	}
+
+	opts := fsck.Options{BatchSize: *batchSize, MaxProblems: *maxProblems, Repair: *repair, RewriteRoot: *rewriteTreeHead}
+	failed := 0
+	for _, t := range trees {
+		if !checkTree(ctx, sp.LogStorage(), t, opts) {
+			failed++
+		}
+	}
+	if failed > 0 {
+		klog.Exitf("Found problems in %d of %d trees", failed, len(trees))
+	}
+}
+
+// checkTree checks the given tree, logs the result, and returns whether the
+// tree is free of problems.
+func checkTree(ctx context.Context, ls storage.LogStorage, t *trillian.Tree, opts fsck.Options) bool {
+	r, err := fsck.Check(ctx, ls, t, opts)
+	for _, p := range r.Problems {
+		klog.Warningf("Tree %d: %v", t.TreeId, p)
+	}
+	if n := r.ProblemCount - int64(len(r.Problems)); n > 0 {
+		klog.Warningf("Tree %d: %d more problems", t.TreeId, n)
+	}
+	if r.Repaired > 0 {
+		klog.Infof("Tree %d: repaired %d nodes", t.TreeId, r.Repaired)
+	}
+	if err != nil {
+		klog.Errorf("Tree %d: %v", t.TreeId, err)
+		return false
+	}
+	klog.Infof("Tree %d: checked %d leaves, %d nodes and %d tree heads up to size %d with root hash %x, found %d problems",
+		t.TreeId, r.Leaves, r.Nodes, r.Heads, r.Root.TreeSize, r.Root.RootHash, r.ProblemCount)
+	return r.OK()
+}
+
+// treesToCheck returns the trees in --tree_ids, or all the log trees in the
+// given storage.
+func treesToCheck(ctx context.Context, as storage.AdminStorage) ([]*trillian.Tree, error) {
+	if *treeIDs == "" {
+		trees, err := storage.ListTrees(ctx, as, false)
+		if err != nil {
+			return nil, err
+		}
+		var ret []*trillian.Tree
+		for _, t := range trees {
+			switch t.TreeType {
+			case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+				ret = append(ret, t)
+			}
+		}
+		return ret, nil
+	}
+
+	var ret []*trillian.Tree
+	for _, s := range strings.Split(*treeIDs, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tree ID %q: %v", s, err)
+		}
+		t, err := storage.GetTree(ctx, as, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tree %d: %v", id, err)
+		}
+		ret = append(ret, t)
+	}
+	return ret, nil
+}
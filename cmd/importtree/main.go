@@ -0,0 +1,87 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the importtree
+// command, which creates a log tree from an archive written by the exporttree
+// command.
+//
+// The imported tree gets a new ID, which is printed to stdout.
+//
+// Example usage:
+// $ ./importtree --storage_system=mysql --archive=tree.trlarc
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/archive"
+	"k8s.io/klog/v2"
+
+	// Register supported storage providers.
+	_ "github.com/google/trillian/storage/bolt"
+	_ "github.com/google/trillian/storage/cloudspanner"
+	_ "github.com/google/trillian/storage/crdb"
+	_ "github.com/google/trillian/storage/memory"
+	_ "github.com/google/trillian/storage/mysql"
+	_ "github.com/google/trillian/storage/postgresql"
+	_ "github.com/google/trillian/storage/sqlite"
+)
+
+var (
+	storageSystem = flag.String("storage_system", "mysql", fmt.Sprintf("Storage system to use. One of: %v", storage.Providers()))
+	archivePath   = flag.String("archive", "", "Path of the archive to read, or - for stdin")
+	batchSize     = flag.Int("batch_size", archive.DefaultBatchSize, "Number of leaves written to storage in each transaction")
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+	defer klog.Flush()
+
+	if *archivePath == "" {
+		klog.Exit("--archive must be set")
+	}
+
+	var r io.Reader = os.Stdin
+	if *archivePath != "-" {
+		f, err := os.Open(*archivePath)
+		if err != nil {
+			klog.Exitf("Failed to open archive: %v", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	ctx := context.Background()
+	sp, err := storage.NewProviderWithContext(ctx, *storageSystem, monitoring.InertMetricFactory{})
+	if err != nil {
+		klog.Exitf("Failed to get storage provider: %v", err)
+	}
+	defer sp.Close()
+
+	opts := archive.ImportOptions{BatchSize: *batchSize}
+	tree, err := archive.Import(ctx, bufio.NewReader(r), sp.AdminStorage(), sp.LogStorage(), opts)
+	if err != nil {
+		klog.Exitf("Failed to import tree: %v", err)
+	}
+	klog.Infof("Imported %s as tree %d", *archivePath, tree.TreeId)
+	fmt.Println(tree.TreeId)
+}
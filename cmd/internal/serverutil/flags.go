@@ -0,0 +1,64 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serverutil
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TreeDurations is a flag.Value holding a duration per tree ID, set from a
+// comma-separated list of treeID=duration pairs, e.g. "123=24h,456=30m".
+type TreeDurations map[int64]time.Duration
+
+// String implements flag.Value.
+func (d TreeDurations) String() string {
+	ids := make([]int64, 0, len(d))
+	for id := range d {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	pairs := make([]string, len(ids))
+	for i, id := range ids {
+		pairs[i] = fmt.Sprintf("%d=%v", id, d[id])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set implements flag.Value.
+func (d TreeDurations) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		if pair == "" {
+			continue
+		}
+		idStr, durStr, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("%q is not of the form treeID=duration", pair)
+		}
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid tree ID in %q: %v", pair, err)
+		}
+		dur, err := time.ParseDuration(durStr)
+		if err != nil {
+			return fmt.Errorf("invalid duration in %q: %v", pair, err)
+		}
+		d[id] = dur
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serverutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTreeDurations(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		want    TreeDurations
+		wantErr bool
+	}{
+		{value: "", want: TreeDurations{}},
+		{value: "1=24h", want: TreeDurations{1: 24 * time.Hour}},
+		{value: "2=0s,1=30m,", want: TreeDurations{1: 30 * time.Minute, 2: 0}},
+		{value: "1", wantErr: true},
+		{value: "x=1h", wantErr: true},
+		{value: "1=forever", wantErr: true},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			got := TreeDurations{}
+			err := got.Set(tc.value)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Set(%q) = %v, want error: %v", tc.value, err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Set(%q) diff (-want +got):\n%s", tc.value, diff)
+			}
+		})
+	}
+
+	if got, want := (TreeDurations{2: time.Hour, 1: time.Minute}).String(), "1=1m0s,2=1h0m0s"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
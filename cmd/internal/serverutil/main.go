@@ -51,6 +51,18 @@ const (
 	// hard-deleting them.
 	// Actual runs happen randomly between [minInterval,2*minInterval).
 	DefaultTreeDeleteMinInterval = 4 * time.Hour
+
+	// DefaultSubtreeGCRetention is the suggested minimum time for which tree heads remain
+	// readable after they have been superseded, when superseded subtree revisions are deleted.
+	DefaultSubtreeGCRetention = 24 * time.Hour
+
+	// DefaultSubtreeGCMinInterval is the suggested min interval between subtree GC sweeps.
+	// Actual runs happen randomly between [minInterval,2*minInterval).
+	DefaultSubtreeGCMinInterval = time.Hour
+
+	// DefaultTreeStatsMinInterval is the suggested min interval between exports of the storage
+	// usage statistics of trees. Actual runs happen randomly between [minInterval,2*minInterval).
+	DefaultTreeStatsMinInterval = 5 * time.Minute
 )
 
 // Main encapsulates the data and logic to start a Trillian server (Log or Map).
@@ -88,6 +100,16 @@ type Main struct {
 	TreeDeleteThreshold   time.Duration
 	TreeDeleteMinInterval time.Duration
 
+	// SubtreeGCEnabled makes the server periodically delete superseded subtree revisions of
+	// logs, if the log storage supports it.
+	SubtreeGCEnabled bool
+	SubtreeGCOptions admin.SubtreeGCOptions
+
+	// TreeStatsEnabled makes the server periodically export the storage usage statistics of
+	// logs as metrics, if the log storage supports it.
+	TreeStatsEnabled     bool
+	TreeStatsMinInterval time.Duration
+
 	// These will be added to the GRPC server options.
 	ExtraOptions []grpc.ServerOption
 }
@@ -196,6 +218,28 @@ func (m *Main) Run(ctx context.Context) error {
 		})
 	}
 
+	if m.SubtreeGCEnabled {
+		gc, err := admin.NewSubtreeGC(m.Registry.AdminStorage, m.Registry.LogStorage, m.SubtreeGCOptions, m.Registry.MetricFactory)
+		if err != nil {
+			klog.Warningf("Subtree GC disabled: %v", err)
+		} else {
+			g.Go(func() error {
+				klog.Info("Subtree GC started")
+				gc.Run(ctx)
+				return nil
+			})
+		}
+	}
+
+	if m.TreeStatsEnabled {
+		g.Go(func() error {
+			klog.Info("Tree stats exporter started")
+			e := admin.NewTreeStatsExporter(m.Registry.AdminStorage, m.Registry.LogStorage, m.TreeStatsMinInterval, m.Registry.MetricFactory)
+			e.Run(ctx)
+			return nil
+		})
+	}
+
 	run := func() error {
 		if err := srv.Serve(lis); err != nil {
 			return fmt.Errorf("RPC server terminated: %v", err)
@@ -0,0 +1,103 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the
+// migrateschema command, which upgrades the schema of an SQL storage system
+// to a newer version.
+//
+// The database is configured with the usual flags of the storage provider.
+// Migrations which are compatible with the running binaries, such as making
+// columns optional, are separate from the ones which are not, such as dropping
+// them, so upgrades can be done online: migrate to the oldest version the new
+// binaries support with --target_version, roll them out, and then migrate to
+// the latest version.
+//
+// Example usage:
+// $ ./migrateschema --storage_system=mysql --dry_run
+// $ ./migrateschema --storage_system=mysql --target_version=2
+// $ ./migrateschema --storage_system=mysql
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/google/trillian/storage/sqlschema"
+	"k8s.io/klog/v2"
+
+	// Register supported storage providers.
+	_ "github.com/google/trillian/storage/crdb"
+	_ "github.com/google/trillian/storage/mysql"
+	_ "github.com/google/trillian/storage/postgresql"
+	_ "github.com/google/trillian/storage/sqlite"
+)
+
+var (
+	storageSystem = flag.String("storage_system", "mysql", fmt.Sprintf("Storage system whose schema to upgrade. One of: %v", sqlschema.Names()))
+	targetVersion = flag.Int("target_version", 0, "Schema version to upgrade to. If 0, the latest version is used")
+	dryRun        = flag.Bool("dry_run", false, "If true, only print the migrations which would be applied")
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+	defer klog.Flush()
+
+	ctx := context.Background()
+	st, err := sqlschema.Get(*storageSystem)
+	if err != nil {
+		klog.Exitf("Failed to get storage schema: %v", err)
+	}
+	db, err := st.Open()
+	if err != nil {
+		klog.Exitf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	version, err := st.Schema.Version(ctx, db)
+	if err != nil {
+		klog.Exitf("Failed to read schema version: %v", err)
+	}
+	target := *targetVersion
+	if target == 0 {
+		target = st.Schema.LatestVersion()
+	}
+	klog.Infof("Schema is at version %d, upgrading to version %d (binaries need version %d to %d)",
+		version, target, st.Schema.MinVersion(), st.Schema.LatestVersion())
+
+	if *dryRun {
+		for _, m := range st.Schema.Migrations() {
+			if m.Version <= version || m.Version > target {
+				continue
+			}
+			fmt.Printf("-- Version %d: %s\n", m.Version, m.Description)
+			for _, stmt := range m.Statements {
+				fmt.Printf("%s;\n", stmt)
+			}
+		}
+		return
+	}
+
+	applied, err := st.Schema.Migrate(ctx, db, target)
+	for _, m := range applied {
+		klog.Infof("Upgraded schema to version %d: %s", m.Version, m.Description)
+	}
+	if err != nil {
+		klog.Exitf("Failed to upgrade schema: %v", err)
+	}
+	if len(applied) == 0 {
+		klog.Infof("Schema is up to date")
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the
+// migratetree command, which copies log trees from one storage system to
+// another, preserving their IDs and log roots.
+//
+// The source and destination storage systems are configured with the usual
+// flags of their providers, so they must be different systems. Without
+// --cutover, the trees are copied up to their latest log roots, and can be
+// copied again later to catch up with a live source. With --cutover, the
+// source trees are drained and frozen, and the destination trees take over
+// once the final copy matches.
+//
+// If the log servers keep large leaf values in blob stores, the store of the
+// source must be given with --src_leaf_blob_store so that the values are
+// copied rather than references to them, and that of the destination with
+// --dst_leaf_blob_store so that they're offloaded again. Likewise, if the
+// source log server archives old leaves, its archive store must be given with
+// --src_leaf_archive_store. The copied leaves aren't archived again until the
+// archiving job of the destination log server runs.
+//
+// Example usage:
+// $ ./migratetree --src_storage_system=mysql --dst_storage_system=crdb --tree_ids=1,2
+// $ ./migratetree --src_storage_system=mysql --dst_storage_system=crdb --tree_ids=1,2 --cutover
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/blob"
+	"github.com/google/trillian/storage/leafarchive"
+	"github.com/google/trillian/storage/migrate"
+	"k8s.io/klog/v2"
+
+	// Register supported storage providers.
+	_ "github.com/google/trillian/storage/bolt"
+	_ "github.com/google/trillian/storage/cloudspanner"
+	_ "github.com/google/trillian/storage/crdb"
+	_ "github.com/google/trillian/storage/memory"
+	_ "github.com/google/trillian/storage/mysql"
+	_ "github.com/google/trillian/storage/postgresql"
+	_ "github.com/google/trillian/storage/sqlite"
+)
+
+var (
+	srcStorageSystem     = flag.String("src_storage_system", "", fmt.Sprintf("Storage system to copy trees from. One of: %v", storage.Providers()))
+	dstStorageSystem     = flag.String("dst_storage_system", "", fmt.Sprintf("Storage system to copy trees to. One of: %v", storage.Providers()))
+	treeIDs              = flag.String("tree_ids", "", "Comma-separated IDs of the log trees to migrate. If empty, all the log trees in the source storage which aren't deleted are migrated")
+	cutover              = flag.Bool("cutover", false, "If true, the source trees are drained and frozen, and the destination trees activated once the final copy is complete")
+	batchSize            = flag.Int("batch_size", migrate.DefaultBatchSize, "Number of leaves copied in each transaction")
+	pollInterval         = flag.Duration("poll_interval", migrate.DefaultPollInterval, "Interval at which draining source trees are checked during cutover")
+	srcLeafBlobStore     = flag.String("src_leaf_blob_store", "", "Blob store holding the large leaf values of the source storage, as for its log server, if any")
+	srcLeafArchiveStore  = flag.String("src_leaf_archive_store", "", "Blob store holding the archived leaves of the source storage, as for its log server, if any")
+	dstLeafBlobStore     = flag.String("dst_leaf_blob_store", "", "If set, leaf values longer than --dst_leaf_blob_threshold bytes are kept in this blob store instead of the destination storage, as for its log server")
+	dstLeafBlobThreshold = flag.Int("dst_leaf_blob_threshold", 64<<10, "Leaf values longer than this many bytes are kept in --dst_leaf_blob_store, if set")
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+	defer klog.Flush()
+
+	if *srcStorageSystem == "" || *dstStorageSystem == "" {
+		klog.Exit("--src_storage_system and --dst_storage_system must be set")
+	}
+	if *srcStorageSystem == *dstStorageSystem {
+		klog.Exit("--src_storage_system and --dst_storage_system must be different")
+	}
+
+	ctx := context.Background()
+	mf := monitoring.InertMetricFactory{}
+	src, err := storage.NewProviderWithContext(ctx, *srcStorageSystem, mf)
+	if err != nil {
+		klog.Exitf("Failed to get source storage provider: %v", err)
+	}
+	defer src.Close()
+	if *srcLeafArchiveStore != "" {
+		as, err := blob.OpenStore(*srcLeafArchiveStore)
+		if err != nil {
+			klog.Exitf("Failed to open source leaf archive store: %v", err)
+		}
+		// Leaves are read in order, so each segment is only decoded once.
+		src = leafarchive.NewProvider(src, as, 1)
+	}
+	if *srcLeafBlobStore != "" {
+		bs, err := blob.OpenStore(*srcLeafBlobStore)
+		if err != nil {
+			klog.Exitf("Failed to open source leaf blob store: %v", err)
+		}
+		// Nothing is written to the blob store, as no leaves are queued.
+		src = blob.NewProvider(src, bs, math.MaxInt32)
+	}
+	dst, err := storage.NewProviderWithContext(ctx, *dstStorageSystem, mf)
+	if err != nil {
+		klog.Exitf("Failed to get destination storage provider: %v", err)
+	}
+	defer dst.Close()
+	if *dstLeafBlobStore != "" {
+		bs, err := blob.OpenStore(*dstLeafBlobStore)
+		if err != nil {
+			klog.Exitf("Failed to open destination leaf blob store: %v", err)
+		}
+		dst = blob.NewProvider(dst, bs, *dstLeafBlobThreshold)
+	}
+
+	ids, err := treesToMigrate(ctx, src)
+	if err != nil {
+		klog.Exitf("Failed to find trees to migrate: %v", err)
+	}
+
+	opts := migrate.Options{BatchSize: *batchSize, PollInterval: *pollInterval}
+	failed := 0
+	for _, id := range ids {
+		if err := migrateTree(ctx, src, dst, id, opts); err != nil {
+			klog.Errorf("Tree %d: %v", id, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		klog.Exitf("Failed to migrate %d of %d trees", failed, len(ids))
+	}
+}
+
+func migrateTree(ctx context.Context, src, dst storage.Provider, treeID int64, opts migrate.Options) error {
+	migrateFn := migrate.CatchUp
+	if *cutover {
+		migrateFn = migrate.Cutover
+	}
+	root, err := migrateFn(ctx, src, dst, treeID, opts)
+	if err != nil {
+		return err
+	}
+	if err := migrate.Compare(ctx, src, dst, treeID); err != nil {
+		// The source tree is live, and may have moved on already.
+		klog.Warningf("Tree %d: %v", treeID, err)
+	}
+	if root == nil {
+		klog.Infof("Tree %d: not initialized", treeID)
+		return nil
+	}
+	klog.Infof("Tree %d: copied up to size %d with root hash %x", treeID, root.TreeSize, root.RootHash)
+	return nil
+}
+
+// treesToMigrate returns the IDs in --tree_ids, or of all the log trees in
+// the given storage.
+func treesToMigrate(ctx context.Context, sp storage.Provider) ([]int64, error) {
+	var ids []int64
+	if *treeIDs != "" {
+		for _, s := range strings.Split(*treeIDs, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tree ID %q: %v", s, err)
+			}
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+
+	trees, err := storage.ListTrees(ctx, sp.AdminStorage(), false)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range trees {
+		switch t.TreeType {
+		case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+			ids = append(ids, t.TreeId)
+		}
+	}
+	return ids, nil
+}
@@ -38,7 +38,12 @@ import (
 	"github.com/google/trillian/quota/etcd/quotaapi"
 	"github.com/google/trillian/quota/etcd/quotapb"
 	"github.com/google/trillian/server"
+	"github.com/google/trillian/server/admin"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/blob"
+	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/storage/instrumented"
+	"github.com/google/trillian/storage/leafarchive"
 	"github.com/google/trillian/util"
 	"github.com/google/trillian/util/clock"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -46,13 +51,18 @@ import (
 	"k8s.io/klog/v2"
 
 	// Register supported storage providers.
+	_ "github.com/google/trillian/storage/bolt"
 	_ "github.com/google/trillian/storage/cloudspanner"
 	_ "github.com/google/trillian/storage/crdb"
+	_ "github.com/google/trillian/storage/faulty"
 	_ "github.com/google/trillian/storage/mysql"
+	_ "github.com/google/trillian/storage/postgresql"
+	_ "github.com/google/trillian/storage/sqlite"
 
 	// Load quota providers
 	_ "github.com/google/trillian/quota/crdbqm"
 	_ "github.com/google/trillian/quota/mysqlqm"
+	_ "github.com/google/trillian/quota/postgresqlqm"
 )
 
 var (
@@ -67,12 +77,34 @@ var (
 	quotaSystem = flag.String("quota_system", "mysql", fmt.Sprintf("Quota system to use. One of: %v", quota.Providers()))
 	quotaDryRun = flag.Bool("quota_dry_run", false, "If true no requests are blocked due to lack of tokens")
 
-	storageSystem = flag.String("storage_system", "mysql", fmt.Sprintf("Storage system to use. One of: %v", storage.Providers()))
+	storageSystem  = flag.String("storage_system", "mysql", fmt.Sprintf("Storage system to use. One of: %v", storage.Providers()))
+	storageMetrics = flag.Bool("storage_metrics", false, "If true, latency and error metrics, and tracing spans, are recorded for all the storage methods")
+
+	leafBlobStore     = flag.String("leaf_blob_store", "", "If set, large leaf values are kept in this blob store instead of the storage system, e.g. file:///var/lib/trillian/blobs or s3://bucket/prefix?region=us-east-1&endpoint=http://host:9000")
+	leafBlobThreshold = flag.Int("leaf_blob_threshold", 64<<10, "Leaf values longer than this many bytes are kept in --leaf_blob_store, if set")
+
+	leafArchiveStore          = flag.String("leaf_archive_store", "", "If set, archived leaves are read from segments in this blob store, e.g. file:///var/lib/trillian/archive or s3://bucket/prefix")
+	leafArchiveCacheSize      = flag.Int("leaf_archive_cache_size", 64, "Number of decoded archive segments cached in memory")
+	leafArchiveJob            = flag.Bool("leaf_archive_job", false, "If true, old leaves of logs are periodically moved to --leaf_archive_store, if the storage system supports it")
+	leafArchiveSegmentSize    = flag.Int64("leaf_archive_segment_size", 4096, "Number of leaves in each archive segment")
+	leafArchiveKeepLeaves     = flag.Int64("leaf_archive_keep_leaves", 1<<20, "Number of most recent leaves of each log which are never archived")
+	leafArchiveMinAge         = flag.Duration("leaf_archive_min_age", 30*24*time.Hour, "Minimum time since the integration of a leaf before it can be archived")
+	leafArchiveMinRunInterval = flag.Duration("leaf_archive_min_run_interval", time.Hour, "Minimum interval between leaf archival sweeps. Actual runs happen randomly between [minInterval,2*minInterval).")
+
+	tileCacheSize = flag.Int("subtree_tile_cache_size", 0, "Number of full subtree tiles cached across read-only requests, if supported by the storage system. Zero disables the cache.")
 
 	treeGCEnabled            = flag.Bool("tree_gc", true, "If true, tree garbage collection (hard-deletion) is periodically performed")
 	treeDeleteThreshold      = flag.Duration("tree_delete_threshold", serverutil.DefaultTreeDeleteThreshold, "Minimum period a tree has to remain deleted before being hard-deleted")
 	treeDeleteMinRunInterval = flag.Duration("tree_delete_min_run_interval", serverutil.DefaultTreeDeleteMinInterval, "Minimum interval between tree garbage collection sweeps. Actual runs happen randomly between [minInterval,2*minInterval).")
 
+	subtreeGCEnabled        = flag.Bool("subtree_gc", false, "If true, superseded subtree revisions of logs are periodically deleted, if the storage system supports it")
+	subtreeGCRetention      = flag.Duration("subtree_gc_retention", serverutil.DefaultSubtreeGCRetention, "Minimum period for which a tree head remains readable after it has been superseded")
+	subtreeGCTreeRetention  = serverutil.TreeDurations{}
+	subtreeGCMinRunInterval = flag.Duration("subtree_gc_min_run_interval", serverutil.DefaultSubtreeGCMinInterval, "Minimum interval between subtree garbage collection sweeps. Actual runs happen randomly between [minInterval,2*minInterval).")
+
+	treeStatsEnabled        = flag.Bool("tree_stats", false, "If true, storage usage statistics of logs are periodically exported as metrics, if the storage system supports it")
+	treeStatsMinRunInterval = flag.Duration("tree_stats_min_run_interval", serverutil.DefaultTreeStatsMinInterval, "Minimum interval between exports of storage usage statistics of logs. Actual runs happen randomly between [minInterval,2*minInterval).")
+
 	tracing          = flag.Bool("tracing", false, "If true opencensus Stackdriver tracing will be enabled. See https://opencensus.io/.")
 	tracingProjectID = flag.String("tracing_project_id", "", "project ID to pass to stackdriver. Can be empty for GCP, consult docs for other platforms.")
 	tracingPercent   = flag.Int("tracing_percent", 0, "Percent of requests to be traced. Zero is a special case to use the DefaultSampler")
@@ -84,6 +116,10 @@ var (
 	memProfile = flag.String("memprofile", "", "If set, write memory profile to this file")
 )
 
+func init() {
+	flag.Var(subtreeGCTreeRetention, "subtree_gc_tree_retention", "Comma-separated list of treeID=duration pairs overriding --subtree_gc_retention for specific trees")
+}
+
 func main() {
 	klog.InitFlags(nil)
 	flag.Parse()
@@ -113,11 +149,47 @@ func main() {
 		options = append(options, opts...)
 	}
 
-	sp, err := storage.NewProvider(*storageSystem, mf)
+	if *tileCacheSize > 0 {
+		cache.SetDefaultTileCache(cache.NewTileCache(*tileCacheSize, mf))
+	}
+
+	sp, err := storage.NewProviderWithContext(ctx, *storageSystem, mf)
 	if err != nil {
 		klog.Exitf("Failed to get storage provider: %v", err)
 	}
-	defer sp.Close()
+	// The archival job works on the unwrapped storage, which it moves leaves out of.
+	archiveAdmin, archiveLog := sp.AdminStorage(), sp.LogStorage()
+	if *storageMetrics {
+		sp = instrumented.NewProvider(sp, mf)
+	}
+	if *leafArchiveStore != "" {
+		as, err := blob.OpenStore(*leafArchiveStore)
+		if err != nil {
+			klog.Exitf("Failed to open leaf archive store: %v", err)
+		}
+		sp = leafarchive.NewProvider(sp, as, *leafArchiveCacheSize)
+		if *leafArchiveJob {
+			a, err := leafarchive.NewArchiver(archiveAdmin, archiveLog, as, leafarchive.ArchiverOptions{
+				SegmentSize:    *leafArchiveSegmentSize,
+				KeepLeaves:     *leafArchiveKeepLeaves,
+				MinAge:         *leafArchiveMinAge,
+				MinRunInterval: *leafArchiveMinRunInterval,
+			}, mf)
+			if err != nil {
+				klog.Exitf("Failed to create leaf archiver: %v", err)
+			}
+			go a.Run(ctx)
+		}
+	} else if *leafArchiveJob {
+		klog.Exit("--leaf_archive_job requires --leaf_archive_store")
+	}
+	if *leafBlobStore != "" {
+		bs, err := blob.OpenStore(*leafBlobStore)
+		if err != nil {
+			klog.Exitf("Failed to open leaf blob store: %v", err)
+		}
+		sp = blob.NewProvider(sp, bs, *leafBlobThreshold)
+	}
 
 	var client *clientv3.Client
 	if servers := *etcd.Servers; servers != "" {
@@ -188,6 +260,14 @@ func main() {
 		TreeGCEnabled:         *treeGCEnabled,
 		TreeDeleteThreshold:   *treeDeleteThreshold,
 		TreeDeleteMinInterval: *treeDeleteMinRunInterval,
+		SubtreeGCEnabled:      *subtreeGCEnabled,
+		SubtreeGCOptions: admin.SubtreeGCOptions{
+			Retention:      *subtreeGCRetention,
+			TreeRetention:  subtreeGCTreeRetention,
+			MinRunInterval: *subtreeGCMinRunInterval,
+		},
+		TreeStatsEnabled:     *treeStatsEnabled,
+		TreeStatsMinInterval: *treeStatsMinRunInterval,
 	}
 
 	if err := m.Run(ctx); err != nil {
@@ -24,8 +24,10 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"flag"
 	"fmt"
+	"io"
 	_ "net/http/pprof" // Register pprof HTTP handlers.
 	"os"
 	"runtime/pprof"
@@ -42,23 +44,31 @@ import (
 	"github.com/google/trillian/quota"
 	"github.com/google/trillian/quota/etcd"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/instrumented"
 	"github.com/google/trillian/util"
 	"github.com/google/trillian/util/clock"
 	"github.com/google/trillian/util/election"
 	"github.com/google/trillian/util/election2"
 	etcdelect "github.com/google/trillian/util/election2/etcd"
+	"github.com/google/trillian/util/election2/flock"
+	sqlelect "github.com/google/trillian/util/election2/sql"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"google.golang.org/grpc"
 	"k8s.io/klog/v2"
 
 	// Register supported storage providers.
+	_ "github.com/google/trillian/storage/bolt"
 	_ "github.com/google/trillian/storage/cloudspanner"
-	_ "github.com/google/trillian/storage/crdb"
-	_ "github.com/google/trillian/storage/mysql"
+	"github.com/google/trillian/storage/crdb"
+	_ "github.com/google/trillian/storage/faulty"
+	"github.com/google/trillian/storage/mysql"
+	"github.com/google/trillian/storage/postgresql"
+	_ "github.com/google/trillian/storage/sqlite"
 
 	// Load quota providers
 	_ "github.com/google/trillian/quota/crdbqm"
 	_ "github.com/google/trillian/quota/mysqlqm"
+	_ "github.com/google/trillian/quota/postgresqlqm"
 )
 
 var (
@@ -72,19 +82,24 @@ var (
 	sequencerGuardWindowFlag = flag.Duration("sequencer_guard_window", 0, "If set, the time elapsed before submitted leaves are eligible for sequencing")
 	forceMaster              = flag.Bool("force_master", false, "If true, assume master for all logs")
 	etcdHTTPService          = flag.String("etcd_http_service", "trillian-logsigner-http", "Service name to announce our HTTP endpoint under")
-	lockDir                  = flag.String("lock_file_path", "/test/multimaster", "etcd lock file directory path")
+	lockDir                  = flag.String("lock_file_path", "/test/multimaster", "Lock file directory path: an etcd key prefix for --election_system=etcd, or a local directory for --election_system=flock")
+	electionSystem           = flag.String("election_system", "etcd", "Master election system to use unless --force_master is set. One of: etcd, mysql, crdb, postgresql, flock")
+	electionLeaseTTL         = flag.Duration("election_lease_ttl", 30*time.Second, "Mastership lease TTL. Only effective for --election_system=mysql, crdb or postgresql")
 	healthzTimeout           = flag.Duration("healthz_timeout", time.Second*5, "Timeout used during healthz checks")
+	drainTimeout             = flag.Duration("drain_timeout", 10*time.Second, "Time allowed on shutdown for completing in-flight sequencing passes and handing log mastership over to other signers (0 means no graceful handover)")
 
 	quotaSystem         = flag.String("quota_system", "mysql", fmt.Sprintf("Quota system to use. One of: %v", quota.Providers()))
 	quotaIncreaseFactor = flag.Float64("quota_increase_factor", log.QuotaIncreaseFactor,
 		"Increase factor for tokens replenished by sequencing-based quotas (1 means a 1:1 relationship between sequenced leaves and replenished tokens)."+
 			"Only effective for --quota_system=etcd.")
 
-	storageSystem = flag.String("storage_system", "mysql", fmt.Sprintf("Storage system to use. One of: %v", storage.Providers()))
+	storageSystem  = flag.String("storage_system", "mysql", fmt.Sprintf("Storage system to use. One of: %v", storage.Providers()))
+	storageMetrics = flag.Bool("storage_metrics", false, "If true, latency and error metrics, and tracing spans, are recorded for all the storage methods")
 
 	preElectionPause   = flag.Duration("pre_election_pause", 1*time.Second, "Maximum time to wait before starting elections")
 	masterHoldInterval = flag.Duration("master_hold_interval", 60*time.Second, "Minimum interval to hold mastership for")
 	masterHoldJitter   = flag.Duration("master_hold_jitter", 120*time.Second, "Maximal random addition to --master_hold_interval")
+	balanceMastership  = flag.Bool("balance_mastership", false, "If true, resign mastership for logs held in excess of a fair share among signers, after --master_hold_interval. Only effective for --election_system=etcd")
 
 	configFile = flag.String("config", "", "Config file containing flags, file contents can be overridden by command line flags")
 
@@ -110,11 +125,17 @@ func main() {
 	mf := prometheus.MetricFactory{}
 	monitoring.SetStartSpan(opencensus.StartSpan)
 
-	sp, err := storage.NewProvider(*storageSystem, mf)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go util.AwaitSignal(ctx, cancel)
+
+	sp, err := storage.NewProviderWithContext(ctx, *storageSystem, mf)
 	if err != nil {
 		klog.Exitf("Failed to get storage provider: %v", err)
 	}
-	defer sp.Close()
+	if *storageMetrics {
+		sp = instrumented.NewProvider(sp, mf)
+	}
 
 	var client *clientv3.Client
 	if servers := *etcd.Servers; servers != "" {
@@ -127,19 +148,33 @@ func main() {
 		defer client.Close()
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	go util.AwaitSignal(ctx, cancel)
-
 	hostname, _ := os.Hostname()
 	instanceID := fmt.Sprintf("%s.%d", hostname, os.Getpid())
 	var electionFactory election2.Factory
+	// The SQL elections can fence the writes of deposed masters if they keep
+	// their leases in the storage's database.
+	fenceWrites := false
 	switch {
 	case *forceMaster:
 		klog.Warning("**** Acting as master for all logs ****")
 		electionFactory = election2.NoopFactory{}
+	case *electionSystem == "mysql":
+		electionFactory = mustCreateSQLFactory(instanceID, mysql.GetDatabase, sqlelect.MySQL)
+		fenceWrites = *storageSystem == "mysql"
+	case *electionSystem == "crdb":
+		electionFactory = mustCreateSQLFactory(instanceID, crdb.GetDatabase, sqlelect.CockroachDB)
+		fenceWrites = *storageSystem == crdb.StorageProviderName
+	case *electionSystem == "postgresql":
+		electionFactory = mustCreateSQLFactory(instanceID, postgresql.GetDatabase, sqlelect.CockroachDB)
+		fenceWrites = *storageSystem == postgresql.StorageProviderName
+	case *electionSystem == "flock":
+		if electionFactory, err = flock.NewFactory(instanceID, *lockDir, 0); err != nil {
+			klog.Exitf("Failed to create flock election factory: %v", err)
+		}
+	case *electionSystem != "etcd":
+		klog.Exitf("Unknown election system %q", *electionSystem)
 	case client != nil:
-		electionFactory = etcdelect.NewFactory(instanceID, client, *lockDir)
+		electionFactory = etcdelect.NewFactoryWithMetrics(instanceID, client, *lockDir, mf)
 	default:
 		klog.Exit("Either --force_master or --etcd_servers must be supplied")
 	}
@@ -181,9 +216,22 @@ func main() {
 			MasterHoldJitter:   *masterHoldJitter,
 			TimeSource:         clock.System,
 		},
+		BalanceMastership: *balanceMastership,
+		FenceWrites:       fenceWrites,
+		DrainTimeout:      *drainTimeout,
 	}
 	sequencerTask := log.NewOperationManager(info, sequencerManager)
-	go sequencerTask.OperationLoop(ctx)
+	sequencerDone := make(chan struct{})
+	go func() {
+		defer close(sequencerDone)
+		sequencerTask.OperationLoop(ctx)
+		// Release the resources shared by elections, such as etcd sessions.
+		if c, ok := electionFactory.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				klog.Warningf("Failed to close election factory: %v", err)
+			}
+		}
+	}()
 
 	// Enable CPU profile if requested
 	if *cpuProfile != "" {
@@ -192,13 +240,21 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	// Let the sequencer finish in-flight work and hand over mastership before
+	// closing the storage.
+	closeStorage := func() error {
+		cancel()
+		<-sequencerDone
+		return sp.Close()
+	}
+
 	m := serverutil.Main{
 		RPCEndpoint:      *rpcEndpoint,
 		HTTPEndpoint:     *httpEndpoint,
 		TLSCertFile:      *tlsCertFile,
 		TLSKeyFile:       *tlsKeyFile,
 		StatsPrefix:      "logsigner",
-		DBClose:          sp.Close,
+		DBClose:          closeStorage,
 		Registry:         registry,
 		RegisterServerFn: func(s *grpc.Server, _ extension.Registry) error { return nil },
 		IsHealthy:        sp.AdminStorage().CheckDatabaseAccessible,
@@ -219,6 +275,20 @@ func main() {
 	time.Sleep(time.Second * 5)
 }
 
+// mustCreateSQLFactory returns an election factory storing mastership leases in
+// the database returned by getDB.
+func mustCreateSQLFactory(instanceID string, getDB func() (*sql.DB, error), dialect sqlelect.Dialect) election2.Factory {
+	db, err := getDB()
+	if err != nil {
+		klog.Exitf("Failed to open %s election database: %v", *electionSystem, err)
+	}
+	f, err := sqlelect.NewFactory(instanceID, db, dialect, *electionLeaseTTL)
+	if err != nil {
+		klog.Exitf("Failed to create %s election factory: %v", *electionSystem, err)
+	}
+	return f
+}
+
 func mustCreate(fileName string) *os.File {
 	f, err := os.Create(fileName)
 	if err != nil {
@@ -17,6 +17,7 @@ package integration
 import (
 	"context"
 	"flag"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -27,6 +28,8 @@ import (
 	"github.com/google/trillian/client"
 	"github.com/google/trillian/extension"
 	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage/bolt"
+	"github.com/google/trillian/storage/faulty"
 	"github.com/google/trillian/storage/memory"
 	"github.com/google/trillian/storage/testdb"
 	"github.com/google/trillian/testonly/integration"
@@ -147,3 +150,52 @@ func TestInProcessLogIntegrationDuplicateLeaves(t *testing.T) {
 		t.Fatalf("Test failed: %v", err)
 	}
 }
+
+func TestInProcessLogIntegrationWithStorageFaults(t *testing.T) {
+	ctx := context.Background()
+	const numSequencers = 2
+	db, err := bolt.OpenDB(filepath.Join(t.TempDir(), "trillian.bolt"))
+	if err != nil {
+		t.Fatalf("Failed to open DB: %v", err)
+	}
+	defer db.Close()
+
+	// Only the sequencer's writes fail, so that the client RPCs succeed and
+	// the leaves are integrated once the sequencer has recovered.
+	inj := faulty.NewInjector(1,
+		faulty.Rule{Method: "SetMerkleNodes", Calls: []int{1, 3}, Fault: faulty.Fault{Kind: faulty.Error}},
+		faulty.Rule{Method: "StoreSignedLogRoot", Calls: []int{3}, Fault: faulty.Fault{Kind: faulty.Abort}},
+		faulty.Rule{Method: "UpdateSequencedLeaves", Probability: 0.2, Fault: faulty.Fault{Kind: faulty.Error}},
+	)
+	reggie := extension.Registry{
+		AdminStorage: bolt.NewAdminStorage(db),
+		LogStorage:   faulty.NewLogStorage(bolt.NewLogStorage(db, nil), inj),
+		QuotaManager: quota.Noop(),
+	}
+
+	env, err := integration.NewLogEnvWithRegistry(ctx, numSequencers, reggie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+
+	tree, err := client.CreateAndInitTree(ctx, &trillian.CreateTreeRequest{
+		Tree: stestonly.LogTree,
+	}, env.Admin, env.Log)
+	if err != nil {
+		t.Fatalf("Failed to create log: %v", err)
+	}
+
+	params := DefaultTestParameters(tree.TreeId)
+	params.LeafCount = 200
+	params.UniqueLeaves = 200
+	params.SequencingPollWait = 500 * time.Millisecond
+	if err := RunLogIntegration(env.Log, params); err != nil {
+		t.Fatalf("Test failed: %v", err)
+	}
+	for _, method := range []string{"SetMerkleNodes", "StoreSignedLogRoot"} {
+		if got := inj.Injected(method); got == 0 {
+			t.Errorf("Injected(%s) = 0, want faults", method)
+		}
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/util/clock"
 	"github.com/google/trillian/util/election"
+	"github.com/google/trillian/util/election2"
 	"golang.org/x/sync/semaphore"
 	"k8s.io/klog/v2"
 )
@@ -37,6 +38,9 @@ import (
 var (
 	// DefaultTimeout is the default timeout on a single log operation run.
 	DefaultTimeout = 60 * time.Second
+	// BalanceInterval is the minimum interval between checks of whether this
+	// instance holds more than its fair share of logs.
+	BalanceInterval = 10 * time.Second
 
 	once              sync.Once
 	knownLogs         monitoring.Gauge
@@ -95,6 +99,17 @@ type OperationInfo struct {
 
 	// Election-related configuration. Copied for each log.
 	ElectionConfig election.RunnerConfig
+	// BalanceMastership makes the instance voluntarily resign mastership for
+	// the logs it holds in excess of ceil(logs/instances), so that the logs get
+	// evenly distributed between the instances. It requires the election
+	// factory to implement election2.LoadCensus.
+	BalanceMastership bool
+	// FenceWrites makes each operation run write to the storage only while the
+	// mastership term it started in is current, so that a deposed master can't
+	// overwrite the work of its successor. It requires the elections to
+	// implement election2.Epocher, and to keep their leases in the
+	// MasterElection table of the storage's database.
+	FenceWrites bool
 
 	// RunInterval is the time between starting batches of processing.  If a
 	// batch takes longer than this interval to complete, the next batch
@@ -105,6 +120,12 @@ type OperationInfo struct {
 	// Timeout sets an optional timeout on each operation run.
 	// If unset, default to the value of DefaultTimeout.
 	Timeout time.Duration
+	// DrainTimeout is the time allowed for shutting down gracefully once the
+	// context passed in to OperationLoop is canceled. Within this deadline,
+	// the in-flight operation runs are completed, and then the mastership of
+	// all logs is resigned so that other instances can take over immediately.
+	// If unset, the operations are canceled along with the context.
+	DrainTimeout time.Duration
 }
 
 // OperationManager controls scheduling activities for logs.
@@ -123,6 +144,15 @@ type OperationManager struct {
 
 	tracker *election.MasterTracker
 
+	// runners contains the currently running election Runner for each logID.
+	runners map[string]*election.Runner
+	// runnersMutex guards the runners field.
+	runnersMutex sync.Mutex
+	// lastLoad is the number of held logs last published for balancing, or -1.
+	lastLoad int
+	// nextBalance is the earliest time to check the mastership balance again.
+	nextBalance time.Time
+
 	// Cache of logID => name. Names are assumed not to change during runtime.
 	logNames map[int64]string
 	// A recent list of active logs that this instance is master for.
@@ -139,6 +169,9 @@ func NewOperationManager(info OperationInfo, logOperation Operation) *OperationM
 	if info.Timeout == 0 {
 		info.Timeout = DefaultTimeout
 	}
+	if info.DrainTimeout > 0 && info.ElectionConfig.HandoverTimeout == 0 {
+		info.ElectionConfig.HandoverTimeout = info.DrainTimeout
+	}
 	tracker := election.NewMasterTracker(nil, func(id string, v bool) {
 		val := 0.0
 		if v {
@@ -152,6 +185,8 @@ func NewOperationManager(info OperationInfo, logOperation Operation) *OperationM
 		runnerCancels:       make(map[string]context.CancelFunc),
 		pendingResignations: make(chan election.Resignation, 100),
 		tracker:             tracker,
+		runners:             make(map[string]*election.Runner),
+		lastLoad:            -1,
 		logNames:            make(map[int64]string),
 	}
 }
@@ -235,13 +270,15 @@ func (o *OperationManager) masterFor(ctx context.Context, allIDs []int64) ([]int
 
 // runElectionWithRestarts runs the election/resignation loop for the given log
 // indefinitely, until the returned CancelFunc is invoked. Any failure during
-// the loop leads to a restart of the loop with a few seconds delay.
+// the loop leads to a restart of the loop with a few seconds delay. The passed
+// in work context also bounds the mastership handover when the loop stops, so
+// that it completes within the drain deadline.
 //
 // TODO(pavelkalinnikov): Restart the whole log operation rather than just the
 // election, and have a metric for restarts.
-func (o *OperationManager) runElectionWithRestarts(ctx context.Context, logID string) context.CancelFunc {
+func (o *OperationManager) runElectionWithRestarts(wctx context.Context, logID string) context.CancelFunc {
 	klog.Infof("create master election goroutine for %v", logID)
-	cctx, cancel := context.WithCancel(ctx)
+	cctx, cancel := context.WithCancel(wctx)
 	run := func(ctx context.Context) {
 		e, err := o.info.Registry.ElectionFactory.NewElection(ctx, logID)
 		if err != nil {
@@ -253,6 +290,9 @@ func (o *OperationManager) runElectionWithRestarts(ctx context.Context, logID st
 		config := o.info.ElectionConfig
 		// TODO(pavelkalinnikov): Passing the cancel function is not needed here.
 		r := election.NewRunner(logID, &config, o.tracker, cancel, e)
+		r.HandoverContext = wctx
+		o.setRunner(logID, r)
+		defer o.setRunner(logID, nil)
 		r.Run(ctx, o.pendingResignations)
 	}
 	o.runnerWG.Add(1)
@@ -272,6 +312,66 @@ func (o *OperationManager) runElectionWithRestarts(ctx context.Context, logID st
 	return cancel
 }
 
+// setRunner records the election Runner currently running for the given log.
+func (o *OperationManager) setRunner(logID string, r *election.Runner) {
+	o.runnersMutex.Lock()
+	defer o.runnersMutex.Unlock()
+	if r == nil {
+		delete(o.runners, logID)
+	} else {
+		o.runners[logID] = r
+	}
+}
+
+// balance publishes the number of logs this instance is master for, and sheds
+// mastership for the logs held in excess of a fair share among all the live
+// instances. The shed logs are resigned no earlier than the configured master
+// hold interval after their mastership was captured.
+func (o *OperationManager) balance(ctx context.Context, heldIDs, activeIDs []int64) {
+	if !o.info.BalanceMastership {
+		return
+	}
+	census, ok := o.info.Registry.ElectionFactory.(election2.LoadCensus)
+	if !ok {
+		return
+	}
+	if held := len(heldIDs); held != o.lastLoad {
+		if err := census.PublishLoad(ctx, held); err != nil {
+			klog.Warningf("failed to publish mastership load: %v", err)
+		} else {
+			o.lastLoad = held
+		}
+	}
+
+	now := o.info.TimeSource.Now()
+	if now.Before(o.nextBalance) {
+		return
+	}
+	o.nextBalance = now.Add(BalanceInterval)
+	loads, err := census.Loads(ctx)
+	if err != nil {
+		klog.Warningf("failed to get mastership loads: %v", err)
+		return
+	}
+	instances := len(loads)
+	if instances == 0 {
+		instances = 1 // Our own load is not visible yet.
+	}
+	share := (len(activeIDs) + instances - 1) / instances
+	excess := len(heldIDs) - share
+	if excess <= 0 {
+		return
+	}
+	klog.Infof("Master for %d logs, above the fair share of %d for %d instances; shedding %d", len(heldIDs), share, instances, excess)
+	o.runnersMutex.Lock()
+	defer o.runnersMutex.Unlock()
+	for _, logID := range heldIDs[len(heldIDs)-excess:] {
+		if r := o.runners[strconv.FormatInt(logID, 10)]; r != nil {
+			r.Shed()
+		}
+	}
+}
+
 // updateHeldIDs updates the process status with the number/list of logs that
 // the instance holds mastership for.
 func (o *OperationManager) updateHeldIDs(ctx context.Context, logIDs, activeIDs []int64) {
@@ -307,8 +407,9 @@ func (o *OperationManager) getLogsAndExecutePass(ctx context.Context) error {
 		return fmt.Errorf("failed to determine log IDs we're master for: %v", err)
 	}
 	o.updateHeldIDs(ctx, logIDs, activeIDs)
+	o.balance(ctx, logIDs, activeIDs)
 
-	executePassForAll(runCtx, &o.info, o.logOperation, logIDs)
+	o.executePassForAll(runCtx, logIDs)
 	return nil
 }
 
@@ -323,19 +424,26 @@ func (o *OperationManager) OperationSingle(ctx context.Context) {
 }
 
 // OperationLoop starts the manager working. It continues until told to exit.
+// If a DrainTimeout is configured, the in-flight operations are completed and
+// mastership is handed over to other instances before returning.
 // TODO(Martin2112): No mechanism for error reporting etc., this is OK for v1 but needs work
 func (o *OperationManager) OperationLoop(ctx context.Context) {
 	klog.Infof("Log operation manager starting")
+	// The operations and elections run in a context which outlives ctx while
+	// draining, so that they are not interrupted halfway through.
+	wctx, cancel := o.drainContext(ctx)
+	defer cancel()
 
 	// Outer loop, runs until terminated.
 	for {
-		if err := o.operateOnce(ctx); err != nil {
+		if err := o.operateOnce(ctx, wctx); err != nil {
 			klog.Infof("Log operation manager shutting down")
 			break
 		}
 	}
 
-	// Terminate all the election Runners.
+	// Terminate all the election Runners. They hand over mastership while
+	// wctx is still active.
 	for logID, cancel := range o.runnerCancels {
 		if cancel != nil {
 			klog.V(1).Infof("cancel election runner for %s", logID)
@@ -347,7 +455,7 @@ func (o *OperationManager) OperationLoop(ctx context.Context) {
 	close(o.pendingResignations)
 	for r := range o.pendingResignations {
 		resignations.Inc(r.ID)
-		r.Execute(ctx)
+		r.Execute(wctx)
 	}
 
 	klog.Infof("wait for termination of election runners...")
@@ -355,13 +463,36 @@ func (o *OperationManager) OperationLoop(ctx context.Context) {
 	klog.Infof("wait for termination of election runners...done")
 }
 
+// drainContext returns a context for running operations and elections, which
+// is canceled DrainTimeout after ctx is done, or when the returned CancelFunc
+// is called. If DrainTimeout is unset, returns a child context of ctx.
+func (o *OperationManager) drainContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.info.DrainTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	wctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-wctx.Done():
+			return
+		case <-ctx.Done():
+		}
+		klog.Infof("Log operation manager draining for up to %v", o.info.DrainTimeout)
+		if err := clock.SleepSource(wctx, o.info.DrainTimeout, o.info.TimeSource); err == nil {
+			klog.Warning("Log operation manager drain deadline exceeded")
+			cancel()
+		}
+	}()
+	return wctx, cancel
+}
+
 // operateOnce runs a single round of operation for each of the active logs
-// that this instance is master for. Returns an error only if the context is
-// canceled, i.e. the operation is being shut down.
-func (o *OperationManager) operateOnce(ctx context.Context) error {
+// that this instance is master for, within the work context wctx. Returns an
+// error only if ctx is canceled, i.e. the operation is being shut down.
+func (o *OperationManager) operateOnce(ctx, wctx context.Context) error {
 	// TODO(alcutter): want a child context with deadline here?
 	start := o.info.TimeSource.Now()
-	if err := o.getLogsAndExecutePass(ctx); err != nil {
+	if err := o.getLogsAndExecutePass(wctx); err != nil {
 		// Suppress the error if ctx is done (ctx.Err != nil) as we're exiting.
 		if ctx.Err() != nil {
 			klog.Errorf("failed to execute operation on logs: %v", err)
@@ -375,7 +506,7 @@ func (o *OperationManager) operateOnce(ctx context.Context) error {
 		select {
 		case r := <-o.pendingResignations:
 			resignations.Inc(r.ID)
-			r.Execute(ctx)
+			r.Execute(wctx)
 		default:
 			doneResigning = true
 		}
@@ -402,9 +533,10 @@ func (o *OperationManager) operateOnce(ctx context.Context) error {
 	return nil
 }
 
-// executePassForAll runs ExecutePass of the given operation for each of the
+// executePassForAll runs ExecutePass of the log operation for each of the
 // passed-in logs, allowing up to a configurable number of parallel operations.
-func executePassForAll(ctx context.Context, info *OperationInfo, op Operation, logIDs []int64) {
+func (o *OperationManager) executePassForAll(ctx context.Context, logIDs []int64) {
+	info, op := &o.info, o.logOperation
 	startBatch := info.TimeSource.Now()
 
 	numWorkers := info.NumWorkers
@@ -424,7 +556,7 @@ func executePassForAll(ctx context.Context, info *OperationInfo, op Operation, l
 		go func(logID int64) {
 			defer wg.Done()
 			defer sem.Release(1)
-			if err := executePass(ctx, info, op, logID); err != nil {
+			if err := executePass(o.withMasterFence(ctx, logID), info, op, logID); err != nil {
 				klog.Errorf("ExecutePass(%v) failed: %v", logID, err)
 			}
 		}(logID)
@@ -436,6 +568,24 @@ func executePassForAll(ctx context.Context, info *OperationInfo, op Operation, l
 	klog.V(1).Infof("Group run completed in %.2f seconds", d)
 }
 
+// withMasterFence returns ctx with the fence of the current mastership term of
+// the given log if FenceWrites is set, so that the storage rejects the writes
+// of the operation run once the term has ended.
+func (o *OperationManager) withMasterFence(ctx context.Context, logID int64) context.Context {
+	if !o.info.FenceWrites {
+		return ctx
+	}
+	id := strconv.FormatInt(logID, 10)
+	o.runnersMutex.Lock()
+	r := o.runners[id]
+	o.runnersMutex.Unlock()
+	var epoch int64
+	if r != nil {
+		epoch = r.Epoch()
+	}
+	return storage.WithMasterFence(ctx, storage.MasterFence{ResourceID: id, Epoch: epoch})
+}
+
 // executePass runs ExecutePass of the given operation for the passed-in log.
 func executePass(ctx context.Context, info *OperationInfo, op Operation, logID int64) error {
 	label := strconv.FormatInt(logID, 10)
@@ -19,7 +19,9 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -286,6 +288,94 @@ func TestOperationManagerOperationLoopExitOnContext(t *testing.T) {
 	t.Logf("Exited operationLoop")
 }
 
+func TestOperationManagerOperationLoopDrainsOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fakeStorage, mockAdmin := setupLogIDs(ctrl, map[int64]string{451: "LogID1"})
+	factory := &handoverFactory{}
+	registry := extension.Registry{
+		LogStorage:      fakeStorage,
+		AdminStorage:    mockAdmin,
+		ElectionFactory: factory,
+	}
+	info := defaultOperationInfo(registry)
+	info.RunInterval = 10 * time.Millisecond
+	info.TimeSource = clock.System
+	info.ElectionConfig.TimeSource = clock.System
+	info.DrainTimeout = 5 * time.Second
+
+	var passErr error
+	mockLogOp := NewMockOperation(ctrl)
+	mockLogOp.EXPECT().ExecutePass(gomock.Any(), int64(451), gomock.Any()).DoAndReturn(func(ctx context.Context, _ int64, _ *OperationInfo) (int, error) {
+		cancel() // Shut down while the pass is in flight.
+		time.Sleep(100 * time.Millisecond)
+		passErr = ctx.Err()
+		return 1, nil
+	})
+
+	lom := NewOperationManager(info, mockLogOp)
+	lom.OperationLoop(ctx)
+
+	if passErr != nil {
+		t.Errorf("in-flight pass interrupted: %v", passErr)
+	}
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+	if !factory.resigned {
+		t.Error("mastership not resigned on shutdown")
+	} else if factory.resignErr != nil {
+		t.Errorf("mastership resigned with done context: %v", factory.resignErr)
+	}
+}
+
+func TestOperationManagerOperationLoopHandoverWithinDrainTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fakeStorage, mockAdmin := setupLogIDs(ctrl, map[int64]string{451: "LogID1"})
+	// Resignations block until their context is done.
+	factory := &handoverFactory{block: true}
+	registry := extension.Registry{
+		LogStorage:      fakeStorage,
+		AdminStorage:    mockAdmin,
+		ElectionFactory: factory,
+	}
+	info := defaultOperationInfo(registry)
+	info.RunInterval = 10 * time.Millisecond
+	info.TimeSource = clock.System
+	info.ElectionConfig.TimeSource = clock.System
+	info.ElectionConfig.HandoverTimeout = time.Minute
+	info.DrainTimeout = 200 * time.Millisecond
+
+	mockLogOp := NewMockOperation(ctrl)
+	mockLogOp.EXPECT().ExecutePass(gomock.Any(), int64(451), gomock.Any()).DoAndReturn(func(context.Context, int64, *OperationInfo) (int, error) {
+		cancel()
+		return 1, nil
+	})
+
+	lom := NewOperationManager(info, mockLogOp)
+	start := time.Now()
+	lom.OperationLoop(ctx)
+
+	if got, limit := time.Since(start), 10*time.Second; got > limit {
+		t.Errorf("OperationLoop() took %v to shut down, want less than %v", got, limit)
+	}
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+	if !factory.resigned {
+		t.Error("mastership not resigned on shutdown")
+	} else if factory.resignErr == nil {
+		t.Error("handover not interrupted by the drain deadline")
+	}
+}
+
 func TestOperationManagerOperationLoopExecutePassError(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -380,6 +470,41 @@ func TestHeldInfo(t *testing.T) {
 	}
 }
 
+func TestOperationManagerFencesWrites(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logID := int64(451)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fakeStorage, mockAdmin := setupLogIDs(ctrl, map[int64]string{logID: "LogID"})
+	registry := extension.Registry{
+		LogStorage:      fakeStorage,
+		AdminStorage:    mockAdmin,
+		ElectionFactory: epochFactory{epoch: 7},
+	}
+
+	mockLogOp := NewMockOperation(ctrl)
+	mockLogOp.EXPECT().ExecutePass(gomock.Any(), logID, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, logID int64, info *OperationInfo) (int, error) {
+			want := storage.MasterFence{ResourceID: "451", Epoch: 7}
+			if got, ok := storage.MasterFenceFromContext(ctx); !ok || got != want {
+				t.Errorf("MasterFenceFromContext()=%+v,%v; want %+v,true", got, ok, want)
+			}
+			return 0, nil
+		})
+
+	info := defaultOperationInfo(registry)
+	info.TimeSource = clock.System
+	info.FenceWrites = true
+	lom := NewOperationManager(info, mockLogOp)
+
+	// Give the election Runner a chance to capture mastership.
+	lom.masterFor(ctx, []int64{logID})
+	time.Sleep(100 * time.Millisecond)
+	lom.OperationSingle(ctx)
+}
+
 func TestMasterFor(t *testing.T) {
 	ctx := context.Background()
 	firstIDs := []int64{1, 2, 3, 4}
@@ -425,6 +550,58 @@ func TestMasterFor(t *testing.T) {
 	}
 }
 
+func TestBalance(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	ts := clock.NewFake(start)
+	factory := &censusFactory{loads: map[string]int{"peer": 0}}
+	info := OperationInfo{
+		Registry:   extension.Registry{ElectionFactory: factory},
+		TimeSource: ts,
+		ElectionConfig: election.RunnerConfig{
+			MasterHoldInterval: election.MinMasterHoldInterval,
+			MasterHoldJitter:   time.Hour,
+			TimeSource:         ts,
+		},
+		BalanceMastership: true,
+	}
+	lom := NewOperationManager(info, nil)
+
+	allIDs := []int64{1, 2, 3, 4}
+	lom.masterFor(ctx, allIDs)
+	time.Sleep(100 * time.Millisecond) // Let the election Runners start.
+	ts.Set(start.Add(election.MinPreElectionPause))
+	time.Sleep(100 * time.Millisecond) // Let the Runners capture mastership.
+	held, err := lom.masterFor(ctx, allIDs)
+	if err != nil || !reflect.DeepEqual(held, allIDs) {
+		t.Fatalf("masterFor()=%v,%v; want %v,nil", held, err, allIDs)
+	}
+
+	lom.balance(ctx, held, allIDs)
+	if got, want := factory.loads["self"], len(allIDs); got != want {
+		t.Errorf("published load %d, want %d", got, want)
+	}
+	// Nothing is resigned before the master hold interval.
+	time.Sleep(100 * time.Millisecond)
+	if got := len(lom.pendingResignations); got != 0 {
+		t.Errorf("got %d resignations before master hold interval, want 0", got)
+	}
+
+	ts.Set(start.Add(election.MinPreElectionPause + election.MinMasterHoldInterval))
+	time.Sleep(100 * time.Millisecond)
+	var resigned []string
+	for len(lom.pendingResignations) > 0 {
+		r := <-lom.pendingResignations
+		resigned = append(resigned, r.ID)
+		r.Execute(ctx)
+	}
+	sort.Strings(resigned)
+	if want := []string{"3", "4"}; !reflect.DeepEqual(resigned, want) {
+		t.Errorf("resigned %v, want %v", resigned, want)
+	}
+}
+
 type alwaysMasterFactory struct{}
 
 func (m alwaysMasterFactory) NewElection(ctx context.Context, treeID string) (election2.Election, error) {
@@ -445,8 +622,73 @@ func (m masterForEvenFactory) NewElection(ctx context.Context, treeID string) (e
 	return d, nil
 }
 
+// epochFactory creates elections which are always the master at the given
+// epoch.
+type epochFactory struct {
+	epoch int64
+}
+
+func (f epochFactory) NewElection(ctx context.Context, treeID string) (election2.Election, error) {
+	return epochElection{Decorator: eto.NewDecorator(eto.NewElection()), epoch: f.epoch}, nil
+}
+
+type epochElection struct {
+	*eto.Decorator
+	epoch int64
+}
+
+func (e epochElection) Epoch() int64 {
+	return e.epoch
+}
+
 type failureFactory struct{}
 
 func (ff failureFactory) NewElection(ctx context.Context, treeID string) (election2.Election, error) {
 	return nil, errors.New("injected failure")
 }
+
+// censusFactory is an alwaysMasterFactory which also implements LoadCensus.
+type censusFactory struct {
+	alwaysMasterFactory
+	loads map[string]int
+}
+
+func (c *censusFactory) PublishLoad(ctx context.Context, held int) error {
+	c.loads["self"] = held
+	return nil
+}
+
+func (c *censusFactory) Loads(ctx context.Context) (map[string]int, error) {
+	return c.loads, nil
+}
+
+// handoverFactory creates elections which record how the mastership was
+// resigned. If block is set, resignations wait for their context to be done.
+type handoverFactory struct {
+	block bool
+
+	mu        sync.Mutex
+	resigned  bool
+	resignErr error
+}
+
+func (h *handoverFactory) NewElection(ctx context.Context, treeID string) (election2.Election, error) {
+	return &handoverElection{Election: eto.NewElection(), h: h}, nil
+}
+
+type handoverElection struct {
+	*eto.Election
+	h *handoverFactory
+}
+
+func (e *handoverElection) Resign(ctx context.Context) error {
+	if e.h.block {
+		<-ctx.Done()
+	}
+	e.h.mu.Lock()
+	defer e.h.mu.Unlock()
+	if !e.h.resigned {
+		e.h.resigned, e.h.resignErr = true, ctx.Err()
+	}
+	return e.Election.Resign(ctx)
+}
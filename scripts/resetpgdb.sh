@@ -0,0 +1,86 @@
+#!/bin/bash
+
+set -e
+
+usage() {
+  cat <<EOF
+$(basename $0) [--force] [--verbose] ...
+All unrecognised arguments will be passed through to the 'psql' command.
+Accepts environment variables:
+- PG_ROOT_USER: A user with sufficient rights to create/reset the Trillian
+  database (default: postgres). Its password, if any, is taken from
+  PGPASSWORD or ~/.pgpass as usual for psql.
+- PG_HOST: The hostname of the PostgreSQL server (default: localhost).
+- PG_PORT: The port the PostgreSQL server is listening on (default: 5432).
+- PG_DATABASE: The name to give to the new Trillian database (default: test).
+- PG_USER: The name to give to the new Trillian user (default: test).
+- PG_PASSWORD: The password to use for the new Trillian user
+  (default: zaphod).
+EOF
+}
+
+die() {
+  echo "$*" > /dev/stderr
+  exit 1
+}
+
+collect_vars() {
+  # set unset environment variables to defaults
+  [ -z ${PG_ROOT_USER+x} ] && PG_ROOT_USER="postgres"
+  [ -z ${PG_HOST+x} ] && PG_HOST="localhost"
+  [ -z ${PG_PORT+x} ] && PG_PORT="5432"
+  [ -z ${PG_DATABASE+x} ] && PG_DATABASE="test"
+  [ -z ${PG_USER+x} ] && PG_USER="test"
+  [ -z ${PG_PASSWORD+x} ] && PG_PASSWORD="zaphod"
+  FLAGS=()
+
+  # handle flags
+  FORCE=false
+  VERBOSE=false
+  while [[ $# -gt 0 ]]; do
+    case "$1" in
+      --force) FORCE=true ;;
+      --verbose) VERBOSE=true ;;
+      --help) usage; exit ;;
+      *) FLAGS+=("$1")
+    esac
+    shift 1
+  done
+
+  FLAGS+=(-U "${PG_ROOT_USER}")
+  FLAGS+=(--host "${PG_HOST}")
+  FLAGS+=(--port "${PG_PORT}")
+  FLAGS+=(-v ON_ERROR_STOP=1)
+
+  # Optionally print flags
+  [[ ${VERBOSE} = 'true' ]] && echo "- Using psql Flags: ${FLAGS[@]}"
+}
+
+main() {
+  collect_vars "$@"
+
+  readonly TRILLIAN_PATH=$(go list -f '{{.Dir}}' github.com/google/trillian)
+
+  echo "Warning: about to destroy and reset database '${PG_DATABASE}'"
+
+  [[ ${FORCE} = true ]] || read -p "Are you sure? [Y/N]: " -n 1 -r
+  echo # Print newline following the above prompt
+
+  if [ -z ${REPLY+x} ] || [[ $REPLY =~ ^[Yy]$ ]]
+  then
+      echo "Resetting DB..."
+      set -eux
+      psql "${FLAGS[@]}" -d postgres -c "DROP DATABASE IF EXISTS ${PG_DATABASE};" || \
+        die "Error: Failed to drop database '${PG_DATABASE}'."
+      psql "${FLAGS[@]}" -d postgres -tAc "SELECT 1 FROM pg_roles WHERE rolname = '${PG_USER}'" | grep -q 1 || \
+        psql "${FLAGS[@]}" -d postgres -c "CREATE USER ${PG_USER} WITH PASSWORD '${PG_PASSWORD}';" || \
+        die "Error: Failed to create user '${PG_USER}'."
+      psql "${FLAGS[@]}" -d postgres -c "CREATE DATABASE ${PG_DATABASE} OWNER ${PG_USER};" || \
+        die "Error: Failed to create database '${PG_DATABASE}'."
+      psql "${FLAGS[@]}" -d ${PG_DATABASE} -c "SET ROLE ${PG_USER};" -f ${TRILLIAN_PATH}/storage/postgresql/schema/storage.sql || \
+        die "Error: Failed to create tables in '${PG_DATABASE}' database."
+      echo "Reset Complete"
+  fi
+}
+
+main "$@"
@@ -16,7 +16,9 @@ package admin
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"strconv"
 
 	"github.com/google/trillian"
 	"github.com/google/trillian/extension"
@@ -38,6 +40,7 @@ type Server struct {
 // allowedTreeTypes defines which tree types may be created through this server,
 // with nil meaning unrestricted.
 func New(registry extension.Registry, allowedTreeTypes []trillian.TreeType) *Server {
+	createTreeStatsMetrics(registry.MetricFactory)
 	return &Server{
 		registry:         registry,
 		allowedTreeTypes: allowedTreeTypes,
@@ -53,11 +56,81 @@ func (s *Server) IsHealthy() error {
 // ListTrees implements trillian.TrillianAdminServer.ListTrees.
 func (s *Server) ListTrees(ctx context.Context, req *trillian.ListTreesRequest) (*trillian.ListTreesResponse, error) {
 	// TODO(codingllama): This needs access control
-	resp, err := storage.ListTrees(ctx, s.registry.AdminStorage, req.GetShowDeleted())
+	opts, err := listTreesOptions(req)
 	if err != nil {
 		return nil, err
 	}
-	return &trillian.ListTreesResponse{Tree: resp}, nil
+	pageSize := opts.Limit
+	if pageSize > 0 {
+		// Read one more tree to find out whether there's a next page.
+		opts.Limit++
+	}
+	trees, err := storage.ListTreesFiltered(ctx, s.registry.AdminStorage, opts)
+	if err != nil {
+		return nil, err
+	}
+	resp := &trillian.ListTreesResponse{Tree: trees}
+	if pageSize > 0 && len(trees) > pageSize {
+		resp.Tree = trees[:pageSize]
+		resp.NextPageToken = encodePageToken(trees[pageSize-1].TreeId)
+	}
+	return resp, nil
+}
+
+// listTreesOptions returns the storage options selecting the trees requested
+// by req.
+func listTreesOptions(req *trillian.ListTreesRequest) (storage.ListTreesOptions, error) {
+	if req.GetPageSize() < 0 {
+		return storage.ListTreesOptions{}, status.Errorf(codes.InvalidArgument, "negative page_size: %d", req.GetPageSize())
+	}
+	for _, tt := range req.GetTreeType() {
+		if tt == trillian.TreeType_UNKNOWN_TREE_TYPE {
+			return storage.ListTreesOptions{}, status.Errorf(codes.InvalidArgument, "invalid tree_type: %v", tt)
+		}
+	}
+	for _, ts := range req.GetTreeState() {
+		if ts == trillian.TreeState_UNKNOWN_TREE_STATE {
+			return storage.ListTreesOptions{}, status.Errorf(codes.InvalidArgument, "invalid tree_state: %v", ts)
+		}
+	}
+	opts := storage.ListTreesOptions{
+		IncludeDeleted: req.GetShowDeleted(),
+		Labels:         req.GetLabels(),
+		TreeTypes:      req.GetTreeType(),
+		TreeStates:     req.GetTreeState(),
+		Limit:          int(req.GetPageSize()),
+	}
+	if token := req.GetPageToken(); token != "" {
+		afterTreeID, err := decodePageToken(token)
+		if err != nil {
+			return storage.ListTreesOptions{}, status.Errorf(codes.InvalidArgument, "invalid page_token: %v", err)
+		}
+		opts.AfterTreeID = afterTreeID
+	}
+	return opts, nil
+}
+
+// encodePageToken returns the page token of the trees, or audit events,
+// following the one with the given ID.
+func encodePageToken(lastID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(lastID, 10)))
+}
+
+// decodePageToken returns the ID of the last tree, or audit event, of the
+// previous page.
+func decodePageToken(token string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, fmt.Errorf("ID %d is not positive", id)
+	}
+	return id, nil
 }
 
 // GetTree implements trillian.TrillianAdminServer.GetTree.
@@ -69,6 +142,26 @@ func (s *Server) GetTree(ctx context.Context, req *trillian.GetTreeRequest) (*tr
 	return tree, nil
 }
 
+// GetTreeStats implements trillian.TrillianAdminServer.GetTreeStats.
+func (s *Server) GetTreeStats(ctx context.Context, req *trillian.GetTreeStatsRequest) (*trillian.GetTreeStatsResponse, error) {
+	tree, err := storage.GetTree(ctx, s.registry.AdminStorage, req.GetTreeId())
+	if err != nil {
+		return nil, err
+	}
+	switch tree.TreeType {
+	case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "tree %d is not a log", tree.TreeId)
+	}
+	stats, err := storage.GetTreeStats(ctx, s.registry.LogStorage, tree)
+	if err != nil {
+		return nil, err
+	}
+	now := timeNow()
+	recordTreeStats(tree.TreeId, stats, now)
+	return treeStatsResponse(tree.TreeId, stats, now), nil
+}
+
 // CreateTree implements trillian.TrillianAdminServer.CreateTree.
 func (s *Server) CreateTree(ctx context.Context, req *trillian.CreateTreeRequest) (*trillian.Tree, error) {
 	tree := req.GetTree()
@@ -89,7 +182,13 @@ func (s *Server) CreateTree(ctx context.Context, req *trillian.CreateTreeRequest
 	tree.Deleted = false
 	tree.DeleteTime = nil
 
-	createdTree, err := storage.CreateTree(ctx, s.registry.AdminStorage, tree)
+	event, err := newAuditEvent(ctx, trillian.AdminAuditEvent_CREATE_TREE, 0, req)
+	if err != nil {
+		return nil, err
+	}
+	createdTree, err := auditedTransaction(ctx, s.registry.AdminStorage, event, func(ctx context.Context, tx storage.AdminTX) (*trillian.Tree, error) {
+		return tx.CreateTree(ctx, tree)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -120,11 +219,17 @@ func (s *Server) UpdateTree(ctx context.Context, req *trillian.UpdateTreeRequest
 		return nil, err
 	}
 
-	updatedTree, err := storage.UpdateTree(ctx, s.registry.AdminStorage, tree.TreeId, func(other *trillian.Tree) {
-		if err := applyUpdateMask(tree, other, mask); err != nil {
-			// Should never happen (famous last words).
-			klog.Errorf("Error applying mask on tree update: %v", err)
-		}
+	event, err := newAuditEvent(ctx, trillian.AdminAuditEvent_UPDATE_TREE, tree.TreeId, req)
+	if err != nil {
+		return nil, err
+	}
+	updatedTree, err := auditedTransaction(ctx, s.registry.AdminStorage, event, func(ctx context.Context, tx storage.AdminTX) (*trillian.Tree, error) {
+		return tx.UpdateTree(ctx, tree.TreeId, func(other *trillian.Tree) {
+			if err := applyUpdateMask(tree, other, mask); err != nil {
+				// Should never happen (famous last words).
+				klog.Errorf("Error applying mask on tree update: %v", err)
+			}
+		})
 	})
 	if err != nil {
 		return nil, err
@@ -150,6 +255,8 @@ func applyUpdateMask(from, to *trillian.Tree, mask *field_mask.FieldMask) error
 			to.StorageSettings = from.StorageSettings
 		case "max_root_duration":
 			to.MaxRootDuration = from.MaxRootDuration
+		case "labels":
+			to.Labels = from.Labels
 		default:
 			return status.Errorf(codes.InvalidArgument, "invalid update_mask path: %q", path)
 		}
@@ -159,7 +266,13 @@ func applyUpdateMask(from, to *trillian.Tree, mask *field_mask.FieldMask) error
 
 // DeleteTree implements trillian.TrillianAdminServer.DeleteTree.
 func (s *Server) DeleteTree(ctx context.Context, req *trillian.DeleteTreeRequest) (*trillian.Tree, error) {
-	tree, err := storage.SoftDeleteTree(ctx, s.registry.AdminStorage, req.GetTreeId())
+	event, err := newAuditEvent(ctx, trillian.AdminAuditEvent_DELETE_TREE, req.GetTreeId(), req)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := auditedTransaction(ctx, s.registry.AdminStorage, event, func(ctx context.Context, tx storage.AdminTX) (*trillian.Tree, error) {
+		return tx.SoftDeleteTree(ctx, req.GetTreeId())
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -168,9 +281,48 @@ func (s *Server) DeleteTree(ctx context.Context, req *trillian.DeleteTreeRequest
 
 // UndeleteTree implements trillian.TrillianAdminServer.UndeleteTree.
 func (s *Server) UndeleteTree(ctx context.Context, req *trillian.UndeleteTreeRequest) (*trillian.Tree, error) {
-	tree, err := storage.UndeleteTree(ctx, s.registry.AdminStorage, req.GetTreeId())
+	event, err := newAuditEvent(ctx, trillian.AdminAuditEvent_UNDELETE_TREE, req.GetTreeId(), req)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := auditedTransaction(ctx, s.registry.AdminStorage, event, func(ctx context.Context, tx storage.AdminTX) (*trillian.Tree, error) {
+		return tx.UndeleteTree(ctx, req.GetTreeId())
+	})
 	if err != nil {
 		return nil, err
 	}
 	return tree, nil
 }
+
+// ListAdminAuditEvents implements trillian.TrillianAdminServer.ListAdminAuditEvents.
+func (s *Server) ListAdminAuditEvents(ctx context.Context, req *trillian.ListAdminAuditEventsRequest) (*trillian.ListAdminAuditEventsResponse, error) {
+	if req.GetPageSize() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "negative page_size: %d", req.GetPageSize())
+	}
+	opts := storage.ListAuditEventsOptions{
+		TreeID: req.GetTreeId(),
+		Limit:  int(req.GetPageSize()),
+	}
+	if token := req.GetPageToken(); token != "" {
+		afterEventID, err := decodePageToken(token)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page_token: %v", err)
+		}
+		opts.AfterEventID = afterEventID
+	}
+	pageSize := opts.Limit
+	if pageSize > 0 {
+		// Read one more event to find out whether there's a next page.
+		opts.Limit++
+	}
+	events, err := storage.ListAuditEvents(ctx, s.registry.AdminStorage, opts)
+	if err != nil {
+		return nil, err
+	}
+	resp := &trillian.ListAdminAuditEventsResponse{Event: events}
+	if pageSize > 0 && len(events) > pageSize {
+		resp.Event = events[:pageSize]
+		resp.NextPageToken = encodePageToken(events[pageSize-1].EventId)
+	}
+	return resp, nil
+}
@@ -16,6 +16,7 @@ package admin
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"strings"
 	"testing"
@@ -166,6 +167,114 @@ func TestServer_ListTrees(t *testing.T) {
 	}
 }
 
+func TestServer_ListTreesPaging(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var trees []*trillian.Tree
+	for id := int64(1); id <= 5; id++ {
+		tree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+		tree.TreeId = id
+		if id%2 == 0 {
+			tree.TreeState = trillian.TreeState_FROZEN
+		}
+		trees = append(trees, tree)
+	}
+
+	tests := []struct {
+		desc          string
+		req           *trillian.ListTreesRequest
+		wantIDs       []int64
+		wantNextToken string
+	}{
+		{
+			desc:    "noPageSize",
+			req:     &trillian.ListTreesRequest{},
+			wantIDs: []int64{1, 2, 3, 4, 5},
+		},
+		{
+			desc:          "firstPage",
+			req:           &trillian.ListTreesRequest{PageSize: 2},
+			wantIDs:       []int64{1, 2},
+			wantNextToken: encodePageToken(2),
+		},
+		{
+			desc:          "middlePage",
+			req:           &trillian.ListTreesRequest{PageSize: 2, PageToken: encodePageToken(2)},
+			wantIDs:       []int64{3, 4},
+			wantNextToken: encodePageToken(4),
+		},
+		{
+			desc:    "lastPage",
+			req:     &trillian.ListTreesRequest{PageSize: 2, PageToken: encodePageToken(4)},
+			wantIDs: []int64{5},
+		},
+		{
+			desc:    "exactLastPage",
+			req:     &trillian.ListTreesRequest{PageSize: 3, PageToken: encodePageToken(2)},
+			wantIDs: []int64{3, 4, 5},
+		},
+		{
+			desc:          "filteredByState",
+			req:           &trillian.ListTreesRequest{PageSize: 1, TreeState: []trillian.TreeState{trillian.TreeState_FROZEN}},
+			wantIDs:       []int64{2},
+			wantNextToken: encodePageToken(2),
+		},
+	}
+
+	ctx := context.Background()
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			setup := setupAdminServer(
+				ctrl,
+				true, /* snapshot */
+				true, /* shouldCommit */
+				false /* commitErr */)
+			setup.snapshotTX.EXPECT().ListTrees(gomock.Any(), false).Return(trees, nil)
+
+			resp, err := setup.server.ListTrees(ctx, test.req)
+			if err != nil {
+				t.Fatalf("ListTrees() returned err = %v", err)
+			}
+			var gotIDs []int64
+			for _, tree := range resp.Tree {
+				gotIDs = append(gotIDs, tree.TreeId)
+			}
+			if diff := cmp.Diff(test.wantIDs, gotIDs); diff != "" {
+				t.Errorf("ListTrees() diff in tree IDs (-want +got):\n%v", diff)
+			}
+			if got, want := resp.NextPageToken, test.wantNextToken; got != want {
+				t.Errorf("ListTrees() next_page_token = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestServer_ListTreesInvalidRequest(t *testing.T) {
+	tests := []struct {
+		desc string
+		req  *trillian.ListTreesRequest
+	}{
+		{desc: "negativePageSize", req: &trillian.ListTreesRequest{PageSize: -1}},
+		{desc: "malformedPageToken", req: &trillian.ListTreesRequest{PageToken: "!!"}},
+		{desc: "nonNumericPageToken", req: &trillian.ListTreesRequest{PageToken: base64.RawURLEncoding.EncodeToString([]byte("llama"))}},
+		{desc: "nonPositivePageToken", req: &trillian.ListTreesRequest{PageToken: encodePageToken(0)}},
+		{desc: "unknownTreeType", req: &trillian.ListTreesRequest{TreeType: []trillian.TreeType{trillian.TreeType_UNKNOWN_TREE_TYPE}}},
+		{desc: "unknownTreeState", req: &trillian.ListTreesRequest{TreeState: []trillian.TreeState{trillian.TreeState_UNKNOWN_TREE_STATE}}},
+	}
+
+	ctx := context.Background()
+	s := &Server{registry: extension.Registry{AdminStorage: &testonly.FakeAdminStorage{}}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := s.ListTrees(ctx, test.req)
+			if got, want := status.Code(err), codes.InvalidArgument; got != want {
+				t.Errorf("ListTrees() returned err = %v, want code %v", err, want)
+			}
+		})
+	}
+}
+
 func TestServer_ListTreesErrors(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -412,9 +521,10 @@ func TestServer_UpdateTree(t *testing.T) {
 		Description:     "Brand New Tree Desc",
 		StorageSettings: settings,
 		MaxRootDuration: durationpb.New(2 * time.Nanosecond),
+		Labels:          map[string]string{"tenant": "llamas"},
 	}
 	successMask := &field_mask.FieldMask{
-		Paths: []string{"tree_state", "display_name", "description", "storage_settings", "max_root_duration"},
+		Paths: []string{"tree_state", "display_name", "description", "storage_settings", "max_root_duration", "labels"},
 	}
 
 	successWant := proto.Clone(existingTree).(*trillian.Tree)
@@ -423,6 +533,7 @@ func TestServer_UpdateTree(t *testing.T) {
 	successWant.Description = successTree.Description
 	successWant.StorageSettings = successTree.StorageSettings
 	successWant.MaxRootDuration = successTree.MaxRootDuration
+	successWant.Labels = successTree.Labels
 
 	tests := []struct {
 		desc                           string
@@ -0,0 +1,106 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// gcCaller is the caller recorded in the audit events of the trees
+// hard-deleted by DeletedTreeGC.
+const gcCaller = "DeletedTreeGC"
+
+// newAuditEvent returns the audit event of the operation requested by req to
+// the tree with the given ID, which is zero for created trees.
+func newAuditEvent(ctx context.Context, op trillian.AdminAuditEvent_Operation, treeID int64, req proto.Message) (*trillian.AdminAuditEvent, error) {
+	anyReq, err := anypb.New(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to marshal request: %v", err)
+	}
+	return &trillian.AdminAuditEvent{
+		TreeId:    treeID,
+		Operation: op,
+		Caller:    callerIdentity(ctx),
+		Request:   anyReq,
+	}, nil
+}
+
+// callerIdentity returns the identity of the caller of the RPC served with
+// ctx: the subject of its verified TLS client certificate if it presented one,
+// and its network address otherwise.
+func callerIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		if chains := info.State.VerifiedChains; len(chains) > 0 && len(chains[0]) > 0 {
+			return chains[0][0].Subject.String()
+		}
+	}
+	if p.Addr == nil {
+		return ""
+	}
+	return p.Addr.String()
+}
+
+// auditedTransaction runs fn, which changes the tree of event, in a read-write
+// transaction of admin, and returns the tree it returns. If the storage keeps
+// an audit log, the event is recorded in the same transaction, with the tree
+// before and after the change and the current time.
+func auditedTransaction(ctx context.Context, admin storage.AdminStorage, event *trillian.AdminAuditEvent, fn func(context.Context, storage.AdminTX) (*trillian.Tree, error)) (*trillian.Tree, error) {
+	var tree *trillian.Tree
+	err := admin.ReadWriteTransaction(ctx, func(ctx context.Context, tx storage.AdminTX) error {
+		// The transaction may be retried, so don't modify the event passed in.
+		event := proto.Clone(event).(*trillian.AdminAuditEvent)
+		_, audited := tx.(storage.AuditLogWriter)
+		if audited && event.TreeId != 0 {
+			before, err := tx.GetTree(ctx, event.TreeId)
+			if err != nil {
+				return err
+			}
+			// Some storage systems return trees which fn modifies in place.
+			event.TreeBefore = proto.Clone(before).(*trillian.Tree)
+		}
+
+		var err error
+		if tree, err = fn(ctx, tx); err != nil {
+			return err
+		}
+		if !audited {
+			return nil
+		}
+		if tree != nil {
+			event.TreeId = tree.TreeId
+			event.TreeAfter = proto.Clone(tree).(*trillian.Tree)
+		}
+		event.EventTime = timestamppb.New(timeNow())
+		return storage.AddAuditEvent(ctx, tx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
@@ -0,0 +1,276 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/trillian"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/memory"
+	"github.com/google/trillian/storage/testonly"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// auditingTX is an AdminTX which keeps the audit events added to it.
+type auditingTX struct {
+	storage.AdminTX
+	events []*trillian.AdminAuditEvent
+}
+
+func (t *auditingTX) AddAuditEvent(ctx context.Context, event *trillian.AdminAuditEvent) error {
+	t.events = append(t.events, event)
+	return nil
+}
+
+func mustMarshalAny(t *testing.T, m proto.Message) *anypb.Any {
+	t.Helper()
+	a, err := anypb.New(m)
+	if err != nil {
+		t.Fatalf("anypb.New(): %v", err)
+	}
+	return a
+}
+
+func TestServer_AuditLog(t *testing.T) {
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000}})
+	s := New(extension.Registry{AdminStorage: memory.NewAdminStorage(memory.NewTreeStorage())}, nil /* allowedTreeTypes */)
+
+	createReq := &trillian.CreateTreeRequest{Tree: proto.Clone(testonly.LogTree).(*trillian.Tree)}
+	created, err := s.CreateTree(ctx, createReq)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	createdCopy := proto.Clone(created).(*trillian.Tree)
+	updateReq := &trillian.UpdateTreeRequest{
+		Tree:       &trillian.Tree{TreeId: created.TreeId, DisplayName: "Alpacas Log"},
+		UpdateMask: &field_mask.FieldMask{Paths: []string{"display_name"}},
+	}
+	updated, err := s.UpdateTree(ctx, updateReq)
+	if err != nil {
+		t.Fatalf("UpdateTree(): %v", err)
+	}
+
+	want := []*trillian.AdminAuditEvent{
+		{
+			EventId:   1,
+			TreeId:    created.TreeId,
+			Operation: trillian.AdminAuditEvent_CREATE_TREE,
+			Caller:    "127.0.0.1:5000",
+			Request:   mustMarshalAny(t, createReq),
+			TreeAfter: createdCopy,
+			EventTime: timestamppb.New(now),
+		},
+		{
+			EventId:    2,
+			TreeId:     created.TreeId,
+			Operation:  trillian.AdminAuditEvent_UPDATE_TREE,
+			Caller:     "127.0.0.1:5000",
+			Request:    mustMarshalAny(t, updateReq),
+			TreeBefore: createdCopy,
+			TreeAfter:  updated,
+			EventTime:  timestamppb.New(now),
+		},
+	}
+
+	resp, err := s.ListAdminAuditEvents(ctx, &trillian.ListAdminAuditEventsRequest{TreeId: created.TreeId})
+	if err != nil {
+		t.Fatalf("ListAdminAuditEvents(): %v", err)
+	}
+	if diff := cmp.Diff(&trillian.ListAdminAuditEventsResponse{Event: want}, resp, protocmp.Transform()); diff != "" {
+		t.Errorf("ListAdminAuditEvents() diff (-want +got):\n%s", diff)
+	}
+
+	// Read the events one page at a time.
+	var got []*trillian.AdminAuditEvent
+	req := &trillian.ListAdminAuditEventsRequest{PageSize: 1}
+	for i := 0; i <= len(want); i++ {
+		resp, err := s.ListAdminAuditEvents(ctx, req)
+		if err != nil {
+			t.Fatalf("ListAdminAuditEvents(%v): %v", req, err)
+		}
+		got = append(got, resp.Event...)
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("ListAdminAuditEvents() pages diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestServer_ListAdminAuditEventsErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	for _, test := range []struct {
+		desc     string
+		req      *trillian.ListAdminAuditEventsRequest
+		wantCode codes.Code
+	}{
+		{desc: "negativePageSize", req: &trillian.ListAdminAuditEventsRequest{PageSize: -1}, wantCode: codes.InvalidArgument},
+		{desc: "malformedPageToken", req: &trillian.ListAdminAuditEventsRequest{PageToken: "!!"}, wantCode: codes.InvalidArgument},
+		{desc: "unsupportedStorage", req: &trillian.ListAdminAuditEventsRequest{}, wantCode: codes.Unimplemented},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			setup := setupAdminServer(ctrl, true /* snapshot */, false /* shouldCommit */, false /* commitErr */)
+			_, err := setup.server.ListAdminAuditEvents(ctx, test.req)
+			if got := status.Code(err); got != test.wantCode {
+				t.Errorf("ListAdminAuditEvents() returned err = %v, want code %v", err, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestServer_DeleteTreeAudited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	before := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	before.TreeId = 12345
+	after := proto.Clone(before).(*trillian.Tree)
+	after.Deleted = true
+	after.DeleteTime = timestamppb.New(now)
+
+	mockTX := storage.NewMockAdminTX(ctrl)
+	mockTX.EXPECT().GetTree(gomock.Any(), before.TreeId).Return(before, nil)
+	mockTX.EXPECT().SoftDeleteTree(gomock.Any(), before.TreeId).Return(after, nil)
+	mockTX.EXPECT().Commit().Return(nil)
+	mockTX.EXPECT().Close().Return(nil)
+	tx := &auditingTX{AdminTX: mockTX}
+	s := &Server{registry: extension.Registry{AdminStorage: &testonly.FakeAdminStorage{TX: []storage.AdminTX{tx}}}}
+
+	req := &trillian.DeleteTreeRequest{TreeId: before.TreeId}
+	if _, err := s.DeleteTree(context.Background(), req); err != nil {
+		t.Fatalf("DeleteTree(): %v", err)
+	}
+	want := []*trillian.AdminAuditEvent{{
+		TreeId:     before.TreeId,
+		Operation:  trillian.AdminAuditEvent_DELETE_TREE,
+		Request:    mustMarshalAny(t, req),
+		TreeBefore: before,
+		TreeAfter:  after,
+		EventTime:  timestamppb.New(now),
+	}}
+	if diff := cmp.Diff(want, tx.events, protocmp.Transform()); diff != "" {
+		t.Errorf("DeleteTree() audit events diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDeletedTreeGC_AuditsHardDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Date(2017, 9, 22, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	tree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	tree.TreeId = 1
+	tree.Deleted = true
+	tree.DeleteTime = timestamppb.New(now.Add(-2 * time.Hour))
+
+	listTX := storage.NewMockReadOnlyAdminTX(ctrl)
+	listTX.EXPECT().ListTrees(gomock.Any(), true /* includeDeleted */).Return([]*trillian.Tree{tree}, nil)
+	listTX.EXPECT().Close().Return(nil)
+	listTX.EXPECT().Commit().Return(nil)
+	mockTX := storage.NewMockAdminTX(ctrl)
+	mockTX.EXPECT().GetTree(gomock.Any(), tree.TreeId).Return(tree, nil)
+	mockTX.EXPECT().HardDeleteTree(gomock.Any(), tree.TreeId).Return(nil)
+	mockTX.EXPECT().Close().Return(nil)
+	mockTX.EXPECT().Commit().Return(nil)
+	deleteTX := &auditingTX{AdminTX: mockTX}
+	as := &testonly.FakeAdminStorage{
+		TX:         []storage.AdminTX{deleteTX},
+		ReadOnlyTX: []storage.ReadOnlyAdminTX{listTX},
+	}
+
+	gc := NewDeletedTreeGC(as, 1*time.Hour /* threshold */, 1*time.Second /* minRunInterval */, nil /* mf */)
+	if _, err := gc.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce(): %v", err)
+	}
+	want := []*trillian.AdminAuditEvent{{
+		TreeId:     tree.TreeId,
+		Operation:  trillian.AdminAuditEvent_HARD_DELETE_TREE,
+		Caller:     gcCaller,
+		TreeBefore: tree,
+		EventTime:  timestamppb.New(now),
+	}}
+	if diff := cmp.Diff(want, deleteTX.events, protocmp.Transform()); diff != "" {
+		t.Errorf("RunOnce() audit events diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCallerIdentity(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443}
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "llama", Organization: []string{"Llamas Inc"}}}
+	for _, test := range []struct {
+		desc string
+		ctx  context.Context
+		want string
+	}{
+		{desc: "noPeer", ctx: context.Background()},
+		{desc: "address", ctx: peer.NewContext(context.Background(), &peer.Peer{Addr: addr}), want: "10.0.0.1:443"},
+		{
+			desc: "unverifiedCert",
+			ctx: peer.NewContext(context.Background(), &peer.Peer{
+				Addr:     addr,
+				AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+			}),
+			want: "10.0.0.1:443",
+		},
+		{
+			desc: "verifiedCert",
+			ctx: peer.NewContext(context.Background(), &peer.Peer{
+				Addr:     addr,
+				AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+			}),
+			want: "CN=llama,O=Llamas Inc",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := callerIdentity(test.ctx); got != test.want {
+				t.Errorf("callerIdentity() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
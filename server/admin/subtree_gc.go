@@ -0,0 +1,146 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"k8s.io/klog/v2"
+)
+
+var (
+	subtreeRevisionsDeleted monitoring.Counter
+	subtreeGCErrors         monitoring.Counter
+	subtreeGCMetricsOnce    sync.Once
+)
+
+// SubtreeGCOptions configures a SubtreeGC.
+type SubtreeGCOptions struct {
+	// Retention is the minimum time for which a tree head remains readable
+	// after it has been superseded.
+	Retention time.Duration
+
+	// TreeRetention overrides Retention for the trees with the given IDs.
+	TreeRetention map[int64]time.Duration
+
+	// MinRunInterval defines how frequently sweeps for superseded subtree revisions are
+	// performed. Actual runs happen randomly between [minInterval,2*minInterval).
+	MinRunInterval time.Duration
+}
+
+// SubtreeGC garbage collects superseded subtree revisions of logs.
+//
+// Some storage implementations store a new revision of each subtree changed by a tree head,
+// so the subtrees along the right edge of a log get a new revision for every tree head.
+// SubtreeGC deletes the revisions which are no longer needed to read the latest tree head of
+// a log, nor the tree heads more recent than its retention period.
+type SubtreeGC struct {
+	admin  storage.AdminStorage
+	pruner storage.SubtreeRevisionPruner
+	opts   SubtreeGCOptions
+}
+
+// NewSubtreeGC returns a new SubtreeGC, or an error if the log storage doesn't support deleting
+// subtree revisions.
+func NewSubtreeGC(admin storage.AdminStorage, ls storage.LogStorage, opts SubtreeGCOptions, mf monitoring.MetricFactory) (*SubtreeGC, error) {
+	pruner, ok := ls.(storage.SubtreeRevisionPruner)
+	if !ok {
+		return nil, fmt.Errorf("log storage %T doesn't support deleting subtree revisions", ls)
+	}
+	subtreeGCMetricsOnce.Do(func() {
+		if mf == nil {
+			mf = monitoring.InertMetricFactory{}
+		}
+		subtreeRevisionsDeleted = mf.NewCounter("subtree_gc_revisions_deleted", "Number of superseded subtree revisions deleted", monitoring.TreeIDLabel)
+		subtreeGCErrors = mf.NewCounter("subtree_gc_errors", "Number of failures to delete superseded subtree revisions", monitoring.TreeIDLabel)
+	})
+	return &SubtreeGC{admin: admin, pruner: pruner, opts: opts}, nil
+}
+
+// Run starts the subtree garbage collection process. It runs until ctx is cancelled.
+func (gc *SubtreeGC) Run(ctx context.Context) {
+	for {
+		count, err := gc.RunOnce(ctx)
+		if err != nil {
+			klog.Errorf("SubtreeGC.Run: %v", err)
+		}
+		if count > 0 {
+			klog.Infof("SubtreeGC.Run: deleted %v subtree revisions", count)
+		}
+
+		d := gc.opts.MinRunInterval + time.Duration(rand.Int63n(gc.opts.MinRunInterval.Nanoseconds()))
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeAfter(d):
+		}
+	}
+}
+
+// RunOnce performs a single subtree garbage collection sweep over all the logs which aren't
+// deleted. Returns the number of subtree revisions deleted.
+//
+// It attempts to sweep all logs, regardless of failures. If it encounters any failures the
+// resulting error is non-nil.
+func (gc *SubtreeGC) RunOnce(ctx context.Context) (int64, error) {
+	now := timeNow()
+
+	trees, err := storage.ListTrees(ctx, gc.admin, false /* includeDeleted */)
+	if err != nil {
+		return 0, fmt.Errorf("error listing trees: %v", err)
+	}
+
+	var total int64
+	var errs []string
+	for _, tree := range trees {
+		switch tree.TreeType {
+		case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+		default:
+			continue
+		}
+		retention, ok := gc.opts.TreeRetention[tree.TreeId]
+		if !ok {
+			retention = gc.opts.Retention
+		}
+
+		label := fmt.Sprint(tree.TreeId)
+		count, err := gc.pruner.PruneSubtreeRevisions(ctx, tree.TreeId, now.Add(-retention))
+		// Some revisions may have been deleted even if it failed.
+		total += count
+		subtreeRevisionsDeleted.Add(float64(count), label)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("error deleting subtree revisions of tree %v: %v", tree.TreeId, err))
+			subtreeGCErrors.Inc(label)
+			continue
+		}
+		if count > 0 {
+			klog.V(1).Infof("SubtreeGC.RunOnce: deleted %v subtree revisions of tree %v", count, tree.TreeId)
+		}
+	}
+
+	if len(errs) == 0 {
+		return total, nil
+	}
+	return total, errors.New("encountered errors deleting subtree revisions:\n\t" + strings.Join(errs, "\n\t"))
+}
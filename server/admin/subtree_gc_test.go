@@ -0,0 +1,106 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakePruner is a LogStorage which records the calls to PruneSubtreeRevisions.
+type fakePruner struct {
+	storage.LogStorage
+	keepSince map[int64]time.Time
+	deleted   map[int64]int64
+	errs      map[int64]error
+}
+
+func (p *fakePruner) PruneSubtreeRevisions(ctx context.Context, treeID int64, keepSince time.Time) (int64, error) {
+	p.keepSince[treeID] = keepSince
+	return p.deleted[treeID], p.errs[treeID]
+}
+
+func TestNewSubtreeGCUnsupported(t *testing.T) {
+	if _, err := NewSubtreeGC(nil, &testonly.FakeLogStorage{}, SubtreeGCOptions{}, nil /* mf */); err == nil {
+		t.Error("NewSubtreeGC() succeeded with storage not supporting it, want error")
+	}
+}
+
+func TestSubtreeGC_RunOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	newTree := func(id int64, treeType trillian.TreeType) *trillian.Tree {
+		tree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+		tree.TreeId = id
+		tree.TreeType = treeType
+		return tree
+	}
+	trees := []*trillian.Tree{
+		newTree(1, trillian.TreeType_LOG),
+		newTree(2, trillian.TreeType_PREORDERED_LOG),
+		newTree(3, trillian.TreeType_LOG),
+		newTree(4, trillian.TreeType_UNKNOWN_TREE_TYPE),
+	}
+
+	listTX := storage.NewMockReadOnlyAdminTX(ctrl)
+	listTX.EXPECT().ListTrees(gomock.Any(), false /* includeDeleted */).Return(trees, nil)
+	listTX.EXPECT().Close().Return(nil)
+	listTX.EXPECT().Commit().Return(nil)
+	as := &testonly.FakeAdminStorage{ReadOnlyTX: []storage.ReadOnlyAdminTX{listTX}}
+
+	ls := &fakePruner{
+		keepSince: make(map[int64]time.Time),
+		deleted:   map[int64]int64{1: 10, 2: 5, 3: 1},
+		errs:      map[int64]error{3: errors.New("failed")},
+	}
+
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	gc, err := NewSubtreeGC(as, ls, SubtreeGCOptions{
+		Retention:     time.Hour,
+		TreeRetention: map[int64]time.Duration{2: 24 * time.Hour},
+	}, nil /* mf */)
+	if err != nil {
+		t.Fatalf("NewSubtreeGC(): %v", err)
+	}
+	count, err := gc.RunOnce(context.Background())
+	if err == nil {
+		t.Error("RunOnce() returned no error, want error for tree 3")
+	}
+	if got, want := count, int64(16); got != want {
+		t.Errorf("RunOnce() = %v, want %v", got, want)
+	}
+
+	want := map[int64]time.Time{
+		1: now.Add(-time.Hour),
+		2: now.Add(-24 * time.Hour),
+		3: now.Add(-time.Hour),
+	}
+	if diff := cmp.Diff(want, ls.keepSince); diff != "" {
+		t.Errorf("PruneSubtreeRevisions() calls diff (-want +got):\n%s", diff)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/trillian"
 	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/storage"
 	"k8s.io/klog/v2"
@@ -138,7 +139,7 @@ func (gc *DeletedTreeGC) RunOnce(ctx context.Context) (int, error) {
 		}
 
 		klog.Infof("DeletedTreeGC.RunOnce: Hard-deleting tree %v after %v", tree.TreeId, durationSinceDelete)
-		if err := storage.HardDeleteTree(ctx, gc.admin, tree.TreeId); err != nil {
+		if err := gc.hardDeleteTree(ctx, tree.TreeId); err != nil {
 			errs = append(errs, fmt.Errorf("error hard-deleting tree %v: %v", tree.TreeId, err))
 			incHardDeleteCounter(tree.TreeId, false, deleteErrReason)
 			continue
@@ -160,3 +161,17 @@ func (gc *DeletedTreeGC) RunOnce(ctx context.Context) (int, error) {
 	}
 	return count, errors.New(buf.String())
 }
+
+// hardDeleteTree hard-deletes the specified tree, recording it in the audit
+// log if the storage keeps one.
+func (gc *DeletedTreeGC) hardDeleteTree(ctx context.Context, treeID int64) error {
+	event := &trillian.AdminAuditEvent{
+		TreeId:    treeID,
+		Operation: trillian.AdminAuditEvent_HARD_DELETE_TREE,
+		Caller:    gcCaller,
+	}
+	_, err := auditedTransaction(ctx, gc.admin, event, func(ctx context.Context, tx storage.AdminTX) (*trillian.Tree, error) {
+		return nil, tx.HardDeleteTree(ctx, treeID)
+	})
+	return err
+}
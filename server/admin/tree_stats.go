@@ -0,0 +1,169 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"k8s.io/klog/v2"
+)
+
+var (
+	treeLeafCount        monitoring.Gauge
+	treeQueueDepth       monitoring.Gauge
+	treeOldestQueuedAge  monitoring.Gauge
+	treeLatestRootAge    monitoring.Gauge
+	treeLeafBytes        monitoring.Gauge
+	treeTileBytes        monitoring.Gauge
+	treeStatsMetricsOnce sync.Once
+)
+
+func createTreeStatsMetrics(mf monitoring.MetricFactory) {
+	treeStatsMetricsOnce.Do(func() {
+		if mf == nil {
+			mf = monitoring.InertMetricFactory{}
+		}
+		treeLeafCount = mf.NewGauge("tree_leaf_count", "Number of sequenced leaves of the tree", monitoring.TreeIDLabel)
+		treeQueueDepth = mf.NewGauge("tree_queue_depth", "Number of leaves of the tree waiting to be sequenced", monitoring.TreeIDLabel)
+		treeOldestQueuedAge = mf.NewGauge("tree_oldest_queued_leaf_age_seconds", "Time since the oldest leaf of the tree waiting to be sequenced was queued", monitoring.TreeIDLabel)
+		treeLatestRootAge = mf.NewGauge("tree_latest_root_age_seconds", "Time since the latest log root of the tree was created", monitoring.TreeIDLabel)
+		treeLeafBytes = mf.NewGauge("tree_leaf_bytes", "Number of bytes of leaf values and extra data of the tree in storage", monitoring.TreeIDLabel)
+		treeTileBytes = mf.NewGauge("tree_tile_bytes", "Number of bytes of Merkle tree tiles of the tree in storage", monitoring.TreeIDLabel)
+	})
+}
+
+// age returns the time from t to now, or zero if t is zero.
+func age(t, now time.Time) time.Duration {
+	if t.IsZero() {
+		return 0
+	}
+	return now.Sub(t)
+}
+
+// recordTreeStats sets the gauges of the given tree to its statistics.
+func recordTreeStats(treeID int64, stats *storage.TreeStats, now time.Time) {
+	label := fmt.Sprint(treeID)
+	treeLeafCount.Set(float64(stats.LeafCount), label)
+	treeQueueDepth.Set(float64(stats.QueueDepth), label)
+	treeOldestQueuedAge.Set(age(stats.OldestQueued, now).Seconds(), label)
+	treeLatestRootAge.Set(age(stats.LatestRoot, now).Seconds(), label)
+	treeLeafBytes.Set(float64(stats.LeafBytes), label)
+	treeTileBytes.Set(float64(stats.TileBytes), label)
+}
+
+// treeStatsResponse returns the GetTreeStats response for the given statistics.
+func treeStatsResponse(treeID int64, stats *storage.TreeStats, now time.Time) *trillian.GetTreeStatsResponse {
+	resp := &trillian.GetTreeStatsResponse{
+		TreeId:     treeID,
+		LeafCount:  stats.LeafCount,
+		QueueDepth: stats.QueueDepth,
+		LeafBytes:  stats.LeafBytes,
+		TileBytes:  stats.TileBytes,
+	}
+	if !stats.OldestQueued.IsZero() {
+		resp.OldestQueuedLeafAge = durationpb.New(age(stats.OldestQueued, now))
+	}
+	if !stats.LatestRoot.IsZero() {
+		resp.LatestRootAge = durationpb.New(age(stats.LatestRoot, now))
+	}
+	return resp
+}
+
+// TreeStatsExporter periodically exports the storage usage statistics of logs
+// as metrics.
+type TreeStatsExporter struct {
+	admin          storage.AdminStorage
+	ls             storage.LogStorage
+	minRunInterval time.Duration
+}
+
+// NewTreeStatsExporter returns a new TreeStatsExporter, which reads the
+// statistics of the logs listed by admin from ls, and exports them to mf.
+// minRunInterval defines how frequently they are exported. Actual runs happen
+// randomly between [minInterval,2*minInterval).
+func NewTreeStatsExporter(admin storage.AdminStorage, ls storage.LogStorage, minRunInterval time.Duration, mf monitoring.MetricFactory) *TreeStatsExporter {
+	createTreeStatsMetrics(mf)
+	return &TreeStatsExporter{admin: admin, ls: ls, minRunInterval: minRunInterval}
+}
+
+// Run starts exporting the statistics of logs. It runs until ctx is cancelled,
+// or until the log storage turns out not to support tree statistics.
+func (e *TreeStatsExporter) Run(ctx context.Context) {
+	for {
+		if _, err := e.RunOnce(ctx); err != nil {
+			if status.Code(err) == codes.Unimplemented {
+				klog.Warningf("TreeStatsExporter.Run: stopping: %v", err)
+				return
+			}
+			klog.Errorf("TreeStatsExporter.Run: %v", err)
+		}
+
+		d := e.minRunInterval + time.Duration(rand.Int63n(e.minRunInterval.Nanoseconds()))
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeAfter(d):
+		}
+	}
+}
+
+// RunOnce exports the statistics of all the logs which aren't deleted. Returns
+// the number of logs whose statistics were exported.
+//
+// It attempts to read the statistics of all logs, regardless of failures. If
+// it encounters any failures the resulting error is non-nil.
+func (e *TreeStatsExporter) RunOnce(ctx context.Context) (int, error) {
+	trees, err := storage.ListTrees(ctx, e.admin, false /* includeDeleted */)
+	if err != nil {
+		return 0, fmt.Errorf("error listing trees: %v", err)
+	}
+
+	count := 0
+	var errs []string
+	for _, tree := range trees {
+		switch tree.TreeType {
+		case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+		default:
+			continue
+		}
+		stats, err := storage.GetTreeStats(ctx, e.ls, tree)
+		if status.Code(err) == codes.Unimplemented {
+			return count, err
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("error reading statistics of tree %v: %v", tree.TreeId, err))
+			continue
+		}
+		recordTreeStats(tree.TreeId, stats, timeNow())
+		count++
+	}
+
+	if len(errs) == 0 {
+		return count, nil
+	}
+	return count, errors.New("encountered errors reading tree statistics:\n\t" + strings.Join(errs, "\n\t"))
+}
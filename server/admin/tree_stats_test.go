@@ -0,0 +1,210 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// fakeStatsReader is a LogStorage which returns fixed tree statistics.
+type fakeStatsReader struct {
+	storage.LogStorage
+	stats map[int64]*storage.TreeStats
+	errs  map[int64]error
+}
+
+func (r *fakeStatsReader) GetTreeStats(ctx context.Context, tree *trillian.Tree) (*storage.TreeStats, error) {
+	return r.stats[tree.TreeId], r.errs[tree.TreeId]
+}
+
+func newStatsTree(id int64, treeType trillian.TreeType) *trillian.Tree {
+	tree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	tree.TreeId = id
+	tree.TreeType = treeType
+	return tree
+}
+
+func gaugeValue(t *testing.T, g monitoring.Gauge, treeID int64) float64 {
+	t.Helper()
+	inert, ok := g.(*monitoring.InertFloat)
+	if !ok {
+		t.Fatalf("gauge is %T, want *monitoring.InertFloat", g)
+	}
+	return inert.Value(fmt.Sprint(treeID))
+}
+
+func TestTreeStatsExporter_RunOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	trees := []*trillian.Tree{
+		newStatsTree(101, trillian.TreeType_LOG),
+		newStatsTree(102, trillian.TreeType_PREORDERED_LOG),
+		newStatsTree(103, trillian.TreeType_LOG),
+		newStatsTree(104, trillian.TreeType_UNKNOWN_TREE_TYPE),
+	}
+	listTX := storage.NewMockReadOnlyAdminTX(ctrl)
+	listTX.EXPECT().ListTrees(gomock.Any(), false /* includeDeleted */).Return(trees, nil)
+	listTX.EXPECT().Close().Return(nil)
+	listTX.EXPECT().Commit().Return(nil)
+	as := &testonly.FakeAdminStorage{ReadOnlyTX: []storage.ReadOnlyAdminTX{listTX}}
+
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	ls := &fakeStatsReader{
+		stats: map[int64]*storage.TreeStats{
+			101: {LeafCount: 10, QueueDepth: 2, OldestQueued: now.Add(-time.Minute), LatestRoot: now.Add(-time.Second), LeafBytes: 100, TileBytes: 200},
+			102: {LeafCount: 5},
+		},
+		errs: map[int64]error{103: errors.New("failed")},
+	}
+
+	e := NewTreeStatsExporter(as, ls, time.Minute, nil /* mf */)
+	count, err := e.RunOnce(context.Background())
+	if err == nil {
+		t.Error("RunOnce() returned no error, want error for tree 103")
+	}
+	if got, want := count, 2; got != want {
+		t.Errorf("RunOnce() = %v, want %v", got, want)
+	}
+
+	for _, test := range []struct {
+		name   string
+		gauge  monitoring.Gauge
+		treeID int64
+		want   float64
+	}{
+		{name: "leaf count", gauge: treeLeafCount, treeID: 101, want: 10},
+		{name: "queue depth", gauge: treeQueueDepth, treeID: 101, want: 2},
+		{name: "oldest queued age", gauge: treeOldestQueuedAge, treeID: 101, want: 60},
+		{name: "latest root age", gauge: treeLatestRootAge, treeID: 101, want: 1},
+		{name: "leaf bytes", gauge: treeLeafBytes, treeID: 101, want: 100},
+		{name: "tile bytes", gauge: treeTileBytes, treeID: 101, want: 200},
+		{name: "preordered leaf count", gauge: treeLeafCount, treeID: 102, want: 5},
+		{name: "no latest root", gauge: treeLatestRootAge, treeID: 102, want: 0},
+	} {
+		if got := gaugeValue(t, test.gauge, test.treeID); got != test.want {
+			t.Errorf("%s of tree %d = %v, want %v", test.name, test.treeID, got, test.want)
+		}
+	}
+}
+
+func TestTreeStatsExporter_RunOnceUnimplemented(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	listTX := storage.NewMockReadOnlyAdminTX(ctrl)
+	listTX.EXPECT().ListTrees(gomock.Any(), false /* includeDeleted */).Return([]*trillian.Tree{newStatsTree(1, trillian.TreeType_LOG)}, nil)
+	listTX.EXPECT().Close().Return(nil)
+	listTX.EXPECT().Commit().Return(nil)
+	as := &testonly.FakeAdminStorage{ReadOnlyTX: []storage.ReadOnlyAdminTX{listTX}}
+
+	e := NewTreeStatsExporter(as, &testonly.FakeLogStorage{}, time.Minute, nil /* mf */)
+	if _, err := e.RunOnce(context.Background()); status.Code(err) != codes.Unimplemented {
+		t.Errorf("RunOnce() = %v, want %v", err, codes.Unimplemented)
+	}
+}
+
+func TestServer_GetTreeStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	createTreeStatsMetrics(nil)
+
+	ctx := context.Background()
+	for _, test := range []struct {
+		desc     string
+		treeType trillian.TreeType
+		stats    *storage.TreeStats
+		want     *trillian.GetTreeStatsResponse
+		wantCode codes.Code
+	}{
+		{
+			desc:     "log",
+			treeType: trillian.TreeType_LOG,
+			stats:    &storage.TreeStats{LeafCount: 3, QueueDepth: 1, OldestQueued: now.Add(-time.Minute), LatestRoot: now.Add(-time.Second), LeafBytes: 30, TileBytes: 64},
+			want: &trillian.GetTreeStatsResponse{
+				TreeId:              12345,
+				LeafCount:           3,
+				QueueDepth:          1,
+				OldestQueuedLeafAge: durationpb.New(time.Minute),
+				LatestRootAge:       durationpb.New(time.Second),
+				LeafBytes:           30,
+				TileBytes:           64,
+			},
+		},
+		{
+			desc:     "emptyLog",
+			treeType: trillian.TreeType_PREORDERED_LOG,
+			stats:    &storage.TreeStats{},
+			want:     &trillian.GetTreeStatsResponse{TreeId: 12345},
+		},
+		{
+			desc:     "notLog",
+			treeType: trillian.TreeType_UNKNOWN_TREE_TYPE,
+			wantCode: codes.InvalidArgument,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			setup := setupAdminServer(ctrl, true /* snapshot */, true /* shouldCommit */, false /* commitErr */)
+			tree := newStatsTree(12345, test.treeType)
+			setup.snapshotTX.EXPECT().GetTree(gomock.Any(), tree.TreeId).Return(tree, nil)
+			setup.server.registry.LogStorage = &fakeStatsReader{stats: map[int64]*storage.TreeStats{tree.TreeId: test.stats}}
+
+			resp, err := setup.server.GetTreeStats(ctx, &trillian.GetTreeStatsRequest{TreeId: tree.TreeId})
+			if got := status.Code(err); got != test.wantCode {
+				t.Fatalf("GetTreeStats() = (_, %v), want code %v", err, test.wantCode)
+			}
+			if diff := cmp.Diff(test.want, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("GetTreeStats() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServer_GetTreeStatsUnimplemented(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	setup := setupAdminServer(ctrl, true /* snapshot */, true /* shouldCommit */, false /* commitErr */)
+	tree := newStatsTree(12345, trillian.TreeType_LOG)
+	setup.snapshotTX.EXPECT().GetTree(gomock.Any(), tree.TreeId).Return(tree, nil)
+	setup.server.registry.LogStorage = &testonly.FakeLogStorage{}
+
+	if _, err := setup.server.GetTreeStats(context.Background(), &trillian.GetTreeStatsRequest{TreeId: tree.TreeId}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("GetTreeStats() = (_, %v), want code %v", err, codes.Unimplemented)
+	}
+}
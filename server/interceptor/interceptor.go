@@ -364,7 +364,9 @@ func newRPCInfoForRequest(req interface{}) (*rpcInfo, error) {
 		info.getTree = false // Zero to many trees
 
 	// Admin / readonly
-	case *trillian.GetTreeRequest:
+	case *trillian.GetTreeRequest,
+		*trillian.GetTreeStatsRequest,
+		*trillian.ListAdminAuditEventsRequest:
 		info.getTree = false // Read done within RPC handler
 
 	// Admin / readwrite
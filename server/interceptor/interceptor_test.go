@@ -84,6 +84,16 @@ func TestTrillianInterceptor_TreeInterception(t *testing.T) {
 			method: "/trillian.TrillianAdmin/GetTree",
 			req:    &trillian.GetTreeRequest{TreeId: logTree.TreeId},
 		},
+		{
+			desc:   "adminStatsByID",
+			method: "/trillian.TrillianAdmin/GetTreeStats",
+			req:    &trillian.GetTreeStatsRequest{TreeId: logTree.TreeId},
+		},
+		{
+			desc:   "adminAuditEventsByID",
+			method: "/trillian.TrillianAdmin/ListAdminAuditEvents",
+			req:    &trillian.ListAdminAuditEventsRequest{TreeId: logTree.TreeId},
+		},
 		{
 			desc:   "adminWriteByID",
 			method: "/trillian.TrillianAdmin/DeleteTree",
@@ -490,6 +500,7 @@ func TestTrillianInterceptor_NotIntercepted(t *testing.T) {
 		// Admin
 		{method: "/trillian.TrillianAdmin/CreateTree", req: &trillian.CreateTreeRequest{}},
 		{method: "/trillian.TrillianAdmin/ListTrees", req: &trillian.ListTreesRequest{}},
+		{method: "/trillian.TrillianAdmin/ListAdminAuditEvents", req: &trillian.ListAdminAuditEventsRequest{}},
 		// Quota
 		{method: "/quotapb.Quota/CreateConfig", req: &quotapb.CreateConfigRequest{}},
 		{method: "/quotapb.Quota/DeleteConfig", req: &quotapb.DeleteConfigRequest{}},
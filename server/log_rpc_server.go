@@ -181,6 +181,7 @@ func (t *TrillianLogRPCServer) GetInclusionProof(ctx context.Context, req *trill
 		return nil, err
 	}
 	ctx = trees.NewContext(ctx, tree)
+	ctx = storage.WithMinTreeSize(ctx, uint64(req.TreeSize))
 
 	// Next we need to make sure the requested tree size corresponds to an STH, so that we
 	// have a usable tree revision
@@ -231,6 +232,7 @@ func (t *TrillianLogRPCServer) GetInclusionProofByHash(ctx context.Context, req
 		return nil, err
 	}
 	ctx = trees.NewContext(ctx, tree)
+	ctx = storage.WithMinTreeSize(ctx, uint64(req.TreeSize))
 
 	if err := validateGetInclusionProofByHashRequest(req, hasher); err != nil {
 		return nil, err
@@ -306,6 +308,7 @@ func (t *TrillianLogRPCServer) GetConsistencyProof(ctx context.Context, req *tri
 		return nil, err
 	}
 	ctx = trees.NewContext(ctx, tree)
+	ctx = storage.WithMinTreeSize(ctx, uint64(req.SecondTreeSize))
 
 	tx, err := t.snapshotForTree(ctx, tree, "GetConsistencyProof")
 	if err != nil {
@@ -351,6 +354,7 @@ func (t *TrillianLogRPCServer) GetLatestSignedLogRoot(ctx context.Context, req *
 		return nil, err
 	}
 	ctx = trees.NewContext(ctx, tree)
+	ctx = storage.WithMinTreeSize(ctx, uint64(req.FirstTreeSize))
 	tx, err := t.registry.LogStorage.SnapshotForTree(ctx, tree)
 	if err != nil {
 		return nil, err
@@ -424,6 +428,8 @@ func (t *TrillianLogRPCServer) GetLeavesByRange(ctx context.Context, req *trilli
 	if err != nil {
 		return nil, err
 	}
+	// Only leaves below the tree size are returned, so ask for the first one.
+	ctx = storage.WithMinTreeSize(ctx, uint64(req.StartIndex)+1)
 	tx, err := t.snapshotForTree(ctx, tree, "GetLeavesByRange")
 	if err != nil {
 		return nil, err
@@ -472,6 +478,7 @@ func (t *TrillianLogRPCServer) GetEntryAndProof(ctx context.Context, req *trilli
 		return nil, err
 	}
 	ctx = trees.NewContext(ctx, tree)
+	ctx = storage.WithMinTreeSize(ctx, uint64(req.TreeSize))
 
 	// Next we need to make sure the requested tree size corresponds to an STH, so that we
 	// have a usable tree revision
@@ -1751,3 +1751,83 @@ func addTreeID(tree *trillian.Tree, treeID int64) *trillian.Tree {
 	newTree.TreeId = treeID
 	return newTree
 }
+
+func TestReadsSetMinTreeSize(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		call func(context.Context, *TrillianLogRPCServer) error
+		want uint64
+	}{
+		{
+			name: "GetInclusionProof",
+			call: func(ctx context.Context, s *TrillianLogRPCServer) error {
+				_, err := s.GetInclusionProof(ctx, &getInclusionProofByIndexRequest25)
+				return err
+			},
+			want: 50,
+		},
+		{
+			name: "GetInclusionProofByHash",
+			call: func(ctx context.Context, s *TrillianLogRPCServer) error {
+				_, err := s.GetInclusionProofByHash(ctx, &getInclusionProofByHashRequest25)
+				return err
+			},
+			want: 25,
+		},
+		{
+			name: "GetConsistencyProof",
+			call: func(ctx context.Context, s *TrillianLogRPCServer) error {
+				_, err := s.GetConsistencyProof(ctx, &getConsistencyProofRequest48)
+				return err
+			},
+			want: 8,
+		},
+		{
+			name: "GetLatestSignedLogRoot",
+			call: func(ctx context.Context, s *TrillianLogRPCServer) error {
+				_, err := s.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: logID1, FirstTreeSize: 5})
+				return err
+			},
+			want: 5,
+		},
+		{
+			name: "GetLeavesByRange",
+			call: func(ctx context.Context, s *TrillianLogRPCServer) error {
+				_, err := s.GetLeavesByRange(ctx, &trillian.GetLeavesByRangeRequest{LogId: logID1, StartIndex: 10, Count: 5})
+				return err
+			},
+			want: 11,
+		},
+		{
+			name: "GetEntryAndProof",
+			call: func(ctx context.Context, s *TrillianLogRPCServer) error {
+				_, err := s.GetEntryAndProof(ctx, &getEntryAndProofRequest17)
+				return err
+			},
+			want: 17,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			fakeStorage := storage.NewMockLogStorage(ctrl)
+			var got uint64
+			fakeStorage.EXPECT().SnapshotForTree(gomock.Any(), cmpMatcher{tree1}).DoAndReturn(
+				func(ctx context.Context, _ *trillian.Tree) (storage.ReadOnlyLogTreeTX, error) {
+					got = storage.MinTreeSize(ctx)
+					return nil, errors.New("TX")
+				})
+			registry := extension.Registry{
+				AdminStorage: fakeAdminStorage(ctrl, storageParams{treeID: logID1, numSnapshots: 1}),
+				LogStorage:   fakeStorage,
+			}
+			server := NewTrillianLogRPCServer(registry, fakeTimeSource)
+			if err := tc.call(context.Background(), server); err == nil {
+				t.Fatal("call succeeded, want error")
+			}
+			if got != tc.want {
+				t.Errorf("SnapshotForTree() got min tree size %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
@@ -17,9 +17,12 @@ package storage
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/google/trillian"
 	"github.com/google/trillian/monitoring"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const traceSpanRoot = "/trillian/storage"
@@ -54,6 +57,82 @@ func ListTrees(ctx context.Context, admin AdminStorage, includeDeleted bool) ([]
 	return resp, err
 }
 
+// ListTreesFiltered reads the trees selected by opts from storage using a
+// snapshot transaction.
+// It's a convenience wrapper around RunInAdminSnapshot and FilterTrees.
+// See RunInAdminSnapshot if you need to perform more than one action per transaction.
+func ListTreesFiltered(ctx context.Context, admin AdminStorage, opts ListTreesOptions) ([]*trillian.Tree, error) {
+	ctx, spanEnd := spanFor(ctx, "ListTreesFiltered")
+	defer spanEnd()
+	var resp []*trillian.Tree
+	err := RunInAdminSnapshot(ctx, admin, func(tx ReadOnlyAdminTX) error {
+		var err error
+		resp, err = FilterTrees(ctx, tx, opts)
+		return err
+	})
+	return resp, err
+}
+
+// FilterTrees returns the trees selected by opts, ordered by ID. It uses tx's
+// ListTreesFiltered if tx implements TreeLister, and otherwise filters all
+// the trees returned by its ListTrees.
+func FilterTrees(ctx context.Context, tx ReadOnlyAdminTX, opts ListTreesOptions) ([]*trillian.Tree, error) {
+	if l, ok := tx.(TreeLister); ok {
+		return l.ListTreesFiltered(ctx, opts)
+	}
+	all, err := tx.ListTrees(ctx, opts.IncludeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].TreeId < all[j].TreeId })
+	trees := []*trillian.Tree{}
+	for _, tree := range all {
+		if opts.Limit > 0 && len(trees) == opts.Limit {
+			break
+		}
+		if opts.Matches(tree) {
+			trees = append(trees, tree)
+		}
+	}
+	return trees, nil
+}
+
+// ListAuditEvents reads the audit events selected by opts from storage using a
+// snapshot transaction.
+// It's a convenience wrapper around RunInAdminSnapshot and ReadAuditEvents.
+// See RunInAdminSnapshot if you need to perform more than one action per transaction.
+func ListAuditEvents(ctx context.Context, admin AdminStorage, opts ListAuditEventsOptions) ([]*trillian.AdminAuditEvent, error) {
+	ctx, spanEnd := spanFor(ctx, "ListAuditEvents")
+	defer spanEnd()
+	var resp []*trillian.AdminAuditEvent
+	err := RunInAdminSnapshot(ctx, admin, func(tx ReadOnlyAdminTX) error {
+		var err error
+		resp, err = ReadAuditEvents(ctx, tx, opts)
+		return err
+	})
+	return resp, err
+}
+
+// ReadAuditEvents returns the audit events selected by opts, ordered by ID, or
+// an Unimplemented error if tx doesn't implement AuditLogReader.
+func ReadAuditEvents(ctx context.Context, tx ReadOnlyAdminTX, opts ListAuditEventsOptions) ([]*trillian.AdminAuditEvent, error) {
+	r, ok := tx.(AuditLogReader)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "admin storage transaction %T doesn't keep an audit log", tx)
+	}
+	return r.ListAuditEvents(ctx, opts)
+}
+
+// AddAuditEvent appends event to the audit log if tx implements
+// AuditLogWriter, and does nothing otherwise.
+func AddAuditEvent(ctx context.Context, tx AdminTX, event *trillian.AdminAuditEvent) error {
+	w, ok := tx.(AuditLogWriter)
+	if !ok {
+		return nil
+	}
+	return w.AddAuditEvent(ctx, event)
+}
+
 // CreateTree creates a tree in storage.
 // It's a convenience wrapper around ReadWriteTransaction and AdminWriter's CreateTree.
 // See ReadWriteTransaction if you need to perform more than one action per transaction.
@@ -69,6 +148,34 @@ func CreateTree(ctx context.Context, admin AdminStorage, tree *trillian.Tree) (*
 	return createdTree, err
 }
 
+// CreateTreeWithID creates a tree in storage with the given ID, or returns an
+// Unimplemented error if the storage doesn't support choosing tree IDs.
+// It's a convenience wrapper around ReadWriteTransaction and AddTreeWithID.
+func CreateTreeWithID(ctx context.Context, admin AdminStorage, tree *trillian.Tree, treeID int64) (*trillian.Tree, error) {
+	ctx, spanEnd := spanFor(ctx, "CreateTreeWithID")
+	defer spanEnd()
+	var createdTree *trillian.Tree
+	err := admin.ReadWriteTransaction(ctx, func(ctx context.Context, tx AdminTX) error {
+		var err error
+		createdTree, err = AddTreeWithID(ctx, tx, tree, treeID)
+		return err
+	})
+	return createdTree, err
+}
+
+// AddTreeWithID creates a tree with the given ID in the transaction. It returns
+// an Unimplemented error if tx doesn't implement TreeCreatorWithID.
+func AddTreeWithID(ctx context.Context, tx AdminTX, tree *trillian.Tree, treeID int64) (*trillian.Tree, error) {
+	if treeID <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "tree ID %d is not positive", treeID)
+	}
+	c, ok := tx.(TreeCreatorWithID)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "admin storage %T doesn't support choosing tree IDs", tx)
+	}
+	return c.CreateTreeWithID(ctx, tree, treeID)
+}
+
 // UpdateTree updates a tree in storage.
 // It's a convenience wrapper around ReadWriteTransaction and AdminWriter's UpdateTree.
 // See ReadWriteTransaction if you need to perform more than one action per transaction.
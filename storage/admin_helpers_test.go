@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
 	"github.com/google/trillian"
 	"google.golang.org/protobuf/proto"
 )
@@ -173,3 +174,59 @@ func TestRunInAdminSnapshot(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterTrees(t *testing.T) {
+	newTree := func(id int64, treeType trillian.TreeType, state trillian.TreeState, deleted bool, labels map[string]string) *trillian.Tree {
+		return &trillian.Tree{TreeId: id, TreeType: treeType, TreeState: state, Deleted: deleted, Labels: labels}
+	}
+	trees := []*trillian.Tree{
+		newTree(5, trillian.TreeType_LOG, trillian.TreeState_ACTIVE, false, map[string]string{"tenant": "a", "env": "prod"}),
+		newTree(2, trillian.TreeType_PREORDERED_LOG, trillian.TreeState_ACTIVE, false, map[string]string{"tenant": "a"}),
+		newTree(9, trillian.TreeType_LOG, trillian.TreeState_FROZEN, false, map[string]string{"tenant": "b"}),
+		newTree(7, trillian.TreeType_LOG, trillian.TreeState_ACTIVE, true, map[string]string{"tenant": "a"}),
+		newTree(3, trillian.TreeType_LOG, trillian.TreeState_DRAINING, false, nil),
+	}
+
+	for _, tc := range []struct {
+		name    string
+		opts    ListTreesOptions
+		wantIDs []int64
+	}{
+		{name: "all", opts: ListTreesOptions{}, wantIDs: []int64{2, 3, 5, 9}},
+		{name: "deleted", opts: ListTreesOptions{IncludeDeleted: true}, wantIDs: []int64{2, 3, 5, 7, 9}},
+		{name: "label", opts: ListTreesOptions{Labels: map[string]string{"tenant": "a"}}, wantIDs: []int64{2, 5}},
+		{name: "labels", opts: ListTreesOptions{Labels: map[string]string{"tenant": "a", "env": "prod"}}, wantIDs: []int64{5}},
+		{name: "empty label value", opts: ListTreesOptions{Labels: map[string]string{"env": ""}}},
+		{name: "types", opts: ListTreesOptions{TreeTypes: []trillian.TreeType{trillian.TreeType_PREORDERED_LOG}}, wantIDs: []int64{2}},
+		{name: "states", opts: ListTreesOptions{TreeStates: []trillian.TreeState{trillian.TreeState_FROZEN, trillian.TreeState_DRAINING}}, wantIDs: []int64{3, 9}},
+		{name: "first page", opts: ListTreesOptions{Limit: 2}, wantIDs: []int64{2, 3}},
+		{name: "next page", opts: ListTreesOptions{AfterTreeID: 3, Limit: 2}, wantIDs: []int64{5, 9}},
+		{name: "last page", opts: ListTreesOptions{AfterTreeID: 9, Limit: 2}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			atx := NewMockReadOnlyAdminTX(ctrl)
+			var listed []*trillian.Tree
+			for _, tree := range trees {
+				if !tree.Deleted || tc.opts.IncludeDeleted {
+					listed = append(listed, tree)
+				}
+			}
+			atx.EXPECT().ListTrees(gomock.Any(), tc.opts.IncludeDeleted).Return(listed, nil)
+
+			got, err := FilterTrees(context.Background(), atx, tc.opts)
+			if err != nil {
+				t.Fatalf("FilterTrees(): %v", err)
+			}
+			var gotIDs []int64
+			for _, tree := range got {
+				gotIDs = append(gotIDs, tree.TreeId)
+			}
+			if diff := cmp.Diff(tc.wantIDs, gotIDs); diff != "" {
+				t.Errorf("FilterTrees() IDs diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -20,6 +20,62 @@ import (
 	"github.com/google/trillian"
 )
 
+// ListTreesOptions selects the trees returned by TreeLister.ListTreesFiltered.
+// Trees must match all the set fields.
+type ListTreesOptions struct {
+	// IncludeDeleted includes soft-deleted trees.
+	IncludeDeleted bool
+	// Labels, if set, selects the trees with all these labels and values.
+	Labels map[string]string
+	// TreeTypes, if set, selects the trees of these types.
+	TreeTypes []trillian.TreeType
+	// TreeStates, if set, selects the trees in these states.
+	TreeStates []trillian.TreeState
+	// AfterTreeID selects the trees with greater IDs, to continue a listing
+	// from the last tree of a previous page. Tree IDs are positive, so zero
+	// selects all trees.
+	AfterTreeID int64
+	// Limit, if positive, is the maximum number of trees returned.
+	Limit int
+}
+
+// Matches returns whether tree is selected by opts, disregarding Limit.
+func (opts ListTreesOptions) Matches(tree *trillian.Tree) bool {
+	if tree.Deleted && !opts.IncludeDeleted || tree.TreeId <= opts.AfterTreeID {
+		return false
+	}
+	if len(opts.TreeTypes) > 0 && !containsTreeType(opts.TreeTypes, tree.TreeType) {
+		return false
+	}
+	if len(opts.TreeStates) > 0 && !containsTreeState(opts.TreeStates, tree.TreeState) {
+		return false
+	}
+	for k, v := range opts.Labels {
+		if got, ok := tree.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+func containsTreeType(types []trillian.TreeType, t trillian.TreeType) bool {
+	for _, tt := range types {
+		if tt == t {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTreeState(states []trillian.TreeState, s trillian.TreeState) bool {
+	for _, ts := range states {
+		if ts == s {
+			return true
+		}
+	}
+	return false
+}
+
 // ReadOnlyAdminTX is a transaction capable only of read operations in the
 // AdminStorage.
 type ReadOnlyAdminTX interface {
@@ -41,6 +97,56 @@ type ReadOnlyAdminTX interface {
 	Close() error
 }
 
+// TreeLister is an optional interface of ReadOnlyAdminTX, implemented by
+// storage systems which can select trees without reading all of them. Use
+// FilterTrees to list trees with any ReadOnlyAdminTX.
+type TreeLister interface {
+	// ListTreesFiltered returns the trees selected by opts, ordered by ID.
+	ListTreesFiltered(ctx context.Context, opts ListTreesOptions) ([]*trillian.Tree, error)
+}
+
+// ListAuditEventsOptions selects the events returned by
+// AuditLogReader.ListAuditEvents.
+type ListAuditEventsOptions struct {
+	// TreeID, if non-zero, selects the events of this tree.
+	TreeID int64
+	// AfterEventID selects the events with greater IDs, to continue a listing
+	// from the last event of a previous page. Event IDs are positive, so zero
+	// selects all events.
+	AfterEventID int64
+	// Limit, if positive, is the maximum number of events returned.
+	Limit int
+}
+
+// AuditLogReader is an optional interface of ReadOnlyAdminTX, implemented by
+// storage systems which keep an audit log of the changes made to trees.
+type AuditLogReader interface {
+	// ListAuditEvents returns the events selected by opts, ordered by ID.
+	ListAuditEvents(ctx context.Context, opts ListAuditEventsOptions) ([]*trillian.AdminAuditEvent, error)
+}
+
+// AuditLogWriter is an optional interface of AdminTX, implemented by storage
+// systems which keep an audit log of the changes made to trees. Events are
+// written in the transaction which makes the change, so they're only recorded
+// if the change is committed.
+type AuditLogWriter interface {
+	// AddAuditEvent appends event to the audit log, setting its EventId to a
+	// positive value greater than the IDs of the events already recorded.
+	// Events aren't linked to their trees, so they're kept after the trees
+	// are hard-deleted.
+	AddAuditEvent(ctx context.Context, event *trillian.AdminAuditEvent) error
+}
+
+// TreeCreatorWithID is an optional interface of AdminTX, implemented by storage
+// systems which can create a tree with a given ID rather than a random one.
+// It's meant for tools which copy trees between storage systems, and need to
+// preserve their IDs.
+type TreeCreatorWithID interface {
+	// CreateTreeWithID is like CreateTree, but uses the given tree ID, which
+	// must be positive. It fails if a tree with this ID already exists.
+	CreateTreeWithID(ctx context.Context, tree *trillian.Tree, treeID int64) (*trillian.Tree, error)
+}
+
 // AdminTX is a transaction capable of read and write operations in the
 // AdminStorage.
 type AdminTX interface {
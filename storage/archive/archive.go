@@ -0,0 +1,298 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package archive defines a portable archive format for logs, and exports
+// and imports archives through the generic storage interfaces, so that a log
+// can be backed up, or moved between storage systems and clusters.
+//
+// An archive starts with the magic string "TRLARC\x00", followed by a byte
+// holding the format version, which is currently 1. The rest of the archive is
+// a sequence of records, each of which is a byte holding the record type, the
+// uvarint-encoded length of the payload, and the payload:
+//
+//	type 1, tree: the trillian.Tree proto of the log
+//	type 2, root: the trillian.SignedLogRoot proto of the latest log root
+//	type 3, leaf: a trillian.LogLeaf proto
+//	type 4, node: a Merkle tree node, as its level in a byte, followed by
+//	              its index as an 8-byte big-endian number, and its hash
+//	type 0, end:  an empty payload, marking the end of the archive
+//
+// The tree record comes first, followed by the root record, the leaves in
+// LeafIndex order from 0 up to the size of the root, the nodes if any, and
+// finally the end record. Nodes are optional as they can be recomputed from
+// the leaves, but including them allows Import to check them.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage/tree"
+	"github.com/transparency-dev/merkle/compact"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// Version is the version of the archive format written by Writer.
+	Version = 1
+
+	// maxRecordSize is the largest record payload that Reader accepts, which
+	// protects it from allocating huge buffers for corrupt archives.
+	maxRecordSize = 64 << 20
+)
+
+var magic = []byte("TRLARC\x00")
+
+// recordType identifies the payload of an archive record.
+type recordType byte
+
+const (
+	endRecord recordType = iota
+	treeRecord
+	rootRecord
+	leafRecord
+	nodeRecord
+)
+
+func (t recordType) String() string {
+	switch t {
+	case endRecord:
+		return "end"
+	case treeRecord:
+		return "tree"
+	case rootRecord:
+		return "root"
+	case leafRecord:
+		return "leaf"
+	case nodeRecord:
+		return "node"
+	}
+	return fmt.Sprintf("unknown(%d)", byte(t))
+}
+
+// Writer writes an archive. Its methods must be called in the order in which
+// the records appear in the archive, and Close must be called to complete it.
+type Writer struct {
+	w *bufio.Writer
+}
+
+// NewWriter writes the archive header to w, and returns a Writer for the
+// rest of the archive.
+func NewWriter(w io.Writer) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(magic); err != nil {
+		return nil, err
+	}
+	if err := bw.WriteByte(Version); err != nil {
+		return nil, err
+	}
+	return &Writer{w: bw}, nil
+}
+
+// WriteTree writes the tree record.
+func (w *Writer) WriteTree(t *trillian.Tree) error {
+	return w.writeProto(treeRecord, t)
+}
+
+// WriteRoot writes the root record.
+func (w *Writer) WriteRoot(root *trillian.SignedLogRoot) error {
+	return w.writeProto(rootRecord, root)
+}
+
+// WriteLeaf writes a leaf record.
+func (w *Writer) WriteLeaf(leaf *trillian.LogLeaf) error {
+	return w.writeProto(leafRecord, leaf)
+}
+
+// WriteNode writes a node record.
+func (w *Writer) WriteNode(n tree.Node) error {
+	payload := make([]byte, 9, 9+len(n.Hash))
+	payload[0] = byte(n.ID.Level)
+	binary.BigEndian.PutUint64(payload[1:], n.ID.Index)
+	return w.writeRecord(nodeRecord, append(payload, n.Hash...))
+}
+
+// Close writes the end record and flushes the archive. It doesn't close the
+// underlying io.Writer.
+func (w *Writer) Close() error {
+	if err := w.writeRecord(endRecord, nil); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+func (w *Writer) writeProto(t recordType, m proto.Message) error {
+	payload, err := proto.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %v record: %v", t, err)
+	}
+	return w.writeRecord(t, payload)
+}
+
+func (w *Writer) writeRecord(t recordType, payload []byte) error {
+	var hdr [1 + binary.MaxVarintLen64]byte
+	hdr[0] = byte(t)
+	n := binary.PutUvarint(hdr[1:], uint64(len(payload)))
+	if _, err := w.w.Write(hdr[:1+n]); err != nil {
+		return err
+	}
+	_, err := w.w.Write(payload)
+	return err
+}
+
+// Reader reads an archive. The tree and root records are read by NewReader,
+// after which the leaves must be read with ReadLeaf, followed by the nodes
+// with ReadNode.
+type Reader struct {
+	r    *bufio.Reader
+	tree *trillian.Tree
+	root *trillian.SignedLogRoot
+
+	// next is the type of the record that has been read but not returned
+	// yet, and payload is its payload.
+	next    recordType
+	payload []byte
+	// pending is true if next and payload hold a record.
+	pending bool
+}
+
+// NewReader reads the archive header, tree and root records from r, and
+// returns a Reader for the rest of the archive.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	hdr := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return nil, fmt.Errorf("failed to read archive header: %v", err)
+	}
+	if !bytes.Equal(hdr[:len(magic)], magic) {
+		return nil, errors.New("not a log archive")
+	}
+	if v := hdr[len(magic)]; v != Version {
+		return nil, fmt.Errorf("unsupported archive version %d, want %d", v, Version)
+	}
+
+	rd := &Reader{r: br, tree: &trillian.Tree{}, root: &trillian.SignedLogRoot{}}
+	if err := rd.readProto(treeRecord, rd.tree); err != nil {
+		return nil, err
+	}
+	if err := rd.readProto(rootRecord, rd.root); err != nil {
+		return nil, err
+	}
+	return rd, nil
+}
+
+// Tree returns the tree stored in the archive.
+func (r *Reader) Tree() *trillian.Tree {
+	return r.tree
+}
+
+// Root returns the log root stored in the archive.
+func (r *Reader) Root() *trillian.SignedLogRoot {
+	return r.root
+}
+
+// ReadLeaf returns the next leaf in the archive, or io.EOF if there are no
+// more leaves.
+func (r *Reader) ReadLeaf() (*trillian.LogLeaf, error) {
+	t, payload, err := r.peek()
+	if err != nil {
+		return nil, err
+	}
+	if t != leafRecord {
+		if t != nodeRecord && t != endRecord {
+			return nil, fmt.Errorf("unexpected %v record, want leaf", t)
+		}
+		return nil, io.EOF
+	}
+	r.pending = false
+	leaf := &trillian.LogLeaf{}
+	if err := proto.Unmarshal(payload, leaf); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal leaf record: %v", err)
+	}
+	return leaf, nil
+}
+
+// ReadNode returns the next node in the archive, or io.EOF if there are no
+// more nodes. It fails if there are leaves left to read.
+func (r *Reader) ReadNode() (tree.Node, error) {
+	t, payload, err := r.peek()
+	if err != nil {
+		return tree.Node{}, err
+	}
+	switch t {
+	case endRecord:
+		return tree.Node{}, io.EOF
+	case nodeRecord:
+	default:
+		return tree.Node{}, fmt.Errorf("unexpected %v record, want node", t)
+	}
+	r.pending = false
+	if len(payload) < 9 {
+		return tree.Node{}, fmt.Errorf("node record too short: %d bytes", len(payload))
+	}
+	id := compact.NewNodeID(uint(payload[0]), binary.BigEndian.Uint64(payload[1:9]))
+	return tree.Node{ID: id, Hash: payload[9:]}, nil
+}
+
+func (r *Reader) readProto(want recordType, m proto.Message) error {
+	t, payload, err := r.peek()
+	if err != nil {
+		return err
+	}
+	if t != want {
+		return fmt.Errorf("unexpected %v record, want %v", t, want)
+	}
+	r.pending = false
+	if err := proto.Unmarshal(payload, m); err != nil {
+		return fmt.Errorf("failed to unmarshal %v record: %v", t, err)
+	}
+	return nil
+}
+
+// peek returns the next record without consuming it. Reaching the end of the
+// input before the end record is an error, as the archive is truncated.
+func (r *Reader) peek() (recordType, []byte, error) {
+	if r.pending {
+		return r.next, r.payload, nil
+	}
+	b, err := r.r.ReadByte()
+	if err != nil {
+		return 0, nil, truncated(err)
+	}
+	size, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return 0, nil, truncated(err)
+	}
+	if size > maxRecordSize {
+		return 0, nil, fmt.Errorf("record of %d bytes exceeds the maximum of %d", size, maxRecordSize)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r.r, payload); err != nil {
+		return 0, nil, truncated(err)
+	}
+	r.next, r.payload, r.pending = recordType(b), payload, true
+	return r.next, r.payload, nil
+}
+
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.New("archive is truncated")
+	}
+	return err
+}
@@ -0,0 +1,288 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/trillian"
+	"github.com/google/trillian/log"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/bolt"
+	"github.com/google/trillian/storage/memory"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/types"
+	"github.com/google/trillian/util/clock"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+var fakeTime = time.Date(2016, 11, 10, 15, 16, 27, 0, time.UTC)
+
+func init() {
+	log.InitMetrics(nil)
+}
+
+type testStorage struct {
+	as storage.AdminStorage
+	ls storage.LogStorage
+}
+
+func newMemoryStorage(t *testing.T) testStorage {
+	t.Helper()
+	ts := memory.NewTreeStorage()
+	return testStorage{as: memory.NewAdminStorage(ts), ls: memory.NewLogStorage(ts, nil)}
+}
+
+func newBoltStorage(t *testing.T) testStorage {
+	t.Helper()
+	db, err := bolt.OpenDB(filepath.Join(t.TempDir(), "trillian.bolt"))
+	if err != nil {
+		t.Fatalf("OpenDB(): %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return testStorage{as: bolt.NewAdminStorage(db), ls: bolt.NewLogStorage(db, nil)}
+}
+
+func createLeaves(n int) []*trillian.LogLeaf {
+	leaves := make([]*trillian.LogLeaf, 0, n)
+	for i := 0; i < n; i++ {
+		data := []byte(fmt.Sprintf("leaf %d", i))
+		id := sha256.Sum256(data)
+		leaves = append(leaves, &trillian.LogLeaf{
+			LeafIndex:        int64(i),
+			LeafValue:        data,
+			ExtraData:        []byte(fmt.Sprintf("extra %d", i)),
+			LeafIdentityHash: id[:],
+			MerkleLeafHash:   rfc6962.DefaultHasher.HashLeaf(data),
+		})
+	}
+	return leaves
+}
+
+// populate creates a tree of the given type with numLeaves leaves, added
+// in several batches and integrated by the log sequencer.
+func populate(ctx context.Context, t *testing.T, s testStorage, treeType trillian.TreeType, numLeaves int) *trillian.Tree {
+	t.Helper()
+	tmpl := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	tmpl.TreeType = treeType
+	tmpl.DisplayName = "archived"
+	tree, err := storage.CreateTree(ctx, s.as, tmpl)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+
+	ts := clock.NewFake(fakeTime)
+	logRoot, err := (&types.LogRootV1{RootHash: rfc6962.DefaultHasher.EmptyRoot(), TimestampNanos: uint64(ts.Now().UnixNano())}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary(): %v", err)
+	}
+	if err := s.ls.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: logRoot})
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(init): %v", err)
+	}
+
+	leaves := createLeaves(numLeaves)
+	for len(leaves) > 0 {
+		n := 4
+		if n > len(leaves) {
+			n = len(leaves)
+		}
+		ts.Set(ts.Now().Add(time.Second))
+		if treeType == trillian.TreeType_PREORDERED_LOG {
+			_, err = s.ls.AddSequencedLeaves(ctx, tree, leaves[:n], ts.Now())
+		} else {
+			_, err = s.ls.QueueLeaves(ctx, tree, leaves[:n], ts.Now())
+		}
+		if err != nil {
+			t.Fatalf("Adding leaves: %v", err)
+		}
+		ts.Set(ts.Now().Add(time.Second))
+		if _, err := log.IntegrateBatch(ctx, tree, 3, 0, 0, ts, s.ls, quota.Noop()); err != nil {
+			t.Fatalf("IntegrateBatch(): %v", err)
+		}
+		ts.Set(ts.Now().Add(time.Second))
+		if _, err := log.IntegrateBatch(ctx, tree, 3, 0, 0, ts, s.ls, quota.Noop()); err != nil {
+			t.Fatalf("IntegrateBatch(): %v", err)
+		}
+		leaves = leaves[n:]
+	}
+	return tree
+}
+
+// readTree returns the latest root and all the leaves of a tree.
+func readTree(ctx context.Context, t *testing.T, s testStorage, tree *trillian.Tree) (*trillian.SignedLogRoot, []*trillian.LogLeaf) {
+	t.Helper()
+	tx, err := s.ls.SnapshotForTree(ctx, tree)
+	if err != nil {
+		t.Fatalf("SnapshotForTree(): %v", err)
+	}
+	defer tx.Close()
+	slr, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil {
+		t.Fatalf("LatestSignedLogRoot(): %v", err)
+	}
+	var root types.LogRootV1
+	if err := root.UnmarshalBinary(slr.LogRoot); err != nil {
+		t.Fatalf("UnmarshalBinary(): %v", err)
+	}
+	var leaves []*trillian.LogLeaf
+	if root.TreeSize > 0 {
+		if leaves, err = tx.GetLeavesByRange(ctx, 0, int64(root.TreeSize)); err != nil {
+			t.Fatalf("GetLeavesByRange(): %v", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit(): %v", err)
+	}
+	return slr, leaves
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		desc      string
+		src, dst  func(*testing.T) testStorage
+		treeType  trillian.TreeType
+		numLeaves int
+		export    ExportOptions
+		import_   ImportOptions
+	}{
+		{
+			desc:      "memory-to-memory",
+			src:       newMemoryStorage,
+			dst:       newMemoryStorage,
+			treeType:  trillian.TreeType_LOG,
+			numLeaves: 11,
+			export:    ExportOptions{BatchSize: 3, IncludeTiles: true},
+			import_:   ImportOptions{BatchSize: 4},
+		},
+		{
+			desc:      "memory-to-bolt",
+			src:       newMemoryStorage,
+			dst:       newBoltStorage,
+			treeType:  trillian.TreeType_LOG,
+			numLeaves: 11,
+			import_:   ImportOptions{BatchSize: 11},
+		},
+		{
+			desc:      "preordered",
+			src:       newBoltStorage,
+			dst:       newBoltStorage,
+			treeType:  trillian.TreeType_PREORDERED_LOG,
+			numLeaves: 9,
+			export:    ExportOptions{IncludeTiles: true},
+			import_:   ImportOptions{BatchSize: 2},
+		},
+		{
+			desc:     "empty",
+			src:      newMemoryStorage,
+			dst:      newBoltStorage,
+			treeType: trillian.TreeType_LOG,
+			export:   ExportOptions{IncludeTiles: true},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			src := tc.src(t)
+			tree := populate(ctx, t, src, tc.treeType, tc.numLeaves)
+			wantRoot, wantLeaves := readTree(ctx, t, src, tree)
+
+			var buf bytes.Buffer
+			if err := Export(ctx, &buf, src.as, src.ls, tree.TreeId, tc.export); err != nil {
+				t.Fatalf("Export(): %v", err)
+			}
+
+			dst := tc.dst(t)
+			imported, err := Import(ctx, &buf, dst.as, dst.ls, tc.import_)
+			if err != nil {
+				t.Fatalf("Import(): %v", err)
+			}
+			if got, want := imported.DisplayName, tree.DisplayName; got != want {
+				t.Errorf("DisplayName = %q, want %q", got, want)
+			}
+			if got, want := imported.TreeType, tree.TreeType; got != want {
+				t.Errorf("TreeType = %v, want %v", got, want)
+			}
+			if got, want := imported.TreeState, tree.TreeState; got != want {
+				t.Errorf("TreeState = %v, want %v", got, want)
+			}
+
+			gotRoot, gotLeaves := readTree(ctx, t, dst, imported)
+			if !proto.Equal(gotRoot, wantRoot) {
+				t.Errorf("Imported root = %v, want %v", gotRoot, wantRoot)
+			}
+			if diff := cmp.Diff(wantLeaves, gotLeaves, protocmp.Transform(),
+				protocmp.IgnoreFields(&trillian.LogLeaf{}, "queue_timestamp")); diff != "" {
+				t.Errorf("Imported leaves diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	ctx := context.Background()
+	src := newMemoryStorage(t)
+	tree := populate(ctx, t, src, trillian.TreeType_LOG, 5)
+	var buf bytes.Buffer
+	if err := Export(ctx, &buf, src.as, src.ls, tree.TreeId, ExportOptions{IncludeTiles: true}); err != nil {
+		t.Fatalf("Export(): %v", err)
+	}
+	archive := buf.Bytes()
+
+	// corrupt returns a copy of the archive with the byte at index i flipped.
+	corrupt := func(i int) []byte {
+		b := append([]byte(nil), archive...)
+		b[i] ^= 0xff
+		return b
+	}
+
+	for _, tc := range []struct {
+		desc    string
+		archive []byte
+	}{
+		{desc: "empty", archive: nil},
+		{desc: "bad magic", archive: corrupt(0)},
+		{desc: "bad version", archive: corrupt(len(magic))},
+		{desc: "truncated", archive: archive[:len(archive)/2]},
+		{desc: "missing end", archive: archive[:len(archive)-2]},
+		{desc: "corrupt node", archive: corrupt(len(archive) - 2)},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			dst := newMemoryStorage(t)
+			if _, err := Import(ctx, bytes.NewReader(tc.archive), dst.as, dst.ls, ImportOptions{}); err == nil {
+				t.Error("Import() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestExportMissingTree(t *testing.T) {
+	ctx := context.Background()
+	s := newMemoryStorage(t)
+	var buf bytes.Buffer
+	if err := Export(ctx, &buf, s.as, s.ls, 12345, ExportOptions{}); err == nil {
+		t.Error("Export() of missing tree succeeded, want error")
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/types"
+	"github.com/transparency-dev/merkle/compact"
+)
+
+// DefaultBatchSize is the number of leaves or nodes read or written in each
+// storage call by default.
+const DefaultBatchSize = 1000
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	// BatchSize is the number of leaves or nodes read from storage at a
+	// time. DefaultBatchSize is used if it is zero.
+	BatchSize int
+	// IncludeTiles makes the archive include all the Merkle tree nodes.
+	IncludeTiles bool
+}
+
+// Export writes an archive of the log with the given ID to w. The archive
+// contains the leaves up to the size of the latest log root, which are read
+// from a single storage snapshot so that they are consistent with the root.
+// Unsequenced leaves, and leaves of pre-ordered logs beyond the size of the
+// root, are not exported.
+func Export(ctx context.Context, w io.Writer, as storage.AdminStorage, ls storage.LogStorage, treeID int64, opts ExportOptions) error {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	tree, err := storage.GetTree(ctx, as, treeID)
+	if err != nil {
+		return err
+	}
+	switch tree.TreeType {
+	case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+	default:
+		return fmt.Errorf("can't export tree %d of type %v", treeID, tree.TreeType)
+	}
+
+	tx, err := ls.SnapshotForTree(ctx, tree)
+	if tx != nil {
+		// Some storage implementations return a transaction along with
+		// ErrTreeNeedsInit, which must be closed too.
+		defer tx.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	slr, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil {
+		return err
+	}
+	var root types.LogRootV1
+	if err := root.UnmarshalBinary(slr.LogRoot); err != nil {
+		return fmt.Errorf("failed to unmarshal log root: %v", err)
+	}
+
+	aw, err := NewWriter(w)
+	if err != nil {
+		return err
+	}
+	if err := aw.WriteTree(tree); err != nil {
+		return err
+	}
+	if err := aw.WriteRoot(slr); err != nil {
+		return err
+	}
+
+	size := int64(root.TreeSize)
+	for start := int64(0); start < size; {
+		count := size - start
+		if count > int64(batchSize) {
+			count = int64(batchSize)
+		}
+		leaves, err := tx.GetLeavesByRange(ctx, start, count)
+		if err != nil {
+			return fmt.Errorf("failed to read leaves [%d, %d): %v", start, start+count, err)
+		}
+		if len(leaves) == 0 {
+			return fmt.Errorf("missing leaf %d in tree of size %d", start, size)
+		}
+		for _, leaf := range leaves {
+			if leaf.LeafIndex != start {
+				return fmt.Errorf("got leaf %d, want %d", leaf.LeafIndex, start)
+			}
+			if err := aw.WriteLeaf(leaf); err != nil {
+				return err
+			}
+			start++
+		}
+	}
+
+	if opts.IncludeTiles {
+		if err := exportNodes(ctx, aw, tx, root.TreeSize, batchSize); err != nil {
+			return err
+		}
+	}
+
+	if err := aw.Close(); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// exportNodes writes all the nodes of the perfect subtrees of the tree of
+// the given size, level by level.
+func exportNodes(ctx context.Context, aw *Writer, tx storage.ReadOnlyLogTreeTX, size uint64, batchSize int) error {
+	ids := make([]compact.NodeID, 0, batchSize)
+	flush := func() error {
+		if len(ids) == 0 {
+			return nil
+		}
+		nodes, err := tx.GetMerkleNodes(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("failed to read nodes: %v", err)
+		}
+		if got, want := len(nodes), len(ids); got != want {
+			return fmt.Errorf("got %d nodes, want %d", got, want)
+		}
+		for _, n := range nodes {
+			if err := aw.WriteNode(n); err != nil {
+				return err
+			}
+		}
+		ids = ids[:0]
+		return nil
+	}
+
+	for level := uint(0); size>>level > 0; level++ {
+		for index := uint64(0); index < size>>level; index++ {
+			ids = append(ids, compact.NewNodeID(level, index))
+			if len(ids) == batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return flush()
+}
@@ -0,0 +1,410 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/tree"
+	"github.com/google/trillian/types"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+// ImportOptions configures Import.
+type ImportOptions struct {
+	// BatchSize is the number of leaves integrated in each storage
+	// transaction. DefaultBatchSize is used if it is zero.
+	BatchSize int
+}
+
+// Import creates a new tree from the archive read from r, and returns it.
+//
+// The tree gets a new ID, and is kept FROZEN while it is populated, so that
+// log signers leave it alone. Its leaves are integrated batch by batch, each
+// in its own transaction, recomputing the Merkle tree nodes from the leaves.
+// The intermediate log roots are timestamped just before the archived root,
+// which is stored last, once the recomputed root hash matches it. Finally,
+// the root hash is recomputed from the nodes read back from storage, the
+// nodes are checked against those in the archive, if any, and the tree is
+// set to the state recorded in the archive.
+//
+// If Import fails after creating the tree, the tree is left FROZEN, and the
+// returned error includes its ID so that it can be deleted.
+func Import(ctx context.Context, r io.Reader, as storage.AdminStorage, ls storage.LogStorage, opts ImportOptions) (*trillian.Tree, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	rd, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	archived := rd.Tree()
+	switch archived.TreeType {
+	case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+	default:
+		return nil, fmt.Errorf("can't import tree of type %v", archived.TreeType)
+	}
+	var root types.LogRootV1
+	if err := root.UnmarshalBinary(rd.Root().LogRoot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal log root: %v", err)
+	}
+	// Each batch stores a log root, and so does the initialization of the
+	// log, with consecutive timestamps ending with that of the archived root.
+	batches := (root.TreeSize + uint64(batchSize) - 1) / uint64(batchSize)
+	if root.TimestampNanos <= batches {
+		return nil, fmt.Errorf("log root timestamp %d is too small to import %d batches", root.TimestampNanos, batches)
+	}
+
+	newTree := proto.Clone(archived).(*trillian.Tree)
+	newTree.TreeId = 0
+	newTree.TreeState = trillian.TreeState_ACTIVE
+	newTree.CreateTime, newTree.UpdateTime = nil, nil
+	newTree.Deleted, newTree.DeleteTime = false, nil
+	t, err := storage.CreateTree(ctx, as, newTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tree: %v", err)
+	}
+	if t, err = storage.UpdateTree(ctx, as, t.TreeId, func(t *trillian.Tree) {
+		t.TreeState = trillian.TreeState_FROZEN
+	}); err != nil {
+		return nil, fmt.Errorf("failed to freeze tree %d: %v", t.TreeId, err)
+	}
+
+	im := &importer{
+		rd:        rd,
+		ls:        ls,
+		tree:      t,
+		root:      root,
+		batchSize: batchSize,
+		batches:   batches,
+		cr:        (&compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}).NewEmptyRange(0),
+	}
+	if err := im.run(ctx); err != nil {
+		return nil, fmt.Errorf("failed to import into tree %d: %v", t.TreeId, err)
+	}
+
+	if state := archived.TreeState; state != trillian.TreeState_FROZEN {
+		if t, err = storage.UpdateTree(ctx, as, t.TreeId, func(t *trillian.Tree) {
+			t.TreeState = state
+		}); err != nil {
+			return nil, fmt.Errorf("failed to set state of tree %d: %v", t.TreeId, err)
+		}
+	}
+	return t, nil
+}
+
+// importer holds the state of an import.
+type importer struct {
+	rd        *Reader
+	ls        storage.LogStorage
+	tree      *trillian.Tree
+	root      types.LogRootV1
+	batchSize int
+	batches   uint64
+	// cr is the compact range of the leaves integrated so far.
+	cr *compact.Range
+}
+
+func (im *importer) run(ctx context.Context) error {
+	if im.root.TreeSize == 0 {
+		if !bytes.Equal(im.root.RootHash, rfc6962.DefaultHasher.EmptyRoot()) {
+			return fmt.Errorf("root hash %x of empty log isn't the empty root hash", im.root.RootHash)
+		}
+		return im.storeRoot(ctx, im.rd.Root())
+	}
+
+	// Initialize the log with an empty root, as the log server does.
+	if err := im.storeRoot(ctx, im.logRoot(0, rfc6962.DefaultHasher.EmptyRoot(), im.batches)); err != nil {
+		return fmt.Errorf("failed to initialize log: %v", err)
+	}
+	for batch := uint64(1); batch <= im.batches; batch++ {
+		leaves, err := im.readLeaves()
+		if err != nil {
+			return err
+		}
+		if err := im.integrate(ctx, batch, leaves); err != nil {
+			return fmt.Errorf("failed to integrate leaves [%d, %d): %v", leaves[0].LeafIndex, im.cr.End(), err)
+		}
+	}
+	if _, err := im.rd.ReadLeaf(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("archive has more leaves than the tree size %d", im.root.TreeSize)
+		}
+		return err
+	}
+	return im.verify(ctx)
+}
+
+// readLeaves returns the next batch of leaves from the archive.
+func (im *importer) readLeaves() ([]*trillian.LogLeaf, error) {
+	next := im.cr.End()
+	count := im.root.TreeSize - next
+	if count > uint64(im.batchSize) {
+		count = uint64(im.batchSize)
+	}
+	leaves := make([]*trillian.LogLeaf, 0, count)
+	for i := uint64(0); i < count; i++ {
+		leaf, err := im.rd.ReadLeaf()
+		if err == io.EOF {
+			return nil, fmt.Errorf("archive has %d leaves, want %d", next+i, im.root.TreeSize)
+		} else if err != nil {
+			return nil, err
+		}
+		if want := int64(next + i); leaf.LeafIndex != want {
+			return nil, fmt.Errorf("got leaf %d, want %d", leaf.LeafIndex, want)
+		}
+		leaves = append(leaves, leaf)
+	}
+	return leaves, nil
+}
+
+// integrate writes the given batch of leaves to storage, along with the
+// Merkle tree nodes and the log root they result in.
+func (im *importer) integrate(ctx context.Context, batch uint64, leaves []*trillian.LogLeaf) error {
+	nodeMap := make(map[compact.NodeID][]byte)
+	store := func(id compact.NodeID, hash []byte) { nodeMap[id] = hash }
+	for _, leaf := range leaves {
+		if err := im.cr.Append(leaf.MerkleLeafHash, store); err != nil {
+			return err
+		}
+	}
+	nodes := make([]tree.Node, 0, len(nodeMap))
+	for id, hash := range nodeMap {
+		nodes = append(nodes, tree.Node{ID: id, Hash: hash})
+	}
+	hash, err := im.cr.GetRootHash(nil)
+	if err != nil {
+		return err
+	}
+
+	slr := im.rd.Root()
+	if batch < im.batches {
+		slr = im.logRoot(im.cr.End(), hash, im.batches-batch)
+	} else if !bytes.Equal(hash, im.root.RootHash) {
+		return fmt.Errorf("root hash mismatch: got %x, want %x", hash, im.root.RootHash)
+	}
+
+	if im.tree.TreeType == trillian.TreeType_PREORDERED_LOG {
+		return im.addSequenced(ctx, leaves, nodes, slr)
+	}
+	return im.queueAndSequence(ctx, leaves, nodes, slr)
+}
+
+// addSequenced writes leaves of a PREORDERED_LOG tree.
+func (im *importer) addSequenced(ctx context.Context, leaves []*trillian.LogLeaf, nodes []tree.Node, slr *trillian.SignedLogRoot) error {
+	res, err := im.ls.AddSequencedLeaves(ctx, im.tree, leaves, queueTime(leaves[0]))
+	if err != nil {
+		return err
+	}
+	for i, r := range res {
+		if c := codes.Code(r.GetStatus().GetCode()); c != codes.OK {
+			return fmt.Errorf("failed to add leaf %d: %v: %s", leaves[i].LeafIndex, c, r.GetStatus().GetMessage())
+		}
+	}
+	return im.ls.ReadWriteTransaction(ctx, im.tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		if err := tx.SetMerkleNodes(ctx, nodes); err != nil {
+			return err
+		}
+		return tx.StoreSignedLogRoot(ctx, slr)
+	})
+}
+
+// queueAndSequence writes leaves of a LOG tree. They are queued and then
+// sequenced at their original indices, in the same way as the log signer
+// does, as there is no other way of adding leaves to a LOG tree.
+func (im *importer) queueAndSequence(ctx context.Context, leaves []*trillian.LogLeaf, nodes []tree.Node, slr *trillian.SignedLogRoot) error {
+	// Queue runs of leaves with the same queue timestamp together.
+	var cutoff time.Time
+	for start := 0; start < len(leaves); {
+		ts := queueTime(leaves[start])
+		end := start + 1
+		for end < len(leaves) && queueTime(leaves[end]).Equal(ts) {
+			end++
+		}
+		res, err := im.ls.QueueLeaves(ctx, im.tree, leaves[start:end], ts)
+		if err != nil {
+			return err
+		}
+		for i, r := range res {
+			if c := codes.Code(r.GetStatus().GetCode()); c != codes.OK {
+				return fmt.Errorf("failed to queue leaf %d: %v: %s", leaves[start+i].LeafIndex, c, r.GetStatus().GetMessage())
+			}
+		}
+		if ts.After(cutoff) {
+			cutoff = ts
+		}
+		start = end
+	}
+
+	byIdentity := make(map[string]*trillian.LogLeaf, len(leaves))
+	for _, leaf := range leaves {
+		byIdentity[string(leaf.LeafIdentityHash)] = leaf
+	}
+	return im.ls.ReadWriteTransaction(ctx, im.tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		dequeued, err := tx.DequeueLeaves(ctx, len(leaves), cutoff)
+		if err != nil {
+			return err
+		}
+		if got, want := len(dequeued), len(leaves); got != want {
+			return fmt.Errorf("dequeued %d leaves, want %d", got, want)
+		}
+		for _, leaf := range dequeued {
+			orig, ok := byIdentity[string(leaf.LeafIdentityHash)]
+			if !ok {
+				return fmt.Errorf("dequeued unexpected leaf with identity hash %x", leaf.LeafIdentityHash)
+			}
+			leaf.LeafIndex = orig.LeafIndex
+			leaf.IntegrateTimestamp = orig.IntegrateTimestamp
+		}
+		if err := tx.UpdateSequencedLeaves(ctx, dequeued); err != nil {
+			return err
+		}
+		if err := tx.SetMerkleNodes(ctx, nodes); err != nil {
+			return err
+		}
+		return tx.StoreSignedLogRoot(ctx, slr)
+	})
+}
+
+// storeRoot stores a log root in its own transaction.
+func (im *importer) storeRoot(ctx context.Context, slr *trillian.SignedLogRoot) error {
+	return im.ls.ReadWriteTransaction(ctx, im.tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, slr)
+	})
+}
+
+// logRoot returns an intermediate log root, timestamped the given number of
+// nanoseconds before the archived root.
+func (im *importer) logRoot(size uint64, hash []byte, before uint64) *trillian.SignedLogRoot {
+	logRoot, err := (&types.LogRootV1{
+		TreeSize:       size,
+		RootHash:       hash,
+		TimestampNanos: im.root.TimestampNanos - before,
+	}).MarshalBinary()
+	if err != nil {
+		// LogRootV1 only fails to marshal oversized hashes and metadata.
+		panic(err)
+	}
+	return &trillian.SignedLogRoot{LogRoot: logRoot}
+}
+
+// verify checks the imported tree against the archive.
+func (im *importer) verify(ctx context.Context) error {
+	tx, err := im.ls.SnapshotForTree(ctx, im.tree)
+	if tx != nil {
+		defer tx.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	slr, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(slr.LogRoot, im.rd.Root().LogRoot) {
+		return errors.New("latest log root differs from the archived root")
+	}
+
+	// Recompute the root hash from the stored nodes.
+	ids := compact.RangeNodes(0, im.root.TreeSize, nil)
+	nodes, err := tx.GetMerkleNodes(ctx, ids)
+	if err != nil {
+		return err
+	}
+	if got, want := len(nodes), len(ids); got != want {
+		return fmt.Errorf("got %d nodes, want %d", got, want)
+	}
+	hashes := make([][]byte, len(nodes))
+	for i, n := range nodes {
+		hashes[i] = n.Hash
+	}
+	cr, err := (&compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}).NewRange(0, im.root.TreeSize, hashes)
+	if err != nil {
+		return err
+	}
+	hash, err := cr.GetRootHash(nil)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(hash, im.root.RootHash) {
+		return fmt.Errorf("stored root hash mismatch: got %x, want %x", hash, im.root.RootHash)
+	}
+
+	if err := im.verifyNodes(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// verifyNodes checks the nodes in the archive, if any, against the stored
+// nodes.
+func (im *importer) verifyNodes(ctx context.Context, tx storage.ReadOnlyLogTreeTX) error {
+	for done := false; !done; {
+		want := make([]tree.Node, 0, im.batchSize)
+		for len(want) < im.batchSize {
+			n, err := im.rd.ReadNode()
+			if err == io.EOF {
+				done = true
+				break
+			} else if err != nil {
+				return err
+			}
+			want = append(want, n)
+		}
+		if len(want) == 0 {
+			break
+		}
+
+		ids := make([]compact.NodeID, len(want))
+		for i, n := range want {
+			ids[i] = n.ID
+		}
+		got, err := tx.GetMerkleNodes(ctx, ids)
+		if err != nil {
+			return err
+		}
+		if len(got) != len(want) {
+			return fmt.Errorf("got %d nodes, want %d", len(got), len(want))
+		}
+		for i := range want {
+			if !bytes.Equal(got[i].Hash, want[i].Hash) {
+				return fmt.Errorf("node %+v has hash %x, want %x", want[i].ID, got[i].Hash, want[i].Hash)
+			}
+		}
+	}
+	return nil
+}
+
+// queueTime returns the queue timestamp of the leaf, or the zero Unix time
+// if it has none.
+func queueTime(leaf *trillian.LogLeaf) time.Time {
+	if leaf.QueueTimestamp == nil {
+		return time.Unix(0, 0)
+	}
+	return leaf.QueueTimestamp.AsTime()
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package blob offloads large leaf values from log storage to a separate,
+// content-addressed blob store, which keeps the SQL databases, and their
+// backups, small for logs of big entries such as container attestations.
+//
+// Leaf values larger than a threshold are written to a Store, keyed by the
+// hex-encoded SHA-256 hash of their contents, and the LeafValue kept in the
+// underlying storage is replaced by a reference to the blob. A reference is
+// the marker "\x00TRILLIAN-BLOB-SHA256\x00" followed by the 32-byte hash. Values
+// which happen to start with the marker are always offloaded, whatever their
+// size, so that every stored value starting with it is a genuine reference.
+//
+// Reads through the wrapped storage resolve references transparently, and
+// check that the blob contents match the hash in the reference.
+//
+// Only the log server needs to wrap its storage: the log signer sequences the
+// queued leaves by their hashes, and stores their references unchanged. Tools
+// which read leaves from the storage directly, such as exporttree, migratetree
+// and fscktree, must be given the blob store to resolve them.
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrNotFound is returned by Store.Get for keys which are not in the store.
+var ErrNotFound = errors.New("blob not found")
+
+var refMarker = []byte("\x00TRILLIAN-BLOB-SHA256\x00")
+
+// Store is a content-addressed blob store.
+type Store interface {
+	// Put stores data under key, which is the hex-encoded SHA-256 hash of
+	// data. Putting a key which already exists is not an error.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the data stored under key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// Key returns the key that data is stored under.
+func Key(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// makeRef returns the reference to the blob holding data.
+func makeRef(data []byte) []byte {
+	h := sha256.Sum256(data)
+	return append(append(make([]byte, 0, len(refMarker)+len(h)), refMarker...), h[:]...)
+}
+
+// parseRef returns the key of the blob referenced by value, and whether value
+// is a reference at all.
+func parseRef(value []byte) (string, bool, error) {
+	if !bytes.HasPrefix(value, refMarker) {
+		return "", false, nil
+	}
+	h := value[len(refMarker):]
+	if len(h) != sha256.Size {
+		return "", true, fmt.Errorf("malformed blob reference of %d bytes", len(value))
+	}
+	return hex.EncodeToString(h), true, nil
+}
+
+// IsRef returns whether the leaf value is a reference to a blob.
+func IsRef(value []byte) bool {
+	return bytes.HasPrefix(value, refMarker)
+}
+
+// Fetch reads the blob with the given key from s and checks its hash.
+func Fetch(ctx context.Context, s Store, key string) ([]byte, error) {
+	data, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", key, err)
+	}
+	if got := Key(data); got != key {
+		return nil, fmt.Errorf("blob %s is corrupt: contents hash to %s", key, got)
+	}
+	return data, nil
+}
+
+// OpenStore returns the Store described by the given URI, which is one of:
+//
+//	file:///path/to/dir
+//	s3://bucket/optional/prefix?region=us-east-1&endpoint=http://host:9000
+//
+// For S3, the region and endpoint query parameters are optional and default
+// to the usual AWS configuration. Setting an endpoint selects path-style
+// addressing, which most S3-compatible services need. Credentials come from
+// the usual AWS environment variables and configuration files.
+func OpenStore(uri string) (Store, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blob store URI %q: %v", uri, err)
+	}
+	switch u.Scheme {
+	case "file":
+		if u.Host != "" {
+			return nil, fmt.Errorf("invalid blob store URI %q: file URIs must not have a host", uri)
+		}
+		return NewFileStore(u.Path)
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid blob store URI %q: missing bucket", uri)
+		}
+		q := u.Query()
+		return NewS3StoreFromConfig(u.Host, u.Path, q.Get("region"), q.Get("endpoint"))
+	default:
+		return nil, fmt.Errorf("unsupported blob store URI %q", uri)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRefRoundTrip(t *testing.T) {
+	data := []byte("a large leaf")
+	key, ok, err := parseRef(makeRef(data))
+	if err != nil || !ok {
+		t.Fatalf("parseRef(makeRef()): %v, %v", ok, err)
+	}
+	if want := Key(data); key != want {
+		t.Errorf("parseRef(makeRef()): key %s, want %s", key, want)
+	}
+	if _, ok, err := parseRef(data); ok || err != nil {
+		t.Errorf("parseRef(%q): %v, %v, want false, nil", data, ok, err)
+	}
+	if _, ok, err := parseRef(append(append([]byte(nil), refMarker...), 1, 2, 3)); !ok || err == nil {
+		t.Errorf("parseRef(truncated): %v, %v, want true, error", ok, err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileStore(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatalf("NewFileStore(): %v", err)
+	}
+	testStore(ctx, t, s)
+
+	if err := s.Put(ctx, "../escape", nil); err == nil {
+		t.Error("Put(../escape): want error")
+	}
+}
+
+// testStore checks the behaviour common to all Store implementations.
+func testStore(ctx context.Context, t *testing.T, s Store) {
+	t.Helper()
+	data := []byte("blob contents")
+	key := Key(data)
+	if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing): %v, want ErrNotFound", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Put(ctx, key, data); err != nil {
+			t.Fatalf("Put(): %v", err)
+		}
+	}
+	got, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get(): %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Get(): %q, want %q", got, data)
+	}
+}
+
+func TestOpenStore(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		uri     string
+		wantErr string
+	}{
+		{uri: "file://" + dir},
+		{uri: "s3://bucket/prefix?region=us-east-1&endpoint=http://localhost:9000"},
+		{uri: "file://host" + dir, wantErr: "must not have a host"},
+		{uri: "s3:///prefix", wantErr: "missing bucket"},
+		{uri: "gs://bucket", wantErr: "unsupported"},
+		{uri: dir, wantErr: "unsupported"},
+	} {
+		t.Run(tc.uri, func(t *testing.T) {
+			_, err := OpenStore(tc.uri)
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("OpenStore(): %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("OpenStore(): %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
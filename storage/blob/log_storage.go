@@ -0,0 +1,232 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"google.golang.org/protobuf/proto"
+)
+
+// NewProvider returns a Provider whose LogStorage offloads leaf values longer
+// than threshold bytes from p to s. Its AdminStorage is that of p.
+func NewProvider(p storage.Provider, s Store, threshold int) storage.Provider {
+	return &provider{Provider: p, store: s, threshold: threshold}
+}
+
+type provider struct {
+	storage.Provider
+	store     Store
+	threshold int
+}
+
+func (p *provider) LogStorage() storage.LogStorage {
+	return NewLogStorage(p.Provider.LogStorage(), p.store, p.threshold)
+}
+
+// NewLogStorage returns a LogStorage which offloads leaf values longer than
+// threshold bytes from ls to s, and resolves them again on reads.
+//
+// Leaves returned by DequeueLeaves are not resolved, and keep the reference in
+// their LeafValue, as sequencing only needs their hashes.
+func NewLogStorage(ls storage.LogStorage, s Store, threshold int) storage.LogStorage {
+	l := &logStorage{LogStorage: ls, store: s, threshold: threshold}
+	if p, ok := ls.(storage.SubtreeRevisionPruner); ok {
+		return &prunableLogStorage{logStorage: l, SubtreeRevisionPruner: p}
+	}
+	return l
+}
+
+// prunableLogStorage is a logStorage which keeps the SubtreeRevisionPruner
+// implementation of the underlying storage, as pruning doesn't touch leaves.
+type prunableLogStorage struct {
+	*logStorage
+	storage.SubtreeRevisionPruner
+}
+
+type logStorage struct {
+	storage.LogStorage
+	store     Store
+	threshold int
+}
+
+// offload writes the values of the large leaves to the blob store, and returns
+// the leaves with the values replaced by references. The input leaves are not
+// modified.
+func (l *logStorage) offload(ctx context.Context, leaves []*trillian.LogLeaf) ([]*trillian.LogLeaf, error) {
+	out, copied := leaves, false
+	for i, leaf := range leaves {
+		if len(leaf.LeafValue) <= l.threshold && !bytes.HasPrefix(leaf.LeafValue, refMarker) {
+			continue
+		}
+		if err := l.store.Put(ctx, Key(leaf.LeafValue), leaf.LeafValue); err != nil {
+			return nil, err
+		}
+		if !copied {
+			out, copied = append([]*trillian.LogLeaf(nil), leaves...), true
+		}
+		c := proto.Clone(leaf).(*trillian.LogLeaf)
+		c.LeafValue = makeRef(leaf.LeafValue)
+		out[i] = c
+	}
+	return out, nil
+}
+
+// restore resolves the leaves returned by QueueLeaves or AddSequencedLeaves for
+// the given input leaves. Leaves that were stored get their input values back,
+// and existing leaves are resolved from the blob store.
+func (l *logStorage) restore(ctx context.Context, ret []*trillian.QueuedLogLeaf, leaves, stored []*trillian.LogLeaf) error {
+	for i, r := range ret {
+		if r == nil || r.Leaf == nil {
+			continue
+		}
+		if i < len(stored) && r.Leaf == stored[i] {
+			if stored[i] != leaves[i] {
+				// Copy the leaf, which may be shared with the underlying
+				// storage, so as to keep any fields it set.
+				leaf := proto.Clone(r.Leaf).(*trillian.LogLeaf)
+				leaf.LeafValue = leaves[i].LeafValue
+				r.Leaf = leaf
+			}
+			continue
+		}
+		leaf, err := resolveLeaf(ctx, l.store, r.Leaf)
+		if err != nil {
+			return err
+		}
+		r.Leaf = leaf
+	}
+	return nil
+}
+
+func (l *logStorage) QueueLeaves(ctx context.Context, tree *trillian.Tree, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
+	stored, err := l.offload(ctx, leaves)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := l.LogStorage.QueueLeaves(ctx, tree, stored, queueTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	return ret, l.restore(ctx, ret, leaves, stored)
+}
+
+func (l *logStorage) AddSequencedLeaves(ctx context.Context, tree *trillian.Tree, leaves []*trillian.LogLeaf, timestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
+	stored, err := l.offload(ctx, leaves)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := l.LogStorage.AddSequencedLeaves(ctx, tree, stored, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	return ret, l.restore(ctx, ret, leaves, stored)
+}
+
+func (l *logStorage) ReadWriteTransaction(ctx context.Context, tree *trillian.Tree, f storage.LogTXFunc) error {
+	return l.LogStorage.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return f(ctx, &logTX{LogTreeTX: tx, store: l.store})
+	})
+}
+
+// Unwrap implements storage.LogStorageWrapper. The tree statistics of the
+// underlying storage don't include the sizes of the leaf values in the blob
+// store.
+func (l *logStorage) Unwrap() storage.LogStorage {
+	return l.LogStorage
+}
+
+func (l *logStorage) SnapshotForTree(ctx context.Context, tree *trillian.Tree) (storage.ReadOnlyLogTreeTX, error) {
+	tx, err := l.LogStorage.SnapshotForTree(ctx, tree)
+	if tx != nil {
+		tx = &readOnlyLogTX{ReadOnlyLogTreeTX: tx, store: l.store}
+	}
+	return tx, err
+}
+
+type readOnlyLogTX struct {
+	storage.ReadOnlyLogTreeTX
+	store Store
+}
+
+func (t *readOnlyLogTX) GetLeavesByRange(ctx context.Context, start, count int64) ([]*trillian.LogLeaf, error) {
+	leaves, err := t.ReadOnlyLogTreeTX.GetLeavesByRange(ctx, start, count)
+	if err != nil {
+		return nil, err
+	}
+	return resolveLeaves(ctx, t.store, leaves)
+}
+
+func (t *readOnlyLogTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, orderBySequence bool) ([]*trillian.LogLeaf, error) {
+	leaves, err := t.ReadOnlyLogTreeTX.GetLeavesByHash(ctx, leafHashes, orderBySequence)
+	if err != nil {
+		return nil, err
+	}
+	return resolveLeaves(ctx, t.store, leaves)
+}
+
+type logTX struct {
+	storage.LogTreeTX
+	store Store
+}
+
+func (t *logTX) GetLeavesByRange(ctx context.Context, start, count int64) ([]*trillian.LogLeaf, error) {
+	leaves, err := t.LogTreeTX.GetLeavesByRange(ctx, start, count)
+	if err != nil {
+		return nil, err
+	}
+	return resolveLeaves(ctx, t.store, leaves)
+}
+
+func (t *logTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, orderBySequence bool) ([]*trillian.LogLeaf, error) {
+	leaves, err := t.LogTreeTX.GetLeavesByHash(ctx, leafHashes, orderBySequence)
+	if err != nil {
+		return nil, err
+	}
+	return resolveLeaves(ctx, t.store, leaves)
+}
+
+// resolveLeaves resolves the references in the given leaves in place.
+func resolveLeaves(ctx context.Context, s Store, leaves []*trillian.LogLeaf) ([]*trillian.LogLeaf, error) {
+	for i, leaf := range leaves {
+		r, err := resolveLeaf(ctx, s, leaf)
+		if err != nil {
+			return nil, err
+		}
+		leaves[i] = r
+	}
+	return leaves, nil
+}
+
+// resolveLeaf returns the leaf with its value read from the blob store, if it
+// holds a reference. The leaf itself is not modified, as it may be shared with
+// the underlying storage.
+func resolveLeaf(ctx context.Context, s Store, leaf *trillian.LogLeaf) (*trillian.LogLeaf, error) {
+	key, ok, err := parseRef(leaf.LeafValue)
+	if err != nil || !ok {
+		return leaf, err
+	}
+	data, err := Fetch(ctx, s, key)
+	if err != nil {
+		return nil, err
+	}
+	c := proto.Clone(leaf).(*trillian.LogLeaf)
+	c.LeafValue = data
+	return c, nil
+}
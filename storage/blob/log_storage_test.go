@@ -0,0 +1,249 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/trillian"
+	"github.com/google/trillian/log"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/bolt"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/types"
+	"github.com/google/trillian/util/clock"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+const threshold = 16
+
+var fakeTime = time.Date(2016, 11, 10, 15, 16, 27, 0, time.UTC)
+
+func init() {
+	log.InitMetrics(nil)
+}
+
+func newLeaf(value string) *trillian.LogLeaf {
+	id := sha256.Sum256([]byte(value))
+	return &trillian.LogLeaf{
+		LeafValue:        []byte(value),
+		LeafIdentityHash: id[:],
+		MerkleLeafHash:   rfc6962.DefaultHasher.HashLeaf([]byte(value)),
+	}
+}
+
+// setup returns a bolt LogStorage with an initialized tree of the given type,
+// the same storage wrapped to offload leaves to the returned FileStore.
+func setup(ctx context.Context, t *testing.T, treeType trillian.TreeType) (*trillian.Tree, storage.LogStorage, storage.LogStorage, *FileStore) {
+	t.Helper()
+	db, err := bolt.OpenDB(filepath.Join(t.TempDir(), "trillian.bolt"))
+	if err != nil {
+		t.Fatalf("OpenDB(): %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ls := bolt.NewLogStorage(db, nil)
+
+	tmpl := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	tmpl.TreeType = treeType
+	tree, err := storage.CreateTree(ctx, bolt.NewAdminStorage(db), tmpl)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	logRoot, err := (&types.LogRootV1{RootHash: rfc6962.DefaultHasher.EmptyRoot(), TimestampNanos: uint64(fakeTime.UnixNano())}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary(): %v", err)
+	}
+	if err := ls.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: logRoot})
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(init): %v", err)
+	}
+
+	bs, err := NewFileStore(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatalf("NewFileStore(): %v", err)
+	}
+	return tree, ls, NewLogStorage(ls, bs, threshold), bs
+}
+
+func readLeaves(ctx context.Context, t *testing.T, ls storage.LogStorage, tree *trillian.Tree, count int64) []*trillian.LogLeaf {
+	t.Helper()
+	tx, err := ls.SnapshotForTree(ctx, tree)
+	if err != nil {
+		t.Fatalf("SnapshotForTree(): %v", err)
+	}
+	defer tx.Close()
+	leaves, err := tx.GetLeavesByRange(ctx, 0, count)
+	if err != nil {
+		t.Fatalf("GetLeavesByRange(): %v", err)
+	}
+	return leaves
+}
+
+func values(leaves []*trillian.LogLeaf) []string {
+	var ret []string
+	for _, l := range leaves {
+		ret = append(ret, string(l.LeafValue))
+	}
+	return ret
+}
+
+func TestQueueLeaves(t *testing.T) {
+	ctx := context.Background()
+	tree, inner, ls, bs := setup(ctx, t, trillian.TreeType_LOG)
+
+	want := []string{"small", "a leaf well above the threshold", string(refMarker) + "lookalike"}
+	leaves := []*trillian.LogLeaf{newLeaf(want[0]), newLeaf(want[1]), newLeaf(want[2])}
+
+	ret, err := ls.QueueLeaves(ctx, tree, leaves, fakeTime)
+	if err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+	if got := values(leaves); !cmp.Equal(got, want) {
+		t.Errorf("QueueLeaves() modified its input to %q", got)
+	}
+	for i, r := range ret {
+		if got := string(r.Leaf.LeafValue); got != want[i] {
+			t.Errorf("QueueLeaves(): leaf %d has value %q, want %q", i, got, want[i])
+		}
+	}
+
+	// Queueing the leaves again returns the existing leaves, resolved.
+	ret, err = ls.QueueLeaves(ctx, tree, []*trillian.LogLeaf{newLeaf(want[1])}, fakeTime)
+	if err != nil {
+		t.Fatalf("QueueLeaves(duplicate): %v", err)
+	}
+	if got, want := codes.Code(ret[0].GetStatus().GetCode()), codes.AlreadyExists; got != want {
+		t.Errorf("QueueLeaves(duplicate): status %v, want %v", got, want)
+	}
+	if got := string(ret[0].Leaf.LeafValue); got != want[1] {
+		t.Errorf("QueueLeaves(duplicate): value %q, want %q", got, want[1])
+	}
+
+	if _, err := log.IntegrateBatch(ctx, tree, 10, 0, 0, clock.NewFake(fakeTime.Add(time.Second)), ls, quota.Noop()); err != nil {
+		t.Fatalf("IntegrateBatch(): %v", err)
+	}
+
+	// Only the small leaf is kept in the underlying storage as is.
+	for _, l := range readLeaves(ctx, t, inner, tree, 3) {
+		v := string(l.LeafValue)
+		switch {
+		case v == want[0]:
+		case v == string(makeRef([]byte(want[1]))), v == string(makeRef([]byte(want[2]))):
+		default:
+			t.Errorf("Stored value %q, want one of the small value or the references", v)
+		}
+	}
+	leaves = readLeaves(ctx, t, ls, tree, 3)
+	if got := values(leaves); !cmp.Equal(sortedCopy(got), sortedCopy(want)) {
+		t.Errorf("GetLeavesByRange(): %q, want %q", got, want)
+	}
+
+	tx, err := ls.SnapshotForTree(ctx, tree)
+	if err != nil {
+		t.Fatalf("SnapshotForTree(): %v", err)
+	}
+	defer tx.Close()
+	byHash, err := tx.GetLeavesByHash(ctx, [][]byte{newLeaf(want[1]).MerkleLeafHash}, false)
+	if err != nil {
+		t.Fatalf("GetLeavesByHash(): %v", err)
+	}
+	if got := values(byHash); !cmp.Equal(got, want[1:2]) {
+		t.Errorf("GetLeavesByHash(): %q, want %q", got, want[1:2])
+	}
+
+	// Corrupting a blob makes reads of the leaf fail.
+	key := Key([]byte(want[1]))
+	if err := writeFile(bs, key, []byte("tampered")); err != nil {
+		t.Fatalf("writeFile(): %v", err)
+	}
+	if _, err := tx.GetLeavesByHash(ctx, [][]byte{newLeaf(want[1]).MerkleLeafHash}, false); err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Errorf("GetLeavesByHash(corrupt): %v, want corruption error", err)
+	}
+}
+
+func TestAddSequencedLeaves(t *testing.T) {
+	ctx := context.Background()
+	tree, inner, ls, _ := setup(ctx, t, trillian.TreeType_PREORDERED_LOG)
+
+	want := []string{"a leaf well above the threshold", "small"}
+	leaves := []*trillian.LogLeaf{newLeaf(want[0]), newLeaf(want[1])}
+	for i, l := range leaves {
+		l.LeafIndex = int64(i)
+	}
+	if _, err := ls.AddSequencedLeaves(ctx, tree, leaves, fakeTime); err != nil {
+		t.Fatalf("AddSequencedLeaves(): %v", err)
+	}
+	if got := string(leaves[0].LeafValue); got != want[0] {
+		t.Errorf("AddSequencedLeaves() modified its input to %q", got)
+	}
+
+	stored := readLeaves(ctx, t, inner, tree, 2)
+	if got, want := values(stored), []string{string(makeRef([]byte(want[0]))), want[1]}; !cmp.Equal(got, want) {
+		t.Errorf("Stored values %q, want %q", got, want)
+	}
+	if got := values(readLeaves(ctx, t, ls, tree, 2)); !cmp.Equal(got, want) {
+		t.Errorf("GetLeavesByRange(): %q, want %q", got, want)
+	}
+}
+
+func writeFile(s *FileStore, key string, data []byte) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p, data, 0o644)
+}
+
+func sortedCopy(s []string) []string {
+	c := append([]string(nil), s...)
+	sort.Strings(c)
+	return c
+}
+
+func TestKeepsSubtreeRevisionPruner(t *testing.T) {
+	ctx := context.Background()
+	_, inner, ls, _ := setup(ctx, t, trillian.TreeType_LOG)
+	if _, ok := inner.(storage.SubtreeRevisionPruner); ok {
+		t.Fatalf("%T unexpectedly implements SubtreeRevisionPruner", inner)
+	}
+	if _, ok := ls.(storage.SubtreeRevisionPruner); ok {
+		t.Errorf("NewLogStorage(%T) implements SubtreeRevisionPruner", inner)
+	}
+
+	p := NewLogStorage(prunerStorage{inner}, nil, threshold)
+	if _, ok := p.(storage.SubtreeRevisionPruner); !ok {
+		t.Errorf("NewLogStorage(%T) doesn't implement SubtreeRevisionPruner", prunerStorage{})
+	}
+}
+
+type prunerStorage struct {
+	storage.LogStorage
+}
+
+func (prunerStorage) PruneSubtreeRevisions(ctx context.Context, treeID int64, keepSince time.Time) (int64, error) {
+	return 0, nil
+}
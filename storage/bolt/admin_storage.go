@@ -0,0 +1,337 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// NewAdminStorage returns a bolt storage.AdminStorage implementation backed by DB.
+func NewAdminStorage(db *bbolt.DB) storage.AdminStorage {
+	return &boltAdminStorage{db}
+}
+
+// boltAdminStorage implements storage.AdminStorage
+type boltAdminStorage struct {
+	db *bbolt.DB
+}
+
+func (s *boltAdminStorage) Snapshot(ctx context.Context) (storage.ReadOnlyAdminTX, error) {
+	return s.beginInternal(ctx, true /* readonly */)
+}
+
+func (s *boltAdminStorage) beginInternal(ctx context.Context, readonly bool) (storage.AdminTX, error) {
+	tx, err := s.db.Begin(!readonly)
+	if err != nil {
+		return nil, err
+	}
+	return &adminTX{tx: tx, trees: tx.Bucket(treesBucket), readonly: readonly}, nil
+}
+
+func (s *boltAdminStorage) ReadWriteTransaction(ctx context.Context, f storage.AdminTXFunc) error {
+	tx, err := s.beginInternal(ctx, false /* readonly */)
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+	if err := f(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *boltAdminStorage) CheckDatabaseAccessible(ctx context.Context) error {
+	return checkDatabaseAccessible(s.db)
+}
+
+// checkDatabaseAccessible returns an error if the buckets of the database
+// can't be read.
+func checkDatabaseAccessible(db *bbolt.DB) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		for _, b := range [][]byte{treesBucket, treeDataBucket, auditEventsBucket} {
+			if tx.Bucket(b) == nil {
+				return fmt.Errorf("bucket %s not found", b)
+			}
+		}
+		return nil
+	})
+}
+
+// treeKey formats the key of the tree with the given ID in the Trees bucket.
+func treeKey(treeID int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(treeID))
+	return b
+}
+
+type adminTX struct {
+	tx       *bbolt.Tx
+	trees    *bbolt.Bucket
+	readonly bool
+
+	// mu guards reads/writes on closed, which happen on Commit/Close methods.
+	//
+	// We don't check closed on methods apart from the ones above, as we trust tx
+	// to keep tabs on its state, and hence fail to do queries after closed.
+	mu     sync.RWMutex
+	closed bool
+}
+
+func (t *adminTX) Commit() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return bbolt.ErrTxClosed
+	}
+	t.closed = true
+	if t.readonly {
+		return t.tx.Rollback()
+	}
+	return t.tx.Commit()
+}
+
+func (t *adminTX) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	return t.tx.Rollback()
+}
+
+func (t *adminTX) GetTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
+	v := t.trees.Get(treeKey(treeID))
+	if v == nil {
+		return nil, status.Errorf(codes.NotFound, "tree %v not found", treeID)
+	}
+	tree := &trillian.Tree{}
+	if err := proto.Unmarshal(v, tree); err != nil {
+		return nil, fmt.Errorf("error reading tree %v: %v", treeID, err)
+	}
+	return tree, nil
+}
+
+func (t *adminTX) ListTrees(ctx context.Context, includeDeleted bool) ([]*trillian.Tree, error) {
+	trees := []*trillian.Tree{}
+	err := t.trees.ForEach(func(_, v []byte) error {
+		tree := &trillian.Tree{}
+		if err := proto.Unmarshal(v, tree); err != nil {
+			return err
+		}
+		if tree.Deleted && !includeDeleted {
+			return nil
+		}
+		trees = append(trees, tree)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return trees, nil
+}
+
+// ListAuditEvents implements storage.AuditLogReader. The events are kept in
+// the AdminAuditEvents bucket, keyed by their IDs like trees are.
+func (t *adminTX) ListAuditEvents(ctx context.Context, opts storage.ListAuditEventsOptions) ([]*trillian.AdminAuditEvent, error) {
+	events := []*trillian.AdminAuditEvent{}
+	c := t.tx.Bucket(auditEventsBucket).Cursor()
+	for k, v := c.Seek(treeKey(opts.AfterEventID + 1)); k != nil; k, v = c.Next() {
+		if opts.Limit > 0 && len(events) == opts.Limit {
+			break
+		}
+		event := &trillian.AdminAuditEvent{}
+		if err := proto.Unmarshal(v, event); err != nil {
+			return nil, fmt.Errorf("error reading audit event %x: %v", k, err)
+		}
+		if opts.TreeID == 0 || event.TreeId == opts.TreeID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// AddAuditEvent implements storage.AuditLogWriter.
+func (t *adminTX) AddAuditEvent(ctx context.Context, event *trillian.AdminAuditEvent) error {
+	b := t.tx.Bucket(auditEventsBucket)
+	id, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+	event.EventId = int64(id)
+	v, err := proto.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.Put(treeKey(event.EventId), v)
+}
+
+// putTree stores the tree, replacing any existing tree with the same ID.
+func (t *adminTX) putTree(tree *trillian.Tree) error {
+	v, err := proto.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return t.trees.Put(treeKey(tree.TreeId), v)
+}
+
+func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillian.Tree, error) {
+	id, err := storage.NewTreeID()
+	if err != nil {
+		return nil, err
+	}
+	return t.CreateTreeWithID(ctx, tree, id)
+}
+
+// CreateTreeWithID implements storage.TreeCreatorWithID.
+func (t *adminTX) CreateTreeWithID(ctx context.Context, tree *trillian.Tree, id int64) (*trillian.Tree, error) {
+	if err := storage.ValidateTreeForCreation(ctx, tree); err != nil {
+		return nil, err
+	}
+	if err := validateStorageSettings(tree); err != nil {
+		return nil, err
+	}
+	if t.trees.Get(treeKey(id)) != nil {
+		return nil, status.Errorf(codes.AlreadyExists, "tree %v already exists", id)
+	}
+
+	// Use the time truncated-to-millis throughout, so that trees read back are
+	// identical to those returned here, as with the SQL implementations.
+	now := storage.FromMillisSinceEpoch(storage.ToMillisSinceEpoch(time.Now()))
+
+	newTree := proto.Clone(tree).(*trillian.Tree)
+	newTree.TreeId = id
+	newTree.CreateTime = timestamppb.New(now)
+	if err := newTree.CreateTime.CheckValid(); err != nil {
+		return nil, fmt.Errorf("failed to build create time: %w", err)
+	}
+	newTree.UpdateTime = timestamppb.New(now)
+	if err := newTree.UpdateTime.CheckValid(); err != nil {
+		return nil, fmt.Errorf("failed to build update time: %w", err)
+	}
+	if err := newTree.MaxRootDuration.CheckValid(); err != nil {
+		return nil, fmt.Errorf("could not parse MaxRootDuration: %w", err)
+	}
+
+	if err := t.putTree(newTree); err != nil {
+		return nil, err
+	}
+	return newTree, nil
+}
+
+func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(*trillian.Tree)) (*trillian.Tree, error) {
+	tree, err := t.GetTree(ctx, treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	beforeUpdate := proto.Clone(tree).(*trillian.Tree)
+	updateFunc(tree)
+	if err := storage.ValidateTreeForUpdate(ctx, beforeUpdate, tree); err != nil {
+		return nil, err
+	}
+	if err := validateStorageSettings(tree); err != nil {
+		return nil, err
+	}
+
+	// TODO(pavelkalinnikov): When switching TreeType from PREORDERED_LOG to LOG,
+	// ensure all entries in SequencedLeafData are integrated.
+
+	now := storage.FromMillisSinceEpoch(storage.ToMillisSinceEpoch(time.Now()))
+	tree.UpdateTime = timestamppb.New(now)
+	if err := tree.MaxRootDuration.CheckValid(); err != nil {
+		return nil, fmt.Errorf("could not parse MaxRootDuration: %w", err)
+	}
+
+	if err := t.putTree(tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func (t *adminTX) SoftDeleteTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
+	return t.updateDeleted(ctx, treeID, true /* deleted */)
+}
+
+func (t *adminTX) UndeleteTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
+	return t.updateDeleted(ctx, treeID, false /* deleted */)
+}
+
+// updateDeleted updates the Deleted and DeleteTime fields of the specified tree.
+func (t *adminTX) updateDeleted(ctx context.Context, treeID int64, deleted bool) (*trillian.Tree, error) {
+	tree, err := t.validateDeleted(ctx, treeID, !deleted)
+	if err != nil {
+		return nil, err
+	}
+	tree.Deleted = deleted
+	tree.DeleteTime = nil
+	if deleted {
+		tree.DeleteTime = timestamppb.New(storage.FromMillisSinceEpoch(storage.ToMillisSinceEpoch(time.Now())))
+	}
+	if err := t.putTree(tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func (t *adminTX) HardDeleteTree(ctx context.Context, treeID int64) error {
+	if _, err := t.validateDeleted(ctx, treeID, true /* wantDeleted */); err != nil {
+		return err
+	}
+
+	// There are no foreign keys to cascade the deletion, so remove all the
+	// data of the tree explicitly.
+	if err := deletePrefix(t.tx.Bucket(treeDataBucket), treeKeyPrefix(treeID)); err != nil {
+		return err
+	}
+	return t.trees.Delete(treeKey(treeID))
+}
+
+// validateDeleted returns the specified tree if its soft deletion status is
+// as wanted, or an error otherwise.
+func (t *adminTX) validateDeleted(ctx context.Context, treeID int64, wantDeleted bool) (*trillian.Tree, error) {
+	tree, err := t.GetTree(ctx, treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch deleted := tree.Deleted; {
+	case wantDeleted && !deleted:
+		return nil, status.Errorf(codes.FailedPrecondition, "tree %v is not soft deleted", treeID)
+	case !wantDeleted && deleted:
+		return nil, status.Errorf(codes.FailedPrecondition, "tree %v already soft deleted", treeID)
+	}
+	return tree, nil
+}
+
+func validateStorageSettings(tree *trillian.Tree) error {
+	if tree.StorageSettings != nil {
+		return fmt.Errorf("storage_settings not supported, but got %v", tree.StorageSettings)
+	}
+	return nil
+}
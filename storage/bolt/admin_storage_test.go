@@ -0,0 +1,155 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+func TestBoltAdminStorage(t *testing.T) {
+	tester := &testonly.AdminStorageTester{NewAdminStorage: func() storage.AdminStorage {
+		cleanTestDB(DB)
+		return NewAdminStorage(DB)
+	}}
+	tester.RunAllTests(t)
+}
+
+func TestCreateTreeInvalidStates(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+
+	states := []trillian.TreeState{trillian.TreeState_DRAINING, trillian.TreeState_FROZEN}
+
+	for _, state := range states {
+		inTree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+		inTree.TreeState = state
+		if _, err := storage.CreateTree(ctx, s, inTree); err == nil {
+			t.Errorf("CreateTree() state: %v got: nil want: err", state)
+		}
+	}
+}
+
+func TestAdminTX_StorageSettingsNotSupported(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+
+	settings, err := anypb.New(&trillian.Tree{})
+	if err != nil {
+		t.Fatalf("Error marshaling proto: %v", err)
+	}
+
+	tests := []struct {
+		desc string
+		// fn attempts to either create or update a tree with a non-nil, valid Any proto
+		// on Tree.StorageSettings. It's expected to return an error.
+		fn func(storage.AdminStorage) error
+	}{
+		{
+			desc: "CreateTree",
+			fn: func(s storage.AdminStorage) error {
+				tree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+				tree.StorageSettings = settings
+				_, err := storage.CreateTree(ctx, s, tree)
+				return err
+			},
+		},
+		{
+			desc: "UpdateTree",
+			fn: func(s storage.AdminStorage) error {
+				tree, err := storage.CreateTree(ctx, s, testonly.LogTree)
+				if err != nil {
+					t.Fatalf("CreateTree() failed with err = %v", err)
+				}
+				_, err = storage.UpdateTree(ctx, s, tree.TreeId, func(tree *trillian.Tree) { tree.StorageSettings = settings })
+				return err
+			},
+		},
+	}
+	for _, test := range tests {
+		if err := test.fn(s); err == nil {
+			t.Errorf("%v: err = nil, want non-nil", test.desc)
+		}
+	}
+}
+
+func TestAdminTX_HardDeleteTree(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+
+	tree, err := storage.CreateTree(ctx, s, testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree() returned err = %v", err)
+	}
+
+	if err := s.ReadWriteTransaction(ctx, func(ctx context.Context, tx storage.AdminTX) error {
+		if _, err := tx.SoftDeleteTree(ctx, tree.TreeId); err != nil {
+			return err
+		}
+		return tx.HardDeleteTree(ctx, tree.TreeId)
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction() returned err = %v", err)
+	}
+
+	// Unlike the HardDelete tests on AdminStorageTester, here we have the chance to poke inside the
+	// database and check that the data is gone, so let's do just that.
+	if err := DB.View(func(tx *bbolt.Tx) error {
+		if v := tx.Bucket(treesBucket).Get(treeKey(tree.TreeId)); v != nil {
+			t.Errorf("Tree %v still present after HardDeleteTree()", tree.TreeId)
+		}
+		prefix := treeKeyPrefix(tree.TreeId)
+		if k, _ := tx.Bucket(treeDataBucket).Cursor().Seek(prefix); k != nil && bytes.HasPrefix(k, prefix) {
+			t.Errorf("Key %q still present after HardDeleteTree()", k)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("View() returned err = %v", err)
+	}
+}
+
+func TestCheckDatabaseAccessible_Fails(t *testing.T) {
+	ctx := context.Background()
+
+	// Pass in a closed database to provoke a failure.
+	db := openTestDBOrDie(t.TempDir())
+	cleanTestDB(db)
+	s := NewAdminStorage(db)
+	db.Close()
+
+	if err := s.CheckDatabaseAccessible(ctx); err == nil {
+		t.Error("TestCheckDatabaseAccessible_Fails got: nil, want: err")
+	}
+}
+
+func TestCheckDatabaseAccessible_OK(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+	if err := s.CheckDatabaseAccessible(ctx); err != nil {
+		t.Errorf("TestCheckDatabaseAccessible_OK got: %v, want: nil", err)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/google/trillian"
+	"go.etcd.io/bbolt"
+)
+
+// ListSignedLogRoots implements storage.LogRootLister.
+func (m *boltLogStorage) ListSignedLogRoots(ctx context.Context, tree *trillian.Tree, after uint64, limit int) ([]*trillian.SignedLogRoot, error) {
+	var ret []*trillian.SignedLogRoot
+	err := m.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(treeDataBucket).Cursor()
+		prefix, from := sthKeyPrefix(tree.TreeId), sthKey(tree.TreeId, after)
+		for k, v := c.Seek(from); k != nil && bytes.HasPrefix(k, prefix) && len(ret) < limit; k, v = c.Next() {
+			if bytes.Equal(k, from) {
+				continue
+			}
+			// The value is the tree revision, followed by the LogRoot.
+			if len(v) < 8 {
+				return fmt.Errorf("malformed tree head for tree %d", tree.TreeId)
+			}
+			ret = append(ret, &trillian.SignedLogRoot{LogRoot: append([]byte{}, v[8:]...)})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
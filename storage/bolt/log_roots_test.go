@@ -0,0 +1,77 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/types"
+)
+
+func TestListSignedLogRoots(t *testing.T) {
+	ctx := context.Background()
+	cleanTestDB(DB)
+	tree := mustCreateTree(ctx, t, NewAdminStorage(DB), testonly.LogTree)
+	s := NewLogStorage(DB, nil)
+
+	var want []*types.LogRootV1
+	for i := uint64(1); i <= 3; i++ {
+		root := &types.LogRootV1{TreeSize: i * 10, RootHash: []byte{byte(i)}, TimestampNanos: i * 100}
+		logRoot, err := root.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary(): %v", err)
+		}
+		if err := s.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+			return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: logRoot})
+		}); err != nil {
+			t.Fatalf("ReadWriteTransaction(): %v", err)
+		}
+		want = append(want, root)
+	}
+
+	for _, tc := range []struct {
+		after uint64
+		limit int
+		want  []*types.LogRootV1
+	}{
+		{after: 0, limit: 10, want: want},
+		{after: 0, limit: 2, want: want[:2]},
+		{after: 100, limit: 10, want: want[1:]},
+		{after: 150, limit: 1, want: want[1:2]},
+		{after: 300, limit: 10, want: nil},
+	} {
+		slrs, err := storage.ListSignedLogRoots(ctx, s, tree, tc.after, tc.limit)
+		if err != nil {
+			t.Fatalf("ListSignedLogRoots(%d, %d): %v", tc.after, tc.limit, err)
+		}
+		if got, want := len(slrs), len(tc.want); got != want {
+			t.Fatalf("ListSignedLogRoots(%d, %d) returned %d roots, want %d", tc.after, tc.limit, got, want)
+		}
+		for i, slr := range slrs {
+			var got types.LogRootV1
+			if err := got.UnmarshalBinary(slr.LogRoot); err != nil {
+				t.Fatalf("UnmarshalBinary(): %v", err)
+			}
+			if w := tc.want[i]; got.TreeSize != w.TreeSize || got.TimestampNanos != w.TimestampNanos {
+				t.Errorf("ListSignedLogRoots(%d, %d)[%d] has size %d and timestamp %d, want %d and %d",
+					tc.after, tc.limit, i, got.TreeSize, got.TimestampNanos, w.TreeSize, w.TimestampNanos)
+			}
+		}
+	}
+}
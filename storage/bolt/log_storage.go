@@ -0,0 +1,654 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/storage/tree"
+	"github.com/google/trillian/types"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"k8s.io/klog/v2"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+const logIDLabel = "logid"
+
+var (
+	once             sync.Once
+	queuedCounter    monitoring.Counter
+	queuedDupCounter monitoring.Counter
+	dequeuedCounter  monitoring.Counter
+)
+
+func createMetrics(mf monitoring.MetricFactory) {
+	queuedCounter = mf.NewCounter("bolt_queued_leaves", "Number of leaves queued", logIDLabel)
+	queuedDupCounter = mf.NewCounter("bolt_queued_dup_leaves", "Number of duplicate leaves queued", logIDLabel)
+	dequeuedCounter = mf.NewCounter("bolt_dequeued_leaves", "Number of leaves dequeued", logIDLabel)
+}
+
+func labelForTX(t *logTreeTX) string {
+	return strconv.FormatInt(t.treeID, 10)
+}
+
+// leafDataKey formats the key of the data of the leaf with the given
+// identity hash, as supplied when it was queued.
+func leafDataKey(treeID int64, leafIdentityHash []byte) []byte {
+	return []byte(fmt.Sprintf("/%d/leaf/%x", treeID, leafIdentityHash))
+}
+
+// seqLeafKey formats the key of the sequencing information of the leaf at the
+// given sequence number.
+func seqLeafKey(treeID, seq int64) []byte {
+	return []byte(fmt.Sprintf("/%d/seq/%020d", treeID, seq))
+}
+
+// hashToSeqKeyPrefix returns the prefix of the keys mapping the given Merkle
+// leaf hash to the sequence numbers of the leaves with that hash.
+func hashToSeqKeyPrefix(treeID int64, merkleLeafHash []byte) []byte {
+	return []byte(fmt.Sprintf("/%d/h2s/%x/", treeID, merkleLeafHash))
+}
+
+// hashToSeqKey formats the key recording that the leaf at the given sequence
+// number has the given Merkle leaf hash. The associated value is empty.
+func hashToSeqKey(treeID int64, merkleLeafHash []byte, seq int64) []byte {
+	return append(hashToSeqKeyPrefix(treeID, merkleLeafHash), fmt.Sprintf("%020d", seq)...)
+}
+
+// unseqKeyPrefix returns the prefix of the keys of the unsequenced leaves.
+func unseqKeyPrefix(treeID int64) []byte {
+	return []byte(fmt.Sprintf("/%d/unseq/", treeID))
+}
+
+// unseqKey formats the key of a leaf awaiting sequencing. The keys sort in
+// the order in which leaves are dequeued.
+func unseqKey(treeID, queueTimestampNanos int64, leafIdentityHash []byte) []byte {
+	return append(unseqKeyPrefix(treeID), fmt.Sprintf("%020d/%x", queueTimestampNanos, leafIdentityHash)...)
+}
+
+// sthKeyPrefix returns the prefix of the keys of the tree heads.
+func sthKeyPrefix(treeID int64) []byte {
+	return []byte(fmt.Sprintf("/%d/sth/", treeID))
+}
+
+// sthKey formats the key of the tree head with the given timestamp. The
+// associated value is the tree revision, followed by the LogRoot.
+func sthKey(treeID int64, timestamp uint64) []byte {
+	return append(sthKeyPrefix(treeID), fmt.Sprintf("%020d", timestamp)...)
+}
+
+type boltLogStorage struct {
+	*boltTreeStorage
+	metricFactory monitoring.MetricFactory
+}
+
+// NewLogStorage creates a storage.LogStorage instance backed by the given bolt database.
+// It assumes storage.AdminStorage is backed by the same bolt database as well.
+func NewLogStorage(db *bbolt.DB, mf monitoring.MetricFactory) storage.LogStorage {
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+	return &boltLogStorage{
+		boltTreeStorage: newTreeStorage(db),
+		metricFactory:   mf,
+	}
+}
+
+func (m *boltLogStorage) CheckDatabaseAccessible(ctx context.Context) error {
+	return checkDatabaseAccessible(m.db)
+}
+
+func (m *boltLogStorage) GetActiveLogIDs(ctx context.Context) ([]int64, error) {
+	ids := []int64{}
+	err := m.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(treesBucket).ForEach(func(_, v []byte) error {
+			var tree trillian.Tree
+			if err := proto.Unmarshal(v, &tree); err != nil {
+				return err
+			}
+			if tree.Deleted {
+				return nil
+			}
+			// Include logs that are DRAINING in the active list as we're still
+			// integrating leaves into them.
+			switch tree.TreeType {
+			case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+				switch tree.TreeState {
+				case trillian.TreeState_ACTIVE, trillian.TreeState_DRAINING:
+					ids = append(ids, tree.TreeId)
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (m *boltLogStorage) beginInternal(ctx context.Context, tree *trillian.Tree, readonly bool) (*logTreeTX, error) {
+	once.Do(func() {
+		createMetrics(m.metricFactory)
+	})
+
+	stCache := cache.NewLogSubtreeCache(rfc6962.DefaultHasher)
+	ttx, err := m.beginTreeTx(ctx, tree, rfc6962.DefaultHasher.Size(), stCache, readonly)
+	if err != nil {
+		return nil, err
+	}
+
+	ltx := &logTreeTX{
+		treeTX:   ttx,
+		ls:       m,
+		dequeued: make(map[string][]byte),
+	}
+	ltx.slr, ltx.readRev, err = ltx.fetchLatestRoot(ctx)
+	if err == storage.ErrTreeNeedsInit {
+		ltx.treeTX.writeRevision = 0
+		return ltx, err
+	} else if err != nil {
+		ttx.Close()
+		return nil, err
+	}
+
+	if err := ltx.root.UnmarshalBinary(ltx.slr.LogRoot); err != nil {
+		ttx.Close()
+		return nil, err
+	}
+
+	ltx.treeTX.writeRevision = ltx.readRev + 1
+	return ltx, nil
+}
+
+func (m *boltLogStorage) ReadWriteTransaction(ctx context.Context, tree *trillian.Tree, f storage.LogTXFunc) error {
+	tx, err := m.beginInternal(ctx, tree, false /* readonly */)
+	if err != nil && err != storage.ErrTreeNeedsInit {
+		return err
+	}
+	defer tx.Close()
+	if err := f(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (m *boltLogStorage) AddSequencedLeaves(ctx context.Context, tree *trillian.Tree, leaves []*trillian.LogLeaf, timestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
+	tx, err := m.beginInternal(ctx, tree, false /* readonly */)
+	if tx != nil {
+		// Ensure we don't leak the transaction. For example if we get an
+		// ErrTreeNeedsInit from beginInternal() or if AddSequencedLeaves fails
+		// below.
+		defer tx.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.AddSequencedLeaves(ctx, leaves, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (m *boltLogStorage) SnapshotForTree(ctx context.Context, tree *trillian.Tree) (storage.ReadOnlyLogTreeTX, error) {
+	tx, err := m.beginInternal(ctx, tree, true /* readonly */)
+	if err != nil && err != storage.ErrTreeNeedsInit {
+		return nil, err
+	}
+	tx.tiles = cache.DefaultTileCache()
+	return tx, err
+}
+
+func (m *boltLogStorage) QueueLeaves(ctx context.Context, tree *trillian.Tree, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
+	tx, err := m.beginInternal(ctx, tree, false /* readonly */)
+	if tx != nil {
+		// Ensure we don't leak the transaction. For example if we get an
+		// ErrTreeNeedsInit from beginInternal() or if QueueLeaves fails
+		// below.
+		defer tx.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	existing, err := tx.QueueLeaves(ctx, leaves, queueTimestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	ret := make([]*trillian.QueuedLogLeaf, len(leaves))
+	for i, e := range existing {
+		if e != nil {
+			ret[i] = &trillian.QueuedLogLeaf{
+				Leaf:   e,
+				Status: status.Newf(codes.AlreadyExists, "leaf already exists: %v", e.LeafIdentityHash).Proto(),
+			}
+			continue
+		}
+		ret[i] = &trillian.QueuedLogLeaf{Leaf: leaves[i]}
+	}
+	return ret, nil
+}
+
+type logTreeTX struct {
+	treeTX
+	ls      *boltLogStorage
+	root    types.LogRootV1
+	readRev int64
+	slr     *trillian.SignedLogRoot
+	// dequeued maps the identity hashes of the leaves dequeued in this
+	// transaction to their keys in the unsequenced queue.
+	dequeued map[string][]byte
+	// tiles, if set, is the process-wide cache of full tiles, which is
+	// shared by read-only snapshots.
+	tiles *cache.TileCache
+}
+
+// GetMerkleNodes returns the requested nodes at the read revision.
+func (t *logTreeTX) GetMerkleNodes(ctx context.Context, ids []compact.NodeID) ([]tree.Node, error) {
+	t.treeTX.mu.Lock()
+	defer t.treeTX.mu.Unlock()
+	getSubtrees := t.getSubtreesAtRev(ctx, t.readRev)
+	if t.tiles != nil {
+		getSubtrees = t.tiles.Wrap(t.treeID, t.readRev, getSubtrees)
+	}
+	return t.subtreeCache.GetNodes(ids, getSubtrees)
+}
+
+func (t *logTreeTX) DequeueLeaves(ctx context.Context, limit int, cutoffTime time.Time) ([]*trillian.LogLeaf, error) {
+	t.treeTX.mu.Lock()
+	defer t.treeTX.mu.Unlock()
+
+	if t.treeType == trillian.TreeType_PREORDERED_LOG {
+		return t.getLeavesByRangeInternal(ctx, int64(t.root.TreeSize), int64(limit))
+	}
+
+	// The queue is ordered by timestamp, so everything from the first key
+	// after the cutoff onwards is too recent.
+	prefix := unseqKeyPrefix(t.treeID)
+	end := unseqKey(t.treeID, cutoffTime.UnixNano()+1, nil)
+	leaves := make([]*trillian.LogLeaf, 0, limit)
+	c := t.data.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.Compare(k, end) < 0 && len(leaves) < limit; k, v = c.Next() {
+		var leaf trillian.LogLeaf
+		if err := proto.Unmarshal(v, &leaf); err != nil {
+			klog.Warningf("Error dequeuing leaf: %v", err)
+			return nil, err
+		}
+		if len(leaf.LeafIdentityHash) != t.hashSizeBytes {
+			return nil, errors.New("dequeued a leaf with incorrect hash size")
+		}
+
+		id := string(leaf.LeafIdentityHash)
+		if _, ok := t.dequeued[id]; ok {
+			// dupe, user probably called DequeueLeaves more than once.
+			continue
+		}
+		t.dequeued[id] = append([]byte{}, k...)
+		leaves = append(leaves, &leaf)
+	}
+
+	dequeuedCounter.Add(float64(len(leaves)), labelForTX(t))
+	return leaves, nil
+}
+
+func (t *logTreeTX) QueueLeaves(ctx context.Context, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.LogLeaf, error) {
+	t.treeTX.mu.Lock()
+	defer t.treeTX.mu.Unlock()
+
+	// Don't accept batches if any of the leaves are invalid.
+	for _, leaf := range leaves {
+		if len(leaf.LeafIdentityHash) != t.hashSizeBytes {
+			return nil, fmt.Errorf("queued leaf must have a leaf ID hash of length %d", t.hashSizeBytes)
+		}
+		leaf.QueueTimestamp = timestamppb.New(queueTimestamp)
+		if err := leaf.QueueTimestamp.CheckValid(); err != nil {
+			return nil, fmt.Errorf("got invalid queue timestamp: %w", err)
+		}
+	}
+	label := labelForTX(t)
+
+	existingLeaves := make([]*trillian.LogLeaf, len(leaves))
+	for i, leaf := range leaves {
+		existing, err := t.getLeafData(leaf.LeafIdentityHash)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			existingLeaves[i] = existing
+			queuedDupCounter.Inc(label)
+			continue
+		}
+
+		if err := t.putLeafData(leaf); err != nil {
+			klog.Warningf("Error inserting %d into leaf data: %s", i, err)
+			return nil, err
+		}
+		entry, err := proto.Marshal(&trillian.LogLeaf{
+			LeafIdentityHash: leaf.LeafIdentityHash,
+			MerkleLeafHash:   leaf.MerkleLeafHash,
+			QueueTimestamp:   leaf.QueueTimestamp,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := t.data.Put(unseqKey(t.treeID, queueTimestamp.UnixNano(), leaf.LeafIdentityHash), entry); err != nil {
+			klog.Warningf("Error inserting into unsequenced queue: %s", err)
+			return nil, err
+		}
+	}
+	queuedCounter.Add(float64(len(leaves)), label)
+
+	return existingLeaves, nil
+}
+
+func (t *logTreeTX) AddSequencedLeaves(ctx context.Context, leaves []*trillian.LogLeaf, timestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
+	t.treeTX.mu.Lock()
+	defer t.treeTX.mu.Unlock()
+
+	res := make([]*trillian.QueuedLogLeaf, len(leaves))
+	ok := status.New(codes.OK, "OK").Proto()
+
+	for i, leaf := range leaves {
+		// This should fail on insert, but catch it early.
+		if got, want := len(leaf.LeafIdentityHash), t.hashSizeBytes; got != want {
+			return nil, status.Errorf(codes.FailedPrecondition, "leaves[%d] has incorrect hash size %d, want %d", i, got, want)
+		}
+		res[i] = &trillian.QueuedLogLeaf{Status: ok}
+
+		// Both conflicts are checked before writing anything, so that there
+		// are no side effects to undo.
+		if t.data.Get(leafDataKey(t.treeID, leaf.LeafIdentityHash)) != nil {
+			res[i].Status = status.New(codes.FailedPrecondition, "conflicting LeafIdentityHash").Proto()
+			continue
+		}
+		if t.data.Get(seqLeafKey(t.treeID, leaf.LeafIndex)) != nil {
+			res[i].Status = status.New(codes.FailedPrecondition, "conflicting LeafIndex").Proto()
+			continue
+		}
+
+		data := proto.Clone(leaf).(*trillian.LogLeaf)
+		data.QueueTimestamp = timestamppb.New(timestamp)
+		if err := t.putLeafData(data); err != nil {
+			klog.Errorf("Error inserting leaves[%d] into leaf data: %s", i, err)
+			return nil, err
+		}
+		// TODO(pavelkalinnikov): Update IntegrateTimestamp on integrating the leaf.
+		data.IntegrateTimestamp = timestamppb.New(time.Unix(0, 0))
+		if err := t.putSequencedLeaf(data); err != nil {
+			klog.Errorf("Error inserting leaves[%d] into sequenced leaves: %s", i, err)
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func (t *logTreeTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillian.LogLeaf) error {
+	t.treeTX.mu.Lock()
+	defer t.treeTX.mu.Unlock()
+
+	for _, leaf := range leaves {
+		// This should fail on insert but catch it early
+		if len(leaf.LeafIdentityHash) != t.hashSizeBytes {
+			return errors.New("sequenced leaf has incorrect hash size")
+		}
+		if err := leaf.IntegrateTimestamp.CheckValid(); err != nil {
+			return fmt.Errorf("got invalid integrate timestamp: %w", err)
+		}
+		if t.data.Get(seqLeafKey(t.treeID, leaf.LeafIndex)) != nil {
+			return fmt.Errorf("leaf index %d is already sequenced", leaf.LeafIndex)
+		}
+		if err := t.putSequencedLeaf(leaf); err != nil {
+			klog.Warningf("Failed to update sequenced leaves: %s", err)
+			return err
+		}
+
+		k, ok := t.dequeued[string(leaf.LeafIdentityHash)]
+		if !ok {
+			return fmt.Errorf("attempting to update leaf that wasn't dequeued. IdentityHash: %x", leaf.LeafIdentityHash)
+		}
+		if err := t.data.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putLeafData stores the data of the leaf, as supplied to QueueLeaves or
+// AddSequencedLeaves.
+func (t *logTreeTX) putLeafData(leaf *trillian.LogLeaf) error {
+	data, err := proto.Marshal(&trillian.LogLeaf{
+		LeafIdentityHash: leaf.LeafIdentityHash,
+		LeafValue:        leaf.LeafValue,
+		ExtraData:        leaf.ExtraData,
+		QueueTimestamp:   leaf.QueueTimestamp,
+	})
+	if err != nil {
+		return err
+	}
+	return t.data.Put(leafDataKey(t.treeID, leaf.LeafIdentityHash), data)
+}
+
+// putSequencedLeaf records the sequence number of the leaf, whose data must
+// have been stored already.
+func (t *logTreeTX) putSequencedLeaf(leaf *trillian.LogLeaf) error {
+	seq, err := proto.Marshal(&trillian.LogLeaf{
+		LeafIdentityHash:   leaf.LeafIdentityHash,
+		MerkleLeafHash:     leaf.MerkleLeafHash,
+		LeafIndex:          leaf.LeafIndex,
+		IntegrateTimestamp: leaf.IntegrateTimestamp,
+	})
+	if err != nil {
+		return err
+	}
+	if err := t.data.Put(seqLeafKey(t.treeID, leaf.LeafIndex), seq); err != nil {
+		return err
+	}
+	return t.data.Put(hashToSeqKey(t.treeID, leaf.MerkleLeafHash, leaf.LeafIndex), []byte{})
+}
+
+// getLeafData returns the data of the leaf with the given identity hash, or
+// nil if there is no such leaf. Note that the returned LogLeaf will not have
+// a valid MerkleLeafHash, LeafIndex, or IntegrateTimestamp.
+func (t *logTreeTX) getLeafData(leafIdentityHash []byte) (*trillian.LogLeaf, error) {
+	v := t.data.Get(leafDataKey(t.treeID, leafIdentityHash))
+	if v == nil {
+		return nil, nil
+	}
+	var leaf trillian.LogLeaf
+	if err := proto.Unmarshal(v, &leaf); err != nil {
+		return nil, err
+	}
+	return &leaf, nil
+}
+
+// getSequencedLeaf returns the leaf at the given sequence number, or nil if
+// there is no such leaf.
+func (t *logTreeTX) getSequencedLeaf(seq int64) (*trillian.LogLeaf, error) {
+	v := t.data.Get(seqLeafKey(t.treeID, seq))
+	if v == nil {
+		return nil, nil
+	}
+	return t.joinLeafData(v)
+}
+
+// joinLeafData combines the sequencing information of a leaf with its data.
+func (t *logTreeTX) joinLeafData(seqValue []byte) (*trillian.LogLeaf, error) {
+	var seq trillian.LogLeaf
+	if err := proto.Unmarshal(seqValue, &seq); err != nil {
+		return nil, err
+	}
+	leaf, err := t.getLeafData(seq.LeafIdentityHash)
+	if err != nil {
+		return nil, err
+	}
+	if leaf == nil {
+		return nil, fmt.Errorf("missing data for leaf %d with identity hash %x", seq.LeafIndex, seq.LeafIdentityHash)
+	}
+	leaf.MerkleLeafHash = seq.MerkleLeafHash
+	leaf.LeafIndex = seq.LeafIndex
+	leaf.IntegrateTimestamp = seq.IntegrateTimestamp
+	return leaf, nil
+}
+
+func (t *logTreeTX) GetLeavesByRange(ctx context.Context, start, count int64) ([]*trillian.LogLeaf, error) {
+	t.treeTX.mu.Lock()
+	defer t.treeTX.mu.Unlock()
+	return t.getLeavesByRangeInternal(ctx, start, count)
+}
+
+func (t *logTreeTX) getLeavesByRangeInternal(ctx context.Context, start, count int64) ([]*trillian.LogLeaf, error) {
+	if count <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid count %d, want > 0", count)
+	}
+	if start < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid start %d, want >= 0", start)
+	}
+
+	if t.treeType == trillian.TreeType_LOG {
+		treeSize := int64(t.root.TreeSize)
+		if treeSize <= 0 {
+			return nil, status.Errorf(codes.OutOfRange, "empty tree")
+		} else if start >= treeSize {
+			return nil, status.Errorf(codes.OutOfRange, "invalid start %d, want < TreeSize(%d)", start, treeSize)
+		}
+		// Ensure no entries queried/returned beyond the tree.
+		if maxCount := treeSize - start; count > maxCount {
+			count = maxCount
+		}
+	}
+
+	ret := make([]*trillian.LogLeaf, 0, count)
+	for index := start; index < start+count; index++ {
+		leaf, err := t.getSequencedLeaf(index)
+		if err != nil {
+			klog.Warningf("Failed to get leaves by range: %s", err)
+			return nil, err
+		}
+		if leaf == nil {
+			if index < int64(t.root.TreeSize) {
+				return nil, fmt.Errorf("missing leaf %d in tree of size %d", index, t.root.TreeSize)
+			}
+			break
+		}
+		ret = append(ret, leaf)
+	}
+	return ret, nil
+}
+
+func (t *logTreeTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, orderBySequence bool) ([]*trillian.LogLeaf, error) {
+	t.treeTX.mu.Lock()
+	defer t.treeTX.mu.Unlock()
+
+	var ret []*trillian.LogLeaf
+	c := t.data.Cursor()
+	for _, hash := range leafHashes {
+		// The tree could include duplicates, so there may be any number of
+		// leaves with each hash.
+		prefix := hashToSeqKeyPrefix(t.treeID, hash)
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			seq, err := strconv.ParseInt(string(k[len(prefix):]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed key %q: %v", k, err)
+			}
+			leaf, err := t.getSequencedLeaf(seq)
+			if err != nil {
+				return nil, err
+			}
+			if leaf == nil {
+				return nil, fmt.Errorf("missing leaf %d with Merkle leaf hash %x", seq, hash)
+			}
+			ret = append(ret, leaf)
+		}
+	}
+	if orderBySequence {
+		sort.Slice(ret, func(i, j int) bool { return ret[i].LeafIndex < ret[j].LeafIndex })
+	}
+	return ret, nil
+}
+
+func (t *logTreeTX) LatestSignedLogRoot(ctx context.Context) (*trillian.SignedLogRoot, error) {
+	t.treeTX.mu.Lock()
+	defer t.treeTX.mu.Unlock()
+
+	if t.slr == nil {
+		return nil, storage.ErrTreeNeedsInit
+	}
+
+	return t.slr, nil
+}
+
+// fetchLatestRoot reads the latest root and the revision from the DB.
+func (t *logTreeTX) fetchLatestRoot(ctx context.Context) (*trillian.SignedLogRoot, int64, error) {
+	_, v := lastWithPrefix(t.data.Cursor(), sthKeyPrefix(t.treeID))
+	if v == nil {
+		// It's possible there are no roots for this tree yet
+		return nil, 0, storage.ErrTreeNeedsInit
+	}
+	if len(v) < 8 {
+		return nil, 0, fmt.Errorf("malformed tree head for tree %d", t.treeID)
+	}
+	rev := int64(binary.BigEndian.Uint64(v[:8]))
+	logRoot := append([]byte{}, v[8:]...)
+	return &trillian.SignedLogRoot{LogRoot: logRoot}, rev, nil
+}
+
+func (t *logTreeTX) StoreSignedLogRoot(ctx context.Context, root *trillian.SignedLogRoot) error {
+	t.treeTX.mu.Lock()
+	defer t.treeTX.mu.Unlock()
+
+	var logRoot types.LogRootV1
+	if err := logRoot.UnmarshalBinary(root.LogRoot); err != nil {
+		klog.Warningf("Failed to parse log root: %x %v", root.LogRoot, err)
+		return err
+	}
+
+	k := sthKey(t.treeID, logRoot.TimestampNanos)
+	if t.data.Get(k) != nil {
+		return fmt.Errorf("tree head with timestamp %d already exists", logRoot.TimestampNanos)
+	}
+	v := append(encodeRevision(t.treeTX.writeRevision), root.LogRoot...)
+	if err := t.data.Put(k, v); err != nil {
+		klog.Warningf("Failed to store signed root: %s", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"flag"
+	"sync"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"k8s.io/klog/v2"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+const (
+	// StorageProviderName is the name of the storage provider.
+	StorageProviderName = "bolt"
+)
+
+var (
+	boltPath = flag.String("bolt_path", "trillian.bolt", "Path of the bolt database file, which is created if it does not exist")
+
+	boltMu              sync.Mutex
+	boltErr             error
+	boltDB              *bbolt.DB
+	boltStorageInstance *boltProvider
+)
+
+// GetDatabase returns an instance of the bolt database, or opens one.
+func GetDatabase() (*bbolt.DB, error) {
+	boltMu.Lock()
+	defer boltMu.Unlock()
+	return getBoltDatabaseLocked()
+}
+
+func init() {
+	if err := storage.RegisterProvider(StorageProviderName, newBoltStorageProvider); err != nil {
+		klog.Fatalf("Failed to register storage provider bolt: %v", err)
+	}
+}
+
+type boltProvider struct {
+	db *bbolt.DB
+	mf monitoring.MetricFactory
+}
+
+func newBoltStorageProvider(mf monitoring.MetricFactory) (storage.Provider, error) {
+	boltMu.Lock()
+	defer boltMu.Unlock()
+	if boltStorageInstance == nil {
+		db, err := getBoltDatabaseLocked()
+		if err != nil {
+			return nil, err
+		}
+		boltStorageInstance = &boltProvider{
+			db: db,
+			mf: mf,
+		}
+	}
+	return boltStorageInstance, nil
+}
+
+// getBoltDatabaseLocked returns an instance of the bolt database, or opens
+// one. Requires boltMu to be locked.
+func getBoltDatabaseLocked() (*bbolt.DB, error) {
+	if boltDB != nil || boltErr != nil {
+		return boltDB, boltErr
+	}
+	db, err := OpenDB(*boltPath)
+	if err != nil {
+		boltErr = err
+		return nil, err
+	}
+	boltDB, boltErr = db, nil
+	return db, nil
+}
+
+func (s *boltProvider) LogStorage() storage.LogStorage {
+	return NewLogStorage(s.db, s.mf)
+}
+
+func (s *boltProvider) AdminStorage() storage.AdminStorage {
+	return NewAdminStorage(s.db)
+}
+
+func (s *boltProvider) Close() error {
+	return s.db.Close()
+}
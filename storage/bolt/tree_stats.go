@@ -0,0 +1,110 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+)
+
+// GetTreeStats implements storage.TreeStatsReader.
+func (m *boltLogStorage) GetTreeStats(ctx context.Context, tree *trillian.Tree) (*storage.TreeStats, error) {
+	treeID := tree.TreeId
+	var stats storage.TreeStats
+	err := m.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(treeDataBucket).Cursor()
+		if err := forEachWithPrefix(c, []byte(fmt.Sprintf("/%d/seq/", treeID)), func(_, _ []byte) error {
+			stats.LeafCount++
+			return nil
+		}); err != nil {
+			return err
+		}
+		if err := forEachWithPrefix(c, unseqKeyPrefix(treeID), func(k, _ []byte) error {
+			if stats.QueueDepth == 0 {
+				// The keys sort by queue timestamp.
+				ts, err := keyTimestamp(k, unseqKeyPrefix(treeID))
+				if err != nil {
+					return err
+				}
+				stats.OldestQueued = ts
+			}
+			stats.QueueDepth++
+			return nil
+		}); err != nil {
+			return err
+		}
+		if err := forEachWithPrefix(c, []byte(fmt.Sprintf("/%d/leaf/", treeID)), func(_, v []byte) error {
+			var leaf trillian.LogLeaf
+			if err := proto.Unmarshal(v, &leaf); err != nil {
+				return err
+			}
+			stats.LeafBytes += int64(len(leaf.LeafValue) + len(leaf.ExtraData))
+			return nil
+		}); err != nil {
+			return err
+		}
+		if err := forEachWithPrefix(c, []byte(fmt.Sprintf("/%d/subtree/", treeID)), func(_, v []byte) error {
+			stats.TileBytes += int64(len(v))
+			return nil
+		}); err != nil {
+			return err
+		}
+		if k, _ := lastWithPrefix(c, sthKeyPrefix(treeID)); k != nil {
+			ts, err := keyTimestamp(k, sthKeyPrefix(treeID))
+			if err != nil {
+				return err
+			}
+			stats.LatestRoot = ts
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// forEachWithPrefix calls f with each key with the given prefix and its value,
+// in key order, until f returns an error.
+func forEachWithPrefix(c *bbolt.Cursor, prefix []byte, f func(k, v []byte) error) error {
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if err := f(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyTimestamp returns the timestamp in nanoseconds which follows the prefix
+// in the key.
+func keyTimestamp(k, prefix []byte) (time.Time, error) {
+	s := string(k[len(prefix):])
+	if i := bytes.IndexByte(k[len(prefix):], '/'); i >= 0 {
+		s = s[:i]
+	}
+	nanos, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed key %q: %v", k, err)
+	}
+	return time.Unix(0, nanos), nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestGetTreeStats(t *testing.T) {
+	ctx := context.Background()
+
+	cleanTestDB(DB)
+	as := NewAdminStorage(DB)
+	tree := mustCreateTree(ctx, t, as, testonly.LogTree)
+	s := NewLogStorage(DB, nil)
+	mustSignAndStoreLogRoot(ctx, t, s, tree, 0)
+
+	stats, err := s.(storage.TreeStatsReader).GetTreeStats(ctx, tree)
+	if err != nil {
+		t.Fatalf("GetTreeStats(): %v", err)
+	}
+	if want := (storage.TreeStats{LatestRoot: time.Unix(0, 0)}); *stats != want {
+		t.Errorf("GetTreeStats() = %+v, want %+v", stats, want)
+	}
+
+	if _, err := s.QueueLeaves(ctx, tree, createTestLeaves(3, 0), fakeQueueTime); err != nil {
+		t.Fatalf("Failed to queue leaves: %v", err)
+	}
+	runLogTX(s, tree, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		dequeued, err := tx.DequeueLeaves(ctx, 2, fakeQueueTime)
+		if err != nil {
+			t.Fatalf("DequeueLeaves(): %v", err)
+		}
+		for i, l := range dequeued {
+			l.IntegrateTimestamp = timestamppb.Now()
+			l.LeafIndex = int64(i)
+		}
+		if err := tx.UpdateSequencedLeaves(ctx, dequeued); err != nil {
+			t.Fatalf("UpdateSequencedLeaves(): %v", err)
+		}
+		return nil
+	})
+
+	stats, err = s.(storage.TreeStatsReader).GetTreeStats(ctx, tree)
+	if err != nil {
+		t.Fatalf("GetTreeStats(): %v", err)
+	}
+	want := storage.TreeStats{
+		LeafCount:    2,
+		QueueDepth:   1,
+		OldestQueued: fakeQueueTime,
+		LatestRoot:   time.Unix(0, 0),
+		// Each of the leaves has a 6 byte value and 7 bytes of extra data.
+		LeafBytes: 3 * 13,
+	}
+	if stats.LeafCount != want.LeafCount || stats.QueueDepth != want.QueueDepth || !stats.OldestQueued.Equal(want.OldestQueued) ||
+		!stats.LatestRoot.Equal(want.LatestRoot) || stats.LeafBytes != want.LeafBytes {
+		t.Errorf("GetTreeStats() = %+v, want %+v", stats, want)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bolt provides a storage layer implementation backed by an embedded
+// bbolt key-value store, for single-node deployments that need durability.
+//
+// All data lives in a single database file. Trees are stored in the Trees
+// bucket, keyed by tree ID, and everything else is stored in the TreeData
+// bucket under per-tree key prefixes, in the same fashion as the keys of the
+// memory storage's BTree:
+//
+//	/<treeID>/subtree/<prefix>/<revision>     SubtreeProto
+//	/<treeID>/leaf/<leafIdentityHash>         LogLeaf data, as queued
+//	/<treeID>/seq/<sequenceNumber>            LogLeaf sequencing info
+//	/<treeID>/h2s/<merkleLeafHash>/<sequence> (empty)
+//	/<treeID>/unseq/<queueTimestamp>/<hash>   LogLeaf awaiting sequencing
+//	/<treeID>/sth/<timestamp>                 revision and LogRoot
+//
+// Hashes are hex-encoded and numbers are zero-padded decimals, so that keys
+// sort in their natural order. Every storage transaction maps to a bbolt
+// transaction, which makes commits atomic and crash-safe, and gives read-only
+// transactions a consistent snapshot of the database.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/storage/storagepb"
+	"github.com/google/trillian/storage/tree"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/klog/v2"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// openTimeout is how long OpenDB waits for the lock on the database file,
+// which is held by any other process using it.
+const openTimeout = 5 * time.Second
+
+// initialMmapSize is the initial size of the memory map of the database file.
+// bbolt can't grow the map while read-only transactions are open, so this is
+// set generously to avoid writers blocking on long-lived snapshots. It only
+// reserves address space and doesn't affect the size of the file.
+const initialMmapSize = 1 << 30
+
+var (
+	treesBucket       = []byte("Trees")
+	treeDataBucket    = []byte("TreeData")
+	auditEventsBucket = []byte("AdminAuditEvents")
+)
+
+// OpenDB opens the bolt database at the specified path, creating it and its
+// buckets if they do not exist.
+func OpenDB(path string) (*bbolt.DB, error) {
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: openTimeout, InitialMmapSize: initialMmapSize})
+	if err != nil {
+		klog.Warningf("Could not open bolt database, check config: %s", err)
+		return nil, err
+	}
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		for _, b := range [][]byte{treesBucket, treeDataBucket, auditEventsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		klog.Warningf("Failed to create buckets in bolt database: %s", err)
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// treeKeyPrefix returns the prefix of all the keys of a tree in the TreeData
+// bucket.
+func treeKeyPrefix(treeID int64) []byte {
+	return []byte(fmt.Sprintf("/%d/", treeID))
+}
+
+// subtreeKeyPrefix returns the prefix of the keys of all revisions of the
+// subtree with the given prefix.
+func subtreeKeyPrefix(treeID int64, prefix []byte) []byte {
+	return []byte(fmt.Sprintf("/%d/subtree/%x/", treeID, prefix))
+}
+
+// subtreeKey formats the key of the SubtreeProto with the given prefix, as
+// written at the given revision.
+func subtreeKey(treeID, rev int64, prefix []byte) []byte {
+	return append(subtreeKeyPrefix(treeID, prefix), fmt.Sprintf("%020d", rev)...)
+}
+
+// deletePrefix deletes all the keys with the given prefix from b.
+func deletePrefix(b *bbolt.Bucket, prefix []byte) error {
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lastBefore returns the greatest key with the given prefix which sorts
+// before limit, and its value, or nil if there are no such keys.
+func lastBefore(c *bbolt.Cursor, limit, prefix []byte) ([]byte, []byte) {
+	k, v := c.Seek(limit)
+	if k == nil {
+		k, v = c.Last()
+	} else {
+		k, v = c.Prev()
+	}
+	if k == nil || !bytes.HasPrefix(k, prefix) {
+		return nil, nil
+	}
+	return k, v
+}
+
+// lastWithPrefix returns the greatest key with the given prefix, and its
+// value, or nil if there are no such keys.
+func lastWithPrefix(c *bbolt.Cursor, prefix []byte) ([]byte, []byte) {
+	// All the key components are hex or decimal digits, which sort before
+	// 0xff, so the prefix followed by it is past all the keys with the prefix.
+	return lastBefore(c, append(append([]byte{}, prefix...), 0xff), prefix)
+}
+
+func encodeRevision(rev int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(rev))
+	return b
+}
+
+type boltTreeStorage struct {
+	db *bbolt.DB
+}
+
+func newTreeStorage(db *bbolt.DB) *boltTreeStorage {
+	return &boltTreeStorage{db: db}
+}
+
+func (m *boltTreeStorage) beginTreeTx(ctx context.Context, tree *trillian.Tree, hashSizeBytes int, subtreeCache *cache.SubtreeCache, readonly bool) (treeTX, error) {
+	t, err := m.db.Begin(!readonly)
+	if err != nil {
+		klog.Warningf("Could not start tree TX: %s", err)
+		return treeTX{}, err
+	}
+	return treeTX{
+		tx:            t,
+		data:          t.Bucket(treeDataBucket),
+		mu:            &sync.Mutex{},
+		ts:            m,
+		treeID:        tree.TreeId,
+		treeType:      tree.TreeType,
+		hashSizeBytes: hashSizeBytes,
+		subtreeCache:  subtreeCache,
+		writeRevision: -1,
+	}, nil
+}
+
+type treeTX struct {
+	// mu ensures that tx can only be used for one operation at a time, as
+	// bbolt transactions are not safe for concurrent use.
+	mu            *sync.Mutex
+	closed        bool
+	tx            *bbolt.Tx
+	data          *bbolt.Bucket
+	ts            *boltTreeStorage
+	treeID        int64
+	treeType      trillian.TreeType
+	hashSizeBytes int
+	subtreeCache  *cache.SubtreeCache
+	writeRevision int64
+}
+
+func (t *treeTX) getSubtrees(ctx context.Context, treeRevision int64, ids [][]byte) ([]*storagepb.SubtreeProto, error) {
+	klog.V(2).Infof("getSubtrees(len(ids)=%d)", len(ids))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	ret := make([]*storagepb.SubtreeProto, 0, len(ids))
+	c := t.data.Cursor()
+	for _, id := range ids {
+		// Look for the latest revision of the subtree at or below treeRevision.
+		_, v := lastBefore(c, subtreeKey(t.treeID, treeRevision+1, id), subtreeKeyPrefix(t.treeID, id))
+		if v == nil {
+			continue
+		}
+		var subtree storagepb.SubtreeProto
+		if err := proto.Unmarshal(v, &subtree); err != nil {
+			klog.Warningf("Failed to unmarshal SubtreeProto: %s", err)
+			return nil, err
+		}
+		if subtree.Prefix == nil {
+			subtree.Prefix = []byte{}
+		}
+		ret = append(ret, &subtree)
+	}
+
+	// The InternalNodes cache is possibly nil here, but the SubtreeCache (which called
+	// this method) will re-populate it.
+	return ret, nil
+}
+
+func (t *treeTX) storeSubtrees(ctx context.Context, subtrees []*storagepb.SubtreeProto) error {
+	klog.V(2).Infof("storeSubtrees(len(subtrees)=%d)", len(subtrees))
+	for _, s := range subtrees {
+		if s.Prefix == nil {
+			panic(fmt.Errorf("nil prefix on %v", s))
+		}
+		subtreeBytes, err := proto.Marshal(s)
+		if err != nil {
+			return err
+		}
+		if err := t.data.Put(subtreeKey(t.treeID, t.writeRevision, s.Prefix), subtreeBytes); err != nil {
+			klog.Warningf("Failed to set merkle subtrees: %s", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// getSubtreesAtRev returns a GetSubtreesFunc which reads at the passed in rev.
+func (t *treeTX) getSubtreesAtRev(ctx context.Context, rev int64) cache.GetSubtreesFunc {
+	return func(ids [][]byte) ([]*storagepb.SubtreeProto, error) {
+		return t.getSubtrees(ctx, rev, ids)
+	}
+}
+
+func (t *treeTX) SetMerkleNodes(ctx context.Context, nodes []tree.Node) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rev := t.writeRevision - 1
+	return t.subtreeCache.SetNodes(nodes, t.getSubtreesAtRev(ctx, rev))
+}
+
+func (t *treeTX) Commit(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return bbolt.ErrTxClosed
+	}
+	t.closed = true
+	if !t.tx.Writable() {
+		return t.tx.Rollback()
+	}
+	if t.writeRevision > -1 {
+		tiles, err := t.subtreeCache.UpdatedTiles()
+		if err != nil {
+			klog.Warningf("SubtreeCache updated tiles error: %v", err)
+			t.tx.Rollback()
+			return err
+		}
+		if err := t.storeSubtrees(ctx, tiles); err != nil {
+			klog.Warningf("TX commit flush error: %v", err)
+			t.tx.Rollback()
+			return err
+		}
+	}
+	if err := t.tx.Commit(); err != nil {
+		klog.Warningf("TX commit error: %s", err)
+		return err
+	}
+	return nil
+}
+
+func (t *treeTX) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	if err := t.tx.Rollback(); err != nil {
+		klog.Warningf("Rollback error on Close(): %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage/storagepb"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	defaultTilesMu sync.RWMutex
+	defaultTiles   *TileCache
+)
+
+// SetDefaultTileCache sets the TileCache shared by the read-only snapshots of
+// the storage providers which support it. A nil cache, which is the default,
+// disables the sharing.
+func SetDefaultTileCache(c *TileCache) {
+	defaultTilesMu.Lock()
+	defer defaultTilesMu.Unlock()
+	defaultTiles = c
+}
+
+// DefaultTileCache returns the TileCache set with SetDefaultTileCache, if any.
+func DefaultTileCache() *TileCache {
+	defaultTilesMu.RLock()
+	defer defaultTilesMu.RUnlock()
+	return defaultTiles
+}
+
+// TileCache is a bounded LRU cache of full log tiles, which can be shared by
+// the transactions of a process, unlike SubtreeCache which lives for one
+// transaction only.
+//
+// Only full tiles are cached, i.e. those with all of their leaves set, since
+// they never change once the tree has grown past them. The partial tiles on the
+// right edge of the tree are always read from storage. Each cached tile records
+// the earliest tree revision it was read as full at, and is only served to
+// transactions reading at that revision or later, as earlier revisions may
+// have a partial version of it.
+//
+// TileCache is safe for concurrent use.
+type TileCache struct {
+	size int
+
+	mu      sync.Mutex
+	lru     *list.List // Of *tileEntry, most recently used first.
+	entries map[tileKey]*list.Element
+
+	hits   monitoring.Counter
+	misses monitoring.Counter
+	tiles  monitoring.Gauge
+}
+
+type tileKey struct {
+	treeID int64
+	id     string
+}
+
+type tileEntry struct {
+	key  tileKey
+	rev  int64
+	tile *storagepb.SubtreeProto
+}
+
+// NewTileCache returns a TileCache holding up to size tiles. The metrics are
+// created with mf, so there should be at most one TileCache per process with
+// a given MetricFactory.
+func NewTileCache(size int, mf monitoring.MetricFactory) *TileCache {
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+	return &TileCache{
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[tileKey]*list.Element),
+		hits:    mf.NewCounter("subtree_tile_cache_hits", "Number of tiles read from the shared tile cache"),
+		misses:  mf.NewCounter("subtree_tile_cache_misses", "Number of tiles not found in the shared tile cache, and read from storage"),
+		tiles:   mf.NewGauge("subtree_tile_cache_tiles", "Number of tiles in the shared tile cache"),
+	}
+}
+
+// Wrap returns a GetSubtreesFunc which serves the full tiles of the given tree
+// from the cache, if they were cached at or before revision rev, and reads
+// the others with getSubtrees, which must read at revision rev. The full tiles
+// read from storage are added to the cache.
+//
+// The returned tiles are copies, which the caller is free to modify.
+func (c *TileCache) Wrap(treeID, rev int64, getSubtrees GetSubtreesFunc) GetSubtreesFunc {
+	return func(ids [][]byte) ([]*storagepb.SubtreeProto, error) {
+		ret := make([]*storagepb.SubtreeProto, 0, len(ids))
+		var missing [][]byte
+		for _, id := range ids {
+			if t := c.get(tileKey{treeID: treeID, id: string(id)}, rev); t != nil {
+				ret = append(ret, t)
+			} else {
+				missing = append(missing, id)
+			}
+		}
+		c.hits.Add(float64(len(ret)))
+		if len(missing) == 0 {
+			return ret, nil
+		}
+		c.misses.Add(float64(len(missing)))
+
+		tiles, err := getSubtrees(missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tiles {
+			if isFullTile(t) {
+				c.put(tileKey{treeID: treeID, id: string(t.Prefix)}, rev, t)
+			}
+		}
+		return append(ret, tiles...), nil
+	}
+}
+
+// Len returns the number of tiles in the cache.
+func (c *TileCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// InvalidateTree removes the tiles of the given tree from the cache. It must
+// be called after rewriting tiles which may be cached, such as when repairing
+// a tree, as cached tiles are otherwise assumed to never change.
+func (c *TileCache) InvalidateTree(treeID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		if key.treeID == treeID {
+			c.lru.Remove(e)
+			delete(c.entries, key)
+		}
+	}
+	c.tiles.Set(float64(c.lru.Len()))
+}
+
+func (c *TileCache) get(key tileKey, rev int64) *storagepb.SubtreeProto {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := e.Value.(*tileEntry)
+	if rev < entry.rev {
+		return nil
+	}
+	c.lru.MoveToFront(e)
+	return proto.Clone(entry.tile).(*storagepb.SubtreeProto)
+}
+
+func (c *TileCache) put(key tileKey, rev int64, t *storagepb.SubtreeProto) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*tileEntry)
+		if rev < entry.rev {
+			entry.rev = rev
+		}
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&tileEntry{key: key, rev: rev, tile: proto.Clone(t).(*storagepb.SubtreeProto)})
+	for c.lru.Len() > c.size {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*tileEntry).key)
+	}
+	c.tiles.Set(float64(c.lru.Len()))
+}
+
+// isFullTile returns whether all the leaves of the tile are set.
+func isFullTile(t *storagepb.SubtreeProto) bool {
+	return t.Depth > 0 && len(t.Leaves) == 1<<uint(t.Depth)
+}
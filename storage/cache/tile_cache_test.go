@@ -0,0 +1,223 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage/storagepb"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+// newTile returns a log tile with the given prefix and number of leaves, as
+// it would be read from storage.
+func newTile(prefix string, leaves int) *storagepb.SubtreeProto {
+	st := newEmptyTile([]byte(prefix))
+	for i := 0; i < leaves; i++ {
+		st.Leaves[toSuffix(compact.NewNodeID(0, uint64(i)))] = rfc6962.DefaultHasher.HashLeaf([]byte(fmt.Sprintf("%s-%d", prefix, i)))
+	}
+	if leaves == 256 {
+		// All the internal nodes but the root, which PopulateLogTile rebuilds.
+		st.InternalNodeCount = 254
+		st.InternalNodes = nil
+	}
+	return st
+}
+
+// tileStorage is a fake storage of tiles, which counts the tiles read.
+type tileStorage struct {
+	tiles map[string]*storagepb.SubtreeProto
+	reads int
+}
+
+func (s *tileStorage) get(ids [][]byte) ([]*storagepb.SubtreeProto, error) {
+	var ret []*storagepb.SubtreeProto
+	for _, id := range ids {
+		s.reads++
+		if t, ok := s.tiles[string(id)]; ok {
+			ret = append(ret, proto.Clone(t).(*storagepb.SubtreeProto))
+		}
+	}
+	return ret, nil
+}
+
+func TestTileCacheOnlyCachesFullTiles(t *testing.T) {
+	s := &tileStorage{tiles: map[string]*storagepb.SubtreeProto{
+		"\x00": newTile("\x00", 256),
+		"\x01": newTile("\x01", 10),
+	}}
+	c := NewTileCache(10, monitoring.InertMetricFactory{})
+	ids := [][]byte{[]byte("\x00"), []byte("\x01"), []byte("\x02")}
+
+	for i := 0; i < 3; i++ {
+		tiles, err := c.Wrap(1, 5, s.get)(ids)
+		if err != nil {
+			t.Fatalf("GetSubtrees(): %v", err)
+		}
+		if got, want := len(tiles), 2; got != want {
+			t.Fatalf("GetSubtrees(): got %d tiles, want %d", got, want)
+		}
+	}
+	// The full tile is read once, and the partial and missing ones each time.
+	if got, want := s.reads, 3+2+2; got != want {
+		t.Errorf("Storage reads: got %d, want %d", got, want)
+	}
+	if got, want := c.Len(), 1; got != want {
+		t.Errorf("Len(): got %d, want %d", got, want)
+	}
+	if got, want := c.hits.Value(), 2.0; got != want {
+		t.Errorf("Hits: got %v, want %v", got, want)
+	}
+	if got, want := c.misses.Value(), 7.0; got != want {
+		t.Errorf("Misses: got %v, want %v", got, want)
+	}
+}
+
+func TestTileCacheRevisions(t *testing.T) {
+	s := &tileStorage{tiles: map[string]*storagepb.SubtreeProto{"\x00": newTile("\x00", 256)}}
+	c := NewTileCache(10, nil)
+	ids := [][]byte{[]byte("\x00")}
+
+	for _, tc := range []struct {
+		treeID    int64
+		rev       int64
+		wantReads int
+	}{
+		{treeID: 1, rev: 5, wantReads: 1},
+		{treeID: 1, rev: 5, wantReads: 1},
+		{treeID: 1, rev: 9, wantReads: 1},
+		// Earlier revisions may have had a partial tile.
+		{treeID: 1, rev: 3, wantReads: 2},
+		// But now the tile is known to be full since revision 3.
+		{treeID: 1, rev: 4, wantReads: 2},
+		// Other trees have their own tiles.
+		{treeID: 2, rev: 9, wantReads: 3},
+	} {
+		if _, err := c.Wrap(tc.treeID, tc.rev, s.get)(ids); err != nil {
+			t.Fatalf("GetSubtrees(): %v", err)
+		}
+		if got := s.reads; got != tc.wantReads {
+			t.Errorf("tree %d at rev %d: got %d reads, want %d", tc.treeID, tc.rev, got, tc.wantReads)
+		}
+	}
+}
+
+func TestTileCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	s := &tileStorage{tiles: map[string]*storagepb.SubtreeProto{
+		"a": newTile("a", 256),
+		"b": newTile("b", 256),
+		"c": newTile("c", 256),
+	}}
+	c := NewTileCache(2, nil)
+	get := c.Wrap(1, 1, s.get)
+	for _, id := range []string{"a", "b", "a", "c"} {
+		if _, err := get([][]byte{[]byte(id)}); err != nil {
+			t.Fatalf("GetSubtrees(%q): %v", id, err)
+		}
+	}
+	if got, want := s.reads, 3; got != want {
+		t.Fatalf("Storage reads: got %d, want %d", got, want)
+	}
+	// Tile "b" was evicted, and reading it again evicts "a".
+	for _, id := range []string{"c", "b", "c", "a"} {
+		if _, err := get([][]byte{[]byte(id)}); err != nil {
+			t.Fatalf("GetSubtrees(%q): %v", id, err)
+		}
+	}
+	if got, want := s.reads, 5; got != want {
+		t.Errorf("Storage reads: got %d, want %d", got, want)
+	}
+	if got, want := c.Len(), 2; got != want {
+		t.Errorf("Len(): got %d, want %d", got, want)
+	}
+}
+
+func TestTileCacheInvalidateTree(t *testing.T) {
+	s := &tileStorage{tiles: map[string]*storagepb.SubtreeProto{"\x00": newTile("\x00", 256)}}
+	c := NewTileCache(10, nil)
+	ids := [][]byte{[]byte("\x00")}
+	for _, treeID := range []int64{1, 2} {
+		if _, err := c.Wrap(treeID, 1, s.get)(ids); err != nil {
+			t.Fatalf("GetSubtrees(): %v", err)
+		}
+	}
+
+	c.InvalidateTree(1)
+	if got, want := c.Len(), 1; got != want {
+		t.Errorf("Len(): got %d, want %d", got, want)
+	}
+	// The tile of tree 1 is read from storage again, and that of tree 2 is
+	// still cached.
+	for _, treeID := range []int64{1, 2} {
+		if _, err := c.Wrap(treeID, 1, s.get)(ids); err != nil {
+			t.Fatalf("GetSubtrees(): %v", err)
+		}
+	}
+	if got, want := s.reads, 3; got != want {
+		t.Errorf("Storage reads: got %d, want %d", got, want)
+	}
+}
+
+func TestTileCacheReturnsCopies(t *testing.T) {
+	want := newTile("\x00", 256)
+	s := &tileStorage{tiles: map[string]*storagepb.SubtreeProto{"\x00": want}}
+	c := NewTileCache(10, nil)
+	get := c.Wrap(1, 1, s.get)
+	for i := 0; i < 3; i++ {
+		tiles, err := get([][]byte{[]byte("\x00")})
+		if err != nil {
+			t.Fatalf("GetSubtrees(): %v", err)
+		}
+		if diff := cmp.Diff(want, tiles[0], protocmp.Transform()); diff != "" {
+			t.Fatalf("GetSubtrees(): diff (-want +got):\n%s", diff)
+		}
+		// Modifying the tile, as SubtreeCache does, does not affect the cache.
+		if err := PopulateLogTile(tiles[0], rfc6962.DefaultHasher); err != nil {
+			t.Fatalf("PopulateLogTile(): %v", err)
+		}
+		tiles[0].Leaves["x"] = []byte("modified")
+	}
+}
+
+func TestTileCacheWithSubtreeCache(t *testing.T) {
+	s := &tileStorage{tiles: map[string]*storagepb.SubtreeProto{
+		"\x00\x00\x00\x00\x00\x00\x00": newTile("\x00\x00\x00\x00\x00\x00\x00", 256),
+	}}
+	ids := []compact.NodeID{compact.NewNodeID(0, 3), compact.NewNodeID(2, 5), compact.NewNodeID(7, 1)}
+
+	want, err := NewLogSubtreeCache(rfc6962.DefaultHasher).GetNodes(ids, s.get)
+	if err != nil {
+		t.Fatalf("GetNodes(): %v", err)
+	}
+	c := NewTileCache(10, nil)
+	for i := 0; i < 2; i++ {
+		got, err := NewLogSubtreeCache(rfc6962.DefaultHasher).GetNodes(ids, c.Wrap(1, 1, s.get))
+		if err != nil {
+			t.Fatalf("GetNodes(): %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("GetNodes(): diff (-want +got):\n%s", diff)
+		}
+	}
+	if got, want := s.reads, 2; got != want {
+		t.Errorf("Storage reads: got %d, want %d", got, want)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/google/trillian"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/cloudspanner/spannerpb"
+	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
@@ -229,15 +230,7 @@ func (t *adminTX) getTreeInfo(ctx context.Context, treeID int64) (*spannerpb.Tre
 func (t *adminTX) ListTrees(ctx context.Context, includeDeleted bool) ([]*trillian.Tree, error) {
 	trees := []*trillian.Tree{}
 	err := t.readTrees(ctx, includeDeleted, false /* idOnly */, func(r *spanner.Row) error {
-		var infoBytes []byte
-		if err := r.Columns(&infoBytes); err != nil {
-			return err
-		}
-		info := &spannerpb.TreeInfo{}
-		if err := proto.Unmarshal(infoBytes, info); err != nil {
-			return err
-		}
-		tree, err := toTrillianTree(info)
+		tree, err := rowToTree(r)
 		if err != nil {
 			return err
 		}
@@ -247,6 +240,74 @@ func (t *adminTX) ListTrees(ctx context.Context, includeDeleted bool) ([]*trilli
 	return trees, err
 }
 
+// ListTreesFiltered implements storage.TreeLister. Spanner selects the trees
+// by ID, type and state, while labels, which are only stored in TreeInfo, are
+// matched as the trees are read.
+func (t *adminTX) ListTreesFiltered(ctx context.Context, opts storage.ListTreesOptions) ([]*trillian.Tree, error) {
+	stmt := spanner.NewStatement("SELECT t.TreeInfo FROM TreeRoots t WHERE t.TreeID > @after_tree_id")
+	stmt.Params["after_tree_id"] = opts.AfterTreeID
+	if !opts.IncludeDeleted {
+		stmt.SQL += " AND t.Deleted = @deleted"
+		stmt.Params["deleted"] = false
+	}
+	if len(opts.TreeTypes) > 0 {
+		// Types which can't be stored select no trees.
+		types := []int64{}
+		for _, tt := range opts.TreeTypes {
+			if v, ok := treeTypeMap[tt]; ok {
+				types = append(types, int64(v))
+			}
+		}
+		stmt.SQL += " AND t.TreeType IN UNNEST(@tree_types)"
+		stmt.Params["tree_types"] = types
+	}
+	if len(opts.TreeStates) > 0 {
+		states := []int64{}
+		for _, ts := range opts.TreeStates {
+			if v, ok := treeStateMap[ts]; ok {
+				states = append(states, int64(v))
+			}
+		}
+		stmt.SQL += " AND t.TreeState IN UNNEST(@tree_states)"
+		stmt.Params["tree_states"] = states
+	}
+	stmt.SQL += " ORDER BY t.TreeID"
+
+	trees := []*trillian.Tree{}
+	rows := t.tx.Query(ctx, stmt)
+	defer rows.Stop()
+	for opts.Limit <= 0 || len(trees) < opts.Limit {
+		r, err := rows.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		tree, err := rowToTree(r)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Matches(tree) {
+			trees = append(trees, tree)
+		}
+	}
+	return trees, nil
+}
+
+// rowToTree returns the tree whose TreeInfo is the only column of r.
+func rowToTree(r *spanner.Row) (*trillian.Tree, error) {
+	var infoBytes []byte
+	if err := r.Columns(&infoBytes); err != nil {
+		return nil, err
+	}
+	info := &spannerpb.TreeInfo{}
+	if err := proto.Unmarshal(infoBytes, info); err != nil {
+		return nil, err
+	}
+	return toTrillianTree(info)
+}
+
 func (t *adminTX) readTrees(ctx context.Context, includeDeleted, idOnly bool, f func(*spanner.Row) error) error {
 	var stmt spanner.Statement
 	if idOnly {
@@ -262,16 +323,87 @@ func (t *adminTX) readTrees(ctx context.Context, includeDeleted, idOnly bool, f
 	return rows.Do(f)
 }
 
-// CreateTree implements AdminWriter.CreateTree.
-func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillian.Tree, error) {
-	if err := storage.ValidateTreeForCreation(ctx, tree); err != nil {
+// ListAuditEvents implements storage.AuditLogReader.
+func (t *adminTX) ListAuditEvents(ctx context.Context, opts storage.ListAuditEventsOptions) ([]*trillian.AdminAuditEvent, error) {
+	stmt := spanner.NewStatement("SELECT EventID, Event FROM AdminAuditEvents WHERE EventID > @after_event_id")
+	stmt.Params["after_event_id"] = opts.AfterEventID
+	if opts.TreeID != 0 {
+		stmt.SQL += " AND TreeID = @tree_id"
+		stmt.Params["tree_id"] = opts.TreeID
+	}
+	stmt.SQL += " ORDER BY EventID"
+	if opts.Limit > 0 {
+		stmt.SQL += " LIMIT @limit"
+		stmt.Params["limit"] = int64(opts.Limit)
+	}
+
+	events := []*trillian.AdminAuditEvent{}
+	err := t.tx.Query(ctx, stmt).Do(func(r *spanner.Row) error {
+		var id int64
+		var eventBytes []byte
+		if err := r.Columns(&id, &eventBytes); err != nil {
+			return err
+		}
+		event := &trillian.AdminAuditEvent{}
+		if err := proto.Unmarshal(eventBytes, event); err != nil {
+			return fmt.Errorf("failed to unmarshal audit event %d: %v", id, err)
+		}
+		event.EventId = id
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	return events, nil
+}
+
+// AddAuditEvent implements storage.AuditLogWriter.
+// Event IDs are allocated by reading the greatest ID in the transaction, so
+// concurrent transactions adding events conflict, and are retried by Spanner.
+func (t *adminTX) AddAuditEvent(ctx context.Context, event *trillian.AdminAuditEvent) error {
+	stx, ok := t.tx.(*spanner.ReadWriteTransaction)
+	if !ok {
+		return ErrWrongTXType
+	}
+	if err := event.EventTime.CheckValid(); err != nil {
+		return fmt.Errorf("invalid event_time: %v", err)
+	}
 
+	var maxID spanner.NullInt64
+	err := stx.Query(ctx, spanner.NewStatement("SELECT MAX(EventID) FROM AdminAuditEvents")).Do(func(r *spanner.Row) error {
+		return r.Columns(&maxID)
+	})
+	if err != nil {
+		return err
+	}
+	event.EventId = maxID.Int64 + 1
+
+	eventBytes, err := proto.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return stx.BufferWrite([]*spanner.Mutation{spanner.Insert(
+		"AdminAuditEvents",
+		[]string{"EventID", "TreeID", "EventTimeMillis", "Event"},
+		[]interface{}{event.EventId, event.TreeId, event.EventTime.AsTime().UnixNano() / nanosPerMilli, eventBytes},
+	)})
+}
+
+// CreateTree implements AdminWriter.CreateTree.
+func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillian.Tree, error) {
 	id, err := storage.NewTreeID()
 	if err != nil {
 		return nil, err
 	}
+	return t.CreateTreeWithID(ctx, tree, id)
+}
+
+// CreateTreeWithID implements storage.TreeCreatorWithID.
+func (t *adminTX) CreateTreeWithID(ctx context.Context, tree *trillian.Tree, id int64) (*trillian.Tree, error) {
+	if err := storage.ValidateTreeForCreation(ctx, tree); err != nil {
+		return nil, err
+	}
 
 	info, err := newTreeInfo(tree, id, TimeNow())
 	if err != nil {
@@ -336,6 +468,7 @@ func newTreeInfo(tree *trillian.Tree, treeID int64, now time.Time) (*spannerpb.T
 		CreateTimeNanos:       now.UnixNano(),
 		UpdateTimeNanos:       now.UnixNano(),
 		MaxRootDurationMillis: int64(maxRootDuration / time.Millisecond),
+		Labels:                tree.Labels,
 	}
 
 	switch tt := tree.TreeType; tt {
@@ -412,6 +545,7 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 	info.Description = tree.Description
 	info.UpdateTimeNanos = now.UnixNano()
 	info.MaxRootDurationMillis = int64(maxRootDuration / time.Millisecond)
+	info.Labels = tree.Labels
 
 	if err := t.updateTreeInfo(ctx, info); err != nil {
 		return nil, err
@@ -534,6 +668,7 @@ func toTrillianTree(info *spannerpb.TreeInfo) (*trillian.Tree, error) {
 		CreateTime:      createdPB,
 		UpdateTime:      updatedPB,
 		MaxRootDuration: durationpb.New(time.Duration(info.MaxRootDurationMillis) * time.Millisecond),
+		Labels:          info.Labels,
 	}
 
 	ts, ok := treeStateReverseMap[info.TreeState]
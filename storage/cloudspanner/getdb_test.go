@@ -131,6 +131,7 @@ func cleanTestDB(ctx context.Context, t *testing.T, db *spanner.Client) {
 		mutations := make([]*spanner.Mutation, 0)
 		for _, table := range []string{
 			"TreeRoots",
+			"AdminAuditEvents",
 			"TreeHeads",
 			"SubtreeData",
 			"LeafData",
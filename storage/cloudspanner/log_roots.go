@@ -0,0 +1,59 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudspanner
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/trillian"
+	"github.com/google/trillian/types"
+)
+
+// listSignedLogRootsSQL selects the tree heads of a tree after a given
+// timestamp, oldest first.
+const listSignedLogRootsSQL = `SELECT TimestampNanos, TreeSize, RootHash, TreeMetadata FROM TreeHeads
+	WHERE TreeID = @tree_id AND TimestampNanos > @after
+	ORDER BY TimestampNanos LIMIT @limit`
+
+// ListSignedLogRoots implements storage.LogRootLister.
+func (ls *logStorage) ListSignedLogRoots(ctx context.Context, tree *trillian.Tree, after uint64, limit int) ([]*trillian.SignedLogRoot, error) {
+	stmt := spanner.NewStatement(listSignedLogRootsSQL)
+	stmt.Params["tree_id"] = tree.TreeId
+	stmt.Params["after"] = int64(after)
+	stmt.Params["limit"] = int64(limit)
+	var ret []*trillian.SignedLogRoot
+	if err := ls.readOnlyTX().Query(ctx, stmt).Do(func(r *spanner.Row) error {
+		var timestamp, treeSize int64
+		var rootHash, metadata []byte
+		if err := r.Columns(&timestamp, &treeSize, &rootHash, &metadata); err != nil {
+			return err
+		}
+		logRoot, err := (&types.LogRootV1{
+			TimestampNanos: uint64(timestamp),
+			RootHash:       rootHash,
+			TreeSize:       uint64(treeSize),
+			Metadata:       metadata,
+		}).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		ret = append(ret, &trillian.SignedLogRoot{LogRoot: logRoot})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -20,33 +20,39 @@ CiAgVHJlZVN0YXRlICAgICAgICAgICAgIElOVDY0IE5PVCBOVUxMLAogIFRyZWVUeXBlICAgICAg
 ICAgICAgICBJTlQ2NCBOT1QgTlVMTCwKICBUcmVlSW5mbyAgICAgICAgICAgICAgQllURVMoMjA5
 NzE1MikgTk9UIE5VTEwsCiAgRGVsZXRlZCAgICAgICAgICAgICAgIEJPT0wgTk9UIE5VTEwsCiAg
 RGVsZXRlVGltZU1pbGxpcyAgICAgIElOVDY0LAopIFBSSU1BUlkgS0VZKFRyZWVJRCk7CgpDUkVB
-VEUgSU5ERVggVHJlZVJvb3RzQnlEZWxldGVkCiAgT04gVHJlZVJvb3RzIChEZWxldGVkKTsKCkNS
-RUFURSBUQUJMRSBUcmVlSGVhZHMoCiAgVHJlZUlEICAgICAgICAgICAgICAgICAgSU5UNjQgTk9U
-IE5VTEwsCiAgVGltZXN0YW1wTmFub3MgICAgICAgICAgSU5UNjQgTk9UIE5VTEwsCiAgVHJlZVNp
-emUgICAgICAgICAgICAgICAgSU5UNjQgTk9UIE5VTEwsCiAgUm9vdEhhc2ggICAgICAgICAgICAg
-ICAgQllURVMoMjU2KSBOT1QgTlVMTCwKICBSb290U2lnbmF0dXJlICAgICAgICAgICBCWVRFUygx
-MDI0KSBOT1QgTlVMTCwKICBUcmVlUmV2aXNpb24gICAgICAgICAgICBJTlQ2NCBOT1QgTlVMTCwK
-ICBUcmVlTWV0YWRhdGEgICAgICAgICAgICBCWVRFUygyMDk3MTUyKSwKKSBQUklNQVJZIEtFWShU
-cmVlSUQsIFRyZWVSZXZpc2lvbiBERVNDKTsKCkNSRUFURSBUQUJMRSBTdWJ0cmVlRGF0YSgKICBU
-cmVlSUQgICAgICBJTlQ2NCBOT1QgTlVMTCwKICBTdWJ0cmVlSUQgICBCWVRFUygyNTYpIE5PVCBO
-VUxMLAogIFJldmlzaW9uICAgIElOVDY0IE5PVCBOVUxMLAogIFN1YnRyZWUgICAgIEJZVEVTKE1B
-WCkgTk9UIE5VTEwKKSBQUklNQVJZIEtFWShUcmVlSUQsIFN1YnRyZWVJRCwgUmV2aXNpb24gREVT
-Qyk7CgpDUkVBVEUgVEFCTEUgTGVhZkRhdGEoCiAgVHJlZUlEICAgICAgICAgICAgICBJTlQ2NCBO
-T1QgTlVMTCwKICBMZWFmSWRlbnRpdHlIYXNoICAgIEJZVEVTKDI1NikgTk9UIE5VTEwsCiAgTGVh
-ZlZhbHVlICAgICAgICAgICBCWVRFUyhNQVgpIE5PVCBOVUxMLAogIEV4dHJhRGF0YSAgICAgICAg
-ICAgQllURVMoTUFYKSwKICBRdWV1ZVRpbWVzdGFtcE5hbm9zIElOVDY0IE5PVCBOVUxMLAopIFBS
-SU1BUlkgS0VZKFRyZWVJRCwgTGVhZklkZW50aXR5SGFzaCk7CgpDUkVBVEUgVEFCTEUgU2VxdWVu
-Y2VkTGVhZkRhdGEoCiAgVHJlZUlEICAgICAgICAgICAgICAgICAgSU5UNjQgTk9UIE5VTEwsCiAg
-U2VxdWVuY2VOdW1iZXIgICAgICAgICAgSU5UNjQgTk9UIE5VTEwsCiAgTGVhZklkZW50aXR5SGFz
-aCAgICAgICAgQllURVMoMjU2KSBOT1QgTlVMTCwKICBNZXJrbGVMZWFmSGFzaCAgICAgICAgICBC
-WVRFUygyNTYpIE5PVCBOVUxMLAogIEludGVncmF0ZVRpbWVzdGFtcE5hbm9zIElOVDY0IE5PVCBO
-VUxMLAopIFBSSU1BUlkgS0VZKFRyZWVJRCwgU2VxdWVuY2VOdW1iZXIpOwoKQ1JFQVRFIElOREVY
-IFNlcXVlbmNlQnlNZXJrbGVIYXNoCiAgT04gU2VxdWVuY2VkTGVhZkRhdGEoVHJlZUlELCBNZXJr
-bGVMZWFmSGFzaCkKICBTVE9SSU5HKExlYWZJZGVudGl0eUhhc2gpOwoKQ1JFQVRFIFRBQkxFIFVu
-c2VxdWVuY2VkKAogIFRyZWVJRCAgICAgICAgICAgICAgICAgSU5UNjQgTk9UIE5VTEwsCiAgQnVj
-a2V0ICAgICAgICAgICAgICAgICBJTlQ2NCBOT1QgTlVMTCwKICBRdWV1ZVRpbWVzdGFtcE5hbm9z
-ICAgIElOVDY0IE5PVCBOVUxMLAogIE1lcmtsZUxlYWZIYXNoICAgICAgICAgQllURVMoMjU2KSBO
-T1QgTlVMTCwKICBMZWFmSWRlbnRpdHlIYXNoICAgICAgIEJZVEVTKDI1NikgTk9UIE5VTEwsCikg
-UFJJTUFSWSBLRVkgKFRyZWVJRCwgQnVja2V0LCBRdWV1ZVRpbWVzdGFtcE5hbm9zLCBNZXJrbGVM
-ZWFmSGFzaCk7Cg==
+VEUgSU5ERVggVHJlZVJvb3RzQnlEZWxldGVkCiAgT04gVHJlZVJvb3RzIChEZWxldGVkKTsKCi0t
+IEF1ZGl0IGxvZyBvZiB0aGUgY2hhbmdlcyBtYWRlIHRvIHRyZWVzLiBFdmVudHMgYXJlIGtlcHQg
+YWZ0ZXIgdGhlaXIgdHJlZXMKLS0gYXJlIGhhcmQtZGVsZXRlZC4KQ1JFQVRFIFRBQkxFIEFkbWlu
+QXVkaXRFdmVudHMoCiAgRXZlbnRJRCAgICAgICAgICAgICAgIElOVDY0IE5PVCBOVUxMLAogIFRy
+ZWVJRCAgICAgICAgICAgICAgICBJTlQ2NCBOT1QgTlVMTCwKICBFdmVudFRpbWVNaWxsaXMgICAg
+ICAgSU5UNjQgTk9UIE5VTEwsCiAgRXZlbnQgICAgICAgICAgICAgICAgIEJZVEVTKE1BWCkgTk9U
+IE5VTEwsCikgUFJJTUFSWSBLRVkoRXZlbnRJRCk7CgpDUkVBVEUgSU5ERVggQWRtaW5BdWRpdEV2
+ZW50c0J5VHJlZQogIE9OIEFkbWluQXVkaXRFdmVudHMgKFRyZWVJRCk7CgpDUkVBVEUgVEFCTEUg
+VHJlZUhlYWRzKAogIFRyZWVJRCAgICAgICAgICAgICAgICAgIElOVDY0IE5PVCBOVUxMLAogIFRp
+bWVzdGFtcE5hbm9zICAgICAgICAgIElOVDY0IE5PVCBOVUxMLAogIFRyZWVTaXplICAgICAgICAg
+ICAgICAgIElOVDY0IE5PVCBOVUxMLAogIFJvb3RIYXNoICAgICAgICAgICAgICAgIEJZVEVTKDI1
+NikgTk9UIE5VTEwsCiAgUm9vdFNpZ25hdHVyZSAgICAgICAgICAgQllURVMoMTAyNCkgTk9UIE5V
+TEwsCiAgVHJlZVJldmlzaW9uICAgICAgICAgICAgSU5UNjQgTk9UIE5VTEwsCiAgVHJlZU1ldGFk
+YXRhICAgICAgICAgICAgQllURVMoMjA5NzE1MiksCikgUFJJTUFSWSBLRVkoVHJlZUlELCBUcmVl
+UmV2aXNpb24gREVTQyk7CgpDUkVBVEUgVEFCTEUgU3VidHJlZURhdGEoCiAgVHJlZUlEICAgICAg
+SU5UNjQgTk9UIE5VTEwsCiAgU3VidHJlZUlEICAgQllURVMoMjU2KSBOT1QgTlVMTCwKICBSZXZp
+c2lvbiAgICBJTlQ2NCBOT1QgTlVMTCwKICBTdWJ0cmVlICAgICBCWVRFUyhNQVgpIE5PVCBOVUxM
+CikgUFJJTUFSWSBLRVkoVHJlZUlELCBTdWJ0cmVlSUQsIFJldmlzaW9uIERFU0MpOwoKQ1JFQVRF
+IFRBQkxFIExlYWZEYXRhKAogIFRyZWVJRCAgICAgICAgICAgICAgSU5UNjQgTk9UIE5VTEwsCiAg
+TGVhZklkZW50aXR5SGFzaCAgICBCWVRFUygyNTYpIE5PVCBOVUxMLAogIExlYWZWYWx1ZSAgICAg
+ICAgICAgQllURVMoTUFYKSBOT1QgTlVMTCwKICBFeHRyYURhdGEgICAgICAgICAgIEJZVEVTKE1B
+WCksCiAgUXVldWVUaW1lc3RhbXBOYW5vcyBJTlQ2NCBOT1QgTlVMTCwKKSBQUklNQVJZIEtFWShU
+cmVlSUQsIExlYWZJZGVudGl0eUhhc2gpOwoKQ1JFQVRFIFRBQkxFIFNlcXVlbmNlZExlYWZEYXRh
+KAogIFRyZWVJRCAgICAgICAgICAgICAgICAgIElOVDY0IE5PVCBOVUxMLAogIFNlcXVlbmNlTnVt
+YmVyICAgICAgICAgIElOVDY0IE5PVCBOVUxMLAogIExlYWZJZGVudGl0eUhhc2ggICAgICAgIEJZ
+VEVTKDI1NikgTk9UIE5VTEwsCiAgTWVya2xlTGVhZkhhc2ggICAgICAgICAgQllURVMoMjU2KSBO
+T1QgTlVMTCwKICBJbnRlZ3JhdGVUaW1lc3RhbXBOYW5vcyBJTlQ2NCBOT1QgTlVMTCwKKSBQUklN
+QVJZIEtFWShUcmVlSUQsIFNlcXVlbmNlTnVtYmVyKTsKCkNSRUFURSBJTkRFWCBTZXF1ZW5jZUJ5
+TWVya2xlSGFzaAogIE9OIFNlcXVlbmNlZExlYWZEYXRhKFRyZWVJRCwgTWVya2xlTGVhZkhhc2gp
+CiAgU1RPUklORyhMZWFmSWRlbnRpdHlIYXNoKTsKCkNSRUFURSBUQUJMRSBVbnNlcXVlbmNlZCgK
+ICBUcmVlSUQgICAgICAgICAgICAgICAgIElOVDY0IE5PVCBOVUxMLAogIEJ1Y2tldCAgICAgICAg
+ICAgICAgICAgSU5UNjQgTk9UIE5VTEwsCiAgUXVldWVUaW1lc3RhbXBOYW5vcyAgICBJTlQ2NCBO
+T1QgTlVMTCwKICBNZXJrbGVMZWFmSGFzaCAgICAgICAgIEJZVEVTKDI1NikgTk9UIE5VTEwsCiAg
+TGVhZklkZW50aXR5SGFzaCAgICAgICBCWVRFUygyNTYpIE5PVCBOVUxMLAopIFBSSU1BUlkgS0VZ
+IChUcmVlSUQsIEJ1Y2tldCwgUXVldWVUaW1lc3RhbXBOYW5vcywgTWVya2xlTGVhZkhhc2gpOwo=
 `
@@ -0,0 +1,54 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudspanner
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+)
+
+// getTreeStatsSQL selects the statistics of a tree. The sizes of the leaf
+// data and tiles are computed by scanning all the rows of the tree.
+const getTreeStatsSQL = `SELECT
+	(SELECT COUNT(*) FROM SequencedLeafData WHERE TreeID = @tree_id),
+	(SELECT COUNT(*) FROM Unsequenced WHERE TreeID = @tree_id),
+	(SELECT MIN(QueueTimestampNanos) FROM Unsequenced WHERE TreeID = @tree_id),
+	(SELECT MAX(TimestampNanos) FROM TreeHeads WHERE TreeID = @tree_id),
+	(SELECT COALESCE(SUM(LENGTH(LeafValue) + COALESCE(LENGTH(ExtraData), 0)), 0) FROM LeafData WHERE TreeID = @tree_id),
+	(SELECT COALESCE(SUM(LENGTH(Subtree)), 0) FROM SubtreeData WHERE TreeID = @tree_id)`
+
+// GetTreeStats implements storage.TreeStatsReader.
+func (ls *logStorage) GetTreeStats(ctx context.Context, tree *trillian.Tree) (*storage.TreeStats, error) {
+	stmt := spanner.NewStatement(getTreeStatsSQL)
+	stmt.Params["tree_id"] = tree.TreeId
+	var stats storage.TreeStats
+	var oldest, latest spanner.NullInt64
+	if err := ls.readOnlyTX().Query(ctx, stmt).Do(func(r *spanner.Row) error {
+		return r.Columns(&stats.LeafCount, &stats.QueueDepth, &oldest, &latest, &stats.LeafBytes, &stats.TileBytes)
+	}); err != nil {
+		return nil, err
+	}
+	if oldest.Valid {
+		stats.OldestQueued = time.Unix(0, oldest.Int64)
+	}
+	if latest.Valid {
+		stats.LatestRoot = time.Unix(0, latest.Int64)
+	}
+	return &stats, nil
+}
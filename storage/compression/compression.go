@@ -0,0 +1,263 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compression compresses the values and extra data of leaves stored by
+// the SQL and memory storage providers, for the trees which opt in to it.
+//
+// A tree opts in with the leaf_compression of the storagepb.LogStorageSettings
+// in its storage_settings, which selects how the leaves written from then on
+// are stored. It can be set when the tree is created, or changed later.
+//
+// A compressed value is stored as the marker "\x00TRLZ", followed by a byte
+// holding the LeafCompression it is compressed with, and then the compressed
+// data. Values without the marker are stored as they are, so that compressed
+// and uncompressed rows can coexist, e.g. after enabling compression for a tree
+// which already has leaves. Values which happen to start with the marker are
+// always stored with it, using NO_LEAF_COMPRESSION if they aren't compressed,
+// so that every stored value starting with it is genuinely encoded.
+//
+// Values are only stored compressed if that makes them shorter.
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage/storagepb"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// marker starts the stored values which are encoded with a header.
+var marker = []byte("\x00TRLZ")
+
+// headerLen is the length of the marker and algorithm preceding encoded values.
+var headerLen = len(marker) + 1
+
+var (
+	metricsOnce sync.Once
+	rawBytes    monitoring.Counter
+	storedBytes monitoring.Counter
+	ratio       monitoring.Histogram
+)
+
+// InitMetrics creates the metrics recording how well leaves compress with mf.
+// Only the first call has an effect, so the storage providers call it when
+// they create their own metrics. Leaves encoded before it is called are
+// recorded in inert metrics.
+func InitMetrics(mf monitoring.MetricFactory) {
+	metricsOnce.Do(func() {
+		if mf == nil {
+			mf = monitoring.InertMetricFactory{}
+		}
+		rawBytes = mf.NewCounter("leaf_compression_raw_bytes", "Number of bytes of leaf values and extra data written for trees with compression enabled, before compression", monitoring.TreeIDLabel)
+		storedBytes = mf.NewCounter("leaf_compression_stored_bytes", "Number of bytes of leaf values and extra data written for trees with compression enabled, after compression", monitoring.TreeIDLabel)
+		ratio = mf.NewHistogramWithBuckets("leaf_compression_ratio", "Ratio of the uncompressed to the stored size of leaf values and extra data, for trees with compression enabled", monitoring.ExpBuckets(1, 1.25, 16), monitoring.TreeIDLabel)
+	})
+}
+
+// Settings returns the storage settings of the tree, or the default ones if it
+// has none.
+func Settings(tree *trillian.Tree) (*storagepb.LogStorageSettings, error) {
+	settings := &storagepb.LogStorageSettings{}
+	if tree.GetStorageSettings() == nil {
+		return settings, nil
+	}
+	if err := tree.StorageSettings.UnmarshalTo(settings); err != nil {
+		return nil, fmt.Errorf("storage_settings not supported: %v", err)
+	}
+	return settings, nil
+}
+
+// Algorithm returns the compression algorithm of the leaves of the tree.
+func Algorithm(tree *trillian.Tree) (storagepb.LeafCompression, error) {
+	settings, err := Settings(tree)
+	if err != nil {
+		return storagepb.LeafCompression_NO_LEAF_COMPRESSION, err
+	}
+	return settings.LeafCompression, nil
+}
+
+// ValidateSettings returns an error if the storage settings of the tree aren't
+// a storagepb.LogStorageSettings with a supported compression algorithm.
+func ValidateSettings(tree *trillian.Tree) error {
+	a, err := Algorithm(tree)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, ok := storagepb.LeafCompression_name[int32(a)]; !ok {
+		return status.Errorf(codes.InvalidArgument, "unsupported leaf_compression %v", a)
+	}
+	return nil
+}
+
+// Encode returns the data as it should be stored for the given tree, whose
+// leaves are compressed with a.
+func Encode(treeID int64, a storagepb.LeafCompression, data []byte) ([]byte, error) {
+	if len(data) == 0 || (a == storagepb.LeafCompression_NO_LEAF_COMPRESSION && !bytes.HasPrefix(data, marker)) {
+		return data, nil
+	}
+	var out []byte
+	if a != storagepb.LeafCompression_NO_LEAF_COMPRESSION {
+		compressed, err := compress(a, data)
+		if err != nil {
+			return nil, err
+		}
+		if headerLen+len(compressed) < len(data) {
+			out = withHeader(a, compressed)
+		}
+	}
+	if out == nil {
+		out = data
+		if bytes.HasPrefix(data, marker) {
+			out = withHeader(storagepb.LeafCompression_NO_LEAF_COMPRESSION, data)
+		}
+	}
+	if a != storagepb.LeafCompression_NO_LEAF_COMPRESSION {
+		InitMetrics(nil)
+		label := strconv.FormatInt(treeID, 10)
+		rawBytes.Add(float64(len(data)), label)
+		storedBytes.Add(float64(len(out)), label)
+		ratio.Observe(float64(len(data))/float64(len(out)), label)
+	}
+	return out, nil
+}
+
+// withHeader returns the data preceded by the marker and the algorithm.
+func withHeader(a storagepb.LeafCompression, data []byte) []byte {
+	out := make([]byte, 0, headerLen+len(data))
+	out = append(out, marker...)
+	out = append(out, byte(a))
+	return append(out, data...)
+}
+
+// Decode returns the original data of the stored data, which records whether
+// and how it is compressed.
+func Decode(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, marker) {
+		return data, nil
+	}
+	if len(data) < headerLen {
+		return nil, errors.New("truncated compression header")
+	}
+	a, body := storagepb.LeafCompression(data[len(marker)]), data[headerLen:]
+	if a == storagepb.LeafCompression_NO_LEAF_COMPRESSION {
+		return body, nil
+	}
+	out, err := decompress(a, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress: %v", err)
+	}
+	return out, nil
+}
+
+// EncodeLeaf returns the leaf value and extra data of the leaf as they should
+// be stored for the given tree, whose leaves are compressed with a.
+func EncodeLeaf(treeID int64, a storagepb.LeafCompression, leaf *trillian.LogLeaf) ([]byte, []byte, error) {
+	value, err := Encode(treeID, a, leaf.LeafValue)
+	if err != nil {
+		return nil, nil, err
+	}
+	extra, err := Encode(treeID, a, leaf.ExtraData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, extra, nil
+}
+
+// DecodeLeaf replaces the stored leaf value and extra data of the leaf by the
+// original ones, in place.
+func DecodeLeaf(leaf *trillian.LogLeaf) error {
+	value, err := Decode(leaf.LeafValue)
+	if err != nil {
+		return fmt.Errorf("leaf value: %v", err)
+	}
+	extra, err := Decode(leaf.ExtraData)
+	if err != nil {
+		return fmt.Errorf("extra data: %v", err)
+	}
+	leaf.LeafValue, leaf.ExtraData = value, extra
+	return nil
+}
+
+var (
+	gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+
+	// The zstd encoder and decoder are safe for concurrent use with EncodeAll
+	// and DecodeAll, so they are shared.
+	zstdOnce    sync.Once
+	zstdErr     error
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+func initZstd() error {
+	zstdOnce.Do(func() {
+		if zstdEncoder, zstdErr = zstd.NewWriter(nil); zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdErr
+}
+
+func compress(a storagepb.LeafCompression, data []byte) ([]byte, error) {
+	switch a {
+	case storagepb.LeafCompression_GZIP:
+		var buf bytes.Buffer
+		zw := gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(zw)
+		zw.Reset(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case storagepb.LeafCompression_ZSTD:
+		if err := initZstd(); err != nil {
+			return nil, err
+		}
+		return zstdEncoder.EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression algorithm %v", a)
+	}
+}
+
+func decompress(a storagepb.LeafCompression, data []byte) ([]byte, error) {
+	switch a {
+	case storagepb.LeafCompression_GZIP:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(zr)
+	case storagepb.LeafCompression_ZSTD:
+		if err := initZstd(); err != nil {
+			return nil, err
+		}
+		return zstdDecoder.DecodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("unsupported compression algorithm %v", a)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compression
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage/storagepb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	text := []byte(strings.Repeat(`{"attestation": "some text which compresses well"}`, 20))
+	random := make([]byte, 1000)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatalf("rand.Read(): %v", err)
+	}
+
+	for _, tc := range []struct {
+		desc        string
+		a           storagepb.LeafCompression
+		data        []byte
+		wantSmaller bool
+	}{
+		{desc: "gzip", a: storagepb.LeafCompression_GZIP, data: text, wantSmaller: true},
+		{desc: "gzip-incompressible", a: storagepb.LeafCompression_GZIP, data: random},
+		{desc: "gzip-empty", a: storagepb.LeafCompression_GZIP, data: nil},
+		{desc: "zstd", a: storagepb.LeafCompression_ZSTD, data: text, wantSmaller: true},
+		{desc: "zstd-incompressible", a: storagepb.LeafCompression_ZSTD, data: random},
+		{desc: "zstd-empty", a: storagepb.LeafCompression_ZSTD, data: nil},
+		{desc: "none", a: storagepb.LeafCompression_NO_LEAF_COMPRESSION, data: text},
+		{desc: "none-with-marker", a: storagepb.LeafCompression_NO_LEAF_COMPRESSION, data: append([]byte("\x00TRLZ"), text...)},
+		{desc: "gzip-incompressible-with-marker", a: storagepb.LeafCompression_GZIP, data: append([]byte("\x00TRLZ"), random...)},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			stored, err := Encode(1, tc.a, tc.data)
+			if err != nil {
+				t.Fatalf("Encode(): %v", err)
+			}
+			if got := len(stored) < len(tc.data); got != tc.wantSmaller {
+				t.Errorf("Encode() returned %d bytes for %d, smaller: %v, want %v", len(stored), len(tc.data), got, tc.wantSmaller)
+			}
+			if !tc.wantSmaller && !bytes.HasPrefix(tc.data, marker) && !bytes.Equal(stored, tc.data) {
+				t.Errorf("Encode() changed uncompressed data")
+			}
+			got, err := Decode(stored)
+			if err != nil {
+				t.Fatalf("Decode(): %v", err)
+			}
+			if !bytes.Equal(got, tc.data) {
+				t.Errorf("Decode() = %q, want %q", got, tc.data)
+			}
+		})
+	}
+
+	if got := storedBytes.Value("1"); got >= rawBytes.Value("1") {
+		t.Errorf("stored bytes = %v, want less than raw bytes %v", got, rawBytes.Value("1"))
+	}
+}
+
+func TestDecodeUncompressed(t *testing.T) {
+	// Values stored before the tree opted in to compression have no marker.
+	for _, data := range [][]byte{nil, []byte("plain value"), []byte("\x00TRL")} {
+		got, err := Decode(data)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("Decode(%q) = %q, %v, want the data unchanged", data, got, err)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		data []byte
+	}{
+		{desc: "truncated-header", data: []byte("\x00TRLZ")},
+		{desc: "unknown-algorithm", data: []byte("\x00TRLZ\x63\x01\x02")},
+		{desc: "corrupt-gzip", data: []byte("\x00TRLZ\x01\x01\x02\x03")},
+		{desc: "corrupt-zstd", data: []byte("\x00TRLZ\x02\x01\x02\x03")},
+	} {
+		if _, err := Decode(tc.data); err == nil {
+			t.Errorf("%s: Decode() succeeded, want error", tc.desc)
+		}
+	}
+}
+
+func TestEncodeDecodeLeaf(t *testing.T) {
+	leaf := &trillian.LogLeaf{
+		LeafValue: []byte(strings.Repeat("value ", 100)),
+		ExtraData: []byte(strings.Repeat("extra ", 100)),
+	}
+	value, extra, err := EncodeLeaf(1, storagepb.LeafCompression_ZSTD, leaf)
+	if err != nil {
+		t.Fatalf("EncodeLeaf(): %v", err)
+	}
+	if len(value) >= len(leaf.LeafValue) || len(extra) >= len(leaf.ExtraData) {
+		t.Fatalf("EncodeLeaf() didn't compress the leaf")
+	}
+	stored := &trillian.LogLeaf{LeafValue: value, ExtraData: extra}
+	if err := DecodeLeaf(stored); err != nil {
+		t.Fatalf("DecodeLeaf(): %v", err)
+	}
+	if !bytes.Equal(stored.LeafValue, leaf.LeafValue) || !bytes.Equal(stored.ExtraData, leaf.ExtraData) {
+		t.Errorf("DecodeLeaf() = %q, %q, want %q, %q", stored.LeafValue, stored.ExtraData, leaf.LeafValue, leaf.ExtraData)
+	}
+}
+
+func treeWithSettings(t *testing.T, settings *storagepb.LogStorageSettings) *trillian.Tree {
+	t.Helper()
+	tree := &trillian.Tree{}
+	if settings != nil {
+		s, err := anypb.New(settings)
+		if err != nil {
+			t.Fatalf("anypb.New(): %v", err)
+		}
+		tree.StorageSettings = s
+	}
+	return tree
+}
+
+func TestValidateSettings(t *testing.T) {
+	other, err := anypb.New(&emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("anypb.New(): %v", err)
+	}
+	for _, tc := range []struct {
+		desc    string
+		tree    *trillian.Tree
+		want    storagepb.LeafCompression
+		wantErr bool
+	}{
+		{desc: "no-settings", tree: treeWithSettings(t, nil)},
+		{desc: "zstd", tree: treeWithSettings(t, &storagepb.LogStorageSettings{LeafCompression: storagepb.LeafCompression_ZSTD}), want: storagepb.LeafCompression_ZSTD},
+		{desc: "unknown-algorithm", tree: treeWithSettings(t, &storagepb.LogStorageSettings{LeafCompression: 99}), want: 99, wantErr: true},
+		{desc: "other-settings", tree: &trillian.Tree{StorageSettings: other}, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := ValidateSettings(tc.tree); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateSettings() = %v, want error: %v", err, tc.wantErr)
+			}
+			if got, _ := Algorithm(tc.tree); got != tc.want {
+				t.Errorf("Algorithm() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+)
+
+func TestArchiveLeafData(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	handle := openTestDBOrDie(t)
+	as := NewSQLAdminStorage(handle.db)
+	tree := mustCreateTree(ctx, t, as, testonly.LogTree)
+	s := NewLogStorage(handle.db, nil)
+
+	const size = 10
+	for i := int64(0); i < size; i++ {
+		data := []byte(fmt.Sprintf("leaf %d", i))
+		id := sha256.Sum256(data)
+		hash := sha256.Sum256(id[:])
+		createFakeLeaf(ctx, handle.db, tree.TreeId, id[:], hash[:], data, []byte("extra"), i, t)
+	}
+	mustSignAndStoreLogRoot(ctx, t, s, tree, size)
+
+	ref := []byte("ref")
+	archiver := s.(storage.LeafArchiver)
+	got, err := archiver.ArchiveLeafData(ctx, tree, 2, 5, ref)
+	if err != nil {
+		t.Fatalf("ArchiveLeafData(): %v", err)
+	}
+	if got != 3 {
+		t.Errorf("ArchiveLeafData() = %d, want 3", got)
+	}
+
+	runLogTX(s, tree, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		leaves, err := tx.GetLeavesByRange(ctx, 0, size)
+		if err != nil {
+			return err
+		}
+		for i, leaf := range leaves {
+			archived := i >= 2 && i < 5
+			if got := bytes.Equal(leaf.LeafValue, ref); got != archived {
+				t.Errorf("Leaf %d has value %q, archived: %v", i, leaf.LeafValue, archived)
+			}
+			if got := leaf.ExtraData == nil; got != archived {
+				t.Errorf("Leaf %d has extra data %q, archived: %v", i, leaf.ExtraData, archived)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,27 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crdb
+
+import (
+	"context"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+)
+
+// ListSignedLogRoots implements storage.LogRootLister.
+func (m *crdbLogStorage) ListSignedLogRoots(ctx context.Context, tree *trillian.Tree, after uint64, limit int) ([]*trillian.SignedLogRoot, error) {
+	return storage.ListSQLSignedLogRoots(ctx, m.db, tree.TreeId, after, limit, storage.DollarNumber)
+}
@@ -36,6 +36,8 @@ import (
 	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/storage/compression"
+	"github.com/google/trillian/storage/storagepb"
 	"github.com/google/trillian/storage/tree"
 	"github.com/google/trillian/types"
 )
@@ -52,7 +54,7 @@ const (
 		  AND TreeState IN($3,$4)
 		  AND (Deleted IS NULL OR Deleted = 'false')`
 
-	selectLatestSignedLogRootSQL = `SELECT TreeHeadTimestamp,TreeSize,RootHash,TreeRevision,RootSignature
+	selectLatestSignedLogRootSQL = `SELECT TreeHeadTimestamp,TreeSize,RootHash,TreeRevision
 			FROM TreeHead WHERE TreeId=$1
 			ORDER BY TreeHeadTimestamp DESC LIMIT 1`
 
@@ -84,6 +86,12 @@ const (
 	selectLeavesByMerkleHashOrderedBySequenceSQL = selectLeavesByMerkleHashSQL + orderBySequenceNumberSQL
 
 	logIDLabel = "logid"
+
+	followerReadResultLabel = "result"
+
+	// followerReadSQL makes a transaction read-only, and read the data as of a
+	// few seconds ago from the nearest replica, rather than the leaseholder.
+	followerReadSQL = "SET TRANSACTION AS OF SYSTEM TIME follower_read_timestamp()"
 )
 
 var (
@@ -100,6 +108,8 @@ var (
 	dequeueLatency          monitoring.Histogram
 	dequeueSelectLatency    monitoring.Histogram
 	dequeueRemoveLatency    monitoring.Histogram
+
+	followerReadSnapshotCounter monitoring.Counter
 )
 
 func createMetrics(mf monitoring.MetricFactory) {
@@ -116,6 +126,8 @@ func createMetrics(mf monitoring.MetricFactory) {
 	dequeueLatency = mf.NewHistogram("crdb_dequeue_leaves_latency", "Latency of dequeue leaves operation in seconds", logIDLabel)
 	dequeueSelectLatency = mf.NewHistogram("crdb_dequeue_leaves_latency_select", "Latency of selection part of dequeue leaves operation in seconds", logIDLabel)
 	dequeueRemoveLatency = mf.NewHistogram("crdb_dequeue_leaves_latency_remove", "Latency of removal part of dequeue leaves operation in seconds", logIDLabel)
+
+	followerReadSnapshotCounter = mf.NewCounter("crdb_follower_read_snapshots", "Number of snapshots attempted with follower reads, by result", logIDLabel, followerReadResultLabel)
 }
 
 func labelForTX(t *logTreeTX) string {
@@ -130,6 +142,13 @@ type crdbLogStorage struct {
 	*crdbTreeStorage
 	admin         storage.AdminStorage
 	metricFactory monitoring.MetricFactory
+	// followerReads makes snapshots use follower reads when they are recent
+	// enough.
+	followerReads bool
+	// sizes holds the largest root of each tree returned by SnapshotForTree
+	// when followerReads is set, so that a stale follower read doesn't serve an
+	// older root than a previous snapshot.
+	sizes storage.TreeSizeWatermark
 }
 
 // NewLogStorage creates a storage.LogStorage instance for the specified CockroachDB URL.
@@ -145,6 +164,19 @@ func NewLogStorage(db *sql.DB, mf monitoring.MetricFactory) storage.LogStorage {
 	}
 }
 
+// NewLogStorageWithFollowerReads creates a storage.LogStorage instance like
+// NewLogStorage, whose snapshots use follower reads. These are served by the
+// nearest replica, at the cost of being a few seconds stale. Snapshots fall
+// back to current reads if the tree wasn't initialized yet at that time, or
+// its latest root is smaller than the tree size set with
+// storage.WithMinTreeSize or than the root of a previous snapshot, so the
+// roots served never go backwards.
+func NewLogStorageWithFollowerReads(db *sql.DB, mf monitoring.MetricFactory) storage.LogStorage {
+	ls := NewLogStorage(db, mf).(*crdbLogStorage)
+	ls.followerReads = true
+	return ls
+}
+
 func (m *crdbLogStorage) CheckDatabaseAccessible(ctx context.Context) error {
 	return m.db.PingContext(ctx)
 }
@@ -184,20 +216,38 @@ func (m *crdbLogStorage) GetActiveLogIDs(ctx context.Context) ([]int64, error) {
 }
 
 func (m *crdbLogStorage) beginInternal(ctx context.Context, tree *trillian.Tree) (*logTreeTX, error) {
+	return m.begin(ctx, tree, false /* followerRead */)
+}
+
+// begin starts a transaction for the tree. If followerRead is true, the
+// transaction is read-only and uses a follower read.
+func (m *crdbLogStorage) begin(ctx context.Context, tree *trillian.Tree, followerRead bool) (*logTreeTX, error) {
 	once.Do(func() {
 		createMetrics(m.metricFactory)
+		compression.InitMetrics(m.metricFactory)
 	})
+	leafCompression, err := compression.Algorithm(tree)
+	if err != nil {
+		return nil, err
+	}
 
 	stCache := cache.NewLogSubtreeCache(rfc6962.DefaultHasher)
 	ttx, err := m.beginTreeTx(ctx, tree, rfc6962.DefaultHasher.Size(), stCache)
 	if err != nil && err != storage.ErrTreeNeedsInit {
 		return nil, err
 	}
+	if followerRead {
+		if _, err := ttx.tx.ExecContext(ctx, followerReadSQL); err != nil {
+			ttx.Close()
+			return nil, err
+		}
+	}
 
 	ltx := &logTreeTX{
-		treeTX:   ttx,
-		ls:       m,
-		dequeued: make(map[string]dequeuedLeaf),
+		treeTX:          ttx,
+		ls:              m,
+		dequeued:        make(map[string]dequeuedLeaf),
+		leafCompression: leafCompression,
 	}
 	ltx.slr, ltx.readRev, err = ltx.fetchLatestRoot(ctx)
 	if err == storage.ErrTreeNeedsInit {
@@ -227,6 +277,9 @@ func (m *crdbLogStorage) ReadWriteTransaction(ctx context.Context, tree *trillia
 		return err
 	}
 	defer tx.Close()
+	if err := storage.CheckMasterFence(ctx, tx.tx, storage.DollarNumber); err != nil {
+		return err
+	}
 	if err := f(ctx, tx); err != nil {
 		return err
 	}
@@ -255,13 +308,53 @@ func (m *crdbLogStorage) AddSequencedLeaves(ctx context.Context, tree *trillian.
 }
 
 func (m *crdbLogStorage) SnapshotForTree(ctx context.Context, tree *trillian.Tree) (storage.ReadOnlyLogTreeTX, error) {
+	if m.followerReads {
+		minTreeSize := storage.MinTreeSize(ctx)
+		if size := m.sizes.Get(tree.TreeId); size > minTreeSize {
+			minTreeSize = size
+		}
+		if tx := m.followerReadSnapshot(ctx, tree, minTreeSize); tx != nil {
+			m.sizes.Observe(tree.TreeId, tx.root.TreeSize)
+			tx.tiles = cache.DefaultTileCache()
+			return tx, nil
+		}
+	}
 	tx, err := m.beginInternal(ctx, tree)
 	if err != nil && err != storage.ErrTreeNeedsInit {
 		return nil, err
 	}
+	if m.followerReads && err == nil {
+		m.sizes.Observe(tree.TreeId, tx.root.TreeSize)
+	}
+	tx.tiles = cache.DefaultTileCache()
 	return tx, err
 }
 
+// followerReadSnapshot returns a snapshot of the tree using a follower read,
+// or nil if it can't serve the read, or its root is smaller than minTreeSize,
+// and a current one should be used instead.
+func (m *crdbLogStorage) followerReadSnapshot(ctx context.Context, tree *trillian.Tree, minTreeSize uint64) *logTreeTX {
+	label := strconv.FormatInt(tree.TreeId, 10)
+	tx, err := m.begin(ctx, tree, true /* followerRead */)
+	switch {
+	case err == storage.ErrTreeNeedsInit:
+		// The tree may have been initialized since.
+		tx.Close()
+		followerReadSnapshotCounter.Inc(label, "uninitialized")
+		return nil
+	case err != nil:
+		klog.Warningf("%v: failed to read snapshot with follower read, using current read: %v", tree.TreeId, err)
+		followerReadSnapshotCounter.Inc(label, "error")
+		return nil
+	case tx.root.TreeSize < minTreeSize:
+		tx.Close()
+		followerReadSnapshotCounter.Inc(label, "stale")
+		return nil
+	}
+	followerReadSnapshotCounter.Inc(label, "served")
+	return tx
+}
+
 func (m *crdbLogStorage) QueueLeaves(ctx context.Context, tree *trillian.Tree, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
 	tx, err := m.beginInternal(ctx, tree)
 	if tx != nil {
@@ -303,13 +396,23 @@ type logTreeTX struct {
 	readRev  int64
 	slr      *trillian.SignedLogRoot
 	dequeued map[string]dequeuedLeaf
+	// tiles, if set, is the process-wide cache of full tiles, which is
+	// shared by read-only snapshots.
+	tiles *cache.TileCache
+	// leafCompression is the algorithm which the leaves of the tree are
+	// compressed with.
+	leafCompression storagepb.LeafCompression
 }
 
 // GetMerkleNodes returns the requested nodes at the read revision.
 func (t *logTreeTX) GetMerkleNodes(ctx context.Context, ids []compact.NodeID) ([]tree.Node, error) {
 	t.treeTX.mu.Lock()
 	defer t.treeTX.mu.Unlock()
-	return t.subtreeCache.GetNodes(ids, t.getSubtreesAtRev(ctx, t.readRev))
+	getSubtrees := t.getSubtreesAtRev(ctx, t.readRev)
+	if t.tiles != nil {
+		getSubtrees = t.tiles.Wrap(t.treeID, t.readRev, getSubtrees)
+	}
+	return t.subtreeCache.GetNodes(ids, getSubtrees)
 }
 
 func (t *logTreeTX) DequeueLeaves(ctx context.Context, limit int, cutoffTime time.Time) ([]*trillian.LogLeaf, error) {
@@ -428,7 +531,11 @@ func (t *logTreeTX) QueueLeaves(ctx context.Context, leaves []*trillian.LogLeaf,
 			return nil, crdbToGRPC(err)
 		}
 
-		_, err := t.tx.ExecContext(ctx, insertLeafDataSQL, t.treeID, leaf.LeafIdentityHash, leaf.LeafValue, leaf.ExtraData, qTimestamp.UnixNano())
+		value, extra, err := compression.EncodeLeaf(t.treeID, t.leafCompression, leaf)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to compress leaves[%d]: %v", i, err)
+		}
+		_, err = t.tx.ExecContext(ctx, insertLeafDataSQL, t.treeID, leaf.LeafIdentityHash, value, extra, qTimestamp.UnixNano())
 		insertDuration := time.Since(leafStart)
 		observe(queueInsertLeafLatency, insertDuration, label)
 		if isDuplicateErr(err) {
@@ -560,8 +667,12 @@ func (t *logTreeTX) AddSequencedLeaves(ctx context.Context, leaves []*trillian.L
 		res[i] = &trillian.QueuedLogLeaf{Status: ok}
 
 		// TODO(pavelkalinnikov): Measure latencies.
-		_, err := t.tx.ExecContext(ctx, insertLeafDataSQL,
-			t.treeID, leaf.LeafIdentityHash, leaf.LeafValue, leaf.ExtraData, timestamp.UnixNano())
+		value, extra, err := compression.EncodeLeaf(t.treeID, t.leafCompression, leaf)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to compress leaves[%d]: %v", i, err)
+		}
+		_, err = t.tx.ExecContext(ctx, insertLeafDataSQL,
+			t.treeID, leaf.LeafIdentityHash, value, extra, timestamp.UnixNano())
 		// TODO(pavelkalinnikov): Detach PREORDERED_LOG integration latency metric.
 
 		// TODO(pavelkalinnikov): Support opting out from duplicates detection.
@@ -662,6 +773,9 @@ func (t *logTreeTX) getLeavesByRangeInternal(ctx context.Context, start, count i
 			}
 			break
 		}
+		if err := compression.DecodeLeaf(leaf); err != nil {
+			return nil, fmt.Errorf("failed to decode leaf %x: %v", leaf.LeafIdentityHash, err)
+		}
 		leaf.QueueTimestamp = timestamppb.New(time.Unix(0, qTimestamp))
 		if err := leaf.QueueTimestamp.CheckValid(); err != nil {
 			return nil, fmt.Errorf("got invalid queue timestamp: %w", err)
@@ -717,10 +831,10 @@ func (t *logTreeTX) LatestSignedLogRoot(ctx context.Context) (*trillian.SignedLo
 // fetchLatestRoot reads the latest root and the revision from the DB.
 func (t *logTreeTX) fetchLatestRoot(ctx context.Context) (*trillian.SignedLogRoot, int64, error) {
 	var timestamp, treeSize, treeRevision int64
-	var rootHash, rootSignatureBytes []byte
+	var rootHash []byte
 	if err := t.tx.QueryRowContext(
 		ctx, selectLatestSignedLogRootSQL, t.treeID).Scan(
-		&timestamp, &treeSize, &rootHash, &treeRevision, &rootSignatureBytes,
+		&timestamp, &treeSize, &rootHash, &treeRevision,
 	); err == sql.ErrNoRows {
 		// It's possible there are no roots for this tree yet
 		return nil, 0, storage.ErrTreeNeedsInit
@@ -759,8 +873,7 @@ func (t *logTreeTX) StoreSignedLogRoot(ctx context.Context, root *trillian.Signe
 		logRoot.TimestampNanos,
 		logRoot.TreeSize,
 		logRoot.RootHash,
-		t.treeTX.writeRevision,
-		[]byte{})
+		t.treeTX.writeRevision)
 	if err != nil {
 		klog.Warningf("Failed to store signed root: %s", err)
 	}
@@ -800,6 +913,9 @@ func (t *logTreeTX) getLeavesByHashInternal(ctx context.Context, leafHashes [][]
 			klog.Warningf("LogID: %d Scan() %s = %s", t.treeID, desc, err)
 			return nil, err
 		}
+		if err := compression.DecodeLeaf(leaf); err != nil {
+			return nil, fmt.Errorf("failed to decode leaf %x: %v", leaf.LeafIdentityHash, err)
+		}
 		leaf.QueueTimestamp = timestamppb.New(time.Unix(0, queueTS))
 		if err := leaf.QueueTimestamp.CheckValid(); err != nil {
 			return nil, fmt.Errorf("got invalid queue timestamp: %w", err)
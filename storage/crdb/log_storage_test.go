@@ -590,6 +590,31 @@ func TestLatestSignedRootNoneWritten(t *testing.T) {
 	commit(ctx, tx, t)
 }
 
+func TestSnapshotWithFollowerReads(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	handle := openTestDBOrDie(t)
+	as := NewSQLAdminStorage(handle.db)
+	tree := mustCreateTree(ctx, t, as, testonly.LogTree)
+	s := NewLogStorageWithFollowerReads(handle.db, nil)
+	mustSignAndStoreLogRoot(ctx, t, s, tree, 10)
+
+	// Follower reads lag behind by a few seconds, so the snapshot of the new
+	// tree should fall back to a current read.
+	for _, minTreeSize := range []uint64{0, 10} {
+		tx, err := s.SnapshotForTree(storage.WithMinTreeSize(ctx, minTreeSize), tree)
+		if err != nil {
+			t.Fatalf("SnapshotForTree(): %v", err)
+		}
+		if got, want := tx.(*logTreeTX).root.TreeSize, uint64(10); got != want {
+			t.Errorf("snapshot tree size = %d, want %d", got, want)
+		}
+		commit(ctx, tx, t)
+	}
+}
+
 func SignLogRoot(root *types.LogRootV1) (*trillian.SignedLogRoot, error) {
 	logRoot, err := root.MarshalBinary()
 	if err != nil {
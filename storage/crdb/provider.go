@@ -15,6 +15,7 @@
 package crdb
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"sync"
@@ -37,6 +38,8 @@ var (
 	maxConns = flag.Int("crdb_max_conns", 0, "Maximum connections to the database")
 	maxIdle  = flag.Int("crdb_max_idle_conns", -1, "Maximum idle database connections in the connection pool")
 
+	followerReads = flag.Bool("crdb_follower_reads", false, "If true, read-only log RPCs use follower reads when they have caught up with the requested tree size. Requires a CockroachDB version and license which support follower reads")
+
 	crdbErr             error
 	crdbHandle          *sql.DB
 	crdbStorageInstance *crdbProvider
@@ -104,9 +107,17 @@ func (p *crdbProvider) Close() error {
 }
 
 func (p *crdbProvider) LogStorage() storage.LogStorage {
+	if *followerReads {
+		return NewLogStorageWithFollowerReads(p.db, p.mf)
+	}
 	return NewLogStorage(p.db, p.mf)
 }
 
 func (p *crdbProvider) AdminStorage() storage.AdminStorage {
 	return NewSQLAdminStorage(p.db)
 }
+
+// CheckSchema implements storage.SchemaChecker.
+func (p *crdbProvider) CheckSchema(ctx context.Context) error {
+	return versionedSchema.Check(ctx, p.db)
+}
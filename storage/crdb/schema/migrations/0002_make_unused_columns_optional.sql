@@ -0,0 +1,6 @@
+-- The private keys of trees and the signatures of tree heads are no longer
+-- stored. Let binaries which still write them run alongside ones which don't,
+-- until the columns are dropped by the next migration.
+
+ALTER TABLE Trees ALTER COLUMN PrivateKey DROP NOT NULL;
+ALTER TABLE TreeHead ALTER COLUMN RootSignature DROP NOT NULL;
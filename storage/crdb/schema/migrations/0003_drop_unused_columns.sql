@@ -0,0 +1,5 @@
+-- Apply once no binaries which need schema version 1 are running.
+
+ALTER TABLE Trees DROP COLUMN IF EXISTS PrivateKey;
+ALTER TABLE TreeHead DROP COLUMN IF EXISTS RootSignature;
+DROP TABLE IF EXISTS TreeControl;
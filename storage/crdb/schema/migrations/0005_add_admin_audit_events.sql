@@ -0,0 +1,14 @@
+-- Adds the audit log of the changes made to trees. Binaries which record or
+-- list admin audit events need this version.
+
+CREATE TABLE IF NOT EXISTS AdminAuditEvents(
+  -- unique_rowid() values increase with time, though they're only roughly
+  -- ordered between nodes.
+  EventId              BIGINT NOT NULL DEFAULT unique_rowid(),
+  TreeId               BIGINT NOT NULL,
+  EventTimeMillis      BIGINT NOT NULL,
+  -- The serialized AdminAuditEvent.
+  Event                BYTES NOT NULL,
+  PRIMARY KEY(EventId),
+  INDEX AdminAuditEventsTreeIdx(TreeId, EventId)
+);
@@ -23,6 +23,7 @@ import (
 
 	"github.com/google/trillian"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/compression"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
@@ -30,9 +31,8 @@ import (
 )
 
 const (
-	defaultSequenceIntervalSeconds = 60
-
-	nonDeletedWhere = " WHERE (Deleted IS NULL OR Deleted = 'false')"
+	nonDeletedCond  = "(Deleted IS NULL OR Deleted = 'false')"
+	nonDeletedWhere = " WHERE " + nonDeletedCond
 
 	selectTrees = `
 		SELECT
@@ -46,18 +46,21 @@ const (
 			Description,
 			CreateTimeMillis,
 			UpdateTimeMillis,
-			PrivateKey,
 			PublicKey,
 			MaxRootDurationMillis,
 			Deleted,
-			DeleteTimeMillis
+			DeleteTimeMillis,
+			StorageSettings
 		FROM Trees`
 	selectNonDeletedTrees = selectTrees + nonDeletedWhere
 	selectTreeByID        = selectTrees + " WHERE TreeId = $1"
 
 	updateTreeSQL = `UPDATE Trees
-		SET TreeState = $1, TreeType = $2, DisplayName = $3, Description = $4, UpdateTimeMillis = $5, MaxRootDurationMillis = $6, PrivateKey = $7
+		SET TreeState = $1, TreeType = $2, DisplayName = $3, Description = $4, UpdateTimeMillis = $5, MaxRootDurationMillis = $6, StorageSettings = $7
 		WHERE TreeId = $8`
+
+	insertAuditEventSQL = `INSERT INTO AdminAuditEvents(TreeId, EventTimeMillis, Event)
+		VALUES($1, $2, $3) RETURNING EventId`
 )
 
 // NewSQLAdminStorage returns a SQL storage.AdminStorage implementation backed by DB.
@@ -143,6 +146,9 @@ func (t *adminTX) GetTree(ctx context.Context, treeID int64) (*trillian.Tree, er
 	case err != nil:
 		return nil, fmt.Errorf("error reading tree %v: %v", treeID, err)
 	}
+	if err := storage.ReadTreeLabels(ctx, t.tx, []*trillian.Tree{tree}, storage.DollarNumber); err != nil {
+		return nil, fmt.Errorf("error reading labels of tree %v: %v", treeID, err)
+	}
 	return tree, nil
 }
 
@@ -153,13 +159,23 @@ func (t *adminTX) ListTrees(ctx context.Context, includeDeleted bool) ([]*trilli
 	} else {
 		query = selectNonDeletedTrees
 	}
+	return t.readTrees(ctx, query)
+}
 
+// ListTreesFiltered implements storage.TreeLister.
+func (t *adminTX) ListTreesFiltered(ctx context.Context, opts storage.ListTreesOptions) ([]*trillian.Tree, error) {
+	query, args := storage.ListTreesSQL(selectTrees, nonDeletedCond, opts, storage.DollarNumber)
+	return t.readTrees(ctx, query, args...)
+}
+
+// readTrees returns the trees selected by query, with their labels.
+func (t *adminTX) readTrees(ctx context.Context, query string, args ...interface{}) ([]*trillian.Tree, error) {
 	stmt, err := t.tx.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
-	rows, err := stmt.QueryContext(ctx)
+	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -172,20 +188,39 @@ func (t *adminTX) ListTrees(ctx context.Context, includeDeleted bool) ([]*trilli
 		}
 		trees = append(trees, tree)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// The rows must be closed before the labels are read in the same
+	// transaction.
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := storage.ReadTreeLabels(ctx, t.tx, trees, storage.DollarNumber); err != nil {
+		return nil, err
+	}
 	return trees, nil
 }
 
 func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillian.Tree, error) {
+	id, err := storage.NewTreeID()
+	if err != nil {
+		return nil, err
+	}
+	return t.CreateTreeWithID(ctx, tree, id)
+}
+
+// CreateTreeWithID implements storage.TreeCreatorWithID.
+func (t *adminTX) CreateTreeWithID(ctx context.Context, tree *trillian.Tree, id int64) (*trillian.Tree, error) {
 	if err := storage.ValidateTreeForCreation(ctx, tree); err != nil {
 		return nil, err
 	}
-	if err := validateStorageSettings(tree); err != nil {
+	if err := compression.ValidateSettings(tree); err != nil {
 		return nil, err
 	}
-
-	id, err := storage.NewTreeID()
+	storageSettings, err := storage.MarshalStorageSettings(tree)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to marshal storage settings: %w", err)
 	}
 
 	// Use the time truncated-to-millis throughout, as that's what's stored.
@@ -220,9 +255,9 @@ func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillia
 			Description,
 			CreateTimeMillis,
 			UpdateTimeMillis,
-			PrivateKey,
 			PublicKey,
-			MaxRootDurationMillis)
+			MaxRootDurationMillis,
+			StorageSettings)
 		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`)
 	if err != nil {
 		return nil, err
@@ -242,12 +277,15 @@ func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillia
 		nowMillis,
 		nowMillis,
 		[]byte{}, // Unused, filling in for backward compatibility.
-		[]byte{}, // Unused, filling in for backward compatibility.
 		rootDuration/time.Millisecond,
+		storageSettings,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := storage.WriteTreeLabels(ctx, t.tx, newTree, storage.DollarNumber); err != nil {
+		return nil, err
+	}
 
 	// MySQL silently truncates data when running in non-strict mode.
 	// We shouldn't be using non-strict modes, but let's guard against it
@@ -258,29 +296,6 @@ func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillia
 		return nil, fmt.Errorf("enum truncated: %v", err)
 	}
 
-	insertControlStmt, err := t.tx.PrepareContext(
-		ctx,
-		`INSERT INTO TreeControl(
-			TreeId,
-			SigningEnabled,
-			SequencingEnabled,
-			SequenceIntervalSeconds)
-		VALUES($1, $2, $3, $4)`)
-	if err != nil {
-		return nil, err
-	}
-	defer insertControlStmt.Close()
-	_, err = insertControlStmt.ExecContext(
-		ctx,
-		newTree.TreeId,
-		true, /* SigningEnabled */
-		true, /* SequencingEnabled */
-		defaultSequenceIntervalSeconds,
-	)
-	if err != nil {
-		return nil, err
-	}
-
 	return newTree, nil
 }
 
@@ -295,7 +310,7 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 	if err := storage.ValidateTreeForUpdate(ctx, beforeUpdate, tree); err != nil {
 		return nil, err
 	}
-	if err := validateStorageSettings(tree); err != nil {
+	if err := compression.ValidateSettings(tree); err != nil {
 		return nil, err
 	}
 
@@ -313,6 +328,10 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 		return nil, fmt.Errorf("could not parse MaxRootDuration: %w", err)
 	}
 	rootDuration := tree.MaxRootDuration.AsDuration()
+	storageSettings, err := storage.MarshalStorageSettings(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal storage settings: %w", err)
+	}
 
 	stmt, err := t.tx.PrepareContext(ctx, updateTreeSQL)
 	if err != nil {
@@ -328,10 +347,13 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 		tree.Description,
 		nowMillis,
 		rootDuration/time.Millisecond,
-		[]byte{}, // Unused, filling in for backward compatibility.
+		storageSettings,
 		tree.TreeId); err != nil {
 		return nil, err
 	}
+	if err := storage.WriteTreeLabels(ctx, t.tx, tree, storage.DollarNumber); err != nil {
+		return nil, err
+	}
 
 	return tree, nil
 }
@@ -364,14 +386,29 @@ func (t *adminTX) HardDeleteTree(ctx context.Context, treeID int64) error {
 		return err
 	}
 
-	// TreeControl didn't have "ON DELETE CASCADE" on previous versions, so let's hit it explicitly
-	if _, err := t.tx.ExecContext(ctx, "DELETE FROM TreeControl WHERE TreeId = $1", treeID); err != nil {
-		return err
-	}
 	_, err := t.tx.ExecContext(ctx, "DELETE FROM Trees WHERE TreeId = $1", treeID)
 	return err
 }
 
+// ListAuditEvents implements storage.AuditLogReader.
+func (t *adminTX) ListAuditEvents(ctx context.Context, opts storage.ListAuditEventsOptions) ([]*trillian.AdminAuditEvent, error) {
+	return storage.QueryAuditEvents(ctx, t.tx, opts, storage.DollarNumber)
+}
+
+// AddAuditEvent implements storage.AuditLogWriter.
+func (t *adminTX) AddAuditEvent(ctx context.Context, event *trillian.AdminAuditEvent) error {
+	treeID, eventTimeMillis, data, err := storage.AuditEventRow(event)
+	if err != nil {
+		return err
+	}
+	var id int64
+	if err := t.tx.QueryRowContext(ctx, insertAuditEventSQL, treeID, eventTimeMillis, data).Scan(&id); err != nil {
+		return err
+	}
+	event.EventId = id
+	return nil
+}
+
 func validateDeleted(ctx context.Context, tx *sql.Tx, treeID int64, wantDeleted bool) error {
 	var nullDeleted sql.NullBool
 	switch err := tx.QueryRowContext(ctx, "SELECT Deleted FROM Trees WHERE TreeId = $1", treeID).Scan(&nullDeleted); {
@@ -389,10 +426,3 @@ func validateDeleted(ctx context.Context, tx *sql.Tx, treeID int64, wantDeleted
 	}
 	return nil
 }
-
-func validateStorageSettings(tree *trillian.Tree) error {
-	if tree.StorageSettings != nil {
-		return fmt.Errorf("storage_settings not supported, but got %v", tree.StorageSettings)
-	}
-	return nil
-}
@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/types"
+	"github.com/transparency-dev/merkle/compact"
+)
+
+func TestPruneSubtreeRevisions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	handle := openTestDBOrDie(t)
+	as := NewSQLAdminStorage(handle.db)
+	tree := mustCreateTree(ctx, t, as, testonly.LogTree)
+	s := NewLogStorage(handle.db, nil)
+
+	// Each tree head adds a node to the same subtree, and so writes a new
+	// revision of it. Tree head i has revision i.
+	const size = 5
+	nodes := createSomeNodes(size)
+	for i := 0; i <= size; i++ {
+		runLogTX(s, tree, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+			if i > 0 {
+				if err := tx.SetMerkleNodes(ctx, nodes[i-1:i]); err != nil {
+					return err
+				}
+			}
+			logRoot, err := (&types.LogRootV1{TreeSize: uint64(i), RootHash: []byte{byte(i)}, TimestampNanos: uint64(i+1) * 1000}).MarshalBinary()
+			if err != nil {
+				return err
+			}
+			return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: logRoot})
+		})
+	}
+
+	// The queries are tested by storage.TestPruneSQLSubtreeRevisions, so only
+	// check that they run against the schema of the database.
+	pruner := s.(storage.SubtreeRevisionPruner)
+	got, err := pruner.PruneSubtreeRevisions(ctx, tree.TreeId, time.Unix(0, 1e6))
+	if err != nil {
+		t.Fatalf("PruneSubtreeRevisions(): %v", err)
+	}
+	if want := int64(size - 1); got != want {
+		t.Errorf("PruneSubtreeRevisions() = %d, want %d", got, want)
+	}
+
+	var count int
+	if err := handle.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM Subtree WHERE TreeId = $1", tree.TreeId).Scan(&count); err != nil {
+		t.Fatalf("Failed to count subtrees: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Got %d subtree revisions, want 1", count)
+	}
+
+	// The latest tree head can still be read.
+	ids := make([]compact.NodeID, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.ID
+	}
+	runLogTX(s, tree, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		got, err := tx.GetMerkleNodes(ctx, ids)
+		if err != nil {
+			return err
+		}
+		if err := nodesAreEqual(got, nodes); err != nil {
+			t.Errorf("Read back different nodes: %v", err)
+		}
+		return nil
+	})
+}
@@ -38,8 +38,8 @@ const (
 	// NOTE(jaosorior): While using the `ON CONFLICT DO NOTHING` clause
 	// simplifies the StoreSignedLogRoot logic; it may lead to an
 	// unnintuitive error message when trying to insert a duplicate.
-	insertTreeHeadSQL = `INSERT INTO TreeHead(TreeId,TreeHeadTimestamp,TreeSize,RootHash,TreeRevision,RootSignature)
-		 VALUES($1,$2,$3,$4,$5,$6)
+	insertTreeHeadSQL = `INSERT INTO TreeHead(TreeId,TreeHeadTimestamp,TreeSize,RootHash,TreeRevision)
+		 VALUES($1,$2,$3,$4,$5)
 		 ON CONFLICT DO NOTHING`
 
 	selectSubtreeSQL = `
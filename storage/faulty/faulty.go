@@ -0,0 +1,257 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package faulty provides a storage.Provider which wraps another, and injects
+// faults into the calls to the methods of its LogTreeTX transactions, so that
+// tests can exercise the retry and recovery paths of the log server and
+// sequencer.
+//
+// The provider is registered as a wrapper, so the storage system
+// "faulty:<inner>" wraps the storage system named inner, with the faults
+// configured by the --faulty_storage_rules and --faulty_storage_seed flags.
+// Tests can also wrap providers directly with NewProvider.
+package faulty
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Kind is the kind of a fault.
+type Kind int
+
+const (
+	// Delay makes the method wait for the Delay of the fault before running
+	// as normal.
+	Delay Kind = iota
+	// Error makes the method return an error, without calling the underlying
+	// storage.
+	Error
+	// Abort makes the method run as normal, but then the transaction is rolled
+	// back, and ReadWriteTransaction returns an error, as it does when the
+	// database aborts a transaction.
+	Abort
+	// PartialCommit makes the method, and the later methods of the
+	// transaction which write, do nothing, while the changes made before are
+	// committed. ReadWriteTransaction then returns an error, although it
+	// committed, as it does when the connection to the database is lost
+	// during the commit.
+	PartialCommit
+)
+
+var kindNames = map[Kind]string{
+	Delay:         "delay",
+	Error:         "error",
+	Abort:         "abort",
+	PartialCommit: "partial",
+}
+
+func (k Kind) String() string {
+	if n, ok := kindNames[k]; ok {
+		return n
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Fault describes a fault to inject into a method call.
+//
+// The Abort and PartialCommit faults only apply to read-write transactions,
+// and are injected as Error faults into snapshots.
+type Fault struct {
+	Kind Kind
+	// Delay is waited for before the call, whatever the kind of the fault.
+	Delay time.Duration
+	// Err is the error returned for the fault. If not set, an error with the
+	// Unavailable code is returned for Error faults, one with the Aborted code
+	// for Abort faults, and one with the Unknown code for PartialCommit faults.
+	Err error
+}
+
+func (f Fault) err(method string) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	switch f.Kind {
+	case Abort:
+		return status.Errorf(codes.Aborted, "faulty: transaction aborted after %s", method)
+	case PartialCommit:
+		return status.Errorf(codes.Unknown, "faulty: transaction partially committed before %s", method)
+	default:
+		return status.Errorf(codes.Unavailable, "faulty: injected error in %s", method)
+	}
+}
+
+// Rule injects a fault into some of the calls to a LogTreeTX method.
+type Rule struct {
+	// Method is the name of the LogTreeTX method, e.g. "SetMerkleNodes", or
+	// "*" for all of them.
+	Method string
+	// Calls, if set, lists the numbers of the calls to the method which get
+	// the fault, counting from 1 for the first call since the Injector was
+	// created.
+	Calls []int
+	// Probability is the probability of each call getting the fault, if
+	// Calls is not set.
+	Probability float64
+	Fault       Fault
+}
+
+func (r Rule) matches(method string, call int, rnd *rand.Rand) bool {
+	if r.Method != "*" && r.Method != method {
+		return false
+	}
+	if len(r.Calls) > 0 {
+		for _, c := range r.Calls {
+			if c == call {
+				return true
+			}
+		}
+		return false
+	}
+	return rnd.Float64() < r.Probability
+}
+
+// Injector decides which method calls get which faults, following a list of
+// rules. The first rule which matches a call applies to it. Probabilistic
+// rules use a pseudo-random source with a fixed seed, so that the faults
+// follow the same schedule when the calls do.
+//
+// Injector is safe for concurrent use.
+type Injector struct {
+	mu       sync.Mutex
+	rules    []Rule
+	rnd      *rand.Rand
+	calls    map[string]int
+	injected map[string]int
+}
+
+// NewInjector returns an Injector following the given rules.
+func NewInjector(seed int64, rules ...Rule) *Injector {
+	return &Injector{
+		rules:    rules,
+		rnd:      rand.New(rand.NewSource(seed)),
+		calls:    make(map[string]int),
+		injected: make(map[string]int),
+	}
+}
+
+// next counts a call to the given method, and returns the fault to inject
+// into it, if any.
+func (i *Injector) next(method string) (Fault, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.calls[method]++
+	for _, r := range i.rules {
+		if r.matches(method, i.calls[method], i.rnd) {
+			i.injected[method]++
+			return r.Fault, true
+		}
+	}
+	return Fault{}, false
+}
+
+// Calls returns the number of calls made to the given method.
+func (i *Injector) Calls(method string) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.calls[method]
+}
+
+// Injected returns the number of faults injected into calls to the given
+// method.
+func (i *Injector) Injected(method string) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.injected[method]
+}
+
+// ParseRules parses rules from a semicolon-separated list, where each rule has
+// the form <method>:<fault>[@<when>]. The fault is one of "error", "abort",
+// "partial" or "delay", optionally followed by "/" and a duration to wait
+// for, which "delay" requires. The optional when part is either "p=" and the
+// probability of each call getting the fault, or a comma-separated list of the
+// call numbers which get it. Without it, all calls get the fault. For example:
+//
+//	SetMerkleNodes:error@2,5;StoreSignedLogRoot:abort@p=0.1;*:delay/50ms@p=0.5
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, s := range strings.Split(spec, ";") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		r, err := parseRule(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fault rule %q: %v", s, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func parseRule(s string) (Rule, error) {
+	r := Rule{Probability: 1}
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return r, fmt.Errorf("want <method>:<fault>[@<when>]")
+	}
+	r.Method = parts[0]
+	fault := parts[1]
+	if i := strings.Index(fault, "@"); i >= 0 {
+		when := fault[i+1:]
+		fault = fault[:i]
+		if strings.HasPrefix(when, "p=") {
+			p, err := strconv.ParseFloat(when[2:], 64)
+			if err != nil || p < 0 || p > 1 {
+				return r, fmt.Errorf("invalid probability %q", when[2:])
+			}
+			r.Probability = p
+		} else {
+			for _, c := range strings.Split(when, ",") {
+				n, err := strconv.Atoi(c)
+				if err != nil || n < 1 {
+					return r, fmt.Errorf("invalid call number %q", c)
+				}
+				r.Calls = append(r.Calls, n)
+			}
+		}
+	}
+	if i := strings.Index(fault, "/"); i >= 0 {
+		d, err := time.ParseDuration(fault[i+1:])
+		if err != nil {
+			return r, err
+		}
+		r.Fault.Delay = d
+		fault = fault[:i]
+	}
+	found := false
+	for k, n := range kindNames {
+		if n == fault {
+			r.Fault.Kind, found = k, true
+		}
+	}
+	if !found {
+		return r, fmt.Errorf("unknown fault %q", fault)
+	}
+	if r.Fault.Kind == Delay && r.Fault.Delay <= 0 {
+		return r, fmt.Errorf("delay needs a duration, e.g. delay/100ms")
+	}
+	return r, nil
+}
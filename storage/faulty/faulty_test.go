@@ -0,0 +1,129 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faulty
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestParseRules(t *testing.T) {
+	for _, tc := range []struct {
+		spec    string
+		want    []Rule
+		wantErr bool
+	}{
+		{spec: "", want: nil},
+		{
+			spec: "SetMerkleNodes:error@2,5; StoreSignedLogRoot:abort@p=0.1;*:delay/50ms@p=0.5",
+			want: []Rule{
+				{Method: "SetMerkleNodes", Calls: []int{2, 5}, Probability: 1, Fault: Fault{Kind: Error}},
+				{Method: "StoreSignedLogRoot", Probability: 0.1, Fault: Fault{Kind: Abort}},
+				{Method: "*", Probability: 0.5, Fault: Fault{Kind: Delay, Delay: 50 * time.Millisecond}},
+			},
+		},
+		{
+			spec: "DequeueLeaves:partial/1s",
+			want: []Rule{{Method: "DequeueLeaves", Probability: 1, Fault: Fault{Kind: PartialCommit, Delay: time.Second}}},
+		},
+		{spec: "SetMerkleNodes", wantErr: true},
+		{spec: ":error", wantErr: true},
+		{spec: "SetMerkleNodes:explode", wantErr: true},
+		{spec: "SetMerkleNodes:delay", wantErr: true},
+		{spec: "SetMerkleNodes:error/soon", wantErr: true},
+		{spec: "SetMerkleNodes:error@0", wantErr: true},
+		{spec: "SetMerkleNodes:error@p=2", wantErr: true},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			got, err := ParseRules(tc.spec)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("ParseRules() = %v, want error %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("ParseRules(): diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInjectorScripted(t *testing.T) {
+	inj := NewInjector(0,
+		Rule{Method: "SetMerkleNodes", Calls: []int{2, 4}, Fault: Fault{Kind: Error}},
+		Rule{Method: "*", Calls: []int{3}, Fault: Fault{Kind: Abort}},
+	)
+	var got []string
+	for i := 0; i < 5; i++ {
+		for _, m := range []string{"SetMerkleNodes", "DequeueLeaves"} {
+			if f, ok := inj.next(m); ok {
+				got = append(got, m+":"+f.Kind.String())
+			} else {
+				got = append(got, m+":-")
+			}
+		}
+	}
+	want := []string{
+		"SetMerkleNodes:-", "DequeueLeaves:-",
+		"SetMerkleNodes:error", "DequeueLeaves:-",
+		"SetMerkleNodes:abort", "DequeueLeaves:abort",
+		"SetMerkleNodes:error", "DequeueLeaves:-",
+		"SetMerkleNodes:-", "DequeueLeaves:-",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Faults: diff (-want +got):\n%s", diff)
+	}
+	if got, want := inj.Calls("SetMerkleNodes"), 5; got != want {
+		t.Errorf("Calls() = %d, want %d", got, want)
+	}
+	if got, want := inj.Injected("SetMerkleNodes"), 3; got != want {
+		t.Errorf("Injected() = %d, want %d", got, want)
+	}
+}
+
+func TestInjectorProbabilistic(t *testing.T) {
+	schedule := func(seed int64, p float64) []bool {
+		inj := NewInjector(seed, Rule{Method: "*", Probability: p})
+		var ret []bool
+		for i := 0; i < 100; i++ {
+			_, ok := inj.next("GetMerkleNodes")
+			ret = append(ret, ok)
+		}
+		return ret
+	}
+	count := func(s []bool) int {
+		n := 0
+		for _, b := range s {
+			if b {
+				n++
+			}
+		}
+		return n
+	}
+
+	if a, b := schedule(42, 0.5), schedule(42, 0.5); !cmp.Equal(a, b) {
+		t.Errorf("Schedules with the same seed differ: %v and %v", a, b)
+	}
+	if n := count(schedule(42, 0.5)); n < 25 || n > 75 {
+		t.Errorf("Got %d faults with probability 0.5, want about 50", n)
+	}
+	if n := count(schedule(42, 0)); n != 0 {
+		t.Errorf("Got %d faults with probability 0, want 0", n)
+	}
+	if n := count(schedule(42, 1)); n != 100 {
+		t.Errorf("Got %d faults with probability 1, want 100", n)
+	}
+}
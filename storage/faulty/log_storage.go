@@ -0,0 +1,196 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faulty
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/tree"
+	"github.com/transparency-dev/merkle/compact"
+)
+
+// NewLogStorage returns a LogStorage which injects the faults decided by inj
+// into the methods of the LogTreeTX transactions of ls.
+func NewLogStorage(ls storage.LogStorage, inj *Injector) storage.LogStorage {
+	l := &logStorage{LogStorage: ls, inj: inj}
+	if p, ok := ls.(storage.SubtreeRevisionPruner); ok {
+		return &prunableLogStorage{logStorage: l, SubtreeRevisionPruner: p}
+	}
+	return l
+}
+
+type logStorage struct {
+	storage.LogStorage
+	inj *Injector
+}
+
+// prunableLogStorage is a logStorage which keeps the SubtreeRevisionPruner
+// implementation of the underlying storage.
+type prunableLogStorage struct {
+	*logStorage
+	storage.SubtreeRevisionPruner
+}
+
+func (l *logStorage) SnapshotForTree(ctx context.Context, tree *trillian.Tree) (storage.ReadOnlyLogTreeTX, error) {
+	tx, err := l.LogStorage.SnapshotForTree(ctx, tree)
+	if tx != nil {
+		tx = &readOnlyLogTX{tx: tx, faults: &faults{inj: l.inj}}
+	}
+	return tx, err
+}
+
+func (l *logStorage) ReadWriteTransaction(ctx context.Context, tree *trillian.Tree, f storage.LogTXFunc) error {
+	var partial error
+	err := l.LogStorage.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		fs := &faults{inj: l.inj, readWrite: true}
+		partial = nil
+		if err := f(ctx, &logTX{readOnlyLogTX: readOnlyLogTX{tx: tx, faults: fs}, tx: tx}); err != nil {
+			return err
+		}
+		if fs.abort != nil {
+			return fs.abort
+		}
+		partial = fs.partial
+		return nil
+	})
+	if err == nil {
+		err = partial
+	}
+	return err
+}
+
+// Unwrap implements storage.LogStorageWrapper. No faults are injected into the
+// optional interfaces of the underlying storage.
+func (l *logStorage) Unwrap() storage.LogStorage {
+	return l.LogStorage
+}
+
+// faults injects faults into the methods of a transaction, and keeps track
+// of the state they leave it in.
+type faults struct {
+	inj       *Injector
+	readWrite bool
+	// abort, if set, is the error to roll back the transaction with.
+	abort error
+	// partial, if set, is the error to return after committing the changes
+	// made before it was set.
+	partial error
+}
+
+// inject injects the next fault into a call to the given method, if any. It
+// returns an error if the method must fail, and whether it must be skipped.
+func (f *faults) inject(ctx context.Context, method string, write bool) (bool, error) {
+	fault, ok := f.inj.next(method)
+	if ok && fault.Delay > 0 {
+		select {
+		case <-time.After(fault.Delay):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+	if ok {
+		switch {
+		case fault.Kind == Error, fault.Kind != Delay && !f.readWrite:
+			return false, fault.err(method)
+		case fault.Kind == Abort && f.abort == nil:
+			f.abort = fault.err(method)
+		case fault.Kind == PartialCommit && f.partial == nil:
+			f.partial = fault.err(method)
+		}
+	}
+	return write && f.partial != nil, nil
+}
+
+type readOnlyLogTX struct {
+	tx storage.ReadOnlyLogTreeTX
+	*faults
+}
+
+func (t *readOnlyLogTX) Commit(ctx context.Context) error {
+	if _, err := t.inject(ctx, "Commit", false); err != nil {
+		return err
+	}
+	return t.tx.Commit(ctx)
+}
+
+func (t *readOnlyLogTX) Close() error {
+	return t.tx.Close()
+}
+
+func (t *readOnlyLogTX) GetMerkleNodes(ctx context.Context, ids []compact.NodeID) ([]tree.Node, error) {
+	if _, err := t.inject(ctx, "GetMerkleNodes", false); err != nil {
+		return nil, err
+	}
+	return t.tx.GetMerkleNodes(ctx, ids)
+}
+
+func (t *readOnlyLogTX) GetLeavesByRange(ctx context.Context, start, count int64) ([]*trillian.LogLeaf, error) {
+	if _, err := t.inject(ctx, "GetLeavesByRange", false); err != nil {
+		return nil, err
+	}
+	return t.tx.GetLeavesByRange(ctx, start, count)
+}
+
+func (t *readOnlyLogTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, orderBySequence bool) ([]*trillian.LogLeaf, error) {
+	if _, err := t.inject(ctx, "GetLeavesByHash", false); err != nil {
+		return nil, err
+	}
+	return t.tx.GetLeavesByHash(ctx, leafHashes, orderBySequence)
+}
+
+func (t *readOnlyLogTX) LatestSignedLogRoot(ctx context.Context) (*trillian.SignedLogRoot, error) {
+	if _, err := t.inject(ctx, "LatestSignedLogRoot", false); err != nil {
+		return nil, err
+	}
+	return t.tx.LatestSignedLogRoot(ctx)
+}
+
+type logTX struct {
+	readOnlyLogTX
+	tx storage.LogTreeTX
+}
+
+func (t *logTX) SetMerkleNodes(ctx context.Context, nodes []tree.Node) error {
+	if skip, err := t.inject(ctx, "SetMerkleNodes", true); skip || err != nil {
+		return err
+	}
+	return t.tx.SetMerkleNodes(ctx, nodes)
+}
+
+func (t *logTX) StoreSignedLogRoot(ctx context.Context, root *trillian.SignedLogRoot) error {
+	if skip, err := t.inject(ctx, "StoreSignedLogRoot", true); skip || err != nil {
+		return err
+	}
+	return t.tx.StoreSignedLogRoot(ctx, root)
+}
+
+// DequeueLeaves returns no leaves when skipped, as it removes the leaves from
+// the queue of LOG trees.
+func (t *logTX) DequeueLeaves(ctx context.Context, limit int, cutoff time.Time) ([]*trillian.LogLeaf, error) {
+	if skip, err := t.inject(ctx, "DequeueLeaves", true); skip || err != nil {
+		return nil, err
+	}
+	return t.tx.DequeueLeaves(ctx, limit, cutoff)
+}
+
+func (t *logTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillian.LogLeaf) error {
+	if skip, err := t.inject(ctx, "UpdateSequencedLeaves", true); skip || err != nil {
+		return err
+	}
+	return t.tx.UpdateSequencedLeaves(ctx, leaves)
+}
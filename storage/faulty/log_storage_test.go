@@ -0,0 +1,181 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faulty
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/log"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/bolt"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/types"
+	"github.com/google/trillian/util/clock"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var fakeTime = time.Date(2016, 11, 10, 15, 16, 27, 0, time.UTC)
+
+func init() {
+	log.InitMetrics(nil)
+}
+
+// setup returns an initialized log tree with numLeaves queued leaves in bolt
+// storage, and the storage.
+func setup(ctx context.Context, t *testing.T, numLeaves int) (*trillian.Tree, storage.LogStorage) {
+	t.Helper()
+	db, err := bolt.OpenDB(filepath.Join(t.TempDir(), "trillian.bolt"))
+	if err != nil {
+		t.Fatalf("OpenDB(): %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ls := bolt.NewLogStorage(db, nil)
+	tree, err := storage.CreateTree(ctx, bolt.NewAdminStorage(db), testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	logRoot, err := (&types.LogRootV1{RootHash: rfc6962.DefaultHasher.EmptyRoot(), TimestampNanos: uint64(fakeTime.UnixNano())}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary(): %v", err)
+	}
+	if err := ls.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: logRoot})
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(init): %v", err)
+	}
+
+	leaves := make([]*trillian.LogLeaf, 0, numLeaves)
+	for i := 0; i < numLeaves; i++ {
+		data := []byte(fmt.Sprintf("leaf %d", i))
+		id := sha256.Sum256(data)
+		leaves = append(leaves, &trillian.LogLeaf{LeafValue: data, LeafIdentityHash: id[:], MerkleLeafHash: rfc6962.DefaultHasher.HashLeaf(data)})
+	}
+	if _, err := ls.QueueLeaves(ctx, tree, leaves, fakeTime); err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+	return tree, ls
+}
+
+func integrate(ctx context.Context, tree *trillian.Tree, ls storage.LogStorage) error {
+	_, err := log.IntegrateBatch(ctx, tree, 10, 0, 0, clock.NewFake(fakeTime.Add(time.Minute)), ls, quota.Noop())
+	return err
+}
+
+func treeSize(ctx context.Context, t *testing.T, tree *trillian.Tree, ls storage.LogStorage) uint64 {
+	t.Helper()
+	tx, err := ls.SnapshotForTree(ctx, tree)
+	if err != nil {
+		t.Fatalf("SnapshotForTree(): %v", err)
+	}
+	defer tx.Close()
+	slr, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil {
+		t.Fatalf("LatestSignedLogRoot(): %v", err)
+	}
+	var root types.LogRootV1
+	if err := root.UnmarshalBinary(slr.LogRoot); err != nil {
+		t.Fatalf("UnmarshalBinary(): %v", err)
+	}
+	return root.TreeSize
+}
+
+func TestFaults(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		desc    string
+		fault   Fault
+		method  string
+		wantErr bool
+		// wantSize is the tree size after the faulty integration.
+		wantSize uint64
+		// wantRetrySize is the tree size after integrating again.
+		wantRetrySize uint64
+	}{
+		{desc: "error", method: "SetMerkleNodes", fault: Fault{Kind: Error}, wantErr: true, wantSize: 0, wantRetrySize: 5},
+		{desc: "custom-error", method: "DequeueLeaves", fault: Fault{Kind: Error, Err: status.Error(codes.ResourceExhausted, "full")}, wantErr: true, wantSize: 0, wantRetrySize: 5},
+		{desc: "abort", method: "UpdateSequencedLeaves", fault: Fault{Kind: Abort}, wantErr: true, wantSize: 0, wantRetrySize: 5},
+		// The leaves are dequeued and sequenced, but the tree head isn't
+		// stored, so they are lost.
+		{desc: "partial", method: "StoreSignedLogRoot", fault: Fault{Kind: PartialCommit}, wantErr: true, wantSize: 0, wantRetrySize: 0},
+		{desc: "delay", method: "SetMerkleNodes", fault: Fault{Kind: Delay, Delay: 10 * time.Millisecond}, wantSize: 5, wantRetrySize: 5},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			tree, inner := setup(ctx, t, 5)
+			inj := NewInjector(0, Rule{Method: tc.method, Calls: []int{1}, Fault: tc.fault})
+			ls := NewLogStorage(inner, inj)
+
+			start := time.Now()
+			err := integrate(ctx, tree, ls)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("IntegrateBatch() = %v, want err: %v", err, tc.wantErr)
+			}
+			if got := time.Since(start); got < tc.fault.Delay {
+				t.Errorf("IntegrateBatch() took %v, want at least %v", got, tc.fault.Delay)
+			}
+			if got := treeSize(ctx, t, tree, inner); got != tc.wantSize {
+				t.Errorf("Tree size after fault: %d, want %d", got, tc.wantSize)
+			}
+			if got := inj.Injected(tc.method); got != 1 {
+				t.Errorf("Injected(%s) = %d, want 1", tc.method, got)
+			}
+
+			if err := integrate(ctx, tree, ls); err != nil {
+				t.Fatalf("IntegrateBatch(retry): %v", err)
+			}
+			if got := treeSize(ctx, t, tree, inner); got != tc.wantRetrySize {
+				t.Errorf("Tree size after retry: %d, want %d", got, tc.wantRetrySize)
+			}
+		})
+	}
+}
+
+func TestSnapshotFaults(t *testing.T) {
+	ctx := context.Background()
+	tree, inner := setup(ctx, t, 0)
+	ls := NewLogStorage(inner, NewInjector(0,
+		Rule{Method: "LatestSignedLogRoot", Calls: []int{1}, Fault: Fault{Kind: Abort}},
+		Rule{Method: "Commit", Calls: []int{2}, Fault: Fault{Kind: Error}},
+	))
+
+	for _, want := range []struct {
+		read, commit codes.Code
+	}{
+		// Aborts are injected as errors into snapshots.
+		{read: codes.Aborted, commit: codes.OK},
+		{read: codes.OK, commit: codes.Unavailable},
+		{read: codes.OK, commit: codes.OK},
+	} {
+		tx, err := ls.SnapshotForTree(ctx, tree)
+		if err != nil {
+			t.Fatalf("SnapshotForTree(): %v", err)
+		}
+		if _, err := tx.LatestSignedLogRoot(ctx); status.Code(err) != want.read {
+			t.Errorf("LatestSignedLogRoot() = %v, want code %v", err, want.read)
+		}
+		if err := tx.Commit(ctx); status.Code(err) != want.commit {
+			t.Errorf("Commit() = %v, want code %v", err, want.commit)
+		}
+		tx.Close()
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faulty
+
+import (
+	"flag"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"k8s.io/klog/v2"
+)
+
+var (
+	rulesFlag = flag.String("faulty_storage_rules", "", "Faults injected by the faulty:<inner> storage systems, as a semicolon-separated list of <method>:<fault>[@<when>] rules, e.g. SetMerkleNodes:error@2,5;StoreSignedLogRoot:abort@p=0.1;*:delay/50ms@p=0.5")
+	seedFlag  = flag.Int64("faulty_storage_seed", 0, "Seed of the probabilistic fault rules of the faulty:<inner> storage systems")
+)
+
+func init() {
+	if err := storage.RegisterWrapperProvider("faulty", newFaultyProvider); err != nil {
+		klog.Fatalf("Failed to register storage wrapper provider faulty: %v", err)
+	}
+}
+
+func newFaultyProvider(inner storage.Provider, _ monitoring.MetricFactory) (storage.Provider, error) {
+	rules, err := ParseRules(*rulesFlag)
+	if err != nil {
+		return nil, err
+	}
+	klog.Warningf("Injecting faults into storage, following %d rules", len(rules))
+	return NewProvider(inner, NewInjector(*seedFlag, rules...)), nil
+}
+
+// NewProvider returns a Provider whose LogStorage injects the faults decided
+// by inj into the transactions of p. Its AdminStorage is that of p.
+func NewProvider(p storage.Provider, inj *Injector) storage.Provider {
+	return &provider{Provider: p, inj: inj}
+}
+
+type provider struct {
+	storage.Provider
+	inj *Injector
+}
+
+func (p *provider) LogStorage() storage.LogStorage {
+	return NewLogStorage(p.Provider.LogStorage(), p.inj)
+}
@@ -0,0 +1,543 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fsck checks the integrity of log trees in storage, end to end.
+//
+// A check reads all the leaves of a log up to its latest tree head, recomputes
+// their Merkle leaf hashes from their values, and rebuilds the Merkle tree from
+// the leaf hashes with a compact range. Every node of the rebuilt tree is then
+// compared with the node stored in the tiles of the log, and the rebuilt root
+// at the size of each stored tree head with its root hash. Storage which
+// doesn't implement storage.LogRootLister only has its latest tree head
+// checked.
+//
+// Only the generic storage interfaces are used, so any storage system can be
+// checked. Live logs can be checked, as the leaves and nodes below a tree head
+// never change, but repairs should only be made to frozen logs.
+package fsck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/storage/tree"
+	"github.com/google/trillian/types"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultBatchSize is the default number of leaves read in each
+	// transaction.
+	DefaultBatchSize = 1000
+
+	// headPageSize is the number of tree heads listed at a time.
+	headPageSize = 1000
+)
+
+// Options configures a check.
+type Options struct {
+	// BatchSize is the number of leaves read in each transaction.
+	BatchSize int
+
+	// MaxProblems is the maximum number of problems recorded in the Report.
+	// Further problems are only counted. Zero means no limit.
+	MaxProblems int
+
+	// Repair rewrites the stored nodes found to be missing or wrong, if the
+	// rebuilt tree matches the root hash of the latest tree head, which shows
+	// that the leaf hashes are right and the nodes are not.
+	Repair bool
+
+	// RewriteRoot allows a repair to store a copy of the latest tree head
+	// with a fresh timestamp, as storage only reads the rewritten nodes at
+	// the revision of a tree head written with them. Clients see it as a new
+	// signed tree head of the same size and root hash. Repairs fail without
+	// it.
+	RewriteRoot bool
+}
+
+func (o Options) withDefaults() Options {
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultBatchSize
+	}
+	return o
+}
+
+// ProblemKind is the kind of a Problem found by a check.
+type ProblemKind int
+
+const (
+	// MissingLeaf is a leaf below the tree size which can't be read.
+	MissingLeaf ProblemKind = iota
+	// LeafHashMismatch is a leaf whose value doesn't hash to its Merkle leaf
+	// hash.
+	LeafHashMismatch
+	// MissingNode is a node of the tree which isn't stored.
+	MissingNode
+	// NodeMismatch is a stored node which differs from the rebuilt tree.
+	NodeMismatch
+	// RootMismatch is a tree head whose root hash differs from the rebuilt
+	// tree.
+	RootMismatch
+	// RootSizeDecrease is a tree head with a smaller tree size than an
+	// earlier one, which can't be checked.
+	RootSizeDecrease
+)
+
+var problemKindNames = map[ProblemKind]string{
+	MissingLeaf:      "missing leaf",
+	LeafHashMismatch: "leaf hash mismatch",
+	MissingNode:      "missing node",
+	NodeMismatch:     "node mismatch",
+	RootMismatch:     "root mismatch",
+	RootSizeDecrease: "root size decrease",
+}
+
+func (k ProblemKind) String() string {
+	if name, ok := problemKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("ProblemKind(%d)", k)
+}
+
+// Problem is an inconsistency found by a check.
+type Problem struct {
+	Kind ProblemKind
+	// LeafIndex is the index of the leaf, for leaf problems.
+	LeafIndex int64
+	// Node is the ID of the node, for node problems.
+	Node compact.NodeID
+	// Root is the tree head, for tree head problems.
+	Root *types.LogRootV1
+	// Stored is the hash in storage, if any, and Computed the recomputed one.
+	Stored, Computed []byte
+}
+
+func (p Problem) String() string {
+	switch p.Kind {
+	case MissingLeaf:
+		return fmt.Sprintf("%v %d", p.Kind, p.LeafIndex)
+	case LeafHashMismatch:
+		return fmt.Sprintf("%v at leaf %d: stored %x, value hashes to %x", p.Kind, p.LeafIndex, p.Stored, p.Computed)
+	case MissingNode:
+		return fmt.Sprintf("%v %d.%d: want %x", p.Kind, p.Node.Level, p.Node.Index, p.Computed)
+	case NodeMismatch:
+		return fmt.Sprintf("%v at node %d.%d: stored %x, want %x", p.Kind, p.Node.Level, p.Node.Index, p.Stored, p.Computed)
+	case RootMismatch:
+		return fmt.Sprintf("%v at tree head of size %d and timestamp %d: stored %x, want %x", p.Kind, p.Root.TreeSize, p.Root.TimestampNanos, p.Stored, p.Computed)
+	case RootSizeDecrease:
+		return fmt.Sprintf("%v to %d at tree head with timestamp %d", p.Kind, p.Root.TreeSize, p.Root.TimestampNanos)
+	default:
+		return fmt.Sprintf("%v: stored %x, want %x", p.Kind, p.Stored, p.Computed)
+	}
+}
+
+// Report is the result of checking a tree.
+type Report struct {
+	TreeID int64
+	// Root is the latest tree head, up to which the tree was checked.
+	Root types.LogRootV1
+	// Leaves, Nodes and Heads are the numbers of leaves, nodes and tree heads
+	// checked.
+	Leaves, Nodes, Heads int64
+	// Problems holds the problems found, up to Options.MaxProblems.
+	Problems []Problem
+	// ProblemCount is the number of problems found, including those beyond
+	// Options.MaxProblems.
+	ProblemCount int64
+	// Repaired is the number of nodes rewritten by a repair.
+	Repaired int
+
+	maxProblems int
+	// repairs holds the rebuilt nodes which are missing or wrong in storage.
+	repairs []tree.Node
+	// rootOK is whether the rebuilt tree matched the latest tree head.
+	rootOK bool
+}
+
+// OK returns whether no problems were found.
+func (r *Report) OK() bool {
+	return r.ProblemCount == 0
+}
+
+func (r *Report) add(p Problem) {
+	r.ProblemCount++
+	if r.maxProblems <= 0 || len(r.Problems) < r.maxProblems {
+		r.Problems = append(r.Problems, p)
+	}
+}
+
+// Check checks the given log tree in ls, and repairs its nodes if requested.
+// It returns an error if the tree can't be read, or if the check can't
+// proceed, together with the problems found so far.
+func Check(ctx context.Context, ls storage.LogStorage, t *trillian.Tree, opts Options) (*Report, error) {
+	opts = opts.withDefaults()
+	r := &Report{TreeID: t.TreeId, maxProblems: opts.MaxProblems}
+	root, err := latestRoot(ctx, ls, t)
+	if err != nil {
+		return r, err
+	}
+	r.Root = *root
+
+	c := &checker{
+		ls:     ls,
+		tree:   t,
+		report: r,
+		heads:  &headLister{ls: ls, tree: t, latest: root.TimestampNanos},
+	}
+	if err := c.check(ctx, opts.BatchSize); err != nil {
+		return r, err
+	}
+	if opts.Repair && len(r.repairs) > 0 {
+		if !r.rootOK {
+			return r, errors.New("not repairing nodes: the leaf hashes don't match the tree head")
+		}
+		if !opts.RewriteRoot {
+			return r, errors.New("not repairing nodes: a repair stores a new tree head, which must be allowed with RewriteRoot")
+		}
+		if err := repair(ctx, ls, t, root, r.repairs); err != nil {
+			return r, fmt.Errorf("failed to repair nodes: %v", err)
+		}
+		r.Repaired = len(r.repairs)
+	}
+	return r, nil
+}
+
+type checker struct {
+	ls     storage.LogStorage
+	tree   *trillian.Tree
+	report *Report
+	// heads lists the tree heads before the latest one.
+	heads *headLister
+}
+
+func (c *checker) check(ctx context.Context, batchSize int) error {
+	fact := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	cr := fact.NewEmptyRange(0)
+	size := c.report.Root.TreeSize
+	if err := c.checkHeads(ctx, cr); err != nil {
+		return err
+	}
+	for start := uint64(0); start < size; start += uint64(batchSize) {
+		end := start + uint64(batchSize)
+		if end > size {
+			end = size
+		}
+		if err := c.checkBatch(ctx, cr, start, end); err != nil {
+			return err
+		}
+	}
+
+	// The earlier tree heads left are larger than the latest one.
+	for {
+		head, err := c.heads.next(ctx)
+		if err != nil {
+			return err
+		} else if head == nil {
+			break
+		}
+		c.report.Heads++
+		c.report.add(Problem{Kind: RootSizeDecrease, Root: head})
+	}
+
+	c.report.Heads++
+	hash, err := rootHash(cr)
+	if err != nil {
+		return err
+	}
+	if want := c.report.Root.RootHash; !bytes.Equal(hash, want) {
+		c.report.add(Problem{Kind: RootMismatch, Root: &c.report.Root, Stored: want, Computed: hash})
+		return nil
+	}
+	c.report.rootOK = true
+	return nil
+}
+
+// checkHeads checks the root hashes of the earlier tree heads at the size of
+// cr, and reports those with a smaller size, which were skipped.
+func (c *checker) checkHeads(ctx context.Context, cr *compact.Range) error {
+	for {
+		head, err := c.heads.peek(ctx)
+		if err != nil || head == nil || head.TreeSize > cr.End() {
+			return err
+		}
+		c.heads.pop()
+		c.report.Heads++
+		if head.TreeSize < cr.End() {
+			c.report.add(Problem{Kind: RootSizeDecrease, Root: head})
+			continue
+		}
+		hash, err := rootHash(cr)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(hash, head.RootHash) {
+			c.report.add(Problem{Kind: RootMismatch, Root: head, Stored: head.RootHash, Computed: hash})
+		}
+	}
+}
+
+// checkBatch checks the leaves in [start, end), appends them to cr, and checks
+// the nodes this completes.
+func (c *checker) checkBatch(ctx context.Context, cr *compact.Range, start, end uint64) error {
+	tx, err := c.ls.SnapshotForTree(ctx, c.tree)
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	leaves, err := readLeaves(ctx, tx, start, end)
+	if err != nil {
+		return err
+	}
+	// The hashes of missing leaves are taken from the stored tiles, if
+	// possible, so that the rest of the tree can still be checked.
+	var missing []compact.NodeID
+	for i := start; i < end; i++ {
+		if leaves[i-start] == nil {
+			c.report.add(Problem{Kind: MissingLeaf, LeafIndex: int64(i)})
+			missing = append(missing, compact.NewNodeID(0, i))
+		}
+	}
+	stored, err := readNodes(ctx, tx, missing)
+	if err != nil {
+		return err
+	}
+
+	var nodes []tree.Node
+	visit := func(id compact.NodeID, hash []byte) {
+		nodes = append(nodes, tree.Node{ID: id, Hash: hash})
+	}
+	for i := start; i < end; i++ {
+		var hash []byte
+		if leaf := leaves[i-start]; leaf != nil {
+			hash = leaf.MerkleLeafHash
+			if got := rfc6962.DefaultHasher.HashLeaf(leaf.LeafValue); !bytes.Equal(got, hash) {
+				c.report.add(Problem{Kind: LeafHashMismatch, LeafIndex: int64(i), Stored: hash, Computed: got})
+			}
+		} else if hash = stored[compact.NewNodeID(0, i)]; hash == nil {
+			return fmt.Errorf("can't rebuild the tree past missing leaf %d, which has no stored hash", i)
+		}
+		if err := cr.Append(hash, visit); err != nil {
+			return err
+		}
+		c.report.Leaves++
+		if err := c.checkHeads(ctx, cr); err != nil {
+			return err
+		}
+	}
+
+	ids := make([]compact.NodeID, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.ID
+	}
+	if stored, err = readNodes(ctx, tx, ids); err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		got, ok := stored[n.ID]
+		if !ok {
+			c.report.add(Problem{Kind: MissingNode, Node: n.ID, Computed: n.Hash})
+		} else if !bytes.Equal(got, n.Hash) {
+			c.report.add(Problem{Kind: NodeMismatch, Node: n.ID, Stored: got, Computed: n.Hash})
+		} else {
+			continue
+		}
+		c.report.repairs = append(c.report.repairs, n)
+	}
+	c.report.Nodes += int64(len(nodes))
+	return tx.Commit(ctx)
+}
+
+// rootHash returns the root hash of the tree with the leaves in cr, which
+// must start at zero.
+func rootHash(cr *compact.Range) ([]byte, error) {
+	if cr.End() == 0 {
+		return rfc6962.DefaultHasher.EmptyRoot(), nil
+	}
+	hash, err := cr.GetRootHash(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute the root hash: %v", err)
+	}
+	return hash, nil
+}
+
+// headLister lists the tree heads of a tree before the latest one, oldest
+// first, a page at a time. It lists none if the storage doesn't support it.
+type headLister struct {
+	ls   storage.LogStorage
+	tree *trillian.Tree
+	// latest is the timestamp of the latest tree head, which is checked
+	// separately.
+	latest uint64
+
+	after uint64
+	page  []*types.LogRootV1
+	done  bool
+}
+
+// peek returns the next tree head, or nil if there are no more.
+func (h *headLister) peek(ctx context.Context) (*types.LogRootV1, error) {
+	if len(h.page) == 0 && !h.done {
+		slrs, err := storage.ListSignedLogRoots(ctx, h.ls, h.tree, h.after, headPageSize)
+		if status.Code(err) == codes.Unimplemented {
+			h.done = true
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to list tree heads: %v", err)
+		}
+		h.done = len(slrs) < headPageSize
+		for _, slr := range slrs {
+			var root types.LogRootV1
+			if err := root.UnmarshalBinary(slr.LogRoot); err != nil {
+				return nil, fmt.Errorf("failed to parse log root: %v", err)
+			}
+			if root.TimestampNanos >= h.latest {
+				h.done = true
+				break
+			}
+			h.page = append(h.page, &root)
+			h.after = root.TimestampNanos
+		}
+	}
+	if len(h.page) == 0 {
+		return nil, nil
+	}
+	return h.page[0], nil
+}
+
+func (h *headLister) pop() {
+	h.page = h.page[1:]
+}
+
+// next returns the next tree head and moves past it, or returns nil if there
+// are no more.
+func (h *headLister) next(ctx context.Context) (*types.LogRootV1, error) {
+	head, err := h.peek(ctx)
+	if head != nil {
+		h.pop()
+	}
+	return head, err
+}
+
+// readLeaves returns the leaves in [start, end), by offset from start, with
+// nil for the missing ones. Leaves are read one by one if they can't be read
+// together, as storage may fail to read a range of leaves with gaps in it.
+func readLeaves(ctx context.Context, tx storage.ReadOnlyLogTreeTX, start, end uint64) ([]*trillian.LogLeaf, error) {
+	ret := make([]*trillian.LogLeaf, end-start)
+	leaves, err := tx.GetLeavesByRange(ctx, int64(start), int64(end-start))
+	if err != nil {
+		leaves = nil
+		for i := start; i < end; i++ {
+			leaf, err := tx.GetLeavesByRange(ctx, int64(i), 1)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read leaf %d: %v", i, err)
+			}
+			leaves = append(leaves, leaf...)
+		}
+	}
+	for _, leaf := range leaves {
+		if idx := uint64(leaf.LeafIndex); idx >= start && idx < end {
+			ret[idx-start] = leaf
+		}
+	}
+	return ret, nil
+}
+
+// readNodes returns the stored hashes of the given nodes, if any. Nodes are
+// read one by one if they can't be read together, as storage may fail to read
+// a batch of nodes when some of their tiles are missing.
+func readNodes(ctx context.Context, tx storage.ReadOnlyLogTreeTX, ids []compact.NodeID) (map[compact.NodeID][]byte, error) {
+	ret := make(map[compact.NodeID][]byte, len(ids))
+	if len(ids) == 0 {
+		return ret, nil
+	}
+	nodes, err := tx.GetMerkleNodes(ctx, ids)
+	if err != nil {
+		for _, id := range ids {
+			nodes, err := tx.GetMerkleNodes(ctx, []compact.NodeID{id})
+			if err != nil {
+				continue
+			}
+			for _, n := range nodes {
+				ret[n.ID] = n.Hash
+			}
+		}
+		return ret, nil
+	}
+	for _, n := range nodes {
+		ret[n.ID] = n.Hash
+	}
+	return ret, nil
+}
+
+func latestRoot(ctx context.Context, ls storage.LogStorage, t *trillian.Tree) (*types.LogRootV1, error) {
+	tx, err := ls.SnapshotForTree(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+	slr, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var root types.LogRootV1
+	if err := root.UnmarshalBinary(slr.LogRoot); err != nil {
+		return nil, fmt.Errorf("failed to parse log root: %v", err)
+	}
+	return &root, tx.Commit(ctx)
+}
+
+// repair writes the given nodes, and a new tree head identical to root but for
+// its timestamp, so that the nodes are read at its revision. The tiles of the
+// tree cached by this process are dropped, as they may hold the wrong nodes.
+func repair(ctx context.Context, ls storage.LogStorage, t *trillian.Tree, root *types.LogRootV1, nodes []tree.Node) error {
+	if tc := cache.DefaultTileCache(); tc != nil {
+		defer tc.InvalidateTree(t.TreeId)
+	}
+	return ls.ReadWriteTransaction(ctx, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		slr, err := tx.LatestSignedLogRoot(ctx)
+		if err != nil {
+			return err
+		}
+		var latest types.LogRootV1
+		if err := latest.UnmarshalBinary(slr.LogRoot); err != nil {
+			return fmt.Errorf("failed to parse log root: %v", err)
+		}
+		if latest.TreeSize != root.TreeSize || !bytes.Equal(latest.RootHash, root.RootHash) {
+			return fmt.Errorf("tree head changed from size %d to %d since the check", root.TreeSize, latest.TreeSize)
+		}
+		if err := tx.SetMerkleNodes(ctx, nodes); err != nil {
+			return err
+		}
+		ts := uint64(time.Now().UnixNano())
+		if ts <= latest.TimestampNanos {
+			ts = latest.TimestampNanos + 1
+		}
+		newRoot, err := (&types.LogRootV1{TreeSize: latest.TreeSize, RootHash: latest.RootHash, TimestampNanos: ts, Metadata: latest.Metadata}).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: newRoot})
+	})
+}
@@ -0,0 +1,329 @@
+//go:build cgo
+// +build cgo
+
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsck
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/log"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/storage/sqlite"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/storage/tree"
+	"github.com/google/trillian/types"
+	"github.com/google/trillian/util/clock"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+)
+
+var fakeTime = time.Date(2016, 11, 10, 15, 16, 27, 0, time.UTC)
+
+func init() {
+	log.InitMetrics(nil)
+}
+
+func testLeaf(i int) *trillian.LogLeaf {
+	data := []byte(fmt.Sprintf("leaf %d", i))
+	id := sha256.Sum256(data)
+	return &trillian.LogLeaf{
+		LeafValue:        data,
+		LeafIdentityHash: id[:],
+		MerkleLeafHash:   rfc6962.DefaultHasher.HashLeaf(data),
+	}
+}
+
+// setup returns a log tree with numLeaves integrated leaves in SQLite storage,
+// the database, and the log storage.
+func setup(ctx context.Context, t *testing.T, numLeaves int) (*trillian.Tree, *sql.DB, storage.LogStorage) {
+	t.Helper()
+	db, err := sqlite.OpenDB(filepath.Join(t.TempDir(), "trillian.db"))
+	if err != nil {
+		t.Fatalf("OpenDB(): %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ls := sqlite.NewLogStorage(db, nil)
+	tree, err := storage.CreateTree(ctx, sqlite.NewAdminStorage(db), testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	storeRoot(ctx, t, ls, tree, &types.LogRootV1{RootHash: rfc6962.DefaultHasher.EmptyRoot(), TimestampNanos: uint64(fakeTime.UnixNano())}, nil)
+
+	if numLeaves == 0 {
+		return tree, db, ls
+	}
+	leaves := make([]*trillian.LogLeaf, 0, numLeaves)
+	for i := 0; i < numLeaves; i++ {
+		leaves = append(leaves, testLeaf(i))
+	}
+	if _, err := ls.QueueLeaves(ctx, tree, leaves, fakeTime); err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+	if _, err := log.IntegrateBatch(ctx, tree, numLeaves, 0, 0, clock.NewFake(fakeTime.Add(time.Minute)), ls, quota.Noop()); err != nil {
+		t.Fatalf("IntegrateBatch(): %v", err)
+	}
+	return tree, db, ls
+}
+
+// storeRoot stores the given log root, and nodes, in a new revision.
+func storeRoot(ctx context.Context, t *testing.T, ls storage.LogStorage, tr *trillian.Tree, root *types.LogRootV1, nodes []tree.Node) {
+	t.Helper()
+	logRoot, err := root.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary(): %v", err)
+	}
+	if err := ls.ReadWriteTransaction(ctx, tr, func(ctx context.Context, tx storage.LogTreeTX) error {
+		if len(nodes) > 0 {
+			if err := tx.SetMerkleNodes(ctx, nodes); err != nil {
+				return err
+			}
+		}
+		return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: logRoot})
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(): %v", err)
+	}
+}
+
+// bumpRoot stores a copy of the latest log root with a later timestamp and
+// the given hash, together with the given nodes.
+func bumpRoot(ctx context.Context, t *testing.T, ls storage.LogStorage, tr *trillian.Tree, hash []byte, nodes []tree.Node) {
+	t.Helper()
+	root, err := latestRoot(ctx, ls, tr)
+	if err != nil {
+		t.Fatalf("latestRoot(): %v", err)
+	}
+	root.TimestampNanos++
+	if hash != nil {
+		root.RootHash = hash
+	}
+	storeRoot(ctx, t, ls, tr, root, nodes)
+}
+
+func kinds(r *Report) []ProblemKind {
+	ret := make([]ProblemKind, 0, len(r.Problems))
+	for _, p := range r.Problems {
+		ret = append(ret, p.Kind)
+	}
+	return ret
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		desc      string
+		numLeaves int
+		batchSize int
+	}{
+		{desc: "empty", numLeaves: 0, batchSize: 10},
+		{desc: "one-batch", numLeaves: 37, batchSize: 100},
+		{desc: "batches", numLeaves: 37, batchSize: 10},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			tr, _, ls := setup(ctx, t, tc.numLeaves)
+			r, err := Check(ctx, ls, tr, Options{BatchSize: tc.batchSize})
+			if err != nil {
+				t.Fatalf("Check(): %v", err)
+			}
+			if !r.OK() {
+				t.Errorf("Check() found problems: %v", r.Problems)
+			}
+			if got, want := r.Leaves, int64(tc.numLeaves); got != want {
+				t.Errorf("Check() checked %d leaves, want %d", got, want)
+			}
+			if got, want := r.Root.TreeSize, uint64(tc.numLeaves); got != want {
+				t.Errorf("Check() root size %d, want %d", got, want)
+			}
+			// The empty tree head, and the one from the integration.
+			wantHeads := int64(2)
+			if tc.numLeaves == 0 {
+				wantHeads = 1
+			}
+			if got := r.Heads; got != wantHeads {
+				t.Errorf("Check() checked %d tree heads, want %d", got, wantHeads)
+			}
+		})
+	}
+}
+
+func TestCheckLeafHashMismatch(t *testing.T) {
+	ctx := context.Background()
+	tr, db, ls := setup(ctx, t, 20)
+	if _, err := db.ExecContext(ctx, "UPDATE LeafData SET LeafValue = ? WHERE TreeId = ? AND LeafIdentityHash = ?", []byte("corrupt"), tr.TreeId, testLeaf(7).LeafIdentityHash); err != nil {
+		t.Fatalf("Failed to corrupt leaf: %v", err)
+	}
+	r, err := Check(ctx, ls, tr, Options{BatchSize: 8})
+	if err != nil {
+		t.Fatalf("Check(): %v", err)
+	}
+	if got := kinds(r); len(got) != 1 || got[0] != LeafHashMismatch {
+		t.Errorf("Check() found %v, want [%v]", r.Problems, LeafHashMismatch)
+	}
+	// The leaf hashes are intact, so the tree itself is.
+	if !r.rootOK {
+		t.Error("Check() found the root inconsistent")
+	}
+}
+
+func TestCheckMissingLeaf(t *testing.T) {
+	ctx := context.Background()
+	tr, db, ls := setup(ctx, t, 20)
+	if _, err := db.ExecContext(ctx, "DELETE FROM SequencedLeafData WHERE TreeId = ? AND SequenceNumber = ?", tr.TreeId, 5); err != nil {
+		t.Fatalf("Failed to delete leaf: %v", err)
+	}
+	r, err := Check(ctx, ls, tr, Options{BatchSize: 8})
+	if err != nil {
+		t.Fatalf("Check(): %v", err)
+	}
+	if got := r.Problems; len(got) != 1 || got[0].Kind != MissingLeaf || got[0].LeafIndex != 5 {
+		t.Errorf("Check() found %v, want missing leaf 5", got)
+	}
+	if got, want := r.Leaves, int64(20); got != want {
+		t.Errorf("Check() checked %d leaves, want %d", got, want)
+	}
+}
+
+func TestCheckRootMismatch(t *testing.T) {
+	ctx := context.Background()
+	tr, _, ls := setup(ctx, t, 20)
+	bumpRoot(ctx, t, ls, tr, make([]byte, 32), nil)
+	r, err := Check(ctx, ls, tr, Options{})
+	if err != nil {
+		t.Fatalf("Check(): %v", err)
+	}
+	if got := kinds(r); len(got) != 1 || got[0] != RootMismatch {
+		t.Errorf("Check() found %v, want [%v]", r.Problems, RootMismatch)
+	}
+}
+
+func TestCheckEarlierRoots(t *testing.T) {
+	ctx := context.Background()
+	tr, db, ls := setup(ctx, t, 20)
+	// Tree heads between the empty one and the one from the integration.
+	for i, size := range []int64{10, 15, 12, 30} {
+		if _, err := db.ExecContext(ctx, "INSERT INTO TreeHead(TreeId, TreeHeadTimestamp, TreeSize, RootHash, TreeRevision) VALUES(?, ?, ?, ?, ?)",
+			tr.TreeId, fakeTime.UnixNano()+int64(i)+1, size, make([]byte, 32), 100+i); err != nil {
+			t.Fatalf("Failed to insert tree head: %v", err)
+		}
+	}
+
+	r, err := Check(ctx, ls, tr, Options{BatchSize: 8})
+	if err != nil {
+		t.Fatalf("Check(): %v", err)
+	}
+	var sizes []uint64
+	for _, p := range r.Problems {
+		sizes = append(sizes, p.Root.TreeSize)
+	}
+	want := []ProblemKind{RootMismatch, RootMismatch, RootSizeDecrease, RootSizeDecrease}
+	if got := kinds(r); fmt.Sprint(got) != fmt.Sprint(want) || fmt.Sprint(sizes) != "[10 15 12 30]" {
+		t.Errorf("Check() found %v, want %v at sizes 10, 15, 12 and 30", r.Problems, want)
+	}
+	if got, want := r.Heads, int64(6); got != want {
+		t.Errorf("Check() checked %d tree heads, want %d", got, want)
+	}
+	// The latest tree head is consistent.
+	if !r.rootOK {
+		t.Error("Check() found the latest root inconsistent")
+	}
+}
+
+func TestCheckRepair(t *testing.T) {
+	ctx := context.Background()
+	tr, _, ls := setup(ctx, t, 20)
+	bad := []tree.Node{
+		{ID: compact.NewNodeID(1, 3), Hash: make([]byte, 32)},
+		{ID: compact.NewNodeID(3, 1), Hash: make([]byte, 32)},
+	}
+	bumpRoot(ctx, t, ls, tr, nil, bad)
+
+	r, err := Check(ctx, ls, tr, Options{BatchSize: 8, MaxProblems: 1})
+	if err != nil {
+		t.Fatalf("Check(): %v", err)
+	}
+	if got, want := r.ProblemCount, int64(len(bad)); got != want {
+		t.Errorf("Check() found %d problems, want %d", got, want)
+	}
+	if got := kinds(r); len(got) != 1 || got[0] != NodeMismatch {
+		t.Errorf("Check() recorded %v, want one %v", r.Problems, NodeMismatch)
+	}
+	if r.Repaired != 0 {
+		t.Errorf("Check() repaired %d nodes without Repair", r.Repaired)
+	}
+
+	// Repairs store a new tree head, which must be allowed explicitly.
+	if r, err = Check(ctx, ls, tr, Options{BatchSize: 8, Repair: true}); err == nil {
+		t.Error("Check(repair) without RewriteRoot succeeded, want error")
+	}
+	if r.Repaired != 0 {
+		t.Errorf("Check(repair) without RewriteRoot repaired %d nodes", r.Repaired)
+	}
+
+	if r, err = Check(ctx, ls, tr, Options{BatchSize: 8, Repair: true, RewriteRoot: true}); err != nil {
+		t.Fatalf("Check(repair): %v", err)
+	}
+	if got, want := r.Repaired, len(bad); got != want {
+		t.Errorf("Check(repair) repaired %d nodes, want %d", got, want)
+	}
+	if r, err = Check(ctx, ls, tr, Options{BatchSize: 8}); err != nil {
+		t.Fatalf("Check(): %v", err)
+	}
+	if !r.OK() {
+		t.Errorf("Check() after repair found problems: %v", r.Problems)
+	}
+}
+
+func TestCheckNoRepairOfInconsistentRoot(t *testing.T) {
+	ctx := context.Background()
+	tr, _, ls := setup(ctx, t, 20)
+	bumpRoot(ctx, t, ls, tr, make([]byte, 32), []tree.Node{{ID: compact.NewNodeID(1, 3), Hash: make([]byte, 32)}})
+	if _, err := Check(ctx, ls, tr, Options{Repair: true, RewriteRoot: true}); err == nil {
+		t.Error("Check(repair) succeeded, want error")
+	}
+}
+
+func TestCheckRepairInvalidatesTileCache(t *testing.T) {
+	ctx := context.Background()
+	tr, _, ls := setup(ctx, t, 300)
+	tiles := cache.NewTileCache(10, nil)
+	cache.SetDefaultTileCache(tiles)
+	defer cache.SetDefaultTileCache(nil)
+	// The wrong leaf node is in a full tile, which the check caches.
+	bumpRoot(ctx, t, ls, tr, nil, []tree.Node{{ID: compact.NewNodeID(0, 5), Hash: make([]byte, 32)}})
+
+	r, err := Check(ctx, ls, tr, Options{Repair: true, RewriteRoot: true})
+	if err != nil {
+		t.Fatalf("Check(repair): %v", err)
+	}
+	if r.Repaired == 0 {
+		t.Fatalf("Check(repair) repaired no nodes, found %v", r.Problems)
+	}
+	if r, err = Check(ctx, ls, tr, Options{}); err != nil {
+		t.Fatalf("Check(): %v", err)
+	}
+	if !r.OK() {
+		t.Errorf("Check() after repair found problems: %v", r.Problems)
+	}
+}
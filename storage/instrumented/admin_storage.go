@@ -0,0 +1,143 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instrumented
+
+import (
+	"context"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+)
+
+type adminStorage struct {
+	as storage.AdminStorage
+}
+
+func (a *adminStorage) Snapshot(ctx context.Context) (_ storage.ReadOnlyAdminTX, err error) {
+	ctx, done := record(ctx, "AdminStorage.Snapshot", 0)
+	defer func() { done(err) }()
+	tx, err := a.as.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &readOnlyAdminTX{tx: tx}, nil
+}
+
+func (a *adminStorage) ReadWriteTransaction(ctx context.Context, f storage.AdminTXFunc) (err error) {
+	ctx, done := record(ctx, "AdminStorage.ReadWriteTransaction", 0)
+	defer func() { done(err) }()
+	return a.as.ReadWriteTransaction(ctx, func(ctx context.Context, tx storage.AdminTX) error {
+		return f(ctx, &adminTX{readOnlyAdminTX: readOnlyAdminTX{tx: tx}, tx: tx})
+	})
+}
+
+func (a *adminStorage) CheckDatabaseAccessible(ctx context.Context) (err error) {
+	ctx, done := record(ctx, "AdminStorage.CheckDatabaseAccessible", 0)
+	defer func() { done(err) }()
+	return a.as.CheckDatabaseAccessible(ctx)
+}
+
+type readOnlyAdminTX struct {
+	tx storage.ReadOnlyAdminTX
+}
+
+func (t *readOnlyAdminTX) GetTree(ctx context.Context, treeID int64) (_ *trillian.Tree, err error) {
+	ctx, done := record(ctx, "AdminTX.GetTree", treeID)
+	defer func() { done(err) }()
+	return t.tx.GetTree(ctx, treeID)
+}
+
+func (t *readOnlyAdminTX) ListTrees(ctx context.Context, includeDeleted bool) (_ []*trillian.Tree, err error) {
+	ctx, done := record(ctx, "AdminTX.ListTrees", 0)
+	defer func() { done(err) }()
+	return t.tx.ListTrees(ctx, includeDeleted)
+}
+
+// ListTreesFiltered implements storage.TreeLister, using the wrapped
+// transaction's implementation if it has one.
+func (t *readOnlyAdminTX) ListTreesFiltered(ctx context.Context, opts storage.ListTreesOptions) (_ []*trillian.Tree, err error) {
+	ctx, done := record(ctx, "AdminTX.ListTreesFiltered", 0)
+	defer func() { done(err) }()
+	return storage.FilterTrees(ctx, t.tx, opts)
+}
+
+// ListAuditEvents implements storage.AuditLogReader, failing if the wrapped
+// transaction doesn't implement it.
+func (t *readOnlyAdminTX) ListAuditEvents(ctx context.Context, opts storage.ListAuditEventsOptions) (_ []*trillian.AdminAuditEvent, err error) {
+	ctx, done := record(ctx, "AdminTX.ListAuditEvents", opts.TreeID)
+	defer func() { done(err) }()
+	return storage.ReadAuditEvents(ctx, t.tx, opts)
+}
+
+func (t *readOnlyAdminTX) Commit() (err error) {
+	done := measure("AdminTX.Commit", 0)
+	defer func() { done(err) }()
+	return t.tx.Commit()
+}
+
+func (t *readOnlyAdminTX) Close() (err error) {
+	done := measure("AdminTX.Close", 0)
+	defer func() { done(err) }()
+	return t.tx.Close()
+}
+
+type adminTX struct {
+	readOnlyAdminTX
+	tx storage.AdminTX
+}
+
+func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (_ *trillian.Tree, err error) {
+	ctx, done := record(ctx, "AdminTX.CreateTree", 0)
+	defer func() { done(err) }()
+	return t.tx.CreateTree(ctx, tree)
+}
+
+func (t *adminTX) CreateTreeWithID(ctx context.Context, tree *trillian.Tree, treeID int64) (_ *trillian.Tree, err error) {
+	ctx, done := record(ctx, "AdminTX.CreateTreeWithID", treeID)
+	defer func() { done(err) }()
+	return storage.AddTreeWithID(ctx, t.tx, tree, treeID)
+}
+
+func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(*trillian.Tree)) (_ *trillian.Tree, err error) {
+	ctx, done := record(ctx, "AdminTX.UpdateTree", treeID)
+	defer func() { done(err) }()
+	return t.tx.UpdateTree(ctx, treeID, updateFunc)
+}
+
+func (t *adminTX) SoftDeleteTree(ctx context.Context, treeID int64) (_ *trillian.Tree, err error) {
+	ctx, done := record(ctx, "AdminTX.SoftDeleteTree", treeID)
+	defer func() { done(err) }()
+	return t.tx.SoftDeleteTree(ctx, treeID)
+}
+
+func (t *adminTX) HardDeleteTree(ctx context.Context, treeID int64) (err error) {
+	ctx, done := record(ctx, "AdminTX.HardDeleteTree", treeID)
+	defer func() { done(err) }()
+	return t.tx.HardDeleteTree(ctx, treeID)
+}
+
+func (t *adminTX) UndeleteTree(ctx context.Context, treeID int64) (_ *trillian.Tree, err error) {
+	ctx, done := record(ctx, "AdminTX.UndeleteTree", treeID)
+	defer func() { done(err) }()
+	return t.tx.UndeleteTree(ctx, treeID)
+}
+
+// AddAuditEvent implements storage.AuditLogWriter, dropping the event if the
+// wrapped transaction doesn't implement it.
+func (t *adminTX) AddAuditEvent(ctx context.Context, event *trillian.AdminAuditEvent) (err error) {
+	ctx, done := record(ctx, "AdminTX.AddAuditEvent", event.TreeId)
+	defer func() { done(err) }()
+	return storage.AddAuditEvent(ctx, t.tx, event)
+}
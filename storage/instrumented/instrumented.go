@@ -0,0 +1,105 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package instrumented provides a storage.Provider which wraps any other, and
+// records the same latency and error metrics, and tracing spans, for all of
+// them.
+//
+// Every call to a method of LogStorage, LogTreeTX, AdminStorage or AdminTX is
+// recorded in the storage_method_latency histogram, and in the
+// storage_method_errors counter if it fails, labelled by the method name, the
+// tree ID if the method is specific to a tree, and for errors the gRPC code.
+package instrumented
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"google.golang.org/grpc/status"
+)
+
+const traceSpanRoot = "/trillian/storage"
+
+var (
+	once          sync.Once
+	methodLatency monitoring.Histogram
+	methodErrors  monitoring.Counter
+)
+
+func createMetrics(mf monitoring.MetricFactory) {
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+	methodLatency = mf.NewHistogram("storage_method_latency", "Latency of storage methods in seconds", "method", monitoring.TreeIDLabel)
+	methodErrors = mf.NewCounter("storage_method_errors", "Number of storage method calls which failed, by gRPC code", "method", monitoring.TreeIDLabel, "code")
+}
+
+// NewProvider returns a Provider which records metrics and tracing spans for
+// the calls to the storage of p. The metrics are created with the
+// MetricFactory passed to the first call in the process.
+func NewProvider(p storage.Provider, mf monitoring.MetricFactory) storage.Provider {
+	once.Do(func() { createMetrics(mf) })
+	return &provider{Provider: p}
+}
+
+type provider struct {
+	storage.Provider
+}
+
+func (p *provider) LogStorage() storage.LogStorage {
+	return newLogStorage(p.Provider.LogStorage())
+}
+
+func (p *provider) AdminStorage() storage.AdminStorage {
+	return &adminStorage{as: p.Provider.AdminStorage()}
+}
+
+// record starts recording a call to the given method, in a new tracing span.
+// The returned function must be called with the error returned by the method
+// when it completes.
+func record(ctx context.Context, method string, treeID int64) (context.Context, func(error)) {
+	ctx, spanEnd := monitoring.StartSpan(ctx, fmt.Sprintf("%s.%s", traceSpanRoot, method))
+	done := measure(method, treeID)
+	return ctx, func(err error) {
+		spanEnd()
+		done(err)
+	}
+}
+
+// measure is like record, but without a tracing span, for the methods which
+// don't take a context.
+func measure(method string, treeID int64) func(error) {
+	begin := time.Now()
+	return func(err error) {
+		label := treeLabel(treeID)
+		methodLatency.Observe(time.Since(begin).Seconds(), method, label)
+		if err != nil {
+			methodErrors.Inc(method, label, status.Code(err).String())
+		}
+	}
+}
+
+// treeLabel returns the tree ID label, which is empty for methods which aren't
+// specific to a tree.
+func treeLabel(treeID int64) string {
+	if treeID == 0 {
+		return ""
+	}
+	return strconv.FormatInt(treeID, 10)
+}
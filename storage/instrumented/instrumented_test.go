@@ -0,0 +1,172 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instrumented
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/memory"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/types"
+	"google.golang.org/grpc/status"
+)
+
+// spanRecorder records the names of the tracing spans started.
+type spanRecorder struct {
+	mu    sync.Mutex
+	names map[string]int
+}
+
+func (r *spanRecorder) startSpan(ctx context.Context, name string) (context.Context, func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names[name]++
+	return ctx, func() {}
+}
+
+func calls(method string, treeID int64) uint64 {
+	n, _ := methodLatency.Info(method, treeLabel(treeID))
+	return n
+}
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	spans := &spanRecorder{names: make(map[string]int)}
+	monitoring.SetStartSpan(spans.startSpan)
+	defer monitoring.SetStartSpan(func(ctx context.Context, _ string) (context.Context, func()) { return ctx, func() {} })
+
+	ts := memory.NewTreeStorage()
+	sp := NewProvider(&memoryProvider{ts: ts}, nil)
+	as, ls := sp.AdminStorage(), sp.LogStorage()
+
+	tree, err := storage.CreateTree(ctx, as, testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	logRoot, err := (&types.LogRootV1{RootHash: []byte("root")}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary(): %v", err)
+	}
+	if err := ls.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: logRoot})
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(): %v", err)
+	}
+	tx, err := ls.SnapshotForTree(ctx, tree)
+	if err != nil {
+		t.Fatalf("SnapshotForTree(): %v", err)
+	}
+	if _, err := tx.LatestSignedLogRoot(ctx); err != nil {
+		t.Fatalf("LatestSignedLogRoot(): %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit(): %v", err)
+	}
+
+	if _, err := storage.GetTree(ctx, as, tree.TreeId); err != nil {
+		t.Fatalf("GetTree(): %v", err)
+	}
+	missing := tree.TreeId + 1
+	_, err = storage.GetTree(ctx, as, missing)
+	if err == nil {
+		t.Fatal("GetTree(missing): want error")
+	}
+	if got := methodErrors.Value("AdminTX.GetTree", treeLabel(missing), status.Code(err).String()); got != 1 {
+		t.Errorf("GetTree(missing): got %v errors, want 1", got)
+	}
+
+	for _, tc := range []struct {
+		method string
+		treeID int64
+	}{
+		{method: "AdminStorage.ReadWriteTransaction"},
+		{method: "AdminTX.CreateTree"},
+		{method: "AdminStorage.Snapshot"},
+		{method: "AdminTX.GetTree", treeID: tree.TreeId},
+		{method: "AdminTX.GetTree", treeID: missing},
+		{method: "LogStorage.ReadWriteTransaction", treeID: tree.TreeId},
+		{method: "LogTreeTX.StoreSignedLogRoot", treeID: tree.TreeId},
+		{method: "LogStorage.SnapshotForTree", treeID: tree.TreeId},
+		{method: "LogTreeTX.LatestSignedLogRoot", treeID: tree.TreeId},
+		{method: "LogTreeTX.Commit", treeID: tree.TreeId},
+	} {
+		if got := calls(tc.method, tc.treeID); got == 0 {
+			t.Errorf("%s on tree %d: no latency recorded", tc.method, tc.treeID)
+		}
+		if got := spans.names[traceSpanRoot+"."+tc.method]; got == 0 {
+			t.Errorf("%s: no span started", tc.method)
+		}
+	}
+	if got := calls("AdminTX.Commit", 0); got == 0 {
+		t.Error("AdminTX.Commit: no latency recorded")
+	}
+}
+
+func TestKeepsSubtreeRevisionPruner(t *testing.T) {
+	ctx := context.Background()
+	ts := memory.NewTreeStorage()
+	if _, ok := NewProvider(&memoryProvider{ts: ts}, nil).LogStorage().(storage.SubtreeRevisionPruner); ok {
+		t.Error("LogStorage() of memory storage implements SubtreeRevisionPruner")
+	}
+
+	ls := NewProvider(&memoryProvider{ts: ts, prunable: true}, nil).LogStorage()
+	p, ok := ls.(storage.SubtreeRevisionPruner)
+	if !ok {
+		t.Fatal("LogStorage() doesn't implement SubtreeRevisionPruner")
+	}
+	if _, err := p.PruneSubtreeRevisions(ctx, 42, time.Now()); err != nil {
+		t.Fatalf("PruneSubtreeRevisions(): %v", err)
+	}
+	if got := calls("LogStorage.PruneSubtreeRevisions", 42); got != 1 {
+		t.Errorf("PruneSubtreeRevisions(): %d calls recorded, want 1", got)
+	}
+}
+
+// memoryProvider is a storage.Provider of memory storage, whose LogStorage
+// optionally implements SubtreeRevisionPruner.
+type memoryProvider struct {
+	ts       *memory.TreeStorage
+	prunable bool
+}
+
+func (p *memoryProvider) LogStorage() storage.LogStorage {
+	ls := memory.NewLogStorage(p.ts, nil)
+	if p.prunable {
+		return prunerStorage{ls}
+	}
+	return ls
+}
+
+func (p *memoryProvider) AdminStorage() storage.AdminStorage {
+	return memory.NewAdminStorage(p.ts)
+}
+
+func (p *memoryProvider) Close() error {
+	return nil
+}
+
+type prunerStorage struct {
+	storage.LogStorage
+}
+
+func (prunerStorage) PruneSubtreeRevisions(ctx context.Context, treeID int64, keepSince time.Time) (int64, error) {
+	return 0, nil
+}
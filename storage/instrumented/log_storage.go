@@ -0,0 +1,167 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instrumented
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/tree"
+	"github.com/transparency-dev/merkle/compact"
+)
+
+func newLogStorage(ls storage.LogStorage) storage.LogStorage {
+	l := &logStorage{ls: ls}
+	if p, ok := ls.(storage.SubtreeRevisionPruner); ok {
+		return &prunableLogStorage{logStorage: l, pruner: p}
+	}
+	return l
+}
+
+type logStorage struct {
+	ls storage.LogStorage
+}
+
+func (l *logStorage) CheckDatabaseAccessible(ctx context.Context) (err error) {
+	ctx, done := record(ctx, "LogStorage.CheckDatabaseAccessible", 0)
+	defer func() { done(err) }()
+	return l.ls.CheckDatabaseAccessible(ctx)
+}
+
+func (l *logStorage) GetActiveLogIDs(ctx context.Context) (_ []int64, err error) {
+	ctx, done := record(ctx, "LogStorage.GetActiveLogIDs", 0)
+	defer func() { done(err) }()
+	return l.ls.GetActiveLogIDs(ctx)
+}
+
+func (l *logStorage) SnapshotForTree(ctx context.Context, tree *trillian.Tree) (_ storage.ReadOnlyLogTreeTX, err error) {
+	ctx, done := record(ctx, "LogStorage.SnapshotForTree", tree.TreeId)
+	defer func() { done(err) }()
+	tx, err := l.ls.SnapshotForTree(ctx, tree)
+	if tx != nil {
+		tx = &readOnlyLogTX{tx: tx, treeID: tree.TreeId}
+	}
+	return tx, err
+}
+
+func (l *logStorage) ReadWriteTransaction(ctx context.Context, tree *trillian.Tree, f storage.LogTXFunc) (err error) {
+	ctx, done := record(ctx, "LogStorage.ReadWriteTransaction", tree.TreeId)
+	defer func() { done(err) }()
+	return l.ls.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return f(ctx, &logTX{readOnlyLogTX: readOnlyLogTX{tx: tx, treeID: tree.TreeId}, tx: tx})
+	})
+}
+
+func (l *logStorage) QueueLeaves(ctx context.Context, tree *trillian.Tree, leaves []*trillian.LogLeaf, queueTimestamp time.Time) (_ []*trillian.QueuedLogLeaf, err error) {
+	ctx, done := record(ctx, "LogStorage.QueueLeaves", tree.TreeId)
+	defer func() { done(err) }()
+	return l.ls.QueueLeaves(ctx, tree, leaves, queueTimestamp)
+}
+
+func (l *logStorage) AddSequencedLeaves(ctx context.Context, tree *trillian.Tree, leaves []*trillian.LogLeaf, timestamp time.Time) (_ []*trillian.QueuedLogLeaf, err error) {
+	ctx, done := record(ctx, "LogStorage.AddSequencedLeaves", tree.TreeId)
+	defer func() { done(err) }()
+	return l.ls.AddSequencedLeaves(ctx, tree, leaves, timestamp)
+}
+
+// Unwrap implements storage.LogStorageWrapper.
+func (l *logStorage) Unwrap() storage.LogStorage {
+	return l.ls
+}
+
+// prunableLogStorage is a logStorage which also instruments the
+// SubtreeRevisionPruner implementation of the underlying storage.
+type prunableLogStorage struct {
+	*logStorage
+	pruner storage.SubtreeRevisionPruner
+}
+
+func (l *prunableLogStorage) PruneSubtreeRevisions(ctx context.Context, treeID int64, keepSince time.Time) (_ int64, err error) {
+	ctx, done := record(ctx, "LogStorage.PruneSubtreeRevisions", treeID)
+	defer func() { done(err) }()
+	return l.pruner.PruneSubtreeRevisions(ctx, treeID, keepSince)
+}
+
+type readOnlyLogTX struct {
+	tx     storage.ReadOnlyLogTreeTX
+	treeID int64
+}
+
+func (t *readOnlyLogTX) Commit(ctx context.Context) (err error) {
+	ctx, done := record(ctx, "LogTreeTX.Commit", t.treeID)
+	defer func() { done(err) }()
+	return t.tx.Commit(ctx)
+}
+
+func (t *readOnlyLogTX) Close() (err error) {
+	done := measure("LogTreeTX.Close", t.treeID)
+	defer func() { done(err) }()
+	return t.tx.Close()
+}
+
+func (t *readOnlyLogTX) GetMerkleNodes(ctx context.Context, ids []compact.NodeID) (_ []tree.Node, err error) {
+	ctx, done := record(ctx, "LogTreeTX.GetMerkleNodes", t.treeID)
+	defer func() { done(err) }()
+	return t.tx.GetMerkleNodes(ctx, ids)
+}
+
+func (t *readOnlyLogTX) GetLeavesByRange(ctx context.Context, start, count int64) (_ []*trillian.LogLeaf, err error) {
+	ctx, done := record(ctx, "LogTreeTX.GetLeavesByRange", t.treeID)
+	defer func() { done(err) }()
+	return t.tx.GetLeavesByRange(ctx, start, count)
+}
+
+func (t *readOnlyLogTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, orderBySequence bool) (_ []*trillian.LogLeaf, err error) {
+	ctx, done := record(ctx, "LogTreeTX.GetLeavesByHash", t.treeID)
+	defer func() { done(err) }()
+	return t.tx.GetLeavesByHash(ctx, leafHashes, orderBySequence)
+}
+
+func (t *readOnlyLogTX) LatestSignedLogRoot(ctx context.Context) (_ *trillian.SignedLogRoot, err error) {
+	ctx, done := record(ctx, "LogTreeTX.LatestSignedLogRoot", t.treeID)
+	defer func() { done(err) }()
+	return t.tx.LatestSignedLogRoot(ctx)
+}
+
+type logTX struct {
+	readOnlyLogTX
+	tx storage.LogTreeTX
+}
+
+func (t *logTX) SetMerkleNodes(ctx context.Context, nodes []tree.Node) (err error) {
+	ctx, done := record(ctx, "LogTreeTX.SetMerkleNodes", t.treeID)
+	defer func() { done(err) }()
+	return t.tx.SetMerkleNodes(ctx, nodes)
+}
+
+func (t *logTX) StoreSignedLogRoot(ctx context.Context, root *trillian.SignedLogRoot) (err error) {
+	ctx, done := record(ctx, "LogTreeTX.StoreSignedLogRoot", t.treeID)
+	defer func() { done(err) }()
+	return t.tx.StoreSignedLogRoot(ctx, root)
+}
+
+func (t *logTX) DequeueLeaves(ctx context.Context, limit int, cutoff time.Time) (_ []*trillian.LogLeaf, err error) {
+	ctx, done := record(ctx, "LogTreeTX.DequeueLeaves", t.treeID)
+	defer func() { done(err) }()
+	return t.tx.DequeueLeaves(ctx, limit, cutoff)
+}
+
+func (t *logTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillian.LogLeaf) (err error) {
+	ctx, done := record(ctx, "LogTreeTX.UpdateSequencedLeaves", t.treeID)
+	defer func() { done(err) }()
+	return t.tx.UpdateSequencedLeaves(ctx, leaves)
+}
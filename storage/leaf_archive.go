@@ -0,0 +1,52 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage/compression"
+)
+
+// archiveLeafDataSQL replaces the data of the leaves in a range of sequence
+// numbers.
+const archiveLeafDataSQL = `UPDATE LeafData SET LeafValue = %s, ExtraData = NULL
+	WHERE TreeId = %s AND LeafIdentityHash IN (
+		SELECT LeafIdentityHash FROM SequencedLeafData
+		WHERE TreeId = %s AND SequenceNumber >= %s AND SequenceNumber < %s)`
+
+// ArchiveSQLLeafData implements LeafArchiver for SQL log storage with the
+// LeafData and SequencedLeafData tables, in the database dialect of ph. The
+// reference is stored like the leaf values of the tree, i.e. compressed if the
+// tree opted in to it.
+func ArchiveSQLLeafData(ctx context.Context, db *sql.DB, tree *trillian.Tree, start, end int64, ref []byte, ph Placeholder) (int64, error) {
+	a, err := compression.Algorithm(tree)
+	if err != nil {
+		return 0, err
+	}
+	value, err := compression.Encode(tree.TreeId, a, ref)
+	if err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf(archiveLeafDataSQL, ph(1), ph(2), ph(3), ph(4), ph(5))
+	res, err := db.ExecContext(ctx, query, value, tree.TreeId, tree.TreeId, start, end)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
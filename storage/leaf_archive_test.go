@@ -0,0 +1,95 @@
+//go:build cgo
+// +build cgo
+
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/trillian"
+	_ "github.com/mattn/go-sqlite3" // Register the SQLite driver.
+)
+
+func TestArchiveSQLLeafData(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	// Each connection has its own in-memory database.
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range []string{
+		"CREATE TABLE LeafData (TreeId INTEGER, LeafIdentityHash BLOB, LeafValue BLOB, ExtraData BLOB)",
+		"CREATE TABLE SequencedLeafData (TreeId INTEGER, SequenceNumber INTEGER, LeafIdentityHash BLOB)",
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+	}
+	// Trees 1 and 2 have leaves a to e, in that order. Leaf x of tree 1 is
+	// queued, so not sequenced.
+	for _, treeID := range []int64{1, 2} {
+		for i, id := range []string{"a", "b", "c", "d", "e"} {
+			if _, err := db.ExecContext(ctx, "INSERT INTO LeafData VALUES (?, ?, ?, ?)", treeID, id, "value", "extra"); err != nil {
+				t.Fatalf("Failed to insert leaf data: %v", err)
+			}
+			if _, err := db.ExecContext(ctx, "INSERT INTO SequencedLeafData VALUES (?, ?, ?)", treeID, i, id); err != nil {
+				t.Fatalf("Failed to insert sequenced leaf: %v", err)
+			}
+		}
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO LeafData VALUES (1, 'x', 'value', 'extra')"); err != nil {
+		t.Fatalf("Failed to insert leaf data: %v", err)
+	}
+
+	got, err := ArchiveSQLLeafData(ctx, db, &trillian.Tree{TreeId: 1}, 1, 4, []byte("ref"), QuestionMark)
+	if err != nil {
+		t.Fatalf("ArchiveSQLLeafData(): %v", err)
+	}
+	if want := int64(3); got != want {
+		t.Errorf("ArchiveSQLLeafData() = %d, want %d", got, want)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT TreeId, LeafIdentityHash, LeafValue FROM LeafData WHERE ExtraData IS NULL ORDER BY LeafIdentityHash")
+	if err != nil {
+		t.Fatalf("Failed to read leaf data: %v", err)
+	}
+	defer rows.Close()
+	var archived []string
+	for rows.Next() {
+		var treeID int64
+		var id, value string
+		if err := rows.Scan(&treeID, &id, &value); err != nil {
+			t.Fatalf("Failed to read leaf data: %v", err)
+		}
+		if value != "ref" {
+			t.Errorf("Leaf %d/%s has value %q, want %q", treeID, id, value, "ref")
+		}
+		archived = append(archived, id)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Failed to read leaf data: %v", err)
+	}
+	if want := []string{"b", "c", "d"}; !cmp.Equal(archived, want) {
+		t.Errorf("Archived leaves %v of tree 1, want %v", archived, want)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/trillian"
+)
+
+// LeafQueue is an external queue of the leaves waiting to be integrated into
+// log trees, which log storage can use instead of keeping them in its own
+// database until they're sequenced. Leaves are only removed from the queue
+// once the storage has integrated them, so that the sequencer of a tree can
+// peek at the same leaves again if its transaction fails.
+type LeafQueue interface {
+	// Add appends the leaves to the queue of the tree, unless a leaf with the
+	// same LeafIdentityHash is already in it. The leaves must have their
+	// QueueTimestamp set. It returns, for each leaf, nil if the leaf was added,
+	// or the queued leaf with the same LeafIdentityHash.
+	Add(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf) ([]*trillian.LogLeaf, error)
+	// Peek returns up to limit of the first leaves in the queue of the tree
+	// which were queued no later than cutoff, in queue order, without removing
+	// them from the queue.
+	Peek(ctx context.Context, treeID int64, limit int, cutoff time.Time) ([]*trillian.LogLeaf, error)
+	// Remove removes the leaves with the given LeafIdentityHash values from the
+	// queue of the tree. Hashes of leaves not in the queue are ignored.
+	Remove(ctx context.Context, treeID int64, identityHashes [][]byte) error
+	// Len returns the number of leaves in the queue of the tree.
+	Len(ctx context.Context, treeID int64) (int64, error)
+}
+
+// AddLeafQueueStats adds the leaves waiting in the queue of the tree to the
+// statistics of the log storage using it.
+func AddLeafQueueStats(ctx context.Context, q LeafQueue, treeID int64, stats *TreeStats) error {
+	n, err := q.Len(ctx, treeID)
+	if err != nil {
+		return err
+	}
+	stats.QueueDepth += n
+	leaves, err := q.Peek(ctx, treeID, 1, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(leaves) > 0 {
+		if ts := leaves[0].QueueTimestamp.AsTime(); stats.OldestQueued.IsZero() || ts.Before(stats.OldestQueued) {
+			stats.OldestQueued = ts
+		}
+	}
+	return nil
+}
@@ -0,0 +1,360 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leafarchive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/blob"
+	"github.com/google/trillian/types"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"k8s.io/klog/v2"
+)
+
+var (
+	leavesArchived     monitoring.Counter
+	archiveErrors      monitoring.Counter
+	archiveMetricsOnce sync.Once
+
+	timeNow   = time.Now
+	timeAfter = time.After
+)
+
+// ArchiverOptions configures an Archiver.
+type ArchiverOptions struct {
+	// SegmentSize is the number of leaves in each archive segment.
+	SegmentSize int64
+
+	// KeepLeaves is the number of most recent leaves of each log which are
+	// never archived.
+	KeepLeaves int64
+
+	// MinAge is the minimum time since the integration of a leaf before it
+	// can be archived.
+	MinAge time.Duration
+
+	// MinRunInterval defines how frequently sweeps for leaves to archive are
+	// performed. Actual runs happen randomly between [minInterval,2*minInterval).
+	MinRunInterval time.Duration
+}
+
+// Archiver moves the data of old leaves of logs to archive segments.
+//
+// Each sweep archives full segments of leaves, in order of leaf index, for as
+// long as the leaves are older than MinAge and not among the KeepLeaves most
+// recent leaves of the log. Before the leaf data are replaced by references,
+// each segment is read back from the store, and the compact range of its leaf
+// hashes checked against the Merkle tree nodes of the log.
+type Archiver struct {
+	admin storage.AdminStorage
+	ls    storage.LogStorage
+	arch  storage.LeafArchiver
+	store blob.Store
+	cache *segmentCache
+	opts  ArchiverOptions
+}
+
+// NewArchiver returns a new Archiver moving leaves from ls to store, or an error
+// if the log storage doesn't support archiving leaves. The log storage must not
+// be wrapped by NewLogStorage.
+func NewArchiver(admin storage.AdminStorage, ls storage.LogStorage, store blob.Store, opts ArchiverOptions, mf monitoring.MetricFactory) (*Archiver, error) {
+	arch, ok := ls.(storage.LeafArchiver)
+	if !ok {
+		return nil, fmt.Errorf("log storage %T doesn't support archiving leaves", ls)
+	}
+	if opts.SegmentSize <= 0 {
+		return nil, fmt.Errorf("invalid segment size %d", opts.SegmentSize)
+	}
+	archiveMetricsOnce.Do(func() {
+		if mf == nil {
+			mf = monitoring.InertMetricFactory{}
+		}
+		leavesArchived = mf.NewCounter("leaf_archive_leaves_archived", "Number of leaves moved to archive segments", monitoring.TreeIDLabel)
+		archiveErrors = mf.NewCounter("leaf_archive_errors", "Number of failures to archive leaves", monitoring.TreeIDLabel)
+	})
+	return &Archiver{
+		admin: admin,
+		ls:    ls,
+		arch:  arch,
+		store: store,
+		cache: newSegmentCache(store, 1),
+		opts:  opts,
+	}, nil
+}
+
+// Run starts the archival process. It runs until ctx is cancelled.
+func (a *Archiver) Run(ctx context.Context) {
+	for {
+		count, err := a.RunOnce(ctx)
+		if err != nil {
+			klog.Errorf("Archiver.Run: %v", err)
+		}
+		if count > 0 {
+			klog.Infof("Archiver.Run: archived %v leaves", count)
+		}
+
+		d := a.opts.MinRunInterval + time.Duration(rand.Int63n(a.opts.MinRunInterval.Nanoseconds()))
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeAfter(d):
+		}
+	}
+}
+
+// RunOnce performs a single archival sweep over all the logs which aren't
+// deleted. Returns the number of leaves archived.
+//
+// It attempts to sweep all logs, regardless of failures. If it encounters any
+// failures the resulting error is non-nil.
+func (a *Archiver) RunOnce(ctx context.Context) (int64, error) {
+	trees, err := storage.ListTrees(ctx, a.admin, false /* includeDeleted */)
+	if err != nil {
+		return 0, fmt.Errorf("error listing trees: %v", err)
+	}
+
+	var total int64
+	var errs []string
+	for _, tree := range trees {
+		switch tree.TreeType {
+		case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+		default:
+			continue
+		}
+
+		label := fmt.Sprint(tree.TreeId)
+		count, err := a.ArchiveTree(ctx, tree)
+		// Some segments may have been archived even if it failed.
+		total += count
+		leavesArchived.Add(float64(count), label)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("error archiving leaves of tree %v: %v", tree.TreeId, err))
+			archiveErrors.Inc(label)
+			continue
+		}
+		if count > 0 {
+			klog.V(1).Infof("Archiver.RunOnce: archived %v leaves of tree %v", count, tree.TreeId)
+		}
+	}
+
+	if len(errs) == 0 {
+		return total, nil
+	}
+	return total, errors.New("encountered errors archiving leaves:\n\t" + strings.Join(errs, "\n\t"))
+}
+
+// ArchiveTree archives the full segments of leaves of the given log which are
+// due, and returns the number of leaves archived.
+func (a *Archiver) ArchiveTree(ctx context.Context, tree *trillian.Tree) (int64, error) {
+	size, err := a.treeSize(ctx, tree)
+	if err != nil {
+		return 0, err
+	}
+	end := size - a.opts.KeepLeaves
+	start, err := a.firstUnarchived(ctx, tree, end)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for ; start+a.opts.SegmentSize <= end; start += a.opts.SegmentSize {
+		count, err := a.archiveSegment(ctx, tree, start, start+a.opts.SegmentSize)
+		if err != nil {
+			return total, err
+		}
+		if count == 0 {
+			break
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// treeSize returns the size of the latest tree head of the log.
+func (a *Archiver) treeSize(ctx context.Context, tree *trillian.Tree) (int64, error) {
+	tx, err := a.ls.SnapshotForTree(ctx, tree)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Close()
+	slr, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var root types.LogRootV1
+	if err := root.UnmarshalBinary(slr.LogRoot); err != nil {
+		return 0, fmt.Errorf("failed to parse log root: %v", err)
+	}
+	return int64(root.TreeSize), tx.Commit(ctx)
+}
+
+// firstUnarchived returns the index of the first leaf before end which isn't
+// archived, or end if they all are. Leaves are archived in order, so this is a
+// binary search.
+func (a *Archiver) firstUnarchived(ctx context.Context, tree *trillian.Tree, end int64) (int64, error) {
+	if end <= 0 {
+		return 0, nil
+	}
+	tx, err := a.ls.SnapshotForTree(ctx, tree)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Close()
+	var searchErr error
+	i := sort.Search(int(end), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		leaves, err := tx.GetLeavesByRange(ctx, int64(i), 1)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		if len(leaves) != 1 {
+			searchErr = fmt.Errorf("failed to read leaf %d", i)
+			return true
+		}
+		return !isRef(leaves[0].LeafValue)
+	})
+	if searchErr != nil {
+		return 0, searchErr
+	}
+	return int64(i), tx.Commit(ctx)
+}
+
+// archiveSegment archives the leaves in [start, end), if they are all older
+// than MinAge, and returns the number of leaves archived.
+func (a *Archiver) archiveSegment(ctx context.Context, tree *trillian.Tree, start, end int64) (int64, error) {
+	leaves, hashes, err := a.readSegment(ctx, tree, start, end)
+	if err != nil || leaves == nil {
+		return 0, err
+	}
+	data, err := marshalSegment(leaves)
+	if err != nil {
+		return 0, err
+	}
+	key := blob.Key(data)
+	if err := a.store.Put(ctx, key, data); err != nil {
+		return 0, fmt.Errorf("failed to write segment %s: %v", key, err)
+	}
+	if err := a.verifySegment(ctx, key, start, hashes); err != nil {
+		return 0, fmt.Errorf("failed to verify segment %s: %v", key, err)
+	}
+	if _, err := a.arch.ArchiveLeafData(ctx, tree, start, end, makeRef(data)); err != nil {
+		return 0, fmt.Errorf("failed to archive leaves [%d, %d): %v", start, end, err)
+	}
+	return end - start, nil
+}
+
+// readSegment returns the leaves in [start, end), and the hashes of the Merkle
+// tree nodes covering them, or nil if any leaf is more recent than MinAge.
+func (a *Archiver) readSegment(ctx context.Context, tree *trillian.Tree, start, end int64) ([]*trillian.LogLeaf, [][]byte, error) {
+	tx, err := a.ls.SnapshotForTree(ctx, tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Close()
+
+	cutoff := timeNow().Add(-a.opts.MinAge)
+	leaves := make([]*trillian.LogLeaf, 0, end-start)
+	for next := start; next < end; {
+		batch, err := tx.GetLeavesByRange(ctx, next, end-next)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(batch) == 0 {
+			return nil, nil, fmt.Errorf("failed to read leaves from %d", next)
+		}
+		for _, leaf := range batch {
+			if leaf.IntegrateTimestamp.AsTime().After(cutoff) {
+				return nil, nil, nil
+			}
+			// Leaves sharing their data with an archived duplicate get it
+			// back from its segment.
+			r, err := resolveLeaf(ctx, a.cache, leaf)
+			if err != nil {
+				return nil, nil, err
+			}
+			leaves = append(leaves, r)
+		}
+		next += int64(len(batch))
+	}
+
+	ids := compact.RangeNodes(uint64(start), uint64(end), nil)
+	nodes, err := tx.GetMerkleNodes(ctx, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read tree nodes: %v", err)
+	}
+	if got, want := len(nodes), len(ids); got != want {
+		return nil, nil, fmt.Errorf("failed to get %d nodes, got %d", want, got)
+	}
+	hashes := make([][]byte, len(nodes))
+	for i, node := range nodes {
+		hashes[i] = node.Hash
+	}
+	return leaves, hashes, tx.Commit(ctx)
+}
+
+// verifySegment reads back the segment with the given key, and checks that its
+// leaves hash to the given Merkle tree nodes of the range starting at start.
+func (a *Archiver) verifySegment(ctx context.Context, key string, start int64, hashes [][]byte) error {
+	data, err := blob.Fetch(ctx, a.store, key)
+	if err != nil {
+		return err
+	}
+	seg, err := unmarshalSegment(data)
+	if err != nil {
+		return err
+	}
+	if seg.start != start {
+		return fmt.Errorf("segment starts at %d, want %d", seg.start, start)
+	}
+
+	fact := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	cr := fact.NewEmptyRange(uint64(start))
+	for _, leaf := range seg.leaves {
+		// Values offloaded to a blob store are checked when resolved.
+		if !blob.IsRef(leaf.LeafValue) {
+			if got := rfc6962.DefaultHasher.HashLeaf(leaf.LeafValue); !bytes.Equal(got, leaf.MerkleLeafHash) {
+				return fmt.Errorf("leaf %d hashes to %x, want %x", leaf.LeafIndex, got, leaf.MerkleLeafHash)
+			}
+		}
+		if err := cr.Append(leaf.MerkleLeafHash, nil); err != nil {
+			return err
+		}
+	}
+	got := cr.Hashes()
+	if len(got) != len(hashes) {
+		return fmt.Errorf("compact range has %d nodes, want %d", len(got), len(hashes))
+	}
+	for i := range got {
+		if !bytes.Equal(got[i], hashes[i]) {
+			return fmt.Errorf("compact range node %d is %x, want %x", i, got[i], hashes[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+//go:build cgo
+// +build cgo
+
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leafarchive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/log"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/blob"
+	"github.com/google/trillian/storage/sqlite"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/types"
+	"github.com/google/trillian/util/clock"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var fakeTime = time.Date(2016, 11, 10, 15, 16, 27, 0, time.UTC)
+
+func init() {
+	log.InitMetrics(nil)
+}
+
+func testLeaf(i int) *trillian.LogLeaf {
+	data := []byte(fmt.Sprintf("leaf %d", i))
+	id := sha256.Sum256(data)
+	return &trillian.LogLeaf{
+		LeafValue:        data,
+		ExtraData:        []byte(fmt.Sprintf("extra %d", i)),
+		LeafIdentityHash: id[:],
+		MerkleLeafHash:   rfc6962.DefaultHasher.HashLeaf(data),
+	}
+}
+
+// setup returns a log tree with numLeaves integrated leaves in SQLite storage,
+// the database, the admin and log storage, and a blob store.
+func setup(ctx context.Context, t *testing.T, numLeaves int) (*trillian.Tree, *sql.DB, storage.AdminStorage, storage.LogStorage, blob.Store) {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := sqlite.OpenDB(filepath.Join(dir, "trillian.db"))
+	if err != nil {
+		t.Fatalf("OpenDB(): %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	as := sqlite.NewAdminStorage(db)
+	ls := sqlite.NewLogStorage(db, nil)
+	tree, err := storage.CreateTree(ctx, as, testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	logRoot, err := (&types.LogRootV1{RootHash: rfc6962.DefaultHasher.EmptyRoot(), TimestampNanos: uint64(fakeTime.UnixNano())}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary(): %v", err)
+	}
+	if err := ls.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: logRoot})
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(init): %v", err)
+	}
+
+	leaves := make([]*trillian.LogLeaf, 0, numLeaves)
+	for i := 0; i < numLeaves; i++ {
+		leaves = append(leaves, testLeaf(i))
+	}
+	if _, err := ls.QueueLeaves(ctx, tree, leaves, fakeTime); err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+	if _, err := log.IntegrateBatch(ctx, tree, numLeaves, 0, 0, clock.NewFake(fakeTime.Add(time.Minute)), ls, quota.Noop()); err != nil {
+		t.Fatalf("IntegrateBatch(): %v", err)
+	}
+
+	store, err := blob.NewFileStore(filepath.Join(dir, "archive"))
+	if err != nil {
+		t.Fatalf("NewFileStore(): %v", err)
+	}
+	return tree, db, as, ls, store
+}
+
+func getLeaves(ctx context.Context, t *testing.T, ls storage.LogStorage, tree *trillian.Tree, start, count int64) []*trillian.LogLeaf {
+	t.Helper()
+	tx, err := ls.SnapshotForTree(ctx, tree)
+	if err != nil {
+		t.Fatalf("SnapshotForTree(): %v", err)
+	}
+	defer tx.Close()
+	leaves, err := tx.GetLeavesByRange(ctx, start, count)
+	if err != nil {
+		t.Fatalf("GetLeavesByRange(): %v", err)
+	}
+	return leaves
+}
+
+func getLeavesByHash(ctx context.Context, t *testing.T, ls storage.LogStorage, tree *trillian.Tree, hash []byte) []*trillian.LogLeaf {
+	t.Helper()
+	tx, err := ls.SnapshotForTree(ctx, tree)
+	if err != nil {
+		t.Fatalf("SnapshotForTree(): %v", err)
+	}
+	defer tx.Close()
+	leaves, err := tx.GetLeavesByHash(ctx, [][]byte{hash}, false)
+	if err != nil {
+		t.Fatalf("GetLeavesByHash(): %v", err)
+	}
+	return leaves
+}
+
+func TestArchiver(t *testing.T) {
+	ctx := context.Background()
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	timeNow = func() time.Time { return fakeTime.Add(time.Hour) }
+
+	for _, tc := range []struct {
+		desc string
+		opts ArchiverOptions
+		want int64
+	}{
+		{desc: "all-segments", opts: ArchiverOptions{SegmentSize: 10}, want: 20},
+		{desc: "keep-leaves", opts: ArchiverOptions{SegmentSize: 10, KeepLeaves: 10}, want: 10},
+		{desc: "too-recent", opts: ArchiverOptions{SegmentSize: 10, MinAge: 2 * time.Hour}, want: 0},
+		{desc: "big-segment", opts: ArchiverOptions{SegmentSize: 100}, want: 0},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			tree, _, as, ls, store := setup(ctx, t, 25)
+			a, err := NewArchiver(as, ls, store, tc.opts, nil)
+			if err != nil {
+				t.Fatalf("NewArchiver(): %v", err)
+			}
+			got, err := a.RunOnce(ctx)
+			if err != nil {
+				t.Fatalf("RunOnce(): %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("RunOnce() = %d, want %d", got, tc.want)
+			}
+			if got, err := a.RunOnce(ctx); err != nil || got != 0 {
+				t.Errorf("RunOnce() again = %d, %v, want 0, nil", got, err)
+			}
+
+			for i, leaf := range getLeaves(ctx, t, ls, tree, 0, 25) {
+				if got, want := isRef(leaf.LeafValue), int64(i) < tc.want; got != want {
+					t.Errorf("Leaf %d archived: %v, want %v", i, got, want)
+				}
+			}
+
+			// Reads through the wrapper are unchanged.
+			als := NewLogStorage(ls, store, 1)
+			leaves := getLeaves(ctx, t, als, tree, 0, 25)
+			if len(leaves) != 25 {
+				t.Fatalf("GetLeavesByRange() returned %d leaves, want 25", len(leaves))
+			}
+			// Leaves are sequenced in hash order, rather than queue order.
+			wantLeaves := make(map[string]*trillian.LogLeaf)
+			for i := 0; i < 25; i++ {
+				leaf := testLeaf(i)
+				wantLeaves[string(leaf.LeafIdentityHash)] = leaf
+			}
+			for i, leaf := range leaves {
+				want := wantLeaves[string(leaf.LeafIdentityHash)]
+				if want == nil || leaf.LeafIndex != int64(i) || !bytes.Equal(leaf.LeafValue, want.LeafValue) || !bytes.Equal(leaf.ExtraData, want.ExtraData) {
+					t.Errorf("Leaf %d = {%d, %q, %q}, want {%d, %v}", i, leaf.LeafIndex, leaf.LeafValue, leaf.ExtraData, i, want)
+				}
+			}
+
+			want := testLeaf(3)
+			if byHash := getLeavesByHash(ctx, t, als, tree, want.MerkleLeafHash); len(byHash) != 1 || !bytes.Equal(byHash[0].LeafValue, want.LeafValue) || !bytes.Equal(byHash[0].ExtraData, want.ExtraData) {
+				t.Errorf("GetLeavesByHash() = %v, want leaf 3", byHash)
+			}
+
+			// Queuing an archived leaf again returns the resolved leaf.
+			queued, err := als.QueueLeaves(ctx, tree, []*trillian.LogLeaf{testLeaf(3)}, fakeTime)
+			if err != nil {
+				t.Fatalf("QueueLeaves(): %v", err)
+			}
+			if got := queued[0].Leaf; got == nil || !bytes.Equal(got.LeafValue, want.LeafValue) {
+				t.Errorf("QueueLeaves() returned %v, want leaf 3", got)
+			}
+		})
+	}
+}
+
+func TestArchiverVerifies(t *testing.T) {
+	ctx := context.Background()
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	timeNow = func() time.Time { return fakeTime.Add(time.Hour) }
+
+	tree, db, as, ls, store := setup(ctx, t, 20)
+	// Corrupt a leaf of the second segment, which must not be archived.
+	corrupt := getLeaves(ctx, t, ls, tree, 15, 1)[0]
+	if _, err := db.ExecContext(ctx, "UPDATE LeafData SET LeafValue = ? WHERE TreeId = ? AND LeafIdentityHash = ?", []byte("corrupt"), tree.TreeId, corrupt.LeafIdentityHash); err != nil {
+		t.Fatalf("Failed to corrupt leaf: %v", err)
+	}
+	a, err := NewArchiver(as, ls, store, ArchiverOptions{SegmentSize: 10}, nil)
+	if err != nil {
+		t.Fatalf("NewArchiver(): %v", err)
+	}
+	got, err := a.RunOnce(ctx)
+	if err == nil {
+		t.Error("RunOnce() succeeded, want error")
+	}
+	if got != 10 {
+		t.Errorf("RunOnce() = %d, want 10", got)
+	}
+	for i, leaf := range getLeaves(ctx, t, ls, tree, 10, 10) {
+		if isRef(leaf.LeafValue) {
+			t.Errorf("Leaf %d archived, want not", 10+i)
+		}
+	}
+}
+
+func TestNewArchiverUnsupported(t *testing.T) {
+	_, _, as, ls, store := setup(context.Background(), t, 0)
+	if _, err := NewArchiver(as, NewLogStorage(ls, store, 1), store, ArchiverOptions{SegmentSize: 10}, nil); err == nil {
+		t.Error("NewArchiver() of wrapped storage succeeded, want error")
+	}
+}
+
+func TestRejectsRefs(t *testing.T) {
+	ctx := context.Background()
+	tree, _, _, ls, store := setup(ctx, t, 0)
+	leaf := testLeaf(0)
+	leaf.LeafValue = makeRef([]byte("segment"))
+	_, err := NewLogStorage(ls, store, 1).QueueLeaves(ctx, tree, []*trillian.LogLeaf{leaf}, fakeTime)
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Errorf("QueueLeaves() = %v, want code %v", err, want)
+	}
+}
+
+func TestSegmentRoundTrip(t *testing.T) {
+	leaves := make([]*trillian.LogLeaf, 0, 5)
+	for i := 0; i < 5; i++ {
+		leaf := testLeaf(i)
+		leaf.LeafIndex = int64(100 + i)
+		leaves = append(leaves, leaf)
+	}
+	data, err := marshalSegment(leaves)
+	if err != nil {
+		t.Fatalf("marshalSegment(): %v", err)
+	}
+	seg, err := unmarshalSegment(data)
+	if err != nil {
+		t.Fatalf("unmarshalSegment(): %v", err)
+	}
+	if seg.start != 100 || len(seg.leaves) != 5 {
+		t.Fatalf("unmarshalSegment() = {%d, %d leaves}, want {100, 5 leaves}", seg.start, len(seg.leaves))
+	}
+	// Leaves read by identity hash have no index.
+	byID := testLeaf(2)
+	byID.LeafIndex = -1
+	if got := seg.find(byID); got == nil || !bytes.Equal(got.LeafValue, byID.LeafValue) {
+		t.Errorf("find() = %v, want leaf 2", got)
+	}
+	if got := seg.find(testLeaf(7)); got != nil {
+		t.Errorf("find() = %v, want nil", got)
+	}
+
+	leaves[3].LeafIndex = 200
+	if _, err := marshalSegment(leaves); err == nil {
+		t.Error("marshalSegment() of non-consecutive leaves succeeded, want error")
+	}
+	if _, err := unmarshalSegment(data[:len(data)/2]); err == nil {
+		t.Error("unmarshalSegment() of truncated segment succeeded, want error")
+	}
+}
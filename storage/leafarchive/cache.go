@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leafarchive
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/trillian/storage/blob"
+)
+
+// segmentCache is a bounded LRU cache of decoded segments, read from a
+// blob.Store. It is safe for concurrent use.
+type segmentCache struct {
+	store blob.Store
+	size  int
+
+	mu      sync.Mutex
+	lru     *list.List // Of *segmentEntry, most recently used first.
+	entries map[string]*list.Element
+}
+
+type segmentEntry struct {
+	key string
+	seg *segment
+}
+
+func newSegmentCache(store blob.Store, size int) *segmentCache {
+	return &segmentCache{
+		store:   store,
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the segment with the given key, reading it from the store if it
+// isn't cached. The returned segment must not be modified.
+func (c *segmentCache) get(ctx context.Context, key string) (*segment, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*segmentEntry).seg, nil
+	}
+	c.mu.Unlock()
+
+	// Concurrent misses may read the same segment twice, which is harmless.
+	data, err := blob.Fetch(ctx, c.store, key)
+	if err != nil {
+		return nil, err
+	}
+	seg, err := unmarshalSegment(data)
+	if err != nil {
+		return nil, fmt.Errorf("segment %s: %v", key, err)
+	}
+	c.put(key, seg)
+	return seg, nil
+}
+
+func (c *segmentCache) put(key string, seg *segment) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&segmentEntry{key: key, seg: seg})
+	for c.lru.Len() > c.size {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*segmentEntry).key)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leafarchive
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/blob"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// NewProvider returns a Provider whose LogStorage resolves the leaves of p
+// archived to s. Up to cacheSize decoded segments are cached in memory. Its
+// AdminStorage is that of p.
+func NewProvider(p storage.Provider, s blob.Store, cacheSize int) storage.Provider {
+	return &provider{Provider: p, cache: newSegmentCache(s, cacheSize)}
+}
+
+type provider struct {
+	storage.Provider
+	cache *segmentCache
+}
+
+func (p *provider) LogStorage() storage.LogStorage {
+	return newLogStorage(p.Provider.LogStorage(), p.cache)
+}
+
+// NewLogStorage returns a LogStorage which resolves the leaves of ls archived
+// to s, caching up to cacheSize decoded segments in memory.
+//
+// Leaves returned by DequeueLeaves are not resolved, as they are never
+// archived.
+func NewLogStorage(ls storage.LogStorage, s blob.Store, cacheSize int) storage.LogStorage {
+	return newLogStorage(ls, newSegmentCache(s, cacheSize))
+}
+
+func newLogStorage(ls storage.LogStorage, c *segmentCache) storage.LogStorage {
+	l := &logStorage{LogStorage: ls, cache: c}
+	if p, ok := ls.(storage.SubtreeRevisionPruner); ok {
+		return &prunableLogStorage{logStorage: l, SubtreeRevisionPruner: p}
+	}
+	return l
+}
+
+// prunableLogStorage is a logStorage which keeps the SubtreeRevisionPruner
+// implementation of the underlying storage, as pruning doesn't touch leaves.
+type prunableLogStorage struct {
+	*logStorage
+	storage.SubtreeRevisionPruner
+}
+
+type logStorage struct {
+	storage.LogStorage
+	cache *segmentCache
+}
+
+// checkLeaves rejects leaves whose values would be mistaken for references to
+// archive segments.
+func checkLeaves(leaves []*trillian.LogLeaf) error {
+	for _, leaf := range leaves {
+		if isRef(leaf.LeafValue) {
+			return status.Errorf(codes.InvalidArgument, "leaf value must not start with %q", refMarker)
+		}
+	}
+	return nil
+}
+
+// resolveQueued resolves the existing leaves returned by QueueLeaves or
+// AddSequencedLeaves.
+func (l *logStorage) resolveQueued(ctx context.Context, ret []*trillian.QueuedLogLeaf) error {
+	for _, r := range ret {
+		if r == nil || r.Leaf == nil {
+			continue
+		}
+		leaf, err := resolveLeaf(ctx, l.cache, r.Leaf)
+		if err != nil {
+			return err
+		}
+		r.Leaf = leaf
+	}
+	return nil
+}
+
+func (l *logStorage) QueueLeaves(ctx context.Context, tree *trillian.Tree, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
+	if err := checkLeaves(leaves); err != nil {
+		return nil, err
+	}
+	ret, err := l.LogStorage.QueueLeaves(ctx, tree, leaves, queueTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	return ret, l.resolveQueued(ctx, ret)
+}
+
+func (l *logStorage) AddSequencedLeaves(ctx context.Context, tree *trillian.Tree, leaves []*trillian.LogLeaf, timestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
+	if err := checkLeaves(leaves); err != nil {
+		return nil, err
+	}
+	ret, err := l.LogStorage.AddSequencedLeaves(ctx, tree, leaves, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	return ret, l.resolveQueued(ctx, ret)
+}
+
+func (l *logStorage) ReadWriteTransaction(ctx context.Context, tree *trillian.Tree, f storage.LogTXFunc) error {
+	return l.LogStorage.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return f(ctx, &logTX{LogTreeTX: tx, l: l})
+	})
+}
+
+// Unwrap implements storage.LogStorageWrapper. The tree statistics of the
+// underlying storage don't include the sizes of the archive segments.
+func (l *logStorage) Unwrap() storage.LogStorage {
+	return l.LogStorage
+}
+
+func (l *logStorage) SnapshotForTree(ctx context.Context, tree *trillian.Tree) (storage.ReadOnlyLogTreeTX, error) {
+	tx, err := l.LogStorage.SnapshotForTree(ctx, tree)
+	if tx != nil {
+		tx = &readOnlyLogTX{ReadOnlyLogTreeTX: tx, l: l}
+	}
+	return tx, err
+}
+
+type readOnlyLogTX struct {
+	storage.ReadOnlyLogTreeTX
+	l *logStorage
+}
+
+func (t *readOnlyLogTX) GetLeavesByRange(ctx context.Context, start, count int64) ([]*trillian.LogLeaf, error) {
+	leaves, err := t.ReadOnlyLogTreeTX.GetLeavesByRange(ctx, start, count)
+	if err != nil {
+		return nil, err
+	}
+	return t.l.resolveLeaves(ctx, leaves)
+}
+
+func (t *readOnlyLogTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, orderBySequence bool) ([]*trillian.LogLeaf, error) {
+	leaves, err := t.ReadOnlyLogTreeTX.GetLeavesByHash(ctx, leafHashes, orderBySequence)
+	if err != nil {
+		return nil, err
+	}
+	return t.l.resolveLeaves(ctx, leaves)
+}
+
+type logTX struct {
+	storage.LogTreeTX
+	l *logStorage
+}
+
+func (t *logTX) GetLeavesByRange(ctx context.Context, start, count int64) ([]*trillian.LogLeaf, error) {
+	leaves, err := t.LogTreeTX.GetLeavesByRange(ctx, start, count)
+	if err != nil {
+		return nil, err
+	}
+	return t.l.resolveLeaves(ctx, leaves)
+}
+
+func (t *logTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, orderBySequence bool) ([]*trillian.LogLeaf, error) {
+	leaves, err := t.LogTreeTX.GetLeavesByHash(ctx, leafHashes, orderBySequence)
+	if err != nil {
+		return nil, err
+	}
+	return t.l.resolveLeaves(ctx, leaves)
+}
+
+// resolveLeaves resolves the archived leaves in place.
+func (l *logStorage) resolveLeaves(ctx context.Context, leaves []*trillian.LogLeaf) ([]*trillian.LogLeaf, error) {
+	for i, leaf := range leaves {
+		r, err := resolveLeaf(ctx, l.cache, leaf)
+		if err != nil {
+			return nil, err
+		}
+		leaves[i] = r
+	}
+	return leaves, nil
+}
+
+// resolveLeaf returns the leaf with its value and extra data read from its
+// archive segment, if it is archived. The leaf itself is not modified, as it
+// may be shared with the underlying storage.
+func resolveLeaf(ctx context.Context, c *segmentCache, leaf *trillian.LogLeaf) (*trillian.LogLeaf, error) {
+	key, ok, err := parseRef(leaf.LeafValue)
+	if err != nil || !ok {
+		return leaf, err
+	}
+	seg, err := c.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	a := seg.find(leaf)
+	if a == nil {
+		return nil, status.Errorf(codes.Internal, "archive segment %s does not hold leaf %x", key, leaf.LeafIdentityHash)
+	}
+	r := proto.Clone(leaf).(*trillian.LogLeaf)
+	r.LeafValue = a.LeafValue
+	r.ExtraData = a.ExtraData
+	return r, nil
+}
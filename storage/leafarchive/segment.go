@@ -0,0 +1,201 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package leafarchive moves the data of old sequenced leaves out of log
+// storage, into compressed archive segments kept in a blob.Store such as a
+// filesystem or an S3 bucket, so that the databases of very large logs only
+// hold their recent leaves in full.
+//
+// A segment holds the leaves of a contiguous range of leaf indices. It is the
+// gzip compression of the magic string "TRLSEG\x00", a byte holding the format
+// version, which is currently 1, the uvarint-encoded index of the first leaf
+// and number of leaves, and then each leaf as the uvarint-encoded length of its
+// trillian.LogLeaf proto followed by the proto. Segments are stored under the
+// hex-encoded SHA-256 hash of their contents, like other blobs.
+//
+// Archived leaves keep their rows in the database, which index them by hash
+// and detect duplicates, but their LeafValue is replaced by a reference to the
+// segment, and their ExtraData deleted. A reference is the marker
+// "\x00TRILLIAN-ARCHIVE-SHA256\x00" followed by the 32-byte hash of the
+// segment. Reads through the storage returned by NewLogStorage resolve the
+// references transparently.
+package leafarchive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/google/trillian"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// version is the version of the segment format.
+	version = 1
+
+	// maxLeafSize is the largest leaf that a segment is read with, which
+	// protects readers from allocating huge buffers for corrupt segments.
+	maxLeafSize = 64 << 20
+)
+
+var (
+	magic     = []byte("TRLSEG\x00")
+	refMarker = []byte("\x00TRILLIAN-ARCHIVE-SHA256\x00")
+)
+
+// segment is the decoded contents of an archive segment.
+type segment struct {
+	start  int64
+	leaves []*trillian.LogLeaf
+	// byIdentity holds the leaves keyed by LeafIdentityHash.
+	byIdentity map[string]*trillian.LogLeaf
+}
+
+// marshalSegment encodes the leaves, which must have consecutive LeafIndex
+// values, as a segment.
+func marshalSegment(leaves []*trillian.LogLeaf) ([]byte, error) {
+	if len(leaves) == 0 {
+		return nil, errors.New("empty segment")
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	var hdr [2 * binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(hdr[:], uint64(leaves[0].LeafIndex))
+	n += binary.PutUvarint(hdr[n:], uint64(len(leaves)))
+	if _, err := zw.Write(append(append(append([]byte{}, magic...), version), hdr[:n]...)); err != nil {
+		return nil, err
+	}
+	for i, leaf := range leaves {
+		if want := leaves[0].LeafIndex + int64(i); leaf.LeafIndex != want {
+			return nil, fmt.Errorf("leaf %d has index %d, want %d", i, leaf.LeafIndex, want)
+		}
+		data, err := proto.Marshal(leaf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal leaf %d: %v", leaf.LeafIndex, err)
+		}
+		n := binary.PutUvarint(hdr[:], uint64(len(data)))
+		if _, err := zw.Write(hdr[:n]); err != nil {
+			return nil, err
+		}
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalSegment decodes a segment encoded by marshalSegment.
+func unmarshalSegment(data []byte) (*segment, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress segment: %v", err)
+	}
+	r := bufio.NewReader(zr)
+	hdr := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, fmt.Errorf("failed to read segment header: %v", err)
+	}
+	if !bytes.Equal(hdr[:len(magic)], magic) {
+		return nil, errors.New("not an archive segment")
+	}
+	if v := hdr[len(magic)]; v != version {
+		return nil, fmt.Errorf("unsupported segment version %d, want %d", v, version)
+	}
+	start, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read segment header: %v", err)
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read segment header: %v", err)
+	}
+
+	s := &segment{start: int64(start), byIdentity: make(map[string]*trillian.LogLeaf)}
+	for i := uint64(0); i < count; i++ {
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read leaf %d: %v", i, err)
+		}
+		if size > maxLeafSize {
+			return nil, fmt.Errorf("leaf %d is %d bytes, more than the maximum %d", i, size, maxLeafSize)
+		}
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("failed to read leaf %d: %v", i, err)
+		}
+		leaf := &trillian.LogLeaf{}
+		if err := proto.Unmarshal(buf, leaf); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal leaf %d: %v", i, err)
+		}
+		if want := s.start + int64(i); leaf.LeafIndex != want {
+			return nil, fmt.Errorf("leaf %d has index %d, want %d", i, leaf.LeafIndex, want)
+		}
+		s.leaves = append(s.leaves, leaf)
+		s.byIdentity[string(leaf.LeafIdentityHash)] = leaf
+	}
+	if rest, err := ioutil.ReadAll(r); err != nil {
+		return nil, fmt.Errorf("failed to decompress segment: %v", err)
+	} else if len(rest) > 0 {
+		return nil, fmt.Errorf("segment has %d trailing bytes", len(rest))
+	}
+	return s, nil
+}
+
+// find returns the archived version of the given leaf, read from the database,
+// or nil if the segment doesn't hold it. The leaf is looked up by LeafIndex,
+// or else by LeafIdentityHash, as leaves read by identity hash don't have an
+// index, and duplicate leaves share the data of the first one.
+func (s *segment) find(leaf *trillian.LogLeaf) *trillian.LogLeaf {
+	if i := leaf.LeafIndex - s.start; i >= 0 && i < int64(len(s.leaves)) {
+		if l := s.leaves[i]; bytes.Equal(l.LeafIdentityHash, leaf.LeafIdentityHash) {
+			return l
+		}
+	}
+	return s.byIdentity[string(leaf.LeafIdentityHash)]
+}
+
+// makeRef returns the reference to the segment encoded as data.
+func makeRef(data []byte) []byte {
+	h := sha256.Sum256(data)
+	return append(append(make([]byte, 0, len(refMarker)+len(h)), refMarker...), h[:]...)
+}
+
+// isRef returns whether the leaf value is a reference to a segment.
+func isRef(value []byte) bool {
+	return bytes.HasPrefix(value, refMarker)
+}
+
+// parseRef returns the key of the segment referenced by value, and whether
+// value is a reference at all.
+func parseRef(value []byte) (string, bool, error) {
+	if !isRef(value) {
+		return "", false, nil
+	}
+	h := value[len(refMarker):]
+	if len(h) != sha256.Size {
+		return "", true, fmt.Errorf("malformed archive reference of %d bytes", len(value))
+	}
+	return hex.EncodeToString(h), true, nil
+}
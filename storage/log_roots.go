@@ -0,0 +1,62 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/types"
+)
+
+// selectSignedLogRootsSQL selects the tree heads of a tree after a given
+// timestamp, oldest first.
+const selectSignedLogRootsSQL = `SELECT TreeHeadTimestamp, TreeSize, RootHash FROM TreeHead
+	WHERE TreeId = %s AND TreeHeadTimestamp > %s
+	ORDER BY TreeHeadTimestamp LIMIT %s`
+
+// ListSQLSignedLogRoots implements LogRootLister for SQL log storage with the
+// TreeHead table, in the database dialect of ph.
+func ListSQLSignedLogRoots(ctx context.Context, db *sql.DB, treeID int64, after uint64, limit int, ph Placeholder) ([]*trillian.SignedLogRoot, error) {
+	query := fmt.Sprintf(selectSignedLogRootsSQL, ph(1), ph(2), ph(3))
+	rows, err := db.QueryContext(ctx, query, treeID, int64(after), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []*trillian.SignedLogRoot
+	for rows.Next() {
+		var timestamp, treeSize int64
+		var rootHash []byte
+		if err := rows.Scan(&timestamp, &treeSize, &rootHash); err != nil {
+			return nil, err
+		}
+		// As when reading the latest root, the LogRoot is put back together
+		// from its deterministic serialization.
+		logRoot, err := (&types.LogRootV1{
+			RootHash:       rootHash,
+			TimestampNanos: uint64(timestamp),
+			TreeSize:       uint64(treeSize),
+		}).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, &trillian.SignedLogRoot{LogRoot: logRoot})
+	}
+	return ret, rows.Err()
+}
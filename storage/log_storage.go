@@ -170,3 +170,102 @@ type LogStorage interface {
 	// be a good optimization. Could also be optional.
 	AddSequencedLeaves(ctx context.Context, tree *trillian.Tree, leaves []*trillian.LogLeaf, timestamp time.Time) ([]*trillian.QueuedLogLeaf, error)
 }
+
+// SubtreeRevisionPruner is implemented by LogStorage implementations which
+// store a new revision of each subtree changed by a tree head, so that the
+// revisions which are no longer needed can be deleted.
+type SubtreeRevisionPruner interface {
+	// PruneSubtreeRevisions deletes the subtree revisions of the given tree
+	// which aren't needed to read it at its latest tree head, nor at any tree
+	// head with a timestamp at or after keepSince. It returns the number of
+	// revisions deleted.
+	PruneSubtreeRevisions(ctx context.Context, treeID int64, keepSince time.Time) (int64, error)
+}
+
+// LeafArchiver is implemented by LogStorage implementations which can replace
+// the data of sequenced leaves by a reference to where it has been archived,
+// so that it can be moved out of the database while keeping the leaves
+// indexed.
+type LeafArchiver interface {
+	// ArchiveLeafData replaces the LeafValue of the leaves of the given tree
+	// with LeafIndex in [start, end) by ref, and deletes their ExtraData. It
+	// returns the number of leaves whose data was replaced, which can be less
+	// than end-start if the tree has duplicate leaves.
+	ArchiveLeafData(ctx context.Context, tree *trillian.Tree, start, end int64, ref []byte) (int64, error)
+}
+
+// TreeStats holds statistics about the storage used by a log tree.
+type TreeStats struct {
+	// LeafCount is the number of sequenced leaves.
+	LeafCount int64
+	// QueueDepth is the number of leaves waiting to be sequenced.
+	QueueDepth int64
+	// OldestQueued is the queue time of the oldest leaf waiting to be
+	// sequenced, or zero if there are none.
+	OldestQueued time.Time
+	// LatestRoot is the timestamp of the latest log root, or zero if the tree
+	// isn't initialised.
+	LatestRoot time.Time
+	// LeafBytes is the number of bytes of leaf values and extra data, and
+	// TileBytes of Merkle tree tiles, in storage.
+	LeafBytes, TileBytes int64
+}
+
+// TreeStatsReader is implemented by LogStorage implementations which can
+// report statistics about the storage used by each tree.
+type TreeStatsReader interface {
+	// GetTreeStats returns statistics about the storage used by the given
+	// tree. They aren't read atomically, and may scan all the data of the
+	// tree, so they are meant for monitoring rather than frequent use.
+	GetTreeStats(ctx context.Context, tree *trillian.Tree) (*TreeStats, error)
+}
+
+// GetTreeStats returns statistics about the storage used by the given tree, or
+// an Unimplemented error if neither ls nor any LogStorage it wraps implements
+// TreeStatsReader.
+func GetTreeStats(ctx context.Context, ls LogStorage, tree *trillian.Tree) (*TreeStats, error) {
+	for s := ls; s != nil; s = unwrap(s) {
+		if r, ok := s.(TreeStatsReader); ok {
+			return r.GetTreeStats(ctx, tree)
+		}
+	}
+	return nil, status.Errorf(codes.Unimplemented, "log storage %T doesn't support tree statistics", ls)
+}
+
+// LogRootLister is implemented by LogStorage implementations which can list
+// all the log roots stored for a tree, not just the latest one.
+type LogRootLister interface {
+	// ListSignedLogRoots returns up to limit log roots of the given tree with
+	// a timestamp after the given one, in nanoseconds, oldest first. Listing
+	// from a timestamp of zero returns the oldest roots of the tree.
+	ListSignedLogRoots(ctx context.Context, tree *trillian.Tree, after uint64, limit int) ([]*trillian.SignedLogRoot, error)
+}
+
+// ListSignedLogRoots returns up to limit log roots of the given tree with a
+// timestamp after the given one, oldest first, or an Unimplemented error if
+// neither ls nor any LogStorage it wraps implements LogRootLister.
+func ListSignedLogRoots(ctx context.Context, ls LogStorage, tree *trillian.Tree, after uint64, limit int) ([]*trillian.SignedLogRoot, error) {
+	for s := ls; s != nil; s = unwrap(s) {
+		if l, ok := s.(LogRootLister); ok {
+			return l.ListSignedLogRoots(ctx, tree, after, limit)
+		}
+	}
+	return nil, status.Errorf(codes.Unimplemented, "log storage %T doesn't support listing log roots", ls)
+}
+
+// LogStorageWrapper is implemented by LogStorage implementations which wrap
+// another one, such as to add caching or instrumentation, so that the helpers
+// of the optional interfaces, like GetTreeStats, can use the implementation of
+// the wrapped storage without each wrapper having to forward it.
+type LogStorageWrapper interface {
+	// Unwrap returns the wrapped LogStorage.
+	Unwrap() LogStorage
+}
+
+// unwrap returns the LogStorage wrapped by ls, or nil if there isn't one.
+func unwrap(ls LogStorage) LogStorage {
+	if w, ok := ls.(LogStorageWrapper); ok {
+		return w.Unwrap()
+	}
+	return nil
+}
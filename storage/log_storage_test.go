@@ -0,0 +1,67 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/trillian"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type statsLogStorage struct {
+	LogStorage
+	stats *TreeStats
+}
+
+func (s *statsLogStorage) GetTreeStats(context.Context, *trillian.Tree) (*TreeStats, error) {
+	return s.stats, nil
+}
+
+type wrappingLogStorage struct {
+	LogStorage
+}
+
+func (w *wrappingLogStorage) Unwrap() LogStorage {
+	return w.LogStorage
+}
+
+func TestGetTreeStats(t *testing.T) {
+	stats := &TreeStats{LeafCount: 5}
+	reader := &statsLogStorage{stats: stats}
+	for _, tc := range []struct {
+		name     string
+		ls       LogStorage
+		wantCode codes.Code
+	}{
+		{name: "reader", ls: reader},
+		{name: "wrapped", ls: &wrappingLogStorage{reader}},
+		{name: "wrapped twice", ls: &wrappingLogStorage{&wrappingLogStorage{reader}}},
+		{name: "unsupported", ls: &MockLogStorage{}, wantCode: codes.Unimplemented},
+		{name: "wrapped unsupported", ls: &wrappingLogStorage{&MockLogStorage{}}, wantCode: codes.Unimplemented},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := GetTreeStats(context.Background(), tc.ls, &trillian.Tree{TreeId: 1})
+			if code := status.Code(err); code != tc.wantCode {
+				t.Fatalf("GetTreeStats() = %v, want code %v", err, tc.wantCode)
+			}
+			if tc.wantCode == codes.OK && got != stats {
+				t.Errorf("GetTreeStats() = %+v, want %+v", got, stats)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type masterFenceKey struct{}
+
+// MasterFence identifies a mastership term of a tree in the MasterElection
+// table of the SQL storage, as captured by the master election in
+// util/election2/sql.
+type MasterFence struct {
+	// ResourceID is the ID of the elected resource, i.e. the tree ID.
+	ResourceID string
+	// Epoch is the number of the term, or 0 if no term is held.
+	Epoch int64
+}
+
+// WithMasterFence returns a ctx which tells LogStorage.ReadWriteTransaction to
+// commit the writes only while the given mastership term is current, so that a
+// deposed master which hasn't noticed losing mastership yet can't overwrite the
+// work of its successor. The MySQL, CockroachDB and PostgreSQL storage check
+// it against the MasterElection table of their database, so it must be used
+// only when the election keeps its leases in the same database.
+func WithMasterFence(ctx context.Context, f MasterFence) context.Context {
+	return context.WithValue(ctx, masterFenceKey{}, f)
+}
+
+// MasterFenceFromContext returns the fence set in ctx with WithMasterFence.
+func MasterFenceFromContext(ctx context.Context) (MasterFence, bool) {
+	f, ok := ctx.Value(masterFenceKey{}).(MasterFence)
+	return f, ok
+}
+
+// CheckMasterFence returns an error if ctx has a fence set with WithMasterFence
+// whose term is not the current one in the MasterElection table. The row of
+// the term is locked until tx ends, so that no other instance can capture
+// mastership before the writes of tx are committed.
+func CheckMasterFence(ctx context.Context, tx *sql.Tx, ph Placeholder) error {
+	f, ok := MasterFenceFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	var epoch int64
+	err := tx.QueryRowContext(ctx,
+		"SELECT Epoch FROM MasterElection WHERE ResourceId = "+ph(1)+" FOR UPDATE",
+		f.ResourceID).Scan(&epoch)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read mastership epoch: %v", err)
+	}
+	if f.Epoch == 0 || epoch != f.Epoch {
+		return status.Errorf(codes.FailedPrecondition, "mastership of %s at epoch %d has ended", f.ResourceID, f.Epoch)
+	}
+	return nil
+}
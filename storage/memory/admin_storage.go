@@ -17,11 +17,15 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/google/trillian"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/compression"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"k8s.io/klog/v2"
@@ -101,18 +105,67 @@ func (t *adminTX) ListTrees(ctx context.Context, includeDeleted bool) ([]*trilli
 	return ret, nil
 }
 
-func (t *adminTX) CreateTree(ctx context.Context, tr *trillian.Tree) (*trillian.Tree, error) {
-	if err := storage.ValidateTreeForCreation(ctx, tr); err != nil {
-		return nil, err
+// ListTreesFiltered implements storage.TreeLister.
+func (t *adminTX) ListTreesFiltered(ctx context.Context, opts storage.ListTreesOptions) ([]*trillian.Tree, error) {
+	t.ms.mu.RLock()
+	defer t.ms.mu.RUnlock()
+
+	ret := []*trillian.Tree{}
+	for _, v := range t.ms.trees {
+		if opts.Matches(v.meta) {
+			ret = append(ret, v.meta)
+		}
 	}
-	if err := validateStorageSettings(tr); err != nil {
-		return nil, err
+	sort.Slice(ret, func(i, j int) bool { return ret[i].TreeId < ret[j].TreeId })
+	if opts.Limit > 0 && len(ret) > opts.Limit {
+		ret = ret[:opts.Limit]
 	}
+	return ret, nil
+}
+
+// ListAuditEvents implements storage.AuditLogReader.
+func (t *adminTX) ListAuditEvents(ctx context.Context, opts storage.ListAuditEventsOptions) ([]*trillian.AdminAuditEvent, error) {
+	t.ms.auditMu.Lock()
+	defer t.ms.auditMu.Unlock()
+
+	ret := []*trillian.AdminAuditEvent{}
+	for _, e := range t.ms.auditEvents {
+		if opts.Limit > 0 && len(ret) == opts.Limit {
+			break
+		}
+		if e.EventId > opts.AfterEventID && (opts.TreeID == 0 || e.TreeId == opts.TreeID) {
+			ret = append(ret, proto.Clone(e).(*trillian.AdminAuditEvent))
+		}
+	}
+	return ret, nil
+}
+
+// AddAuditEvent implements storage.AuditLogWriter.
+func (t *adminTX) AddAuditEvent(ctx context.Context, event *trillian.AdminAuditEvent) error {
+	t.ms.auditMu.Lock()
+	defer t.ms.auditMu.Unlock()
 
+	event.EventId = int64(len(t.ms.auditEvents) + 1)
+	t.ms.auditEvents = append(t.ms.auditEvents, proto.Clone(event).(*trillian.AdminAuditEvent))
+	return nil
+}
+
+func (t *adminTX) CreateTree(ctx context.Context, tr *trillian.Tree) (*trillian.Tree, error) {
 	id, err := storage.NewTreeID()
 	if err != nil {
 		return nil, err
 	}
+	return t.CreateTreeWithID(ctx, tr, id)
+}
+
+// CreateTreeWithID implements storage.TreeCreatorWithID.
+func (t *adminTX) CreateTreeWithID(ctx context.Context, tr *trillian.Tree, id int64) (*trillian.Tree, error) {
+	if err := storage.ValidateTreeForCreation(ctx, tr); err != nil {
+		return nil, err
+	}
+	if err := compression.ValidateSettings(tr); err != nil {
+		return nil, err
+	}
 
 	now := time.Now()
 
@@ -129,6 +182,9 @@ func (t *adminTX) CreateTree(ctx context.Context, tr *trillian.Tree) (*trillian.
 
 	t.ms.mu.Lock()
 	defer t.ms.mu.Unlock()
+	if _, ok := t.ms.trees[id]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "tree %d already exists", id)
+	}
 	t.ms.trees[id] = newTree(meta)
 
 	klog.V(1).Infof("trees: %v", t.ms.trees)
@@ -147,7 +203,7 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 	if err := storage.ValidateTreeForUpdate(ctx, beforeUpdate, tree); err != nil {
 		return nil, err
 	}
-	if err := validateStorageSettings(tree); err != nil {
+	if err := compression.ValidateSettings(tree); err != nil {
 		return nil, err
 	}
 
@@ -169,10 +225,3 @@ func (t *adminTX) HardDeleteTree(ctx context.Context, treeID int64) error {
 func (t *adminTX) UndeleteTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
 	return nil, fmt.Errorf("method not supported: UndeleteTree")
 }
-
-func validateStorageSettings(tree *trillian.Tree) error {
-	if tree.StorageSettings != nil {
-		return fmt.Errorf("storage_settings not supported, but got %v", tree.StorageSettings)
-	}
-	return nil
-}
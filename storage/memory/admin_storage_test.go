@@ -0,0 +1,97 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestListTreesFiltered(t *testing.T) {
+	ctx := context.Background()
+	as := NewAdminStorage(NewTreeStorage())
+
+	for i, labels := range []map[string]string{{"tenant": "a"}, {"tenant": "b"}, {"tenant": "a"}, nil} {
+		tree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+		tree.Labels = labels
+		if _, err := storage.CreateTreeWithID(ctx, as, tree, int64(10-i)); err != nil {
+			t.Fatalf("CreateTreeWithID(): %v", err)
+		}
+	}
+
+	for _, test := range []struct {
+		desc    string
+		opts    storage.ListTreesOptions
+		wantIDs []int64
+	}{
+		{desc: "all", wantIDs: []int64{7, 8, 9, 10}},
+		{desc: "label", opts: storage.ListTreesOptions{Labels: map[string]string{"tenant": "a"}}, wantIDs: []int64{8, 10}},
+		{desc: "page", opts: storage.ListTreesOptions{AfterTreeID: 7, Limit: 2}, wantIDs: []int64{8, 9}},
+	} {
+		trees, err := storage.ListTreesFiltered(ctx, as, test.opts)
+		if err != nil {
+			t.Fatalf("%v: ListTreesFiltered(): %v", test.desc, err)
+		}
+		var gotIDs []int64
+		for _, tree := range trees {
+			gotIDs = append(gotIDs, tree.TreeId)
+		}
+		if diff := cmp.Diff(test.wantIDs, gotIDs); diff != "" {
+			t.Errorf("%v: ListTreesFiltered() IDs diff (-want +got):\n%s", test.desc, diff)
+		}
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	ctx := context.Background()
+	as := NewAdminStorage(NewTreeStorage())
+
+	for _, treeID := range []int64{1, 2, 1} {
+		event := &trillian.AdminAuditEvent{TreeId: treeID, Operation: trillian.AdminAuditEvent_UPDATE_TREE}
+		if err := as.ReadWriteTransaction(ctx, func(ctx context.Context, tx storage.AdminTX) error {
+			return storage.AddAuditEvent(ctx, tx, event)
+		}); err != nil {
+			t.Fatalf("AddAuditEvent(): %v", err)
+		}
+	}
+
+	for _, test := range []struct {
+		desc    string
+		opts    storage.ListAuditEventsOptions
+		wantIDs []int64
+	}{
+		{desc: "all", wantIDs: []int64{1, 2, 3}},
+		{desc: "tree", opts: storage.ListAuditEventsOptions{TreeID: 1}, wantIDs: []int64{1, 3}},
+		{desc: "page", opts: storage.ListAuditEventsOptions{AfterEventID: 1, Limit: 1}, wantIDs: []int64{2}},
+	} {
+		events, err := storage.ListAuditEvents(ctx, as, test.opts)
+		if err != nil {
+			t.Fatalf("%v: ListAuditEvents(): %v", test.desc, err)
+		}
+		var gotIDs []int64
+		for _, event := range events {
+			gotIDs = append(gotIDs, event.EventId)
+		}
+		if diff := cmp.Diff(test.wantIDs, gotIDs); diff != "" {
+			t.Errorf("%v: ListAuditEvents() IDs diff (-want +got):\n%s", test.desc, diff)
+		}
+	}
+}
@@ -30,4 +30,10 @@
 // rolled-back.
 //
 // Currently, the Admin Storage does not honor transactional semantics.
+//
+// The contents of a TreeStorage can be saved to a file and loaded back with
+// SaveFile and LoadFile, so that demo and test environments survive restarts.
+// The storage provider does this when --memory_snapshot_path is set, loading
+// the file at startup and saving it on Close, as well as periodically if
+// --memory_snapshot_interval is set.
 package memory
@@ -0,0 +1,49 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/btree"
+	"github.com/google/trillian"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListSignedLogRoots implements storage.LogRootLister.
+func (m *memoryLogStorage) ListSignedLogRoots(ctx context.Context, tree *trillian.Tree, after uint64, limit int) ([]*trillian.SignedLogRoot, error) {
+	t := m.getTree(tree.TreeId)
+	if t == nil {
+		return nil, status.Errorf(codes.NotFound, "tree %d not found", tree.TreeId)
+	}
+	t.RLock()
+	defer t.RUnlock()
+
+	var ret []*trillian.SignedLogRoot
+	from := sthKey(tree.TreeId, after).(*kv)
+	end := &kv{k: fmt.Sprintf("/%d/sth0", tree.TreeId)}
+	t.store.AscendRange(from, end, func(i btree.Item) bool {
+		if len(ret) >= limit {
+			return false
+		}
+		if e := i.(*kv); e.k != from.k {
+			ret = append(ret, e.v.(*trillian.SignedLogRoot))
+		}
+		return true
+	})
+	return ret, nil
+}
@@ -27,12 +27,15 @@ import (
 	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/storage/compression"
+	"github.com/google/trillian/storage/storagepb"
 	stree "github.com/google/trillian/storage/tree"
 	"github.com/google/trillian/types"
 	"github.com/transparency-dev/merkle/compact"
 	"github.com/transparency-dev/merkle/rfc6962"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 const logIDLabel = "logid"
@@ -130,7 +133,12 @@ func (m *memoryLogStorage) GetActiveLogIDs(ctx context.Context) ([]int64, error)
 func (m *memoryLogStorage) beginInternal(ctx context.Context, tree *trillian.Tree, readonly bool) (*logTreeTX, error) {
 	once.Do(func() {
 		createMetrics(m.metricFactory)
+		compression.InitMetrics(m.metricFactory)
 	})
+	leafCompression, err := compression.Algorithm(tree)
+	if err != nil {
+		return nil, err
+	}
 
 	stCache := cache.NewLogSubtreeCache(rfc6962.DefaultHasher)
 	ttx, err := m.TreeStorage.beginTreeTX(ctx, tree.TreeId, rfc6962.DefaultHasher.Size(), stCache, readonly)
@@ -139,8 +147,9 @@ func (m *memoryLogStorage) beginInternal(ctx context.Context, tree *trillian.Tre
 	}
 
 	ltx := &logTreeTX{
-		treeTX: ttx,
-		ls:     m,
+		treeTX:          ttx,
+		ls:              m,
+		leafCompression: leafCompression,
 	}
 
 	var rev int64
@@ -181,6 +190,10 @@ func (m *memoryLogStorage) AddSequencedLeaves(ctx context.Context, tree *trillia
 func (m *memoryLogStorage) SnapshotForTree(ctx context.Context, tree *trillian.Tree) (storage.ReadOnlyLogTreeTX, error) {
 	tx, err := m.beginInternal(ctx, tree, true /* readonly */)
 	if err != nil {
+		if tx != nil {
+			// Release the tree lock taken along with ErrTreeNeedsInit.
+			tx.Close()
+		}
 		return nil, err
 	}
 	return tx, err
@@ -225,6 +238,9 @@ type logTreeTX struct {
 	ls   *memoryLogStorage
 	root types.LogRootV1
 	slr  *trillian.SignedLogRoot
+	// leafCompression is the algorithm which the leaves of the tree are
+	// compressed with.
+	leafCompression storagepb.LeafCompression
 }
 
 // GetMerkleNodes returns the requested nodes at (or below) the read revision.
@@ -274,7 +290,11 @@ func (t *logTreeTX) GetLeavesByRange(ctx context.Context, start, count int64) ([
 	for i := int64(0); i < count; i++ {
 		leaf := t.tx.Get(seqLeafKey(t.treeID, start+i))
 		if leaf != nil {
-			ret = append(ret, leaf.(*kv).v.(*trillian.LogLeaf))
+			l, err := t.decodeLeaf(leaf.(*kv).v.(*trillian.LogLeaf))
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, l)
 		}
 	}
 	return ret, nil
@@ -294,7 +314,11 @@ func (t *logTreeTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, or
 			if l == nil {
 				continue
 			}
-			ret = append(ret, l.(*kv).v.(*trillian.LogLeaf))
+			leaf, err := t.decodeLeaf(l.(*kv).v.(*trillian.LogLeaf))
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, leaf)
 		}
 	}
 	return ret, nil
@@ -350,9 +374,13 @@ func (t *logTreeTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillia
 		}
 		mh := string(leaf.MerkleLeafHash)
 		countByMerkleHash[mh]++
+		stored, err := t.encodeLeaf(leaf)
+		if err != nil {
+			return err
+		}
 		// insert sequenced leaf:
 		k := seqLeafKey(t.treeID, leaf.LeafIndex)
-		k.(*kv).v = leaf
+		k.(*kv).v = stored
 		t.tx.ReplaceOrInsert(k)
 		// update merkle-to-seq mapping:
 		m := t.tx.Get(hashToSeqKey(t.treeID))
@@ -384,3 +412,25 @@ func (t *logTreeTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillia
 
 	return nil
 }
+
+// encodeLeaf returns the leaf as it should be stored, with its value and extra
+// data compressed if the tree opted in to it.
+func (t *logTreeTX) encodeLeaf(leaf *trillian.LogLeaf) (*trillian.LogLeaf, error) {
+	value, extra, err := compression.EncodeLeaf(t.treeID, t.leafCompression, leaf)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to compress leaf %d: %v", leaf.LeafIndex, err)
+	}
+	stored := proto.Clone(leaf).(*trillian.LogLeaf)
+	stored.LeafValue, stored.ExtraData = value, extra
+	return stored, nil
+}
+
+// decodeLeaf returns the stored leaf with its value and extra data
+// decompressed. The stored leaf itself is not modified.
+func (t *logTreeTX) decodeLeaf(stored *trillian.LogLeaf) (*trillian.LogLeaf, error) {
+	leaf := proto.Clone(stored).(*trillian.LogLeaf)
+	if err := compression.DecodeLeaf(leaf); err != nil {
+		return nil, fmt.Errorf("failed to decode leaf %d: %v", leaf.LeafIndex, err)
+	}
+	return leaf, nil
+}
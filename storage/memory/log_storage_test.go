@@ -0,0 +1,116 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/storagepb"
+	"github.com/google/trillian/storage/testonly"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestSnapshotForTreeNeedsInit(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTreeStorage()
+	ls := NewLogStorage(ts, nil)
+	tree, err := storage.CreateTree(ctx, NewAdminStorage(ts), testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+
+	if _, err := ls.SnapshotForTree(ctx, tree); !errors.Is(err, storage.ErrTreeNeedsInit) {
+		t.Fatalf("SnapshotForTree() = %v, want %v", err, storage.ErrTreeNeedsInit)
+	}
+	// The tree must not be left locked.
+	if err := ls.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return storeLogRoot(ctx, tx, 0, 1)
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(): %v", err)
+	}
+}
+
+func TestLeafCompression(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTreeStorage()
+	ls := NewLogStorage(ts, nil)
+	settings, err := anypb.New(&storagepb.LogStorageSettings{LeafCompression: storagepb.LeafCompression_GZIP})
+	if err != nil {
+		t.Fatalf("anypb.New(): %v", err)
+	}
+	tree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	tree.StorageSettings = settings
+	tree, err = storage.CreateTree(ctx, NewAdminStorage(ts), tree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+
+	value := []byte(strings.Repeat("compressible value ", 50))
+	hash := sha256Hash(string(value))
+	leaf := &trillian.LogLeaf{LeafIdentityHash: hash, MerkleLeafHash: hash, LeafValue: value, ExtraData: []byte("extra")}
+	if err := ls.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return storeLogRoot(ctx, tx, 0, 1)
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(init): %v", err)
+	}
+	if _, err := ls.QueueLeaves(ctx, tree, []*trillian.LogLeaf{leaf}, fakeTime); err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+	if err := ls.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		dequeued, err := tx.DequeueLeaves(ctx, 1, fakeTime)
+		if err != nil {
+			return err
+		}
+		dequeued[0].IntegrateTimestamp = timestamppb.New(fakeTime)
+		if err := tx.UpdateSequencedLeaves(ctx, dequeued); err != nil {
+			return err
+		}
+		return storeLogRoot(ctx, tx, 1, 2)
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(): %v", err)
+	}
+
+	stored := ts.getTree(tree.TreeId).store.Get(seqLeafKey(tree.TreeId, 0)).(*kv).v.(*trillian.LogLeaf)
+	if len(stored.LeafValue) >= len(value) {
+		t.Errorf("Stored value of %d bytes is not compressed", len(stored.LeafValue))
+	}
+
+	tx, err := ls.SnapshotForTree(ctx, tree)
+	if err != nil {
+		t.Fatalf("SnapshotForTree(): %v", err)
+	}
+	defer tx.Close()
+	byRange, err := tx.GetLeavesByRange(ctx, 0, 1)
+	if err != nil {
+		t.Fatalf("GetLeavesByRange(): %v", err)
+	}
+	byHash, err := tx.GetLeavesByHash(ctx, [][]byte{hash}, false)
+	if err != nil {
+		t.Fatalf("GetLeavesByHash(): %v", err)
+	}
+	for _, leaves := range [][]*trillian.LogLeaf{byRange, byHash} {
+		if len(leaves) != 1 || !bytes.Equal(leaves[0].LeafValue, value) || !bytes.Equal(leaves[0].ExtraData, leaf.ExtraData) {
+			t.Errorf("Got leaves %v, want the original leaf", leaves)
+		}
+	}
+}
@@ -15,11 +15,22 @@
 package memory
 
 import (
+	"errors"
+	"flag"
+	"os"
+	"sync"
+	"time"
+
 	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/storage"
 	"k8s.io/klog/v2"
 )
 
+var (
+	snapshotPath     = flag.String("memory_snapshot_path", "", "If set, the memory storage is loaded from the snapshot in this file at startup, if it exists, and saved to it on shutdown")
+	snapshotInterval = flag.Duration("memory_snapshot_interval", 0, "If non-zero, the memory storage is also saved to --memory_snapshot_path at this interval")
+)
+
 func init() {
 	if err := storage.RegisterProvider("memory", newMemoryStorageProvider); err != nil {
 		klog.Fatalf("Failed to register storage provider memory: %v", err)
@@ -29,13 +40,61 @@ func init() {
 type memProvider struct {
 	mf monitoring.MetricFactory
 	ts *TreeStorage
+
+	// snapshotPath is the file the storage is saved to, if any.
+	snapshotPath string
+	// done is closed to stop the periodic snapshots, which signal wg when
+	// they have stopped.
+	done chan struct{}
+	wg   sync.WaitGroup
 }
 
 func newMemoryStorageProvider(mf monitoring.MetricFactory) (storage.Provider, error) {
-	return &memProvider{
-		mf: mf,
-		ts: NewTreeStorage(),
-	}, nil
+	p := &memProvider{
+		mf:           mf,
+		ts:           NewTreeStorage(),
+		snapshotPath: *snapshotPath,
+		done:         make(chan struct{}),
+	}
+	if p.snapshotPath == "" {
+		if *snapshotInterval != 0 {
+			return nil, errors.New("--memory_snapshot_interval requires --memory_snapshot_path")
+		}
+		return p, nil
+	}
+
+	switch err := p.ts.LoadFile(p.snapshotPath); {
+	case errors.Is(err, os.ErrNotExist):
+		klog.Infof("Memory storage snapshot %q not found, starting empty", p.snapshotPath)
+	case err != nil:
+		return nil, err
+	default:
+		klog.Infof("Loaded memory storage snapshot %q", p.snapshotPath)
+	}
+
+	if *snapshotInterval > 0 {
+		p.wg.Add(1)
+		go p.saveSnapshots(*snapshotInterval)
+	}
+	return p, nil
+}
+
+// saveSnapshots saves the storage to the snapshot file at the given interval,
+// until p.done is closed.
+func (s *memProvider) saveSnapshots(interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.ts.SaveFile(s.snapshotPath); err != nil {
+				klog.Errorf("Failed to save memory storage snapshot %q: %v", s.snapshotPath, err)
+			}
+		}
+	}
 }
 
 func (s *memProvider) LogStorage() storage.LogStorage {
@@ -47,5 +106,10 @@ func (s *memProvider) AdminStorage() storage.AdminStorage {
 }
 
 func (s *memProvider) Close() error {
-	return nil
+	if s.snapshotPath == "" {
+		return nil
+	}
+	close(s.done)
+	s.wg.Wait()
+	return s.ts.SaveFile(s.snapshotPath)
 }
@@ -15,9 +15,14 @@
 package memory
 
 import (
+	"context"
+	"flag"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/testonly/flagsaver"
 )
 
 func TestMemoryStorageProvider(t *testing.T) {
@@ -65,3 +70,44 @@ func TestMemoryStorageProviderClose(t *testing.T) {
 		t.Fatalf("Failed to close the memory storage provider: %v", err)
 	}
 }
+
+func TestMemoryStorageProviderSnapshot(t *testing.T) {
+	defer flagsaver.Save().MustRestore()
+	ctx := context.Background()
+	if err := flag.Set("memory_snapshot_path", filepath.Join(t.TempDir(), "trillian.snapshot")); err != nil {
+		t.Fatalf("Failed to set flag: %v", err)
+	}
+
+	// The snapshot file doesn't exist yet, so this starts empty.
+	sp, err := storage.NewProvider("memory", nil)
+	if err != nil {
+		t.Fatalf("NewProvider(): %v", err)
+	}
+	tree, err := storage.CreateTree(ctx, sp.AdminStorage(), testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	if err := sp.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+
+	// Start again, as a restarted server would.
+	sp, err = storage.NewProvider("memory", nil)
+	if err != nil {
+		t.Fatalf("NewProvider() after restart: %v", err)
+	}
+	defer sp.Close()
+	if _, err := storage.GetTree(ctx, sp.AdminStorage(), tree.TreeId); err != nil {
+		t.Errorf("GetTree() after restart: %v", err)
+	}
+}
+
+func TestMemoryStorageProviderSnapshotIntervalNeedsPath(t *testing.T) {
+	defer flagsaver.Save().MustRestore()
+	if err := flag.Set("memory_snapshot_interval", "1m"); err != nil {
+		t.Fatalf("Failed to set flag: %v", err)
+	}
+	if _, err := storage.NewProvider("memory", nil); err == nil {
+		t.Error("NewProvider() = nil error, want error")
+	}
+}
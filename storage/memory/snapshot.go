@@ -0,0 +1,288 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/btree"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage/storagepb"
+	"google.golang.org/protobuf/proto"
+)
+
+// snapshotVersion identifies the format of the snapshots written by Save.
+const snapshotVersion = 1
+
+// snapshot is the serialized form of a TreeStorage. Protos are stored in
+// their wire format, as gob can't encode them directly.
+type snapshot struct {
+	Version int
+	Trees   []treeSnapshot
+}
+
+// treeSnapshot is the serialized form of a tree.
+type treeSnapshot struct {
+	Meta       []byte
+	CurrentSTH uint64
+	Entries    []entrySnapshot
+}
+
+// entryKind identifies the type of the value of a BTree item.
+type entryKind int
+
+const (
+	subtreeEntry entryKind = iota + 1
+	leafEntry
+	rootEntry
+	revisionEntry
+	queueEntry
+	hashToSeqEntry
+)
+
+// entrySnapshot is the serialized form of an item in a tree's BTree store.
+// Which of the value fields is set depends on Kind.
+type entrySnapshot struct {
+	Key  string
+	Kind entryKind
+	// Proto holds SubtreeProto, LogLeaf and SignedLogRoot values.
+	Proto     []byte
+	Revision  int64
+	Queue     [][]byte
+	HashToSeq map[string][]int64
+}
+
+// Save writes a snapshot of all the trees in the storage to w. Each tree is
+// read-locked while it is being written, so the snapshot of each tree is
+// consistent, but trees may be written at different points in time.
+func (m *TreeStorage) Save(w io.Writer) error {
+	m.mu.RLock()
+	trees := make([]*tree, 0, len(m.trees))
+	for _, t := range m.trees {
+		trees = append(trees, t)
+	}
+	m.mu.RUnlock()
+	// Map iteration order is random, so sort the trees to make snapshots of
+	// the same data identical.
+	sort.Slice(trees, func(i, j int) bool { return trees[i].meta.TreeId < trees[j].meta.TreeId })
+
+	s := snapshot{Version: snapshotVersion, Trees: make([]treeSnapshot, 0, len(trees))}
+	for _, t := range trees {
+		ts, err := t.snapshot()
+		if err != nil {
+			return err
+		}
+		s.Trees = append(s.Trees, ts)
+	}
+	return gob.NewEncoder(w).Encode(&s)
+}
+
+// Load replaces all the trees in the storage with those in the snapshot
+// read from r, which must have been written by Save.
+func (m *TreeStorage) Load(r io.Reader) error {
+	var s snapshot
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %v", err)
+	}
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d, want %d", s.Version, snapshotVersion)
+	}
+
+	trees := make(map[int64]*tree, len(s.Trees))
+	for _, ts := range s.Trees {
+		t, err := restoreTree(ts)
+		if err != nil {
+			return err
+		}
+		trees[t.meta.TreeId] = t
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.trees = trees
+	return nil
+}
+
+// SaveFile writes a snapshot of the storage to the file at path. The snapshot
+// is written to a temporary file which is then renamed to path, so that the
+// previous snapshot is kept intact if writing fails part-way.
+func (m *TreeStorage) SaveFile(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Removing the file fails harmlessly once it has been renamed.
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriter(f)
+	if err := m.Save(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// LoadFile replaces all the trees in the storage with those in the snapshot
+// file at path, which must have been written by SaveFile.
+func (m *TreeStorage) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.Load(bufio.NewReader(f))
+}
+
+// snapshot returns the serialized form of the tree.
+func (t *tree) snapshot() (treeSnapshot, error) {
+	t.RLock()
+	defer t.RUnlock()
+
+	meta, err := proto.Marshal(t.meta)
+	if err != nil {
+		return treeSnapshot{}, err
+	}
+	ret := treeSnapshot{
+		Meta:       meta,
+		CurrentSTH: t.currentSTH,
+		Entries:    make([]entrySnapshot, 0, t.store.Len()),
+	}
+
+	t.store.Ascend(func(bi btree.Item) bool {
+		var e entrySnapshot
+		e, err = snapshotEntry(bi.(*kv))
+		if err != nil {
+			return false
+		}
+		ret.Entries = append(ret.Entries, e)
+		return true
+	})
+	if err != nil {
+		return treeSnapshot{}, err
+	}
+	return ret, nil
+}
+
+// snapshotEntry returns the serialized form of a BTree item.
+func snapshotEntry(i *kv) (entrySnapshot, error) {
+	e := entrySnapshot{Key: i.k}
+	var err error
+	switch v := i.v.(type) {
+	case *storagepb.SubtreeProto:
+		e.Kind = subtreeEntry
+		e.Proto, err = proto.Marshal(v)
+	case *trillian.LogLeaf:
+		e.Kind = leafEntry
+		e.Proto, err = proto.Marshal(v)
+	case *trillian.SignedLogRoot:
+		e.Kind = rootEntry
+		e.Proto, err = proto.Marshal(v)
+	case int64:
+		e.Kind = revisionEntry
+		e.Revision = v
+	case *list.List:
+		e.Kind = queueEntry
+		for le := v.Front(); le != nil && err == nil; le = le.Next() {
+			var leaf []byte
+			leaf, err = proto.Marshal(le.Value.(*trillian.LogLeaf))
+			e.Queue = append(e.Queue, leaf)
+		}
+	case map[string][]int64:
+		e.Kind = hashToSeqEntry
+		e.HashToSeq = v
+	default:
+		return entrySnapshot{}, fmt.Errorf("key %q has value of unexpected type %T", i.k, i.v)
+	}
+	if err != nil {
+		return entrySnapshot{}, fmt.Errorf("failed to marshal value of key %q: %v", i.k, err)
+	}
+	return e, nil
+}
+
+// restoreTree returns the tree in the given serialized form.
+func restoreTree(ts treeSnapshot) (*tree, error) {
+	var meta trillian.Tree
+	if err := proto.Unmarshal(ts.Meta, &meta); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tree: %v", err)
+	}
+	t := &tree{
+		store:      btree.New(degree),
+		currentSTH: ts.CurrentSTH,
+		meta:       &meta,
+	}
+	for _, e := range ts.Entries {
+		v, err := restoreValue(e)
+		if err != nil {
+			return nil, fmt.Errorf("tree %d: %v", meta.TreeId, err)
+		}
+		t.store.ReplaceOrInsert(&kv{k: e.Key, v: v})
+	}
+	return t, nil
+}
+
+// restoreValue returns the value of a BTree item from its serialized form.
+func restoreValue(e entrySnapshot) (interface{}, error) {
+	var m proto.Message
+	switch e.Kind {
+	case subtreeEntry:
+		m = &storagepb.SubtreeProto{}
+	case leafEntry:
+		m = &trillian.LogLeaf{}
+	case rootEntry:
+		m = &trillian.SignedLogRoot{}
+	case revisionEntry:
+		return e.Revision, nil
+	case queueEntry:
+		q := list.New()
+		for _, b := range e.Queue {
+			var leaf trillian.LogLeaf
+			if err := proto.Unmarshal(b, &leaf); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal queued leaf of key %q: %v", e.Key, err)
+			}
+			q.PushBack(&leaf)
+		}
+		return q, nil
+	case hashToSeqEntry:
+		if e.HashToSeq == nil {
+			// gob doesn't transmit empty maps.
+			return make(map[string][]int64), nil
+		}
+		return e.HashToSeq, nil
+	default:
+		return nil, fmt.Errorf("key %q has unknown kind %d", e.Key, e.Kind)
+	}
+	if err := proto.Unmarshal(e.Proto, m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal value of key %q: %v", e.Key, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	stree "github.com/google/trillian/storage/tree"
+	"github.com/google/trillian/types"
+	"github.com/transparency-dev/merkle/compact"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var fakeTime = time.Date(2016, 11, 10, 15, 16, 27, 0, time.UTC)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTreeStorage()
+	as := NewAdminStorage(ts)
+	ls := NewLogStorage(ts, nil)
+
+	tree, err := storage.CreateTree(ctx, as, testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	if err := ls.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return storeLogRoot(ctx, tx, 0, 1)
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(init): %v", err)
+	}
+
+	leaves := createTestLeaves(3)
+	if _, err := ls.QueueLeaves(ctx, tree, leaves, fakeTime); err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+
+	// Sequence the first two leaves, leaving the third one queued.
+	nodes := []stree.Node{
+		{ID: compact.NewNodeID(0, 0), Hash: leaves[0].MerkleLeafHash},
+		{ID: compact.NewNodeID(0, 1), Hash: leaves[1].MerkleLeafHash},
+		{ID: compact.NewNodeID(1, 0), Hash: sha256Hash("root")},
+	}
+	if err := ls.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		dequeued, err := tx.DequeueLeaves(ctx, 2, fakeTime)
+		if err != nil {
+			return err
+		}
+		for i, leaf := range dequeued {
+			leaf.LeafIndex = int64(i)
+			leaf.IntegrateTimestamp = timestamppb.New(fakeTime)
+		}
+		if err := tx.UpdateSequencedLeaves(ctx, dequeued); err != nil {
+			return err
+		}
+		if err := tx.SetMerkleNodes(ctx, nodes); err != nil {
+			return err
+		}
+		return storeLogRoot(ctx, tx, 2, 2)
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(sequence): %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ts.Save(&buf); err != nil {
+		t.Fatalf("Save(): %v", err)
+	}
+	restored := NewTreeStorage()
+	if err := restored.Load(&buf); err != nil {
+		t.Fatalf("Load(): %v", err)
+	}
+
+	// Everything read from the restored storage should match the original.
+	for _, s := range []*TreeStorage{ts, restored} {
+		gotTree, err := storage.GetTree(ctx, NewAdminStorage(s), tree.TreeId)
+		if err != nil {
+			t.Fatalf("GetTree(): %v", err)
+		}
+		if diff := cmp.Diff(tree, gotTree, protocmp.Transform()); diff != "" {
+			t.Errorf("GetTree() diff (-want +got):\n%s", diff)
+		}
+	}
+	read := func(s *TreeStorage) (*trillian.SignedLogRoot, []*trillian.LogLeaf, []stree.Node, []*trillian.LogLeaf) {
+		t.Helper()
+		var root *trillian.SignedLogRoot
+		var seq, queued []*trillian.LogLeaf
+		var gotNodes []stree.Node
+		if err := NewLogStorage(s, nil).ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+			var err error
+			if root, err = tx.LatestSignedLogRoot(ctx); err != nil {
+				return err
+			}
+			if seq, err = tx.GetLeavesByRange(ctx, 0, 2); err != nil {
+				return err
+			}
+			if gotNodes, err = tx.GetMerkleNodes(ctx, []compact.NodeID{nodes[0].ID, nodes[1].ID, nodes[2].ID}); err != nil {
+				return err
+			}
+			queued, err = tx.DequeueLeaves(ctx, 10, fakeTime)
+			return err
+		}); err != nil {
+			t.Fatalf("ReadWriteTransaction(read): %v", err)
+		}
+		return root, seq, gotNodes, queued
+	}
+	wantRoot, wantSeq, _, wantQueued := read(ts)
+	gotRoot, gotSeq, gotNodes, gotQueued := read(restored)
+	if diff := cmp.Diff(wantRoot, gotRoot, protocmp.Transform()); diff != "" {
+		t.Errorf("LatestSignedLogRoot() diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(wantSeq, gotSeq, protocmp.Transform()); diff != "" {
+		t.Errorf("GetLeavesByRange() diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(nodes, gotNodes); diff != "" {
+		t.Errorf("GetMerkleNodes() diff (-want +got):\n%s", diff)
+	}
+	if got, want := len(gotQueued), 1; got != want {
+		t.Errorf("DequeueLeaves() returned %d leaves, want %d", got, want)
+	}
+	if diff := cmp.Diff(wantQueued, gotQueued, protocmp.Transform()); diff != "" {
+		t.Errorf("DequeueLeaves() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadRejectsGarbage(t *testing.T) {
+	if err := NewTreeStorage().Load(bytes.NewReader([]byte("not a snapshot"))); err == nil {
+		t.Error("Load() = nil, want error")
+	}
+}
+
+func TestSaveFileReplacesSnapshot(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/snapshot"
+	ts := NewTreeStorage()
+	if err := ts.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile(empty): %v", err)
+	}
+	tree, err := storage.CreateTree(ctx, NewAdminStorage(ts), testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	if err := ts.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile(): %v", err)
+	}
+
+	restored := NewTreeStorage()
+	if err := restored.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile(): %v", err)
+	}
+	if _, err := storage.GetTree(ctx, NewAdminStorage(restored), tree.TreeId); err != nil {
+		t.Errorf("GetTree() after LoadFile(): %v", err)
+	}
+}
+
+func storeLogRoot(ctx context.Context, tx storage.LogTreeTX, size, timestamp uint64) error {
+	logRoot, err := (&types.LogRootV1{TreeSize: size, TimestampNanos: timestamp, RootHash: sha256Hash("root")}).MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: logRoot})
+}
+
+func createTestLeaves(n int) []*trillian.LogLeaf {
+	leaves := make([]*trillian.LogLeaf, 0, n)
+	for i := 0; i < n; i++ {
+		value := fmt.Sprintf("Leaf %d", i)
+		hash := sha256Hash(value)
+		leaves = append(leaves, &trillian.LogLeaf{
+			LeafIdentityHash: hash,
+			MerkleLeafHash:   hash,
+			LeafValue:        []byte(value),
+			ExtraData:        []byte(fmt.Sprintf("Extra %d", i)),
+			QueueTimestamp:   timestamppb.New(fakeTime),
+		})
+	}
+	return leaves
+}
+
+func sha256Hash(s string) []byte {
+	h := sha256.Sum256([]byte(s))
+	return h[:]
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/btree"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/storagepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// GetTreeStats implements storage.TreeStatsReader.
+func (m *memoryLogStorage) GetTreeStats(ctx context.Context, tree *trillian.Tree) (*storage.TreeStats, error) {
+	t := m.getTree(tree.TreeId)
+	if t == nil {
+		return nil, status.Errorf(codes.NotFound, "tree %d not found", tree.TreeId)
+	}
+	t.RLock()
+	defer t.RUnlock()
+
+	var stats storage.TreeStats
+	q := t.store.Get(unseqKey(tree.TreeId)).(*kv).v.(*list.List)
+	stats.QueueDepth = int64(q.Len())
+	if e := q.Front(); e != nil {
+		if ts := e.Value.(*trillian.LogLeaf).QueueTimestamp; ts != nil {
+			stats.OldestQueued = ts.AsTime()
+		}
+	}
+	for e := q.Front(); e != nil; e = e.Next() {
+		leaf := e.Value.(*trillian.LogLeaf)
+		stats.LeafBytes += int64(len(leaf.LeafValue) + len(leaf.ExtraData))
+	}
+	ascendPrefix(t.store, fmt.Sprintf("/%d/seq/", tree.TreeId), func(v interface{}) {
+		leaf := v.(*trillian.LogLeaf)
+		stats.LeafCount++
+		stats.LeafBytes += int64(len(leaf.LeafValue) + len(leaf.ExtraData))
+	})
+	ascendPrefix(t.store, fmt.Sprintf("/%d/subtree/", tree.TreeId), func(v interface{}) {
+		stats.TileBytes += int64(proto.Size(v.(*storagepb.SubtreeProto)))
+	})
+	if t.store.Get(sthKey(tree.TreeId, t.currentSTH)) != nil {
+		stats.LatestRoot = time.Unix(0, int64(t.currentSTH))
+	}
+	return &stats, nil
+}
+
+// ascendPrefix calls f with the values of the items whose keys start with
+// prefix, in key order.
+func ascendPrefix(store *btree.BTree, prefix string, f func(v interface{})) {
+	// The keys with the prefix are those before the prefix with its last
+	// character, a '/', replaced by the next one.
+	end := prefix[:len(prefix)-1] + "0"
+	store.AscendRange(&kv{k: prefix}, &kv{k: end}, func(i btree.Item) bool {
+		f(i.(*kv).v)
+		return true
+	})
+}
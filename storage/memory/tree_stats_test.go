@@ -0,0 +1,76 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestGetTreeStats(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTreeStorage()
+	ls := NewLogStorage(ts, nil)
+	tree, err := storage.CreateTree(ctx, NewAdminStorage(ts), testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	if err := ls.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return storeLogRoot(ctx, tx, 0, 1)
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(init): %v", err)
+	}
+	if _, err := ls.QueueLeaves(ctx, tree, createTestLeaves(3), fakeTime); err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+	if err := ls.ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		dequeued, err := tx.DequeueLeaves(ctx, 2, fakeTime)
+		if err != nil {
+			return err
+		}
+		for i, leaf := range dequeued {
+			leaf.LeafIndex = int64(i)
+			leaf.IntegrateTimestamp = timestamppb.New(fakeTime)
+		}
+		if err := tx.UpdateSequencedLeaves(ctx, dequeued); err != nil {
+			return err
+		}
+		return storeLogRoot(ctx, tx, 2, 2)
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(): %v", err)
+	}
+
+	stats, err := ls.(storage.TreeStatsReader).GetTreeStats(ctx, tree)
+	if err != nil {
+		t.Fatalf("GetTreeStats(): %v", err)
+	}
+	want := storage.TreeStats{
+		LeafCount:    2,
+		QueueDepth:   1,
+		OldestQueued: fakeTime,
+		LatestRoot:   time.Unix(0, 2),
+		// Each of the leaves has a 6 byte value and 7 bytes of extra data.
+		LeafBytes: 3 * 13,
+	}
+	if stats.LeafCount != want.LeafCount || stats.QueueDepth != want.QueueDepth || !stats.OldestQueued.Equal(want.OldestQueued) ||
+		!stats.LatestRoot.Equal(want.LatestRoot) || stats.LeafBytes != want.LeafBytes {
+		t.Errorf("GetTreeStats() = %+v, want %+v", stats, want)
+	}
+}
@@ -82,6 +82,10 @@ type TreeStorage struct {
 	// mu only protects access to the trees map.
 	mu    sync.RWMutex
 	trees map[int64]*tree
+
+	// auditMu protects access to auditEvents, the admin audit log.
+	auditMu     sync.Mutex
+	auditEvents []*trillian.AdminAuditEvent
 }
 
 // NewTreeStorage returns a new instance of the in-memory tree storage database.
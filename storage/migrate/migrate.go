@@ -0,0 +1,666 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package migrate copies log trees between storage systems, preserving their
+// IDs, leaves, Merkle tree nodes and log roots.
+//
+// A tree is migrated by calling CatchUp repeatedly while the source tree is
+// live, each call copying what has been integrated since the previous one,
+// and then calling Cutover to drain and freeze the source tree, copy the
+// remainder, and hand the tree over to the destination.
+//
+// Only the generic storage interfaces are used, so any pair of storage
+// systems is supported, as long as the source storage implements
+// storage.LogRootLister. Each log root of the source tree is copied verbatim,
+// once its root hash has been checked, in a transaction with the leaves it
+// added and the Merkle tree nodes stored for them in the source tree.
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/tree"
+	"github.com/google/trillian/types"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultBatchSize is the default number of leaves copied in each
+	// transaction.
+	DefaultBatchSize = 1000
+	// DefaultPollInterval is the default interval at which Cutover checks
+	// whether the source tree has been drained.
+	DefaultPollInterval = time.Second
+
+	// rootPageSize is the number of source log roots listed at a time.
+	rootPageSize = 1000
+)
+
+// Options configures a migration.
+type Options struct {
+	// BatchSize is the number of leaves read from the source tree in each
+	// transaction. DefaultBatchSize is used if it is zero.
+	BatchSize int
+	// PollInterval is the interval at which Cutover checks whether the
+	// source tree has been drained. DefaultPollInterval is used if it is
+	// zero.
+	PollInterval time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultBatchSize
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = DefaultPollInterval
+	}
+	return o
+}
+
+// CatchUp copies the log tree with the given ID from src to dst, up to the
+// latest log root in src, and returns that root. It returns a nil root if the
+// source tree hasn't been initialized yet.
+//
+// The first call creates the tree in dst, with the same ID and config as in
+// src, and sets it to FROZEN so that log signers using dst leave it alone.
+// Later calls copy only the leaves added to the source tree since. CatchUp
+// is safe to call while the source tree is in use.
+func CatchUp(ctx context.Context, src, dst storage.Provider, treeID int64, opts Options) (*types.LogRootV1, error) {
+	opts = opts.withDefaults()
+	srcTree, err := storage.GetTree(ctx, src.AdminStorage(), treeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source tree: %v", err)
+	}
+	dstTree, err := copyTree(ctx, dst.AdminStorage(), srcTree)
+	if err != nil {
+		return nil, err
+	}
+	c := &copier{
+		src:       src.LogStorage(),
+		dst:       dst.LogStorage(),
+		srcTree:   srcTree,
+		dstTree:   dstTree,
+		batchSize: opts.BatchSize,
+	}
+	return c.catchUp(ctx)
+}
+
+// Cutover completes the migration of the log tree with the given ID from src
+// to dst, and returns the final log root.
+//
+// The source tree is set to DRAINING, so that it no longer accepts new
+// leaves, until the log signer has integrated all those already queued, and
+// then to FROZEN. The remaining leaves are then copied, and the log roots on
+// both sides compared, before the destination tree is set to the state that
+// the source tree was in. From then on, the tree should be served from dst.
+//
+// If Cutover fails after freezing the source tree, calling it again finishes
+// the migration, but leaves the destination tree FROZEN. It can be set to
+// the original state with the updatetree command.
+func Cutover(ctx context.Context, src, dst storage.Provider, treeID int64, opts Options) (*types.LogRootV1, error) {
+	opts = opts.withDefaults()
+	srcTree, err := storage.GetTree(ctx, src.AdminStorage(), treeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source tree: %v", err)
+	}
+	state := srcTree.TreeState
+
+	// Copy as much as possible while the source tree is still live, to keep
+	// the time it spends DRAINING short.
+	if _, err := CatchUp(ctx, src, dst, treeID, opts); err != nil {
+		return nil, err
+	}
+
+	switch state {
+	case trillian.TreeState_ACTIVE, trillian.TreeState_DRAINING:
+		if err := setState(ctx, src.AdminStorage(), treeID, trillian.TreeState_DRAINING); err != nil {
+			return nil, fmt.Errorf("failed to drain source tree: %v", err)
+		}
+		if err := waitDrained(ctx, src.LogStorage(), srcTree, opts.PollInterval); err != nil {
+			return nil, fmt.Errorf("failed waiting for source tree to drain: %v", err)
+		}
+		if err := setState(ctx, src.AdminStorage(), treeID, trillian.TreeState_FROZEN); err != nil {
+			return nil, fmt.Errorf("failed to freeze source tree: %v", err)
+		}
+	}
+
+	root, err := CatchUp(ctx, src, dst, treeID, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := Compare(ctx, src, dst, treeID); err != nil {
+		return nil, err
+	}
+
+	if err := setState(ctx, dst.AdminStorage(), treeID, state); err != nil {
+		return nil, fmt.Errorf("failed to set state of destination tree: %v", err)
+	}
+	if srcTree.Deleted {
+		if _, err := storage.SoftDeleteTree(ctx, dst.AdminStorage(), treeID); err != nil {
+			return nil, fmt.Errorf("failed to delete destination tree: %v", err)
+		}
+	}
+	return root, nil
+}
+
+// Compare checks that the latest log roots of the tree with the given ID are
+// the same in src and dst.
+func Compare(ctx context.Context, src, dst storage.Provider, treeID int64) error {
+	srcTree, err := storage.GetTree(ctx, src.AdminStorage(), treeID)
+	if err != nil {
+		return fmt.Errorf("failed to read source tree: %v", err)
+	}
+	dstTree, err := storage.GetTree(ctx, dst.AdminStorage(), treeID)
+	if err != nil {
+		return fmt.Errorf("failed to read destination tree: %v", err)
+	}
+	srcSLR, srcRoot, srcErr := latestRoot(ctx, src.LogStorage(), srcTree)
+	dstSLR, dstRoot, dstErr := latestRoot(ctx, dst.LogStorage(), dstTree)
+	if errors.Is(srcErr, storage.ErrTreeNeedsInit) && errors.Is(dstErr, storage.ErrTreeNeedsInit) {
+		// Neither tree has been initialized.
+		return nil
+	}
+	if srcErr != nil {
+		return fmt.Errorf("failed to read source root: %v", srcErr)
+	}
+	if dstErr != nil {
+		return fmt.Errorf("failed to read destination root: %v", dstErr)
+	}
+	if !bytes.Equal(srcSLR.LogRoot, dstSLR.LogRoot) {
+		return fmt.Errorf("log roots differ: source has size %d and hash %x, destination has size %d and hash %x",
+			srcRoot.TreeSize, srcRoot.RootHash, dstRoot.TreeSize, dstRoot.RootHash)
+	}
+	return nil
+}
+
+// copyTree returns the destination copy of the given source tree, creating
+// it if it doesn't exist yet.
+func copyTree(ctx context.Context, as storage.AdminStorage, srcTree *trillian.Tree) (*trillian.Tree, error) {
+	switch srcTree.TreeType {
+	case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+	default:
+		return nil, fmt.Errorf("can't migrate tree %d of type %v", srcTree.TreeId, srcTree.TreeType)
+	}
+
+	// Not all storage implementations return NotFound for missing trees, so
+	// look for it in the list instead.
+	trees, err := storage.ListTrees(ctx, as, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list destination trees: %v", err)
+	}
+	for _, t := range trees {
+		if t.TreeId != srcTree.TreeId {
+			continue
+		}
+		if t.TreeType != srcTree.TreeType {
+			return nil, fmt.Errorf("destination tree %d has type %v, want %v", t.TreeId, t.TreeType, srcTree.TreeType)
+		}
+		return t, nil
+	}
+
+	newTree := proto.Clone(srcTree).(*trillian.Tree)
+	newTree.TreeId = 0
+	newTree.TreeState = trillian.TreeState_ACTIVE
+	newTree.CreateTime, newTree.UpdateTime = nil, nil
+	newTree.Deleted, newTree.DeleteTime = false, nil
+	if _, err := storage.CreateTreeWithID(ctx, as, newTree, srcTree.TreeId); err != nil {
+		return nil, fmt.Errorf("failed to create destination tree: %v", err)
+	}
+	t, err := storage.UpdateTree(ctx, as, srcTree.TreeId, func(t *trillian.Tree) {
+		t.TreeState = trillian.TreeState_FROZEN
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to freeze destination tree: %v", err)
+	}
+	klog.Infof("Created tree %d in destination storage", t.TreeId)
+	return t, nil
+}
+
+func setState(ctx context.Context, as storage.AdminStorage, treeID int64, state trillian.TreeState) error {
+	_, err := storage.UpdateTree(ctx, as, treeID, func(t *trillian.Tree) {
+		t.TreeState = state
+	})
+	return err
+}
+
+// waitDrained waits until the tree has no leaves waiting to be integrated,
+// and its log root hasn't changed for an interval.
+func waitDrained(ctx context.Context, ls storage.LogStorage, t *trillian.Tree, interval time.Duration) error {
+	var prev []byte
+	for {
+		pending := 0
+		var logRoot []byte
+		// DequeueLeaves only reads the leaves, which are removed from the
+		// queue when they are sequenced.
+		if err := ls.ReadWriteTransaction(ctx, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+			leaves, err := tx.DequeueLeaves(ctx, 1, time.Now())
+			if err != nil {
+				return err
+			}
+			pending = len(leaves)
+			slr, err := tx.LatestSignedLogRoot(ctx)
+			if err != nil {
+				return err
+			}
+			logRoot = slr.LogRoot
+			return nil
+		}); err != nil {
+			return err
+		}
+		if pending == 0 && prev != nil && bytes.Equal(logRoot, prev) {
+			return nil
+		}
+		prev = logRoot
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// latestRoot returns the latest log root of the tree, or ErrTreeNeedsInit if
+// it has none.
+func latestRoot(ctx context.Context, ls storage.LogStorage, t *trillian.Tree) (*trillian.SignedLogRoot, *types.LogRootV1, error) {
+	tx, err := ls.SnapshotForTree(ctx, t)
+	if tx != nil {
+		// Some storage implementations return a transaction along with
+		// ErrTreeNeedsInit, which must be closed too.
+		defer tx.Close()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	slr, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var root types.LogRootV1
+	if err := root.UnmarshalBinary(slr.LogRoot); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal log root: %v", err)
+	}
+	return slr, &root, tx.Commit(ctx)
+}
+
+// copier copies the leaves, nodes and log roots of a tree from one storage
+// system to another.
+type copier struct {
+	src, dst         storage.LogStorage
+	srcTree, dstTree *trillian.Tree
+	batchSize        int
+	// cr is the compact range of the leaves in the destination tree.
+	cr *compact.Range
+}
+
+func (c *copier) catchUp(ctx context.Context) (*types.LogRootV1, error) {
+	srcSLR, srcRoot, err := latestRoot(ctx, c.src, c.srcTree)
+	if errors.Is(err, storage.ErrTreeNeedsInit) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read source root: %v", err)
+	}
+	dstSLR, dstRoot, err := latestRoot(ctx, c.dst, c.dstTree)
+	if err != nil && !errors.Is(err, storage.ErrTreeNeedsInit) {
+		return nil, fmt.Errorf("failed to read destination root: %v", err)
+	}
+	if dstSLR != nil && bytes.Equal(dstSLR.LogRoot, srcSLR.LogRoot) {
+		return srcRoot, nil
+	}
+
+	roots := &rootLister{ls: c.src, tree: c.srcTree, latest: srcSLR, latestRoot: srcRoot}
+	var size uint64
+	if dstRoot != nil {
+		size = dstRoot.TreeSize
+		if size > srcRoot.TreeSize {
+			return nil, fmt.Errorf("destination tree size %d is larger than source tree size %d", size, srcRoot.TreeSize)
+		}
+		if c.cr, err = c.dstRange(ctx, dstRoot); err != nil {
+			return nil, err
+		}
+		roots.after = dstRoot.TimestampNanos
+	}
+
+	for {
+		slr, root, err := roots.next(ctx)
+		if err != nil {
+			return nil, err
+		} else if slr == nil {
+			break
+		}
+		if err := c.copyRoot(ctx, slr, root); err != nil {
+			return nil, fmt.Errorf("failed to copy log root of size %d with timestamp %d: %v", root.TreeSize, root.TimestampNanos, err)
+		}
+	}
+	klog.Infof("Tree %d: copied leaves [%d, %d)", c.srcTree.TreeId, size, srcRoot.TreeSize)
+
+	dstSLR, _, err = latestRoot(ctx, c.dst, c.dstTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read destination root: %v", err)
+	}
+	if !bytes.Equal(dstSLR.LogRoot, srcSLR.LogRoot) {
+		return nil, errors.New("destination root differs from source root after copy")
+	}
+	return srcRoot, nil
+}
+
+// copyRoot copies a source log root to the destination tree, together with
+// the leaves it added, and their nodes, in one transaction.
+func (c *copier) copyRoot(ctx context.Context, slr *trillian.SignedLogRoot, root *types.LogRootV1) error {
+	if c.cr == nil {
+		// Storage only accepts leaves for initialized trees, which the log
+		// server does with an empty log root.
+		if root.TreeSize > 0 {
+			return errors.New("the destination tree can only be initialized with an empty log root, which the source tree doesn't have")
+		}
+		c.cr = (&compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}).NewEmptyRange(0)
+	}
+	if root.TreeSize < c.cr.End() {
+		return fmt.Errorf("size is smaller than the %d leaves already copied", c.cr.End())
+	}
+
+	var leaves []*trillian.LogLeaf
+	var nodes []tree.Node
+	for c.cr.End() < root.TreeSize {
+		start := c.cr.End()
+		count := root.TreeSize - start
+		if count > uint64(c.batchSize) {
+			count = uint64(c.batchSize)
+		}
+		l, n, err := c.fetch(ctx, start, count)
+		if err != nil {
+			return fmt.Errorf("failed to read leaves [%d, %d) from source: %v", start, start+count, err)
+		}
+		leaves = append(leaves, l...)
+		nodes = append(nodes, n...)
+	}
+	hash, err := rootHash(c.cr)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(hash, root.RootHash) {
+		return fmt.Errorf("root hash mismatch: computed %x, source has %x", hash, root.RootHash)
+	}
+
+	if len(leaves) == 0 {
+		return c.storeRoot(ctx, slr)
+	}
+	if err := c.write(ctx, leaves, nodes, slr); err != nil {
+		return fmt.Errorf("failed to write leaves [%d, %d) to destination: %v", leaves[0].LeafIndex, root.TreeSize, err)
+	}
+	return nil
+}
+
+// rootLister lists the log roots of the source tree after a timestamp, oldest
+// first, a page at a time, and ending with its latest log root.
+type rootLister struct {
+	ls   storage.LogStorage
+	tree *trillian.Tree
+	// latest is the latest log root of the tree, as of the start of the copy,
+	// which is listed last.
+	latest     *trillian.SignedLogRoot
+	latestRoot *types.LogRootV1
+
+	after uint64
+	page  []*trillian.SignedLogRoot
+	roots []*types.LogRootV1
+	done  bool
+}
+
+// next returns the next log root, or nil if there are no more.
+func (l *rootLister) next(ctx context.Context) (*trillian.SignedLogRoot, *types.LogRootV1, error) {
+	if len(l.page) == 0 && !l.done {
+		slrs, err := storage.ListSignedLogRoots(ctx, l.ls, l.tree, l.after, rootPageSize)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list source roots: %v", err)
+		}
+		l.done = len(slrs) < rootPageSize
+		for _, slr := range slrs {
+			var root types.LogRootV1
+			if err := root.UnmarshalBinary(slr.LogRoot); err != nil {
+				return nil, nil, fmt.Errorf("failed to unmarshal log root: %v", err)
+			}
+			if root.TimestampNanos >= l.latestRoot.TimestampNanos {
+				l.done = true
+				break
+			}
+			l.page = append(l.page, slr)
+			l.roots = append(l.roots, &root)
+			l.after = root.TimestampNanos
+		}
+		if l.done {
+			l.page = append(l.page, l.latest)
+			l.roots = append(l.roots, l.latestRoot)
+		}
+	}
+	if len(l.page) == 0 {
+		return nil, nil, nil
+	}
+	slr, root := l.page[0], l.roots[0]
+	l.page, l.roots = l.page[1:], l.roots[1:]
+	return slr, root, nil
+}
+
+// dstRange returns the compact range of the destination tree, checking that
+// it matches the root.
+func (c *copier) dstRange(ctx context.Context, root *types.LogRootV1) (*compact.Range, error) {
+	tx, err := c.dst.SnapshotForTree(ctx, c.dstTree)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+	ids := compact.RangeNodes(0, root.TreeSize, nil)
+	nodes, err := tx.GetMerkleNodes(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read destination compact range: %v", err)
+	}
+	if got, want := len(nodes), len(ids); got != want {
+		return nil, fmt.Errorf("got %d destination nodes, want %d", got, want)
+	}
+	hashes := make([][]byte, len(nodes))
+	for i, n := range nodes {
+		hashes[i] = n.Hash
+	}
+	cr, err := (&compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}).NewRange(0, root.TreeSize, hashes)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := cr.GetRootHash(nil)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(hash, root.RootHash) {
+		return nil, fmt.Errorf("destination root hash mismatch: computed %x, stored %x", hash, root.RootHash)
+	}
+	return cr, tx.Commit(ctx)
+}
+
+// fetch reads the given range of leaves from the source tree, and appends
+// them to the compact range. It returns the leaves, and the Merkle tree nodes
+// that they complete as stored in the source tree, once checked against the
+// compact range.
+func (c *copier) fetch(ctx context.Context, start, count uint64) ([]*trillian.LogLeaf, []tree.Node, error) {
+	tx, err := c.src.SnapshotForTree(ctx, c.srcTree)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Close()
+
+	leaves := make([]*trillian.LogLeaf, 0, count)
+	for next := int64(start); len(leaves) < int(count); {
+		batch, err := tx.GetLeavesByRange(ctx, next, int64(count)-int64(len(leaves)))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(batch) == 0 {
+			return nil, nil, fmt.Errorf("missing leaf %d", next)
+		}
+		for _, leaf := range batch {
+			if leaf.LeafIndex != next {
+				return nil, nil, fmt.Errorf("got leaf %d, want %d", leaf.LeafIndex, next)
+			}
+			leaves = append(leaves, leaf)
+			next++
+		}
+	}
+
+	var nodes []tree.Node
+	store := func(id compact.NodeID, hash []byte) { nodes = append(nodes, tree.Node{ID: id, Hash: hash}) }
+	for _, leaf := range leaves {
+		if err := c.cr.Append(leaf.MerkleLeafHash, store); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ids := make([]compact.NodeID, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.ID
+	}
+	stored, err := tx.GetMerkleNodes(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	if got, want := len(stored), len(nodes); got != want {
+		return nil, nil, fmt.Errorf("got %d source nodes, want %d", got, want)
+	}
+	for i, n := range stored {
+		if n.ID != nodes[i].ID || !bytes.Equal(n.Hash, nodes[i].Hash) {
+			return nil, nil, fmt.Errorf("source node %+v has hash %x, computed %x", nodes[i].ID, n.Hash, nodes[i].Hash)
+		}
+	}
+	return leaves, stored, tx.Commit(ctx)
+}
+
+// write writes a batch of leaves to the destination tree, along with the
+// nodes and log root resulting from them.
+func (c *copier) write(ctx context.Context, leaves []*trillian.LogLeaf, nodes []tree.Node, slr *trillian.SignedLogRoot) error {
+	preordered := c.dstTree.TreeType == trillian.TreeType_PREORDERED_LOG
+	if preordered {
+		res, err := c.dst.AddSequencedLeaves(ctx, c.dstTree, leaves, queueTime(leaves[0]))
+		if err != nil {
+			return err
+		}
+		if err := checkResults(res, leaves); err != nil {
+			return err
+		}
+	} else if err := c.queue(ctx, leaves); err != nil {
+		return err
+	}
+
+	byIdentity := make(map[string]*trillian.LogLeaf, len(leaves))
+	var cutoff time.Time
+	for _, leaf := range leaves {
+		byIdentity[string(leaf.LeafIdentityHash)] = leaf
+		if ts := queueTime(leaf); ts.After(cutoff) {
+			cutoff = ts
+		}
+	}
+	return c.dst.ReadWriteTransaction(ctx, c.dstTree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		if !preordered {
+			// Sequence the queued leaves at their indices in the source tree.
+			dequeued, err := tx.DequeueLeaves(ctx, len(leaves), cutoff)
+			if err != nil {
+				return err
+			}
+			if got, want := len(dequeued), len(leaves); got != want {
+				return fmt.Errorf("dequeued %d leaves, want %d", got, want)
+			}
+			for _, leaf := range dequeued {
+				orig, ok := byIdentity[string(leaf.LeafIdentityHash)]
+				if !ok {
+					return fmt.Errorf("dequeued unexpected leaf with identity hash %x", leaf.LeafIdentityHash)
+				}
+				leaf.LeafIndex = orig.LeafIndex
+				leaf.IntegrateTimestamp = orig.IntegrateTimestamp
+			}
+			if err := tx.UpdateSequencedLeaves(ctx, dequeued); err != nil {
+				return err
+			}
+		}
+		if err := tx.SetMerkleNodes(ctx, nodes); err != nil {
+			return err
+		}
+		return tx.StoreSignedLogRoot(ctx, slr)
+	})
+}
+
+// queue adds leaves to the queue of the destination tree, in runs with the
+// same queue timestamp. Leaves already queued by an earlier, interrupted,
+// copy are accepted.
+func (c *copier) queue(ctx context.Context, leaves []*trillian.LogLeaf) error {
+	for start := 0; start < len(leaves); {
+		ts := queueTime(leaves[start])
+		end := start + 1
+		for end < len(leaves) && queueTime(leaves[end]).Equal(ts) {
+			end++
+		}
+		res, err := c.dst.QueueLeaves(ctx, c.dstTree, leaves[start:end], ts)
+		if err != nil {
+			return err
+		}
+		if err := checkResults(res, leaves[start:end]); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// checkResults checks the results of queueing or adding leaves. Leaves that
+// already exist were written by an earlier, interrupted, copy.
+func checkResults(res []*trillian.QueuedLogLeaf, leaves []*trillian.LogLeaf) error {
+	for i, r := range res {
+		switch c := codes.Code(r.GetStatus().GetCode()); c {
+		case codes.OK, codes.AlreadyExists:
+		default:
+			return fmt.Errorf("failed to write leaf %d: %v: %s", leaves[i].LeafIndex, c, r.GetStatus().GetMessage())
+		}
+	}
+	return nil
+}
+
+// storeRoot stores a log root in the destination tree.
+func (c *copier) storeRoot(ctx context.Context, slr *trillian.SignedLogRoot) error {
+	return c.dst.ReadWriteTransaction(ctx, c.dstTree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, slr)
+	})
+}
+
+// rootHash returns the root hash of the tree with the leaves in cr, which
+// must start at zero.
+func rootHash(cr *compact.Range) ([]byte, error) {
+	if cr.End() == 0 {
+		return rfc6962.DefaultHasher.EmptyRoot(), nil
+	}
+	return cr.GetRootHash(nil)
+}
+
+// queueTime returns the queue timestamp of the leaf, or the zero Unix time
+// if it has none.
+func queueTime(leaf *trillian.LogLeaf) time.Time {
+	if leaf.QueueTimestamp == nil {
+		return time.Unix(0, 0)
+	}
+	return leaf.QueueTimestamp.AsTime()
+}
@@ -0,0 +1,360 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/trillian"
+	"github.com/google/trillian/log"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/bolt"
+	"github.com/google/trillian/storage/memory"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/types"
+	"github.com/google/trillian/util/clock"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func init() {
+	log.InitMetrics(nil)
+}
+
+// testProvider is a storage.Provider wrapping existing storage.
+type testProvider struct {
+	as storage.AdminStorage
+	ls storage.LogStorage
+}
+
+func (p testProvider) AdminStorage() storage.AdminStorage { return p.as }
+func (p testProvider) LogStorage() storage.LogStorage     { return p.ls }
+func (p testProvider) Close() error                       { return nil }
+
+func newMemoryProvider(t *testing.T) storage.Provider {
+	t.Helper()
+	ts := memory.NewTreeStorage()
+	return testProvider{as: memory.NewAdminStorage(ts), ls: memory.NewLogStorage(ts, nil)}
+}
+
+func newBoltProvider(t *testing.T) storage.Provider {
+	t.Helper()
+	db, err := bolt.OpenDB(filepath.Join(t.TempDir(), "trillian.bolt"))
+	if err != nil {
+		t.Fatalf("OpenDB(): %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return testProvider{as: bolt.NewAdminStorage(db), ls: bolt.NewLogStorage(db, nil)}
+}
+
+// testLog is a log tree in the source storage.
+type testLog struct {
+	t    *testing.T
+	sp   storage.Provider
+	tree *trillian.Tree
+	next int
+}
+
+func newTestLog(ctx context.Context, t *testing.T, sp storage.Provider, treeType trillian.TreeType) *testLog {
+	t.Helper()
+	tmpl := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	tmpl.TreeType = treeType
+	tree, err := storage.CreateTree(ctx, sp.AdminStorage(), tmpl)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	logRoot, err := (&types.LogRootV1{RootHash: rfc6962.DefaultHasher.EmptyRoot(), TimestampNanos: uint64(time.Now().UnixNano())}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary(): %v", err)
+	}
+	if err := sp.LogStorage().ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: logRoot})
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(init): %v", err)
+	}
+	return &testLog{t: t, sp: sp, tree: tree}
+}
+
+// add adds n leaves to the log, without integrating them.
+func (l *testLog) add(ctx context.Context, n int) {
+	l.t.Helper()
+	leaves := make([]*trillian.LogLeaf, 0, n)
+	for i := l.next; i < l.next+n; i++ {
+		data := []byte(fmt.Sprintf("leaf %d", i))
+		id := sha256.Sum256(data)
+		leaves = append(leaves, &trillian.LogLeaf{
+			LeafIndex:        int64(i),
+			LeafValue:        data,
+			LeafIdentityHash: id[:],
+			MerkleLeafHash:   rfc6962.DefaultHasher.HashLeaf(data),
+		})
+	}
+	l.next += n
+	var err error
+	if l.tree.TreeType == trillian.TreeType_PREORDERED_LOG {
+		_, err = l.sp.LogStorage().AddSequencedLeaves(ctx, l.tree, leaves, time.Now())
+	} else {
+		_, err = l.sp.LogStorage().QueueLeaves(ctx, l.tree, leaves, time.Now())
+	}
+	if err != nil {
+		l.t.Fatalf("Adding leaves: %v", err)
+	}
+}
+
+// integrate runs the log sequencer until all the added leaves are
+// integrated.
+func (l *testLog) integrate(ctx context.Context) {
+	l.t.Helper()
+	for {
+		n, err := log.IntegrateBatch(ctx, l.tree, 3, 0, 0, clock.System, l.sp.LogStorage(), quota.Noop())
+		if err != nil {
+			l.t.Fatalf("IntegrateBatch(): %v", err)
+		}
+		if n == 0 {
+			return
+		}
+	}
+}
+
+// readAll returns the latest root and all the leaves of a tree.
+func readAll(ctx context.Context, t *testing.T, sp storage.Provider, treeID int64) (*types.LogRootV1, []*trillian.LogLeaf) {
+	t.Helper()
+	tree, err := storage.GetTree(ctx, sp.AdminStorage(), treeID)
+	if err != nil {
+		t.Fatalf("GetTree(): %v", err)
+	}
+	tx, err := sp.LogStorage().SnapshotForTree(ctx, tree)
+	if err != nil {
+		t.Fatalf("SnapshotForTree(): %v", err)
+	}
+	defer tx.Close()
+	slr, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil {
+		t.Fatalf("LatestSignedLogRoot(): %v", err)
+	}
+	var root types.LogRootV1
+	if err := root.UnmarshalBinary(slr.LogRoot); err != nil {
+		t.Fatalf("UnmarshalBinary(): %v", err)
+	}
+	var leaves []*trillian.LogLeaf
+	if root.TreeSize > 0 {
+		if leaves, err = tx.GetLeavesByRange(ctx, 0, int64(root.TreeSize)); err != nil {
+			t.Fatalf("GetLeavesByRange(): %v", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit(): %v", err)
+	}
+	return &root, leaves
+}
+
+// listRoots returns all the log roots of a tree.
+func listRoots(ctx context.Context, t *testing.T, sp storage.Provider, treeID int64) []*trillian.SignedLogRoot {
+	t.Helper()
+	tree, err := storage.GetTree(ctx, sp.AdminStorage(), treeID)
+	if err != nil {
+		t.Fatalf("GetTree(): %v", err)
+	}
+	slrs, err := storage.ListSignedLogRoots(ctx, sp.LogStorage(), tree, 0, 1000)
+	if err != nil {
+		t.Fatalf("ListSignedLogRoots(): %v", err)
+	}
+	return slrs
+}
+
+func checkCopy(ctx context.Context, t *testing.T, src, dst storage.Provider, treeID int64) {
+	t.Helper()
+	if err := Compare(ctx, src, dst, treeID); err != nil {
+		t.Errorf("Compare(): %v", err)
+	}
+	// All the log roots are copied verbatim.
+	if diff := cmp.Diff(listRoots(ctx, t, src, treeID), listRoots(ctx, t, dst, treeID), protocmp.Transform()); diff != "" {
+		t.Errorf("Log roots diff (-source +destination):\n%s", diff)
+	}
+	srcRoot, srcLeaves := readAll(ctx, t, src, treeID)
+	dstRoot, dstLeaves := readAll(ctx, t, dst, treeID)
+	if diff := cmp.Diff(srcRoot, dstRoot); diff != "" {
+		t.Errorf("Root diff (-source +destination):\n%s", diff)
+	}
+	if diff := cmp.Diff(srcLeaves, dstLeaves, protocmp.Transform(),
+		protocmp.IgnoreFields(&trillian.LogLeaf{}, "queue_timestamp")); diff != "" {
+		t.Errorf("Leaves diff (-source +destination):\n%s", diff)
+	}
+}
+
+func treeState(ctx context.Context, t *testing.T, sp storage.Provider, treeID int64) trillian.TreeState {
+	t.Helper()
+	tree, err := storage.GetTree(ctx, sp.AdminStorage(), treeID)
+	if err != nil {
+		t.Fatalf("GetTree(): %v", err)
+	}
+	return tree.TreeState
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		desc     string
+		src, dst func(*testing.T) storage.Provider
+		treeType trillian.TreeType
+	}{
+		{desc: "memory-to-bolt", src: newMemoryProvider, dst: newBoltProvider, treeType: trillian.TreeType_LOG},
+		{desc: "bolt-to-memory", src: newBoltProvider, dst: newMemoryProvider, treeType: trillian.TreeType_LOG},
+		{desc: "preordered", src: newBoltProvider, dst: newBoltProvider, treeType: trillian.TreeType_PREORDERED_LOG},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			src, dst := tc.src(t), tc.dst(t)
+			l := newTestLog(ctx, t, src, tc.treeType)
+			treeID := l.tree.TreeId
+			opts := Options{BatchSize: 4, PollInterval: 10 * time.Millisecond}
+
+			l.add(ctx, 10)
+			l.integrate(ctx)
+			root, err := CatchUp(ctx, src, dst, treeID, opts)
+			if err != nil {
+				t.Fatalf("CatchUp(): %v", err)
+			}
+			if got, want := root.TreeSize, uint64(10); got != want {
+				t.Errorf("CatchUp(): TreeSize = %d, want %d", got, want)
+			}
+			if got, want := treeState(ctx, t, dst, treeID), trillian.TreeState_FROZEN; got != want {
+				t.Errorf("Destination TreeState = %v, want %v", got, want)
+			}
+			checkCopy(ctx, t, src, dst, treeID)
+
+			// Catch up with leaves added since, and with nothing new.
+			l.add(ctx, 7)
+			l.integrate(ctx)
+			for i := 0; i < 2; i++ {
+				if _, err := CatchUp(ctx, src, dst, treeID, opts); err != nil {
+					t.Fatalf("CatchUp(): %v", err)
+				}
+				checkCopy(ctx, t, src, dst, treeID)
+			}
+
+			// Cut over with leaves still waiting to be integrated, which
+			// the log signer does in the background.
+			l.add(ctx, 5)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				root, err = Cutover(ctx, src, dst, treeID, opts)
+			}()
+			for integrating := true; integrating; {
+				select {
+				case <-done:
+					integrating = false
+				case <-time.After(time.Millisecond):
+					if _, err := log.IntegrateBatch(ctx, l.tree, 3, 0, 0, clock.System, src.LogStorage(), quota.Noop()); err != nil {
+						t.Fatalf("IntegrateBatch(): %v", err)
+					}
+				}
+			}
+			if err != nil {
+				t.Fatalf("Cutover(): %v", err)
+			}
+			if got, want := root.TreeSize, uint64(22); got != want {
+				t.Errorf("Cutover(): TreeSize = %d, want %d", got, want)
+			}
+			if got, want := treeState(ctx, t, src, treeID), trillian.TreeState_FROZEN; got != want {
+				t.Errorf("Source TreeState = %v, want %v", got, want)
+			}
+			if got, want := treeState(ctx, t, dst, treeID), trillian.TreeState_ACTIVE; got != want {
+				t.Errorf("Destination TreeState = %v, want %v", got, want)
+			}
+			checkCopy(ctx, t, src, dst, treeID)
+		})
+	}
+}
+
+// noRootListing hides the LogRootLister implementation of a LogStorage.
+type noRootListing struct {
+	storage.LogStorage
+}
+
+func TestCatchUpWithoutRootListing(t *testing.T) {
+	ctx := context.Background()
+	mem := newMemoryProvider(t)
+	src := testProvider{as: mem.AdminStorage(), ls: noRootListing{mem.LogStorage()}}
+	l := newTestLog(ctx, t, src, trillian.TreeType_LOG)
+	if _, err := CatchUp(ctx, src, newBoltProvider(t), l.tree.TreeId, Options{}); err == nil {
+		t.Error("CatchUp() succeeded, want error")
+	}
+}
+
+func TestCatchUpWithoutEmptyRoot(t *testing.T) {
+	ctx := context.Background()
+	src, dst := newMemoryProvider(t), newBoltProvider(t)
+	tree, err := storage.CreateTree(ctx, src.AdminStorage(), testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	// The oldest log root of the source tree isn't empty, so the destination
+	// tree can't be initialized with it.
+	logRoot, err := (&types.LogRootV1{TreeSize: 1, RootHash: rfc6962.DefaultHasher.HashLeaf(nil), TimestampNanos: 1}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary(): %v", err)
+	}
+	if err := src.LogStorage().ReadWriteTransaction(ctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: logRoot})
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(): %v", err)
+	}
+	if _, err := CatchUp(ctx, src, dst, tree.TreeId, Options{}); err == nil {
+		t.Error("CatchUp() succeeded, want error")
+	}
+}
+
+func TestCatchUpUninitialized(t *testing.T) {
+	ctx := context.Background()
+	src, dst := newMemoryProvider(t), newBoltProvider(t)
+	tree, err := storage.CreateTree(ctx, src.AdminStorage(), testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	root, err := CatchUp(ctx, src, dst, tree.TreeId, Options{})
+	if err != nil {
+		t.Fatalf("CatchUp(): %v", err)
+	}
+	if root != nil {
+		t.Errorf("CatchUp(): root = %+v, want nil", root)
+	}
+	if err := Compare(ctx, src, dst, tree.TreeId); err != nil {
+		t.Errorf("Compare(): %v", err)
+	}
+}
+
+func TestCatchUpTypeMismatch(t *testing.T) {
+	ctx := context.Background()
+	src, dst := newMemoryProvider(t), newBoltProvider(t)
+	l := newTestLog(ctx, t, src, trillian.TreeType_LOG)
+
+	// A different tree with the same ID exists in the destination.
+	other := proto.Clone(testonly.PreorderedLogTree).(*trillian.Tree)
+	if _, err := storage.CreateTreeWithID(ctx, dst.AdminStorage(), other, l.tree.TreeId); err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	if _, err := CatchUp(ctx, src, dst, l.tree.TreeId, Options{}); err == nil {
+		t.Error("CatchUp() succeeded, want error")
+	}
+}
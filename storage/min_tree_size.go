@@ -0,0 +1,66 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+type minTreeSizeKey struct{}
+
+// WithMinTreeSize returns a ctx which tells LogStorage.SnapshotForTree that
+// the read needs a log root covering at least the given tree size, e.g. the
+// one a client asked for an inclusion proof at. Implementations which serve
+// snapshots from replicas that may lag behind use it to decide when to read
+// from the primary instead.
+func WithMinTreeSize(ctx context.Context, size uint64) context.Context {
+	return context.WithValue(ctx, minTreeSizeKey{}, size)
+}
+
+// MinTreeSize returns the tree size set in ctx with WithMinTreeSize, or 0.
+func MinTreeSize(ctx context.Context) uint64 {
+	size, _ := ctx.Value(minTreeSizeKey{}).(uint64)
+	return size
+}
+
+// TreeSizeWatermark records the largest log root size seen for each tree.
+// Storage implementations which serve snapshots from replicas that may lag
+// behind use it to make sure the roots they return never go backwards, e.g.
+// when a replica serves a read after the primary has served a newer root.
+// The zero value is ready to use, and it is safe for concurrent use.
+type TreeSizeWatermark struct {
+	mu    sync.Mutex
+	sizes map[int64]uint64
+}
+
+// Get returns the largest tree size recorded for the tree, or 0.
+func (w *TreeSizeWatermark) Get(treeID int64) uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sizes[treeID]
+}
+
+// Observe records a log root size seen for the tree.
+func (w *TreeSizeWatermark) Observe(treeID int64, size uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.sizes == nil {
+		w.sizes = make(map[int64]uint64)
+	}
+	if size > w.sizes[treeID] {
+		w.sizes[treeID] = size
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMinTreeSize(t *testing.T) {
+	ctx := context.Background()
+	if got := MinTreeSize(ctx); got != 0 {
+		t.Errorf("MinTreeSize() = %d, want 0", got)
+	}
+	if got := MinTreeSize(WithMinTreeSize(ctx, 42)); got != 42 {
+		t.Errorf("MinTreeSize(WithMinTreeSize(42)) = %d, want 42", got)
+	}
+}
+
+func TestTreeSizeWatermark(t *testing.T) {
+	var w TreeSizeWatermark
+	if got := w.Get(1); got != 0 {
+		t.Errorf("Get(1) = %d, want 0", got)
+	}
+	w.Observe(1, 10)
+	w.Observe(1, 5)
+	w.Observe(2, 3)
+	if got := w.Get(1); got != 10 {
+		t.Errorf("Get(1) = %d, want 10", got)
+	}
+	if got := w.Get(2); got != 3 {
+		t.Errorf("Get(2) = %d, want 3", got)
+	}
+}
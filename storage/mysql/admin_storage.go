@@ -23,6 +23,7 @@ import (
 
 	"github.com/google/trillian"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/compression"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
@@ -30,9 +31,8 @@ import (
 )
 
 const (
-	defaultSequenceIntervalSeconds = 60
-
-	nonDeletedWhere = " WHERE (Deleted IS NULL OR Deleted = 'false')"
+	nonDeletedCond  = "(Deleted IS NULL OR Deleted = 'false')"
+	nonDeletedWhere = " WHERE " + nonDeletedCond
 
 	selectTrees = `
 		SELECT
@@ -46,18 +46,21 @@ const (
 			Description,
 			CreateTimeMillis,
 			UpdateTimeMillis,
-			PrivateKey,
 			PublicKey,
 			MaxRootDurationMillis,
 			Deleted,
-			DeleteTimeMillis
+			DeleteTimeMillis,
+			StorageSettings
 		FROM Trees`
 	selectNonDeletedTrees = selectTrees + nonDeletedWhere
 	selectTreeByID        = selectTrees + " WHERE TreeId = ?"
 
 	updateTreeSQL = `UPDATE Trees
-		SET TreeState = ?, TreeType = ?, DisplayName = ?, Description = ?, UpdateTimeMillis = ?, MaxRootDurationMillis = ?, PrivateKey = ?
+		SET TreeState = ?, TreeType = ?, DisplayName = ?, Description = ?, UpdateTimeMillis = ?, MaxRootDurationMillis = ?, StorageSettings = ?
 		WHERE TreeId = ?`
+
+	insertAuditEventSQL = `INSERT INTO AdminAuditEvents(TreeId, EventTimeMillis, Event)
+		VALUES(?, ?, ?)`
 )
 
 // NewAdminStorage returns a MySQL storage.AdminStorage implementation backed by DB.
@@ -142,6 +145,9 @@ func (t *adminTX) GetTree(ctx context.Context, treeID int64) (*trillian.Tree, er
 	case err != nil:
 		return nil, fmt.Errorf("error reading tree %v: %v", treeID, err)
 	}
+	if err := storage.ReadTreeLabels(ctx, t.tx, []*trillian.Tree{tree}, storage.QuestionMark); err != nil {
+		return nil, fmt.Errorf("error reading labels of tree %v: %v", treeID, err)
+	}
 	return tree, nil
 }
 
@@ -152,13 +158,23 @@ func (t *adminTX) ListTrees(ctx context.Context, includeDeleted bool) ([]*trilli
 	} else {
 		query = selectNonDeletedTrees
 	}
+	return t.readTrees(ctx, query)
+}
 
+// ListTreesFiltered implements storage.TreeLister.
+func (t *adminTX) ListTreesFiltered(ctx context.Context, opts storage.ListTreesOptions) ([]*trillian.Tree, error) {
+	query, args := storage.ListTreesSQL(selectTrees, nonDeletedCond, opts, storage.QuestionMark)
+	return t.readTrees(ctx, query, args...)
+}
+
+// readTrees returns the trees selected by query, with their labels.
+func (t *adminTX) readTrees(ctx context.Context, query string, args ...interface{}) ([]*trillian.Tree, error) {
 	stmt, err := t.tx.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
-	rows, err := stmt.QueryContext(ctx)
+	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -171,20 +187,39 @@ func (t *adminTX) ListTrees(ctx context.Context, includeDeleted bool) ([]*trilli
 		}
 		trees = append(trees, tree)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// The rows must be closed before the labels are read in the same
+	// transaction.
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := storage.ReadTreeLabels(ctx, t.tx, trees, storage.QuestionMark); err != nil {
+		return nil, err
+	}
 	return trees, nil
 }
 
 func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillian.Tree, error) {
+	id, err := storage.NewTreeID()
+	if err != nil {
+		return nil, err
+	}
+	return t.CreateTreeWithID(ctx, tree, id)
+}
+
+// CreateTreeWithID implements storage.TreeCreatorWithID.
+func (t *adminTX) CreateTreeWithID(ctx context.Context, tree *trillian.Tree, id int64) (*trillian.Tree, error) {
 	if err := storage.ValidateTreeForCreation(ctx, tree); err != nil {
 		return nil, err
 	}
-	if err := validateStorageSettings(tree); err != nil {
+	if err := compression.ValidateSettings(tree); err != nil {
 		return nil, err
 	}
-
-	id, err := storage.NewTreeID()
+	storageSettings, err := storage.MarshalStorageSettings(tree)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to marshal storage settings: %w", err)
 	}
 
 	// Use the time truncated-to-millis throughout, as that's what's stored.
@@ -219,9 +254,9 @@ func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillia
 			Description,
 			CreateTimeMillis,
 			UpdateTimeMillis,
-			PrivateKey,
 			PublicKey,
-			MaxRootDurationMillis)
+			MaxRootDurationMillis,
+			StorageSettings)
 		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return nil, err
@@ -241,12 +276,15 @@ func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillia
 		nowMillis,
 		nowMillis,
 		[]byte{}, // Unused, filling in for backward compatibility.
-		[]byte{}, // Unused, filling in for backward compatibility.
 		rootDuration/time.Millisecond,
+		storageSettings,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := storage.WriteTreeLabels(ctx, t.tx, newTree, storage.QuestionMark); err != nil {
+		return nil, err
+	}
 
 	// MySQL silently truncates data when running in non-strict mode.
 	// We shouldn't be using non-strict modes, but let's guard against it
@@ -257,29 +295,6 @@ func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillia
 		return nil, fmt.Errorf("enum truncated: %v", err)
 	}
 
-	insertControlStmt, err := t.tx.PrepareContext(
-		ctx,
-		`INSERT INTO TreeControl(
-			TreeId,
-			SigningEnabled,
-			SequencingEnabled,
-			SequenceIntervalSeconds)
-		VALUES(?, ?, ?, ?)`)
-	if err != nil {
-		return nil, err
-	}
-	defer insertControlStmt.Close()
-	_, err = insertControlStmt.ExecContext(
-		ctx,
-		newTree.TreeId,
-		true, /* SigningEnabled */
-		true, /* SequencingEnabled */
-		defaultSequenceIntervalSeconds,
-	)
-	if err != nil {
-		return nil, err
-	}
-
 	return newTree, nil
 }
 
@@ -294,7 +309,7 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 	if err := storage.ValidateTreeForUpdate(ctx, beforeUpdate, tree); err != nil {
 		return nil, err
 	}
-	if err := validateStorageSettings(tree); err != nil {
+	if err := compression.ValidateSettings(tree); err != nil {
 		return nil, err
 	}
 
@@ -312,6 +327,10 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 		return nil, fmt.Errorf("could not parse MaxRootDuration: %w", err)
 	}
 	rootDuration := tree.MaxRootDuration.AsDuration()
+	storageSettings, err := storage.MarshalStorageSettings(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal storage settings: %w", err)
+	}
 
 	stmt, err := t.tx.PrepareContext(ctx, updateTreeSQL)
 	if err != nil {
@@ -327,10 +346,13 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 		tree.Description,
 		nowMillis,
 		rootDuration/time.Millisecond,
-		[]byte{}, // Unused, filling in for backward compatibility.
+		storageSettings,
 		tree.TreeId); err != nil {
 		return nil, err
 	}
+	if err := storage.WriteTreeLabels(ctx, t.tx, tree, storage.QuestionMark); err != nil {
+		return nil, err
+	}
 
 	return tree, nil
 }
@@ -363,14 +385,33 @@ func (t *adminTX) HardDeleteTree(ctx context.Context, treeID int64) error {
 		return err
 	}
 
-	// TreeControl didn't have "ON DELETE CASCADE" on previous versions, so let's hit it explicitly
-	if _, err := t.tx.ExecContext(ctx, "DELETE FROM TreeControl WHERE TreeId = ?", treeID); err != nil {
-		return err
-	}
 	_, err := t.tx.ExecContext(ctx, "DELETE FROM Trees WHERE TreeId = ?", treeID)
 	return err
 }
 
+// ListAuditEvents implements storage.AuditLogReader.
+func (t *adminTX) ListAuditEvents(ctx context.Context, opts storage.ListAuditEventsOptions) ([]*trillian.AdminAuditEvent, error) {
+	return storage.QueryAuditEvents(ctx, t.tx, opts, storage.QuestionMark)
+}
+
+// AddAuditEvent implements storage.AuditLogWriter.
+func (t *adminTX) AddAuditEvent(ctx context.Context, event *trillian.AdminAuditEvent) error {
+	treeID, eventTimeMillis, data, err := storage.AuditEventRow(event)
+	if err != nil {
+		return err
+	}
+	res, err := t.tx.ExecContext(ctx, insertAuditEventSQL, treeID, eventTimeMillis, data)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	event.EventId = id
+	return nil
+}
+
 func validateDeleted(ctx context.Context, tx *sql.Tx, treeID int64, wantDeleted bool) error {
 	var nullDeleted sql.NullBool
 	switch err := tx.QueryRowContext(ctx, "SELECT Deleted FROM Trees WHERE TreeId = ?", treeID).Scan(&nullDeleted); {
@@ -388,10 +429,3 @@ func validateDeleted(ctx context.Context, tx *sql.Tx, treeID int64, wantDeleted
 	}
 	return nil
 }
-
-func validateStorageSettings(tree *trillian.Tree) error {
-	if tree.StorageSettings != nil {
-		return fmt.Errorf("storage_settings not supported, but got %v", tree.StorageSettings)
-	}
-	return nil
-}
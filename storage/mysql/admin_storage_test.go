@@ -27,8 +27,6 @@ import (
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
-const selectTreeControlByID = "SELECT SigningEnabled, SequencingEnabled, SequenceIntervalSeconds FROM TreeControl WHERE TreeId = ?"
-
 func TestMysqlAdminStorage(t *testing.T) {
 	tester := &testonly.AdminStorageTester{NewAdminStorage: func() storage.AdminStorage {
 		cleanTestDB(DB)
@@ -37,29 +35,6 @@ func TestMysqlAdminStorage(t *testing.T) {
 	tester.RunAllTests(t)
 }
 
-func TestAdminTX_CreateTree_InitializesStorageStructures(t *testing.T) {
-	cleanTestDB(DB)
-	s := NewAdminStorage(DB)
-	ctx := context.Background()
-
-	tree, err := storage.CreateTree(ctx, s, testonly.LogTree)
-	if err != nil {
-		t.Fatalf("CreateTree() failed: %v", err)
-	}
-
-	// Check if TreeControl is correctly written.
-	var signingEnabled, sequencingEnabled bool
-	var sequenceIntervalSeconds int
-	if err := DB.QueryRowContext(ctx, selectTreeControlByID, tree.TreeId).Scan(&signingEnabled, &sequencingEnabled, &sequenceIntervalSeconds); err != nil {
-		t.Fatalf("Failed to read TreeControl: %v", err)
-	}
-	// We don't mind about specific values, defaults change, but let's check
-	// that important numbers are not zeroed.
-	if sequenceIntervalSeconds <= 0 {
-		t.Errorf("sequenceIntervalSeconds = %v, want > 0", sequenceIntervalSeconds)
-	}
-}
-
 func TestCreateTreeInvalidStates(t *testing.T) {
 	cleanTestDB(DB)
 	s := NewAdminStorage(DB)
@@ -140,8 +115,9 @@ func TestAdminTX_StorageSettingsNotSupported(t *testing.T) {
 
 	tests := []struct {
 		desc string
-		// fn attempts to either create or update a tree with a non-nil, valid Any proto
-		// on Tree.StorageSettings. It's expected to return an error.
+		// fn attempts to either create or update a tree with a valid Any proto
+		// on Tree.StorageSettings, which doesn't hold LogStorageSettings. It's
+		// expected to return an error.
 		fn func(storage.AdminStorage) error
 	}{
 		{
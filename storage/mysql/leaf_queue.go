@@ -0,0 +1,186 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage/compression"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// queueLeavesExternal queues the leaves in the external queue of the storage
+// rather than in the Unsequenced table. Their LeafData is only written when
+// they are integrated, by updateExternalLeaves.
+func (t *logTreeTX) queueLeavesExternal(ctx context.Context, leaves []*trillian.LogLeaf) ([]*trillian.LogLeaf, error) {
+	label := labelForTX(t)
+	// Leaves which are in the LeafData table already are duplicates.
+	stored, err := t.storedLeafData(ctx, leaves)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve existing leaves: %v", err)
+	}
+	existing := make([]*trillian.LogLeaf, len(leaves))
+	toQueue := make([]*trillian.LogLeaf, 0, len(leaves))
+	positions := make([]int, 0, len(leaves))
+	for i, leaf := range leaves {
+		if s, ok := stored[string(leaf.LeafIdentityHash)]; ok {
+			existing[i] = s
+			continue
+		}
+		toQueue = append(toQueue, leaf)
+		positions = append(positions, i)
+	}
+
+	queued, err := t.ls.queue.Add(ctx, t.treeID, toQueue)
+	if err != nil {
+		klog.Warningf("Error adding leaves to queue: %s", err)
+		return nil, err
+	}
+	for j, q := range queued {
+		existing[positions[j]] = q
+	}
+	for _, e := range existing {
+		if e != nil {
+			queuedDupCounter.Inc(label)
+		}
+	}
+	queuedCounter.Add(float64(len(leaves)), label)
+	return existing, nil
+}
+
+// dequeueExternal returns up to limit leaves from the external queue of the
+// storage which were queued no later than cutoff. The leaves are left in the
+// queue until the transaction integrating them commits.
+func (t *logTreeTX) dequeueExternal(ctx context.Context, limit int, cutoff time.Time) ([]*trillian.LogLeaf, error) {
+	queued, err := t.ls.queue.Peek(ctx, t.treeID, limit, cutoff)
+	if err != nil {
+		klog.Warningf("Failed to peek at queue: %s", err)
+		return nil, err
+	}
+	// Leaves in the LeafData table were integrated by a transaction which
+	// failed to remove them from the queue, or are still in the Unsequenced
+	// table.
+	stored, err := t.storedLeafData(ctx, queued)
+	if err != nil {
+		return nil, err
+	}
+
+	var stale [][]byte
+	leaves := make([]*trillian.LogLeaf, 0, len(queued))
+	for _, leaf := range queued {
+		if len(leaf.LeafIdentityHash) != t.hashSizeBytes {
+			return nil, errors.New("dequeued a leaf with incorrect hash size")
+		}
+		k := string(leaf.LeafIdentityHash)
+		if _, ok := stored[k]; ok {
+			stale = append(stale, leaf.LeafIdentityHash)
+			continue
+		}
+		if _, ok := t.dequeued[k]; ok {
+			continue
+		}
+		if t.dequeuedExternal[k] {
+			// dupe, user probably called DequeueLeaves more than once.
+			continue
+		}
+		t.dequeuedExternal[k] = true
+		leaves = append(leaves, leaf)
+	}
+	if len(stale) > 0 {
+		if err := t.ls.queue.Remove(ctx, t.treeID, stale); err != nil {
+			klog.Warningf("Failed to remove %d stored leaves from queue: %s", len(stale), err)
+		}
+	}
+	return leaves, nil
+}
+
+// updateExternalLeaves writes the LeafData and SequencedLeafData of the
+// leaves which were dequeued from the external queue, and returns the others.
+// The written leaves are removed from the queue when the transaction commits.
+func (t *logTreeTX) updateExternalLeaves(ctx context.Context, leaves []*trillian.LogLeaf) ([]*trillian.LogLeaf, error) {
+	if len(t.dequeuedExternal) == 0 {
+		return leaves, nil
+	}
+	rest := make([]*trillian.LogLeaf, 0, len(leaves))
+	for _, leaf := range leaves {
+		if !t.dequeuedExternal[string(leaf.LeafIdentityHash)] {
+			rest = append(rest, leaf)
+			continue
+		}
+		if err := leaf.QueueTimestamp.CheckValid(); err != nil {
+			return nil, fmt.Errorf("got invalid queue timestamp: %w", err)
+		}
+		if err := leaf.IntegrateTimestamp.CheckValid(); err != nil {
+			return nil, fmt.Errorf("got invalid integrate timestamp: %w", err)
+		}
+		value, extra, err := compression.EncodeLeaf(t.treeID, t.leafCompression, leaf)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to compress leaf: %v", err)
+		}
+		if _, err := t.tx.ExecContext(ctx, insertLeafDataSQL, t.treeID, leaf.LeafIdentityHash, value, extra, leaf.QueueTimestamp.AsTime().UnixNano()); err != nil {
+			klog.Warningf("Failed to insert leaf data: %s", err)
+			return nil, mysqlToGRPC(err)
+		}
+		if _, err := t.tx.ExecContext(ctx, insertSequencedLeafSQL+valuesPlaceholder5,
+			t.treeID, leaf.LeafIdentityHash, leaf.MerkleLeafHash, leaf.LeafIndex, leaf.IntegrateTimestamp.AsTime().UnixNano()); err != nil {
+			klog.Warningf("Failed to update sequenced leaves: %s", err)
+			return nil, err
+		}
+		t.integrated = append(t.integrated, leaf.LeafIdentityHash)
+	}
+	return rest, nil
+}
+
+// storedLeafData returns the leaves in the LeafData table with the same
+// LeafIdentityHash as any of the given leaves, keyed by the hash.
+func (t *logTreeTX) storedLeafData(ctx context.Context, leaves []*trillian.LogLeaf) (map[string]*trillian.LogLeaf, error) {
+	stored := make(map[string]*trillian.LogLeaf)
+	if len(leaves) == 0 {
+		return stored, nil
+	}
+	hashes := make([][]byte, 0, len(leaves))
+	for _, leaf := range leaves {
+		hashes = append(hashes, leaf.LeafIdentityHash)
+	}
+	results, err := t.getLeafDataByIdentityHash(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+	for _, leaf := range results {
+		stored[string(leaf.LeafIdentityHash)] = leaf
+	}
+	return stored, nil
+}
+
+// Commit commits the transaction, and then removes the leaves it integrated
+// from the external queue, if any. Leaves which fail to be removed are
+// removed when they are next dequeued instead.
+func (t *logTreeTX) Commit(ctx context.Context) error {
+	if err := t.treeTX.Commit(ctx); err != nil {
+		return err
+	}
+	if len(t.integrated) > 0 {
+		if err := t.ls.queue.Remove(ctx, t.treeID, t.integrated); err != nil {
+			klog.Warningf("Failed to remove %d integrated leaves from queue: %s", len(t.integrated), err)
+		}
+	}
+	return nil
+}
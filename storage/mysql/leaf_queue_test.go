@@ -0,0 +1,255 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/integration/storagetest"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/types"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeQueue is an in-memory storage.LeafQueue.
+type fakeQueue struct {
+	mu     sync.Mutex
+	leaves map[int64][]*trillian.LogLeaf
+}
+
+func newFakeQueue() *fakeQueue {
+	return &fakeQueue{leaves: make(map[int64][]*trillian.LogLeaf)}
+}
+
+func (q *fakeQueue) find(treeID int64, hash []byte) int {
+	for i, l := range q.leaves[treeID] {
+		if bytes.Equal(l.LeafIdentityHash, hash) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *fakeQueue) Add(_ context.Context, treeID int64, leaves []*trillian.LogLeaf) ([]*trillian.LogLeaf, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	existing := make([]*trillian.LogLeaf, len(leaves))
+	for i, leaf := range leaves {
+		if j := q.find(treeID, leaf.LeafIdentityHash); j >= 0 {
+			existing[i] = proto.Clone(q.leaves[treeID][j]).(*trillian.LogLeaf)
+			continue
+		}
+		q.leaves[treeID] = append(q.leaves[treeID], proto.Clone(leaf).(*trillian.LogLeaf))
+	}
+	return existing, nil
+}
+
+func (q *fakeQueue) Peek(_ context.Context, treeID int64, limit int, cutoff time.Time) ([]*trillian.LogLeaf, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var leaves []*trillian.LogLeaf
+	for _, l := range q.leaves[treeID] {
+		if len(leaves) == limit {
+			break
+		}
+		if !l.QueueTimestamp.AsTime().After(cutoff) {
+			leaves = append(leaves, proto.Clone(l).(*trillian.LogLeaf))
+		}
+	}
+	return leaves, nil
+}
+
+func (q *fakeQueue) Remove(_ context.Context, treeID int64, hashes [][]byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, h := range hashes {
+		if i := q.find(treeID, h); i >= 0 {
+			q.leaves[treeID] = append(q.leaves[treeID][:i], q.leaves[treeID][i+1:]...)
+		}
+	}
+	return nil
+}
+
+func (q *fakeQueue) len(treeID int64) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.leaves[treeID])
+}
+
+func (q *fakeQueue) Len(_ context.Context, treeID int64) (int64, error) {
+	return int64(q.len(treeID)), nil
+}
+
+func TestLogSuiteWithQueue(t *testing.T) {
+	storageFactory := func(context.Context, *testing.T) (storage.LogStorage, storage.AdminStorage) {
+		t.Cleanup(func() { cleanTestDB(DB) })
+		return NewLogStorageWithQueue(DB, newFakeQueue(), nil), NewAdminStorage(DB)
+	}
+
+	storagetest.RunLogStorageTests(t, storageFactory)
+}
+
+// integrateQueued sequences up to limit queued leaves after the first size
+// leaves of the tree, and returns them.
+func integrateQueued(ctx context.Context, t *testing.T, s storage.LogStorage, tree *trillian.Tree, size int64, limit int) []*trillian.LogLeaf {
+	t.Helper()
+	var leaves []*trillian.LogLeaf
+	runLogTX(s, tree, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		var err error
+		if leaves, err = tx.DequeueLeaves(ctx, limit, fakeDequeueCutoffTime); err != nil {
+			return err
+		}
+		for i, leaf := range leaves {
+			leaf.LeafIndex = size + int64(i)
+			leaf.IntegrateTimestamp = timestamppb.New(fakeIntegrateTime)
+		}
+		if err := tx.UpdateSequencedLeaves(ctx, leaves); err != nil {
+			return err
+		}
+		newSize := uint64(size) + uint64(len(leaves))
+		logRoot, err := (&types.LogRootV1{TreeSize: newSize, RootHash: []byte{0}, TimestampNanos: uint64(time.Now().UnixNano())}).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: logRoot})
+	})
+	return leaves
+}
+
+func TestLeafQueue(t *testing.T) {
+	ctx := context.Background()
+	cleanTestDB(DB)
+	as := NewAdminStorage(DB)
+	tree := mustCreateTree(ctx, t, as, testonly.LogTree)
+	q := newFakeQueue()
+	s := NewLogStorageWithQueue(DB, q, nil)
+	mustSignAndStoreLogRoot(ctx, t, s, tree, 0)
+
+	// Leaves queued before the queue was used are integrated first.
+	sqlLeaves := createTestLeaves(2, 0)
+	if _, err := NewLogStorage(DB, nil).QueueLeaves(ctx, tree, sqlLeaves, fakeQueueTime); err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+	leaves := createTestLeaves(5, 2)
+	queued, err := s.QueueLeaves(ctx, tree, append(leaves, sqlLeaves[0], leaves[0]), fakeQueueTime)
+	if err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+	for i, want := range []bool{false, false, false, false, false, true, true} {
+		if got := queued[i].Status != nil; got != want {
+			t.Errorf("QueueLeaves()[%d] duplicate: %v, want %v", i, got, want)
+		}
+	}
+	stats, err := s.(storage.TreeStatsReader).GetTreeStats(ctx, tree)
+	if err != nil {
+		t.Fatalf("GetTreeStats(): %v", err)
+	}
+	if got, want := stats.QueueDepth, int64(len(sqlLeaves)+len(leaves)); got != want {
+		t.Errorf("GetTreeStats() queue depth %d, want %d", got, want)
+	}
+	if got, want := stats.OldestQueued, fakeQueueTime; !got.Equal(want) {
+		t.Errorf("GetTreeStats() oldest queued %v, want %v", got, want)
+	}
+	if got, want := q.len(tree.TreeId), len(leaves); got != want {
+		t.Errorf("Queue has %d leaves, want %d", got, want)
+	}
+	var count int
+	if err := DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM LeafData WHERE TreeID=?", tree.TreeId).Scan(&count); err != nil {
+		t.Fatalf("Could not query row count: %v", err)
+	}
+	if want := len(sqlLeaves); count != want {
+		t.Errorf("Got %d LeafData rows, want %d", count, want)
+	}
+
+	got := integrateQueued(ctx, t, s, tree, 0, 4)
+	if !sameIdentityHashes(got[:2], sqlLeaves) || !sameIdentityHashes(got[2:], leaves[:2]) {
+		t.Errorf("DequeueLeaves() = %v, want %v then %v", got, sqlLeaves, leaves[:2])
+	}
+	got = integrateQueued(ctx, t, s, tree, 4, 10)
+	if want := leaves[2:]; !sameIdentityHashes(got, want) {
+		t.Errorf("DequeueLeaves() = %v, want %v", got, want)
+	}
+	if got := q.len(tree.TreeId); got != 0 {
+		t.Errorf("Queue has %d leaves after integration, want 0", got)
+	}
+
+	// The leaves are stored with their data.
+	tx, err := s.SnapshotForTree(ctx, tree)
+	if err != nil {
+		t.Fatalf("SnapshotForTree(): %v", err)
+	}
+	defer tx.Close()
+	stored, err := tx.GetLeavesByRange(ctx, 0, 7)
+	if err != nil {
+		t.Fatalf("GetLeavesByRange(): %v", err)
+	}
+	if len(stored) != 7 {
+		t.Fatalf("GetLeavesByRange() returned %d leaves, want 7", len(stored))
+	}
+	byHash := make(map[string]*trillian.LogLeaf)
+	for _, leaf := range stored {
+		byHash[string(leaf.LeafIdentityHash)] = leaf
+	}
+	for _, leaf := range append(sqlLeaves, leaves...) {
+		if got := byHash[string(leaf.LeafIdentityHash)]; got == nil || !bytes.Equal(got.LeafValue, leaf.LeafValue) || !bytes.Equal(got.ExtraData, leaf.ExtraData) || !got.QueueTimestamp.AsTime().Equal(fakeQueueTime) {
+			t.Errorf("GetLeavesByRange() has %v, want data of %v", got, leaf)
+		}
+	}
+	commit(ctx, tx, t)
+
+	// Integrated leaves are duplicates, and are dropped from the queue if they
+	// end up in it again.
+	queued, err = s.QueueLeaves(ctx, tree, leaves[:1], fakeQueueTime)
+	if err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+	if queued[0].Status == nil || !bytes.Equal(queued[0].Leaf.LeafValue, leaves[0].LeafValue) {
+		t.Errorf("QueueLeaves() = %v, want duplicate of %v", queued[0], leaves[0])
+	}
+	if _, err := q.Add(ctx, tree.TreeId, leaves[:1]); err != nil {
+		t.Fatalf("Add(): %v", err)
+	}
+	if got := integrateQueued(ctx, t, s, tree, 7, 10); len(got) != 0 {
+		t.Errorf("DequeueLeaves() = %v, want none", got)
+	}
+	if got := q.len(tree.TreeId); got != 0 {
+		t.Errorf("Queue has %d leaves, want 0", got)
+	}
+}
+
+// sameIdentityHashes returns whether the leaves have the same identity hashes,
+// in any order.
+func sameIdentityHashes(got, want []*trillian.LogLeaf) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	hashes := make(map[string]bool)
+	for _, leaf := range want {
+		hashes[string(leaf.LeafIdentityHash)] = true
+	}
+	for _, leaf := range got {
+		if !hashes[string(leaf.LeafIdentityHash)] {
+			return false
+		}
+	}
+	return true
+}
@@ -29,6 +29,8 @@ import (
 	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/storage/compression"
+	"github.com/google/trillian/storage/storagepb"
 	"github.com/google/trillian/storage/tree"
 	"github.com/google/trillian/types"
 	"github.com/transparency-dev/merkle/compact"
@@ -51,7 +53,7 @@ const (
 		  AND TreeState IN(?,?)
 		  AND (Deleted IS NULL OR Deleted = 'false')`
 
-	selectLatestSignedLogRootSQL = `SELECT TreeHeadTimestamp,TreeSize,RootHash,TreeRevision,RootSignature
+	selectLatestSignedLogRootSQL = `SELECT TreeHeadTimestamp,TreeSize,RootHash,TreeRevision
 			FROM TreeHead WHERE TreeId=?
 			ORDER BY TreeHeadTimestamp DESC LIMIT 1`
 
@@ -79,6 +81,8 @@ const (
 	selectLeavesByMerkleHashOrderedBySequenceSQL = selectLeavesByMerkleHashSQL + orderBySequenceNumberSQL
 
 	logIDLabel = "logid"
+
+	replicaResultLabel = "result"
 )
 
 var (
@@ -95,6 +99,8 @@ var (
 	dequeueLatency          monitoring.Histogram
 	dequeueSelectLatency    monitoring.Histogram
 	dequeueRemoveLatency    monitoring.Histogram
+
+	replicaSnapshotCounter monitoring.Counter
 )
 
 func createMetrics(mf monitoring.MetricFactory) {
@@ -111,6 +117,8 @@ func createMetrics(mf monitoring.MetricFactory) {
 	dequeueLatency = mf.NewHistogram("mysql_dequeue_leaves_latency", "Latency of dequeue leaves operation in seconds", logIDLabel)
 	dequeueSelectLatency = mf.NewHistogram("mysql_dequeue_leaves_latency_select", "Latency of selection part of dequeue leaves operation in seconds", logIDLabel)
 	dequeueRemoveLatency = mf.NewHistogram("mysql_dequeue_leaves_latency_remove", "Latency of removal part of dequeue leaves operation in seconds", logIDLabel)
+
+	replicaSnapshotCounter = mf.NewCounter("mysql_replica_snapshots", "Number of snapshots attempted on the read replica, by result", logIDLabel, replicaResultLabel)
 }
 
 func labelForTX(t *logTreeTX) string {
@@ -125,6 +133,15 @@ type mySQLLogStorage struct {
 	*mySQLTreeStorage
 	admin         storage.AdminStorage
 	metricFactory monitoring.MetricFactory
+	// replica, if set, serves the snapshots which it is recent enough for.
+	replica *mySQLLogStorage
+	// sizes holds the largest root of each tree returned by SnapshotForTree
+	// when the replica is set, so that a lagging replica doesn't serve an
+	// older root than a previous snapshot.
+	sizes storage.TreeSizeWatermark
+	// queue, if set, holds the leaves waiting to be integrated instead of the
+	// Unsequenced table.
+	queue storage.LeafQueue
 }
 
 // NewLogStorage creates a storage.LogStorage instance for the specified MySQL URL.
@@ -140,6 +157,28 @@ func NewLogStorage(db *sql.DB, mf monitoring.MetricFactory) storage.LogStorage {
 	}
 }
 
+// NewLogStorageWithReplica creates a storage.LogStorage instance which reads
+// snapshots from the replica database, and does everything else in the
+// primary db. Snapshots fall back to the primary if the replica doesn't have
+// the tree yet, or its latest root is smaller than the tree size set with
+// storage.WithMinTreeSize or than the root of a previous snapshot, so the
+// roots served never go backwards.
+func NewLogStorageWithReplica(db, replica *sql.DB, mf monitoring.MetricFactory) storage.LogStorage {
+	ls := NewLogStorage(db, mf).(*mySQLLogStorage)
+	ls.replica = NewLogStorage(replica, mf).(*mySQLLogStorage)
+	return ls
+}
+
+// NewLogStorageWithQueue creates a storage.LogStorage instance which keeps
+// queued leaves in q rather than in the Unsequenced table, and only writes them
+// to the database when they are integrated. Leaves already in the Unsequenced
+// table are still integrated, before the leaves in q.
+func NewLogStorageWithQueue(db *sql.DB, q storage.LeafQueue, mf monitoring.MetricFactory) storage.LogStorage {
+	ls := NewLogStorage(db, mf).(*mySQLLogStorage)
+	ls.queue = q
+	return ls
+}
+
 func (m *mySQLLogStorage) CheckDatabaseAccessible(ctx context.Context) error {
 	return m.db.PingContext(ctx)
 }
@@ -181,7 +220,12 @@ func (m *mySQLLogStorage) GetActiveLogIDs(ctx context.Context) ([]int64, error)
 func (m *mySQLLogStorage) beginInternal(ctx context.Context, tree *trillian.Tree) (*logTreeTX, error) {
 	once.Do(func() {
 		createMetrics(m.metricFactory)
+		compression.InitMetrics(m.metricFactory)
 	})
+	leafCompression, err := compression.Algorithm(tree)
+	if err != nil {
+		return nil, err
+	}
 
 	stCache := cache.NewLogSubtreeCache(rfc6962.DefaultHasher)
 	ttx, err := m.beginTreeTx(ctx, tree, rfc6962.DefaultHasher.Size(), stCache)
@@ -190,9 +234,11 @@ func (m *mySQLLogStorage) beginInternal(ctx context.Context, tree *trillian.Tree
 	}
 
 	ltx := &logTreeTX{
-		treeTX:   ttx,
-		ls:       m,
-		dequeued: make(map[string]dequeuedLeaf),
+		treeTX:           ttx,
+		ls:               m,
+		dequeued:         make(map[string]dequeuedLeaf),
+		dequeuedExternal: make(map[string]bool),
+		leafCompression:  leafCompression,
 	}
 	ltx.slr, ltx.readRev, err = ltx.fetchLatestRoot(ctx)
 	if err == storage.ErrTreeNeedsInit {
@@ -222,6 +268,9 @@ func (m *mySQLLogStorage) ReadWriteTransaction(ctx context.Context, tree *trilli
 		return err
 	}
 	defer tx.Close()
+	if err := storage.CheckMasterFence(ctx, tx.tx, storage.QuestionMark); err != nil {
+		return err
+	}
 	if err := f(ctx, tx); err != nil {
 		return err
 	}
@@ -250,13 +299,53 @@ func (m *mySQLLogStorage) AddSequencedLeaves(ctx context.Context, tree *trillian
 }
 
 func (m *mySQLLogStorage) SnapshotForTree(ctx context.Context, tree *trillian.Tree) (storage.ReadOnlyLogTreeTX, error) {
+	if m.replica != nil {
+		minTreeSize := storage.MinTreeSize(ctx)
+		if size := m.sizes.Get(tree.TreeId); size > minTreeSize {
+			minTreeSize = size
+		}
+		if tx := m.replica.replicaSnapshot(ctx, tree, minTreeSize); tx != nil {
+			m.sizes.Observe(tree.TreeId, tx.root.TreeSize)
+			tx.tiles = cache.DefaultTileCache()
+			return tx, nil
+		}
+	}
 	tx, err := m.beginInternal(ctx, tree)
 	if err != nil && err != storage.ErrTreeNeedsInit {
 		return nil, err
 	}
+	if m.replica != nil && err == nil {
+		m.sizes.Observe(tree.TreeId, tx.root.TreeSize)
+	}
+	tx.tiles = cache.DefaultTileCache()
 	return tx, err
 }
 
+// replicaSnapshot returns a snapshot of the tree from the replica, or nil if
+// the replica can't serve it, or its root is smaller than minTreeSize, and the
+// primary should be used instead.
+func (m *mySQLLogStorage) replicaSnapshot(ctx context.Context, tree *trillian.Tree, minTreeSize uint64) *logTreeTX {
+	label := strconv.FormatInt(tree.TreeId, 10)
+	tx, err := m.beginInternal(ctx, tree)
+	switch {
+	case err == storage.ErrTreeNeedsInit:
+		// The tree may have been initialized on the primary since.
+		tx.Close()
+		replicaSnapshotCounter.Inc(label, "uninitialized")
+		return nil
+	case err != nil:
+		klog.Warningf("%v: failed to read snapshot from replica, using primary: %v", tree.TreeId, err)
+		replicaSnapshotCounter.Inc(label, "error")
+		return nil
+	case tx.root.TreeSize < minTreeSize:
+		tx.Close()
+		replicaSnapshotCounter.Inc(label, "stale")
+		return nil
+	}
+	replicaSnapshotCounter.Inc(label, "served")
+	return tx
+}
+
 func (m *mySQLLogStorage) QueueLeaves(ctx context.Context, tree *trillian.Tree, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
 	tx, err := m.beginInternal(ctx, tree)
 	if tx != nil {
@@ -298,13 +387,28 @@ type logTreeTX struct {
 	readRev  int64
 	slr      *trillian.SignedLogRoot
 	dequeued map[string]dequeuedLeaf
+	// tiles, if set, is the process-wide cache of full tiles, which is
+	// shared by read-only snapshots.
+	tiles *cache.TileCache
+	// dequeuedExternal holds the identity hashes of the leaves dequeued from
+	// the external queue of the storage, and integrated those which were
+	// written by UpdateSequencedLeaves.
+	dequeuedExternal map[string]bool
+	integrated       [][]byte
+	// leafCompression is the algorithm which the leaves of the tree are
+	// compressed with.
+	leafCompression storagepb.LeafCompression
 }
 
 // GetMerkleNodes returns the requested nodes at the read revision.
 func (t *logTreeTX) GetMerkleNodes(ctx context.Context, ids []compact.NodeID) ([]tree.Node, error) {
 	t.treeTX.mu.Lock()
 	defer t.treeTX.mu.Unlock()
-	return t.subtreeCache.GetNodes(ids, t.getSubtreesAtRev(ctx, t.readRev))
+	getSubtrees := t.getSubtreesAtRev(ctx, t.readRev)
+	if t.tiles != nil {
+		getSubtrees = t.tiles.Wrap(t.treeID, t.readRev, getSubtrees)
+	}
+	return t.subtreeCache.GetNodes(ids, getSubtrees)
 }
 
 func (t *logTreeTX) DequeueLeaves(ctx context.Context, limit int, cutoffTime time.Time) ([]*trillian.LogLeaf, error) {
@@ -356,6 +460,13 @@ func (t *logTreeTX) DequeueLeaves(ctx context.Context, limit int, cutoffTime tim
 	if rows.Err() != nil {
 		return nil, rows.Err()
 	}
+	if t.ls.queue != nil && len(leaves) < limit {
+		external, err := t.dequeueExternal(ctx, limit-len(leaves), cutoffTime)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, external...)
+	}
 	label := labelForTX(t)
 	observe(dequeueSelectLatency, time.Since(start), label)
 	observe(dequeueLatency, time.Since(start), label)
@@ -391,6 +502,9 @@ func (t *logTreeTX) QueueLeaves(ctx context.Context, leaves []*trillian.LogLeaf,
 			return nil, fmt.Errorf("got invalid queue timestamp: %w", err)
 		}
 	}
+	if t.ls.queue != nil {
+		return t.queueLeavesExternal(ctx, leaves)
+	}
 	start := time.Now()
 	label := labelForTX(t)
 
@@ -406,7 +520,11 @@ func (t *logTreeTX) QueueLeaves(ctx context.Context, leaves []*trillian.LogLeaf,
 			return nil, fmt.Errorf("got invalid queue timestamp: %w", err)
 		}
 		qTimestamp := leaf.QueueTimestamp.AsTime()
-		_, err := t.tx.ExecContext(ctx, insertLeafDataSQL, t.treeID, leaf.LeafIdentityHash, leaf.LeafValue, leaf.ExtraData, qTimestamp.UnixNano())
+		value, extra, err := compression.EncodeLeaf(t.treeID, t.leafCompression, leaf)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to compress leaves[%d]: %v", i, err)
+		}
+		_, err = t.tx.ExecContext(ctx, insertLeafDataSQL, t.treeID, leaf.LeafIdentityHash, value, extra, qTimestamp.UnixNano())
 		insertDuration := time.Since(leafStart)
 		observe(queueInsertLeafLatency, insertDuration, label)
 		if isDuplicateErr(err) {
@@ -527,8 +645,12 @@ func (t *logTreeTX) AddSequencedLeaves(ctx context.Context, leaves []*trillian.L
 		res[i] = &trillian.QueuedLogLeaf{Status: ok}
 
 		// TODO(pavelkalinnikov): Measure latencies.
-		_, err := t.tx.ExecContext(ctx, insertLeafDataSQL,
-			t.treeID, leaf.LeafIdentityHash, leaf.LeafValue, leaf.ExtraData, timestamp.UnixNano())
+		value, extra, err := compression.EncodeLeaf(t.treeID, t.leafCompression, leaf)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to compress leaves[%d]: %v", i, err)
+		}
+		_, err = t.tx.ExecContext(ctx, insertLeafDataSQL,
+			t.treeID, leaf.LeafIdentityHash, value, extra, timestamp.UnixNano())
 		// TODO(pavelkalinnikov): Detach PREORDERED_LOG integration latency metric.
 
 		// TODO(pavelkalinnikov): Support opting out from duplicates detection.
@@ -624,6 +746,9 @@ func (t *logTreeTX) getLeavesByRangeInternal(ctx context.Context, start, count i
 			}
 			break
 		}
+		if err := compression.DecodeLeaf(leaf); err != nil {
+			return nil, fmt.Errorf("failed to decode leaf %x: %v", leaf.LeafIdentityHash, err)
+		}
 		leaf.QueueTimestamp = timestamppb.New(time.Unix(0, qTimestamp))
 		if err := leaf.QueueTimestamp.CheckValid(); err != nil {
 			return nil, fmt.Errorf("got invalid queue timestamp: %w", err)
@@ -679,10 +804,10 @@ func (t *logTreeTX) LatestSignedLogRoot(ctx context.Context) (*trillian.SignedLo
 // fetchLatestRoot reads the latest root and the revision from the DB.
 func (t *logTreeTX) fetchLatestRoot(ctx context.Context) (*trillian.SignedLogRoot, int64, error) {
 	var timestamp, treeSize, treeRevision int64
-	var rootHash, rootSignatureBytes []byte
+	var rootHash []byte
 	if err := t.tx.QueryRowContext(
 		ctx, selectLatestSignedLogRootSQL, t.treeID).Scan(
-		&timestamp, &treeSize, &rootHash, &treeRevision, &rootSignatureBytes,
+		&timestamp, &treeSize, &rootHash, &treeRevision,
 	); err == sql.ErrNoRows {
 		// It's possible there are no roots for this tree yet
 		return nil, 0, storage.ErrTreeNeedsInit
@@ -721,8 +846,7 @@ func (t *logTreeTX) StoreSignedLogRoot(ctx context.Context, root *trillian.Signe
 		logRoot.TimestampNanos,
 		logRoot.TreeSize,
 		logRoot.RootHash,
-		t.treeTX.writeRevision,
-		[]byte{})
+		t.treeTX.writeRevision)
 	if err != nil {
 		klog.Warningf("Failed to store signed root: %s", err)
 	}
@@ -762,6 +886,9 @@ func (t *logTreeTX) getLeavesByHashInternal(ctx context.Context, leafHashes [][]
 			klog.Warningf("LogID: %d Scan() %s = %s", t.treeID, desc, err)
 			return nil, err
 		}
+		if err := compression.DecodeLeaf(leaf); err != nil {
+			return nil, fmt.Errorf("failed to decode leaf %x: %v", leaf.LeafIdentityHash, err)
+		}
 		leaf.QueueTimestamp = timestamppb.New(time.Unix(0, queueTS))
 		if err := leaf.QueueTimestamp.CheckValid(); err != nil {
 			return nil, fmt.Errorf("got invalid queue timestamp: %w", err)
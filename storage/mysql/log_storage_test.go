@@ -21,6 +21,7 @@ import (
 	"database/sql"
 	"fmt"
 	"sort"
+	"strconv"
 	"testing"
 	"time"
 
@@ -30,13 +31,15 @@ import (
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/testonly"
 	"github.com/google/trillian/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
-var allTables = []string{"Unsequenced", "TreeHead", "SequencedLeafData", "LeafData", "Subtree", "TreeControl", "Trees"}
+var allTables = []string{"Unsequenced", "TreeHead", "SequencedLeafData", "LeafData", "Subtree", "AdminAuditEvents", "Trees", "MasterElection"}
 
 // Must be 32 bytes to match sha256 length if it was a real hash
 var (
@@ -565,6 +568,82 @@ func TestLatestSignedRootNoneWritten(t *testing.T) {
 	commit(ctx, tx, t)
 }
 
+func TestReadWriteTransactionMasterFence(t *testing.T) {
+	ctx := context.Background()
+	cleanTestDB(DB)
+	as := NewAdminStorage(DB)
+	tree := mustCreateTree(ctx, t, as, testonly.LogTree)
+	s := NewLogStorage(DB, nil)
+	resourceID := strconv.FormatInt(tree.TreeId, 10)
+	if _, err := DB.ExecContext(ctx, "INSERT INTO MasterElection(ResourceId, InstanceId, Epoch, ExpiryNanos) VALUES(?, 'master', 2, 0)", resourceID); err != nil {
+		t.Fatalf("Failed to insert lease: %v", err)
+	}
+
+	for _, tc := range []struct {
+		desc    string
+		fence   storage.MasterFence
+		wantErr bool
+	}{
+		{desc: "current", fence: storage.MasterFence{ResourceID: resourceID, Epoch: 2}},
+		{desc: "deposed", fence: storage.MasterFence{ResourceID: resourceID, Epoch: 1}, wantErr: true},
+		{desc: "not-master", fence: storage.MasterFence{ResourceID: resourceID}, wantErr: true},
+		{desc: "no-lease", fence: storage.MasterFence{ResourceID: "other", Epoch: 2}, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			fctx := storage.WithMasterFence(ctx, tc.fence)
+			err := s.ReadWriteTransaction(fctx, tree, func(ctx context.Context, tx storage.LogTreeTX) error {
+				return nil
+			})
+			if got, want := status.Code(err), codes.FailedPrecondition; tc.wantErr && got != want {
+				t.Errorf("ReadWriteTransaction()=%v, want code %v", err, want)
+			} else if !tc.wantErr && err != nil {
+				t.Errorf("ReadWriteTransaction()=%v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestSnapshotFromReplica(t *testing.T) {
+	ctx := context.Background()
+
+	cleanTestDB(DB)
+	as := NewAdminStorage(DB)
+	tree := mustCreateTree(ctx, t, as, testonly.LogTree)
+	mustSignAndStoreLogRoot(ctx, t, NewLogStorage(DB, nil), tree, 10)
+	emptyDB, done := openTestDBOrDie()
+	defer done(ctx)
+
+	for _, tc := range []struct {
+		desc         string
+		replica      *sql.DB
+		minTreeSize  uint64
+		seenTreeSize uint64
+		wantReplica  bool
+	}{
+		{desc: "up-to-date", replica: DB, minTreeSize: 10, wantReplica: true},
+		{desc: "no-min-size", replica: DB, wantReplica: true},
+		{desc: "stale", replica: DB, minTreeSize: 11},
+		{desc: "behind-previous-snapshot", replica: DB, seenTreeSize: 11},
+		{desc: "uninitialized", replica: emptyDB},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ls := NewLogStorageWithReplica(DB, tc.replica, nil).(*mySQLLogStorage)
+			ls.sizes.Observe(tree.TreeId, tc.seenTreeSize)
+			tx, err := ls.SnapshotForTree(storage.WithMinTreeSize(ctx, tc.minTreeSize), tree)
+			if err != nil {
+				t.Fatalf("SnapshotForTree(): %v", err)
+			}
+			defer commit(ctx, tx, t)
+			if got := tx.(*logTreeTX).ls == ls.replica; got != tc.wantReplica {
+				t.Errorf("snapshot from replica: %v, want %v", got, tc.wantReplica)
+			}
+			if got, want := tx.(*logTreeTX).root.TreeSize, uint64(10); got != want {
+				t.Errorf("snapshot tree size = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
 func SignLogRoot(root *types.LogRootV1) (*trillian.SignedLogRoot, error) {
 	logRoot, err := root.MarshalBinary()
 	if err != nil {
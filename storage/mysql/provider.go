@@ -15,12 +15,16 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"flag"
+	"fmt"
 	"sync"
 
+	"github.com/go-redis/redis"
 	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/redisqueue"
 	"k8s.io/klog/v2"
 
 	// Load MySQL driver
@@ -32,6 +36,10 @@ var (
 	maxConns = flag.Int("mysql_max_conns", 0, "Maximum connections to the database")
 	maxIdle  = flag.Int("mysql_max_idle_conns", -1, "Maximum idle database connections in the connection pool")
 
+	mySQLReplicaURI = flag.String("mysql_replica_uri", "", "Connection URI for a read replica of the MySQL database. If set, read-only log RPCs are served from it when it has caught up with the requested tree size")
+
+	leafQueueRedis = flag.String("mysql_leaf_queue_redis", "", "Address of a Redis server to queue leaves in, as Redis Streams, instead of the Unsequenced table. Queued leaves are only written to MySQL when integrated")
+
 	mysqlMu              sync.Mutex
 	mysqlErr             error
 	mysqlDB              *sql.DB
@@ -55,8 +63,11 @@ func init() {
 }
 
 type mysqlProvider struct {
-	db *sql.DB
-	mf monitoring.MetricFactory
+	db      *sql.DB
+	replica *sql.DB
+	redis   *redis.Client
+	queue   storage.LeafQueue
+	mf      monitoring.MetricFactory
 }
 
 func newMySQLStorageProvider(mf monitoring.MetricFactory) (storage.Provider, error) {
@@ -67,9 +78,24 @@ func newMySQLStorageProvider(mf monitoring.MetricFactory) (storage.Provider, err
 		if err != nil {
 			return nil, err
 		}
+		var replica *sql.DB
+		if *mySQLReplicaURI != "" {
+			if replica, err = openReplica(*mySQLReplicaURI); err != nil {
+				return nil, err
+			}
+		}
+		var rdb *redis.Client
+		var queue storage.LeafQueue
+		if *leafQueueRedis != "" {
+			rdb = redis.NewClient(&redis.Options{Addr: *leafQueueRedis})
+			queue = redisqueue.New(rdb, redisqueue.DefaultPrefix)
+		}
 		mysqlStorageInstance = &mysqlProvider{
-			db: db,
-			mf: mf,
+			db:      db,
+			replica: replica,
+			redis:   rdb,
+			queue:   queue,
+			mf:      mf,
 		}
 	}
 	return mysqlStorageInstance, nil
@@ -96,8 +122,29 @@ func getMySQLDatabaseLocked() (*sql.DB, error) {
 	return db, nil
 }
 
+// openReplica opens the read replica database, with the same connection
+// limits as the primary.
+func openReplica(uri string) (*sql.DB, error) {
+	db, err := OpenDB(uri)
+	if err != nil {
+		return nil, err
+	}
+	if *maxConns > 0 {
+		db.SetMaxOpenConns(*maxConns)
+	}
+	if *maxIdle >= 0 {
+		db.SetMaxIdleConns(*maxIdle)
+	}
+	return db, nil
+}
+
 func (s *mysqlProvider) LogStorage() storage.LogStorage {
-	return NewLogStorage(s.db, s.mf)
+	ls := NewLogStorage(s.db, s.mf).(*mySQLLogStorage)
+	if s.replica != nil {
+		ls = NewLogStorageWithReplica(s.db, s.replica, s.mf).(*mySQLLogStorage)
+	}
+	ls.queue = s.queue
+	return ls
 }
 
 func (s *mysqlProvider) AdminStorage() storage.AdminStorage {
@@ -105,5 +152,29 @@ func (s *mysqlProvider) AdminStorage() storage.AdminStorage {
 }
 
 func (s *mysqlProvider) Close() error {
+	if s.replica != nil {
+		if err := s.replica.Close(); err != nil {
+			klog.Warningf("Failed to close replica database: %v", err)
+		}
+	}
+	if s.redis != nil {
+		if err := s.redis.Close(); err != nil {
+			klog.Warningf("Failed to close Redis client: %v", err)
+		}
+	}
 	return s.db.Close()
 }
+
+// CheckSchema implements storage.SchemaChecker. The replica, if set, must have
+// a supported schema version too.
+func (s *mysqlProvider) CheckSchema(ctx context.Context) error {
+	if err := versionedSchema.Check(ctx, s.db); err != nil {
+		return err
+	}
+	if s.replica != nil {
+		if err := versionedSchema.Check(ctx, s.replica); err != nil {
+			return fmt.Errorf("replica: %v", err)
+		}
+	}
+	return nil
+}
@@ -82,6 +82,10 @@ func queueArgs(_ int64, _ []byte, queueTimestamp time.Time) []interface{} {
 }
 
 func (t *logTreeTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillian.LogLeaf) error {
+	leaves, err := t.updateExternalLeaves(ctx, leaves)
+	if err != nil || len(leaves) == 0 {
+		return err
+	}
 	dequeuedLeaves := make([]dequeuedLeaf, 0, len(leaves))
 	for _, leaf := range leaves {
 		// This should fail on insert but catch it early
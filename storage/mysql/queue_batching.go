@@ -94,6 +94,10 @@ func queueArgs(treeID int64, identityHash []byte, queueTimestamp time.Time) []in
 }
 
 func (t *logTreeTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillian.LogLeaf) error {
+	leaves, err := t.updateExternalLeaves(ctx, leaves)
+	if err != nil || len(leaves) == 0 {
+		return err
+	}
 	querySuffix := []string{}
 	args := []interface{}{}
 	dequeuedLeaves := make([]dequeuedLeaf, 0, len(leaves))
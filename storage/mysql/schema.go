@@ -0,0 +1,42 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"embed"
+
+	"github.com/google/trillian/storage/sqlschema"
+	"k8s.io/klog/v2"
+)
+
+// minSchemaVersion is the oldest version of the schema this code works with.
+// Version 4 added the TreeLabels table, version 5 the AdminAuditEvents table,
+// and version 6 the StorageSettings column of the Trees table. Version 7 added
+// the MasterElection table, which only the SQL master election uses.
+const minSchemaVersion = 6
+
+// migrations holds the scripts which upgrade the schema of existing databases
+// to the version created by schema/storage.sql.
+//
+//go:embed schema/migrations/*.sql
+var migrations embed.FS
+
+var versionedSchema = sqlschema.MustNew(sqlschema.MySQL, minSchemaVersion, migrations, "schema/migrations")
+
+func init() {
+	if err := sqlschema.Register("mysql", sqlschema.Storage{Schema: versionedSchema, Open: GetDatabase}); err != nil {
+		klog.Fatalf("Failed to register MySQL schema: %v", err)
+	}
+}
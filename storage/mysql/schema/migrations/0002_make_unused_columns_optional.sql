@@ -0,0 +1,6 @@
+-- The private keys of trees and the signatures of tree heads are no longer
+-- stored. Let binaries which still write them run alongside ones which don't,
+-- until the columns are dropped by the next migration.
+
+ALTER TABLE Trees MODIFY PrivateKey MEDIUMBLOB, ALGORITHM=INPLACE, LOCK=NONE;
+ALTER TABLE TreeHead MODIFY RootSignature VARBINARY(1024), ALGORITHM=INPLACE, LOCK=NONE;
@@ -0,0 +1,5 @@
+-- Apply once no binaries which need schema version 1 are running.
+
+ALTER TABLE Trees DROP COLUMN PrivateKey, ALGORITHM=INPLACE, LOCK=NONE;
+ALTER TABLE TreeHead DROP COLUMN RootSignature, ALGORITHM=INPLACE, LOCK=NONE;
+DROP TABLE IF EXISTS TreeControl;
@@ -0,0 +1,12 @@
+-- Adds the audit log of the changes made to trees. Binaries which record or
+-- list admin audit events need this version.
+
+CREATE TABLE IF NOT EXISTS AdminAuditEvents(
+  EventId              BIGINT NOT NULL AUTO_INCREMENT,
+  TreeId               BIGINT NOT NULL,
+  EventTimeMillis      BIGINT NOT NULL,
+  -- The serialized AdminAuditEvent.
+  Event                MEDIUMBLOB NOT NULL,
+  PRIMARY KEY(EventId),
+  INDEX AdminAuditEventsTreeIdx(TreeId, EventId)
+);
@@ -0,0 +1,4 @@
+-- Adds the storage settings of trees, which select e.g. the compression of their
+-- leaves. Binaries which read and write trees need this version.
+
+ALTER TABLE Trees ADD COLUMN StorageSettings MEDIUMBLOB, ALGORITHM=INPLACE, LOCK=NONE;
@@ -0,0 +1,12 @@
+-- Adds the MasterElection table, which the SQL master election in
+-- util/election2/sql keeps the leases of its masters in. Databases created from
+-- storage.sql at version 6 may already have it.
+
+CREATE TABLE IF NOT EXISTS MasterElection(
+  ResourceId           VARCHAR(255) NOT NULL,
+  InstanceId           VARCHAR(255) NOT NULL,
+  Epoch                BIGINT NOT NULL,
+  -- The time at which the lease expires, or 0 if the holder has resigned.
+  ExpiryNanos          BIGINT NOT NULL,
+  PRIMARY KEY(ResourceId)
+);
@@ -1,4 +1,28 @@
 # MySQL / MariaDB version of the tree schema
+--
+-- This script creates the latest version of the schema in an empty database.
+-- Existing databases are upgraded with the migrations in the migrations
+-- directory, using the migrateschema command.
+
+-- ---------------------------------------------
+-- Schema version
+-- ---------------------------------------------
+
+-- Each row is a version of the schema which has been applied to the database,
+-- either by this script or by a migration. The highest one is current. The
+-- version of this script is only recorded if it creates the Trees table, as
+-- an existing database may be older, and must be upgraded by the migrations.
+CREATE TABLE IF NOT EXISTS SchemaVersion(
+  Version              INTEGER NOT NULL,
+  Description          VARCHAR(255) NOT NULL,
+  AppliedTimeMillis    BIGINT NOT NULL,
+  PRIMARY KEY(Version)
+);
+
+INSERT IGNORE INTO SchemaVersion(Version, Description, AppliedTimeMillis)
+  SELECT 7, 'storage.sql', UNIX_TIMESTAMP() * 1000 FROM DUAL
+  WHERE NOT EXISTS (SELECT * FROM information_schema.tables
+    WHERE table_schema = DATABASE() AND table_name = 'Trees');
 
 -- ---------------------------------------------
 -- Tree stuff here
@@ -18,24 +42,35 @@ CREATE TABLE IF NOT EXISTS Trees(
   CreateTimeMillis      BIGINT NOT NULL,
   UpdateTimeMillis      BIGINT NOT NULL,
   MaxRootDurationMillis BIGINT NOT NULL,
-  PrivateKey            MEDIUMBLOB NOT NULL,
   PublicKey             MEDIUMBLOB NOT NULL,
   Deleted               BOOLEAN,
   DeleteTimeMillis      BIGINT,
+  StorageSettings       MEDIUMBLOB,
   PRIMARY KEY(TreeId)
 );
 
--- This table contains tree parameters that can be changed at runtime such as for
--- administrative purposes.
-CREATE TABLE IF NOT EXISTS TreeControl(
-  TreeId                  BIGINT NOT NULL,
-  SigningEnabled          BOOLEAN NOT NULL,
-  SequencingEnabled       BOOLEAN NOT NULL,
-  SequenceIntervalSeconds INTEGER NOT NULL,
-  PRIMARY KEY(TreeId),
+-- Labels of trees, which ListTrees can select them by.
+CREATE TABLE IF NOT EXISTS TreeLabels(
+  TreeId               BIGINT NOT NULL,
+  LabelKey             VARCHAR(63) NOT NULL,
+  LabelValue           VARCHAR(63) NOT NULL,
+  PRIMARY KEY(TreeId, LabelKey),
   FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
 );
 
+-- Audit log of the changes made to trees through the admin API, and of their
+-- hard deletion. Events are kept after their trees are deleted, so TreeId
+-- doesn't reference Trees.
+CREATE TABLE IF NOT EXISTS AdminAuditEvents(
+  EventId              BIGINT NOT NULL AUTO_INCREMENT,
+  TreeId               BIGINT NOT NULL,
+  EventTimeMillis      BIGINT NOT NULL,
+  -- The serialized AdminAuditEvent.
+  Event                MEDIUMBLOB NOT NULL,
+  PRIMARY KEY(EventId),
+  INDEX AdminAuditEventsTreeIdx(TreeId, EventId)
+);
+
 CREATE TABLE IF NOT EXISTS Subtree(
   TreeId               BIGINT NOT NULL,
   SubtreeId            VARBINARY(255) NOT NULL,
@@ -54,7 +89,6 @@ CREATE TABLE IF NOT EXISTS TreeHead(
   TreeHeadTimestamp    BIGINT,
   TreeSize             BIGINT,
   RootHash             VARBINARY(255) NOT NULL,
-  RootSignature        VARBINARY(1024) NOT NULL,
   TreeRevision         BIGINT,
   PRIMARY KEY(TreeId, TreeHeadTimestamp),
   FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
@@ -135,3 +169,20 @@ CREATE TABLE IF NOT EXISTS Unsequenced(
   QueueID VARBINARY(32) DEFAULT NULL UNIQUE,
   PRIMARY KEY (TreeId, Bucket, QueueTimestampNanos, LeafIdentityHash)
 );
+
+-- ---------------------------------------------
+-- Master election stuff here
+-- ---------------------------------------------
+
+-- Each row is a mastership lease for a resource (e.g. a log), as used by the
+-- SQL-based master election in util/election2/sql. The Epoch increases every
+-- time the lease changes hands. The log storage checks it in the transactions
+-- of the signer to reject the writes of a deposed master.
+CREATE TABLE IF NOT EXISTS MasterElection(
+  ResourceId           VARCHAR(255) NOT NULL,
+  InstanceId           VARCHAR(255) NOT NULL,
+  Epoch                BIGINT NOT NULL,
+  -- The time at which the lease expires, or 0 if the holder has resigned.
+  ExpiryNanos          BIGINT NOT NULL,
+  PRIMARY KEY(ResourceId)
+);
@@ -0,0 +1,27 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/trillian/storage"
+)
+
+// PruneSubtreeRevisions implements storage.SubtreeRevisionPruner.
+func (m *mySQLLogStorage) PruneSubtreeRevisions(ctx context.Context, treeID int64, keepSince time.Time) (int64, error) {
+	return storage.PruneSQLSubtreeRevisions(ctx, m.db, treeID, keepSince, storage.QuestionMark)
+}
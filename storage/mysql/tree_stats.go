@@ -0,0 +1,38 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+)
+
+// GetTreeStats implements storage.TreeStatsReader.
+func (m *mySQLLogStorage) GetTreeStats(ctx context.Context, tree *trillian.Tree) (*storage.TreeStats, error) {
+	stats, err := storage.QueryTreeStats(ctx, m.db, tree.TreeId, storage.QuestionMark)
+	if err != nil {
+		return nil, err
+	}
+	if m.queue != nil {
+		// The statistics only include the leaves left in the Unsequenced
+		// table from before the queue was used.
+		if err := storage.AddLeafQueueStats(ctx, m.queue, tree.TreeId, stats); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,428 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/compression"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	nonDeletedCond  = "(Deleted IS NULL OR Deleted = 'false')"
+	nonDeletedWhere = " WHERE " + nonDeletedCond
+
+	selectTrees = `
+		SELECT
+			TreeId,
+			TreeState,
+			TreeType,
+			HashStrategy,
+			HashAlgorithm,
+			SignatureAlgorithm,
+			DisplayName,
+			Description,
+			CreateTimeMillis,
+			UpdateTimeMillis,
+			PublicKey,
+			MaxRootDurationMillis,
+			Deleted,
+			DeleteTimeMillis,
+			StorageSettings
+		FROM Trees`
+	selectNonDeletedTrees = selectTrees + nonDeletedWhere
+	selectTreeByID        = selectTrees + " WHERE TreeId = $1"
+
+	updateTreeSQL = `UPDATE Trees
+		SET TreeState = $1, TreeType = $2, DisplayName = $3, Description = $4, UpdateTimeMillis = $5, MaxRootDurationMillis = $6, StorageSettings = $7
+		WHERE TreeId = $8`
+
+	insertAuditEventSQL = `INSERT INTO AdminAuditEvents(TreeId, EventTimeMillis, Event)
+		VALUES($1, $2, $3) RETURNING EventId`
+)
+
+// NewAdminStorage returns a SQL storage.AdminStorage implementation backed by DB.
+// Should work for MySQL, CockroachDB and PostgreSQL
+func NewAdminStorage(db *sql.DB) storage.AdminStorage {
+	return &pgAdminStorage{db}
+}
+
+// pgAdminStorage implements storage.AdminStorage
+type pgAdminStorage struct {
+	db *sql.DB
+}
+
+func (s *pgAdminStorage) Snapshot(ctx context.Context) (storage.ReadOnlyAdminTX, error) {
+	return s.beginInternal(ctx)
+}
+
+func (s *pgAdminStorage) beginInternal(ctx context.Context) (storage.AdminTX, error) {
+	tx, err := s.db.BeginTx(ctx, nil /* opts */)
+	if err != nil {
+		return nil, err
+	}
+	return &adminTX{tx: tx}, nil
+}
+
+func (s *pgAdminStorage) ReadWriteTransaction(ctx context.Context, f storage.AdminTXFunc) error {
+	tx, err := s.beginInternal(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+	if err := f(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *pgAdminStorage) CheckDatabaseAccessible(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+type adminTX struct {
+	tx *sql.Tx
+
+	// mu guards reads/writes on closed, which happen on Commit/Close methods.
+	//
+	// We don't check closed on methods apart from the ones above, as we trust tx
+	// to keep tabs on its state, and hence fail to do queries after closed.
+	mu     sync.RWMutex
+	closed bool
+}
+
+func (t *adminTX) Commit() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	return t.tx.Commit()
+}
+
+func (t *adminTX) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	return t.tx.Rollback()
+}
+
+func (t *adminTX) GetTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
+	stmt, err := t.tx.PrepareContext(ctx, selectTreeByID)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	// GetTree is an entry point for most RPCs, let's provide somewhat nicer error messages.
+	tree, err := storage.ReadTree(stmt.QueryRowContext(ctx, treeID))
+	switch {
+	case err == sql.ErrNoRows:
+		// ErrNoRows doesn't provide useful information, so we don't forward it.
+		return nil, status.Errorf(codes.NotFound, "tree %v not found", treeID)
+	case err != nil:
+		return nil, fmt.Errorf("error reading tree %v: %v", treeID, err)
+	}
+	if err := storage.ReadTreeLabels(ctx, t.tx, []*trillian.Tree{tree}, storage.DollarNumber); err != nil {
+		return nil, fmt.Errorf("error reading labels of tree %v: %v", treeID, err)
+	}
+	return tree, nil
+}
+
+func (t *adminTX) ListTrees(ctx context.Context, includeDeleted bool) ([]*trillian.Tree, error) {
+	var query string
+	if includeDeleted {
+		query = selectTrees
+	} else {
+		query = selectNonDeletedTrees
+	}
+	return t.readTrees(ctx, query)
+}
+
+// ListTreesFiltered implements storage.TreeLister.
+func (t *adminTX) ListTreesFiltered(ctx context.Context, opts storage.ListTreesOptions) ([]*trillian.Tree, error) {
+	query, args := storage.ListTreesSQL(selectTrees, nonDeletedCond, opts, storage.DollarNumber)
+	return t.readTrees(ctx, query, args...)
+}
+
+// readTrees returns the trees selected by query, with their labels.
+func (t *adminTX) readTrees(ctx context.Context, query string, args ...interface{}) ([]*trillian.Tree, error) {
+	stmt, err := t.tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	trees := []*trillian.Tree{}
+	for rows.Next() {
+		tree, err := storage.ReadTree(rows)
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, tree)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// The rows must be closed before the labels are read in the same
+	// transaction.
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := storage.ReadTreeLabels(ctx, t.tx, trees, storage.DollarNumber); err != nil {
+		return nil, err
+	}
+	return trees, nil
+}
+
+func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillian.Tree, error) {
+	id, err := storage.NewTreeID()
+	if err != nil {
+		return nil, err
+	}
+	return t.CreateTreeWithID(ctx, tree, id)
+}
+
+// CreateTreeWithID implements storage.TreeCreatorWithID.
+func (t *adminTX) CreateTreeWithID(ctx context.Context, tree *trillian.Tree, id int64) (*trillian.Tree, error) {
+	if err := storage.ValidateTreeForCreation(ctx, tree); err != nil {
+		return nil, err
+	}
+	if err := compression.ValidateSettings(tree); err != nil {
+		return nil, err
+	}
+	storageSettings, err := storage.MarshalStorageSettings(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal storage settings: %w", err)
+	}
+
+	// Use the time truncated-to-millis throughout, as that's what's stored.
+	nowMillis := storage.ToMillisSinceEpoch(time.Now())
+	now := storage.FromMillisSinceEpoch(nowMillis)
+
+	newTree := proto.Clone(tree).(*trillian.Tree)
+	newTree.TreeId = id
+	newTree.CreateTime = timestamppb.New(now)
+	if err := newTree.CreateTime.CheckValid(); err != nil {
+		return nil, fmt.Errorf("failed to build create time: %w", err)
+	}
+	newTree.UpdateTime = timestamppb.New(now)
+	if err := newTree.UpdateTime.CheckValid(); err != nil {
+		return nil, fmt.Errorf("failed to build update time: %w", err)
+	}
+	if err := newTree.MaxRootDuration.CheckValid(); err != nil {
+		return nil, fmt.Errorf("could not parse MaxRootDuration: %w", err)
+	}
+	rootDuration := newTree.MaxRootDuration.AsDuration()
+
+	insertTreeStmt, err := t.tx.PrepareContext(
+		ctx,
+		`INSERT INTO Trees(
+			TreeId,
+			TreeState,
+			TreeType,
+			HashStrategy,
+			HashAlgorithm,
+			SignatureAlgorithm,
+			DisplayName,
+			Description,
+			CreateTimeMillis,
+			UpdateTimeMillis,
+			PublicKey,
+			MaxRootDurationMillis,
+			StorageSettings)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`)
+	if err != nil {
+		return nil, err
+	}
+	defer insertTreeStmt.Close()
+
+	_, err = insertTreeStmt.ExecContext(
+		ctx,
+		newTree.TreeId,
+		newTree.TreeState.String(),
+		newTree.TreeType.String(),
+		"RFC6962_SHA256", // Unused, filling in for backward compatibility.
+		"SHA256",         // Unused, filling in for backward compatibility.
+		"ECDSA",          // Unused, filling in for backward compatibility.
+		newTree.DisplayName,
+		newTree.Description,
+		nowMillis,
+		nowMillis,
+		[]byte{}, // Unused, filling in for backward compatibility.
+		rootDuration/time.Millisecond,
+		storageSettings,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.WriteTreeLabels(ctx, t.tx, newTree, storage.DollarNumber); err != nil {
+		return nil, err
+	}
+
+	// MySQL silently truncates data when running in non-strict mode.
+	// We shouldn't be using non-strict modes, but let's guard against it
+	// anyway.
+	if _, err := t.GetTree(ctx, newTree.TreeId); err != nil {
+		// GetTree will fail for truncated enums (they get recorded as
+		// empty strings, which will not match any known value).
+		return nil, fmt.Errorf("enum truncated: %v", err)
+	}
+
+	return newTree, nil
+}
+
+func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(*trillian.Tree)) (*trillian.Tree, error) {
+	tree, err := t.GetTree(ctx, treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	beforeUpdate := proto.Clone(tree).(*trillian.Tree)
+	updateFunc(tree)
+	if err := storage.ValidateTreeForUpdate(ctx, beforeUpdate, tree); err != nil {
+		return nil, err
+	}
+	if err := compression.ValidateSettings(tree); err != nil {
+		return nil, err
+	}
+
+	// TODO(pavelkalinnikov): When switching TreeType from PREORDERED_LOG to LOG,
+	// ensure all entries in SequencedLeafData are integrated.
+
+	// Use the time truncated-to-millis throughout, as that's what's stored.
+	nowMillis := storage.ToMillisSinceEpoch(time.Now())
+	now := storage.FromMillisSinceEpoch(nowMillis)
+	tree.UpdateTime = timestamppb.New(now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build update time: %v", err)
+	}
+	if err := tree.MaxRootDuration.CheckValid(); err != nil {
+		return nil, fmt.Errorf("could not parse MaxRootDuration: %w", err)
+	}
+	rootDuration := tree.MaxRootDuration.AsDuration()
+	storageSettings, err := storage.MarshalStorageSettings(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal storage settings: %w", err)
+	}
+
+	stmt, err := t.tx.PrepareContext(ctx, updateTreeSQL)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(
+		ctx,
+		tree.TreeState.String(),
+		tree.TreeType.String(),
+		tree.DisplayName,
+		tree.Description,
+		nowMillis,
+		rootDuration/time.Millisecond,
+		storageSettings,
+		tree.TreeId); err != nil {
+		return nil, err
+	}
+	if err := storage.WriteTreeLabels(ctx, t.tx, tree, storage.DollarNumber); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+func (t *adminTX) SoftDeleteTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
+	return t.updateDeleted(ctx, treeID, true /* deleted */, storage.ToMillisSinceEpoch(time.Now()) /* deleteTimeMillis */)
+}
+
+func (t *adminTX) UndeleteTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
+	return t.updateDeleted(ctx, treeID, false /* deleted */, nil /* deleteTimeMillis */)
+}
+
+// updateDeleted updates the Deleted and DeleteTimeMillis fields of the specified tree.
+// deleteTimeMillis must be either an int64 (in millis since epoch) or nil.
+func (t *adminTX) updateDeleted(ctx context.Context, treeID int64, deleted bool, deleteTimeMillis interface{}) (*trillian.Tree, error) {
+	if err := validateDeleted(ctx, t.tx, treeID, !deleted); err != nil {
+		return nil, err
+	}
+	if _, err := t.tx.ExecContext(
+		ctx,
+		"UPDATE Trees SET Deleted = $1, DeleteTimeMillis = $2 WHERE TreeId = $3",
+		deleted, deleteTimeMillis, treeID); err != nil {
+		return nil, err
+	}
+	return t.GetTree(ctx, treeID)
+}
+
+func (t *adminTX) HardDeleteTree(ctx context.Context, treeID int64) error {
+	if err := validateDeleted(ctx, t.tx, treeID, true /* wantDeleted */); err != nil {
+		return err
+	}
+
+	_, err := t.tx.ExecContext(ctx, "DELETE FROM Trees WHERE TreeId = $1", treeID)
+	return err
+}
+
+// ListAuditEvents implements storage.AuditLogReader.
+func (t *adminTX) ListAuditEvents(ctx context.Context, opts storage.ListAuditEventsOptions) ([]*trillian.AdminAuditEvent, error) {
+	return storage.QueryAuditEvents(ctx, t.tx, opts, storage.DollarNumber)
+}
+
+// AddAuditEvent implements storage.AuditLogWriter.
+func (t *adminTX) AddAuditEvent(ctx context.Context, event *trillian.AdminAuditEvent) error {
+	treeID, eventTimeMillis, data, err := storage.AuditEventRow(event)
+	if err != nil {
+		return err
+	}
+	var id int64
+	if err := t.tx.QueryRowContext(ctx, insertAuditEventSQL, treeID, eventTimeMillis, data).Scan(&id); err != nil {
+		return err
+	}
+	event.EventId = id
+	return nil
+}
+
+func validateDeleted(ctx context.Context, tx *sql.Tx, treeID int64, wantDeleted bool) error {
+	var nullDeleted sql.NullBool
+	switch err := tx.QueryRowContext(ctx, "SELECT Deleted FROM Trees WHERE TreeId = $1", treeID).Scan(&nullDeleted); {
+	case err == sql.ErrNoRows:
+		return status.Errorf(codes.NotFound, "tree %v not found", treeID)
+	case err != nil:
+		return err
+	}
+
+	switch deleted := nullDeleted.Valid && nullDeleted.Bool; {
+	case wantDeleted && !deleted:
+		return status.Errorf(codes.FailedPrecondition, "tree %v is not soft deleted", treeID)
+	case !wantDeleted && deleted:
+		return status.Errorf(codes.FailedPrecondition, "tree %v already soft deleted", treeID)
+	}
+	return nil
+}
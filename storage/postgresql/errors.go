@@ -0,0 +1,39 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgresql
+
+import (
+	"github.com/lib/pq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	serializationFailureErrorCode = pq.ErrorCode("40001")
+	deadlockDetectedErrorCode     = pq.ErrorCode("40P01")
+)
+
+// pgToGRPC converts some types of PostgreSQL errors to GRPC errors. This gives
+// clients more signal when the operation can be retried.
+func pgToGRPC(err error) error {
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
+		return err
+	}
+	if pqErr.Code == serializationFailureErrorCode || pqErr.Code == deadlockDetectedErrorCode {
+		return status.Errorf(codes.Aborted, "PostgreSQL: %v", pqErr)
+	}
+	return err
+}
@@ -0,0 +1,27 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgresql
+
+import (
+	"context"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+)
+
+// ArchiveLeafData implements storage.LeafArchiver.
+func (m *pgLogStorage) ArchiveLeafData(ctx context.Context, tree *trillian.Tree, start, end int64, ref []byte) (int64, error) {
+	return storage.ArchiveSQLLeafData(ctx, m.db, tree, start, end, ref, storage.DollarNumber)
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"sync"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"k8s.io/klog/v2"
+
+	_ "github.com/lib/pq" // Register the Postgres driver.
+)
+
+const (
+	// StorageProviderName is the name of the storage provider.
+	StorageProviderName = "postgresql"
+)
+
+var (
+	postgreSQLURI = flag.String("postgresql_uri", "postgresql://postgres@localhost:5432/trillian?sslmode=disable", "Connection URI for PostgreSQL database")
+	maxConns      = flag.Int("postgresql_max_conns", 0, "Maximum connections to the database")
+	maxIdle       = flag.Int("postgresql_max_idle_conns", -1, "Maximum idle database connections in the connection pool")
+
+	pgErr             error
+	pgHandle          *sql.DB
+	pgStorageInstance *pgProvider
+	dbConnMu          sync.Mutex
+)
+
+// GetDatabase returns the database handle for the provider.
+func GetDatabase() (*sql.DB, error) {
+	dbConnMu.Lock()
+	defer dbConnMu.Unlock()
+	return getPGDatabaseLocked()
+}
+
+func init() {
+	if err := storage.RegisterProvider(StorageProviderName, newPGStorageProvider); err != nil {
+		klog.Fatalf("Failed to register storage provider postgresql: %v", err)
+	}
+}
+
+type pgProvider struct {
+	db *sql.DB
+	mf monitoring.MetricFactory
+}
+
+func newPGStorageProvider(mf monitoring.MetricFactory) (storage.Provider, error) {
+	dbConnMu.Lock()
+	defer dbConnMu.Unlock()
+	if pgStorageInstance == nil {
+		db, err := getPGDatabaseLocked()
+		if err != nil {
+			return nil, err
+		}
+		pgStorageInstance = &pgProvider{
+			db: db,
+			mf: mf,
+		}
+	}
+
+	return pgStorageInstance, nil
+}
+
+// Lazy initializes the database connection handle and returns the instance.
+// Requires lock to be held.
+func getPGDatabaseLocked() (*sql.DB, error) {
+	if pgHandle != nil || pgErr != nil {
+		return pgHandle, pgErr
+	}
+	db, err := OpenDB(*postgreSQLURI)
+	if err != nil {
+		pgErr = err
+		return nil, err
+	}
+	if *maxConns > 0 {
+		db.SetMaxOpenConns(*maxConns)
+	}
+	if *maxIdle >= 0 {
+		db.SetMaxIdleConns(*maxIdle)
+	}
+	pgHandle, pgErr = db, nil
+	return db, nil
+}
+
+func (p *pgProvider) Close() error {
+	return p.db.Close()
+}
+
+func (p *pgProvider) LogStorage() storage.LogStorage {
+	return NewLogStorage(p.db, p.mf)
+}
+
+func (p *pgProvider) AdminStorage() storage.AdminStorage {
+	return NewAdminStorage(p.db)
+}
+
+// CheckSchema implements storage.SchemaChecker.
+func (p *pgProvider) CheckSchema(ctx context.Context) error {
+	return versionedSchema.Check(ctx, p.db)
+}
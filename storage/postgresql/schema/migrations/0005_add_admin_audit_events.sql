@@ -0,0 +1,14 @@
+-- Adds the audit log of the changes made to trees. Binaries which record or
+-- list admin audit events need this version.
+
+CREATE TABLE IF NOT EXISTS AdminAuditEvents(
+  EventId              BIGINT GENERATED ALWAYS AS IDENTITY,
+  TreeId               BIGINT NOT NULL,
+  EventTimeMillis      BIGINT NOT NULL,
+  -- The serialized AdminAuditEvent.
+  Event                BYTEA NOT NULL,
+  PRIMARY KEY(EventId)
+);
+
+CREATE INDEX IF NOT EXISTS AdminAuditEventsTreeIdx
+  ON AdminAuditEvents(TreeId, EventId);
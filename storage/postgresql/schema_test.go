@@ -0,0 +1,52 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgresql
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestSchemaScriptVersion(t *testing.T) {
+	t.Parallel()
+
+	script, err := os.ReadFile("schema/storage.sql")
+	if err != nil {
+		t.Fatalf("ReadFile(): %v", err)
+	}
+	m := regexp.MustCompile(`INTO SchemaVersion\(Version, Description, AppliedTimeMillis\)\s+SELECT (\d+),`).FindSubmatch(script)
+	if m == nil {
+		t.Fatal("schema/storage.sql does not record its version")
+	}
+	if got, want := string(m[1]), strconv.Itoa(versionedSchema.LatestVersion()); got != want {
+		t.Errorf("schema/storage.sql records version %s, want %s", got, want)
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openTestDBOrDie(t).db
+	if v, err := versionedSchema.Version(ctx, db); err != nil || v != versionedSchema.LatestVersion() {
+		t.Errorf("Version() = %v, %v; want %v, nil", v, err, versionedSchema.LatestVersion())
+	}
+	if err := versionedSchema.Check(ctx, db); err != nil {
+		t.Errorf("Check(): %v", err)
+	}
+}
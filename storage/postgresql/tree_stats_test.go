@@ -0,0 +1,78 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgresql
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+)
+
+func TestGetTreeStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	handle := openTestDBOrDie(t)
+	as := NewAdminStorage(handle.db)
+	tree := mustCreateTree(ctx, t, as, testonly.LogTree)
+	s := NewLogStorage(handle.db, nil)
+	reader := s.(storage.TreeStatsReader)
+
+	got, err := reader.GetTreeStats(ctx, tree)
+	if err != nil {
+		t.Fatalf("GetTreeStats(): %v", err)
+	}
+	if diff := cmp.Diff(&storage.TreeStats{}, got); diff != "" {
+		t.Errorf("GetTreeStats() of empty tree diff (-want +got):\n%s", diff)
+	}
+
+	const size = 10
+	for i := int64(0); i < size; i++ {
+		data := []byte(fmt.Sprintf("leaf %d", i))
+		id := sha256.Sum256(data)
+		hash := sha256.Sum256(id[:])
+		createFakeLeaf(ctx, handle.db, tree.TreeId, id[:], hash[:], data, []byte("extra"), i, t)
+	}
+	mustSignAndStoreLogRoot(ctx, t, s, tree, size)
+	if _, err := handle.db.ExecContext(ctx, "INSERT INTO Subtree(TreeId, SubtreeId, Nodes, SubtreeRevision) VALUES($1,$2,$3,$4)", tree.TreeId, []byte{0}, make([]byte, 100), 1); err != nil {
+		t.Fatalf("Failed to insert subtree: %v", err)
+	}
+	if _, err := s.QueueLeaves(ctx, tree, createTestLeaves(3, 100), fakeQueueTime); err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+
+	got, err = reader.GetTreeStats(ctx, tree)
+	if err != nil {
+		t.Fatalf("GetTreeStats(): %v", err)
+	}
+	want := &storage.TreeStats{
+		LeafCount:    size,
+		QueueDepth:   3,
+		OldestQueued: fakeQueueTime,
+		LatestRoot:   time.Unix(0, 0),
+		// The sequenced leaves have 6 + 5 bytes, and the queued ones 8 + 7.
+		LeafBytes: size*11 + 3*15,
+		TileBytes: 100,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetTreeStats() diff (-want +got):\n%s", diff)
+	}
+}
@@ -15,7 +15,9 @@
 package storage
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/google/trillian/monitoring"
@@ -25,9 +27,14 @@ import (
 // provide instances of storage providers.
 type NewProviderFunc func(monitoring.MetricFactory) (Provider, error)
 
+// NewWrapperProviderFunc is the signature of a function which can be
+// registered to provide instances of storage providers which wrap others.
+type NewWrapperProviderFunc func(inner Provider, mf monitoring.MetricFactory) (Provider, error)
+
 var (
-	spMu     sync.RWMutex
-	spByName = make(map[string]NewProviderFunc)
+	spMu          sync.RWMutex
+	spByName      = make(map[string]NewProviderFunc)
+	wrapperByName = make(map[string]NewWrapperProviderFunc)
 )
 
 // RegisterProvider registers the given storage Provider.
@@ -43,17 +50,68 @@ func RegisterProvider(name string, sp NewProviderFunc) error {
 	return nil
 }
 
+// RegisterWrapperProvider registers a storage Provider which wraps others.
+// NewProvider returns it for names of the form "<name>:<inner>", wrapping the
+// Provider named inner.
+func RegisterWrapperProvider(name string, w NewWrapperProviderFunc) error {
+	spMu.Lock()
+	defer spMu.Unlock()
+
+	if _, exists := wrapperByName[name]; exists {
+		return fmt.Errorf("storage wrapper provider %v already registered", name)
+	}
+	wrapperByName[name] = w
+	return nil
+}
+
 // NewProvider returns a new Provider instance of the type specified by name.
+// Names of the form "<wrapper>:<inner>" return the Provider registered with
+// RegisterWrapperProvider as wrapper, wrapping a new Provider named inner.
+//
+// Binaries should use NewProviderWithContext, so that checking the schema of
+// the storage can be cancelled.
 func NewProvider(name string, mf monitoring.MetricFactory) (Provider, error) {
-	spMu.RLock()
-	defer spMu.RUnlock()
+	return NewProviderWithContext(context.Background(), name, mf)
+}
 
+// NewProviderWithContext is like NewProvider, and also checks with ctx that
+// the schema of the storage is supported, if the Provider implements
+// SchemaChecker.
+func NewProviderWithContext(ctx context.Context, name string, mf monitoring.MetricFactory) (Provider, error) {
+	spMu.RLock()
 	sp := spByName[name]
-	if sp == nil {
-		return nil, fmt.Errorf("no such storage provider %v", name)
+	var w NewWrapperProviderFunc
+	if i := strings.Index(name, ":"); sp == nil && i > 0 {
+		w = wrapperByName[name[:i]]
 	}
+	spMu.RUnlock()
 
-	return sp(mf)
+	switch {
+	case sp != nil:
+		p, err := sp(mf)
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := p.(SchemaChecker); ok {
+			if err := c.CheckSchema(ctx); err != nil {
+				return nil, err
+			}
+		}
+		return p, nil
+	case w != nil:
+		inner, err := NewProviderWithContext(ctx, name[strings.Index(name, ":")+1:], mf)
+		if err != nil {
+			return nil, err
+		}
+		p, err := w(inner, mf)
+		if err != nil {
+			inner.Close()
+			return nil, err
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("no such storage provider %v", name)
+	}
 }
 
 // Providers returns a slice of all registered storage provider names.
@@ -80,3 +138,11 @@ type Provider interface {
 	// Close closes the underlying storage.
 	Close() error
 }
+
+// SchemaChecker is implemented by Providers whose storage has a versioned
+// schema, such as those based on SQL databases.
+type SchemaChecker interface {
+	// CheckSchema returns an error unless the schema of the storage has a
+	// version the code works with.
+	CheckSchema(ctx context.Context) error
+}
@@ -15,6 +15,8 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/google/trillian/monitoring"
@@ -68,6 +70,89 @@ func TestProviderRegistration(t *testing.T) {
 	}
 }
 
+func TestWrapperProviderRegistration(t *testing.T) {
+	RegisterProvider("inner", func(_ monitoring.MetricFactory) (Provider, error) {
+		return &provider{}, nil
+	})
+	wraps := 0
+	if err := RegisterWrapperProvider("wrapper", func(p Provider, _ monitoring.MetricFactory) (Provider, error) {
+		wraps++
+		return p, nil
+	}); err != nil {
+		t.Fatalf("RegisterWrapperProvider(): %v", err)
+	}
+	if err := RegisterWrapperProvider("wrapper", nil); err == nil {
+		t.Error("RegisterWrapperProvider(duplicate): want error")
+	}
+
+	for _, test := range []struct {
+		name      string
+		wantWraps int
+		wantErr   bool
+	}{
+		{name: "wrapper:inner", wantWraps: 1},
+		{name: "wrapper:wrapper:inner", wantWraps: 2},
+		{name: "wrapper", wantErr: true},
+		{name: "wrapper:", wantErr: true},
+		{name: "wrapper:unknown", wantErr: true},
+		{name: "unknown:inner", wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			wraps = 0
+			_, err := NewProvider(test.name, nil)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("NewProvider() = %v, want error %v", err, test.wantErr)
+			}
+			if wraps != test.wantWraps {
+				t.Errorf("NewProvider() wrapped %d times, want %d", wraps, test.wantWraps)
+			}
+		})
+	}
+}
+
+type versionedProvider struct {
+	provider
+	err error
+}
+
+func (p *versionedProvider) CheckSchema(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.err
+}
+
+func TestNewProviderWithContextChecksSchema(t *testing.T) {
+	RegisterProvider("versioned-ok", func(_ monitoring.MetricFactory) (Provider, error) {
+		return &versionedProvider{}, nil
+	})
+	RegisterProvider("versioned-old", func(_ monitoring.MetricFactory) (Provider, error) {
+		return &versionedProvider{err: errors.New("too old")}, nil
+	})
+	RegisterWrapperProvider("checked-wrapper", func(p Provider, _ monitoring.MetricFactory) (Provider, error) {
+		return &provider{}, nil
+	})
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, test := range []struct {
+		name    string
+		ctx     context.Context
+		wantErr bool
+	}{
+		{name: "versioned-ok", ctx: context.Background()},
+		{name: "versioned-old", ctx: context.Background(), wantErr: true},
+		{name: "versioned-ok", ctx: cancelled, wantErr: true},
+		{name: "checked-wrapper:versioned-ok", ctx: context.Background()},
+		{name: "checked-wrapper:versioned-old", ctx: context.Background(), wantErr: true},
+	} {
+		_, err := NewProviderWithContext(test.ctx, test.name, nil)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("NewProviderWithContext(%s) = %v, want error %v", test.name, err, test.wantErr)
+		}
+	}
+}
+
 func TestProviders(t *testing.T) {
 	RegisterProvider("a", func(_ monitoring.MetricFactory) (Provider, error) {
 		return &provider{}, nil
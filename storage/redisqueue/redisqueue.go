@@ -0,0 +1,232 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package redisqueue implements a storage.LeafQueue using Redis Streams.
+//
+// The queue of each tree is a stream of marshalled leaves, along with a hash
+// from the LeafIdentityHash of each leaf in the stream to its entry ID, which
+// is used to deduplicate leaves and to remove them once integrated. Both keys
+// of a tree share a hash tag, so that they're in the same Redis Cluster slot.
+package redisqueue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultPrefix is the default prefix of the keys of the queues.
+const DefaultPrefix = "trillian/queue"
+
+// leafField is the field of the stream entries holding the marshalled leaf.
+const leafField = "leaf"
+
+// addScript appends the leaves which aren't already pending to the stream.
+// KEYS[1] is the stream, and KEYS[2] the hash of pending leaves. ARGV holds the
+// identity hash and marshalled leaf of each leaf in turn. It returns, for each
+// leaf, nil if it was added, or the marshalled leaf which was already pending.
+var addScript = redis.NewScript(`
+local result = {}
+for i = 1, #ARGV, 2 do
+  local existing = false
+  local id = redis.call("HGET", KEYS[2], ARGV[i])
+  if id then
+    local entries = redis.call("XRANGE", KEYS[1], id, id)
+    if #entries > 0 then
+      existing = entries[1][2][2]
+    end
+  end
+  if not existing then
+    id = redis.call("XADD", KEYS[1], "*", "` + leafField + `", ARGV[i + 1])
+    redis.call("HSET", KEYS[2], ARGV[i], id)
+  end
+  result[#result + 1] = existing
+end
+return result
+`)
+
+// removeScript removes the pending leaves from the stream. KEYS are as for
+// addScript, and ARGV holds the identity hashes of the leaves. It returns the
+// number of leaves removed.
+var removeScript = redis.NewScript(`
+local removed = 0
+for i = 1, #ARGV do
+  local id = redis.call("HGET", KEYS[2], ARGV[i])
+  if id then
+    removed = removed + redis.call("XDEL", KEYS[1], id)
+    redis.call("HDEL", KEYS[2], ARGV[i])
+  end
+end
+return removed
+`)
+
+// RedisClient is an interface that encompasses the various methods used by
+// Queue, and allows selecting among different Redis client implementations
+// (e.g. regular Redis, Redis Cluster, sharded, etc.)
+type RedisClient interface {
+	// Required to load and execute scripts
+	Eval(script string, keys []string, args ...interface{}) *redis.Cmd
+	EvalSha(sha1 string, keys []string, args ...interface{}) *redis.Cmd
+	ScriptExists(hashes ...string) *redis.BoolSliceCmd
+	ScriptLoad(script string) *redis.StringCmd
+
+	XRangeN(stream, start, stop string, count int64) *redis.XMessageSliceCmd
+	XLen(stream string) *redis.IntCmd
+}
+
+// Queue is a storage.LeafQueue which keeps the queue of each tree in a Redis
+// stream.
+type Queue struct {
+	c      RedisClient
+	prefix string
+}
+
+var _ storage.LeafQueue = &Queue{}
+
+// New returns a Queue which uses the provided Redis client, and whose keys
+// start with prefix.
+func New(client RedisClient, prefix string) *Queue {
+	return &Queue{c: client, prefix: prefix}
+}
+
+// Load preloads the Lua scripts of the queue into the Redis database. Calling
+// this function is optional, but reduces the network traffic to Redis.
+func (q *Queue) Load(ctx context.Context) error {
+	client := withClientContext(ctx, q.c)
+	for _, s := range []*redis.Script{addScript, removeScript} {
+		if err := s.Load(client).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keys returns the keys of the stream and the hash of pending leaves of the
+// tree.
+func (q *Queue) keys(treeID int64) []string {
+	return []string{
+		fmt.Sprintf("%s:{%d}:stream", q.prefix, treeID),
+		fmt.Sprintf("%s:{%d}:pending", q.prefix, treeID),
+	}
+}
+
+// Add implements storage.LeafQueue.
+func (q *Queue) Add(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf) ([]*trillian.LogLeaf, error) {
+	if len(leaves) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, 0, 2*len(leaves))
+	for _, leaf := range leaves {
+		data, err := proto.Marshal(leaf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal leaf: %v", err)
+		}
+		args = append(args, leaf.LeafIdentityHash, data)
+	}
+	resp, err := addScript.Run(withClientContext(ctx, q.c), q.keys(treeID), args...).Result()
+	if err != nil {
+		return nil, err
+	}
+	results, ok := resp.([]interface{})
+	if !ok || len(results) != len(leaves) {
+		return nil, fmt.Errorf("unexpected response from Redis: %v", resp)
+	}
+	existing := make([]*trillian.LogLeaf, len(leaves))
+	for i, r := range results {
+		if r == nil {
+			continue
+		}
+		if existing[i], err = unmarshalLeaf(r); err != nil {
+			return nil, err
+		}
+	}
+	return existing, nil
+}
+
+// Peek implements storage.LeafQueue.
+func (q *Queue) Peek(ctx context.Context, treeID int64, limit int, cutoff time.Time) ([]*trillian.LogLeaf, error) {
+	client := withClientContext(ctx, q.c)
+	msgs, err := client.XRangeN(q.keys(treeID)[0], "-", "+", int64(limit)).Result()
+	if err != nil {
+		return nil, err
+	}
+	leaves := make([]*trillian.LogLeaf, 0, len(msgs))
+	for _, msg := range msgs {
+		leaf, err := unmarshalLeaf(msg.Values[leafField])
+		if err != nil {
+			return nil, fmt.Errorf("entry %s: %v", msg.ID, err)
+		}
+		if leaf.QueueTimestamp.AsTime().After(cutoff) {
+			continue
+		}
+		leaves = append(leaves, leaf)
+	}
+	return leaves, nil
+}
+
+// Remove implements storage.LeafQueue.
+func (q *Queue) Remove(ctx context.Context, treeID int64, identityHashes [][]byte) error {
+	if len(identityHashes) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(identityHashes))
+	for i, h := range identityHashes {
+		args[i] = h
+	}
+	return removeScript.Run(withClientContext(ctx, q.c), q.keys(treeID), args...).Err()
+}
+
+// Len implements storage.LeafQueue.
+func (q *Queue) Len(ctx context.Context, treeID int64) (int64, error) {
+	return withClientContext(ctx, q.c).XLen(q.keys(treeID)[0]).Result()
+}
+
+func unmarshalLeaf(v interface{}) (*trillian.LogLeaf, error) {
+	data, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("got %T, want marshalled leaf", v)
+	}
+	var leaf trillian.LogLeaf
+	if err := proto.Unmarshal([]byte(data), &leaf); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal leaf: %v", err)
+	}
+	return &leaf, nil
+}
+
+// Because each Redis client type in the Go package has a `WithContext` method
+// that returns a concrete type, we can't simply put that method in the
+// RedisClient interface. This method performs type assertions to try and call
+// the `WithContext` method on the appropriate concrete type.
+func withClientContext(ctx context.Context, client RedisClient) RedisClient {
+	type withContextable interface {
+		WithContext(context.Context) RedisClient
+	}
+
+	switch c := client.(type) {
+	case *redis.Client:
+		return c.WithContext(ctx)
+	case *redis.ClusterClient:
+		return c.WithContext(ctx)
+	case *redis.Ring:
+		return c.WithContext(ctx)
+	case withContextable:
+		return c.WithContext(ctx)
+	}
+	return client
+}
@@ -0,0 +1,129 @@
+//go:build integration
+// +build integration
+
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisqueue
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/google/trillian"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var queueTime = time.Date(2016, 11, 10, 15, 16, 27, 0, time.UTC)
+
+func newLeaves(n, start int, queueTime time.Time) []*trillian.LogLeaf {
+	leaves := make([]*trillian.LogLeaf, 0, n)
+	for i := start; i < start+n; i++ {
+		leaves = append(leaves, &trillian.LogLeaf{
+			LeafIdentityHash: []byte(fmt.Sprintf("id %d", i)),
+			MerkleLeafHash:   []byte(fmt.Sprintf("hash %d", i)),
+			LeafValue:        []byte(fmt.Sprintf("value %d", i)),
+			QueueTimestamp:   timestamppb.New(queueTime),
+		})
+	}
+	return leaves
+}
+
+func identityHashes(leaves []*trillian.LogLeaf) [][]byte {
+	hashes := make([][]byte, 0, len(leaves))
+	for _, leaf := range leaves {
+		hashes = append(hashes, leaf.LeafIdentityHash)
+	}
+	return hashes
+}
+
+func checkPeek(ctx context.Context, t *testing.T, q *Queue, treeID int64, limit int, cutoff time.Time, want []*trillian.LogLeaf) {
+	t.Helper()
+	got, err := q.Peek(ctx, treeID, limit, cutoff)
+	if err != nil {
+		t.Fatalf("Peek(): %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Peek() returned %d leaves, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if !bytes.Equal(got[i].LeafIdentityHash, want[i].LeafIdentityHash) || !bytes.Equal(got[i].LeafValue, want[i].LeafValue) {
+			t.Errorf("Peek()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestQueueIntegration(t *testing.T) {
+	ctx := context.Background()
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	q := New(rdb, fmt.Sprintf("test/%d", time.Now().UnixNano()))
+	if err := q.Load(ctx); err != nil {
+		t.Fatalf("failed to load scripts: %v", err)
+	}
+	const treeID = 12345
+
+	leaves := newLeaves(5, 0, queueTime)
+	later := newLeaves(2, 5, queueTime.Add(time.Minute))
+	existing, err := q.Add(ctx, treeID, append(append(leaves, leaves[1]), later...))
+	if err != nil {
+		t.Fatalf("Add(): %v", err)
+	}
+	for i, e := range existing {
+		if wantDup := i == 5; (e != nil) != wantDup {
+			t.Errorf("Add()[%d] = %v, want duplicate: %v", i, e, wantDup)
+		}
+	}
+	if e := existing[5]; e == nil || !bytes.Equal(e.LeafValue, leaves[1].LeafValue) {
+		t.Errorf("Add()[5] = %v, want %v", e, leaves[1])
+	}
+
+	// The queues of other trees are separate.
+	checkPeek(ctx, t, q, treeID+1, 10, queueTime, nil)
+
+	checkPeek(ctx, t, q, treeID, 3, queueTime, leaves[:3])
+	checkPeek(ctx, t, q, treeID, 10, queueTime, leaves)
+	checkPeek(ctx, t, q, treeID, 10, queueTime.Add(time.Hour), append(leaves, later...))
+
+	if err := q.Remove(ctx, treeID, append(identityHashes(leaves[:2]), []byte("unknown"))); err != nil {
+		t.Fatalf("Remove(): %v", err)
+	}
+	checkPeek(ctx, t, q, treeID, 10, queueTime, leaves[2:])
+	if got, err := q.Len(ctx, treeID); err != nil || got != 5 {
+		t.Errorf("Len() = %d, %v, want 5, nil", got, err)
+	}
+
+	// Removed leaves can be queued again.
+	existing, err = q.Add(ctx, treeID, leaves[:1])
+	if err != nil {
+		t.Fatalf("Add(): %v", err)
+	}
+	if existing[0] != nil {
+		t.Errorf("Add() = %v, want nil", existing[0])
+	}
+	checkPeek(ctx, t, q, treeID, 10, queueTime, append(leaves[2:], leaves[0]))
+
+	if err := q.Remove(ctx, treeID, identityHashes(append(leaves, later...))); err != nil {
+		t.Fatalf("Remove(): %v", err)
+	}
+	checkPeek(ctx, t, q, treeID, 10, queueTime.Add(time.Hour), nil)
+	if got, err := q.Len(ctx, treeID); err != nil || got != 0 {
+		t.Errorf("Len() = %d, %v, want 0, nil", got, err)
+	}
+}
@@ -15,11 +15,16 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/trillian"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -54,7 +59,7 @@ func ReadTree(row Row) (*trillian.Tree, error) {
 	var treeState, treeType, hashStrategy, hashAlgorithm, signatureAlgorithm string
 	var createMillis, updateMillis, maxRootDurationMillis int64
 	var displayName, description sql.NullString
-	var privateKey, publicKey []byte
+	var publicKey, storageSettings []byte
 	var deleted sql.NullBool
 	var deleteMillis sql.NullInt64
 	err := row.Scan(
@@ -68,11 +73,11 @@ func ReadTree(row Row) (*trillian.Tree, error) {
 		&description,
 		&createMillis,
 		&updateMillis,
-		&privateKey,
 		&publicKey,
 		&maxRootDurationMillis,
 		&deleted,
 		&deleteMillis,
+		&storageSettings,
 	)
 	if err != nil {
 		return nil, err
@@ -124,5 +129,235 @@ func ReadTree(row Row) (*trillian.Tree, error) {
 		}
 	}
 
+	if len(storageSettings) > 0 {
+		tree.StorageSettings = &anypb.Any{}
+		if err := proto.Unmarshal(storageSettings, tree.StorageSettings); err != nil {
+			return nil, fmt.Errorf("failed to parse storage settings: %w", err)
+		}
+	}
+
 	return tree, nil
 }
+
+// MarshalStorageSettings returns the storage settings of the tree as they are
+// stored in the StorageSettings column of the Trees table, or nil if it has
+// none.
+func MarshalStorageSettings(tree *trillian.Tree) ([]byte, error) {
+	if tree.StorageSettings == nil {
+		return nil, nil
+	}
+	return proto.Marshal(tree.StorageSettings)
+}
+
+// Placeholder returns the placeholder of the nth argument of a query, counting
+// from 1.
+type Placeholder func(n int) string
+
+// QuestionMark is the Placeholder of MySQL and SQLite.
+func QuestionMark(int) string { return "?" }
+
+// DollarNumber is the Placeholder of PostgreSQL and CockroachDB.
+func DollarNumber(n int) string { return fmt.Sprintf("$%d", n) }
+
+// ListTreesSQL returns the query which selects the trees matching opts, ordered
+// by ID, and its arguments. selectTrees selects all the trees, and nonDeleted
+// is the condition which excludes soft-deleted ones, as the type of the Deleted
+// column varies between databases.
+func ListTreesSQL(selectTrees, nonDeleted string, opts ListTreesOptions, ph Placeholder) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return ph(len(args))
+	}
+	if !opts.IncludeDeleted {
+		conds = append(conds, nonDeleted)
+	}
+	if opts.AfterTreeID > 0 {
+		conds = append(conds, "TreeId > "+arg(opts.AfterTreeID))
+	}
+	if len(opts.TreeTypes) > 0 {
+		in := make([]string, 0, len(opts.TreeTypes))
+		for _, t := range opts.TreeTypes {
+			in = append(in, arg(t.String()))
+		}
+		conds = append(conds, "TreeType IN ("+strings.Join(in, ", ")+")")
+	}
+	if len(opts.TreeStates) > 0 {
+		in := make([]string, 0, len(opts.TreeStates))
+		for _, s := range opts.TreeStates {
+			in = append(in, arg(s.String()))
+		}
+		conds = append(conds, "TreeState IN ("+strings.Join(in, ", ")+")")
+	}
+	keys := make([]string, 0, len(opts.Labels))
+	for k := range opts.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		conds = append(conds, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM TreeLabels WHERE TreeLabels.TreeId = Trees.TreeId AND LabelKey = %s AND LabelValue = %s)",
+			arg(k), arg(opts.Labels[k])))
+	}
+
+	query := selectTrees
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY TreeId"
+	if opts.Limit > 0 {
+		query += " LIMIT " + arg(opts.Limit)
+	}
+	return query, args
+}
+
+// readTreeLabelsBatch is the most trees whose labels ReadTreeLabels reads in
+// one query, keeping the number of parameters within the limits of all the
+// supported databases.
+const readTreeLabelsBatch = 500
+
+// ReadTreeLabels sets the labels of trees from the TreeLabels table.
+func ReadTreeLabels(ctx context.Context, tx *sql.Tx, trees []*trillian.Tree, ph Placeholder) error {
+	for len(trees) > 0 {
+		n := len(trees)
+		if n > readTreeLabelsBatch {
+			n = readTreeLabelsBatch
+		}
+		if err := readTreeLabels(ctx, tx, trees[:n], ph); err != nil {
+			return err
+		}
+		trees = trees[n:]
+	}
+	return nil
+}
+
+func readTreeLabels(ctx context.Context, tx *sql.Tx, trees []*trillian.Tree, ph Placeholder) error {
+	byID := make(map[int64]*trillian.Tree, len(trees))
+	in := make([]string, 0, len(trees))
+	args := make([]interface{}, 0, len(trees))
+	for _, tree := range trees {
+		byID[tree.TreeId] = tree
+		args = append(args, tree.TreeId)
+		in = append(in, ph(len(args)))
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT TreeId, LabelKey, LabelValue FROM TreeLabels WHERE TreeId IN ("+strings.Join(in, ", ")+")",
+		args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var treeID int64
+		var k, v string
+		if err := rows.Scan(&treeID, &k, &v); err != nil {
+			return err
+		}
+		tree, ok := byID[treeID]
+		if !ok {
+			continue
+		}
+		if tree.Labels == nil {
+			tree.Labels = make(map[string]string)
+		}
+		tree.Labels[k] = v
+	}
+	return rows.Err()
+}
+
+// WriteTreeLabels replaces the labels of tree in the TreeLabels table.
+func WriteTreeLabels(ctx context.Context, tx *sql.Tx, tree *trillian.Tree, ph Placeholder) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM TreeLabels WHERE TreeId = "+ph(1), tree.TreeId); err != nil {
+		return err
+	}
+	insert := fmt.Sprintf("INSERT INTO TreeLabels(TreeId, LabelKey, LabelValue) VALUES(%s, %s, %s)", ph(1), ph(2), ph(3))
+	for k, v := range tree.Labels {
+		if _, err := tx.ExecContext(ctx, insert, tree.TreeId, k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryAuditEvents returns the audit events matching opts from the
+// AdminAuditEvents table, ordered by ID. The table keeps each event serialized
+// in its Event column, except for its ID, which is kept in EventId.
+func QueryAuditEvents(ctx context.Context, tx *sql.Tx, opts ListAuditEventsOptions, ph Placeholder) ([]*trillian.AdminAuditEvent, error) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return ph(len(args))
+	}
+	query := "SELECT EventId, Event FROM AdminAuditEvents WHERE EventId > " + arg(opts.AfterEventID)
+	if opts.TreeID != 0 {
+		query += " AND TreeId = " + arg(opts.TreeID)
+	}
+	query += " ORDER BY EventId"
+	if opts.Limit > 0 {
+		query += " LIMIT " + arg(opts.Limit)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []*trillian.AdminAuditEvent{}
+	for rows.Next() {
+		var id int64
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		event := &trillian.AdminAuditEvent{}
+		if err := proto.Unmarshal(data, event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal audit event %d: %v", id, err)
+		}
+		event.EventId = id
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// AuditEventRow returns the values of the TreeId, EventTimeMillis and Event
+// columns of the AdminAuditEvents table row keeping event.
+func AuditEventRow(event *trillian.AdminAuditEvent) (treeID, eventTimeMillis int64, data []byte, err error) {
+	if err := event.EventTime.CheckValid(); err != nil {
+		return 0, 0, nil, fmt.Errorf("invalid event_time: %v", err)
+	}
+	data, err = proto.Marshal(event)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return event.TreeId, ToMillisSinceEpoch(event.EventTime.AsTime()), data, nil
+}
+
+// QueryTreeStats returns the statistics of the given tree. The sizes of the
+// leaf data and tiles are computed by scanning all the rows of the tree. Some
+// databases sum them as decimals, which are scanned from their text form.
+func QueryTreeStats(ctx context.Context, db *sql.DB, treeID int64, ph Placeholder) (*TreeStats, error) {
+	query := fmt.Sprintf(`SELECT
+	(SELECT COUNT(*) FROM SequencedLeafData WHERE TreeId = %s),
+	(SELECT COUNT(*) FROM Unsequenced WHERE TreeId = %s),
+	(SELECT MIN(QueueTimestampNanos) FROM Unsequenced WHERE TreeId = %s),
+	(SELECT MAX(TreeHeadTimestamp) FROM TreeHead WHERE TreeId = %s),
+	(SELECT COALESCE(SUM(LENGTH(LeafValue) + COALESCE(LENGTH(ExtraData), 0)), 0) FROM LeafData WHERE TreeId = %s),
+	(SELECT COALESCE(SUM(LENGTH(Nodes)), 0) FROM Subtree WHERE TreeId = %s)`,
+		ph(1), ph(2), ph(3), ph(4), ph(5), ph(6))
+
+	var stats TreeStats
+	var oldest, latest sql.NullInt64
+	if err := db.QueryRowContext(ctx, query, treeID, treeID, treeID, treeID, treeID, treeID).Scan(
+		&stats.LeafCount, &stats.QueueDepth, &oldest, &latest, &stats.LeafBytes, &stats.TileBytes); err != nil {
+		return nil, err
+	}
+	if oldest.Valid {
+		stats.OldestQueued = time.Unix(0, oldest.Int64)
+	}
+	if latest.Valid {
+		stats.LatestRoot = time.Unix(0, latest.Int64)
+	}
+	return &stats, nil
+}
@@ -0,0 +1,12 @@
+-- Caution - this removes all tables in our schema
+
+DROP TABLE IF EXISTS SchemaVersion;
+DROP TABLE IF EXISTS Unsequenced;
+DROP TABLE IF EXISTS Subtree;
+DROP TABLE IF EXISTS SequencedLeafData;
+DROP TABLE IF EXISTS TreeHead;
+DROP TABLE IF EXISTS LeafData;
+DROP TABLE IF EXISTS TreeControl;
+DROP TABLE IF EXISTS AdminAuditEvents;
+DROP TABLE IF EXISTS TreeLabels;
+DROP TABLE IF EXISTS Trees;
@@ -0,0 +1,172 @@
+//go:build cgo
+// +build cgo
+
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/compression"
+	"github.com/google/trillian/storage/storagepb"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/types"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestLeafCompression(t *testing.T) {
+	ctx := context.Background()
+	cleanTestDB(DB)
+	as := NewAdminStorage(DB)
+	settings, err := anypb.New(&storagepb.LogStorageSettings{LeafCompression: storagepb.LeafCompression_ZSTD})
+	if err != nil {
+		t.Fatalf("anypb.New(): %v", err)
+	}
+	tree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	tree.StorageSettings = settings
+	tree = mustCreateTree(ctx, t, as, tree)
+	// The log storage must use the settings stored along with the tree.
+	tree, err = storage.GetTree(ctx, as, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree(): %v", err)
+	}
+	if a, err := compression.Algorithm(tree); err != nil || a != storagepb.LeafCompression_ZSTD {
+		t.Fatalf("Algorithm() of the stored tree = %v, %v, want %v", a, err, storagepb.LeafCompression_ZSTD)
+	}
+	s := NewLogStorage(DB, nil)
+	mustSignAndStoreLogRoot(ctx, t, s, tree, 0)
+
+	value := []byte(strings.Repeat("compressible value ", 50))
+	extra := []byte(strings.Repeat("compressible extra ", 50))
+	id := sha256.Sum256(value)
+	hash := sha256.Sum256(id[:])
+	leaf := &trillian.LogLeaf{LeafValue: value, ExtraData: extra, LeafIdentityHash: id[:], MerkleLeafHash: hash[:], LeafIndex: 0}
+	if _, err := s.AddSequencedLeaves(ctx, tree, []*trillian.LogLeaf{leaf}, fakeQueueTime); err != nil {
+		t.Fatalf("AddSequencedLeaves(): %v", err)
+	}
+	runLogTX(s, tree, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		root, err := (&types.LogRootV1{TreeSize: 1, RootHash: []byte{0}, TimestampNanos: 1}).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: root})
+	})
+
+	var storedValue, storedExtra []byte
+	if err := DB.QueryRowContext(ctx, "SELECT LeafValue, ExtraData FROM LeafData WHERE TreeId = ? AND LeafIdentityHash = ?", tree.TreeId, id[:]).Scan(&storedValue, &storedExtra); err != nil {
+		t.Fatalf("Failed to read leaf data: %v", err)
+	}
+	if len(storedValue) >= len(value) {
+		t.Errorf("Stored value of %d bytes is not compressed", len(storedValue))
+	}
+	if len(storedExtra) >= len(extra) {
+		t.Errorf("Stored extra data of %d bytes is not compressed", len(storedExtra))
+	}
+
+	runLogTX(s, tree, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		leaves, err := tx.GetLeavesByRange(ctx, 0, 1)
+		if err != nil {
+			return err
+		}
+		if len(leaves) != 1 || !bytes.Equal(leaves[0].LeafValue, value) || !bytes.Equal(leaves[0].ExtraData, extra) {
+			t.Errorf("GetLeavesByRange() = %v, want {%q, %q}", leaves, value, extra)
+		}
+
+		byHash, err := tx.GetLeavesByHash(ctx, [][]byte{hash[:]}, false)
+		if err != nil {
+			return err
+		}
+		if len(byHash) != 1 || !bytes.Equal(byHash[0].LeafValue, value) || !bytes.Equal(byHash[0].ExtraData, extra) {
+			t.Errorf("GetLeavesByHash() = %v, want the leaf", byHash)
+		}
+		return nil
+	})
+}
+
+func TestLeafCompressionEnabledLater(t *testing.T) {
+	ctx := context.Background()
+	cleanTestDB(DB)
+	as := NewAdminStorage(DB)
+	tree := mustCreateTree(ctx, t, as, testonly.LogTree)
+	s := NewLogStorage(DB, nil)
+	mustSignAndStoreLogRoot(ctx, t, s, tree, 0)
+
+	var leaves []*trillian.LogLeaf
+	addLeaf := func(tree *trillian.Tree) []byte {
+		t.Helper()
+		index := int64(len(leaves))
+		value := []byte(strings.Repeat(fmt.Sprintf("compressible value %d ", index), 50))
+		id := sha256.Sum256(value)
+		hash := sha256.Sum256(id[:])
+		leaf := &trillian.LogLeaf{LeafValue: value, LeafIdentityHash: id[:], MerkleLeafHash: hash[:], LeafIndex: index}
+		if _, err := s.AddSequencedLeaves(ctx, tree, []*trillian.LogLeaf{leaf}, fakeQueueTime); err != nil {
+			t.Fatalf("AddSequencedLeaves(): %v", err)
+		}
+		leaves = append(leaves, leaf)
+		var stored []byte
+		if err := DB.QueryRowContext(ctx, "SELECT LeafValue FROM LeafData WHERE TreeId = ? AND LeafIdentityHash = ?", tree.TreeId, id[:]).Scan(&stored); err != nil {
+			t.Fatalf("Failed to read leaf data: %v", err)
+		}
+		return stored
+	}
+
+	if stored := addLeaf(tree); !bytes.Equal(stored, leaves[0].LeafValue) {
+		t.Errorf("Leaf stored before enabling compression = %q, want it unchanged", stored)
+	}
+
+	settings, err := anypb.New(&storagepb.LogStorageSettings{LeafCompression: storagepb.LeafCompression_GZIP})
+	if err != nil {
+		t.Fatalf("anypb.New(): %v", err)
+	}
+	if _, err := storage.UpdateTree(ctx, as, tree.TreeId, func(tree *trillian.Tree) {
+		tree.StorageSettings = settings
+	}); err != nil {
+		t.Fatalf("UpdateTree(): %v", err)
+	}
+	tree, err = storage.GetTree(ctx, as, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree(): %v", err)
+	}
+	if stored := addLeaf(tree); len(stored) >= len(leaves[1].LeafValue) {
+		t.Errorf("Leaf of %d bytes stored after enabling compression is not compressed", len(stored))
+	}
+
+	runLogTX(s, tree, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		root, err := (&types.LogRootV1{TreeSize: 2, RootHash: []byte{0}, TimestampNanos: 1}).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return tx.StoreSignedLogRoot(ctx, &trillian.SignedLogRoot{LogRoot: root})
+	})
+	runLogTX(s, tree, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		got, err := tx.GetLeavesByRange(ctx, 0, 2)
+		if err != nil {
+			return err
+		}
+		if len(got) != 2 || !bytes.Equal(got[0].LeafValue, leaves[0].LeafValue) || !bytes.Equal(got[1].LeafValue, leaves[1].LeafValue) {
+			t.Errorf("GetLeavesByRange() = %v, want %v", got, leaves)
+		}
+		return nil
+	})
+}
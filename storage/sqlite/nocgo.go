@@ -0,0 +1,39 @@
+//go:build !cgo
+// +build !cgo
+
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"errors"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"k8s.io/klog/v2"
+)
+
+// StorageProviderName is the name of the storage provider.
+const StorageProviderName = "sqlite"
+
+func init() {
+	if err := storage.RegisterProvider(StorageProviderName, newSQLiteStorageProvider); err != nil {
+		klog.Fatalf("Failed to register storage provider sqlite: %v", err)
+	}
+}
+
+func newSQLiteStorageProvider(monitoring.MetricFactory) (storage.Provider, error) {
+	return nil, errors.New("the sqlite storage provider requires cgo, which this binary was built without")
+}
@@ -0,0 +1,103 @@
+//go:build cgo
+// +build cgo
+
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"database/sql"
+	"flag"
+	"sync"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// StorageProviderName is the name of the storage provider.
+	StorageProviderName = "sqlite"
+)
+
+var (
+	sqliteURI = flag.String("sqlite_uri", "file:trillian.db", "Path or URI of the SQLite database file, which is created if it does not exist")
+
+	sqliteMu              sync.Mutex
+	sqliteErr             error
+	sqliteDB              *sql.DB
+	sqliteStorageInstance *sqliteProvider
+)
+
+// GetDatabase returns an instance of SQLite database, or creates one.
+func GetDatabase() (*sql.DB, error) {
+	sqliteMu.Lock()
+	defer sqliteMu.Unlock()
+	return getSQLiteDatabaseLocked()
+}
+
+func init() {
+	if err := storage.RegisterProvider(StorageProviderName, newSQLiteStorageProvider); err != nil {
+		klog.Fatalf("Failed to register storage provider sqlite: %v", err)
+	}
+}
+
+type sqliteProvider struct {
+	db *sql.DB
+	mf monitoring.MetricFactory
+}
+
+func newSQLiteStorageProvider(mf monitoring.MetricFactory) (storage.Provider, error) {
+	sqliteMu.Lock()
+	defer sqliteMu.Unlock()
+	if sqliteStorageInstance == nil {
+		db, err := getSQLiteDatabaseLocked()
+		if err != nil {
+			return nil, err
+		}
+		sqliteStorageInstance = &sqliteProvider{
+			db: db,
+			mf: mf,
+		}
+	}
+	return sqliteStorageInstance, nil
+}
+
+// getSQLiteDatabaseLocked returns an instance of SQLite database, or creates
+// one. Requires sqliteMu to be locked.
+func getSQLiteDatabaseLocked() (*sql.DB, error) {
+	if sqliteDB != nil || sqliteErr != nil {
+		return sqliteDB, sqliteErr
+	}
+	db, err := OpenDB(*sqliteURI)
+	if err != nil {
+		sqliteErr = err
+		return nil, err
+	}
+	sqliteDB, sqliteErr = db, nil
+	return db, nil
+}
+
+func (s *sqliteProvider) LogStorage() storage.LogStorage {
+	return NewLogStorage(s.db, s.mf)
+}
+
+func (s *sqliteProvider) AdminStorage() storage.AdminStorage {
+	return NewAdminStorage(s.db)
+}
+
+func (s *sqliteProvider) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,95 @@
+//go:build cgo
+// +build cgo
+
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"context"
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/testonly/flagsaver"
+)
+
+func TestSQLiteStorageProviderErrorPersistence(t *testing.T) {
+	defer flagsaver.Save().MustRestore()
+	defer resetProvider()
+	resetProvider()
+	if err := flag.Set("sqlite_uri", filepath.Join(t.TempDir(), "missing", "trillian.db")); err != nil {
+		t.Errorf("Failed to set flag: %v", err)
+	}
+
+	// First call: This should fail due to the directory not existing.
+	_, err1 := storage.NewProvider(StorageProviderName, nil)
+	if err1 == nil {
+		t.Fatalf("Expected 'storage.NewProvider' to fail")
+	}
+
+	// Second call: This should fail with the same error.
+	_, err2 := storage.NewProvider(StorageProviderName, nil)
+	if err2 == nil {
+		t.Fatalf("Expected second call to 'storage.NewProvider' to fail")
+	}
+
+	if err2 != err1 {
+		t.Fatalf("Expected second call to 'storage.NewProvider' to fail with %q, instead got: %q", err1, err2)
+	}
+}
+
+func TestSQLiteStorageProviderPersistsData(t *testing.T) {
+	defer flagsaver.Save().MustRestore()
+	defer resetProvider()
+	ctx := context.Background()
+	if err := flag.Set("sqlite_uri", filepath.Join(t.TempDir(), "trillian.db")); err != nil {
+		t.Errorf("Failed to set flag: %v", err)
+	}
+
+	resetProvider()
+	sp, err := storage.NewProvider(StorageProviderName, nil)
+	if err != nil {
+		t.Fatalf("NewProvider(): %v", err)
+	}
+	tree, err := storage.CreateTree(ctx, sp.AdminStorage(), testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	if err := sp.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+
+	// Re-open the same database file, as a restarted server would.
+	resetProvider()
+	sp, err = storage.NewProvider(StorageProviderName, nil)
+	if err != nil {
+		t.Fatalf("NewProvider() after restart: %v", err)
+	}
+	defer sp.Close()
+	if _, err := storage.GetTree(ctx, sp.AdminStorage(), tree.TreeId); err != nil {
+		t.Errorf("GetTree() after restart: %v", err)
+	}
+}
+
+// resetProvider forgets the database opened by the provider, so that the next
+// provider instance opens it again.
+func resetProvider() {
+	sqliteMu.Lock()
+	defer sqliteMu.Unlock()
+	sqliteDB, sqliteErr, sqliteStorageInstance = nil, nil, nil
+}
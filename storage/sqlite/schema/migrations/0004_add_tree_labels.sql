@@ -0,0 +1,10 @@
+-- Adds the table holding the labels of trees. Binaries which read and write
+-- labels need this version.
+
+CREATE TABLE IF NOT EXISTS TreeLabels(
+  TreeId               BIGINT NOT NULL,
+  LabelKey             TEXT NOT NULL,
+  LabelValue           TEXT NOT NULL,
+  PRIMARY KEY(TreeId, LabelKey),
+  FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
+);
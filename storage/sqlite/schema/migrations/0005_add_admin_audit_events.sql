@@ -0,0 +1,13 @@
+-- Adds the audit log of the changes made to trees. Binaries which record or
+-- list admin audit events need this version.
+
+CREATE TABLE IF NOT EXISTS AdminAuditEvents(
+  EventId              INTEGER PRIMARY KEY AUTOINCREMENT,
+  TreeId               BIGINT NOT NULL,
+  EventTimeMillis      BIGINT NOT NULL,
+  -- The serialized AdminAuditEvent.
+  Event                BLOB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS AdminAuditEventsTreeIdx
+  ON AdminAuditEvents(TreeId, EventId);
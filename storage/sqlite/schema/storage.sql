@@ -0,0 +1,162 @@
+-- SQLite version of the tree schema
+--
+-- This script creates the latest version of the schema in an empty database.
+-- Existing databases are upgraded with the migrations in the migrations
+-- directory when they are opened.
+
+-- ---------------------------------------------
+-- Schema version
+-- ---------------------------------------------
+
+-- Each row is a version of the schema which has been applied to the database,
+-- either by this script or by a migration. The highest one is current. The
+-- version of this script is only recorded if it creates the Trees table, as
+-- an existing database may be older, and must be upgraded by the migrations.
+CREATE TABLE IF NOT EXISTS SchemaVersion(
+  Version              INTEGER NOT NULL,
+  Description          VARCHAR(255) NOT NULL,
+  AppliedTimeMillis    BIGINT NOT NULL,
+  PRIMARY KEY(Version)
+);
+
+INSERT OR IGNORE INTO SchemaVersion(Version, Description, AppliedTimeMillis)
+  SELECT 6, 'storage.sql', CAST(strftime('%s', 'now') AS INTEGER) * 1000
+  WHERE NOT EXISTS (SELECT * FROM sqlite_master
+    WHERE type = 'table' AND name = 'Trees');
+
+-- ---------------------------------------------
+-- Tree stuff here
+-- ---------------------------------------------
+
+-- Tree parameters should not be changed after creation. Doing so can
+-- render the data in the tree unusable or inconsistent.
+CREATE TABLE IF NOT EXISTS Trees(
+  TreeId                BIGINT NOT NULL,
+  TreeState             TEXT NOT NULL CHECK(TreeState IN ('ACTIVE', 'FROZEN', 'DRAINING')),
+  TreeType              TEXT NOT NULL CHECK(TreeType IN ('LOG', 'PREORDERED_LOG')),
+  HashStrategy          TEXT NOT NULL CHECK(HashStrategy IN ('RFC6962_SHA256')),
+  HashAlgorithm         TEXT NOT NULL CHECK(HashAlgorithm IN ('SHA256')),
+  SignatureAlgorithm    TEXT NOT NULL CHECK(SignatureAlgorithm IN ('ECDSA', 'RSA', 'ED25519')),
+  DisplayName           TEXT,
+  Description           TEXT,
+  CreateTimeMillis      BIGINT NOT NULL,
+  UpdateTimeMillis      BIGINT NOT NULL,
+  MaxRootDurationMillis BIGINT NOT NULL,
+  PublicKey             BLOB NOT NULL,
+  Deleted               BOOLEAN,
+  DeleteTimeMillis      BIGINT,
+  StorageSettings       BLOB,
+  PRIMARY KEY(TreeId)
+);
+
+-- Labels of trees, which ListTrees can select them by.
+CREATE TABLE IF NOT EXISTS TreeLabels(
+  TreeId               BIGINT NOT NULL,
+  LabelKey             TEXT NOT NULL,
+  LabelValue           TEXT NOT NULL,
+  PRIMARY KEY(TreeId, LabelKey),
+  FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
+);
+
+-- Audit log of the changes made to trees through the admin API, and of their
+-- hard deletion. Events are kept after their trees are deleted, so TreeId
+-- doesn't reference Trees.
+CREATE TABLE IF NOT EXISTS AdminAuditEvents(
+  EventId              INTEGER PRIMARY KEY AUTOINCREMENT,
+  TreeId               BIGINT NOT NULL,
+  EventTimeMillis      BIGINT NOT NULL,
+  -- The serialized AdminAuditEvent.
+  Event                BLOB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS AdminAuditEventsTreeIdx
+  ON AdminAuditEvents(TreeId, EventId);
+
+CREATE TABLE IF NOT EXISTS Subtree(
+  TreeId               BIGINT NOT NULL,
+  SubtreeId            BLOB NOT NULL,
+  Nodes                BLOB NOT NULL,
+  SubtreeRevision      INTEGER NOT NULL,
+  PRIMARY KEY(TreeId, SubtreeId, SubtreeRevision),
+  FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
+);
+
+-- The TreeRevisionIdx is used to enforce that there is only one STH at any
+-- tree revision
+CREATE TABLE IF NOT EXISTS TreeHead(
+  TreeId               BIGINT NOT NULL,
+  TreeHeadTimestamp    BIGINT,
+  TreeSize             BIGINT,
+  RootHash             BLOB NOT NULL,
+  TreeRevision         BIGINT,
+  PRIMARY KEY(TreeId, TreeHeadTimestamp),
+  FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS TreeHeadRevisionIdx
+  ON TreeHead(TreeId, TreeRevision);
+
+-- ---------------------------------------------
+-- Log specific stuff here
+-- ---------------------------------------------
+
+-- A leaf that has not been sequenced has a row in this table. If duplicate leaves
+-- are allowed they will all reference this row.
+CREATE TABLE IF NOT EXISTS LeafData(
+  TreeId               BIGINT NOT NULL,
+  -- This is a personality specific has of some subset of the leaf data.
+  -- It's only purpose is to allow Trillian to identify duplicate entries in
+  -- the context of the personality.
+  LeafIdentityHash     BLOB NOT NULL,
+  -- This is the data stored in the leaf for example in CT it contains a DER encoded
+  -- X.509 certificate but is application dependent
+  LeafValue            BLOB NOT NULL,
+  -- This is extra data that the application can associate with the leaf should it wish to.
+  -- This data is not included in signing and hashing.
+  ExtraData            BLOB,
+  -- The timestamp from when this leaf data was first queued for inclusion.
+  QueueTimestampNanos  BIGINT NOT NULL,
+  PRIMARY KEY(TreeId, LeafIdentityHash),
+  FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
+);
+
+-- When a leaf is sequenced a row is added to this table. If logs allow duplicates then
+-- multiple rows will exist with different sequence numbers. The signed timestamp
+-- will be communicated via the unsequenced table as this might need to be unique, depending
+-- on the log parameters and we can't insert into this table until we have the sequence number
+-- which is not available at the time we queue the entry. We need both hashes because the
+-- LeafData table is keyed by the raw data hash.
+CREATE TABLE IF NOT EXISTS SequencedLeafData(
+  TreeId               BIGINT NOT NULL,
+  SequenceNumber       BIGINT NOT NULL,
+  -- This is a personality specific has of some subset of the leaf data.
+  -- It's only purpose is to allow Trillian to identify duplicate entries in
+  -- the context of the personality.
+  LeafIdentityHash     BLOB NOT NULL,
+  -- This is a MerkleLeafHash as defined by the treehasher that the log uses. For example for
+  -- CT this hash will include the leaf prefix byte as well as the leaf data.
+  MerkleLeafHash       BLOB NOT NULL,
+  IntegrateTimestampNanos BIGINT NOT NULL,
+  PRIMARY KEY(TreeId, SequenceNumber),
+  FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE,
+  FOREIGN KEY(TreeId, LeafIdentityHash) REFERENCES LeafData(TreeId, LeafIdentityHash) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS SequencedLeafMerkleIdx
+  ON SequencedLeafData(TreeId, MerkleLeafHash);
+
+CREATE TABLE IF NOT EXISTS Unsequenced(
+  TreeId               BIGINT NOT NULL,
+  -- The bucket field is to allow the use of time based ring bucketed schemes if desired. If
+  -- unused this should be set to zero for all entries.
+  Bucket               INTEGER NOT NULL,
+  -- This is a personality specific hash of some subset of the leaf data.
+  -- It's only purpose is to allow Trillian to identify duplicate entries in
+  -- the context of the personality.
+  LeafIdentityHash     BLOB NOT NULL,
+  -- This is a MerkleLeafHash as defined by the treehasher that the log uses. For example for
+  -- CT this hash will include the leaf prefix byte as well as the leaf data.
+  MerkleLeafHash       BLOB NOT NULL,
+  QueueTimestampNanos  BIGINT NOT NULL,
+  PRIMARY KEY (TreeId, Bucket, QueueTimestampNanos, LeafIdentityHash)
+);
@@ -0,0 +1,178 @@
+//go:build cgo
+// +build cgo
+
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+)
+
+func TestSchemaScriptVersion(t *testing.T) {
+	m := regexp.MustCompile(`INTO SchemaVersion\(Version, Description, AppliedTimeMillis\)\s+SELECT (\d+),`).FindStringSubmatch(schemaSQL)
+	if m == nil {
+		t.Fatal("schema/storage.sql does not record its version")
+	}
+	if got, want := m[1], strconv.Itoa(versionedSchema.LatestVersion()); got != want {
+		t.Errorf("schema/storage.sql records version %s, want %s", got, want)
+	}
+}
+
+func TestOpenDBMigratesSchema(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Create a database with the oldest schema the migrations upgrade, by
+	// undoing the later ones, holding a tree.
+	oldPath := filepath.Join(dir, "old.db")
+	db, err := OpenDB(oldPath)
+	if err != nil {
+		t.Fatalf("OpenDB(old): %v", err)
+	}
+	for _, stmt := range []string{
+		"ALTER TABLE Trees DROP COLUMN StorageSettings",
+		"DROP TABLE AdminAuditEvents",
+		"DROP TABLE TreeLabels",
+		"DELETE FROM SchemaVersion",
+		fmt.Sprintf("INSERT INTO SchemaVersion(Version, Description, AppliedTimeMillis) VALUES(%d, 'storage.sql', 0)", versionedSchema.OldestVersion()),
+		`INSERT INTO Trees(TreeId, TreeState, TreeType, HashStrategy, HashAlgorithm,
+		SignatureAlgorithm, CreateTimeMillis, UpdateTimeMillis, MaxRootDurationMillis, PublicKey)
+		VALUES(1, 'ACTIVE', 'LOG', 'RFC6962_SHA256', 'SHA256', 'ECDSA', 0, 0, 0, x'')`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("Exec(%q): %v", stmt, err)
+		}
+	}
+	if v, err := versionedSchema.Version(ctx, db); err != nil || v != versionedSchema.OldestVersion() {
+		t.Fatalf("Version() = %v, %v; want %v, nil", v, err, versionedSchema.OldestVersion())
+	}
+	db.Close()
+
+	old, err := OpenDB(oldPath)
+	if err != nil {
+		t.Fatalf("OpenDB(old): %v", err)
+	}
+	defer old.Close()
+	fresh, err := OpenDB(filepath.Join(dir, "fresh.db"))
+	if err != nil {
+		t.Fatalf("OpenDB(fresh): %v", err)
+	}
+	defer fresh.Close()
+
+	for _, db := range []*sql.DB{old, fresh} {
+		if v, err := versionedSchema.Version(ctx, db); err != nil || v != versionedSchema.LatestVersion() {
+			t.Errorf("Version() = %v, %v; want %v, nil", v, err, versionedSchema.LatestVersion())
+		}
+		if err := versionedSchema.Check(ctx, db); err != nil {
+			t.Errorf("Check(): %v", err)
+		}
+	}
+	if diff := cmp.Diff(dumpSchema(t, fresh), dumpSchema(t, old)); diff != "" {
+		t.Errorf("Migrated schema differs from the created one (-created +migrated):\n%s", diff)
+	}
+
+	// The tree survives the migration, and the storage works with it.
+	as := NewAdminStorage(old)
+	if _, err := storage.GetTree(ctx, as, 1); err != nil {
+		t.Errorf("GetTree(): %v", err)
+	}
+	if _, err := storage.CreateTree(ctx, as, testonly.LogTree); err != nil {
+		t.Errorf("CreateTree(): %v", err)
+	}
+
+	// Reopening a database at the latest version leaves it alone.
+	again, err := OpenDB(oldPath)
+	if err != nil {
+		t.Fatalf("OpenDB(again): %v", err)
+	}
+	again.Close()
+}
+
+func TestSchemaScriptIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenDB(): %v", err)
+	}
+	defer db.Close()
+
+	// Running the script again records no version.
+	if _, err := db.ExecContext(ctx, schemaSQL); err != nil {
+		t.Fatalf("Failed to run the schema script again: %v", err)
+	}
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM SchemaVersion").Scan(&count); err != nil || count != 1 {
+		t.Errorf("SchemaVersion has %d rows, %v; want 1, nil", count, err)
+	}
+
+	// Nor does running it on an older database.
+	if _, err := db.ExecContext(ctx, "DELETE FROM SchemaVersion"); err != nil {
+		t.Fatalf("Failed to delete versions: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, schemaSQL); err != nil {
+		t.Fatalf("Failed to run the schema script again: %v", err)
+	}
+	if v, err := versionedSchema.Version(ctx, db); err != nil || v != 1 {
+		t.Errorf("Version() = %v, %v; want 1, nil", v, err)
+	}
+}
+
+// dumpSchema returns the columns of each table in the database.
+func dumpSchema(t *testing.T, db *sql.DB) map[string][]string {
+	t.Helper()
+	ctx := context.Background()
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		t.Fatalf("Failed to list tables: %v", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("Scan(): %v", err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+
+	schema := make(map[string][]string)
+	for _, table := range tables {
+		cols, err := db.QueryContext(ctx, "SELECT name, type, \"notnull\", pk FROM pragma_table_info(?)", table)
+		if err != nil {
+			t.Fatalf("Failed to list columns of %s: %v", table, err)
+		}
+		for cols.Next() {
+			var name, typ string
+			var notNull, pk int
+			if err := cols.Scan(&name, &typ, &notNull, &pk); err != nil {
+				t.Fatalf("Scan(): %v", err)
+			}
+			schema[table] = append(schema[table], fmt.Sprintf("%s %s notnull=%d pk=%d", name, typ, notNull, pk))
+		}
+		cols.Close()
+	}
+	return schema
+}
@@ -0,0 +1,368 @@
+//go:build cgo
+// +build cgo
+
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	_ "embed" // Needed for the schema.
+	"encoding/base64"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/storage/storagepb"
+	"github.com/google/trillian/storage/tree"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/klog/v2"
+
+	_ "github.com/mattn/go-sqlite3" // Register the SQLite driver.
+)
+
+// busyTimeout is how long an operation waits for a lock held by another
+// process sharing the database file before failing.
+const busyTimeout = 5 * time.Second
+
+// schemaSQL creates the latest version of the schema in an empty database.
+//
+//go:embed schema/storage.sql
+var schemaSQL string
+
+// These statements are fixed
+const (
+	insertSubtreeMultiSQL = `INSERT INTO Subtree(TreeId, SubtreeId, Nodes, SubtreeRevision) ` + placeholderSQL
+	insertTreeHeadSQL     = `INSERT INTO TreeHead(TreeId,TreeHeadTimestamp,TreeSize,RootHash,TreeRevision)
+		 VALUES(?,?,?,?,?)`
+
+	selectSubtreeSQL = `
+ SELECT x.SubtreeId, x.MaxRevision, Subtree.Nodes
+ FROM (
+ 	SELECT n.TreeId, n.SubtreeId, max(n.SubtreeRevision) AS MaxRevision
+	FROM Subtree n
+	WHERE n.SubtreeId IN (` + placeholderSQL + `) AND
+	 n.TreeId = ? AND n.SubtreeRevision <= ?
+	GROUP BY n.TreeId, n.SubtreeId
+ ) AS x
+ INNER JOIN Subtree 
+ ON Subtree.SubtreeId = x.SubtreeId 
+ AND Subtree.SubtreeRevision = x.MaxRevision 
+ AND Subtree.TreeId = x.TreeId
+ AND Subtree.TreeId = ?`
+	placeholderSQL = "<placeholder>"
+)
+
+// sqliteTreeStorage contains functionality which is common to all the tree
+// types.
+type sqliteTreeStorage struct {
+	db *sql.DB
+}
+
+// OpenDB opens the SQLite database at the given path or URI, and creates the
+// tree schema in it if it does not exist yet, or upgrades it to the latest
+// version.
+//
+// SQLite allows a single writer at a time, so the returned handle funnels all
+// the operations through one connection. This also keeps in-memory databases,
+// which are private to the connection that created them, intact. Processes
+// sharing a database file wait up to busyTimeout for each other's locks.
+func OpenDB(dbURI string) (*sql.DB, error) {
+	sep := "?"
+	if strings.Contains(dbURI, "?") {
+		sep = "&"
+	}
+	// Foreign keys are needed for ON DELETE CASCADE. Write-ahead logging lets
+	// other processes read while the database is being written to. Taking the
+	// write lock at the start of every transaction avoids failing to upgrade a
+	// read lock held by a concurrent transaction in another process.
+	params := fmt.Sprintf("_foreign_keys=1&_journal_mode=WAL&_txlock=immediate&_busy_timeout=%d", busyTimeout.Milliseconds())
+	db, err := sql.Open("sqlite3", dbURI+sep+params)
+	if err != nil {
+		// Don't log uri as it could contain credentials
+		klog.Warningf("Could not open SQLite database, check config: %s", err)
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	if err := createOrMigrateSchema(context.TODO(), db); err != nil {
+		klog.Warningf("Failed to create schema in SQLite database: %s", err)
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func newTreeStorage(db *sql.DB) *sqliteTreeStorage {
+	return &sqliteTreeStorage{db: db}
+}
+
+// expandPlaceholderSQL expands an sql statement by adding a specified number of '?'
+// placeholder slots. At most one placeholder will be expanded.
+func expandPlaceholderSQL(sql string, num int, first, rest string) string {
+	if num <= 0 {
+		panic(fmt.Errorf("trying to expand SQL placeholder with <= 0 parameters: %s", sql))
+	}
+
+	parameters := first + strings.Repeat(","+rest, num-1)
+
+	return strings.Replace(sql, placeholderSQL, parameters, 1)
+}
+
+func (m *sqliteTreeStorage) beginTreeTx(ctx context.Context, tree *trillian.Tree, hashSizeBytes int, subtreeCache *cache.SubtreeCache) (treeTX, error) {
+	t, err := m.db.BeginTx(ctx, nil /* opts */)
+	if err != nil {
+		klog.Warningf("Could not start tree TX: %s", err)
+		return treeTX{}, err
+	}
+	return treeTX{
+		tx:            t,
+		mu:            &sync.Mutex{},
+		ts:            m,
+		treeID:        tree.TreeId,
+		treeType:      tree.TreeType,
+		hashSizeBytes: hashSizeBytes,
+		subtreeCache:  subtreeCache,
+		writeRevision: -1,
+	}, nil
+}
+
+type treeTX struct {
+	// mu ensures that tx can only be used for one query/exec at a time.
+	mu            *sync.Mutex
+	closed        bool
+	tx            *sql.Tx
+	ts            *sqliteTreeStorage
+	treeID        int64
+	treeType      trillian.TreeType
+	hashSizeBytes int
+	subtreeCache  *cache.SubtreeCache
+	writeRevision int64
+}
+
+func (t *treeTX) getSubtrees(ctx context.Context, treeRevision int64, ids [][]byte) ([]*storagepb.SubtreeProto, error) {
+	klog.V(2).Infof("getSubtrees(len(ids)=%d)", len(ids))
+	klog.V(4).Infof("getSubtrees(")
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	stx, err := t.tx.PrepareContext(ctx, expandPlaceholderSQL(selectSubtreeSQL, len(ids), "?", "?"))
+	if err != nil {
+		return nil, err
+	}
+	defer stx.Close()
+
+	args := make([]interface{}, 0, len(ids)+3)
+
+	// populate args with ids.
+	for _, id := range ids {
+		klog.V(4).Infof("  id: %x", id)
+		args = append(args, id)
+	}
+
+	args = append(args, t.treeID)
+	args = append(args, treeRevision)
+	args = append(args, t.treeID)
+
+	rows, err := stx.QueryContext(ctx, args...)
+	if err != nil {
+		klog.Warningf("Failed to get merkle subtrees: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Err() != nil {
+		// Nothing from the DB
+		klog.Warningf("Nothing from DB: %s", rows.Err())
+		return nil, rows.Err()
+	}
+
+	ret := make([]*storagepb.SubtreeProto, 0, len(ids))
+
+	for rows.Next() {
+		var subtreeIDBytes []byte
+		var subtreeRev int64
+		var nodesRaw []byte
+		if err := rows.Scan(&subtreeIDBytes, &subtreeRev, &nodesRaw); err != nil {
+			klog.Warningf("Failed to scan merkle subtree: %s", err)
+			return nil, err
+		}
+		var subtree storagepb.SubtreeProto
+		if err := proto.Unmarshal(nodesRaw, &subtree); err != nil {
+			klog.Warningf("Failed to unmarshal SubtreeProto: %s", err)
+			return nil, err
+		}
+		if subtree.Prefix == nil {
+			subtree.Prefix = []byte{}
+		}
+		ret = append(ret, &subtree)
+
+		if klog.V(4).Enabled() {
+			klog.Infof("  subtree: NID: %x, prefix: %x, depth: %d",
+				subtreeIDBytes, subtree.Prefix, subtree.Depth)
+			for k, v := range subtree.Leaves {
+				b, err := base64.StdEncoding.DecodeString(k)
+				if err != nil {
+					klog.Errorf("base64.DecodeString(%v): %v", k, err)
+				}
+				klog.Infof("     %x: %x", b, v)
+			}
+		}
+	}
+
+	// The InternalNodes cache is possibly nil here, but the SubtreeCache (which called
+	// this method) will re-populate it.
+	return ret, nil
+}
+
+func (t *treeTX) storeSubtrees(ctx context.Context, subtrees []*storagepb.SubtreeProto) error {
+	klog.V(2).Infof("storeSubtrees(len(subtrees)=%d)", len(subtrees))
+	if klog.V(4).Enabled() {
+		klog.Infof("storeSubtrees(")
+		for _, s := range subtrees {
+			klog.Infof("  prefix: %x, depth: %d", s.Prefix, s.Depth)
+			for k, v := range s.Leaves {
+				b, err := base64.StdEncoding.DecodeString(k)
+				if err != nil {
+					klog.Errorf("base64.DecodeString(%v): %v", k, err)
+				}
+				klog.Infof("     %x: %x", b, v)
+			}
+		}
+	}
+	if len(subtrees) == 0 {
+		return nil
+	}
+
+	// TODO(al): probably need to be able to batch this in the case where we have
+	// a really large number of subtrees to store.
+	args := make([]interface{}, 0, len(subtrees))
+
+	for _, s := range subtrees {
+		s := s
+		if s.Prefix == nil {
+			panic(fmt.Errorf("nil prefix on %v", s))
+		}
+		subtreeBytes, err := proto.Marshal(s)
+		if err != nil {
+			return err
+		}
+		args = append(args, t.treeID)
+		args = append(args, s.Prefix)
+		args = append(args, subtreeBytes)
+		args = append(args, t.writeRevision)
+	}
+
+	stx, err := t.tx.PrepareContext(ctx, expandPlaceholderSQL(insertSubtreeMultiSQL, len(subtrees), "VALUES(?, ?, ?, ?)", "(?, ?, ?, ?)"))
+	if err != nil {
+		return err
+	}
+	defer stx.Close()
+
+	r, err := stx.ExecContext(ctx, args...)
+	if err != nil {
+		klog.Warningf("Failed to set merkle subtrees: %s", err)
+		return err
+	}
+	_, _ = r.RowsAffected()
+	return nil
+}
+
+func checkResultOkAndRowCountIs(res sql.Result, err error, count int64) error {
+	// The Exec() might have just failed
+	if err != nil {
+		return sqliteToGRPC(err)
+	}
+
+	// Otherwise we have to look at the result of the operation
+	rowsAffected, rowsError := res.RowsAffected()
+
+	if rowsError != nil {
+		return sqliteToGRPC(rowsError)
+	}
+
+	if rowsAffected != count {
+		return fmt.Errorf("expected %d row(s) to be affected but saw: %d", count,
+			rowsAffected)
+	}
+
+	return nil
+}
+
+// getSubtreesAtRev returns a GetSubtreesFunc which reads at the passed in rev.
+func (t *treeTX) getSubtreesAtRev(ctx context.Context, rev int64) cache.GetSubtreesFunc {
+	return func(ids [][]byte) ([]*storagepb.SubtreeProto, error) {
+		return t.getSubtrees(ctx, rev, ids)
+	}
+}
+
+func (t *treeTX) SetMerkleNodes(ctx context.Context, nodes []tree.Node) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rev := t.writeRevision - 1
+	return t.subtreeCache.SetNodes(nodes, t.getSubtreesAtRev(ctx, rev))
+}
+
+func (t *treeTX) Commit(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.writeRevision > -1 {
+		tiles, err := t.subtreeCache.UpdatedTiles()
+		if err != nil {
+			klog.Warningf("SubtreeCache updated tiles error: %v", err)
+			return err
+		}
+		if err := t.storeSubtrees(ctx, tiles); err != nil {
+			klog.Warningf("TX commit flush error: %v", err)
+			return err
+		}
+	}
+	t.closed = true
+	if err := t.tx.Commit(); err != nil {
+		klog.Warningf("TX commit error: %s, stack:\n%s", err, string(debug.Stack()))
+		return err
+	}
+	return nil
+}
+
+func (t *treeTX) rollbackInternal() error {
+	t.closed = true
+	if err := t.tx.Rollback(); err != nil {
+		klog.Warningf("TX rollback error: %s, stack:\n%s", err, string(debug.Stack()))
+		return err
+	}
+	return nil
+}
+
+func (t *treeTX) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	err := t.rollbackInternal()
+	if err != nil {
+		klog.Warningf("Rollback error on Close(): %v", err)
+	}
+	return err
+}
@@ -0,0 +1,366 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sqlschema tracks the versions of the schemas used by the SQL storage
+// implementations, and upgrades them with ordered migrations.
+//
+// The current version of a schema is recorded in the SchemaVersion table of
+// the database. Databases created before versions were recorded have a Trees
+// table but no SchemaVersion table, and are at BaselineVersion. Each storage
+// implementation states the oldest version its code works with, so that a
+// migration which is compatible with the previous release (e.g. making a column
+// optional) can be applied while that release is still running, and a later
+// one which is not (e.g. dropping the column) once it has been replaced.
+package sqlschema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BaselineVersion is the version of the schemas created before versions were
+// recorded in the database.
+const BaselineVersion = 1
+
+// createVersionTableSQL creates the table which records the applied versions.
+// The current version of the schema is the highest one.
+const createVersionTableSQL = `CREATE TABLE IF NOT EXISTS SchemaVersion(
+  Version              INTEGER NOT NULL,
+  Description          VARCHAR(255) NOT NULL,
+  AppliedTimeMillis    BIGINT NOT NULL,
+  PRIMARY KEY(Version)
+)`
+
+const selectVersionSQL = "SELECT MAX(Version) FROM SchemaVersion"
+
+// Dialect holds the statements used for managing versions which differ between
+// SQL databases.
+type Dialect struct {
+	// tableExistsSQL counts the tables of the current database which have the
+	// name passed as its only argument.
+	tableExistsSQL string
+	// insertVersionSQL records a version with its description and the time it
+	// was applied at.
+	insertVersionSQL string
+}
+
+var (
+	// MySQL is the dialect of MySQL and MariaDB.
+	MySQL = Dialect{
+		tableExistsSQL:   "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+		insertVersionSQL: "INSERT INTO SchemaVersion(Version, Description, AppliedTimeMillis) VALUES(?, ?, ?)",
+	}
+	// PostgreSQL is the dialect of PostgreSQL and CockroachDB.
+	PostgreSQL = Dialect{
+		tableExistsSQL:   "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND lower(table_name) = lower($1)",
+		insertVersionSQL: "INSERT INTO SchemaVersion(Version, Description, AppliedTimeMillis) VALUES($1, $2, $3)",
+	}
+	// SQLite is the dialect of SQLite.
+	SQLite = Dialect{
+		tableExistsSQL:   "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+		insertVersionSQL: "INSERT INTO SchemaVersion(Version, Description, AppliedTimeMillis) VALUES(?, ?, ?)",
+	}
+)
+
+// Migration upgrades a schema from the previous version to Version.
+type Migration struct {
+	Version     int
+	Description string
+	// Statements are executed in order. They are not run in a transaction, as
+	// not all databases support schema changes in one, so a migration which
+	// fails part of the way through must be completed by hand.
+	Statements []string
+}
+
+// Schema is a versioned SQL schema.
+type Schema struct {
+	dialect    Dialect
+	minVersion int
+	// oldestVersion is the version the first migration upgrades from.
+	oldestVersion int
+	migrations    []Migration
+}
+
+// New returns the schema with the migrations read from the .sql files in the
+// given directory of fsys. The file names start with the version the
+// migration upgrades to, followed by an underscore and a description, e.g.
+// "0002_drop_unused_columns.sql". The versions must follow on from each other
+// without gaps. They usually start from BaselineVersion, but the schema of a
+// storage implementation added after versions were recorded only needs the
+// migrations from the version it was added at. minVersion is the oldest
+// version of the schema which the storage code works with.
+func New(d Dialect, minVersion int, fsys fs.FS, dir string) (*Schema, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]Migration, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".sql")
+		parts := strings.SplitN(name, "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if len(parts) != 2 || err != nil {
+			return nil, fmt.Errorf("migration file name %q does not start with a version", file)
+		}
+		script, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{
+			Version:     version,
+			Description: strings.ReplaceAll(parts[1], "_", " "),
+			Statements:  SplitStatements(string(script)),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	oldest := BaselineVersion
+	if len(migrations) > 0 {
+		oldest = migrations[0].Version - 1
+	}
+	if oldest < BaselineVersion {
+		return nil, fmt.Errorf("migration to version %d found where version %d or later was expected", oldest+1, BaselineVersion+1)
+	}
+	for i, m := range migrations {
+		if want := oldest + 1 + i; m.Version != want {
+			return nil, fmt.Errorf("migration to version %d found where version %d was expected", m.Version, want)
+		}
+	}
+	s := &Schema{dialect: d, minVersion: minVersion, oldestVersion: oldest, migrations: migrations}
+	if minVersion < oldest || minVersion > s.LatestVersion() {
+		return nil, fmt.Errorf("minimum version %d is outside of the known versions [%d, %d]", minVersion, oldest, s.LatestVersion())
+	}
+	return s, nil
+}
+
+// MustNew is like New, but panics on errors. It is meant for migrations which
+// are embedded in the binary.
+func MustNew(d Dialect, minVersion int, fsys fs.FS, dir string) *Schema {
+	s, err := New(d, minVersion, fsys, dir)
+	if err != nil {
+		panic(fmt.Sprintf("sqlschema: %v", err))
+	}
+	return s
+}
+
+// LatestVersion returns the version the migrations upgrade the schema to.
+func (s *Schema) LatestVersion() int {
+	return s.oldestVersion + len(s.migrations)
+}
+
+// OldestVersion returns the oldest version the migrations upgrade the schema
+// from.
+func (s *Schema) OldestVersion() int {
+	return s.oldestVersion
+}
+
+// MinVersion returns the oldest version the storage code works with.
+func (s *Schema) MinVersion() int {
+	return s.minVersion
+}
+
+// Migrations returns the migrations of the schema, in order.
+func (s *Schema) Migrations() []Migration {
+	return s.migrations
+}
+
+// Version returns the version of the schema in the database, or 0 if the
+// database does not contain the schema.
+func (s *Schema) Version(ctx context.Context, db *sql.DB) (int, error) {
+	versioned, err := s.tableExists(ctx, db, "SchemaVersion")
+	if err != nil {
+		return 0, err
+	}
+	if !versioned {
+		created, err := s.tableExists(ctx, db, "Trees")
+		if err != nil || !created {
+			return 0, err
+		}
+		return BaselineVersion, nil
+	}
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, selectVersionSQL).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %v", err)
+	}
+	if !version.Valid {
+		return BaselineVersion, nil
+	}
+	return int(version.Int64), nil
+}
+
+func (s *Schema) tableExists(ctx context.Context, db *sql.DB, name string) (bool, error) {
+	var count int
+	if err := db.QueryRowContext(ctx, s.dialect.tableExistsSQL, name).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to look up table %s: %v", name, err)
+	}
+	return count > 0, nil
+}
+
+// Check returns an error unless the schema in the database has a version
+// which the storage code works with.
+func (s *Schema) Check(ctx context.Context, db *sql.DB) error {
+	version, err := s.Version(ctx, db)
+	switch {
+	case err != nil:
+		return err
+	case version == 0:
+		return fmt.Errorf("database does not contain the Trillian schema")
+	case version > s.LatestVersion():
+		return fmt.Errorf("database schema version %d is newer than the latest known version %d, a newer binary is needed", version, s.LatestVersion())
+	case version < s.minVersion:
+		return fmt.Errorf("database schema version %d is older than the oldest supported version %d, apply the migrations with migrateschema", version, s.minVersion)
+	}
+	return nil
+}
+
+// Migrate applies the migrations which upgrade the schema in the database to
+// the target version, and returns them. A target of 0 means the latest
+// version. The schema must have been created, and must not be newer than the
+// target.
+func (s *Schema) Migrate(ctx context.Context, db *sql.DB, target int) ([]Migration, error) {
+	if target == 0 {
+		target = s.LatestVersion()
+	}
+	if target > s.LatestVersion() {
+		return nil, fmt.Errorf("target version %d is newer than the latest known version %d", target, s.LatestVersion())
+	}
+	version, err := s.Version(ctx, db)
+	switch {
+	case err != nil:
+		return nil, err
+	case version == 0:
+		return nil, fmt.Errorf("database does not contain the Trillian schema, create it first")
+	case version > target:
+		return nil, fmt.Errorf("database schema version %d is newer than the target version %d", version, target)
+	case version < s.oldestVersion:
+		return nil, fmt.Errorf("database schema version %d is older than the oldest version %d with migrations", version, s.oldestVersion)
+	}
+
+	if _, err := db.ExecContext(ctx, createVersionTableSQL); err != nil {
+		return nil, fmt.Errorf("failed to create SchemaVersion table: %v", err)
+	}
+	if err := s.record(ctx, db, version, "baseline"); err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range s.migrations[version-s.oldestVersion : target-s.oldestVersion] {
+		for _, stmt := range m.Statements {
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				return applied, fmt.Errorf("migration to version %d failed running %q: %v", m.Version, stmt, err)
+			}
+		}
+		if err := s.record(ctx, db, m.Version, m.Description); err != nil {
+			return applied, err
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// record inserts the given version into the SchemaVersion table, unless it is
+// there already.
+func (s *Schema) record(ctx context.Context, db *sql.DB, version int, desc string) error {
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM SchemaVersion WHERE Version >= "+strconv.Itoa(version)).Scan(&count); err != nil {
+		return fmt.Errorf("failed to read schema version: %v", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	if _, err := db.ExecContext(ctx, s.dialect.insertVersionSQL, version, desc, now); err != nil {
+		return fmt.Errorf("failed to record schema version %d: %v", version, err)
+	}
+	return nil
+}
+
+// SplitStatements splits an SQL script into its statements. Lines which are
+// empty or only hold a comment are dropped. Statements are separated by
+// semicolons, which must not appear anywhere else in the script.
+func SplitStatements(script string) []string {
+	var b strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	var stmts []string
+	for _, stmt := range strings.Split(b.String(), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
+
+// Storage is a storage implementation with a versioned schema.
+type Storage struct {
+	Schema *Schema
+	// Open returns the database of the storage, as configured by flags.
+	Open func() (*sql.DB, error)
+}
+
+var (
+	storageMu     sync.RWMutex
+	storageByName = make(map[string]Storage)
+)
+
+// Register makes the versioned schema of the named storage provider available
+// to tools which manage it.
+func Register(name string, st Storage) error {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	if _, exists := storageByName[name]; exists {
+		return fmt.Errorf("schema of storage %v already registered", name)
+	}
+	storageByName[name] = st
+	return nil
+}
+
+// Get returns the registered storage with the given name.
+func Get(name string) (Storage, error) {
+	storageMu.RLock()
+	defer storageMu.RUnlock()
+
+	st, ok := storageByName[name]
+	if !ok {
+		return Storage{}, fmt.Errorf("no versioned schema registered for storage %v", name)
+	}
+	return st, nil
+}
+
+// Names returns the names of all the registered storages.
+func Names() []string {
+	storageMu.RLock()
+	defer storageMu.RUnlock()
+
+	r := []string{}
+	for k := range storageByName {
+		r = append(r, k)
+	}
+	sort.Strings(r)
+	return r
+}
@@ -0,0 +1,238 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlschema
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+
+	_ "github.com/mattn/go-sqlite3" // Register the SQLite driver.
+)
+
+var testMigrations = fstest.MapFS{
+	"migrations/0002_add_color.sql": {Data: []byte(`
+-- Trees get a color.
+ALTER TABLE Trees ADD COLUMN Color TEXT;
+UPDATE Trees SET Color = 'red';
+`)},
+	"migrations/0003_add_size.sql": {Data: []byte("ALTER TABLE Trees ADD COLUMN Size INTEGER;\n")},
+}
+
+func openDB(t *testing.T, stmts ...string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open(): %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Exec(%q): %v", stmt, err)
+		}
+	}
+	return db
+}
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		desc       string
+		fsys       fstest.MapFS
+		minVersion int
+		wantErr    string
+	}{
+		{desc: "ok", fsys: testMigrations, minVersion: 3},
+		{desc: "baseline", fsys: fstest.MapFS{}, minVersion: 1},
+		{desc: "no-version", fsys: fstest.MapFS{"migrations/init.sql": {}}, minVersion: 1, wantErr: "does not start with a version"},
+		{desc: "later-start", fsys: fstest.MapFS{"migrations/0003_x.sql": {}, "migrations/0004_y.sql": {}}, minVersion: 2},
+		{desc: "before-baseline", fsys: fstest.MapFS{"migrations/0001_x.sql": {}}, minVersion: 1, wantErr: "version 2 or later was expected"},
+		{desc: "gap", fsys: fstest.MapFS{"migrations/0002_x.sql": {}, "migrations/0004_y.sql": {}}, minVersion: 1, wantErr: "version 3 was expected"},
+		{desc: "duplicate", fsys: fstest.MapFS{"migrations/0002_x.sql": {}, "migrations/0002_y.sql": {}}, minVersion: 1, wantErr: "version 3 was expected"},
+		{desc: "min-too-new", fsys: testMigrations, minVersion: 4, wantErr: "outside of the known versions"},
+		{desc: "min-too-old", fsys: testMigrations, minVersion: 0, wantErr: "outside of the known versions"},
+		{desc: "min-before-start", fsys: fstest.MapFS{"migrations/0003_x.sql": {}}, minVersion: 1, wantErr: "outside of the known versions"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := New(SQLite, tc.minVersion, tc.fsys, "migrations")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("New() = %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New(): %v", err)
+			}
+			if got, want := s.LatestVersion(), s.OldestVersion()+len(tc.fsys); got != want {
+				t.Errorf("LatestVersion() = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	s := MustNew(SQLite, 3, testMigrations, "migrations")
+
+	db := openDB(t)
+	if v, err := s.Version(ctx, db); err != nil || v != 0 {
+		t.Errorf("Version(empty) = %v, %v; want 0, nil", v, err)
+	}
+	if err := s.Check(ctx, db); err == nil {
+		t.Error("Check(empty) succeeded, want error")
+	}
+	if _, err := s.Migrate(ctx, db, 0); err == nil {
+		t.Error("Migrate(empty) succeeded, want error")
+	}
+
+	if _, err := db.Exec("CREATE TABLE Trees(TreeId INTEGER)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO Trees(TreeId) VALUES(1)"); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if v, err := s.Version(ctx, db); err != nil || v != BaselineVersion {
+		t.Errorf("Version(baseline) = %v, %v; want %v, nil", v, err, BaselineVersion)
+	}
+	if err := s.Check(ctx, db); err == nil || !strings.Contains(err.Error(), "older than") {
+		t.Errorf("Check(baseline) = %v, want too old", err)
+	}
+
+	applied, err := s.Migrate(ctx, db, 2)
+	if err != nil {
+		t.Fatalf("Migrate(2): %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != 2 || applied[0].Description != "add color" {
+		t.Errorf("Migrate(2) applied %+v, want version 2", applied)
+	}
+	var color string
+	if err := db.QueryRow("SELECT Color FROM Trees WHERE TreeId = 1").Scan(&color); err != nil || color != "red" {
+		t.Errorf("Color = %q, %v; want red", color, err)
+	}
+	if _, err := s.Migrate(ctx, db, 1); err == nil {
+		t.Error("Migrate(1) succeeded, want error as the schema is newer")
+	}
+
+	applied, err = s.Migrate(ctx, db, 0)
+	if err != nil {
+		t.Fatalf("Migrate(latest): %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != 3 {
+		t.Errorf("Migrate(latest) applied %+v, want version 3", applied)
+	}
+	if err := s.Check(ctx, db); err != nil {
+		t.Errorf("Check(latest): %v", err)
+	}
+	if applied, err := s.Migrate(ctx, db, 0); err != nil || len(applied) != 0 {
+		t.Errorf("Migrate(latest) again = %+v, %v; want nothing applied", applied, err)
+	}
+	if _, err := s.Migrate(ctx, db, 4); err == nil {
+		t.Error("Migrate(4) succeeded, want error")
+	}
+
+	rows, err := db.Query("SELECT Version, Description FROM SchemaVersion ORDER BY Version")
+	if err != nil {
+		t.Fatalf("Failed to read versions: %v", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var v, desc string
+		if err := rows.Scan(&v, &desc); err != nil {
+			t.Fatalf("Scan(): %v", err)
+		}
+		got = append(got, v+" "+desc)
+	}
+	want := []string{"1 baseline", "2 add color", "3 add size"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("SchemaVersion rows diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestMigrateLaterStart(t *testing.T) {
+	ctx := context.Background()
+	s := MustNew(SQLite, 3, fstest.MapFS{"migrations/0003_add_size.sql": testMigrations["migrations/0003_add_size.sql"]}, "migrations")
+	if got, want := s.OldestVersion(), 2; got != want {
+		t.Errorf("OldestVersion() = %d, want %d", got, want)
+	}
+
+	db := openDB(t, "CREATE TABLE Trees(TreeId INTEGER)")
+	if _, err := s.Migrate(ctx, db, 0); err == nil || !strings.Contains(err.Error(), "older than the oldest version") {
+		t.Errorf("Migrate(baseline) = %v, want too old", err)
+	}
+
+	db = openDB(t, "CREATE TABLE Trees(TreeId INTEGER)", createVersionTableSQL,
+		"INSERT INTO SchemaVersion(Version, Description, AppliedTimeMillis) VALUES(2, 'created', 0)")
+	applied, err := s.Migrate(ctx, db, 0)
+	if err != nil {
+		t.Fatalf("Migrate(): %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != 3 {
+		t.Errorf("Migrate() applied %+v, want version 3", applied)
+	}
+	if err := s.Check(ctx, db); err != nil {
+		t.Errorf("Check(): %v", err)
+	}
+}
+
+func TestCheckNewerSchema(t *testing.T) {
+	ctx := context.Background()
+	s := MustNew(SQLite, 2, testMigrations, "migrations")
+	db := openDB(t, "CREATE TABLE Trees(TreeId INTEGER)", createVersionTableSQL,
+		"INSERT INTO SchemaVersion(Version, Description, AppliedTimeMillis) VALUES(4, 'future', 0)")
+
+	if err := s.Check(ctx, db); err == nil || !strings.Contains(err.Error(), "newer than") {
+		t.Errorf("Check() = %v, want newer schema error", err)
+	}
+	if _, err := s.Migrate(ctx, db, 0); err == nil {
+		t.Error("Migrate() succeeded, want error")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `# MySQL comment
+-- Comment; with a semicolon
+CREATE TABLE A(
+  X INTEGER
+);
+
+  DROP TABLE B ;
+`
+	want := []string{"CREATE TABLE A(\nX INTEGER\n)", "DROP TABLE B"}
+	if diff := cmp.Diff(want, SplitStatements(script)); diff != "" {
+		t.Errorf("SplitStatements() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	s := MustNew(SQLite, 1, fstest.MapFS{}, "migrations")
+	if err := Register("test-storage", Storage{Schema: s}); err != nil {
+		t.Fatalf("Register(): %v", err)
+	}
+	if err := Register("test-storage", Storage{Schema: s}); err == nil {
+		t.Error("Register() twice succeeded, want error")
+	}
+	if st, err := Get("test-storage"); err != nil || st.Schema != s {
+		t.Errorf("Get() = %v, %v; want registered schema", st, err)
+	}
+	if _, err := Get("unknown"); err == nil {
+		t.Error("Get(unknown) succeeded, want error")
+	}
+}
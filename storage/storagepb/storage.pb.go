@@ -34,6 +34,60 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// LeafCompression is an algorithm which leaf values and extra data can be
+// compressed with when they're stored.
+type LeafCompression int32
+
+const (
+	// Leaves are stored uncompressed.
+	LeafCompression_NO_LEAF_COMPRESSION LeafCompression = 0
+	// Leaves are compressed with gzip, at the default compression level.
+	LeafCompression_GZIP LeafCompression = 1
+	// Leaves are compressed with zstd, at the default compression level.
+	LeafCompression_ZSTD LeafCompression = 2
+)
+
+// Enum value maps for LeafCompression.
+var (
+	LeafCompression_name = map[int32]string{
+		0: "NO_LEAF_COMPRESSION",
+		1: "GZIP",
+		2: "ZSTD",
+	}
+	LeafCompression_value = map[string]int32{
+		"NO_LEAF_COMPRESSION": 0,
+		"GZIP":                1,
+		"ZSTD":                2,
+	}
+)
+
+func (x LeafCompression) Enum() *LeafCompression {
+	p := new(LeafCompression)
+	*p = x
+	return p
+}
+
+func (x LeafCompression) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LeafCompression) Descriptor() protoreflect.EnumDescriptor {
+	return file_storage_proto_enumTypes[0].Descriptor()
+}
+
+func (LeafCompression) Type() protoreflect.EnumType {
+	return &file_storage_proto_enumTypes[0]
+}
+
+func (x LeafCompression) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LeafCompression.Descriptor instead.
+func (LeafCompression) EnumDescriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{0}
+}
+
 // SubtreeProto contains nodes of a subtree.
 type SubtreeProto struct {
 	state         protoimpl.MessageState
@@ -123,6 +177,59 @@ func (x *SubtreeProto) GetInternalNodeCount() uint32 {
 	return 0
 }
 
+// LogStorageSettings holds the settings of a log tree which the SQL and memory
+// storage providers accept in Tree.storage_settings. Unlike the other messages
+// of this file, it is set by clients, when they create or update the tree.
+type LogStorageSettings struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The algorithm which the leaf values and extra data of the tree are
+	// compressed with when they're stored. Changing it only affects the leaves
+	// written afterwards, as each stored value records how it is compressed.
+	LeafCompression LeafCompression `protobuf:"varint,1,opt,name=leaf_compression,json=leafCompression,proto3,enum=storagepb.LeafCompression" json:"leaf_compression,omitempty"`
+}
+
+func (x *LogStorageSettings) Reset() {
+	*x = LogStorageSettings{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_storage_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LogStorageSettings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogStorageSettings) ProtoMessage() {}
+
+func (x *LogStorageSettings) ProtoReflect() protoreflect.Message {
+	mi := &file_storage_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogStorageSettings.ProtoReflect.Descriptor instead.
+func (*LogStorageSettings) Descriptor() ([]byte, []int) {
+	return file_storage_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LogStorageSettings) GetLeafCompression() LeafCompression {
+	if x != nil {
+		return x.LeafCompression
+	}
+	return LeafCompression_NO_LEAF_COMPRESSION
+}
+
 var File_storage_proto protoreflect.FileDescriptor
 
 var file_storage_proto_rawDesc = []byte{
@@ -151,11 +258,21 @@ var file_storage_proto_rawDesc = []byte{
 	0x4e, 0x6f, 0x64, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
 	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05,
 	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c,
-	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x4a, 0x04, 0x08, 0x03, 0x10, 0x04, 0x42, 0x2e, 0x5a, 0x2c,
-	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
-	0x65, 0x2f, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2f, 0x73, 0x74, 0x6f, 0x72, 0x61,
-	0x67, 0x65, 0x2f, 0x73, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x33,
+	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x4a, 0x04, 0x08, 0x03, 0x10, 0x04, 0x22, 0x5b, 0x0a, 0x12,
+	0x4c, 0x6f, 0x67, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x53, 0x65, 0x74, 0x74, 0x69, 0x6e,
+	0x67, 0x73, 0x12, 0x45, 0x0a, 0x10, 0x6c, 0x65, 0x61, 0x66, 0x5f, 0x63, 0x6f, 0x6d, 0x70, 0x72,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1a, 0x2e, 0x73,
+	0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x70, 0x62, 0x2e, 0x4c, 0x65, 0x61, 0x66, 0x43, 0x6f, 0x6d,
+	0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x0f, 0x6c, 0x65, 0x61, 0x66, 0x43, 0x6f,
+	0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2a, 0x3e, 0x0a, 0x0f, 0x4c, 0x65, 0x61,
+	0x66, 0x43, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x17, 0x0a, 0x13,
+	0x4e, 0x4f, 0x5f, 0x4c, 0x45, 0x41, 0x46, 0x5f, 0x43, 0x4f, 0x4d, 0x50, 0x52, 0x45, 0x53, 0x53,
+	0x49, 0x4f, 0x4e, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x47, 0x5a, 0x49, 0x50, 0x10, 0x01, 0x12,
+	0x08, 0x0a, 0x04, 0x5a, 0x53, 0x54, 0x44, 0x10, 0x02, 0x42, 0x2e, 0x5a, 0x2c, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x74,
+	0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2f, 0x73, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x2f,
+	0x73, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
 }
 
 var (
@@ -170,20 +287,24 @@ func file_storage_proto_rawDescGZIP() []byte {
 	return file_storage_proto_rawDescData
 }
 
-var file_storage_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_storage_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_storage_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_storage_proto_goTypes = []interface{}{
-	(*SubtreeProto)(nil), // 0: storagepb.SubtreeProto
-	nil,                  // 1: storagepb.SubtreeProto.LeavesEntry
-	nil,                  // 2: storagepb.SubtreeProto.InternalNodesEntry
+	(LeafCompression)(0),       // 0: storagepb.LeafCompression
+	(*SubtreeProto)(nil),       // 1: storagepb.SubtreeProto
+	(*LogStorageSettings)(nil), // 2: storagepb.LogStorageSettings
+	nil,                        // 3: storagepb.SubtreeProto.LeavesEntry
+	nil,                        // 4: storagepb.SubtreeProto.InternalNodesEntry
 }
 var file_storage_proto_depIdxs = []int32{
-	1, // 0: storagepb.SubtreeProto.leaves:type_name -> storagepb.SubtreeProto.LeavesEntry
-	2, // 1: storagepb.SubtreeProto.internal_nodes:type_name -> storagepb.SubtreeProto.InternalNodesEntry
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	3, // 0: storagepb.SubtreeProto.leaves:type_name -> storagepb.SubtreeProto.LeavesEntry
+	4, // 1: storagepb.SubtreeProto.internal_nodes:type_name -> storagepb.SubtreeProto.InternalNodesEntry
+	0, // 2: storagepb.LogStorageSettings.leaf_compression:type_name -> storagepb.LeafCompression
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_storage_proto_init() }
@@ -204,19 +325,32 @@ func file_storage_proto_init() {
 				return nil
 			}
 		}
+		file_storage_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LogStorageSettings); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_storage_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   3,
+			NumEnums:      1,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_storage_proto_goTypes,
 		DependencyIndexes: file_storage_proto_depIdxs,
+		EnumInfos:         file_storage_proto_enumTypes,
 		MessageInfos:      file_storage_proto_msgTypes,
 	}.Build()
 	File_storage_proto = out.File
@@ -0,0 +1,131 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	// selectOldestKeptRevisionSQL selects the revision of the oldest tree head
+	// to keep, which is the oldest of those newer than a given timestamp and
+	// the latest one.
+	selectOldestKeptRevisionSQL = `SELECT MIN(TreeRevision) FROM TreeHead
+	WHERE TreeId = %s AND (TreeHeadTimestamp >= %s OR TreeHeadTimestamp = (
+		SELECT MAX(TreeHeadTimestamp) FROM TreeHead WHERE TreeId = %s))`
+	// selectPrunableSubtreesSQL selects the subtrees with more than one
+	// revision at or before a given revision, along with the newest of them.
+	selectPrunableSubtreesSQL = `SELECT SubtreeId, MAX(SubtreeRevision) FROM Subtree
+	WHERE TreeId = %s AND SubtreeRevision <= %s
+	GROUP BY SubtreeId HAVING COUNT(*) > 1`
+	deleteSubtreeRevisionsSQL = `DELETE FROM Subtree
+	WHERE TreeId = %s AND SubtreeId = %s AND SubtreeRevision < %s`
+
+	// pruneBatchSize is the number of subtrees whose superseded revisions
+	// are deleted in each transaction.
+	pruneBatchSize = 1000
+)
+
+// subtreeRevision identifies a revision of a subtree.
+type subtreeRevision struct {
+	id  []byte
+	rev int64
+}
+
+// PruneSQLSubtreeRevisions implements SubtreeRevisionPruner for SQL log storage
+// with the TreeHead and Subtree tables, in the database dialect of ph.
+func PruneSQLSubtreeRevisions(ctx context.Context, db *sql.DB, treeID int64, keepSince time.Time, ph Placeholder) (int64, error) {
+	var rev sql.NullInt64
+	query := fmt.Sprintf(selectOldestKeptRevisionSQL, ph(1), ph(2), ph(3))
+	if err := db.QueryRowContext(ctx, query, treeID, keepSince.UnixNano(), treeID).Scan(&rev); err != nil {
+		return 0, err
+	}
+	if !rev.Valid {
+		// The tree has no tree heads.
+		return 0, nil
+	}
+
+	// Tree heads are read using the newest revision of each subtree at or
+	// before their own revision. So the oldest tree head kept, and all the
+	// newer ones, only need the newest subtree revisions at or before its
+	// revision, and those after it.
+	var latest []subtreeRevision
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(selectPrunableSubtreesSQL, ph(1), ph(2)), treeID, rev.Int64)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s subtreeRevision
+		if err := rows.Scan(&s.id, &s.rev); err != nil {
+			return 0, err
+		}
+		latest = append(latest, s)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for len(latest) > 0 {
+		batch := latest
+		if len(batch) > pruneBatchSize {
+			batch = batch[:pruneBatchSize]
+		}
+		latest = latest[len(batch):]
+
+		n, err := deleteOlderRevisions(ctx, db, treeID, batch, ph)
+		if err != nil {
+			return deleted, err
+		}
+		deleted += n
+	}
+	return deleted, nil
+}
+
+// deleteOlderRevisions deletes the revisions of the given subtrees older than
+// the given ones, in a single transaction, and returns how many there were.
+func deleteOlderRevisions(ctx context.Context, db *sql.DB, treeID int64, subtrees []subtreeRevision, ph Placeholder) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil /* opts */)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // nolint: errcheck
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(deleteSubtreeRevisionsSQL, ph(1), ph(2), ph(3)))
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var deleted int64
+	for _, s := range subtrees {
+		res, err := stmt.ExecContext(ctx, treeID, s.id, s.rev)
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		deleted += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
@@ -0,0 +1,106 @@
+//go:build cgo
+// +build cgo
+
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // Register the SQLite driver.
+)
+
+func TestPruneSQLSubtreeRevisions(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	// Each connection has its own in-memory database.
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range []string{
+		"CREATE TABLE TreeHead (TreeId INTEGER, TreeHeadTimestamp INTEGER, TreeRevision INTEGER)",
+		"CREATE TABLE Subtree (TreeId INTEGER, SubtreeId BLOB, SubtreeRevision INTEGER)",
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+	}
+	// Trees 1 and 2 have tree heads with revisions 0 to 5, one every 1000ns.
+	// Subtree a is written by all the tree heads but the first, and subtree b
+	// by tree heads 0 and 3.
+	for _, treeID := range []int64{1, 2} {
+		for rev := int64(0); rev <= 5; rev++ {
+			if _, err := db.ExecContext(ctx, "INSERT INTO TreeHead VALUES (?, ?, ?)", treeID, (rev+1)*1000, rev); err != nil {
+				t.Fatalf("Failed to insert tree head: %v", err)
+			}
+			if rev > 0 {
+				if _, err := db.ExecContext(ctx, "INSERT INTO Subtree VALUES (?, ?, ?)", treeID, []byte("a"), rev); err != nil {
+					t.Fatalf("Failed to insert subtree: %v", err)
+				}
+			}
+			if rev == 0 || rev == 3 {
+				if _, err := db.ExecContext(ctx, "INSERT INTO Subtree VALUES (?, ?, ?)", treeID, []byte("b"), rev); err != nil {
+					t.Fatalf("Failed to insert subtree: %v", err)
+				}
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		desc      string
+		treeID    int64
+		keepSince time.Time
+		want      int64
+	}{
+		// Tree head 3 is the oldest kept, and needs revisions a3 and b3.
+		{desc: "keep-recent", treeID: 1, keepSince: time.Unix(0, 4000), want: 3},
+		{desc: "keep-recent-again", treeID: 1, keepSince: time.Unix(0, 4000), want: 0},
+		// Only the latest tree head is kept, which needs a5 and b3.
+		{desc: "keep-latest", treeID: 1, keepSince: time.Unix(0, 1e6), want: 2},
+		{desc: "no-tree-heads", treeID: 3, keepSince: time.Unix(0, 1e6), want: 0},
+	} {
+		got, err := PruneSQLSubtreeRevisions(ctx, db, tc.treeID, tc.keepSince, QuestionMark)
+		if err != nil {
+			t.Fatalf("%s: PruneSQLSubtreeRevisions(): %v", tc.desc, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: PruneSQLSubtreeRevisions() = %d, want %d", tc.desc, got, tc.want)
+		}
+	}
+
+	for _, want := range []struct {
+		treeID int64
+		count  int
+	}{
+		{treeID: 1, count: 2},
+		// Other trees are left alone.
+		{treeID: 2, count: 7},
+	} {
+		var count int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM Subtree WHERE TreeId = ?", want.treeID).Scan(&count); err != nil {
+			t.Fatalf("Failed to count subtrees: %v", err)
+		}
+		if count != want.count {
+			t.Errorf("Tree %d has %d subtree revisions, want %d", want.treeID, count, want.count)
+		}
+	}
+}
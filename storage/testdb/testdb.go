@@ -49,6 +49,12 @@ const (
 	CockroachDBURIEnv = "TEST_COCKROACHDB_URI"
 
 	defaultTestCockroachDBURI = "postgres://root@localhost:26257/?sslmode=disable"
+
+	// PostgreSQLURIEnv is the name of the ENV variable checked for the test PostgreSQL
+	// instance URI to use.
+	PostgreSQLURIEnv = "TEST_POSTGRESQL_URI"
+
+	defaultTestPostgreSQLURI = "postgres://postgres@localhost:5432/?sslmode=disable"
 )
 
 type storageDriverInfo struct {
@@ -60,6 +66,7 @@ type storageDriverInfo struct {
 var (
 	trillianMySQLSchema = testonly.RelativeToPackage("../mysql/schema/storage.sql")
 	trillianCRDBSchema  = testonly.RelativeToPackage("../crdb/schema/storage.sql")
+	trillianPGSchema    = testonly.RelativeToPackage("../postgresql/schema/storage.sql")
 )
 
 // DriverName is the name of a database driver.
@@ -70,6 +77,8 @@ const (
 	DriverMySQL DriverName = "mysql"
 	// DriverCockroachDB is the identifier for the CockroachDB storage driver.
 	DriverCockroachDB DriverName = "cockroachdb"
+	// DriverPostgreSQL is the identifier for the PostgreSQL storage driver.
+	DriverPostgreSQL DriverName = "postgresql"
 )
 
 var driverMapping = map[DriverName]storageDriverInfo{
@@ -83,6 +92,11 @@ var driverMapping = map[DriverName]storageDriverInfo{
 		schema:        trillianCRDBSchema,
 		uriFunc:       crdbURI,
 	},
+	DriverPostgreSQL: {
+		sqlDriverName: "postgres",
+		schema:        trillianPGSchema,
+		uriFunc:       pgURI,
+	},
 }
 
 // mysqlURI returns the MySQL connection URI to use for tests. It returns the
@@ -126,6 +140,20 @@ func crdbURI(dbRef ...string) string {
 	return addPathToURI(uri, dbRef...)
 }
 
+// pgURI returns the PostgreSQL connection URI to use for tests. It returns the
+// value in the ENV variable defined by PostgreSQLURIEnv. If the value is empty,
+// returns defaultTestPostgreSQLURI.
+func pgURI(dbRef ...string) string {
+	var uri *url.URL
+	if e := os.Getenv(PostgreSQLURIEnv); len(e) > 0 {
+		uri = getURL(e)
+	} else {
+		uri = getURL(defaultTestPostgreSQLURI)
+	}
+
+	return addPathToURI(uri, dbRef...)
+}
+
 func addPathToURI(uri *url.URL, paths ...string) string {
 	if len(paths) > 0 {
 		for _, ref := range paths {
@@ -156,6 +184,11 @@ func CockroachDBAvailable() bool {
 	return dbAvailable(DriverCockroachDB)
 }
 
+// PostgreSQLAvailable indicates whether the configured PostgreSQL database is available.
+func PostgreSQLAvailable() bool {
+	return dbAvailable(DriverPostgreSQL)
+}
+
 func dbAvailable(driver DriverName) bool {
 	driverName := driverMapping[driver].sqlDriverName
 	uri := driverMapping[driver].uriFunc()
@@ -223,6 +256,18 @@ func newEmptyDB(ctx context.Context, driver DriverName) (*sql.DB, func(context.C
 	}
 
 	done := func(ctx context.Context) {
+		if driver == DriverPostgreSQL {
+			// PostgreSQL can't drop the database a session is connected to, so
+			// close all connections to it and drop it from the server's default
+			// database instead.
+			db.Close()
+			adminDB, err := sql.Open(inf.sqlDriverName, inf.uriFunc())
+			if err != nil {
+				klog.Warningf("Failed to drop test database %q: %v", name, err)
+				return
+			}
+			db = adminDB
+		}
 		defer db.Close()
 		if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP DATABASE %v", name)); err != nil {
 			klog.Warningf("Failed to drop test database %q: %v", name, err)
@@ -290,3 +335,12 @@ func SkipIfNoCockroachDB(t *testing.T) {
 	}
 	t.Logf("Test CockroachDB available at %q", crdbURI())
 }
+
+// SkipIfNoPostgreSQL is a test helper that skips tests that require a local PostgreSQL.
+func SkipIfNoPostgreSQL(t *testing.T) {
+	t.Helper()
+	if !PostgreSQLAvailable() {
+		t.Skip("Skipping test as PostgreSQL not available")
+	}
+	t.Logf("Test PostgreSQL available at %q", pgURI())
+}
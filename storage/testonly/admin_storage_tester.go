@@ -16,6 +16,7 @@ package testonly
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
@@ -23,12 +24,15 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/google/trillian"
 	"github.com/google/trillian/storage"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 var (
@@ -61,8 +65,10 @@ type AdminStorageTester struct {
 // RunAllTests runs all AdminStorage tests.
 func (tester *AdminStorageTester) RunAllTests(t *testing.T) {
 	t.Run("TestCreateTree", tester.TestCreateTree)
+	t.Run("TestCreateTreeWithID", tester.TestCreateTreeWithID)
 	t.Run("TestUpdateTree", tester.TestUpdateTree)
 	t.Run("TestListTrees", tester.TestListTrees)
+	t.Run("TestListTreesFiltered", tester.TestListTreesFiltered)
 	t.Run("TestSoftDeleteTree", tester.TestSoftDeleteTree)
 	t.Run("TestSoftDeleteTreeErrors", tester.TestSoftDeleteTreeErrors)
 	t.Run("TestHardDeleteTree", tester.TestHardDeleteTree)
@@ -70,6 +76,7 @@ func (tester *AdminStorageTester) RunAllTests(t *testing.T) {
 	t.Run("TestUndeleteTree", tester.TestUndeleteTree)
 	t.Run("TestUndeleteTreeErrors", tester.TestUndeleteTreeErrors)
 	t.Run("TestAdminTXReadWriteTransaction", tester.TestAdminTXReadWriteTransaction)
+	t.Run("TestAuditLog", tester.TestAuditLog)
 }
 
 // TestCreateTree tests AdminStorage Tree creation.
@@ -86,6 +93,9 @@ func (tester *AdminStorageTester) TestCreateTree(t *testing.T) {
 	validTreeWithoutOptionals.DisplayName = ""
 	validTreeWithoutOptionals.Description = ""
 
+	validTreeWithLabels := proto.Clone(LogTree).(*trillian.Tree)
+	validTreeWithLabels.Labels = map[string]string{"tenant": "llamas", "env": ""}
+
 	tests := []struct {
 		desc    string
 		tree    *trillian.Tree
@@ -108,6 +118,10 @@ func (tester *AdminStorageTester) TestCreateTree(t *testing.T) {
 			desc: "validTreeWithoutOptionals",
 			tree: validTreeWithoutOptionals,
 		},
+		{
+			desc: "validTreeWithLabels",
+			tree: validTreeWithLabels,
+		},
 	}
 
 	ctx := context.Background()
@@ -159,6 +173,32 @@ func (tester *AdminStorageTester) TestCreateTree(t *testing.T) {
 	}
 }
 
+// TestCreateTreeWithID tests AdminStorage Tree creation with a given ID.
+func (tester *AdminStorageTester) TestCreateTreeWithID(t *testing.T) {
+	ctx := context.Background()
+	s := tester.NewAdminStorage()
+	const treeID = 12345
+	tree, err := storage.CreateTreeWithID(ctx, s, LogTree, treeID)
+	if status.Code(err) == codes.Unimplemented {
+		t.Skipf("CreateTreeWithID(): %v", err)
+	} else if err != nil {
+		t.Fatalf("CreateTreeWithID(): %v", err)
+	}
+	if got, want := tree.TreeId, int64(treeID); got != want {
+		t.Errorf("CreateTreeWithID(): TreeId = %v, want %v", got, want)
+	}
+	if err := assertStoredTree(ctx, s, tree); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := storage.CreateTreeWithID(ctx, s, PreorderedLogTree, treeID); err == nil {
+		t.Error("CreateTreeWithID() with existing ID succeeded, want error")
+	}
+	if _, err := storage.CreateTreeWithID(ctx, s, LogTree, -1); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateTreeWithID() with negative ID = %v, want %v", err, codes.InvalidArgument)
+	}
+}
+
 // TestUpdateTree tests AdminStorage Tree updates.
 func (tester *AdminStorageTester) TestUpdateTree(t *testing.T) {
 	ctx := context.Background()
@@ -184,6 +224,17 @@ func (tester *AdminStorageTester) TestUpdateTree(t *testing.T) {
 	validLogWithoutOptionals := proto.Clone(referenceLog).(*trillian.Tree)
 	validLogWithoutOptionalsFunc(validLogWithoutOptionals)
 
+	labeledLog := proto.Clone(referenceLog).(*trillian.Tree)
+	labeledLog.Labels = map[string]string{"tenant": "llamas", "env": "prod"}
+	relabeledLogFunc := func(tree *trillian.Tree) {
+		tree.Labels = map[string]string{"tenant": "alpacas"}
+	}
+	relabeledLog := proto.Clone(referenceLog).(*trillian.Tree)
+	relabeledLogFunc(relabeledLog)
+	unlabeledLogFunc := func(tree *trillian.Tree) {
+		tree.Labels = nil
+	}
+
 	invalidLogFunc := func(tree *trillian.Tree) {
 		tree.TreeState = trillian.TreeState_UNKNOWN_TREE_STATE
 	}
@@ -215,6 +266,24 @@ func (tester *AdminStorageTester) TestUpdateTree(t *testing.T) {
 			updateFunc: validLogWithoutOptionalsFunc,
 			want:       validLogWithoutOptionals,
 		},
+		{
+			desc:       "addLabels",
+			create:     referenceLog,
+			updateFunc: relabeledLogFunc,
+			want:       relabeledLog,
+		},
+		{
+			desc:       "changeLabels",
+			create:     labeledLog,
+			updateFunc: relabeledLogFunc,
+			want:       relabeledLog,
+		},
+		{
+			desc:       "removeLabels",
+			create:     labeledLog,
+			updateFunc: unlabeledLogFunc,
+			want:       referenceLog,
+		},
 		{
 			desc:       "invalidLog",
 			create:     referenceLog,
@@ -335,6 +404,60 @@ func runListTreesTest(ctx context.Context, tx storage.ReadOnlyAdminTX, includeDe
 	return nil
 }
 
+// TestListTreesFiltered tests listing trees selected by labels, type and state,
+// in pages.
+func (tester *AdminStorageTester) TestListTreesFiltered(t *testing.T) {
+	ctx := context.Background()
+	s := tester.NewAdminStorage()
+
+	withLabels := func(tree *trillian.Tree, labels map[string]string) *trillian.Tree {
+		return tweakedCopy(tree, func(t *trillian.Tree) { t.Labels = labels })
+	}
+	prodLog := makeTreeOrFail(ctx, s, spec{Tree: withLabels(LogTree, map[string]string{"tenant": "a", "env": "prod"})}, t.Fatalf)
+	preorderedLog := makeTreeOrFail(ctx, s, spec{Tree: withLabels(PreorderedLogTree, map[string]string{"tenant": "a"})}, t.Fatalf)
+	frozenLog := makeTreeOrFail(ctx, s, spec{Tree: withLabels(LogTree, map[string]string{"tenant": "b"}), Frozen: true}, t.Fatalf)
+	deletedLog := makeTreeOrFail(ctx, s, spec{Tree: withLabels(LogTree, map[string]string{"tenant": "a"}), Deleted: true}, t.Fatalf)
+	unlabeledLog := makeTreeOrFail(ctx, s, spec{Tree: LogTree}, t.Fatalf)
+
+	byID := func(trees ...*trillian.Tree) []*trillian.Tree {
+		sort.Slice(trees, func(i, j int) bool { return trees[i].TreeId < trees[j].TreeId })
+		return trees
+	}
+	all := byID(prodLog, preorderedLog, frozenLog, unlabeledLog)
+
+	for _, test := range []struct {
+		desc string
+		opts storage.ListTreesOptions
+		want []*trillian.Tree
+	}{
+		{desc: "all", want: all},
+		{desc: "deleted", opts: storage.ListTreesOptions{IncludeDeleted: true}, want: byID(prodLog, preorderedLog, frozenLog, deletedLog, unlabeledLog)},
+		{desc: "label", opts: storage.ListTreesOptions{Labels: map[string]string{"tenant": "a"}}, want: byID(prodLog, preorderedLog)},
+		{desc: "labels", opts: storage.ListTreesOptions{Labels: map[string]string{"tenant": "a", "env": "prod"}}, want: byID(prodLog)},
+		{desc: "deletedLabel", opts: storage.ListTreesOptions{IncludeDeleted: true, Labels: map[string]string{"tenant": "a"}}, want: byID(prodLog, preorderedLog, deletedLog)},
+		{desc: "unknownLabel", opts: storage.ListTreesOptions{Labels: map[string]string{"tenant": "c"}}},
+		{desc: "type", opts: storage.ListTreesOptions{TreeTypes: []trillian.TreeType{trillian.TreeType_PREORDERED_LOG}}, want: byID(preorderedLog)},
+		{desc: "state", opts: storage.ListTreesOptions{TreeStates: []trillian.TreeState{trillian.TreeState_FROZEN}}, want: byID(frozenLog)},
+		{desc: "typeAndState", opts: storage.ListTreesOptions{TreeTypes: []trillian.TreeType{trillian.TreeType_LOG}, TreeStates: []trillian.TreeState{trillian.TreeState_ACTIVE}}, want: byID(prodLog, unlabeledLog)},
+		{desc: "firstPage", opts: storage.ListTreesOptions{Limit: 3}, want: all[:3]},
+		{desc: "lastPage", opts: storage.ListTreesOptions{AfterTreeID: all[2].TreeId, Limit: 3}, want: all[3:]},
+		{desc: "afterLast", opts: storage.ListTreesOptions{AfterTreeID: all[3].TreeId}},
+	} {
+		var got []*trillian.Tree
+		if err := storage.RunInAdminSnapshot(ctx, s, func(tx storage.ReadOnlyAdminTX) error {
+			var err error
+			got, err = storage.FilterTrees(ctx, tx, test.opts)
+			return err
+		}); err != nil {
+			t.Errorf("%v: FilterTrees() returned err = %v", test.desc, err)
+			continue
+		}
+		if diff := cmp.Diff(test.want, got, cmpopts.EquateEmpty(), protocmp.Transform()); diff != "" {
+			t.Errorf("%v: FilterTrees() diff (-want +got):\n%v", test.desc, diff)
+		}
+	}
+}
+
 // TestSoftDeleteTree tests success scenarios of SoftDeleteTree.
 func (tester *AdminStorageTester) TestSoftDeleteTree(t *testing.T) {
 	ctx := context.Background()
@@ -547,6 +670,101 @@ func (tester *AdminStorageTester) TestAdminTXReadWriteTransaction(t *testing.T)
 	}
 }
 
+// TestAuditLog tests the audit log of storage systems which keep one, i.e.
+// whose transactions implement storage.AuditLogWriter.
+func (tester *AdminStorageTester) TestAuditLog(t *testing.T) {
+	ctx := context.Background()
+	s := tester.NewAdminStorage()
+
+	tree := makeTreeOrFail(ctx, s, spec{Tree: LogTree, Deleted: true}, t.Fatalf)
+	errNoAuditLog := errors.New("no audit log")
+	addEvent := func(event *trillian.AdminAuditEvent, fnErr error) error {
+		return s.ReadWriteTransaction(ctx, func(ctx context.Context, tx storage.AdminTX) error {
+			if _, ok := tx.(storage.AuditLogWriter); !ok {
+				return errNoAuditLog
+			}
+			if err := storage.AddAuditEvent(ctx, tx, event); err != nil {
+				return err
+			}
+			return fnErr
+		})
+	}
+
+	eventTime := timestamppb.New(time.Unix(1600000000, 123456789))
+	events := []*trillian.AdminAuditEvent{
+		{
+			TreeId:     tree.TreeId,
+			Operation:  trillian.AdminAuditEvent_DELETE_TREE,
+			Caller:     "CN=llama",
+			TreeBefore: tweakedCopy(tree, func(t *trillian.Tree) { t.Deleted, t.DeleteTime = false, nil }),
+			TreeAfter:  tree,
+			EventTime:  eventTime,
+		},
+		{
+			TreeId:    12345,
+			Operation: trillian.AdminAuditEvent_CREATE_TREE,
+			Caller:    "127.0.0.1:5000",
+			TreeAfter: tweakedCopy(LogTree, func(t *trillian.Tree) { t.TreeId = 12345 }),
+			EventTime: eventTime,
+		},
+		{
+			TreeId:     tree.TreeId,
+			Operation:  trillian.AdminAuditEvent_HARD_DELETE_TREE,
+			Caller:     "DeletedTreeGC",
+			TreeBefore: tree,
+			EventTime:  eventTime,
+		},
+	}
+	var lastID int64
+	for _, event := range events {
+		err := addEvent(event, nil)
+		if errors.Is(err, errNoAuditLog) {
+			t.Skip("Storage doesn't keep an audit log")
+		}
+		if err != nil {
+			t.Fatalf("AddAuditEvent() returned err = %v", err)
+		}
+		if event.EventId <= lastID {
+			t.Errorf("AddAuditEvent() set EventId = %d, want > %d", event.EventId, lastID)
+		}
+		lastID = event.EventId
+	}
+
+	// Events are kept after their trees are gone, and aren't recorded if their
+	// transaction isn't committed.
+	if err := storage.HardDeleteTree(ctx, s, tree.TreeId); err != nil {
+		t.Fatalf("HardDeleteTree() returned err = %v", err)
+	}
+	errRollback := errors.New("rollback")
+	rolledBack := &trillian.AdminAuditEvent{TreeId: tree.TreeId, Operation: trillian.AdminAuditEvent_UPDATE_TREE, EventTime: eventTime}
+	if err := addEvent(rolledBack, errRollback); !errors.Is(err, errRollback) {
+		t.Fatalf("ReadWriteTransaction() returned err = %v, want %v", err, errRollback)
+	}
+
+	for _, test := range []struct {
+		desc string
+		opts storage.ListAuditEventsOptions
+		want []*trillian.AdminAuditEvent
+	}{
+		{desc: "all", want: events},
+		{desc: "tree", opts: storage.ListAuditEventsOptions{TreeID: tree.TreeId}, want: []*trillian.AdminAuditEvent{events[0], events[2]}},
+		{desc: "unknownTree", opts: storage.ListAuditEventsOptions{TreeID: 54321}},
+		{desc: "firstPage", opts: storage.ListAuditEventsOptions{Limit: 2}, want: events[:2]},
+		{desc: "lastPage", opts: storage.ListAuditEventsOptions{AfterEventID: events[1].EventId, Limit: 2}, want: events[2:]},
+		{desc: "treePage", opts: storage.ListAuditEventsOptions{TreeID: tree.TreeId, AfterEventID: events[0].EventId}, want: events[2:]},
+		{desc: "afterLast", opts: storage.ListAuditEventsOptions{AfterEventID: events[2].EventId}},
+	} {
+		got, err := storage.ListAuditEvents(ctx, s, test.opts)
+		if err != nil {
+			t.Errorf("%v: ListAuditEvents() returned err = %v", test.desc, err)
+			continue
+		}
+		if diff := cmp.Diff(test.want, got, cmpopts.EquateEmpty(), protocmp.Transform()); diff != "" {
+			t.Errorf("%v: ListAuditEvents() diff (-want +got):\n%v", test.desc, diff)
+		}
+	}
+}
+
 // assertStoredTree verifies that "want" is equal to the tree stored under its ID.
 func assertStoredTree(ctx context.Context, s storage.AdminStorage, want *trillian.Tree) error {
 	got, err := storage.GetTree(ctx, s, want.TreeId)
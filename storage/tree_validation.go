@@ -16,6 +16,7 @@ package storage
 
 import (
 	"context"
+	"regexp"
 
 	"github.com/google/trillian"
 	"google.golang.org/grpc/codes"
@@ -23,6 +24,19 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+const (
+	// MaxTreeLabels is the maximum number of labels of a tree.
+	MaxTreeLabels = 64
+	// MaxTreeLabelLength is the maximum length of the keys and values of tree
+	// labels.
+	MaxTreeLabelLength = 63
+)
+
+var (
+	labelKeyRE   = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+	labelValueRE = regexp.MustCompile(`^[a-z0-9_-]*$`)
+)
+
 // ValidateTreeForCreation returns nil if tree is valid for insertion, error
 // otherwise.
 // See the documentation on trillian.Tree for reference on which values are
@@ -64,6 +78,21 @@ func validateTreeTypeUpdate(oldTree, newTree *trillian.Tree) error {
 	return nil
 }
 
+func validateLabels(labels map[string]string) error {
+	if len(labels) > MaxTreeLabels {
+		return status.Errorf(codes.InvalidArgument, "too many labels: %d, max %d", len(labels), MaxTreeLabels)
+	}
+	for k, v := range labels {
+		if len(k) > MaxTreeLabelLength || !labelKeyRE.MatchString(k) {
+			return status.Errorf(codes.InvalidArgument, "invalid label key: %q", k)
+		}
+		if len(v) > MaxTreeLabelLength || !labelValueRE.MatchString(v) {
+			return status.Errorf(codes.InvalidArgument, "invalid value of label %q: %q", k, v)
+		}
+	}
+	return nil
+}
+
 // ValidateTreeForUpdate returns nil if newTree is valid for update, error
 // otherwise.
 // The newTree is compared to the storedTree to determine if readonly fields
@@ -102,6 +131,10 @@ func validateMutableTreeFields(ctx context.Context, tree *trillian.Tree) error {
 		return status.Errorf(codes.InvalidArgument, "max_root_duration negative: %v", tree.MaxRootDuration)
 	}
 
+	if err := validateLabels(tree.Labels); err != nil {
+		return err
+	}
+
 	// Implementations may vary, so let's assume storage_settings is mutable.
 	// Other than checking that it's a valid Any there isn't much to do at this layer, though.
 	if tree.StorageSettings != nil {
@@ -16,6 +16,8 @@ package storage
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -67,6 +69,24 @@ func TestValidateTreeForCreation(t *testing.T) {
 	deleteTimeTree := newTree()
 	deleteTimeTree.DeleteTime = timestamppb.Now()
 
+	validLabels := newTree()
+	validLabels.Labels = map[string]string{"tenant": "llamas-r-us", "env": "", "shard_2": "0"}
+
+	invalidLabelKey := newTree()
+	invalidLabelKey.Labels = map[string]string{"2nd": "x"}
+
+	invalidLabelValue := newTree()
+	invalidLabelValue.Labels = map[string]string{"tenant": "Llamas"}
+
+	longLabelValue := newTree()
+	longLabelValue.Labels = map[string]string{"tenant": strings.Repeat("l", MaxTreeLabelLength+1)}
+
+	tooManyLabels := newTree()
+	tooManyLabels.Labels = make(map[string]string)
+	for i := 0; i <= MaxTreeLabels; i++ {
+		tooManyLabels.Labels[fmt.Sprintf("label%d", i)] = "x"
+	}
+
 	tests := []struct {
 		desc    string
 		tree    *trillian.Tree
@@ -129,6 +149,30 @@ func TestValidateTreeForCreation(t *testing.T) {
 			tree:    deleteTimeTree,
 			wantErr: true,
 		},
+		{
+			desc: "validLabels",
+			tree: validLabels,
+		},
+		{
+			desc:    "invalidLabelKey",
+			tree:    invalidLabelKey,
+			wantErr: true,
+		},
+		{
+			desc:    "invalidLabelValue",
+			tree:    invalidLabelValue,
+			wantErr: true,
+		},
+		{
+			desc:    "longLabelValue",
+			tree:    longLabelValue,
+			wantErr: true,
+		},
+		{
+			desc:    "tooManyLabels",
+			tree:    tooManyLabels,
+			wantErr: true,
+		},
 	}
 	for _, test := range tests {
 		err := ValidateTreeForCreation(ctx, test.tree)
@@ -194,6 +238,19 @@ func TestValidateTreeForUpdate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			desc: "validLabels",
+			updatefn: func(tree *trillian.Tree) {
+				tree.Labels = map[string]string{"tenant": "llamas"}
+			},
+		},
+		{
+			desc: "invalidLabels",
+			updatefn: func(tree *trillian.Tree) {
+				tree.Labels = map[string]string{"Tenant": "llamas"}
+			},
+			wantErr: true,
+		},
 		// Changes on readonly fields
 		{
 			desc: "TreeId",
@@ -80,6 +80,36 @@ func (mr *MockTrillianAdminServerMockRecorder) GetTree(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTree", reflect.TypeOf((*MockTrillianAdminServer)(nil).GetTree), arg0, arg1)
 }
 
+// GetTreeStats mocks base method.
+func (m *MockTrillianAdminServer) GetTreeStats(arg0 context.Context, arg1 *trillian.GetTreeStatsRequest) (*trillian.GetTreeStatsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTreeStats", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.GetTreeStatsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTreeStats indicates an expected call of GetTreeStats.
+func (mr *MockTrillianAdminServerMockRecorder) GetTreeStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreeStats", reflect.TypeOf((*MockTrillianAdminServer)(nil).GetTreeStats), arg0, arg1)
+}
+
+// ListAdminAuditEvents mocks base method.
+func (m *MockTrillianAdminServer) ListAdminAuditEvents(arg0 context.Context, arg1 *trillian.ListAdminAuditEventsRequest) (*trillian.ListAdminAuditEventsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAdminAuditEvents", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.ListAdminAuditEventsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAdminAuditEvents indicates an expected call of ListAdminAuditEvents.
+func (mr *MockTrillianAdminServerMockRecorder) ListAdminAuditEvents(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAdminAuditEvents", reflect.TypeOf((*MockTrillianAdminServer)(nil).ListAdminAuditEvents), arg0, arg1)
+}
+
 // ListTrees mocks base method.
 func (m *MockTrillianAdminServer) ListTrees(arg0 context.Context, arg1 *trillian.ListTreesRequest) (*trillian.ListTreesResponse, error) {
 	m.ctrl.T.Helper()
@@ -23,7 +23,10 @@ package trillian
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
 	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 )
@@ -35,8 +38,73 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Operation which changed the tree.
+type AdminAuditEvent_Operation int32
+
+const (
+	AdminAuditEvent_UNKNOWN_OPERATION AdminAuditEvent_Operation = 0
+	// The tree was created by CreateTree.
+	AdminAuditEvent_CREATE_TREE AdminAuditEvent_Operation = 1
+	// The tree was updated by UpdateTree.
+	AdminAuditEvent_UPDATE_TREE AdminAuditEvent_Operation = 2
+	// The tree was soft-deleted by DeleteTree.
+	AdminAuditEvent_DELETE_TREE AdminAuditEvent_Operation = 3
+	// The tree was undeleted by UndeleteTree.
+	AdminAuditEvent_UNDELETE_TREE AdminAuditEvent_Operation = 4
+	// The soft-deleted tree was permanently deleted by the garbage collector.
+	AdminAuditEvent_HARD_DELETE_TREE AdminAuditEvent_Operation = 5
+)
+
+// Enum value maps for AdminAuditEvent_Operation.
+var (
+	AdminAuditEvent_Operation_name = map[int32]string{
+		0: "UNKNOWN_OPERATION",
+		1: "CREATE_TREE",
+		2: "UPDATE_TREE",
+		3: "DELETE_TREE",
+		4: "UNDELETE_TREE",
+		5: "HARD_DELETE_TREE",
+	}
+	AdminAuditEvent_Operation_value = map[string]int32{
+		"UNKNOWN_OPERATION": 0,
+		"CREATE_TREE":       1,
+		"UPDATE_TREE":       2,
+		"DELETE_TREE":       3,
+		"UNDELETE_TREE":     4,
+		"HARD_DELETE_TREE":  5,
+	}
+)
+
+func (x AdminAuditEvent_Operation) Enum() *AdminAuditEvent_Operation {
+	p := new(AdminAuditEvent_Operation)
+	*p = x
+	return p
+}
+
+func (x AdminAuditEvent_Operation) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AdminAuditEvent_Operation) Descriptor() protoreflect.EnumDescriptor {
+	return file_trillian_admin_api_proto_enumTypes[0].Descriptor()
+}
+
+func (AdminAuditEvent_Operation) Type() protoreflect.EnumType {
+	return &file_trillian_admin_api_proto_enumTypes[0]
+}
+
+func (x AdminAuditEvent_Operation) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AdminAuditEvent_Operation.Descriptor instead.
+func (AdminAuditEvent_Operation) EnumDescriptor() ([]byte, []int) {
+	return file_trillian_admin_api_proto_rawDescGZIP(), []int{9, 0}
+}
+
 // ListTrees request.
-// No filters or pagination options are provided.
+// Trees are returned in order of their IDs. The filters are combined, so only
+// trees matching all of them are returned.
 type ListTreesRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -44,6 +112,20 @@ type ListTreesRequest struct {
 
 	// If true, deleted trees are included in the response.
 	ShowDeleted bool `protobuf:"varint,1,opt,name=show_deleted,json=showDeleted,proto3" json:"show_deleted,omitempty"`
+	// Maximum number of trees to return. If zero, all the trees matching the
+	// filters are returned in a single response.
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token of a previous response, to return the trees following
+	// those returned by it. The other fields of the request must be the same as
+	// in the request which returned the token.
+	PageToken string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// If set, only trees with all these labels, and the same values for them,
+	// are returned.
+	Labels map[string]string `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// If set, only trees of these types are returned.
+	TreeType []TreeType `protobuf:"varint,5,rep,packed,name=tree_type,json=treeType,proto3,enum=trillian.TreeType" json:"tree_type,omitempty"`
+	// If set, only trees in these states are returned.
+	TreeState []TreeState `protobuf:"varint,6,rep,packed,name=tree_state,json=treeState,proto3,enum=trillian.TreeState" json:"tree_state,omitempty"`
 }
 
 func (x *ListTreesRequest) Reset() {
@@ -85,9 +167,42 @@ func (x *ListTreesRequest) GetShowDeleted() bool {
 	return false
 }
 
+func (x *ListTreesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListTreesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListTreesRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *ListTreesRequest) GetTreeType() []TreeType {
+	if x != nil {
+		return x.TreeType
+	}
+	return nil
+}
+
+func (x *ListTreesRequest) GetTreeState() []TreeState {
+	if x != nil {
+		return x.TreeState
+	}
+	return nil
+}
+
 // ListTrees response.
-// No pagination is provided, all trees the requester has access to are
-// returned.
 type ListTreesResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -95,6 +210,9 @@ type ListTreesResponse struct {
 
 	// Trees matching the list request filters.
 	Tree []*Tree `protobuf:"bytes,1,rep,name=tree,proto3" json:"tree,omitempty"`
+	// Token to pass as page_token to retrieve the next page of trees. Empty if
+	// there are no more trees.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
 }
 
 func (x *ListTreesResponse) Reset() {
@@ -136,6 +254,13 @@ func (x *ListTreesResponse) GetTree() []*Tree {
 	return nil
 }
 
+func (x *ListTreesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
 // GetTree request.
 type GetTreeRequest struct {
 	state         protoimpl.MessageState
@@ -185,6 +310,163 @@ func (x *GetTreeRequest) GetTreeId() int64 {
 	return 0
 }
 
+// GetTreeStats request.
+type GetTreeStatsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// ID of the tree to retrieve statistics for.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId,proto3" json:"tree_id,omitempty"`
+}
+
+func (x *GetTreeStatsRequest) Reset() {
+	*x = GetTreeStatsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_trillian_admin_api_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetTreeStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTreeStatsRequest) ProtoMessage() {}
+
+func (x *GetTreeStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trillian_admin_api_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTreeStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetTreeStatsRequest) Descriptor() ([]byte, []int) {
+	return file_trillian_admin_api_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetTreeStatsRequest) GetTreeId() int64 {
+	if x != nil {
+		return x.TreeId
+	}
+	return 0
+}
+
+// GetTreeStats response.
+// The statistics are computed by the storage system when requested, and are
+// approximate, as they're not read atomically.
+type GetTreeStatsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// ID of the tree.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId,proto3" json:"tree_id,omitempty"`
+	// Number of sequenced leaves of the tree.
+	LeafCount int64 `protobuf:"varint,2,opt,name=leaf_count,json=leafCount,proto3" json:"leaf_count,omitempty"`
+	// Number of leaves waiting to be sequenced.
+	QueueDepth int64 `protobuf:"varint,3,opt,name=queue_depth,json=queueDepth,proto3" json:"queue_depth,omitempty"`
+	// Time since the oldest leaf waiting to be sequenced was queued.
+	// Unset if no leaves are waiting.
+	OldestQueuedLeafAge *durationpb.Duration `protobuf:"bytes,4,opt,name=oldest_queued_leaf_age,json=oldestQueuedLeafAge,proto3" json:"oldest_queued_leaf_age,omitempty"`
+	// Time since the latest log root was created.
+	// Unset if the tree isn't initialized.
+	LatestRootAge *durationpb.Duration `protobuf:"bytes,5,opt,name=latest_root_age,json=latestRootAge,proto3" json:"latest_root_age,omitempty"`
+	// Number of bytes of leaf values and extra data kept by the storage system,
+	// not including data kept in external blob stores.
+	LeafBytes int64 `protobuf:"varint,6,opt,name=leaf_bytes,json=leafBytes,proto3" json:"leaf_bytes,omitempty"`
+	// Number of bytes of Merkle tree tiles, including superseded revisions.
+	TileBytes int64 `protobuf:"varint,7,opt,name=tile_bytes,json=tileBytes,proto3" json:"tile_bytes,omitempty"`
+}
+
+func (x *GetTreeStatsResponse) Reset() {
+	*x = GetTreeStatsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_trillian_admin_api_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetTreeStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTreeStatsResponse) ProtoMessage() {}
+
+func (x *GetTreeStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trillian_admin_api_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTreeStatsResponse.ProtoReflect.Descriptor instead.
+func (*GetTreeStatsResponse) Descriptor() ([]byte, []int) {
+	return file_trillian_admin_api_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetTreeStatsResponse) GetTreeId() int64 {
+	if x != nil {
+		return x.TreeId
+	}
+	return 0
+}
+
+func (x *GetTreeStatsResponse) GetLeafCount() int64 {
+	if x != nil {
+		return x.LeafCount
+	}
+	return 0
+}
+
+func (x *GetTreeStatsResponse) GetQueueDepth() int64 {
+	if x != nil {
+		return x.QueueDepth
+	}
+	return 0
+}
+
+func (x *GetTreeStatsResponse) GetOldestQueuedLeafAge() *durationpb.Duration {
+	if x != nil {
+		return x.OldestQueuedLeafAge
+	}
+	return nil
+}
+
+func (x *GetTreeStatsResponse) GetLatestRootAge() *durationpb.Duration {
+	if x != nil {
+		return x.LatestRootAge
+	}
+	return nil
+}
+
+func (x *GetTreeStatsResponse) GetLeafBytes() int64 {
+	if x != nil {
+		return x.LeafBytes
+	}
+	return 0
+}
+
+func (x *GetTreeStatsResponse) GetTileBytes() int64 {
+	if x != nil {
+		return x.TileBytes
+	}
+	return 0
+}
+
 // CreateTree request.
 type CreateTreeRequest struct {
 	state         protoimpl.MessageState
@@ -198,7 +480,7 @@ type CreateTreeRequest struct {
 func (x *CreateTreeRequest) Reset() {
 	*x = CreateTreeRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_trillian_admin_api_proto_msgTypes[3]
+		mi := &file_trillian_admin_api_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -211,7 +493,7 @@ func (x *CreateTreeRequest) String() string {
 func (*CreateTreeRequest) ProtoMessage() {}
 
 func (x *CreateTreeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_trillian_admin_api_proto_msgTypes[3]
+	mi := &file_trillian_admin_api_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -224,7 +506,7 @@ func (x *CreateTreeRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateTreeRequest.ProtoReflect.Descriptor instead.
 func (*CreateTreeRequest) Descriptor() ([]byte, []int) {
-	return file_trillian_admin_api_proto_rawDescGZIP(), []int{3}
+	return file_trillian_admin_api_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *CreateTreeRequest) GetTree() *Tree {
@@ -250,7 +532,7 @@ type UpdateTreeRequest struct {
 func (x *UpdateTreeRequest) Reset() {
 	*x = UpdateTreeRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_trillian_admin_api_proto_msgTypes[4]
+		mi := &file_trillian_admin_api_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -263,7 +545,7 @@ func (x *UpdateTreeRequest) String() string {
 func (*UpdateTreeRequest) ProtoMessage() {}
 
 func (x *UpdateTreeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_trillian_admin_api_proto_msgTypes[4]
+	mi := &file_trillian_admin_api_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -276,7 +558,7 @@ func (x *UpdateTreeRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateTreeRequest.ProtoReflect.Descriptor instead.
 func (*UpdateTreeRequest) Descriptor() ([]byte, []int) {
-	return file_trillian_admin_api_proto_rawDescGZIP(), []int{4}
+	return file_trillian_admin_api_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *UpdateTreeRequest) GetTree() *Tree {
@@ -306,7 +588,7 @@ type DeleteTreeRequest struct {
 func (x *DeleteTreeRequest) Reset() {
 	*x = DeleteTreeRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_trillian_admin_api_proto_msgTypes[5]
+		mi := &file_trillian_admin_api_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -319,7 +601,7 @@ func (x *DeleteTreeRequest) String() string {
 func (*DeleteTreeRequest) ProtoMessage() {}
 
 func (x *DeleteTreeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_trillian_admin_api_proto_msgTypes[5]
+	mi := &file_trillian_admin_api_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -332,7 +614,7 @@ func (x *DeleteTreeRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteTreeRequest.ProtoReflect.Descriptor instead.
 func (*DeleteTreeRequest) Descriptor() ([]byte, []int) {
-	return file_trillian_admin_api_proto_rawDescGZIP(), []int{5}
+	return file_trillian_admin_api_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *DeleteTreeRequest) GetTreeId() int64 {
@@ -355,7 +637,7 @@ type UndeleteTreeRequest struct {
 func (x *UndeleteTreeRequest) Reset() {
 	*x = UndeleteTreeRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_trillian_admin_api_proto_msgTypes[6]
+		mi := &file_trillian_admin_api_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -368,7 +650,7 @@ func (x *UndeleteTreeRequest) String() string {
 func (*UndeleteTreeRequest) ProtoMessage() {}
 
 func (x *UndeleteTreeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_trillian_admin_api_proto_msgTypes[6]
+	mi := &file_trillian_admin_api_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -381,7 +663,7 @@ func (x *UndeleteTreeRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UndeleteTreeRequest.ProtoReflect.Descriptor instead.
 func (*UndeleteTreeRequest) Descriptor() ([]byte, []int) {
-	return file_trillian_admin_api_proto_rawDescGZIP(), []int{6}
+	return file_trillian_admin_api_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *UndeleteTreeRequest) GetTreeId() int64 {
@@ -391,51 +673,399 @@ func (x *UndeleteTreeRequest) GetTreeId() int64 {
 	return 0
 }
 
+// AdminAuditEvent records a change made to a tree, either through the
+// TrillianAdmin API or by the garbage collection of deleted trees.
+type AdminAuditEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// ID of the event, assigned by the storage system. Events recorded later
+	// have greater IDs.
+	EventId int64 `protobuf:"varint,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	// ID of the changed tree.
+	TreeId int64 `protobuf:"varint,2,opt,name=tree_id,json=treeId,proto3" json:"tree_id,omitempty"`
+	// Operation which changed the tree.
+	Operation AdminAuditEvent_Operation `protobuf:"varint,3,opt,name=operation,proto3,enum=trillian.AdminAuditEvent_Operation" json:"operation,omitempty"`
+	// Identity of the caller: the subject of its verified TLS client certificate
+	// if it presented one, and its network address otherwise.
+	// Changes made by the server itself, such as hard deletions, are recorded
+	// with the name of the component which made them, e.g. "DeletedTreeGC".
+	Caller string `protobuf:"bytes,4,opt,name=caller,proto3" json:"caller,omitempty"`
+	// Request which changed the tree. Unset for changes made by the server
+	// itself.
+	Request *anypb.Any `protobuf:"bytes,5,opt,name=request,proto3" json:"request,omitempty"`
+	// The tree before the change. Unset for CREATE_TREE.
+	TreeBefore *Tree `protobuf:"bytes,6,opt,name=tree_before,json=treeBefore,proto3" json:"tree_before,omitempty"`
+	// The tree after the change. Unset for HARD_DELETE_TREE.
+	TreeAfter *Tree `protobuf:"bytes,7,opt,name=tree_after,json=treeAfter,proto3" json:"tree_after,omitempty"`
+	// Time of the change.
+	EventTime *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=event_time,json=eventTime,proto3" json:"event_time,omitempty"`
+}
+
+func (x *AdminAuditEvent) Reset() {
+	*x = AdminAuditEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_trillian_admin_api_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AdminAuditEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdminAuditEvent) ProtoMessage() {}
+
+func (x *AdminAuditEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_trillian_admin_api_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdminAuditEvent.ProtoReflect.Descriptor instead.
+func (*AdminAuditEvent) Descriptor() ([]byte, []int) {
+	return file_trillian_admin_api_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AdminAuditEvent) GetEventId() int64 {
+	if x != nil {
+		return x.EventId
+	}
+	return 0
+}
+
+func (x *AdminAuditEvent) GetTreeId() int64 {
+	if x != nil {
+		return x.TreeId
+	}
+	return 0
+}
+
+func (x *AdminAuditEvent) GetOperation() AdminAuditEvent_Operation {
+	if x != nil {
+		return x.Operation
+	}
+	return AdminAuditEvent_UNKNOWN_OPERATION
+}
+
+func (x *AdminAuditEvent) GetCaller() string {
+	if x != nil {
+		return x.Caller
+	}
+	return ""
+}
+
+func (x *AdminAuditEvent) GetRequest() *anypb.Any {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *AdminAuditEvent) GetTreeBefore() *Tree {
+	if x != nil {
+		return x.TreeBefore
+	}
+	return nil
+}
+
+func (x *AdminAuditEvent) GetTreeAfter() *Tree {
+	if x != nil {
+		return x.TreeAfter
+	}
+	return nil
+}
+
+func (x *AdminAuditEvent) GetEventTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EventTime
+	}
+	return nil
+}
+
+// ListAdminAuditEvents request.
+// Events are returned in order of their IDs, oldest first.
+type ListAdminAuditEventsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// If non-zero, only the events of this tree are returned.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId,proto3" json:"tree_id,omitempty"`
+	// Maximum number of events to return. If zero, all the events matching the
+	// request are returned in a single response.
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token of a previous response, to return the events
+	// following those returned by it. The other fields of the request must be
+	// the same as in the request which returned the token.
+	PageToken string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+}
+
+func (x *ListAdminAuditEventsRequest) Reset() {
+	*x = ListAdminAuditEventsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_trillian_admin_api_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListAdminAuditEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAdminAuditEventsRequest) ProtoMessage() {}
+
+func (x *ListAdminAuditEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trillian_admin_api_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAdminAuditEventsRequest.ProtoReflect.Descriptor instead.
+func (*ListAdminAuditEventsRequest) Descriptor() ([]byte, []int) {
+	return file_trillian_admin_api_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListAdminAuditEventsRequest) GetTreeId() int64 {
+	if x != nil {
+		return x.TreeId
+	}
+	return 0
+}
+
+func (x *ListAdminAuditEventsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListAdminAuditEventsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+// ListAdminAuditEvents response.
+type ListAdminAuditEventsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Events matching the request.
+	Event []*AdminAuditEvent `protobuf:"bytes,1,rep,name=event,proto3" json:"event,omitempty"`
+	// Token to pass as page_token to retrieve the next page of events. Empty if
+	// there are no more events.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+}
+
+func (x *ListAdminAuditEventsResponse) Reset() {
+	*x = ListAdminAuditEventsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_trillian_admin_api_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListAdminAuditEventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAdminAuditEventsResponse) ProtoMessage() {}
+
+func (x *ListAdminAuditEventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trillian_admin_api_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAdminAuditEventsResponse.ProtoReflect.Descriptor instead.
+func (*ListAdminAuditEventsResponse) Descriptor() ([]byte, []int) {
+	return file_trillian_admin_api_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListAdminAuditEventsResponse) GetEvent() []*AdminAuditEvent {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *ListAdminAuditEventsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
 var File_trillian_admin_api_proto protoreflect.FileDescriptor
 
 var file_trillian_admin_api_proto_rawDesc = []byte{
 	0x0a, 0x18, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x5f, 0x61, 0x64, 0x6d, 0x69, 0x6e,
 	0x5f, 0x61, 0x70, 0x69, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x08, 0x74, 0x72, 0x69, 0x6c,
 	0x6c, 0x69, 0x61, 0x6e, 0x1a, 0x0e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x5f, 0x6d, 0x61, 0x73, 0x6b,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x35, 0x0a, 0x10, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x72,
-	0x65, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x73, 0x68,
-	0x6f, 0x77, 0x5f, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08,
-	0x52, 0x0b, 0x73, 0x68, 0x6f, 0x77, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x22, 0x37, 0x0a,
-	0x11, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x72, 0x65, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x12, 0x22, 0x0a, 0x04, 0x74, 0x72, 0x65, 0x65, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
-	0x32, 0x0e, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x54, 0x72, 0x65, 0x65,
-	0x52, 0x04, 0x74, 0x72, 0x65, 0x65, 0x22, 0x29, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x54, 0x72, 0x65,
-	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x74, 0x72, 0x65, 0x65,
-	0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x74, 0x72, 0x65, 0x65, 0x49,
-	0x64, 0x22, 0x47, 0x0a, 0x11, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x54, 0x72, 0x65, 0x65, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x04, 0x74, 0x72, 0x65, 0x65, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e,
-	0x54, 0x72, 0x65, 0x65, 0x52, 0x04, 0x74, 0x72, 0x65, 0x65, 0x4a, 0x04, 0x08, 0x02, 0x10, 0x03,
-	0x52, 0x08, 0x6b, 0x65, 0x79, 0x5f, 0x73, 0x70, 0x65, 0x63, 0x22, 0x74, 0x0a, 0x11, 0x55, 0x70,
-	0x64, 0x61, 0x74, 0x65, 0x54, 0x72, 0x65, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
-	0x22, 0x0a, 0x04, 0x74, 0x72, 0x65, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e,
-	0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x54, 0x72, 0x65, 0x65, 0x52, 0x04, 0x74,
-	0x72, 0x65, 0x65, 0x12, 0x3b, 0x0a, 0x0b, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x5f, 0x6d, 0x61,
-	0x73, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
-	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64,
-	0x4d, 0x61, 0x73, 0x6b, 0x52, 0x0a, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x4d, 0x61, 0x73, 0x6b,
-	0x22, 0x2c, 0x0a, 0x11, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x54, 0x72, 0x65, 0x65, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x74, 0x72, 0x65, 0x65, 0x5f, 0x69, 0x64,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x74, 0x72, 0x65, 0x65, 0x49, 0x64, 0x22, 0x2e,
-	0x0a, 0x13, 0x55, 0x6e, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x54, 0x72, 0x65, 0x65, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x74, 0x72, 0x65, 0x65, 0x5f, 0x69, 0x64,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x74, 0x72, 0x65, 0x65, 0x49, 0x64, 0x32, 0x86,
-	0x03, 0x0a, 0x0d, 0x54, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x41, 0x64, 0x6d, 0x69, 0x6e,
-	0x12, 0x46, 0x0a, 0x09, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x72, 0x65, 0x65, 0x73, 0x12, 0x1a, 0x2e,
-	0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x72, 0x65,
-	0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x74, 0x72, 0x69, 0x6c,
-	0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x72, 0x65, 0x65, 0x73, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x35, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x54,
-	0x72, 0x65, 0x65, 0x12, 0x18, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x47,
-	0x65, 0x74, 0x54, 0x72, 0x65, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e,
-	0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x54, 0x72, 0x65, 0x65, 0x22, 0x00, 0x12,
+	0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x19, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x61, 0x6e, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a,
+	0x1e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2f, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a,
+	0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2f, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x5f, 0x6d, 0x61, 0x73, 0x6b, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x22, 0xd1, 0x02, 0x0a, 0x10, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x72, 0x65, 0x65, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x73, 0x68, 0x6f, 0x77, 0x5f,
+	0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x73,
+	0x68, 0x6f, 0x77, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x70, 0x61,
+	0x67, 0x65, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70,
+	0x61, 0x67, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x61, 0x67, 0x65, 0x5f,
+	0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x61, 0x67,
+	0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x3e, 0x0a, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73,
+	0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61,
+	0x6e, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x72, 0x65, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06,
+	0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x12, 0x2f, 0x0a, 0x09, 0x74, 0x72, 0x65, 0x65, 0x5f, 0x74,
+	0x79, 0x70, 0x65, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x12, 0x2e, 0x74, 0x72, 0x69, 0x6c,
+	0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x54, 0x72, 0x65, 0x65, 0x54, 0x79, 0x70, 0x65, 0x52, 0x08, 0x74,
+	0x72, 0x65, 0x65, 0x54, 0x79, 0x70, 0x65, 0x12, 0x32, 0x0a, 0x0a, 0x74, 0x72, 0x65, 0x65, 0x5f,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x13, 0x2e, 0x74, 0x72,
+	0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x54, 0x72, 0x65, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65,
+	0x52, 0x09, 0x74, 0x72, 0x65, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65, 0x1a, 0x39, 0x0a, 0x0b, 0x4c,
+	0x61, 0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x5f, 0x0a, 0x11, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x72,
+	0x65, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x22, 0x0a, 0x04, 0x74,
+	0x72, 0x65, 0x65, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x72, 0x69, 0x6c,
+	0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x54, 0x72, 0x65, 0x65, 0x52, 0x04, 0x74, 0x72, 0x65, 0x65, 0x12,
+	0x26, 0x0a, 0x0f, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x74, 0x6f, 0x6b,
+	0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6e, 0x65, 0x78, 0x74, 0x50, 0x61,
+	0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0x29, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x54, 0x72,
+	0x65, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x74, 0x72, 0x65,
+	0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x74, 0x72, 0x65, 0x65,
+	0x49, 0x64, 0x22, 0x2e, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x54, 0x72, 0x65, 0x65, 0x53, 0x74, 0x61,
+	0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x74, 0x72, 0x65,
+	0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x74, 0x72, 0x65, 0x65,
+	0x49, 0x64, 0x22, 0xc0, 0x02, 0x0a, 0x14, 0x47, 0x65, 0x74, 0x54, 0x72, 0x65, 0x65, 0x53, 0x74,
+	0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x17, 0x0a, 0x07, 0x74,
+	0x72, 0x65, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x74, 0x72,
+	0x65, 0x65, 0x49, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x65, 0x61, 0x66, 0x5f, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x6c, 0x65, 0x61, 0x66, 0x43, 0x6f,
+	0x75, 0x6e, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x71, 0x75, 0x65, 0x75, 0x65, 0x5f, 0x64, 0x65, 0x70,
+	0x74, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x71, 0x75, 0x65, 0x75, 0x65, 0x44,
+	0x65, 0x70, 0x74, 0x68, 0x12, 0x4e, 0x0a, 0x16, 0x6f, 0x6c, 0x64, 0x65, 0x73, 0x74, 0x5f, 0x71,
+	0x75, 0x65, 0x75, 0x65, 0x64, 0x5f, 0x6c, 0x65, 0x61, 0x66, 0x5f, 0x61, 0x67, 0x65, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x13, 0x6f, 0x6c, 0x64, 0x65, 0x73, 0x74, 0x51, 0x75, 0x65, 0x75, 0x65, 0x64, 0x4c, 0x65, 0x61,
+	0x66, 0x41, 0x67, 0x65, 0x12, 0x41, 0x0a, 0x0f, 0x6c, 0x61, 0x74, 0x65, 0x73, 0x74, 0x5f, 0x72,
+	0x6f, 0x6f, 0x74, 0x5f, 0x61, 0x67, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0d, 0x6c, 0x61, 0x74, 0x65, 0x73, 0x74,
+	0x52, 0x6f, 0x6f, 0x74, 0x41, 0x67, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x65, 0x61, 0x66, 0x5f,
+	0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x6c, 0x65, 0x61,
+	0x66, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x69, 0x6c, 0x65, 0x5f, 0x62,
+	0x79, 0x74, 0x65, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6c, 0x65,
+	0x42, 0x79, 0x74, 0x65, 0x73, 0x22, 0x47, 0x0a, 0x11, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x54,
+	0x72, 0x65, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x04, 0x74, 0x72,
+	0x65, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c,
+	0x69, 0x61, 0x6e, 0x2e, 0x54, 0x72, 0x65, 0x65, 0x52, 0x04, 0x74, 0x72, 0x65, 0x65, 0x4a, 0x04,
+	0x08, 0x02, 0x10, 0x03, 0x52, 0x08, 0x6b, 0x65, 0x79, 0x5f, 0x73, 0x70, 0x65, 0x63, 0x22, 0x74,
+	0x0a, 0x11, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x54, 0x72, 0x65, 0x65, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x04, 0x74, 0x72, 0x65, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x54, 0x72, 0x65,
+	0x65, 0x52, 0x04, 0x74, 0x72, 0x65, 0x65, 0x12, 0x3b, 0x0a, 0x0b, 0x75, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x5f, 0x6d, 0x61, 0x73, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x46,
+	0x69, 0x65, 0x6c, 0x64, 0x4d, 0x61, 0x73, 0x6b, 0x52, 0x0a, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x4d, 0x61, 0x73, 0x6b, 0x22, 0x2c, 0x0a, 0x11, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x54, 0x72,
+	0x65, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x74, 0x72, 0x65,
+	0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x74, 0x72, 0x65, 0x65,
+	0x49, 0x64, 0x22, 0x2e, 0x0a, 0x13, 0x55, 0x6e, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x54, 0x72,
+	0x65, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x74, 0x72, 0x65,
+	0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x74, 0x72, 0x65, 0x65,
+	0x49, 0x64, 0x22, 0xeb, 0x03, 0x0a, 0x0f, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x41, 0x75, 0x64, 0x69,
+	0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x5f,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x49,
+	0x64, 0x12, 0x17, 0x0a, 0x07, 0x74, 0x72, 0x65, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x06, 0x74, 0x72, 0x65, 0x65, 0x49, 0x64, 0x12, 0x41, 0x0a, 0x09, 0x6f, 0x70,
+	0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x23, 0x2e,
+	0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x41, 0x75,
+	0x64, 0x69, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x2e, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x52, 0x09, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a,
+	0x06, 0x63, 0x61, 0x6c, 0x6c, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63,
+	0x61, 0x6c, 0x6c, 0x65, 0x72, 0x12, 0x2e, 0x0a, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x41, 0x6e, 0x79, 0x52, 0x07, 0x72, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2f, 0x0a, 0x0b, 0x74, 0x72, 0x65, 0x65, 0x5f, 0x62, 0x65,
+	0x66, 0x6f, 0x72, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x72, 0x69,
+	0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x54, 0x72, 0x65, 0x65, 0x52, 0x0a, 0x74, 0x72, 0x65, 0x65,
+	0x42, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x12, 0x2d, 0x0a, 0x0a, 0x74, 0x72, 0x65, 0x65, 0x5f, 0x61,
+	0x66, 0x74, 0x65, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x72, 0x69,
+	0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x54, 0x72, 0x65, 0x65, 0x52, 0x09, 0x74, 0x72, 0x65, 0x65,
+	0x41, 0x66, 0x74, 0x65, 0x72, 0x12, 0x39, 0x0a, 0x0a, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x5f, 0x74,
+	0x69, 0x6d, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x69, 0x6d, 0x65,
+	0x22, 0x7e, 0x0a, 0x09, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x15, 0x0a,
+	0x11, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x5f, 0x4f, 0x50, 0x45, 0x52, 0x41, 0x54, 0x49,
+	0x4f, 0x4e, 0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x43, 0x52, 0x45, 0x41, 0x54, 0x45, 0x5f, 0x54,
+	0x52, 0x45, 0x45, 0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b, 0x55, 0x50, 0x44, 0x41, 0x54, 0x45, 0x5f,
+	0x54, 0x52, 0x45, 0x45, 0x10, 0x02, 0x12, 0x0f, 0x0a, 0x0b, 0x44, 0x45, 0x4c, 0x45, 0x54, 0x45,
+	0x5f, 0x54, 0x52, 0x45, 0x45, 0x10, 0x03, 0x12, 0x11, 0x0a, 0x0d, 0x55, 0x4e, 0x44, 0x45, 0x4c,
+	0x45, 0x54, 0x45, 0x5f, 0x54, 0x52, 0x45, 0x45, 0x10, 0x04, 0x12, 0x14, 0x0a, 0x10, 0x48, 0x41,
+	0x52, 0x44, 0x5f, 0x44, 0x45, 0x4c, 0x45, 0x54, 0x45, 0x5f, 0x54, 0x52, 0x45, 0x45, 0x10, 0x05,
+	0x22, 0x72, 0x0a, 0x1b, 0x4c, 0x69, 0x73, 0x74, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x41, 0x75, 0x64,
+	0x69, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x17, 0x0a, 0x07, 0x74, 0x72, 0x65, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x06, 0x74, 0x72, 0x65, 0x65, 0x49, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x70, 0x61, 0x67, 0x65,
+	0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x61, 0x67,
+	0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x74, 0x6f,
+	0x6b, 0x65, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x61, 0x67, 0x65, 0x54,
+	0x6f, 0x6b, 0x65, 0x6e, 0x22, 0x77, 0x0a, 0x1c, 0x4c, 0x69, 0x73, 0x74, 0x41, 0x64, 0x6d, 0x69,
+	0x6e, 0x41, 0x75, 0x64, 0x69, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2f, 0x0a, 0x05, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x41,
+	0x64, 0x6d, 0x69, 0x6e, 0x41, 0x75, 0x64, 0x69, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x05,
+	0x65, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x26, 0x0a, 0x0f, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x70, 0x61,
+	0x67, 0x65, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d,
+	0x6e, 0x65, 0x78, 0x74, 0x50, 0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x32, 0xc0, 0x04,
+	0x0a, 0x0d, 0x54, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x12,
+	0x46, 0x0a, 0x09, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x72, 0x65, 0x65, 0x73, 0x12, 0x1a, 0x2e, 0x74,
+	0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x72, 0x65, 0x65,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c,
+	0x69, 0x61, 0x6e, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x72, 0x65, 0x65, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x35, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x54, 0x72,
+	0x65, 0x65, 0x12, 0x18, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x47, 0x65,
+	0x74, 0x54, 0x72, 0x65, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x74,
+	0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x54, 0x72, 0x65, 0x65, 0x22, 0x00, 0x12, 0x4f,
+	0x0a, 0x0c, 0x47, 0x65, 0x74, 0x54, 0x72, 0x65, 0x65, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x1d,
+	0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x47, 0x65, 0x74, 0x54, 0x72, 0x65,
+	0x65, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e,
+	0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x47, 0x65, 0x74, 0x54, 0x72, 0x65, 0x65,
+	0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12,
 	0x3b, 0x0a, 0x0a, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x54, 0x72, 0x65, 0x65, 0x12, 0x1b, 0x2e,
 	0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x54,
 	0x72, 0x65, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x74, 0x72, 0x69,
@@ -451,13 +1081,19 @@ var file_trillian_admin_api_proto_rawDesc = []byte{
 	0x74, 0x65, 0x54, 0x72, 0x65, 0x65, 0x12, 0x1d, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61,
 	0x6e, 0x2e, 0x55, 0x6e, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x54, 0x72, 0x65, 0x65, 0x52, 0x65,
 	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e,
-	0x2e, 0x54, 0x72, 0x65, 0x65, 0x22, 0x00, 0x42, 0x50, 0x0a, 0x19, 0x63, 0x6f, 0x6d, 0x2e, 0x67,
-	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x42, 0x15, 0x54, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x41, 0x64,
-	0x6d, 0x69, 0x6e, 0x41, 0x70, 0x69, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x1a, 0x67,
-	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
-	0x2f, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x33,
+	0x2e, 0x54, 0x72, 0x65, 0x65, 0x22, 0x00, 0x12, 0x67, 0x0a, 0x14, 0x4c, 0x69, 0x73, 0x74, 0x41,
+	0x64, 0x6d, 0x69, 0x6e, 0x41, 0x75, 0x64, 0x69, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x12,
+	0x25, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x41,
+	0x64, 0x6d, 0x69, 0x6e, 0x41, 0x75, 0x64, 0x69, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61,
+	0x6e, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x41, 0x75, 0x64, 0x69, 0x74,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00,
+	0x42, 0x50, 0x0a, 0x19, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x74,
+	0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x42, 0x15, 0x54,
+	0x72, 0x69, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x41, 0x70, 0x69, 0x50,
+	0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x1a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x74, 0x72, 0x69, 0x6c, 0x6c, 0x69,
+	0x61, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -472,40 +1108,68 @@ func file_trillian_admin_api_proto_rawDescGZIP() []byte {
 	return file_trillian_admin_api_proto_rawDescData
 }
 
-var file_trillian_admin_api_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_trillian_admin_api_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_trillian_admin_api_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
 var file_trillian_admin_api_proto_goTypes = []interface{}{
-	(*ListTreesRequest)(nil),      // 0: trillian.ListTreesRequest
-	(*ListTreesResponse)(nil),     // 1: trillian.ListTreesResponse
-	(*GetTreeRequest)(nil),        // 2: trillian.GetTreeRequest
-	(*CreateTreeRequest)(nil),     // 3: trillian.CreateTreeRequest
-	(*UpdateTreeRequest)(nil),     // 4: trillian.UpdateTreeRequest
-	(*DeleteTreeRequest)(nil),     // 5: trillian.DeleteTreeRequest
-	(*UndeleteTreeRequest)(nil),   // 6: trillian.UndeleteTreeRequest
-	(*Tree)(nil),                  // 7: trillian.Tree
-	(*fieldmaskpb.FieldMask)(nil), // 8: google.protobuf.FieldMask
+	(AdminAuditEvent_Operation)(0),       // 0: trillian.AdminAuditEvent.Operation
+	(*ListTreesRequest)(nil),             // 1: trillian.ListTreesRequest
+	(*ListTreesResponse)(nil),            // 2: trillian.ListTreesResponse
+	(*GetTreeRequest)(nil),               // 3: trillian.GetTreeRequest
+	(*GetTreeStatsRequest)(nil),          // 4: trillian.GetTreeStatsRequest
+	(*GetTreeStatsResponse)(nil),         // 5: trillian.GetTreeStatsResponse
+	(*CreateTreeRequest)(nil),            // 6: trillian.CreateTreeRequest
+	(*UpdateTreeRequest)(nil),            // 7: trillian.UpdateTreeRequest
+	(*DeleteTreeRequest)(nil),            // 8: trillian.DeleteTreeRequest
+	(*UndeleteTreeRequest)(nil),          // 9: trillian.UndeleteTreeRequest
+	(*AdminAuditEvent)(nil),              // 10: trillian.AdminAuditEvent
+	(*ListAdminAuditEventsRequest)(nil),  // 11: trillian.ListAdminAuditEventsRequest
+	(*ListAdminAuditEventsResponse)(nil), // 12: trillian.ListAdminAuditEventsResponse
+	nil,                                  // 13: trillian.ListTreesRequest.LabelsEntry
+	(TreeType)(0),                        // 14: trillian.TreeType
+	(TreeState)(0),                       // 15: trillian.TreeState
+	(*Tree)(nil),                         // 16: trillian.Tree
+	(*durationpb.Duration)(nil),          // 17: google.protobuf.Duration
+	(*fieldmaskpb.FieldMask)(nil),        // 18: google.protobuf.FieldMask
+	(*anypb.Any)(nil),                    // 19: google.protobuf.Any
+	(*timestamppb.Timestamp)(nil),        // 20: google.protobuf.Timestamp
 }
 var file_trillian_admin_api_proto_depIdxs = []int32{
-	7,  // 0: trillian.ListTreesResponse.tree:type_name -> trillian.Tree
-	7,  // 1: trillian.CreateTreeRequest.tree:type_name -> trillian.Tree
-	7,  // 2: trillian.UpdateTreeRequest.tree:type_name -> trillian.Tree
-	8,  // 3: trillian.UpdateTreeRequest.update_mask:type_name -> google.protobuf.FieldMask
-	0,  // 4: trillian.TrillianAdmin.ListTrees:input_type -> trillian.ListTreesRequest
-	2,  // 5: trillian.TrillianAdmin.GetTree:input_type -> trillian.GetTreeRequest
-	3,  // 6: trillian.TrillianAdmin.CreateTree:input_type -> trillian.CreateTreeRequest
-	4,  // 7: trillian.TrillianAdmin.UpdateTree:input_type -> trillian.UpdateTreeRequest
-	5,  // 8: trillian.TrillianAdmin.DeleteTree:input_type -> trillian.DeleteTreeRequest
-	6,  // 9: trillian.TrillianAdmin.UndeleteTree:input_type -> trillian.UndeleteTreeRequest
-	1,  // 10: trillian.TrillianAdmin.ListTrees:output_type -> trillian.ListTreesResponse
-	7,  // 11: trillian.TrillianAdmin.GetTree:output_type -> trillian.Tree
-	7,  // 12: trillian.TrillianAdmin.CreateTree:output_type -> trillian.Tree
-	7,  // 13: trillian.TrillianAdmin.UpdateTree:output_type -> trillian.Tree
-	7,  // 14: trillian.TrillianAdmin.DeleteTree:output_type -> trillian.Tree
-	7,  // 15: trillian.TrillianAdmin.UndeleteTree:output_type -> trillian.Tree
-	10, // [10:16] is the sub-list for method output_type
-	4,  // [4:10] is the sub-list for method input_type
-	4,  // [4:4] is the sub-list for extension type_name
-	4,  // [4:4] is the sub-list for extension extendee
-	0,  // [0:4] is the sub-list for field type_name
+	13, // 0: trillian.ListTreesRequest.labels:type_name -> trillian.ListTreesRequest.LabelsEntry
+	14, // 1: trillian.ListTreesRequest.tree_type:type_name -> trillian.TreeType
+	15, // 2: trillian.ListTreesRequest.tree_state:type_name -> trillian.TreeState
+	16, // 3: trillian.ListTreesResponse.tree:type_name -> trillian.Tree
+	17, // 4: trillian.GetTreeStatsResponse.oldest_queued_leaf_age:type_name -> google.protobuf.Duration
+	17, // 5: trillian.GetTreeStatsResponse.latest_root_age:type_name -> google.protobuf.Duration
+	16, // 6: trillian.CreateTreeRequest.tree:type_name -> trillian.Tree
+	16, // 7: trillian.UpdateTreeRequest.tree:type_name -> trillian.Tree
+	18, // 8: trillian.UpdateTreeRequest.update_mask:type_name -> google.protobuf.FieldMask
+	0,  // 9: trillian.AdminAuditEvent.operation:type_name -> trillian.AdminAuditEvent.Operation
+	19, // 10: trillian.AdminAuditEvent.request:type_name -> google.protobuf.Any
+	16, // 11: trillian.AdminAuditEvent.tree_before:type_name -> trillian.Tree
+	16, // 12: trillian.AdminAuditEvent.tree_after:type_name -> trillian.Tree
+	20, // 13: trillian.AdminAuditEvent.event_time:type_name -> google.protobuf.Timestamp
+	10, // 14: trillian.ListAdminAuditEventsResponse.event:type_name -> trillian.AdminAuditEvent
+	1,  // 15: trillian.TrillianAdmin.ListTrees:input_type -> trillian.ListTreesRequest
+	3,  // 16: trillian.TrillianAdmin.GetTree:input_type -> trillian.GetTreeRequest
+	4,  // 17: trillian.TrillianAdmin.GetTreeStats:input_type -> trillian.GetTreeStatsRequest
+	6,  // 18: trillian.TrillianAdmin.CreateTree:input_type -> trillian.CreateTreeRequest
+	7,  // 19: trillian.TrillianAdmin.UpdateTree:input_type -> trillian.UpdateTreeRequest
+	8,  // 20: trillian.TrillianAdmin.DeleteTree:input_type -> trillian.DeleteTreeRequest
+	9,  // 21: trillian.TrillianAdmin.UndeleteTree:input_type -> trillian.UndeleteTreeRequest
+	11, // 22: trillian.TrillianAdmin.ListAdminAuditEvents:input_type -> trillian.ListAdminAuditEventsRequest
+	2,  // 23: trillian.TrillianAdmin.ListTrees:output_type -> trillian.ListTreesResponse
+	16, // 24: trillian.TrillianAdmin.GetTree:output_type -> trillian.Tree
+	5,  // 25: trillian.TrillianAdmin.GetTreeStats:output_type -> trillian.GetTreeStatsResponse
+	16, // 26: trillian.TrillianAdmin.CreateTree:output_type -> trillian.Tree
+	16, // 27: trillian.TrillianAdmin.UpdateTree:output_type -> trillian.Tree
+	16, // 28: trillian.TrillianAdmin.DeleteTree:output_type -> trillian.Tree
+	16, // 29: trillian.TrillianAdmin.UndeleteTree:output_type -> trillian.Tree
+	12, // 30: trillian.TrillianAdmin.ListAdminAuditEvents:output_type -> trillian.ListAdminAuditEventsResponse
+	23, // [23:31] is the sub-list for method output_type
+	15, // [15:23] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
 }
 
 func init() { file_trillian_admin_api_proto_init() }
@@ -552,7 +1216,7 @@ func file_trillian_admin_api_proto_init() {
 			}
 		}
 		file_trillian_admin_api_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CreateTreeRequest); i {
+			switch v := v.(*GetTreeStatsRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -564,7 +1228,7 @@ func file_trillian_admin_api_proto_init() {
 			}
 		}
 		file_trillian_admin_api_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*UpdateTreeRequest); i {
+			switch v := v.(*GetTreeStatsResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -576,7 +1240,7 @@ func file_trillian_admin_api_proto_init() {
 			}
 		}
 		file_trillian_admin_api_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DeleteTreeRequest); i {
+			switch v := v.(*CreateTreeRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -588,6 +1252,30 @@ func file_trillian_admin_api_proto_init() {
 			}
 		}
 		file_trillian_admin_api_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateTreeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_trillian_admin_api_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteTreeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_trillian_admin_api_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*UndeleteTreeRequest); i {
 			case 0:
 				return &v.state
@@ -599,19 +1287,56 @@ func file_trillian_admin_api_proto_init() {
 				return nil
 			}
 		}
+		file_trillian_admin_api_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AdminAuditEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_trillian_admin_api_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListAdminAuditEventsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_trillian_admin_api_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListAdminAuditEventsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_trillian_admin_api_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   7,
+			NumEnums:      1,
+			NumMessages:   13,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_trillian_admin_api_proto_goTypes,
 		DependencyIndexes: file_trillian_admin_api_proto_depIdxs,
+		EnumInfos:         file_trillian_admin_api_proto_enumTypes,
 		MessageInfos:      file_trillian_admin_api_proto_msgTypes,
 	}.Build()
 	File_trillian_admin_api_proto = out.File
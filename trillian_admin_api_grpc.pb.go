@@ -22,10 +22,14 @@ const _ = grpc.SupportPackageIsVersion7
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type TrillianAdminClient interface {
-	// Lists all trees the requester has access to.
+	// Lists the trees the requester has access to, optionally filtered by
+	// labels, type and state, and split into pages.
 	ListTrees(ctx context.Context, in *ListTreesRequest, opts ...grpc.CallOption) (*ListTreesResponse, error)
 	// Retrieves a tree by ID.
 	GetTree(ctx context.Context, in *GetTreeRequest, opts ...grpc.CallOption) (*Tree, error)
+	// Returns storage usage statistics of a log tree, if the storage system
+	// supports them.
+	GetTreeStats(ctx context.Context, in *GetTreeStatsRequest, opts ...grpc.CallOption) (*GetTreeStatsResponse, error)
 	// Creates a new tree.
 	// System-generated fields are not required and will be ignored if present,
 	// e.g.: tree_id, create_time and update_time.
@@ -42,6 +46,9 @@ type TrillianAdminClient interface {
 	// A soft-deleted tree may be undeleted for a certain period, after which
 	// it'll be permanently deleted.
 	UndeleteTree(ctx context.Context, in *UndeleteTreeRequest, opts ...grpc.CallOption) (*Tree, error)
+	// Lists the audit log of changes made to trees, if the storage system keeps
+	// one.
+	ListAdminAuditEvents(ctx context.Context, in *ListAdminAuditEventsRequest, opts ...grpc.CallOption) (*ListAdminAuditEventsResponse, error)
 }
 
 type trillianAdminClient struct {
@@ -70,6 +77,15 @@ func (c *trillianAdminClient) GetTree(ctx context.Context, in *GetTreeRequest, o
 	return out, nil
 }
 
+func (c *trillianAdminClient) GetTreeStats(ctx context.Context, in *GetTreeStatsRequest, opts ...grpc.CallOption) (*GetTreeStatsResponse, error) {
+	out := new(GetTreeStatsResponse)
+	err := c.cc.Invoke(ctx, "/trillian.TrillianAdmin/GetTreeStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *trillianAdminClient) CreateTree(ctx context.Context, in *CreateTreeRequest, opts ...grpc.CallOption) (*Tree, error) {
 	out := new(Tree)
 	err := c.cc.Invoke(ctx, "/trillian.TrillianAdmin/CreateTree", in, out, opts...)
@@ -106,14 +122,27 @@ func (c *trillianAdminClient) UndeleteTree(ctx context.Context, in *UndeleteTree
 	return out, nil
 }
 
+func (c *trillianAdminClient) ListAdminAuditEvents(ctx context.Context, in *ListAdminAuditEventsRequest, opts ...grpc.CallOption) (*ListAdminAuditEventsResponse, error) {
+	out := new(ListAdminAuditEventsResponse)
+	err := c.cc.Invoke(ctx, "/trillian.TrillianAdmin/ListAdminAuditEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TrillianAdminServer is the server API for TrillianAdmin service.
 // All implementations should embed UnimplementedTrillianAdminServer
 // for forward compatibility
 type TrillianAdminServer interface {
-	// Lists all trees the requester has access to.
+	// Lists the trees the requester has access to, optionally filtered by
+	// labels, type and state, and split into pages.
 	ListTrees(context.Context, *ListTreesRequest) (*ListTreesResponse, error)
 	// Retrieves a tree by ID.
 	GetTree(context.Context, *GetTreeRequest) (*Tree, error)
+	// Returns storage usage statistics of a log tree, if the storage system
+	// supports them.
+	GetTreeStats(context.Context, *GetTreeStatsRequest) (*GetTreeStatsResponse, error)
 	// Creates a new tree.
 	// System-generated fields are not required and will be ignored if present,
 	// e.g.: tree_id, create_time and update_time.
@@ -130,6 +159,9 @@ type TrillianAdminServer interface {
 	// A soft-deleted tree may be undeleted for a certain period, after which
 	// it'll be permanently deleted.
 	UndeleteTree(context.Context, *UndeleteTreeRequest) (*Tree, error)
+	// Lists the audit log of changes made to trees, if the storage system keeps
+	// one.
+	ListAdminAuditEvents(context.Context, *ListAdminAuditEventsRequest) (*ListAdminAuditEventsResponse, error)
 }
 
 // UnimplementedTrillianAdminServer should be embedded to have forward compatible implementations.
@@ -142,6 +174,9 @@ func (UnimplementedTrillianAdminServer) ListTrees(context.Context, *ListTreesReq
 func (UnimplementedTrillianAdminServer) GetTree(context.Context, *GetTreeRequest) (*Tree, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetTree not implemented")
 }
+func (UnimplementedTrillianAdminServer) GetTreeStats(context.Context, *GetTreeStatsRequest) (*GetTreeStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTreeStats not implemented")
+}
 func (UnimplementedTrillianAdminServer) CreateTree(context.Context, *CreateTreeRequest) (*Tree, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateTree not implemented")
 }
@@ -154,6 +189,9 @@ func (UnimplementedTrillianAdminServer) DeleteTree(context.Context, *DeleteTreeR
 func (UnimplementedTrillianAdminServer) UndeleteTree(context.Context, *UndeleteTreeRequest) (*Tree, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UndeleteTree not implemented")
 }
+func (UnimplementedTrillianAdminServer) ListAdminAuditEvents(context.Context, *ListAdminAuditEventsRequest) (*ListAdminAuditEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAdminAuditEvents not implemented")
+}
 
 // UnsafeTrillianAdminServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to TrillianAdminServer will
@@ -202,6 +240,24 @@ func _TrillianAdmin_GetTree_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TrillianAdmin_GetTreeStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTreeStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianAdminServer).GetTreeStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianAdmin/GetTreeStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianAdminServer).GetTreeStats(ctx, req.(*GetTreeStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _TrillianAdmin_CreateTree_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CreateTreeRequest)
 	if err := dec(in); err != nil {
@@ -274,6 +330,24 @@ func _TrillianAdmin_UndeleteTree_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TrillianAdmin_ListAdminAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAdminAuditEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianAdminServer).ListAdminAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianAdmin/ListAdminAuditEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianAdminServer).ListAdminAuditEvents(ctx, req.(*ListAdminAuditEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // TrillianAdmin_ServiceDesc is the grpc.ServiceDesc for TrillianAdmin service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -289,6 +363,10 @@ var TrillianAdmin_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetTree",
 			Handler:    _TrillianAdmin_GetTree_Handler,
 		},
+		{
+			MethodName: "GetTreeStats",
+			Handler:    _TrillianAdmin_GetTreeStats_Handler,
+		},
 		{
 			MethodName: "CreateTree",
 			Handler:    _TrillianAdmin_CreateTree_Handler,
@@ -305,6 +383,10 @@ var TrillianAdmin_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UndeleteTree",
 			Handler:    _TrillianAdmin_UndeleteTree_Handler,
 		},
+		{
+			MethodName: "ListAdminAuditEvents",
+			Handler:    _TrillianAdmin_ListAdminAuditEvents_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "trillian_admin_api.proto",
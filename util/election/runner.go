@@ -42,6 +42,12 @@ type RunnerConfig struct {
 	MasterHoldInterval time.Duration
 	// MasterHoldJitter is the maximum addition to MasterHoldInterval.
 	MasterHoldJitter time.Duration
+	// HandoverTimeout bounds the time spent resigning mastership and closing
+	// the election when the Runner stops. The handover is further bounded by
+	// the Runner's HandoverContext, if set. If zero, these operations use the
+	// context passed in to Run, which is normally canceled by then, so other
+	// instances might have to wait for the mastership to expire.
+	HandoverTimeout time.Duration
 
 	TimeSource clock.TimeSource
 }
@@ -75,11 +81,17 @@ func fixupRunnerConfig(cfg *RunnerConfig) {
 // Runner controls a continuous election process.
 type Runner struct {
 	// Allow the user to store a Cancel function with the runner for convenience.
-	Cancel   context.CancelFunc
+	Cancel context.CancelFunc
+	// HandoverContext, if set, is the parent of the context used for handing
+	// over mastership, which happens after the context passed in to Run is
+	// canceled. It allows bounding the handover by an overall shutdown deadline.
+	HandoverContext context.Context
+
 	id       string
 	cfg      *RunnerConfig
 	tracker  *MasterTracker
 	election election2.Election
+	shed     chan struct{}
 }
 
 // NewRunner builds a new election Runner instance with the given config. On
@@ -93,6 +105,27 @@ func NewRunner(id string, cfg *RunnerConfig, tracker *MasterTracker, cancel cont
 		cfg:      cfg,
 		tracker:  tracker,
 		election: el,
+		shed:     make(chan struct{}, 1),
+	}
+}
+
+// Epoch returns the number of the current mastership term, if the election
+// implements election2.Epocher, or 0.
+func (er *Runner) Epoch() int64 {
+	if e, ok := er.election.(election2.Epocher); ok {
+		return e.Epoch()
+	}
+	return 0
+}
+
+// Shed asks the Runner to voluntarily give up mastership, e.g. in order to
+// balance the load between instances. The resignation is queued as soon as
+// mastership has been held for at least the configured MasterHoldInterval.
+// Does nothing if the instance is not the master.
+func (er *Runner) Shed() {
+	select {
+	case er.shed <- struct{}{}:
+	default: // A request is already pending.
 	}
 }
 
@@ -109,9 +142,7 @@ func (er *Runner) Run(ctx context.Context, pending chan<- Resignation) {
 	klog.V(1).Infof("%s: start election-monitoring loop ", er.id)
 	defer func() {
 		klog.Infof("%s: shutdown election-monitoring loop", er.id)
-		if err := er.election.Close(ctx); err != nil {
-			klog.Warningf("%s: election.Close: %v", er.id, err)
-		}
+		er.handOver(ctx)
 	}()
 
 	for {
@@ -130,6 +161,12 @@ func (er *Runner) beMaster(ctx context.Context, pending chan<- Resignation) erro
 	klog.Infof("%s: Now, I am the master", er.id)
 	er.tracker.Set(er.id, true)
 	defer er.tracker.Set(er.id, false)
+	start := er.cfg.TimeSource.Now()
+	// Discard shed requests made before this mastership term.
+	select {
+	case <-er.shed:
+	default:
+	}
 
 	mctx, err := er.election.WithMastership(ctx)
 	if err != nil {
@@ -137,27 +174,61 @@ func (er *Runner) beMaster(ctx context.Context, pending chan<- Resignation) erro
 	}
 
 	timer := er.cfg.TimeSource.NewTimer(er.cfg.ResignDelay())
-	defer timer.Stop()
+	defer func() { timer.Stop() }()
 
-	select {
-	case <-mctx.Done(): // Mastership context is canceled.
-		klog.Errorf("%s: no longer the master!", er.id)
-		return mctx.Err()
-
-	case <-timer.Chan():
-		klog.Infof("%s: queue up resignation of mastership", er.id)
-		done := make(chan struct{})
-		r := Resignation{ID: er.id, er: er, done: done}
+	for shed, resign := er.shed, false; !resign; {
 		select {
-		case pending <- r:
-			<-done // Block until acted on.
-		default:
-			klog.Warning("Dropping resignation because operation manager seems to be exiting")
+		case <-mctx.Done(): // Mastership context is canceled.
+			klog.Errorf("%s: no longer the master!", er.id)
+			return mctx.Err()
+
+		case <-shed:
+			// Resign early, but not before the minimum hold interval has passed.
+			shed = nil
+			hold := er.cfg.MasterHoldInterval - er.cfg.TimeSource.Now().Sub(start)
+			if resign = hold <= 0; !resign {
+				klog.Infof("%s: shedding mastership in %v", er.id, hold)
+				timer.Stop()
+				timer = er.cfg.TimeSource.NewTimer(hold)
+			}
+
+		case <-timer.Chan():
+			resign = true
 		}
 	}
+
+	klog.Infof("%s: queue up resignation of mastership", er.id)
+	done := make(chan struct{})
+	r := Resignation{ID: er.id, er: er, done: done}
+	select {
+	case pending <- r:
+		<-done // Block until acted on.
+	default:
+		klog.Warning("Dropping resignation because operation manager seems to be exiting")
+	}
 	return nil
 }
 
+// handOver resigns mastership, if held, and closes the election, so that
+// another instance can take over immediately.
+func (er *Runner) handOver(ctx context.Context) {
+	if er.cfg.HandoverTimeout > 0 {
+		parent := er.HandoverContext
+		if parent == nil {
+			parent = context.Background()
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, er.cfg.HandoverTimeout)
+		defer cancel()
+		if err := er.election.Resign(ctx); err != nil {
+			klog.Warningf("%s: election.Resign: %v", er.id, err)
+		}
+	}
+	if err := er.election.Close(ctx); err != nil {
+		klog.Warningf("%s: election.Close: %v", er.id, err)
+	}
+}
+
 // Resignation indicates that a master should explicitly resign mastership, and
 // call the Execute() method as soon as no master-related activity is ongoing.
 type Resignation struct {
@@ -157,3 +157,50 @@ func TestElectionRunnerRun(t *testing.T) {
 		})
 	}
 }
+
+func TestElectionRunnerShed(t *testing.T) {
+	const logID = "6962"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	ts := clock.NewFake(start)
+	tracker := election.NewMasterTracker([]string{logID}, nil)
+	cfg := election.RunnerConfig{
+		MasterHoldInterval: election.MinMasterHoldInterval,
+		MasterHoldJitter:   time.Hour,
+		TimeSource:         ts,
+	}
+	er := election.NewRunner(logID, &cfg, tracker, nil, to.NewElection())
+	resignations := make(chan election.Resignation, 100)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		er.Run(ctx, resignations)
+	}()
+	time.Sleep(100 * time.Millisecond) // Let Run create the pause Timer.
+	ts.Set(start.Add(election.MinPreElectionPause))
+	time.Sleep(100 * time.Millisecond) // Now it becomes the master.
+	if got := tracker.Held(); len(got) != 1 {
+		t.Fatalf("holding %v, want [%s]", got, logID)
+	}
+
+	er.Shed()
+	time.Sleep(100 * time.Millisecond)
+	if got := len(resignations); got != 0 {
+		t.Errorf("got %d resignations before MasterHoldInterval, want 0", got)
+	}
+
+	ts.Set(start.Add(election.MinPreElectionPause + election.MinMasterHoldInterval))
+	time.Sleep(100 * time.Millisecond)
+	if got := len(resignations); got != 1 {
+		t.Fatalf("got %d resignations after MasterHoldInterval, want 1", got)
+	}
+	r := <-resignations
+	r.Execute(ctx)
+
+	cancel()
+	wg.Wait()
+}
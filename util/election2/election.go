@@ -85,3 +85,26 @@ type Election interface {
 type Factory interface {
 	NewElection(ctx context.Context, resourceID string) (Election, error)
 }
+
+// LoadCensus is an optional interface which a Factory can implement to let the
+// instances share how many resources each of them is the master for. This
+// allows the instances to balance the load between themselves.
+type LoadCensus interface {
+	// PublishLoad announces the number of resources this instance is currently
+	// the master for. The announcement is withdrawn when the instance stops.
+	PublishLoad(ctx context.Context, held int) error
+
+	// Loads returns the most recently published number of held resources for
+	// each live instance, keyed by instance ID.
+	Loads(ctx context.Context) (map[string]int, error)
+}
+
+// Epocher is an optional interface which an Election can implement if it
+// numbers the mastership terms of its resource in the storage, like the
+// elections in util/election2/sql. This allows the storage to reject the
+// writes of a master whose term has ended.
+type Epocher interface {
+	// Epoch returns the number of the current mastership term, or 0 if the
+	// instance is not the master.
+	Epoch() int64
+}
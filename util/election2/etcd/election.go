@@ -18,9 +18,14 @@ package etcd
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/util/election2"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/concurrency"
 	"k8s.io/klog/v2"
@@ -28,20 +33,73 @@ import (
 
 const resignID = "<resign>"
 
+// closeTimeout bounds the time spent on resigning in Close if the passed in
+// context is already canceled.
+const closeTimeout = 5 * time.Second
+
+var (
+	once            sync.Once
+	sessionsCreated monitoring.Counter
+	sessionsExpired monitoring.Counter
+)
+
+func createMetrics(mf monitoring.MetricFactory) (created, expired monitoring.Counter) {
+	created = mf.NewCounter("etcd_election_sessions_created", "Number of etcd sessions created for master election")
+	expired = mf.NewCounter("etcd_election_sessions_expired", "Number of etcd sessions for master election found expired")
+	return created, expired
+}
+
+// sessionMetrics returns the session counters for a Factory. Those recorded
+// with a MetricFactory are shared by the process, and created with the first
+// non-nil one, as metrics can only be registered once. Without one, the
+// Factory gets inert counters of its own.
+func sessionMetrics(mf monitoring.MetricFactory) (created, expired monitoring.Counter) {
+	if mf == nil {
+		return createMetrics(monitoring.InertMetricFactory{})
+	}
+	once.Do(func() { sessionsCreated, sessionsExpired = createMetrics(mf) })
+	return sessionsCreated, sessionsExpired
+}
+
 // Election is an implementation of election2.Election based on etcd.
+//
+// The mastership is attached to the lease of the etcd session shared by all
+// the elections of the Factory. Closing an Election resigns, but leaves the
+// session and its lease alive. Users must call Factory.Close to revoke the
+// lease once all its elections are closed; otherwise it lives on until its TTL
+// elapses after the process stops renewing it.
 type Election struct {
 	resourceID string
 	instanceID string
 	lockFile   string
 
-	client   *clientv3.Client
+	factory  *Factory
 	session  *concurrency.Session
 	election *concurrency.Election
+	closed   bool
 }
 
-// Await blocks until the instance captures mastership.
+// Await blocks until the instance captures mastership. If the etcd session
+// used for previous campaigns has expired, it campaigns within a new one.
 func (e *Election) Await(ctx context.Context) error {
-	return e.election.Campaign(ctx, e.instanceID)
+	for attempt := 0; ; attempt++ {
+		session, err := e.factory.getSession(ctx)
+		if err != nil {
+			return err
+		}
+		if session != e.session {
+			klog.Infof("%s: campaigning in session %x", e.resourceID, session.Lease())
+			e.session = session
+			e.election = concurrency.NewElection(session, e.lockFile)
+		}
+		err = e.election.Campaign(ctx, e.instanceID)
+		if err == rpctypes.ErrLeaseNotFound && attempt == 0 {
+			// The lease has expired, but the session has not noticed it yet.
+			e.factory.expireSession(session)
+			continue
+		}
+		return err
+	}
 }
 
 // WithMastership returns a "mastership context" which remains active until the
@@ -51,12 +109,12 @@ func (e *Election) WithMastership(ctx context.Context) (context.Context, error)
 	// context so that the monitoring goroutine below and the goroutine started
 	// by WithMastership will reliably terminate).
 	cctx, cancel := context.WithCancel(ctx)
-	etcdRev := e.election.Rev() // The revision at which e became the master.
-	if etcdRev == 0 {
+	if e.election == nil || e.election.Rev() == 0 {
 		// Not even tried to become the master. Return a canceled context.
 		cancel()
 		return cctx, nil
 	}
+	etcdRev := e.election.Rev() // The revision at which e became the master.
 
 	// Was the master once, so watch for latest mastership updates.
 	ch := e.election.Observe(cctx)
@@ -65,9 +123,18 @@ func (e *Election) WithMastership(ctx context.Context) (context.Context, error)
 	case <-ctx.Done():
 		cancel()
 		return nil, ctx.Err()
+	case <-e.session.Done():
+		// The session has expired, so the mastership is lost.
+		cancel()
+		return cctx, nil
 	case rsp, ok := <-ch:
+		if !ok || len(rsp.Kvs) == 0 {
+			// Mastership not captured at all, or the context is canceled.
+			cancel()
+			return cctx, nil
+		}
 		kv := rsp.Kvs[0]
-		if !ok || kv.CreateRevision != etcdRev || string(kv.Value) == resignID {
+		if kv.CreateRevision != etcdRev || string(kv.Value) == resignID {
 			// Mastership has been overtaken, released, or not capturead at all.
 			cancel()
 			return cctx, nil
@@ -75,24 +142,41 @@ func (e *Election) WithMastership(ctx context.Context) (context.Context, error)
 	}
 
 	// At this point we have observed confirmation that we are the master; start
-	// a goroutine to monitor for anyone else overtaking us.
+	// a goroutine to monitor for anyone else overtaking us, and for expiry of
+	// the session which holds the mastership. The session notices the expiry of
+	// its lease lazily, so also watch for deletion of the leader key, which
+	// happens as soon as the lease expires or is revoked.
+	done := e.session.Done()
+	deleted := e.factory.client.Watch(cctx, e.election.Key(), clientv3.WithRev(etcdRev), clientv3.WithFilterPut())
 	go func() {
 		defer func() {
 			cancel()
 			klog.Infof("%s: canceled mastership context", e.resourceID)
 		}()
 
-		for rsp := range ch {
-			kv := rsp.Kvs[0]
-			if kv.CreateRevision != etcdRev {
-				conquerorID := string(kv.Value)
-				// TODO(pavelkalinnikov): conquerorID can be resignID too. Serialize a
-				// protobuf with all mastership details instead of ID string.
-				klog.Warningf("%s: mastership overtaken by %s", e.resourceID, conquerorID)
-				break
-			} else if string(kv.Value) == resignID {
-				klog.Infof("%s: canceling context due to resignation", e.resourceID)
-				break
+		for {
+			select {
+			case <-done:
+				klog.Warningf("%s: etcd session expired", e.resourceID)
+				return
+			case <-deleted:
+				klog.Warningf("%s: leader key deleted", e.resourceID)
+				return
+			case rsp, ok := <-ch:
+				if !ok {
+					return
+				}
+				kv := rsp.Kvs[0]
+				if kv.CreateRevision != etcdRev {
+					conquerorID := string(kv.Value)
+					// TODO(pavelkalinnikov): conquerorID can be resignID too. Serialize a
+					// protobuf with all mastership details instead of ID string.
+					klog.Warningf("%s: mastership overtaken by %s", e.resourceID, conquerorID)
+					return
+				} else if string(kv.Value) == resignID {
+					klog.Infof("%s: canceling context due to resignation", e.resourceID)
+					return
+				}
 			}
 		}
 	}()
@@ -103,6 +187,9 @@ func (e *Election) WithMastership(ctx context.Context) (context.Context, error)
 // Resign releases mastership for this instance. The instance can be elected
 // again using Await. Idempotent, might be useful to retry if fails.
 func (e *Election) Resign(ctx context.Context) error {
+	if e.election == nil {
+		return nil // Never campaigned.
+	}
 	// Trigger Observe callers to see the update, and cancel mastership contexts.
 	err := e.election.Proclaim(ctx, resignID)
 	if err == concurrency.ErrElectionNotLeader {
@@ -114,54 +201,186 @@ func (e *Election) Resign(ctx context.Context) error {
 }
 
 // Close resigns and permanently stops participating in election. No other
-// method should be called after Close.
+// method should be called after Close, except Close itself which is a no-op.
+//
+// The etcd session is shared with other Election instances, so it stays
+// active, see Factory.Close. If resigning with the passed in context fails,
+// e.g. due to its cancelation, Close retries it with a fresh context.
 func (e *Election) Close(ctx context.Context) error {
-	if err := e.Resign(ctx); err != nil && err != concurrency.ErrElectionNotLeader {
-		klog.Errorf("%s: Resign(): %v", e.resourceID, err)
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	err := e.Resign(ctx)
+	if err == nil {
+		return nil
 	}
-	// Session's Close revokes the underlying lease, which results in removing
-	// the election-related keys. This achieves the effect of resignation even if
-	// the above Resign call failed (e.g. due to ctx cancelation).
-	return e.session.Close()
+	klog.Errorf("%s: Resign(): %v", e.resourceID, err)
+	cctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	return e.Resign(cctx)
 }
 
-// Factory creates Election instances.
+// Factory creates Election instances. All the elections created by a Factory
+// campaign within a single etcd session, which is re-created when it expires.
+//
+// It also implements election2.LoadCensus by storing the load of each
+// instance under the lock directory, and io.Closer. Close must be called to
+// release the shared session, as closing the elections does not do it.
 type Factory struct {
 	client     *clientv3.Client
 	instanceID string
 	lockDir    string
+
+	sessionsCreated monitoring.Counter
+	sessionsExpired monitoring.Counter
+
+	mu      sync.Mutex
+	session *concurrency.Session // The current shared session, or nil.
+	load    string               // The last published load, or empty.
 }
 
 // NewFactory builds an election factory that uses the given parameters. The
 // passed in etcd client should remain valid for the lifetime of the object.
 func NewFactory(instanceID string, client *clientv3.Client, lockDir string) *Factory {
+	return NewFactoryWithMetrics(instanceID, client, lockDir, nil)
+}
+
+// NewFactoryWithMetrics is like NewFactory, and also records etcd session
+// metrics using the given MetricFactory. The metrics are created with the
+// first non-nil MetricFactory passed in the process, and shared by all the
+// Factories built with one.
+func NewFactoryWithMetrics(instanceID string, client *clientv3.Client, lockDir string, mf monitoring.MetricFactory) *Factory {
+	created, expired := sessionMetrics(mf)
 	return &Factory{
-		client:     client,
-		instanceID: instanceID,
-		lockDir:    lockDir,
+		client:          client,
+		instanceID:      instanceID,
+		lockDir:         lockDir,
+		sessionsCreated: created,
+		sessionsExpired: expired,
 	}
 }
 
-// NewElection creates a specific Election instance.
-func (f *Factory) NewElection(ctx context.Context, resourceID string) (election2.Election, error) {
-	// TODO(pavelkalinnikov): Re-create the session if it expires.
-	// TODO(pavelkalinnikov): Share the same session between Election instances.
+// getSession returns the shared etcd session, creating a new one if there is
+// none yet or the previous one has expired.
+func (f *Factory) getSession(ctx context.Context) (*concurrency.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.session != nil {
+		select {
+		case <-f.session.Done():
+			klog.Warningf("etcd session %x expired", f.session.Lease())
+			f.sessionsExpired.Inc()
+			f.session = nil
+		default:
+			return f.session, nil
+		}
+	}
+
 	session, err := concurrency.NewSession(f.client)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create etcd session: %v", err)
 	}
-	lockFile := fmt.Sprintf("%s/%s", strings.TrimRight(f.lockDir, "/"), resourceID)
-	election := concurrency.NewElection(session, lockFile)
+	f.sessionsCreated.Inc()
+	klog.Infof("etcd session %x created", session.Lease())
+	f.session = session
+
+	// The load record was attached to the lease of the old session.
+	if f.load != "" {
+		if _, err := f.client.Put(ctx, f.loadKey(), f.load, clientv3.WithLease(session.Lease())); err != nil {
+			klog.Warningf("Failed to republish load: %v", err)
+		}
+	}
+	return session, nil
+}
+
+// expireSession discards the given shared session, if it is still the current
+// one, so that the next getSession call creates a new session.
+func (f *Factory) expireSession(session *concurrency.Session) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.session != session {
+		return
+	}
+	klog.Warningf("etcd session %x expired", session.Lease())
+	f.sessionsExpired.Inc()
+	session.Orphan()
+	f.session = nil
+}
+
+// Close closes the shared etcd session, which releases mastership of all the
+// elections created by this Factory. No elections should be used after Close.
+func (f *Factory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.session == nil {
+		return nil
+	}
+	err := f.session.Close()
+	f.session = nil
+	return err
+}
 
+// NewElection creates a specific Election instance. The Factory should not be
+// used to create multiple Election instances for the same resource, because
+// they would share mastership.
+func (f *Factory) NewElection(ctx context.Context, resourceID string) (election2.Election, error) {
+	lockFile := fmt.Sprintf("%s/%s", strings.TrimRight(f.lockDir, "/"), resourceID)
 	el := Election{
 		resourceID: resourceID,
 		instanceID: f.instanceID,
 		lockFile:   lockFile,
-		client:     f.client,
-		session:    session,
-		election:   election,
+		factory:    f,
 	}
-	klog.Infof("Election created: %+v", el)
+	klog.Infof("Election created: %s", lockFile)
 
 	return &el, nil
 }
+
+// loadKey returns the key under which this instance publishes its load.
+func (f *Factory) loadKey() string {
+	return f.loadPrefix() + f.instanceID
+}
+
+// loadPrefix returns the key prefix under which instances publish their loads.
+func (f *Factory) loadPrefix() string {
+	return fmt.Sprintf("%s/.load/", strings.TrimRight(f.lockDir, "/"))
+}
+
+// PublishLoad announces the number of resources held by this instance. The
+// record is attached to the shared etcd session, so it disappears shortly after
+// the instance stops.
+func (f *Factory) PublishLoad(ctx context.Context, held int) error {
+	session, err := f.getSession(ctx)
+	if err != nil {
+		return err
+	}
+	load := strconv.Itoa(held)
+	if _, err := f.client.Put(ctx, f.loadKey(), load, clientv3.WithLease(session.Lease())); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.load = load
+	return nil
+}
+
+// Loads returns the most recently published number of held resources for each
+// live instance, keyed by instance ID.
+func (f *Factory) Loads(ctx context.Context) (map[string]int, error) {
+	prefix := f.loadPrefix()
+	rsp, err := f.client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	loads := make(map[string]int, len(rsp.Kvs))
+	for _, kv := range rsp.Kvs {
+		held, err := strconv.Atoi(string(kv.Value))
+		if err != nil {
+			klog.Warningf("Ignoring malformed load record %q: %v", kv.Key, err)
+			continue
+		}
+		loads[strings.TrimPrefix(string(kv.Key), prefix)] = held
+	}
+	return loads, nil
+}
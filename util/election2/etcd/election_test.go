@@ -18,7 +18,10 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/testonly/integration/etcd"
 	"github.com/google/trillian/util/election2/testonly"
 )
@@ -55,6 +58,13 @@ func TestElectionThroughCommonClient(t *testing.T) {
 	if err := el2.Close(ctx); err != nil {
 		t.Fatalf("Close(20): %v", err)
 	}
+	// Closing again is a no-op.
+	if err := el1.Close(ctx); err != nil {
+		t.Errorf("second Close(10): %v", err)
+	}
+	if err := fact.Close(); err != nil {
+		t.Errorf("Factory.Close(): %v", err)
+	}
 }
 
 func TestElection(t *testing.T) {
@@ -72,3 +82,124 @@ func TestElection(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadCensus(t *testing.T) {
+	_, client, cleanup, err := etcd.StartEtcd()
+	if err != nil {
+		t.Fatalf("StartEtcd(): %v", err)
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+	fact1 := NewFactory("serv1", client, "res/")
+	fact2 := NewFactory("serv2", client, "res/")
+	other := NewFactory("serv3", client, "other/")
+
+	for _, p := range []struct {
+		f    *Factory
+		held int
+	}{{f: fact1, held: 3}, {f: fact2, held: 1}, {f: other, held: 5}, {f: fact1, held: 2}} {
+		if err := p.f.PublishLoad(ctx, p.held); err != nil {
+			t.Fatalf("PublishLoad(%d): %v", p.held, err)
+		}
+	}
+
+	loads, err := fact2.Loads(ctx)
+	if err != nil {
+		t.Fatalf("Loads(): %v", err)
+	}
+	if want := map[string]int{"serv1": 2, "serv2": 1}; !cmp.Equal(loads, want) {
+		t.Errorf("Loads(): %v, want %v", loads, want)
+	}
+}
+
+func TestElectionSessionExpiry(t *testing.T) {
+	_, client, cleanup, err := etcd.StartEtcd()
+	if err != nil {
+		t.Fatalf("StartEtcd(): %v", err)
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+	fact := NewFactory("serv", client, "res/")
+	defer fact.Close()
+	if err := fact.PublishLoad(ctx, 1); err != nil {
+		t.Fatalf("PublishLoad(): %v", err)
+	}
+	el1, err := fact.NewElection(ctx, "10")
+	if err != nil {
+		t.Fatalf("NewElection(10): %v", err)
+	}
+	el2, err := fact.NewElection(ctx, "20")
+	if err != nil {
+		t.Fatalf("NewElection(20): %v", err)
+	}
+	e1, e2 := el1.(*Election), el2.(*Election)
+	for _, e := range []*Election{e1, e2} {
+		if err := e.Await(ctx); err != nil {
+			t.Fatalf("Await(%s): %v", e.resourceID, err)
+		}
+	}
+	if e1.session != e2.session {
+		t.Fatal("elections do not share the session")
+	}
+	mctx, err := e1.WithMastership(ctx)
+	if err != nil {
+		t.Fatalf("WithMastership(): %v", err)
+	}
+
+	// Expire the session by revoking its lease.
+	old := e1.session
+	if _, err := client.Revoke(ctx, old.Lease()); err != nil {
+		t.Fatalf("Revoke(): %v", err)
+	}
+	select {
+	case <-mctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("mastership context not canceled after session expiry")
+	}
+
+	// The next campaign uses a new session.
+	if err := e1.Await(ctx); err != nil {
+		t.Fatalf("Await() after expiry: %v", err)
+	}
+	if e1.session == old {
+		t.Error("Await() did not re-create the session")
+	}
+	mctx, err = e1.WithMastership(ctx)
+	if err != nil {
+		t.Fatalf("WithMastership(): %v", err)
+	}
+	if mctx.Err() != nil {
+		t.Error("not the master after re-campaigning")
+	}
+	// The load record is republished within the new session.
+	loads, err := fact.Loads(ctx)
+	if err != nil {
+		t.Fatalf("Loads(): %v", err)
+	}
+	if got, want := loads["serv"], 1; got != want {
+		t.Errorf("Loads()[serv]: %d, want %d", got, want)
+	}
+	if err := e1.Close(ctx); err != nil {
+		t.Errorf("Close(): %v", err)
+	}
+}
+
+func TestFactoryMetrics(t *testing.T) {
+	// A Factory without a MetricFactory doesn't stop a later one from
+	// recording its metrics.
+	inert := NewFactory("serv1", nil, "res/")
+	fact := NewFactoryWithMetrics("serv2", nil, "res/", monitoring.InertMetricFactory{})
+	if fact.sessionsCreated != sessionsCreated || fact.sessionsExpired != sessionsExpired {
+		t.Error("NewFactoryWithMetrics() didn't use the metrics of its MetricFactory")
+	}
+	if inert.sessionsCreated == fact.sessionsCreated || inert.sessionsExpired == fact.sessionsExpired {
+		t.Error("NewFactory() shares the metrics of NewFactoryWithMetrics()")
+	}
+	// Metrics are only registered once.
+	other := NewFactoryWithMetrics("serv3", nil, "res/", monitoring.InertMetricFactory{})
+	if other.sessionsCreated != fact.sessionsCreated {
+		t.Error("Factories with a MetricFactory don't share the metrics")
+	}
+}
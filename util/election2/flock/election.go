@@ -0,0 +1,246 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package flock provides an implementation of master election based on
+// advisory file locks, for use by multiple processes on a single host.
+//
+// Each resource is guarded by an exclusive flock(2) on a file in a directory
+// shared by all the competing instances. The lock is released by the kernel
+// when the holding process exits, so a hot-standby process can take over
+// immediately.
+//
+// File locks are only supported on Linux, macOS, the BSDs and Solaris.
+// Elsewhere, NewFactory returns an error.
+package flock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/trillian/util/clock"
+	"github.com/google/trillian/util/election2"
+	"k8s.io/klog/v2"
+)
+
+// DefaultPollInterval is the default interval between attempts to capture a
+// lock, and between checks that a captured lock is still held.
+const DefaultPollInterval = 100 * time.Millisecond
+
+// Election is an implementation of election2.Election based on a file lock.
+type Election struct {
+	resourceID string
+	instanceID string
+	path       string
+	interval   time.Duration
+	ts         clock.TimeSource
+
+	mu     sync.Mutex
+	file   *os.File      // The locked file, or nil if not the master.
+	lost   chan struct{} // Closed when the current mastership term ends.
+	cancel func()        // Stops monitoring the locked file.
+}
+
+// Await blocks until the instance captures mastership.
+func (e *Election) Await(ctx context.Context) error {
+	for {
+		locked, err := e.tryLock()
+		if err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+		if err := clock.SleepSource(ctx, e.interval, e.ts); err != nil {
+			return err
+		}
+	}
+}
+
+// tryLock makes a single attempt to lock the file, and returns whether the
+// instance is the master.
+func (e *Election) tryLock() (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file != nil {
+		return true, nil
+	}
+
+	f, err := os.OpenFile(e.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return false, fmt.Errorf("failed to open lock file: %v", err)
+	}
+	if locked, err := lockFile(f); err != nil || !locked {
+		f.Close()
+		if err != nil {
+			return false, fmt.Errorf("failed to lock %s: %v", e.path, err)
+		}
+		return false, nil // Somebody else holds the lock.
+	}
+	// The file could have been removed or replaced by its previous holder
+	// between opening and locking it, in which case the lock guards nothing.
+	if held, err := isHeld(f, e.path); err != nil || !held {
+		f.Close()
+		return false, err
+	}
+	// Record the holder for the benefit of humans. This is best effort.
+	if err := f.Truncate(0); err == nil {
+		if _, err := f.WriteAt([]byte(e.instanceID+"\n"), 0); err != nil {
+			klog.Warningf("%s: failed to record lock holder: %v", e.resourceID, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.file, e.lost, e.cancel = f, make(chan struct{}), cancel
+	klog.Infof("%s: captured mastership", e.resourceID)
+	go e.monitor(ctx, f)
+	return true, nil
+}
+
+// isHeld returns whether the locked file f is still the one at the given path.
+func isHeld(f *os.File, path string) (bool, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	pi, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return os.SameFile(fi, pi), nil
+}
+
+// monitor periodically checks that the locked file has not been removed or
+// replaced, and ends the mastership term if it has. Returns when the passed in
+// context is canceled.
+func (e *Election) monitor(ctx context.Context, f *os.File) {
+	for {
+		if err := clock.SleepSource(ctx, e.interval, e.ts); err != nil {
+			return
+		}
+		held, err := isHeld(f, e.path)
+		if err != nil {
+			klog.Warningf("%s: failed to check lock file: %v", e.resourceID, err)
+			continue
+		}
+		if !held {
+			klog.Warningf("%s: lock file %s removed or replaced", e.resourceID, e.path)
+			e.mu.Lock()
+			if e.file == f {
+				e.unlockLocked()
+			}
+			e.mu.Unlock()
+			return
+		}
+	}
+}
+
+// unlockLocked releases the lock, and cancels all mastership contexts. Must be
+// called under lock.
+func (e *Election) unlockLocked() error {
+	if e.file == nil {
+		return nil
+	}
+	e.cancel()
+	close(e.lost)
+	// Closing the file releases the lock.
+	err := e.file.Close()
+	e.file, e.lost, e.cancel = nil, nil, nil
+	return err
+}
+
+// WithMastership returns a "mastership context" which remains active until the
+// instance stops being the master, or the passed in context is canceled.
+func (e *Election) WithMastership(ctx context.Context) (context.Context, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cctx, cancel := context.WithCancel(ctx)
+	if e.file == nil {
+		// Not the master. Return a canceled context.
+		cancel()
+		return cctx, nil
+	}
+
+	lost := e.lost
+	go func() {
+		defer func() {
+			cancel()
+			klog.Infof("%s: canceled mastership context", e.resourceID)
+		}()
+		select {
+		case <-lost:
+		case <-cctx.Done():
+		}
+	}()
+	return cctx, nil
+}
+
+// Resign releases mastership for this instance. The instance can be elected
+// again using Await. Idempotent, might be useful to retry if fails.
+func (e *Election) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.unlockLocked()
+}
+
+// Close resigns and permanently stops participating in election. No other
+// method should be called after Close.
+func (e *Election) Close(ctx context.Context) error {
+	return e.Resign(ctx)
+}
+
+// Factory creates Election instances.
+type Factory struct {
+	dir        string
+	instanceID string
+	interval   time.Duration
+}
+
+// NewFactory builds an election factory which keeps lock files in the given
+// directory. The directory is created if it does not exist, and must be shared
+// by all the instances competing for mastership.
+func NewFactory(instanceID, dir string, pollInterval time.Duration) (*Factory, error) {
+	if !supported {
+		return nil, errors.New("file lock elections are not supported on this platform")
+	}
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %v", err)
+	}
+	return &Factory{dir: dir, instanceID: instanceID, interval: pollInterval}, nil
+}
+
+// NewElection creates a specific Election instance.
+func (f *Factory) NewElection(ctx context.Context, resourceID string) (election2.Election, error) {
+	if resourceID == "" || resourceID != filepath.Base(resourceID) {
+		return nil, fmt.Errorf("invalid resource ID %q", resourceID)
+	}
+	el := &Election{
+		resourceID: resourceID,
+		instanceID: f.instanceID,
+		path:       filepath.Join(f.dir, resourceID+".lock"),
+		interval:   f.interval,
+		ts:         clock.System,
+	}
+	klog.Infof("Election created: %s", el.path)
+	return el, nil
+}
@@ -0,0 +1,138 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly || solaris
+// +build linux darwin freebsd netbsd openbsd dragonfly solaris
+
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/trillian/util/clock"
+	"github.com/google/trillian/util/election2/testonly"
+)
+
+func TestElection(t *testing.T) {
+	for _, nt := range testonly.Tests {
+		// Create a new Factory for each test for better isolation.
+		fact, err := NewFactory("testID", t.TempDir(), 10*time.Millisecond)
+		if err != nil {
+			t.Fatalf("NewFactory(): %v", err)
+		}
+		t.Run(nt.Name, func(t *testing.T) {
+			nt.Run(t, fact)
+		})
+	}
+}
+
+func TestNewElectionRejectsPaths(t *testing.T) {
+	fact, err := NewFactory("testID", t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewFactory(): %v", err)
+	}
+	for _, id := range []string{"", "../10", "a/b"} {
+		if _, err := fact.NewElection(context.Background(), id); err == nil {
+			t.Errorf("NewElection(%q) succeeded, want error", id)
+		}
+	}
+}
+
+func TestElectionCompetition(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	f1, _ := NewFactory("one", dir, 10*time.Millisecond)
+	f2, _ := NewFactory("two", dir, 10*time.Millisecond)
+	e1, err := f1.NewElection(ctx, "10")
+	if err != nil {
+		t.Fatalf("NewElection(): %v", err)
+	}
+	e2, err := f2.NewElection(ctx, "10")
+	if err != nil {
+		t.Fatalf("NewElection(): %v", err)
+	}
+
+	if err := e1.Await(ctx); err != nil {
+		t.Fatalf("Await(): %v", err)
+	}
+	cctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := e2.Await(cctx); err != context.DeadlineExceeded {
+		t.Fatalf("Await() while locked by another instance: %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	done := make(chan error)
+	go func() { done <- e2.Await(ctx) }()
+	if err := e1.Close(ctx); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Await(): %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Await() did not return after the other instance resigned")
+	}
+	if err := e2.Close(ctx); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+}
+
+func TestElectionLockFileRemoved(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	fact, _ := NewFactory("testID", dir, 10*time.Millisecond)
+	e, err := fact.NewElection(ctx, "10")
+	if err != nil {
+		t.Fatalf("NewElection(): %v", err)
+	}
+	ts := clock.NewFake(time.Unix(1000, 0))
+	e.(*Election).ts = ts
+	if err := e.Await(ctx); err != nil {
+		t.Fatalf("Await(): %v", err)
+	}
+	mctx, err := e.WithMastership(ctx)
+	if err != nil {
+		t.Fatalf("WithMastership(): %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "10.lock")); err != nil {
+		t.Fatalf("Remove(): %v", err)
+	}
+	// The lock file is only checked when the fake clock advances.
+	select {
+	case <-mctx.Done():
+		t.Fatal("mastership context canceled before the clock advanced")
+	case <-time.After(50 * time.Millisecond):
+	}
+	deadline := time.After(5 * time.Second)
+	for canceled := false; !canceled; {
+		ts.Set(ts.Now().Add(10 * time.Millisecond))
+		select {
+		case <-mctx.Done():
+			canceled = true
+		case <-deadline:
+			t.Fatal("mastership context not canceled after the lock file was removed")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if err := e.Close(ctx); err != nil {
+		t.Errorf("Close(): %v", err)
+	}
+}
@@ -0,0 +1,358 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sql provides an implementation of master election based on lease
+// rows stored in a SQL database (MySQL, CockroachDB or PostgreSQL).
+//
+// Each resource is guarded by a single row in the MasterElection table, which
+// records the current holder, the expiry time of its lease, and an epoch. The
+// epoch is incremented every time the lease changes hands, and is used to make
+// lease updates conditional on the term they belong to.
+//
+// The epoch also fences the writes of the masters: when the table is in the
+// database of the tree storage, the storage can check the epoch of the term a
+// write was started in against the current one (see storage.WithMasterFence),
+// and reject the write of a deposed master which hasn't noticed losing its
+// lease yet, e.g. due to a paused process or a skewed clock.
+//
+// Lease expiry is computed using the local clocks of the competing instances,
+// so the clock skew between them must be well below the lease TTL.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/trillian/util/clock"
+	"github.com/google/trillian/util/election2"
+	"k8s.io/klog/v2"
+)
+
+const (
+	selectLeaseSQL = `SELECT InstanceId, Epoch, ExpiryNanos FROM MasterElection
+		WHERE ResourceId = ?`
+	insertLeaseSQL = `INSERT INTO MasterElection(ResourceId, InstanceId, Epoch, ExpiryNanos)
+		VALUES(?, ?, ?, ?)`
+	// The Epoch condition turns the update into a compare-and-swap, so that
+	// only one of the instances racing for an expired lease can capture it.
+	captureLeaseSQL = `UPDATE MasterElection SET InstanceId = ?, Epoch = ?, ExpiryNanos = ?
+		WHERE ResourceId = ? AND Epoch = ?`
+	renewLeaseSQL = `UPDATE MasterElection SET ExpiryNanos = ?
+		WHERE ResourceId = ? AND InstanceId = ? AND Epoch = ? AND ExpiryNanos > 0`
+	releaseLeaseSQL = `UPDATE MasterElection SET ExpiryNanos = 0
+		WHERE ResourceId = ? AND InstanceId = ? AND Epoch = ?`
+)
+
+// Dialect identifies the flavour of SQL spoken by the database.
+type Dialect int
+
+const (
+	// MySQL is the dialect of MySQL and MariaDB databases.
+	MySQL Dialect = iota
+	// CockroachDB is the dialect of CockroachDB, and other databases using
+	// PostgreSQL-style statement placeholders.
+	CockroachDB
+)
+
+// query returns the statement adapted to the placeholder style of d.
+func (d Dialect) query(stmt string) string {
+	if d != CockroachDB {
+		return stmt
+	}
+	for i := 1; strings.Contains(stmt, "?"); i++ {
+		stmt = strings.Replace(stmt, "?", fmt.Sprintf("$%d", i), 1)
+	}
+	return stmt
+}
+
+// Election is an implementation of election2.Election based on a lease row in
+// a SQL database.
+type Election struct {
+	resourceID string
+	instanceID string
+	db         *sql.DB
+	dialect    Dialect
+	ttl        time.Duration
+	ts         clock.TimeSource
+
+	mu     sync.Mutex
+	epoch  int64         // Epoch of the held lease, or 0 if not the master.
+	expiry time.Time     // The time at which the held lease expires.
+	lost   chan struct{} // Closed when the current mastership term ends.
+	cancel func()        // Stops renewing the held lease.
+}
+
+// Await blocks until the instance captures mastership.
+func (e *Election) Await(ctx context.Context) error {
+	for {
+		captured, err := e.tryCapture(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if captured {
+			return nil
+		}
+		if err := clock.SleepSource(ctx, e.ttl/3, e.ts); err != nil {
+			return err
+		}
+	}
+}
+
+// tryCapture makes a single attempt to capture the lease, and returns whether
+// the instance is the master.
+func (e *Election) tryCapture(ctx context.Context) (bool, error) {
+	e.mu.Lock()
+	held := e.epoch != 0
+	e.mu.Unlock()
+	if held {
+		return true, nil
+	}
+
+	var holder string
+	var epoch, expiryNanos int64
+	err := e.db.QueryRowContext(ctx, e.dialect.query(selectLeaseSQL), e.resourceID).Scan(&holder, &epoch, &expiryNanos)
+	now := e.ts.Now()
+	expiry := now.Add(e.ttl)
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := e.db.ExecContext(ctx, e.dialect.query(insertLeaseSQL), e.resourceID, e.instanceID, 1, expiry.UnixNano()); err != nil {
+			// The insert fails if another instance created the row first, in which
+			// case we simply lost the race.
+			if rerr := e.db.QueryRowContext(ctx, e.dialect.query(selectLeaseSQL), e.resourceID).Scan(&holder, &epoch, &expiryNanos); rerr == nil {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to create lease row: %v", err)
+		}
+		e.startTerm(1, expiry)
+		return true, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to read lease row: %v", err)
+	}
+
+	if expiryNanos > now.UnixNano() {
+		return false, nil // Somebody else holds the lease.
+	}
+	res, err := e.db.ExecContext(ctx, e.dialect.query(captureLeaseSQL), e.instanceID, epoch+1, expiry.UnixNano(), e.resourceID, epoch)
+	if err != nil {
+		return false, fmt.Errorf("failed to capture lease: %v", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else if n != 1 {
+		return false, nil // Another instance captured the lease first.
+	}
+	e.startTerm(epoch+1, expiry)
+	return true, nil
+}
+
+// startTerm records the captured lease, and starts renewing it in the
+// background.
+func (e *Election) startTerm(epoch int64, expiry time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// Ending a term captured concurrently leaves its lease to expire, which is
+	// safe because it is no longer renewed.
+	e.endTermLocked()
+	ctx, cancel := context.WithCancel(context.Background())
+	e.epoch, e.expiry = epoch, expiry
+	e.lost, e.cancel = make(chan struct{}), cancel
+	klog.Infof("%s: captured mastership at epoch %d", e.resourceID, epoch)
+	go e.renew(ctx, epoch)
+}
+
+// endTermLocked stops renewing the held lease, and cancels all mastership
+// contexts. Must be called under lock.
+func (e *Election) endTermLocked() {
+	if e.epoch == 0 {
+		return
+	}
+	e.cancel()
+	close(e.lost)
+	e.epoch, e.expiry, e.lost, e.cancel = 0, time.Time{}, nil, nil
+}
+
+// renew periodically extends the lease captured at the given epoch, until the
+// passed in context is canceled, or the lease is lost.
+func (e *Election) renew(ctx context.Context, epoch int64) {
+	interval := e.ttl / 3
+	for {
+		if err := clock.SleepSource(ctx, interval, e.ts); err != nil {
+			return
+		}
+		renewed, err := e.extend(ctx, epoch)
+		if ctx.Err() != nil {
+			return
+		}
+
+		e.mu.Lock()
+		if e.epoch != epoch {
+			e.mu.Unlock()
+			return
+		}
+		switch {
+		case err != nil && e.ts.Now().Add(interval).Before(e.expiry):
+			// Retry while the lease is still guaranteed to outlive the next attempt.
+			klog.Warningf("%s: failed to renew lease: %v", e.resourceID, err)
+			e.mu.Unlock()
+			continue
+		case err != nil:
+			klog.Errorf("%s: failed to renew lease before expiry: %v", e.resourceID, err)
+		case !renewed:
+			klog.Warningf("%s: mastership at epoch %d overtaken", e.resourceID, epoch)
+		}
+		if err != nil || !renewed {
+			e.endTermLocked()
+			e.mu.Unlock()
+			return
+		}
+		e.mu.Unlock()
+	}
+}
+
+// extend attempts to push the expiry time of the lease held at the given epoch,
+// and returns whether the lease is still held.
+func (e *Election) extend(ctx context.Context, epoch int64) (bool, error) {
+	expiry := e.ts.Now().Add(e.ttl)
+	res, err := e.db.ExecContext(ctx, e.dialect.query(renewLeaseSQL), expiry.UnixNano(), e.resourceID, e.instanceID, epoch)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n != 1 {
+		return false, nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.epoch == epoch {
+		e.expiry = expiry
+	}
+	return true, nil
+}
+
+// WithMastership returns a "mastership context" which remains active until the
+// instance stops being the master, or the passed in context is canceled.
+func (e *Election) WithMastership(ctx context.Context) (context.Context, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cctx, cancel := context.WithCancel(ctx)
+	if e.epoch == 0 {
+		// Not the master. Return a canceled context.
+		cancel()
+		return cctx, nil
+	}
+
+	lost := e.lost
+	go func() {
+		defer func() {
+			cancel()
+			klog.Infof("%s: canceled mastership context", e.resourceID)
+		}()
+		select {
+		case <-lost:
+		case <-cctx.Done():
+		}
+	}()
+	return cctx, nil
+}
+
+// Resign releases mastership for this instance. The instance can be elected
+// again using Await. Idempotent, might be useful to retry if fails.
+func (e *Election) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	epoch := e.epoch
+	e.mu.Unlock()
+	if epoch == 0 {
+		return nil // Resigning if not master is a no-op.
+	}
+	if _, err := e.db.ExecContext(ctx, e.dialect.query(releaseLeaseSQL), e.resourceID, e.instanceID, epoch); err != nil {
+		return fmt.Errorf("failed to release lease: %v", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.epoch == epoch {
+		e.endTermLocked()
+	}
+	return nil
+}
+
+// Close resigns and permanently stops participating in election. No other
+// method should be called after Close.
+func (e *Election) Close(ctx context.Context) error {
+	if err := e.Resign(ctx); err != nil {
+		klog.Errorf("%s: Resign(): %v", e.resourceID, err)
+		// The lease will expire on its own once it is no longer renewed.
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.endTermLocked()
+	}
+	return nil
+}
+
+// Epoch implements election2.Epocher. It returns the epoch of the current
+// mastership term, or 0 if the instance does not believe it is the master. The
+// epoch of a resource strictly increases every time its mastership is captured.
+func (e *Election) Epoch() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.epoch
+}
+
+// Factory creates Election instances.
+type Factory struct {
+	db         *sql.DB
+	dialect    Dialect
+	instanceID string
+	ttl        time.Duration
+}
+
+// NewFactory builds an election factory that uses the given parameters. The
+// passed in database should remain valid for the lifetime of the object, and
+// contain the MasterElection table. The lease of each master expires unless
+// renewed within ttl.
+func NewFactory(instanceID string, db *sql.DB, dialect Dialect, ttl time.Duration) (*Factory, error) {
+	if ttl <= 0 {
+		return nil, errors.New("lease TTL must be positive")
+	}
+	return &Factory{
+		db:         db,
+		dialect:    dialect,
+		instanceID: instanceID,
+		ttl:        ttl,
+	}, nil
+}
+
+// NewElection creates a specific Election instance.
+func (f *Factory) NewElection(ctx context.Context, resourceID string) (election2.Election, error) {
+	el := &Election{
+		resourceID: resourceID,
+		instanceID: f.instanceID,
+		db:         f.db,
+		dialect:    f.dialect,
+		ttl:        f.ttl,
+		ts:         clock.System,
+	}
+	klog.Infof("Election created: %s/%s", f.instanceID, resourceID)
+	return el, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/trillian/storage/testdb"
+	"github.com/google/trillian/util/election2/testonly"
+)
+
+func TestQuery(t *testing.T) {
+	const stmt = "UPDATE T SET A = ? WHERE B = ? AND C = ?"
+	for _, tc := range []struct {
+		dialect Dialect
+		want    string
+	}{
+		{dialect: MySQL, want: stmt},
+		{dialect: CockroachDB, want: "UPDATE T SET A = $1 WHERE B = $2 AND C = $3"},
+	} {
+		if got := tc.dialect.query(stmt); got != tc.want {
+			t.Errorf("query(%d): got %q, want %q", tc.dialect, got, tc.want)
+		}
+	}
+}
+
+func TestNewFactoryRejectsBadTTL(t *testing.T) {
+	if _, err := NewFactory("serv", nil, MySQL, 0); err == nil {
+		t.Error("NewFactory() with zero TTL succeeded, want error")
+	}
+}
+
+func TestElectionMySQL(t *testing.T) {
+	testdb.SkipIfNoMySQL(t)
+	runElectionTests(t, testdb.DriverMySQL, MySQL)
+}
+
+func TestElectionCockroachDB(t *testing.T) {
+	testdb.SkipIfNoCockroachDB(t)
+	runElectionTests(t, testdb.DriverCockroachDB, CockroachDB)
+}
+
+func runElectionTests(t *testing.T, driver testdb.DriverName, dialect Dialect) {
+	t.Helper()
+	ctx := context.Background()
+	for _, nt := range testonly.Tests {
+		// Use a new database and Factory for each test for isolation, as tests
+		// reuse resource IDs.
+		db, done, err := testdb.NewTrillianDB(ctx, driver)
+		if err != nil {
+			t.Fatalf("NewTrillianDB(): %v", err)
+		}
+		fact, err := NewFactory(fmt.Sprintf("testID-%s", nt.Name), db, dialect, 3*time.Second)
+		if err != nil {
+			t.Fatalf("NewFactory(): %v", err)
+		}
+		t.Run(nt.Name, func(t *testing.T) {
+			nt.Run(t, fact)
+		})
+		done(ctx)
+	}
+
+	t.Run("Epoch", func(t *testing.T) {
+		db, done, err := testdb.NewTrillianDB(ctx, driver)
+		if err != nil {
+			t.Fatalf("NewTrillianDB(): %v", err)
+		}
+		defer done(ctx)
+		testEpoch(t, db, dialect)
+	})
+}
+
+// testEpoch checks that the epoch increases every time the lease of a
+// resource changes hands.
+func testEpoch(t *testing.T, db *sql.DB, dialect Dialect) {
+	t.Helper()
+	ctx := context.Background()
+	f1, _ := NewFactory("one", db, dialect, time.Second)
+	f2, _ := NewFactory("two", db, dialect, time.Second)
+	e1, err := f1.NewElection(ctx, "epoch")
+	if err != nil {
+		t.Fatalf("NewElection(): %v", err)
+	}
+	e2, err := f2.NewElection(ctx, "epoch")
+	if err != nil {
+		t.Fatalf("NewElection(): %v", err)
+	}
+
+	var epochs []int64
+	for _, e := range []*Election{e1.(*Election), e2.(*Election), e1.(*Election)} {
+		actx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err := e.Await(actx)
+		cancel()
+		if err != nil {
+			t.Fatalf("Await(): %v", err)
+		}
+		epochs = append(epochs, e.Epoch())
+		if err := e.Resign(ctx); err != nil {
+			t.Fatalf("Resign(): %v", err)
+		}
+	}
+	for i := 1; i < len(epochs); i++ {
+		if epochs[i] <= epochs[i-1] {
+			t.Errorf("epochs not increasing: %v", epochs)
+		}
+	}
+}